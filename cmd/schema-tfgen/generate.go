@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/tfgen"
+)
+
+func runGenerate(args []string) error {
+	flags := flag.NewFlagSet("generate", flag.ExitOnError)
+	definitions := flags.String("definitions", "definitions", "directory containing row type definition files")
+	out := flags.String("out", ".", "directory to write the generated package to")
+	pkg := flags.String("package", "", "name of the generated package (defaults to the base name of -out)")
+	columnTypes := flags.String("column-types", "", "YAML file of custom column types")
+	tests := flags.Bool("tests", false, "also generate acceptance tests, run against in-memory storage")
+	providerName := flags.String("provider-name", "", "provider type name to use in generated tests, e.g. \"tree\"")
+	migrations := flags.String("migrations", "", "directory to write migration steps to when definitions change (records definitions in "+tfgen.LockFile+" in -out)")
+	clientOut := flags.String("client-out", "", "directory to write a package of typed Go clients to")
+	clientPkg := flags.String("client-package", "", "name of the client package (defaults to the base name of -client-out)")
+	watch := flags.Bool("watch", false, "keep running, and regenerate whenever a definition changes")
+	interval := flags.Duration("interval", time.Second, "how often to check for changes in watch mode")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	cfg := tfgen.Config{
+		Package:          *pkg,
+		OutputDir:        *out,
+		Tests:            *tests,
+		ProviderTypeName: *providerName,
+		MigrationsDir:    *migrations,
+		ClientDir:        *clientOut,
+		ClientPackage:    *clientPkg,
+	}
+	if *columnTypes != "" {
+		var err error
+		cfg.ColumnTypes, err = tfgen.LoadColumnTypes(*columnTypes)
+		if err != nil {
+			return err
+		}
+	}
+	if cfg.Package == "" {
+		abs, err := filepath.Abs(*out)
+		if err != nil {
+			return err
+		}
+		cfg.Package = filepath.Base(abs)
+	}
+
+	if !*watch {
+		return generate(cfg, *definitions)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	return watchDefinitions(ctx, cfg, *definitions, *interval)
+}
+
+func generate(cfg tfgen.Config, definitions string) error {
+	defs, err := tfgen.LoadDefinitions(definitions)
+	if err != nil {
+		return err
+	}
+	if err := tfgen.Generate(cfg, defs); err != nil {
+		return err
+	}
+	log.Printf("generated %d row types into %s", len(defs), cfg.OutputDir)
+	return nil
+}
+
+// watchDefinitions polls the definitions directory, and regenerates whenever
+// its contents change. Generation errors are reported but do not stop the
+// watch, so that a definition can be fixed while the watcher keeps running.
+func watchDefinitions(ctx context.Context, cfg tfgen.Config, definitions string, interval time.Duration) error {
+	log.Printf("watching %s for changes", definitions)
+	last := ""
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		current, err := fingerprint(definitions)
+		if err != nil {
+			return err
+		}
+		if current != last {
+			last = current
+			if err := generate(cfg, definitions); err != nil {
+				log.Print(err.Error())
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// fingerprint summarizes the names, sizes, and modification times of the files
+// in dir.
+func fingerprint(dir string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	parts := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, fmt.Sprintf("%s:%d:%d", entry.Name(), info.Size(), info.ModTime().UnixNano()))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, "\n"), nil
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/dynamodb"
+	"github.com/spilliams/tree-terraform-provider/pkg/tfgen"
+)
+
+func runInfer(args []string) error {
+	flags := flag.NewFlagSet("infer", flag.ExitOnError)
+	profile := flags.String("profile", "", "AWS profile to use")
+	region := flags.String("region", "", "AWS region of the table")
+	table := flags.String("table", "", "DynamoDB table to scan")
+	out := flags.String("out", "definitions", "directory to write inferred definitions to")
+	overwrite := flags.Bool("overwrite", false, "replace existing definition files")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *table == "" {
+		return fmt.Errorf("-table is required")
+	}
+
+	ctx := context.Background()
+	storer, err := dynamodb.NewClient(ctx, *profile, *region, *table, "")
+	if err != nil {
+		return err
+	}
+	defs, warnings, err := tfgen.InferDefinitions(ctx, storer)
+	if err != nil {
+		return err
+	}
+	for _, warning := range warnings {
+		log.Print(warning)
+	}
+	if len(defs) == 0 {
+		return fmt.Errorf("table %s has no rows to infer definitions from", *table)
+	}
+	if err := tfgen.Validate(defs); err != nil {
+		log.Printf("the inferred definitions need editing before they can be generated: %s", err)
+	}
+
+	written, err := tfgen.WriteDefinitions(*out, defs, *overwrite)
+	for _, path := range written {
+		log.Printf("wrote %s", path)
+	}
+	return err
+}
@@ -0,0 +1,46 @@
+// Command schema-tfgen generates Terraform resources and data sources from row
+// type definitions.
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+)
+
+const usage = `usage: schema-tfgen <command> [flags]
+
+commands:
+  generate    generate provider code from row type definitions
+  openapi     propose row type definitions from an OpenAPI document
+  infer       propose row type definitions from the rows in a DynamoDB table
+`
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("schema-tfgen: ")
+
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "generate":
+		err = runGenerate(os.Args[2:])
+	case "openapi":
+		err = runOpenAPI(os.Args[2:])
+	case "infer":
+		err = runInfer(os.Args[2:])
+	case "-h", "-help", "--help", "help":
+		fmt.Fprint(os.Stdout, usage)
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/tfgen"
+)
+
+func runOpenAPI(args []string) error {
+	flags := flag.NewFlagSet("openapi", flag.ExitOnError)
+	spec := flags.String("spec", "", "OpenAPI document to read, in JSON or YAML")
+	out := flags.String("out", "definitions", "directory to write proposed definitions to")
+	overwrite := flags.Bool("overwrite", false, "replace existing definition files")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *spec == "" {
+		return fmt.Errorf("-spec is required")
+	}
+
+	f, err := os.Open(*spec)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	defs, warnings, err := tfgen.ProposeFromOpenAPI(f)
+	if err != nil {
+		return err
+	}
+	for _, warning := range warnings {
+		log.Print(warning)
+	}
+	if err := tfgen.Validate(defs); err != nil {
+		log.Printf("the proposed definitions need editing before they can be generated: %s", err)
+	}
+
+	written, err := tfgen.WriteDefinitions(*out, defs, *overwrite)
+	for _, path := range written {
+		log.Printf("wrote %s", path)
+	}
+	return err
+}
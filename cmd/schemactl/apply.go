@@ -0,0 +1,71 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/changeset"
+)
+
+func runApply(args []string) error {
+	flags := flag.NewFlagSet("apply", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: schemactl apply [flags] <changeset.json>")
+		flags.PrintDefaults()
+	}
+	backend := backendFlag(flags)
+	dryRun := flags.Bool("dry-run", false, "only check the changeset against the stored rows")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return fmt.Errorf("exactly one changeset is required")
+	}
+
+	var r io.Reader = os.Stdin
+	if path := flags.Arg(0); path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	changes, err := changeset.Read(r)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := commandContext()
+	defer stop()
+	storer, err := openBackend(ctx, *backend)
+	if err != nil {
+		return err
+	}
+
+	if *dryRun {
+		conflicts, err := changeset.Validate(ctx, storer, changes)
+		if err != nil {
+			return err
+		}
+		for _, conflict := range conflicts {
+			fmt.Println(conflict)
+		}
+		if len(conflicts) > 0 {
+			return fmt.Errorf("%d of %d changes conflict", len(conflicts), len(changes.Changes))
+		}
+		log.Printf("dry run: all %d changes apply cleanly", len(changes.Changes))
+		return nil
+	}
+
+	applied, err := changeset.Apply(ctx, storer, changes)
+	if err != nil {
+		return fmt.Errorf("applied %d of %d changes: %w", applied, len(changes.Changes), err)
+	}
+	log.Printf("applied %d changes", applied)
+	return nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/integrity"
+)
+
+func runAuditIntegrity(args []string) error {
+	flags := flag.NewFlagSet("audit-integrity", flag.ExitOnError)
+	backend := backendFlag(flags)
+	keyARN := flags.String("key-arn", "", "ARN of the KMS HMAC key rows are signed with, as set in the provider's integrity_key_arn")
+	asJSON := flags.Bool("json", false, "write the report as JSON, for compliance records")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *keyARN == "" {
+		return fmt.Errorf("-key-arn is required")
+	}
+
+	ctx, stop := commandContext()
+	defer stop()
+	storer, err := openBackend(ctx, *backend)
+	if err != nil {
+		return err
+	}
+	profile, region := backendAWSConfig(*backend)
+	signer, err := integrity.NewKMSSigner(ctx, profile, region, *keyARN)
+	if err != nil {
+		return err
+	}
+
+	report, err := integrity.NewStorer(storer, signer, false).Audit(ctx)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			return err
+		}
+	} else {
+		fmt.Printf("%d rows, %d signed, %d findings\ndigest %s\n", report.Rows, report.Signed, len(report.Findings), report.Digest)
+		if !report.OK() {
+			tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(tw, "\nTYPE\tID\tLABEL\tPARENT\tPROBLEM")
+			for _, finding := range report.Findings {
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", finding.Type, finding.ID, finding.Label, orDash(finding.ParentID), finding.Problem)
+			}
+			if err := tw.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if !report.OK() {
+		return fmt.Errorf("%d rows failed the audit", len(report.Findings))
+	}
+	return nil
+}
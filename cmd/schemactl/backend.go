@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/spilliams/tree-terraform-provider/internal/backends"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+const backendUsage = backends.Usage + `
+The backend defaults to the SCHEMACTL_BACKEND environment variable.
+`
+
+// backendFlag adds the -backend flag to a command's flags.
+func backendFlag(flags *flag.FlagSet) *string {
+	return flags.String("backend", os.Getenv("SCHEMACTL_BACKEND"), "storage backend to use, e.g. dynamodb://tree?region=us-west-2")
+}
+
+// openBackend connects to the storage backend the spec describes.
+func openBackend(ctx context.Context, spec string) (storage.RowStorer, error) {
+	if spec == "" {
+		return nil, fmt.Errorf("a backend is required: pass -backend or set SCHEMACTL_BACKEND")
+	}
+	return backends.Open(ctx, spec)
+}
+
+// backendAWSConfig returns the AWS profile and region of a DynamoDB backend
+// spec, for the other AWS clients a command needs.
+func backendAWSConfig(spec string) (profile, region string) {
+	return backends.AWSConfig(spec)
+}
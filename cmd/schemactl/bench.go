@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"runtime/pprof"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/bench"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/profiling"
+)
+
+func runBench(args []string) error {
+	flags := flag.NewFlagSet("bench", flag.ExitOnError)
+	backend := backendFlag(flags)
+	mixName := flags.String("mix", "plan", "operations to run: plan, apply, or write")
+	operations := flags.Int("ops", 1000, "number of operations to run on each backend")
+	concurrency := flags.Int("concurrency", 10, "operations to run at once")
+	parents := flags.Int("parents", 10, "parent rows to create before the operations")
+	children := flags.Int("children", 10, "children to create under each parent before the operations")
+	cpuProfile := flags.String("cpuprofile", "", "file to write a CPU profile to, labeled by storage operation")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: schemactl bench [flags] [backend ...]\n\nCompares backends on a mix of operations; with none named, uses -backend.")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	mix, err := bench.ParseMix(*mixName)
+	if err != nil {
+		return err
+	}
+	backends := flags.Args()
+	if len(backends) == 0 {
+		backends = []string{*backend}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if *cpuProfile != "" {
+		f, err := os.Create(*cpuProfile)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if err := pprof.StartCPUProfile(f); err != nil {
+			return err
+		}
+		defer pprof.StopCPUProfile()
+	}
+
+	opts := bench.Options{Mix: mix, Operations: *operations, Concurrency: *concurrency, Parents: *parents, Children: *children}
+	for _, spec := range backends {
+		storer, err := openBackend(ctx, spec)
+		if err != nil {
+			return err
+		}
+		log.Printf("running %d %s operations on %s", *operations, *mixName, spec)
+		result, err := bench.Run(ctx, profiling.NewStorer(storer), opts)
+		if err != nil {
+			return fmt.Errorf("%s: %w", spec, err)
+		}
+		if err := printBenchResult(os.Stdout, spec, result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func printBenchResult(w io.Writer, spec string, result *bench.Result) error {
+	fmt.Fprintf(w, "%s: %d operations in %s, %.1f/s\n", spec, result.Total(), result.Elapsed.Round(time.Millisecond), result.Throughput())
+	ops := make([]string, 0, len(result.Operations))
+	for op := range result.Operations {
+		ops = append(ops, op)
+	}
+	sort.Strings(ops)
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "OPERATION\tCOUNT\tERRORS\tMEAN\tP50\tP90\tP99\t")
+	for _, op := range ops {
+		stats := result.Operations[op]
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%s\t%s\t%s\t%s\t\n", op, stats.Count, stats.Errors,
+			formatLatency(stats.Mean()), formatLatency(stats.Percentile(50)), formatLatency(stats.Percentile(90)), formatLatency(stats.Percentile(99)))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if result.FirstError != nil {
+		fmt.Fprintf(w, "first error: %s\n", result.FirstError)
+	}
+	fmt.Fprintln(w)
+	return nil
+}
+
+func formatLatency(d time.Duration) string {
+	if d < time.Millisecond {
+		return d.Round(time.Microsecond).String()
+	}
+	return d.Round(100 * time.Microsecond).String()
+}
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+const browseHelp = `commands:
+  ls                     list the rows here
+  cd <n|label|id>        go into a row (.. goes up, / to the top)
+  show                   show this row's columns
+  set <column> <value>   set a column; [a,b] sets a string set
+  unset <column>         remove a column
+  rename <label>         relabel this row
+  new <type> <label>     create a row here
+  rm                     delete this row, if it has no children
+  reload                 re-read the rows
+  help                   show this help
+  quit                   leave
+`
+
+func runBrowse(args []string) error {
+	flags := flag.NewFlagSet("browse", flag.ExitOnError)
+	backend := backendFlag(flags)
+	piiOpts := piiFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	storer, err := openBackend(ctx, *backend)
+	if err != nil {
+		return err
+	}
+	pii, err := piiOpts.columns()
+	if err != nil {
+		return err
+	}
+	b := &browser{storer: storer, out: os.Stdout, pii: pii}
+	if err := b.reload(ctx); err != nil {
+		return err
+	}
+	fmt.Fprint(b.out, "type help for commands\n")
+	return b.run(ctx, os.Stdin)
+}
+
+// browser walks the tree like a shell walks directories. It keeps a copy of
+// every row, re-read after each edit.
+type browser struct {
+	storer storage.RowStorer
+	out    io.Writer
+	// pii columns are masked when shown, but kept, so that edits keep them
+	pii storage.PIIColumns
+
+	rows     map[string]storage.Row
+	children map[string][]storage.Row
+	// path holds the rows from the top down to the current one
+	path []storage.Row
+}
+
+func (b *browser) reload(ctx context.Context) error {
+	b.rows = map[string]storage.Row{}
+	b.children = map[string][]storage.Row{}
+	err := b.storer.ScanRows(ctx, func(row storage.Row) error {
+		b.rows[row.ID()] = row
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, row := range b.rows {
+		parent := row.ParentID()
+		if _, ok := b.rows[parent]; !ok {
+			// orphans show at the top, with the roots
+			parent = ""
+		}
+		b.children[parent] = append(b.children[parent], row)
+	}
+	for _, rows := range b.children {
+		sortRows(rows)
+	}
+
+	// keep as much of the path as still exists, with fresh copies
+	for i, row := range b.path {
+		fresh, ok := b.rows[row.ID()]
+		if !ok {
+			b.path = b.path[:i]
+			break
+		}
+		b.path[i] = fresh
+	}
+	return nil
+}
+
+func (b *browser) current() storage.Row {
+	if len(b.path) == 0 {
+		return nil
+	}
+	return b.path[len(b.path)-1]
+}
+
+func (b *browser) here() []storage.Row {
+	if row := b.current(); row != nil {
+		return b.children[row.ID()]
+	}
+	return b.children[""]
+}
+
+func (b *browser) prompt() string {
+	labels := make([]string, len(b.path))
+	for i, row := range b.path {
+		labels[i] = row.Label()
+	}
+	return "/" + strings.Join(labels, "/") + "> "
+}
+
+func (b *browser) run(ctx context.Context, in io.Reader) error {
+	scanner := bufio.NewScanner(in)
+	for {
+		fmt.Fprint(b.out, b.prompt())
+		if !scanner.Scan() {
+			fmt.Fprintln(b.out)
+			return scanner.Err()
+		}
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		command, args := fields[0], fields[1:]
+		if command == "quit" || command == "exit" {
+			return nil
+		}
+		if err := b.do(ctx, command, args); err != nil {
+			fmt.Fprintf(b.out, "%s: %s\n", command, err)
+		}
+	}
+}
+
+func (b *browser) do(ctx context.Context, command string, args []string) error {
+	current := b.current()
+	switch command {
+	case "help":
+		fmt.Fprint(b.out, browseHelp)
+		return nil
+	case "ls":
+		b.list()
+		return nil
+	case "cd":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: cd <n|label|id>")
+		}
+		return b.cd(args[0])
+	case "show":
+		if current == nil {
+			return fmt.Errorf("cd into a row first")
+		}
+		b.show(current)
+		return nil
+	case "reload":
+		return b.reload(ctx)
+	}
+
+	// the rest edit rows
+	var err error
+	switch command {
+	case "set", "unset":
+		err = b.setColumn(ctx, command, args)
+	case "rename":
+		if current == nil || len(args) == 0 {
+			return fmt.Errorf("usage: rename <label>, in a row")
+		}
+		_, err = b.storer.UpdateRow(ctx, current.Type(), current.ID(), strings.Join(args, " "))
+	case "new":
+		if len(args) < 2 {
+			return fmt.Errorf("usage: new <type> <label>")
+		}
+		label := strings.Join(args[1:], " ")
+		if current == nil {
+			_, err = b.storer.CreateRow(ctx, args[0], label)
+		} else {
+			_, err = b.storer.CreateChild(ctx, args[0], label, current.Type(), current.ID(), nil)
+		}
+	case "rm":
+		if current == nil {
+			return fmt.Errorf("cd into a row first")
+		}
+		if n := len(b.children[current.ID()]); n > 0 {
+			return fmt.Errorf("%s has %d children; delete them first, or use schemactl delete -recursive", current.Label(), n)
+		}
+		if err = b.storer.DeleteRow(ctx, current.Type(), "", current.ID()); err == nil {
+			b.path = b.path[:len(b.path)-1]
+		}
+	default:
+		return fmt.Errorf("unknown command; type help for commands")
+	}
+	if err != nil {
+		return err
+	}
+	return b.reload(ctx)
+}
+
+func (b *browser) list() {
+	rows := b.here()
+	if len(rows) == 0 {
+		fmt.Fprintln(b.out, "(no rows)")
+		return
+	}
+	tw := tabwriter.NewWriter(b.out, 0, 4, 2, ' ', 0)
+	for i, row := range rows {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%d children\n", i+1, row.Type(), row.Label(), row.ID(), len(b.children[row.ID()]))
+	}
+	tw.Flush()
+}
+
+func (b *browser) cd(target string) error {
+	switch target {
+	case "/":
+		b.path = nil
+		return nil
+	case "..":
+		if len(b.path) > 0 {
+			b.path = b.path[:len(b.path)-1]
+		}
+		return nil
+	}
+	rows := b.here()
+	if n, err := strconv.Atoi(target); err == nil && n >= 1 && n <= len(rows) {
+		b.path = append(b.path, rows[n-1])
+		return nil
+	}
+	for _, row := range rows {
+		if row.Label() == target || row.ID() == target {
+			b.path = append(b.path, row)
+			return nil
+		}
+	}
+	return fmt.Errorf("no row here is numbered, labeled, or identified %q", target)
+}
+
+func (b *browser) show(row storage.Row) {
+	tw := tabwriter.NewWriter(b.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "type\t%s\n", row.Type())
+	fmt.Fprintf(tw, "id\t%s\n", row.ID())
+	fmt.Fprintf(tw, "label\t%s\n", row.Label())
+	fmt.Fprintf(tw, "parent\t%s\n", orDash(row.ParentID()))
+	columns := b.pii.Mask(row).Columns()
+	names := make([]string, 0, len(columns))
+	for name := range columns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value := columns[name]
+		if set, ok := value.([]string); ok {
+			value = "[" + strings.Join(set, ", ") + "]"
+		}
+		fmt.Fprintf(tw, "%s\t%v\n", name, value)
+	}
+	tw.Flush()
+}
+
+// setColumn sets or removes one of the current row's columns.
+func (b *browser) setColumn(ctx context.Context, command string, args []string) error {
+	current := b.current()
+	if current == nil {
+		return fmt.Errorf("cd into a row first")
+	}
+	if command == "unset" && len(args) == 1 {
+		return storage.DeleteColumn(ctx, b.storer, current.Type(), current.ID(), args[0])
+	}
+	columns := map[string]interface{}{}
+	for name, value := range current.Columns() {
+		columns[name] = value
+	}
+	switch {
+	case command == "set" && len(args) >= 2:
+		value := strings.Join(args[1:], " ")
+		if inner, ok := strings.CutPrefix(value, "["); ok && strings.HasSuffix(inner, "]") {
+			set := []string{}
+			for _, elem := range strings.Split(strings.TrimSuffix(inner, "]"), ",") {
+				if elem = strings.TrimSpace(elem); elem != "" {
+					set = append(set, elem)
+				}
+			}
+			columns[args[0]] = set
+		} else {
+			columns[args[0]] = value
+		}
+	default:
+		return fmt.Errorf("usage: set <column> <value>, or unset <column>")
+	}
+	return b.storer.UpdateColumns(ctx, current.Type(), current.ID(), columns)
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/dataset"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+func runDelete(args []string) error {
+	flags := flag.NewFlagSet("delete", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: schemactl delete [flags] <id>")
+		flags.PrintDefaults()
+	}
+	backend := backendFlag(flags)
+	recursive := flags.Bool("recursive", false, "also delete every descendant of the row")
+	confirm := flags.Int("confirm", 0, "the number of rows that will be deleted, to confirm without a prompt")
+	backupFirst := flags.Bool("backup-first", false, "export the rows to an NDJSON dataset before deleting them")
+	backupOut := flags.String("backup-out", "", "file for -backup-first (default delete-<id>-<timestamp>.ndjson)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return fmt.Errorf("exactly one row ID is required")
+	}
+	id := flags.Arg(0)
+
+	ctx, stop := commandContext()
+	defer stop()
+	storer, err := openBackend(ctx, *backend)
+	if err != nil {
+		return err
+	}
+	root, err := storage.LoadTree(ctx, storer, id)
+	if err != nil {
+		return err
+	}
+	size := root.Size()
+	if size > 1 && !*recursive {
+		return fmt.Errorf("%w: %s %s has %d descendants; pass -recursive to delete them too", storage.ErrCannotDeleteRow, root.Type(), id, size-1)
+	}
+
+	printTree(os.Stdout, root, "", "")
+	if *confirm == 0 {
+		*confirm, err = promptCount(size)
+		if err != nil {
+			return err
+		}
+	}
+	if *confirm != size {
+		return fmt.Errorf("confirmed %d rows, but %d would be deleted; nothing was deleted", *confirm, size)
+	}
+
+	if *backupFirst {
+		path := *backupOut
+		if path == "" {
+			path = fmt.Sprintf("delete-%s-%s.ndjson", id, time.Now().UTC().Format("20060102T150405Z"))
+		}
+		if err := backupTree(root, path); err != nil {
+			return fmt.Errorf("backing up: %w; nothing was deleted", err)
+		}
+		log.Printf("backed up %d rows to %s", size, path)
+	}
+
+	deleted, err := storage.DeleteTree(ctx, storer, root)
+	if err != nil {
+		return fmt.Errorf("deleted %d of %d rows: %w", deleted, size, err)
+	}
+	log.Printf("deleted %d rows", deleted)
+	return nil
+}
+
+// promptCount asks for the number of rows that will be deleted, so that a
+// deletion larger than expected is caught.
+func promptCount(size int) (int, error) {
+	fmt.Printf("this deletes %d rows. Type %d to confirm: ", size, size)
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return 0, fmt.Errorf("no confirmation: %w", err)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(line))
+	if err != nil {
+		return 0, fmt.Errorf("confirmation %q is not a number; nothing was deleted", strings.TrimSpace(line))
+	}
+	return n, nil
+}
+
+func backupTree(root *storage.Node, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := dataset.ExportTree(root, f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/dataset"
+)
+
+var errDiffers = errors.New("the backends differ")
+
+func runDiff(args []string) error {
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	from := flags.String("from", "", "backend to compare against, such as the source of a migration")
+	to := flags.String("to", "", "backend to compare")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *from == "" || *to == "" {
+		return fmt.Errorf("-from and -to are required")
+	}
+
+	ctx, stop := commandContext()
+	defer stop()
+	source, err := openBackend(ctx, *from)
+	if err != nil {
+		return fmt.Errorf("opening -from: %w", err)
+	}
+	destination, err := openBackend(ctx, *to)
+	if err != nil {
+		return fmt.Errorf("opening -to: %w", err)
+	}
+
+	diff, err := dataset.Compare(ctx, source, destination)
+	if err != nil {
+		return err
+	}
+	for _, row := range diff.Missing {
+		fmt.Printf("- %s\n", row)
+	}
+	for _, row := range diff.Extra {
+		fmt.Printf("+ %s\n", row)
+	}
+	for _, change := range diff.Changed {
+		fmt.Printf("~ %s\n", change)
+	}
+	if !diff.Empty() || len(diff.Extra) > 0 {
+		return fmt.Errorf("%w: %s", errDiffers, diff)
+	}
+	log.Print("the backends have the same rows")
+	return nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/dataset"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/mask"
+)
+
+func runExport(args []string) error {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	backend := backendFlag(flags)
+	out := flags.String("out", "-", "file to write the NDJSON dataset to, or - for standard output")
+	compress := flags.String("compress", "", "compress the dataset: none, gzip, or zstd (default from the -out extension, .gz or .zst)")
+	rootID := flags.String("root", "", "export only the row with this ID and its descendants")
+	rootType := flags.String("root-type", "", "the type of the -root row")
+	rowType := flags.String("type", "", "export only the rows of this type")
+	pii := piiFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if (*rootID == "") != (*rootType == "") {
+		return fmt.Errorf("-root and -root-type go together")
+	}
+	if *rootID != "" && *rowType != "" {
+		return fmt.Errorf("-type can't be used with -root")
+	}
+	compression := dataset.CompressionForPath(*out)
+	if *compress != "" {
+		var err error
+		if compression, err = dataset.ParseCompression(*compress); err != nil {
+			return err
+		}
+	}
+
+	ctx, stop := commandContext()
+	defer stop()
+	storer, err := openBackend(ctx, *backend)
+	if err != nil {
+		return err
+	}
+	storer, err = pii.mask(storer)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "-" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	compressor, err := dataset.NewCompressor(w, compression)
+	if err != nil {
+		return err
+	}
+
+	var n int
+	switch {
+	case *rootID != "":
+		n, err = dataset.ExportSubtree(ctx, storer, *rootType, *rootID, compressor)
+	case *rowType != "":
+		n, err = dataset.ExportType(ctx, storer, *rowType, compressor)
+	default:
+		n, err = dataset.Export(ctx, storer, compressor)
+	}
+	if err != nil {
+		return err
+	}
+	if err := compressor.Close(); err != nil {
+		return err
+	}
+	log.Printf("exported %d rows", n)
+	if _, masked := storer.(*mask.Storer); masked {
+		log.Print("PII columns are masked, so the export can't restore them: pass -reveal-pii for a full backup")
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+func runGC(args []string) error {
+	flags := flag.NewFlagSet("gc", flag.ExitOnError)
+	backend := backendFlag(flags)
+	orphans := flags.Bool("orphans", false, "find rows whose parent no longer exists, and delete or re-parent them")
+	dryRun := flags.Bool("dry-run", false, "only list what would be collected")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if !*orphans {
+		return fmt.Errorf("nothing to collect: pass -orphans")
+	}
+
+	ctx, stop := commandContext()
+	defer stop()
+	storer, err := openBackend(ctx, *backend)
+	if err != nil {
+		return err
+	}
+	found, err := storage.FindOrphans(ctx, storer)
+	if err != nil {
+		return err
+	}
+	if len(found) == 0 {
+		log.Print("no orphans found")
+		return nil
+	}
+	log.Printf("found %d orphans", len(found))
+
+	if *dryRun {
+		for _, orphan := range found {
+			fmt.Println(describeOrphan(orphan))
+		}
+		return nil
+	}
+	return collectOrphans(ctx, storer, found, os.Stdin, os.Stdout)
+}
+
+func describeOrphan(orphan storage.Orphan) string {
+	s := fmt.Sprintf("%s/%s %q: parent %s is missing", orphan.Type(), orphan.ID(), orphan.Label(), orphan.ParentID())
+	if orphan.Children > 0 {
+		s += fmt.Sprintf(", and it has %d children", orphan.Children)
+	}
+	return s
+}
+
+// collectOrphans asks what to do with each orphan: delete it, move it to a new
+// parent, skip it, or stop.
+func collectOrphans(ctx context.Context, storer storage.RowStorer, orphans []storage.Orphan, in io.Reader, out io.Writer) error {
+	scanner := bufio.NewScanner(in)
+	ask := func(prompt string) (string, bool) {
+		fmt.Fprint(out, prompt)
+		if !scanner.Scan() {
+			return "", false
+		}
+		return strings.TrimSpace(scanner.Text()), true
+	}
+
+	deleted, moved := 0, 0
+	defer func() { log.Printf("deleted %d and re-parented %d of %d orphans", deleted, moved, len(orphans)) }()
+	for _, orphan := range orphans {
+		fmt.Fprintln(out, describeOrphan(orphan))
+		for {
+			answer, ok := ask("[d]elete, [r]e-parent, [s]kip, or [q]uit? ")
+			if !ok || answer == "q" {
+				return scanner.Err()
+			}
+			switch answer {
+			case "d":
+				if orphan.Children > 0 {
+					fmt.Fprintln(out, "deleting it would orphan its children; re-parent it instead")
+					continue
+				}
+				if err := storer.DeleteRow(ctx, orphan.Type(), "", orphan.ID()); err != nil {
+					fmt.Fprintf(out, "deleting failed: %s\n", err)
+					continue
+				}
+				deleted++
+			case "r":
+				parent, ok := ask("new parent, as type/id: ")
+				if !ok {
+					return scanner.Err()
+				}
+				parentType, parentID, found := strings.Cut(parent, "/")
+				if !found || parentType == "" || parentID == "" {
+					fmt.Fprintln(out, "the parent must be given as type/id")
+					continue
+				}
+				if _, err := storer.UpdateChild(ctx, orphan.Type(), orphan.ID(), orphan.Label(), parentType, parentID); err != nil {
+					fmt.Fprintf(out, "re-parenting failed: %s\n", err)
+					continue
+				}
+				moved++
+			case "s":
+			default:
+				continue
+			}
+			break
+		}
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+func runGet(args []string) error {
+	flags := flag.NewFlagSet("get", flag.ExitOnError)
+	backend := backendFlag(flags)
+	rowType := flags.String("type", "", "type of the row")
+	id := flags.String("id", "", "ID of the row, or IDs of rows separated by commas")
+	label := flags.String("label", "", "label of the row, if no ID is given")
+	parent := flags.String("parent", "", "ID of the row's parent, to find a child row by label")
+	asJSON := flags.Bool("json", false, "print the row as JSON")
+	pii := piiFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *rowType == "" {
+		return fmt.Errorf("-type is required")
+	}
+	if (*id == "") == (*label == "") {
+		return fmt.Errorf("exactly one of -id and -label is required")
+	}
+
+	ctx, stop := commandContext()
+	defer stop()
+	storer, err := openBackend(ctx, *backend)
+	if err != nil {
+		return err
+	}
+	storer, err = pii.mask(storer)
+	if err != nil {
+		return err
+	}
+
+	if ids := strings.Split(*id, ","); len(ids) > 1 {
+		return getByIDs(ctx, storer, *rowType, ids, *asJSON)
+	}
+	var row storage.Row
+	if *id != "" {
+		row, err = storer.GetRowByID(ctx, *rowType, *id)
+	} else {
+		row, err = getByLabel(ctx, storer, *rowType, *label, *parent)
+	}
+	if err != nil {
+		return err
+	}
+	return printRows(os.Stdout, []storage.Row{row}, *asJSON)
+}
+
+// getByIDs prints the rows with the given type and IDs, read together, and
+// fails after printing them if any wasn't found.
+func getByIDs(ctx context.Context, storer storage.RowStorer, rowType string, ids []string, asJSON bool) error {
+	rows, err := storage.GetRowsByIDs(ctx, storer, rowType, ids)
+	if err != nil {
+		return err
+	}
+	if err := printRows(os.Stdout, rows, asJSON); err != nil {
+		return err
+	}
+	found := map[string]bool{}
+	for _, row := range rows {
+		found[row.ID()] = true
+	}
+	missing := []string{}
+	for _, id := range ids {
+		if !found[id] {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: no %s with ID %s", storage.ErrNotFoundRow, rowType, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// getByLabel returns the row with the given type and label: a child of
+// parentID, or a root row if parentID is empty.
+func getByLabel(ctx context.Context, storer storage.RowStorer, rowType, label, parentID string) (storage.Row, error) {
+	if parentID == "" {
+		return storer.GetRow(ctx, rowType, label)
+	}
+	row, err := storer.GetChild(ctx, label, parentID)
+	if err != nil {
+		return nil, err
+	}
+	if row.Type() != rowType {
+		return nil, fmt.Errorf("%w: the child of %q labeled %q has type %q, not %q", storage.ErrNotFoundRow, parentID, label, row.Type(), rowType)
+	}
+	return row, nil
+}
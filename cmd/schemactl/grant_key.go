@@ -0,0 +1,68 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/attribution"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/dynamodb"
+)
+
+func runGrantKey(args []string) error {
+	flags := flag.NewFlagSet("grant-key", flag.ExitOnError)
+	backend := backendFlag(flags)
+	grantee := flags.String("grantee", "", "ARN of the IAM role or user to grant the use of the table's KMS key; defaults to the caller")
+	check := flags.Bool("check", false, "only list the permissions the grantee's grants are missing")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	ctx, stop := commandContext()
+	defer stop()
+	storer, err := openBackend(ctx, *backend)
+	if err != nil {
+		return err
+	}
+	client, ok := storer.(*dynamodb.Client)
+	if !ok {
+		return fmt.Errorf("grant-key only supports DynamoDB backends")
+	}
+
+	principal := *grantee
+	if principal == "" {
+		profile, region := backendAWSConfig(*backend)
+		caller, err := attribution.CallerIdentity(ctx, profile, region)
+		if err != nil {
+			return err
+		}
+		principal = caller
+	}
+	principal = dynamodb.GranteePrincipal(principal)
+
+	if *check {
+		missing, err := client.MissingKeyGrants(ctx, principal)
+		if err != nil {
+			return err
+		}
+		if len(missing) == 0 {
+			log.Printf("%s is granted every permission it needs on the key", principal)
+			return nil
+		}
+		// the key policy may allow what grants don't, so this isn't an error
+		log.Printf("%s is not granted: %s", principal, strings.Join(dynamodb.KeyPermissions(missing), ", "))
+		return nil
+	}
+
+	granted, err := client.GrantKeyAccess(ctx, principal)
+	if err != nil {
+		return err
+	}
+	if len(granted) == 0 {
+		log.Printf("%s is already granted every permission it needs on the key", principal)
+		return nil
+	}
+	log.Printf("granted %s: %s", principal, strings.Join(dynamodb.KeyPermissions(granted), ", "))
+	return nil
+}
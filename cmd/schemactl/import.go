@@ -0,0 +1,78 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"log"
+	"os"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/dataset"
+)
+
+func runImport(args []string) error {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	backend := backendFlag(flags)
+	in := flags.String("in", "-", "NDJSON dataset to read, or - for standard input, compressed with gzip or zstd or not")
+	onConflict := flags.String("on-conflict", string(dataset.ConflictFail), "what to do with rows that already exist: fail, skip, or overwrite")
+	dryRun := flags.Bool("dry-run", false, "report what would change without writing")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	strategy, err := dataset.ParseConflictStrategy(*onConflict)
+	if err != nil {
+		return err
+	}
+
+	var r io.Reader = os.Stdin
+	if *in != "-" {
+		f, err := os.Open(*in)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	decompressor, err := dataset.NewDecompressor(r)
+	if err != nil {
+		return err
+	}
+	defer decompressor.Close()
+	records, err := dataset.NewReader(decompressor).ReadAll()
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := commandContext()
+	defer stop()
+	storer, err := openBackend(ctx, *backend)
+	if err != nil {
+		return err
+	}
+
+	opts := dataset.ImportOptions{OnConflict: strategy, DryRun: *dryRun}
+	if !*dryRun {
+		opts.Progress = importProgress(len(records))
+	}
+	result, err := dataset.Import(ctx, storer, records, opts)
+	if err != nil {
+		return err
+	}
+	if *dryRun {
+		log.Printf("dry run: would have %s", result)
+		return nil
+	}
+	log.Printf("imported %d rows: %s", len(records), result)
+	return nil
+}
+
+// importProgress logs how far an import of total records has got, every
+// thousand records or so.
+func importProgress(total int) func(int) {
+	logged := 0
+	return func(imported int) {
+		if imported/1000 > logged/1000 {
+			log.Printf("imported %d of %d rows", imported, total)
+			logged = imported
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"strings"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+func runList(args []string) error {
+	flags := flag.NewFlagSet("list", flag.ExitOnError)
+	backend := backendFlag(flags)
+	rowType := flags.String("type", "", "only list rows of this type (lists every type if empty)")
+	label := flags.String("label", "", "only list rows whose label contains this string")
+	parent := flags.String("parent", "", "only list children of the row with this ID")
+	asJSON := flags.Bool("json", false, "print rows as JSON, one per line")
+	pii := piiFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	ctx, stop := commandContext()
+	defer stop()
+	storer, err := openBackend(ctx, *backend)
+	if err != nil {
+		return err
+	}
+	storer, err = pii.mask(storer)
+	if err != nil {
+		return err
+	}
+
+	var rows []storage.Row
+	if *rowType != "" {
+		rows, err = storage.IterRows(ctx, storer, *rowType, *label, *parent).All()
+	} else {
+		err = storer.ScanRows(ctx, func(row storage.Row) error {
+			if strings.Contains(row.Label(), *label) && (*parent == "" || row.ParentID() == *parent) {
+				rows = append(rows, row)
+			}
+			return nil
+		})
+	}
+	if err != nil {
+		return err
+	}
+	return printRows(os.Stdout, rows, *asJSON)
+}
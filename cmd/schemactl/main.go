@@ -0,0 +1,127 @@
+// Command schemactl inspects and administers the rows stored by the provider,
+// without Terraform.
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+)
+
+const usage = `usage: schemactl <command> [flags]
+
+commands:
+  get             show one row, by type and ID or label
+  list            list rows, filtered by type, label, and parent
+  apply           apply a JSON changeset of creates, updates, and deletes
+  audit-integrity check the signature of every row, and report mismatches
+  bench           compare backends on a mix of operations like a provider's
+  browse          walk the tree interactively, viewing and editing rows
+  diff            compare the rows of two backends
+  delete          delete a row, or with -recursive its whole subtree
+  export          write every row to an NDJSON dataset
+  import          store the rows of an NDJSON dataset
+  migrate         copy every row from one backend to another, and verify the copy
+  gc              find rows whose parent is missing, and delete or re-parent them
+  grant-key       grant a role the use of a DynamoDB table's KMS key
+  move            move a row, and the rows under it, to a new parent
+  repair          move the children of a deleted or replaced parent to a new one
+  relabel         rename rows of a type with a regular expression
+  stats           count rows by type and children by parent, and measure depth and size
+  tree            draw the subtree under a row, as text or Graphviz
+  snapshot        create, list, and restore named snapshots, locally or in S3
+  rotate-key      re-encrypt a DynamoDB table with another KMS key, and check access
+  seed            create a hierarchy of rows from fixture files, idempotently
+  sign            sign every unsigned row, for integrity checks
+  tail            print changes to rows as they happen
+  tf-import       write Terraform import blocks for existing rows
+  verify-schema   check a DynamoDB table's keys, indexes, encryption, and backups
+
+`
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("schemactl: ")
+
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage+backendUsage)
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "get":
+		err = runGet(os.Args[2:])
+	case "list":
+		err = runList(os.Args[2:])
+	case "apply":
+		err = runApply(os.Args[2:])
+	case "bench":
+		err = runBench(os.Args[2:])
+	case "browse":
+		err = runBrowse(os.Args[2:])
+	case "diff":
+		err = runDiff(os.Args[2:])
+	case "delete":
+		err = runDelete(os.Args[2:])
+	case "export":
+		err = runExport(os.Args[2:])
+	case "import":
+		err = runImport(os.Args[2:])
+	case "migrate":
+		err = runMigrate(os.Args[2:])
+	case "gc":
+		err = runGC(os.Args[2:])
+	case "move":
+		err = runMove(os.Args[2:])
+	case "repair":
+		err = runRepair(os.Args[2:])
+	case "relabel":
+		err = runRelabel(os.Args[2:])
+	case "stats":
+		err = runStats(os.Args[2:])
+	case "tree":
+		err = runTree(os.Args[2:])
+	case "snapshot":
+		err = runSnapshot(os.Args[2:])
+	case "rotate-key":
+		err = runRotateKey(os.Args[2:])
+	case "seed":
+		err = runSeed(os.Args[2:])
+	case "sign":
+		err = runSign(os.Args[2:])
+	case "grant-key":
+		err = runGrantKey(os.Args[2:])
+	case "audit-integrity":
+		err = runAuditIntegrity(os.Args[2:])
+	case "tail":
+		err = runTail(os.Args[2:])
+	case "tf-import":
+		err = runTFImport(os.Args[2:])
+	case "verify-schema":
+		err = runVerifySchema(os.Args[2:])
+	case "-h", "-help", "--help", "help":
+		fmt.Fprint(os.Stdout, usage+backendUsage)
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s%s", os.Args[1], usage, backendUsage)
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+}
+
+// commandContext returns a context cancelled by the first interrupt, so that
+// a command stops calling its backend and returns what it has done. A second
+// interrupt exits at once, as it would without it.
+func commandContext() (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	return ctx, stop
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/dataset"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+// errNotEmpty stops the scan for existing rows at the first one.
+var errNotEmpty = errors.New("backend is not empty")
+
+func runMigrate(args []string) error {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	from := flags.String("from", "", "backend to copy rows from")
+	to := flags.String("to", "", "backend to copy rows to")
+	allowNonEmpty := flags.Bool("allow-nonempty", false, "copy into a backend that already has rows, replacing any with the same IDs")
+	verify := flags.Bool("verify", true, "compare the backends after copying")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *from == "" || *to == "" {
+		return fmt.Errorf("-from and -to are required")
+	}
+
+	ctx, stop := commandContext()
+	defer stop()
+	source, err := openBackend(ctx, *from)
+	if err != nil {
+		return fmt.Errorf("opening -from: %w", err)
+	}
+	destination, err := openBackend(ctx, *to)
+	if err != nil {
+		return fmt.Errorf("opening -to: %w", err)
+	}
+
+	if !*allowNonEmpty {
+		err := destination.ScanRows(ctx, func(storage.Row) error { return errNotEmpty })
+		if errors.Is(err, errNotEmpty) {
+			return fmt.Errorf("the -to backend already has rows; pass -allow-nonempty to copy into it anyway")
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	copied, err := dataset.Copy(ctx, source, destination, func(copied int) {
+		if copied%1000 == 0 {
+			log.Printf("copied %d rows", copied)
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("after copying %d rows: %w", copied, err)
+	}
+	log.Printf("copied %d rows", copied)
+
+	if !*verify {
+		return nil
+	}
+	diff, err := dataset.Compare(ctx, source, destination)
+	if err != nil {
+		return fmt.Errorf("verifying: %w", err)
+	}
+	if !diff.Empty() {
+		for _, row := range diff.Missing {
+			log.Printf("missing: %s", row)
+		}
+		for _, row := range diff.Changed {
+			log.Printf("changed: %s", row)
+		}
+		return fmt.Errorf("verification failed: %s; were rows written during the migration?", diff)
+	}
+	summary := "verified every row"
+	if len(diff.Extra) > 0 {
+		summary += fmt.Sprintf(", and the -to backend has %d more", len(diff.Extra))
+	}
+	log.Print(summary)
+	return nil
+}
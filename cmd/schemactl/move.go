@@ -0,0 +1,47 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+func runMove(args []string) error {
+	flags := flag.NewFlagSet("move", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: schemactl move [flags] <type>/<id> <parent-type>/<new-parent-id>")
+		flags.PrintDefaults()
+	}
+	backend := backendFlag(flags)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return fmt.Errorf("a row and a new parent are required")
+	}
+	rowType, id, ok := strings.Cut(flags.Arg(0), "/")
+	if !ok {
+		return fmt.Errorf("the row must be given as type/id")
+	}
+	parentType, parentID, ok := strings.Cut(flags.Arg(1), "/")
+	if !ok {
+		return fmt.Errorf("the new parent must be given as type/id")
+	}
+
+	ctx, stop := commandContext()
+	defer stop()
+	storer, err := openBackend(ctx, *backend)
+	if err != nil {
+		return err
+	}
+	moved, err := storage.MoveSubtree(ctx, storer, rowType, id, parentType, parentID)
+	if err != nil {
+		return err
+	}
+	log.Printf("moved %s %s %q, and the rows under it, to parent %s", moved.Type(), moved.ID(), moved.Label(), moved.ParentID())
+	return nil
+}
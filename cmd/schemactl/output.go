@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/dataset"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+func printRows(w io.Writer, rows []storage.Row, asJSON bool) error {
+	sortRows(rows)
+	if asJSON {
+		// in the dataset format, so output can be imported
+		writer := dataset.NewWriter(w)
+		for _, row := range rows {
+			if err := writer.Write(dataset.FromRow(row)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "TYPE\tID\tLABEL\tPARENT\tCOLUMNS")
+	for _, row := range rows {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", row.Type(), row.ID(), row.Label(), orDash(row.ParentID()), formatColumns(row.Columns()))
+	}
+	return tw.Flush()
+}
+
+// sortRows orders rows by type, then label, then ID.
+func sortRows(rows []storage.Row) {
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Type() != rows[j].Type() {
+			return rows[i].Type() < rows[j].Type()
+		}
+		if rows[i].Label() != rows[j].Label() {
+			return rows[i].Label() < rows[j].Label()
+		}
+		return rows[i].ID() < rows[j].ID()
+	})
+}
+
+// formatColumns prints columns as name=value pairs, sorted by name.
+func formatColumns(columns map[string]interface{}) string {
+	if len(columns) == 0 {
+		return "-"
+	}
+	names := make([]string, 0, len(columns))
+	for name := range columns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		value := columns[name]
+		if set, ok := value.([]string); ok {
+			value = "[" + strings.Join(set, ",") + "]"
+		}
+		pairs[i] = fmt.Sprintf("%s=%v", name, value)
+	}
+	return strings.Join(pairs, " ")
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/mask"
+	"github.com/spilliams/tree-terraform-provider/pkg/tfgen"
+)
+
+// piiOptions decide whether a command that shows rows masks their PII
+// columns.
+type piiOptions struct {
+	definitions *string
+	reveal      *bool
+}
+
+// piiFlags adds the -definitions and -reveal-pii flags to a command's flags.
+func piiFlags(flags *flag.FlagSet) *piiOptions {
+	return &piiOptions{
+		definitions: flags.String("definitions", os.Getenv("SCHEMACTL_DEFINITIONS"), "directory of row type definitions, whose PII columns are masked"),
+		reveal:      flags.Bool("reveal-pii", false, "show the values of PII columns, rather than masking them"),
+	}
+}
+
+// columns returns the PII columns to mask: none if they are revealed, or if
+// there are no definitions to name them.
+func (opts *piiOptions) columns() (storage.PIIColumns, error) {
+	if *opts.reveal || *opts.definitions == "" {
+		return storage.PIIColumns{}, nil
+	}
+	defs, err := tfgen.LoadDefinitions(*opts.definitions)
+	if err != nil {
+		return nil, err
+	}
+	return tfgen.PIIColumns(defs), nil
+}
+
+// mask wraps storer to mask the PII columns of the rows it returns.
+func (opts *piiOptions) mask(storer storage.RowStorer) (storage.RowStorer, error) {
+	pii, err := opts.columns()
+	if err != nil {
+		return nil, err
+	}
+	if len(pii) == 0 {
+		return storer, nil
+	}
+	return mask.NewStorer(storer, pii), nil
+}
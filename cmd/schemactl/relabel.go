@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+// relabeling is one planned rename.
+type relabeling struct {
+	row      storage.Row
+	newLabel string
+}
+
+func runRelabel(args []string) error {
+	flags := flag.NewFlagSet("relabel", flag.ExitOnError)
+	backend := backendFlag(flags)
+	rowType := flags.String("type", "", "type of the rows to relabel")
+	match := flags.String("match", "", "regular expression a label must match, in full, to be renamed")
+	replace := flags.String("replace", "", "the new label, where $1 and ${name} expand to the match's groups")
+	parent := flags.String("parent", "", "only relabel children of this parent ID")
+	dryRun := flags.Bool("dry-run", false, "only print the renames")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *rowType == "" || *match == "" {
+		return fmt.Errorf("-type and -match are required")
+	}
+	pattern, err := regexp.Compile("^(?:" + *match + ")$")
+	if err != nil {
+		return fmt.Errorf("-match: %w", err)
+	}
+
+	ctx, stop := commandContext()
+	defer stop()
+	storer, err := openBackend(ctx, *backend)
+	if err != nil {
+		return err
+	}
+	plan, err := planRelabel(ctx, storer, *rowType, *parent, pattern, *replace)
+	if err != nil {
+		return err
+	}
+	if len(plan) == 0 {
+		log.Printf("no %s labels match", *rowType)
+		return nil
+	}
+	if *dryRun {
+		log.Printf("would relabel %d rows", len(plan))
+		return nil
+	}
+	return applyRelabel(ctx, storer, plan)
+}
+
+// planRelabel prints every rename, and fails if any new label would collide
+// with another row's label after all of them are made.
+func planRelabel(ctx context.Context, storer storage.RowStorer, rowType, parentID string, pattern *regexp.Regexp, replace string) ([]relabeling, error) {
+	// labels must be unique among root rows of a type, and among the
+	// children of a parent, whatever their types
+	scope := func(row storage.Row) string {
+		if row.ParentID() == "" {
+			return "type " + row.Type()
+		}
+		return "parent " + row.ParentID()
+	}
+	labels := map[string]map[string][]string{}
+	plan := []relabeling{}
+	err := storer.ScanRows(ctx, func(row storage.Row) error {
+		label := row.Label()
+		if row.Type() == rowType && (parentID == "" || row.ParentID() == parentID) && pattern.MatchString(label) {
+			label = pattern.ReplaceAllString(label, replace)
+			if label != row.Label() {
+				plan = append(plan, relabeling{row: row, newLabel: label})
+			}
+		}
+		if labels[scope(row)] == nil {
+			labels[scope(row)] = map[string][]string{}
+		}
+		labels[scope(row)][label] = append(labels[scope(row)][label], row.Type()+"/"+row.ID())
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(plan, func(i, j int) bool { return plan[i].row.ID() < plan[j].row.ID() })
+
+	collisions := 0
+	for _, r := range plan {
+		fmt.Printf("~ %s/%s: %q -> %q\n", r.row.Type(), r.row.ID(), r.row.Label(), r.newLabel)
+		if r.newLabel == "" {
+			fmt.Println("  ! the new label is empty")
+			collisions++
+			continue
+		}
+		for _, other := range labels[scope(r.row)][r.newLabel] {
+			if other != r.row.Type()+"/"+r.row.ID() {
+				fmt.Printf("  ! the new label collides with %s\n", other)
+				collisions++
+			}
+		}
+	}
+	if collisions > 0 {
+		return nil, fmt.Errorf("%d renames collide; nothing was relabeled", collisions)
+	}
+	return plan, nil
+}
+
+// applyRelabel makes the renames. One may need a label another is giving up,
+// so renames that collide are retried after the rest.
+func applyRelabel(ctx context.Context, storer storage.RowStorer, plan []relabeling) error {
+	done := 0
+	for len(plan) > 0 {
+		pending := []relabeling{}
+		for _, r := range plan {
+			_, err := storer.UpdateRow(ctx, r.row.Type(), r.row.ID(), r.newLabel)
+			if errors.Is(err, storage.ErrCollisionParentLabel) || errors.Is(err, storage.ErrCollisionTypeLabel) {
+				pending = append(pending, r)
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("relabeled %d rows: %s %s: %w", done, r.row.Type(), r.row.ID(), err)
+			}
+			done++
+		}
+		if len(pending) == len(plan) {
+			return fmt.Errorf("relabeled %d rows: the remaining %d swap labels with each other; rename one of them to a temporary label first", done, len(pending))
+		}
+		plan = pending
+	}
+	log.Printf("relabeled %d rows", done)
+	return nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+var errConflicts = errors.New("some children can't be moved")
+
+func runRepair(args []string) error {
+	flags := flag.NewFlagSet("repair", flag.ExitOnError)
+	backend := backendFlag(flags)
+	oldParent := flags.String("old-parent", "", "ID of the deleted or replaced parent whose children to move")
+	parentType := flags.String("parent-type", "", "type of the new parent")
+	parentLabel := flags.String("parent-label", "", "label of the new parent")
+	grandparent := flags.String("parent-parent", "", "ID of the new parent's own parent, if it isn't a root row")
+	dryRun := flags.Bool("dry-run", false, "only print the changes")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *oldParent == "" || *parentType == "" || *parentLabel == "" {
+		return fmt.Errorf("-old-parent, -parent-type, and -parent-label are required")
+	}
+
+	ctx, stop := commandContext()
+	defer stop()
+	storer, err := openBackend(ctx, *backend)
+	if err != nil {
+		return err
+	}
+	parent, err := getByLabel(ctx, storer, *parentType, *parentLabel, *grandparent)
+	if err != nil {
+		return fmt.Errorf("finding the new parent: %w", err)
+	}
+	if parent.ID() == *oldParent {
+		return fmt.Errorf("the new parent is %s, the old parent", parent.ID())
+	}
+	children, err := storage.FindChildren(ctx, storer, *oldParent)
+	if err != nil {
+		return err
+	}
+	if len(children) == 0 {
+		log.Printf("no rows have parent %s", *oldParent)
+		return nil
+	}
+
+	// print the whole diff, and check every child for a label collision,
+	// before moving any of them
+	conflicts := 0
+	for _, child := range children {
+		fmt.Printf("~ %s/%s %q: parent %s -> %s/%s\n", child.Type(), child.ID(), child.Label(), *oldParent, parent.Type(), parent.ID())
+		taken, err := storer.GetChild(ctx, child.Label(), parent.ID())
+		if err != nil && !errors.Is(err, storage.ErrNotFoundRow) {
+			return err
+		}
+		if err == nil {
+			fmt.Printf("  ! the label is taken by %s/%s\n", taken.Type(), taken.ID())
+			conflicts++
+		}
+	}
+	if conflicts > 0 {
+		return fmt.Errorf("%w: %d labels are taken under %s; relabel those children first", errConflicts, conflicts, parent.ID())
+	}
+	if *dryRun {
+		log.Printf("would move %d rows", len(children))
+		return nil
+	}
+
+	for i, child := range children {
+		if _, err := storer.UpdateChild(ctx, child.Type(), child.ID(), child.Label(), parent.Type(), parent.ID()); err != nil {
+			return fmt.Errorf("moved %d of %d rows: %s %s: %w", i, len(children), child.Type(), child.ID(), err)
+		}
+	}
+	log.Printf("moved %d rows", len(children))
+	return nil
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/dynamodb"
+)
+
+func runRotateKey(args []string) error {
+	flags := flag.NewFlagSet("rotate-key", flag.ExitOnError)
+	backend := backendFlag(flags)
+	keyARN := flags.String("key-arn", "", "ARN of the KMS key to encrypt the table with")
+	timeout := flags.Duration("timeout", 30*time.Minute, "how long to wait for the table to switch keys")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *keyARN == "" {
+		return fmt.Errorf("-key-arn is required")
+	}
+
+	ctx, stop := commandContext()
+	defer stop()
+	storer, err := openBackend(ctx, *backend)
+	if err != nil {
+		return err
+	}
+	client, ok := storer.(*dynamodb.Client)
+	if !ok {
+		return fmt.Errorf("rotate-key only supports DynamoDB backends")
+	}
+
+	log.Printf("switching the table to key %s", *keyARN)
+	waitCtx, cancel := context.WithTimeout(ctx, *timeout)
+	defer cancel()
+	if err := client.RotateKey(waitCtx, *keyARN); err != nil {
+		return err
+	}
+	if err := client.CheckKeyAccess(ctx); err != nil {
+		return err
+	}
+	log.Print("the table uses the new key, and its items are readable; set kms_key_arn on the backend to match")
+	return nil
+}
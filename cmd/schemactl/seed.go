@@ -0,0 +1,65 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/fixture"
+)
+
+// filesFlag collects file names or glob patterns from a repeated flag.
+type filesFlag []string
+
+func (f *filesFlag) String() string { return strings.Join(*f, ",") }
+
+func (f *filesFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+func runSeed(args []string) error {
+	flags := flag.NewFlagSet("seed", flag.ExitOnError)
+	backend := backendFlag(flags)
+	var patterns filesFlag
+	flags.Var(&patterns, "f", "fixture file or glob pattern to seed from (repeatable; more files may follow the flags)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	// shells expand -f fixtures/*.yaml into one flag and many arguments
+	patterns = append(patterns, flags.Args()...)
+	if len(patterns) == 0 {
+		return fmt.Errorf("at least one fixture file is required")
+	}
+
+	paths := []string{}
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return err
+		}
+		if len(matches) == 0 {
+			return fmt.Errorf("no fixture files match %s", pattern)
+		}
+		paths = append(paths, matches...)
+	}
+	rows, err := fixture.Load(paths...)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := commandContext()
+	defer stop()
+	storer, err := openBackend(ctx, *backend)
+	if err != nil {
+		return err
+	}
+	result, err := fixture.Seed(ctx, storer, rows)
+	if err != nil {
+		return err
+	}
+	log.Printf("seeded %s: %s", strings.Join(paths, ", "), result)
+	return nil
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/integrity"
+)
+
+func runSign(args []string) error {
+	flags := flag.NewFlagSet("sign", flag.ExitOnError)
+	backend := backendFlag(flags)
+	keyARN := flags.String("key-arn", "", "ARN of the KMS HMAC key to sign rows with, as set in the provider's integrity_key_arn")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *keyARN == "" {
+		return fmt.Errorf("-key-arn is required")
+	}
+
+	ctx, stop := commandContext()
+	defer stop()
+	storer, err := openBackend(ctx, *backend)
+	if err != nil {
+		return err
+	}
+	profile, region := backendAWSConfig(*backend)
+	signer, err := integrity.NewKMSSigner(ctx, profile, region, *keyARN)
+	if err != nil {
+		return err
+	}
+
+	signed, err := integrity.NewStorer(storer, signer, false).SignAll(ctx)
+	log.Printf("signed %d rows", signed)
+	return err
+}
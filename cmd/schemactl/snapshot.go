@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/dataset"
+	"github.com/spilliams/tree-terraform-provider/pkg/snapshot"
+)
+
+const snapshotUsage = `usage: schemactl snapshot <create|list|restore> [flags]
+
+stores:
+  s3://<bucket>/<prefix>?region=<region>&profile=<profile>
+  <directory>, or file://<directory>
+
+The store defaults to the SCHEMACTL_SNAPSHOTS environment variable, or to
+./snapshots.
+`
+
+func runSnapshot(args []string) error {
+	if len(args) < 1 {
+		fmt.Fprint(os.Stderr, snapshotUsage)
+		os.Exit(2)
+	}
+	switch args[0] {
+	case "create":
+		return runSnapshotCreate(args[1:])
+	case "list":
+		return runSnapshotList(args[1:])
+	case "restore":
+		return runSnapshotRestore(args[1:])
+	}
+	return fmt.Errorf("unknown snapshot command %q\n\n%s", args[0], snapshotUsage)
+}
+
+// storeFlag adds the -store flag to a snapshot command's flags.
+func storeFlag(flags *flag.FlagSet) *string {
+	def := os.Getenv("SCHEMACTL_SNAPSHOTS")
+	if def == "" {
+		def = "snapshots"
+	}
+	return flags.String("store", def, "where snapshots are kept: a directory, or s3://<bucket>/<prefix>")
+}
+
+// openStore opens the snapshot store the spec describes.
+func openStore(ctx context.Context, spec string) (snapshot.Store, error) {
+	u, err := url.Parse(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid snapshot store %q: %w", spec, err)
+	}
+	switch u.Scheme {
+	case "s3":
+		if u.Host == "" {
+			return nil, fmt.Errorf("invalid snapshot store %q: a bucket is required, as in s3://<bucket>/<prefix>", spec)
+		}
+		query := u.Query()
+		return snapshot.NewS3Store(ctx, query.Get("profile"), query.Get("region"), u.Host, u.Path)
+	case "file":
+		return &snapshot.LocalStore{Dir: u.Host + u.Path}, nil
+	case "":
+		return &snapshot.LocalStore{Dir: spec}, nil
+	}
+	return nil, fmt.Errorf("unknown snapshot store %q\n\n%s", u.Scheme, snapshotUsage)
+}
+
+func runSnapshotCreate(args []string) error {
+	flags := flag.NewFlagSet("snapshot create", flag.ExitOnError)
+	backend := backendFlag(flags)
+	store := storeFlag(flags)
+	name := flags.String("name", "snapshot", "name of the snapshot; its creation time is appended")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	ctx, stop := commandContext()
+	defer stop()
+	storer, err := openBackend(ctx, *backend)
+	if err != nil {
+		return err
+	}
+	snapshots, err := openStore(ctx, *store)
+	if err != nil {
+		return err
+	}
+	info, n, err := snapshot.Create(ctx, storer, snapshots, *name)
+	if err != nil {
+		return err
+	}
+	log.Printf("saved %d rows to snapshot %s", n, info.ID)
+	return nil
+}
+
+func runSnapshotList(args []string) error {
+	flags := flag.NewFlagSet("snapshot list", flag.ExitOnError)
+	store := storeFlag(flags)
+	name := flags.String("name", "", "only list snapshots with this name")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	ctx, stop := commandContext()
+	defer stop()
+	snapshots, err := openStore(ctx, *store)
+	if err != nil {
+		return err
+	}
+	infos, err := snapshot.List(ctx, snapshots)
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tCREATED\tBYTES")
+	for _, info := range infos {
+		if *name != "" && info.Name != *name {
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\n", info.ID, info.Created.Format("2006-01-02 15:04:05Z"), info.Size)
+	}
+	return tw.Flush()
+}
+
+func runSnapshotRestore(args []string) error {
+	flags := flag.NewFlagSet("snapshot restore", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: schemactl snapshot restore [flags] <id or name>")
+		flags.PrintDefaults()
+	}
+	backend := backendFlag(flags)
+	store := storeFlag(flags)
+	onConflict := flags.String("on-conflict", string(dataset.ConflictFail), "what to do with rows that already exist: fail, skip, or overwrite")
+	dryRun := flags.Bool("dry-run", false, "report what would change without writing")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return fmt.Errorf("exactly one snapshot is required")
+	}
+	strategy, err := dataset.ParseConflictStrategy(*onConflict)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := commandContext()
+	defer stop()
+	snapshots, err := openStore(ctx, *store)
+	if err != nil {
+		return err
+	}
+	info, err := snapshot.Find(ctx, snapshots, strings.TrimSuffix(flags.Arg(0), ".ndjson"))
+	if err != nil {
+		return err
+	}
+	records, err := snapshot.Read(ctx, snapshots, info.ID)
+	if err != nil {
+		return err
+	}
+	storer, err := openBackend(ctx, *backend)
+	if err != nil {
+		return err
+	}
+
+	result, err := dataset.Import(ctx, storer, records, dataset.ImportOptions{OnConflict: strategy, DryRun: *dryRun})
+	if err != nil {
+		return err
+	}
+	if *dryRun {
+		log.Printf("dry run: restoring %s would have %s", info.ID, result)
+		return nil
+	}
+	log.Printf("restored %d rows from %s: %s", len(records), info.ID, result)
+	return nil
+}
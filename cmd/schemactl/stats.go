@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/dataset"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+// stats summarizes the stored rows.
+type stats struct {
+	// rows counts rows by type.
+	rows map[string]int
+	// children lists, by parent type, how many children each parent has.
+	// Parents without children count as zero.
+	children map[string][]int
+	// depth is the number of rows on the longest path from a root down.
+	depth int
+	// sizes lists every row's size in bytes, estimated from its JSON
+	// encoding.
+	sizes []int
+}
+
+func runStats(args []string) error {
+	flags := flag.NewFlagSet("stats", flag.ExitOnError)
+	backend := backendFlag(flags)
+	approximate := flags.Bool("approximate", false, "print only the backend's approximate counts, without reading every row")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	ctx, stop := commandContext()
+	defer stop()
+	storer, err := openBackend(ctx, *backend)
+	if err != nil {
+		return err
+	}
+	if *approximate {
+		count, err := storage.CountApproximately(ctx, storer)
+		if err != nil {
+			return err
+		}
+		return printApproximateCount(os.Stdout, count)
+	}
+	s, err := collectStats(ctx, storer)
+	if err != nil {
+		return err
+	}
+	return s.print(os.Stdout)
+}
+
+func collectStats(ctx context.Context, storer storage.RowStorer) (*stats, error) {
+	s := &stats{rows: map[string]int{}, children: map[string][]int{}}
+	types := map[string]string{}
+	parents := map[string]string{}
+	childCounts := map[string]int{}
+	err := storer.ScanRows(ctx, func(row storage.Row) error {
+		s.rows[row.Type()]++
+		types[row.ID()] = row.Type()
+		parents[row.ID()] = row.ParentID()
+		if row.ParentID() != "" {
+			childCounts[row.ParentID()]++
+		}
+		b, err := json.Marshal(dataset.FromRow(row))
+		if err != nil {
+			return err
+		}
+		s.sizes = append(s.sizes, len(b))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// only types that have children somewhere are parent types
+	parentTypes := map[string]bool{}
+	for id := range childCounts {
+		if t, ok := types[id]; ok {
+			parentTypes[t] = true
+		}
+	}
+	for id, t := range types {
+		if parentTypes[t] {
+			s.children[t] = append(s.children[t], childCounts[id])
+		}
+	}
+	for _, counts := range s.children {
+		sort.Ints(counts)
+	}
+	sort.Ints(s.sizes)
+
+	depths := map[string]int{}
+	var depthOf func(id string, seen int) int
+	depthOf = func(id string, seen int) int {
+		if d, ok := depths[id]; ok {
+			return d
+		}
+		d := 1
+		// orphans count from themselves, and a cycle stops once it's longer
+		// than there are rows
+		if parent := parents[id]; parent != "" && types[parent] != "" && seen < len(types) {
+			d += depthOf(parent, seen+1)
+		}
+		depths[id] = d
+		return d
+	}
+	for id := range types {
+		if d := depthOf(id, 0); d > s.depth {
+			s.depth = d
+		}
+	}
+	return s, nil
+}
+
+func (s *stats) print(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "TYPE\tROWS")
+	total := 0
+	rowTypes := make([]string, 0, len(s.rows))
+	for t := range s.rows {
+		rowTypes = append(rowTypes, t)
+	}
+	sort.Strings(rowTypes)
+	for _, t := range rowTypes {
+		fmt.Fprintf(tw, "%s\t%d\n", t, s.rows[t])
+		total += s.rows[t]
+	}
+	fmt.Fprintf(tw, "total\t%d\n", total)
+
+	fmt.Fprintln(tw, "\nPARENT TYPE\tPARENTS\tMIN CHILDREN\tP50\tP90\tMAX")
+	parentTypes := make([]string, 0, len(s.children))
+	for t := range s.children {
+		parentTypes = append(parentTypes, t)
+	}
+	sort.Strings(parentTypes)
+	for _, t := range parentTypes {
+		counts := s.children[t]
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%d\n", t, len(counts), counts[0], percentile(counts, 50), percentile(counts, 90), counts[len(counts)-1])
+	}
+
+	fmt.Fprintf(tw, "\nmax depth\t%d\n", s.depth)
+	if len(s.sizes) > 0 {
+		fmt.Fprintf(tw, "item bytes\tp50 %d\tp90 %d\tp99 %d\tmax %d\n", percentile(s.sizes, 50), percentile(s.sizes, 90), percentile(s.sizes, 99), s.sizes[len(s.sizes)-1])
+	}
+	return tw.Flush()
+}
+
+// printApproximateCount prints a backend's approximate counts, which for
+// DynamoDB may be hours old.
+func printApproximateCount(w io.Writer, count storage.ApproximateCount) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "approximate rows\t%d\n", count.Rows)
+	fmt.Fprintf(tw, "approximate bytes\t%d\n", count.Bytes)
+	return tw.Flush()
+}
+
+// percentile returns the nearest-rank percentile of sorted, non-empty values.
+func percentile(sorted []int, p int) int {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
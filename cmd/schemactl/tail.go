@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/dataset"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+func runTail(args []string) error {
+	flags := flag.NewFlagSet("tail", flag.ExitOnError)
+	backend := backendFlag(flags)
+	rowType := flags.String("type", "", "only print changes to rows of this type")
+	piiOpts := piiFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	storer, err := openBackend(ctx, *backend)
+	if err != nil {
+		return err
+	}
+	watcher, ok := storer.(storage.Watcher)
+	if !ok {
+		return fmt.Errorf("the %s backend can't stream changes", strings.SplitN(*backend, ":", 2)[0])
+	}
+	pii, err := piiOpts.columns()
+	if err != nil {
+		return err
+	}
+
+	log.Print("watching for changes; press ctrl-c to stop")
+	return watcher.Watch(ctx, func(change storage.Change) error {
+		row := change.Row()
+		if *rowType != "" && row.Type() != *rowType {
+			return nil
+		}
+		change.Old, change.New = pii.Mask(change.Old), pii.Mask(change.New)
+		fmt.Println(formatChange(change))
+		return nil
+	})
+}
+
+func formatChange(change storage.Change) string {
+	row := change.Row()
+	line := fmt.Sprintf("%s %-7s %s/%s %q", change.Time.UTC().Format(time.RFC3339), change.Kind, row.Type(), row.ID(), row.Label())
+	switch {
+	case change.Old != nil && change.New != nil:
+		if fields := dataset.DiffRows(change.Old, change.New); len(fields) > 0 {
+			line += ": " + strings.Join(fields, "; ")
+		}
+	case change.New != nil:
+		line += fmt.Sprintf(" parent=%s %s", orDash(row.ParentID()), formatColumns(row.Columns()))
+	}
+	return line
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/importid"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+func runTFImport(args []string) error {
+	flags := flag.NewFlagSet("tf-import", flag.ExitOnError)
+	backend := backendFlag(flags)
+	rowType := flags.String("type", "", "type of the rows to import")
+	label := flags.String("label", "", "only import rows whose label contains this string")
+	parent := flags.String("parent", "", "only import children of the row with this ID")
+	provider := flags.String("provider", "tree", "the provider's type name, which prefixes its resource types")
+	skeleton := flags.Bool("skeleton", false, "also write a resource block for each row, with its current values")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *rowType == "" {
+		return fmt.Errorf("-type is required")
+	}
+
+	ctx, stop := commandContext()
+	defer stop()
+	storer, err := openBackend(ctx, *backend)
+	if err != nil {
+		return err
+	}
+	rows, err := storage.IterRows(ctx, storer, *rowType, *label, *parent).All()
+	if err != nil {
+		return err
+	}
+	sortRows(rows)
+	return writeImportBlocks(os.Stdout, rows, *provider+"_"+*rowType, *skeleton)
+}
+
+// writeImportBlocks writes a Terraform import block for each row, and, with
+// skeleton, a resource block for it to import into. Resources are named after
+// the rows' labels.
+func writeImportBlocks(w io.Writer, rows []storage.Row, resourceType string, skeleton bool) error {
+	names := map[string]bool{}
+	for i, row := range rows {
+		name := resourceName(row.Label())
+		for n := 2; names[name]; n++ {
+			name = fmt.Sprintf("%s_%d", resourceName(row.Label()), n)
+		}
+		names[name] = true
+
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintln(w, "import {")
+		writeAttributes(w, [][2]string{
+			{"to", resourceType + "." + name},
+			{"id", hclString(importid.Format(row.Type(), row.ID()))},
+		})
+		fmt.Fprintln(w, "}")
+		if !skeleton {
+			continue
+		}
+
+		attributes := [][2]string{{"label", hclString(row.Label())}}
+		if row.ParentID() != "" {
+			attributes = append(attributes, [2]string{"parent_id", hclString(row.ParentID())})
+		}
+		columns := row.Columns()
+		columnNames := make([]string, 0, len(columns))
+		for name := range columns {
+			columnNames = append(columnNames, name)
+		}
+		sort.Strings(columnNames)
+		for _, name := range columnNames {
+			attributes = append(attributes, [2]string{name, hclValue(columns[name])})
+		}
+		fmt.Fprintf(w, "\nresource %q %q {\n", resourceType, name)
+		writeAttributes(w, attributes)
+		fmt.Fprintln(w, "}")
+	}
+	return nil
+}
+
+// writeAttributes writes name = value lines, aligned as terraform fmt would.
+func writeAttributes(w io.Writer, attributes [][2]string) {
+	width := 0
+	for _, attribute := range attributes {
+		if len(attribute[0]) > width {
+			width = len(attribute[0])
+		}
+	}
+	for _, attribute := range attributes {
+		fmt.Fprintf(w, "  %-*s = %s\n", width, attribute[0], attribute[1])
+	}
+}
+
+// resourceName turns a label into a Terraform identifier: lowercase letters,
+// digits, underscores, and dashes, not starting with a digit or dash.
+func resourceName(label string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(label) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '_' || r == '-' {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune('_')
+		}
+	}
+	name := b.String()
+	if name == "" || (name[0] >= '0' && name[0] <= '9') || name[0] == '-' {
+		name = "_" + name
+	}
+	return name
+}
+
+func hclValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return hclString(v)
+	case []string:
+		elems := make([]string, len(v))
+		for i, elem := range v {
+			elems[i] = hclString(elem)
+		}
+		return "[" + strings.Join(elems, ", ") + "]"
+	}
+	return hclString(fmt.Sprint(value))
+}
+
+// hclString quotes s as an HCL string, escaping template sequences so they
+// stay literal.
+func hclString(s string) string {
+	quoted := strconv.Quote(s)
+	quoted = strings.ReplaceAll(quoted, "${", "$${")
+	return strings.ReplaceAll(quoted, "%{", "%%{")
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+func runTree(args []string) error {
+	flags := flag.NewFlagSet("tree", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: schemactl tree [flags] <root-id>")
+		flags.PrintDefaults()
+	}
+	backend := backendFlag(flags)
+	format := flags.String("format", "text", "output format: text, or dot for Graphviz")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return fmt.Errorf("exactly one root ID is required")
+	}
+	if *format != "text" && *format != "dot" {
+		return fmt.Errorf("unknown format %q: use text or dot", *format)
+	}
+
+	ctx, stop := commandContext()
+	defer stop()
+	storer, err := openBackend(ctx, *backend)
+	if err != nil {
+		return err
+	}
+	root, err := storage.LoadTree(ctx, storer, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	if *format == "dot" {
+		return printDot(os.Stdout, root)
+	}
+	printTree(os.Stdout, root, "", "")
+	return nil
+}
+
+// printTree draws the tree with box-drawing characters. prefix starts the
+// node's own line, and indent starts the lines of its descendants.
+func printTree(w io.Writer, node *storage.Node, prefix, indent string) {
+	fmt.Fprintf(w, "%s%s %s (%s)\n", prefix, node.Type(), node.Label(), node.ID())
+	for i, child := range node.Children {
+		if i == len(node.Children)-1 {
+			printTree(w, child, indent+"└── ", indent+"    ")
+		} else {
+			printTree(w, child, indent+"├── ", indent+"│   ")
+		}
+	}
+}
+
+// printDot writes the tree as a Graphviz digraph, with an edge from each
+// parent to each of its children.
+func printDot(w io.Writer, root *storage.Node) error {
+	fmt.Fprintln(w, "digraph tree {")
+	fmt.Fprintln(w, "\tnode [shape=box];")
+	err := root.Walk(func(node *storage.Node, depth int) error {
+		// Go's quoting escapes are also DOT's, so \n breaks the label's line
+		fmt.Fprintf(w, "\t%q [label=%q];\n", node.ID(), node.Type()+"\n"+node.Label())
+		for _, child := range node.Children {
+			fmt.Fprintf(w, "\t%q -> %q;\n", node.ID(), child.ID())
+		}
+		return nil
+	})
+	fmt.Fprintln(w, "}")
+	return err
+}
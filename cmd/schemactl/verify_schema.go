@@ -0,0 +1,75 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/dynamodb"
+)
+
+func runVerifySchema(args []string) error {
+	flags := flag.NewFlagSet("verify-schema", flag.ExitOnError)
+	backend := backendFlag(flags)
+	fix := flags.Bool("fix", false, "apply the corrections that are safe on a live table")
+	claimLabels := flags.Bool("claim-labels", false, "write the label guards of root rows stored before there were any")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	ctx, stop := commandContext()
+	defer stop()
+	storer, err := openBackend(ctx, *backend)
+	if err != nil {
+		return err
+	}
+	client, ok := storer.(*dynamodb.Client)
+	if !ok {
+		return fmt.Errorf("verify-schema only supports DynamoDB backends")
+	}
+
+	if *claimLabels {
+		claimed, duplicates, err := client.ClaimLabels(ctx)
+		if err != nil {
+			return err
+		}
+		log.Printf("%d root rows hold their labels", claimed)
+		if duplicates > 0 {
+			log.Printf("%d root rows share a label with another row of their type; relabel them", duplicates)
+		}
+	}
+
+	problems, err := client.VerifySchema(ctx)
+	if err != nil {
+		return err
+	}
+	if len(problems) == 0 {
+		log.Print("the table matches what the client expects")
+		return nil
+	}
+
+	unfixed := 0
+	for _, problem := range problems {
+		switch {
+		case !problem.Fixable():
+			log.Printf("%s (needs a new table; see schemactl migrate)", problem.Problem)
+			unfixed++
+		case !*fix:
+			log.Printf("%s (fixable with -fix)", problem.Problem)
+			unfixed++
+		default:
+			if err := problem.Fix(ctx); err != nil {
+				// DynamoDB allows one table update at a time, so later fixes
+				// may have to wait for a rerun
+				log.Printf("%s: fixing failed: %s", problem.Problem, err)
+				unfixed++
+				continue
+			}
+			log.Printf("%s: fixed", problem.Problem)
+		}
+	}
+	if unfixed > 0 {
+		return fmt.Errorf("%d of %d problems remain", unfixed, len(problems))
+	}
+	return nil
+}
@@ -0,0 +1,222 @@
+// Command storaged serves the rows of any storage backend over HTTP and gRPC,
+// for the provider and schemactl to reach with an https:// or grpc://
+// backend, so that one team can own the datastore while others need only its
+// address and a token or client certificate.
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/spilliams/tree-terraform-provider/internal/backends"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/grpcclient"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/httpclient"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/metrics"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+const usage = `usage: storaged [flags]
+
+Serves the rows of a storage backend over HTTP, on -addr, to clients bearing
+a token, which is read from the file -token-file names, or from the
+STORAGED_TOKEN environment variable; and over gRPC, on -grpc-addr, to clients
+presenting a certificate of an authority in -tls-client-ca.
+
+flags:
+`
+
+// shutdownTimeout is how long storaged waits for requests in flight when it
+// is stopped.
+const shutdownTimeout = 30 * time.Second
+
+type config struct {
+	backend     string
+	addr        string
+	grpcAddr    string
+	tokenFile   string
+	tlsCert     string
+	tlsKey      string
+	tlsClientCA string
+	metricsAddr string
+}
+
+func main() {
+	log.SetFlags(log.LstdFlags)
+	log.SetPrefix("storaged: ")
+
+	var c config
+	flags := flag.NewFlagSet("storaged", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), usage)
+		flags.PrintDefaults()
+		fmt.Fprint(flags.Output(), "\n"+backends.Usage+"\nThe backend defaults to the STORAGED_BACKEND environment variable.\n")
+	}
+	flags.StringVar(&c.backend, "backend", os.Getenv("STORAGED_BACKEND"), "storage backend to serve, e.g. dynamodb://tree?region=us-west-2")
+	flags.StringVar(&c.addr, "addr", ":8080", "address to serve HTTP on, or empty not to")
+	flags.StringVar(&c.grpcAddr, "grpc-addr", "", "address to serve gRPC on, e.g. :8443, which requires -tls-client-ca")
+	flags.StringVar(&c.tokenFile, "token-file", "", "file holding the token HTTP clients must bear")
+	flags.StringVar(&c.tlsCert, "tls-cert", "", "certificate file, to serve HTTPS and gRPC over TLS")
+	flags.StringVar(&c.tlsKey, "tls-key", "", "private key file of -tls-cert")
+	flags.StringVar(&c.tlsClientCA, "tls-client-ca", "", "file of the certificate authorities whose client certificates are accepted, for mutual TLS")
+	flags.StringVar(&c.metricsAddr, "metrics-addr", "", "address to serve Prometheus metrics of storage operations on, e.g. :9090")
+	_ = flags.Parse(os.Args[1:])
+
+	if err := run(c); err != nil {
+		log.Fatal(err.Error())
+	}
+}
+
+func run(c config) error {
+	if c.backend == "" {
+		return fmt.Errorf("a backend is required: pass -backend or set STORAGED_BACKEND")
+	}
+	if c.addr == "" && c.grpcAddr == "" {
+		return fmt.Errorf("nothing to serve: pass -addr or -grpc-addr")
+	}
+	if (c.tlsCert == "") != (c.tlsKey == "") {
+		return fmt.Errorf("-tls-cert and -tls-key must be passed together")
+	}
+	if c.tlsClientCA != "" && c.tlsCert == "" {
+		return fmt.Errorf("-tls-client-ca requires -tls-cert and -tls-key")
+	}
+	if c.grpcAddr != "" && c.tlsClientCA == "" {
+		return fmt.Errorf("-grpc-addr requires -tls-client-ca, to authenticate clients")
+	}
+	var tlsConfig *tls.Config
+	var err error
+	switch {
+	case c.tlsClientCA != "":
+		tlsConfig, err = grpcclient.ServerTLS(c.tlsCert, c.tlsKey, c.tlsClientCA)
+	case c.tlsCert != "":
+		var cert tls.Certificate
+		cert, err = tls.LoadX509KeyPair(c.tlsCert, c.tlsKey)
+		tlsConfig = &tls.Config{MinVersion: tls.VersionTLS12, Certificates: []tls.Certificate{cert}}
+	}
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	storer, err := backends.Open(ctx, c.backend)
+	if err != nil {
+		return err
+	}
+
+	errs := make(chan error, 3)
+	var metricsServer *http.Server
+	if c.metricsAddr != "" {
+		registry := prometheus.NewRegistry()
+		prom, err := metrics.NewPrometheus(registry)
+		if err != nil {
+			return err
+		}
+		storer = metrics.NewStorer(storer, prom)
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metrics.Handler(registry))
+		metricsServer = &http.Server{Addr: c.metricsAddr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+		go func() {
+			log.Printf("serving metrics on %s", c.metricsAddr)
+			errs <- metricsServer.ListenAndServe()
+		}()
+	}
+	var httpServer *http.Server
+	if c.addr != "" {
+		token, err := readToken(c.tokenFile)
+		if err != nil {
+			return err
+		}
+		httpServer = &http.Server{
+			Addr:              c.addr,
+			Handler:           httpclient.NewHandler(storer, token, nil),
+			TLSConfig:         tlsConfig,
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		go func() {
+			log.Printf("serving HTTP on %s", c.addr)
+			if tlsConfig != nil {
+				errs <- httpServer.ListenAndServeTLS("", "")
+			} else {
+				errs <- httpServer.ListenAndServe()
+			}
+		}()
+	}
+	var grpcServer *grpc.Server
+	if c.grpcAddr != "" {
+		lis, err := net.Listen("tcp", c.grpcAddr)
+		if err != nil {
+			return err
+		}
+		grpcServer = grpc.NewServer(grpc.Creds(credentials.NewTLS(tlsConfig)))
+		grpcclient.Register(grpcServer, storer, nil)
+		go func() {
+			log.Printf("serving gRPC on %s", c.grpcAddr)
+			errs <- grpcServer.Serve(lis)
+		}()
+	}
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+	log.Printf("shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if grpcServer != nil {
+		stopped := make(chan struct{})
+		go func() {
+			grpcServer.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-shutdownCtx.Done():
+			grpcServer.Stop()
+		}
+	}
+	if httpServer != nil {
+		if err := httpServer.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+	}
+	// the metrics are served until the other servers have stopped, so that
+	// the requests they finish are scraped
+	if metricsServer != nil {
+		if err := metricsServer.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+	}
+	return nil
+}
+
+// readToken returns the token in the file, or in STORAGED_TOKEN if the file
+// isn't named.
+func readToken(file string) (string, error) {
+	token := os.Getenv("STORAGED_TOKEN")
+	if file != "" {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return "", fmt.Errorf("reading token: %w", err)
+		}
+		token = strings.TrimSpace(string(b))
+	}
+	if token == "" {
+		return "", fmt.Errorf("a token is required to serve HTTP: pass -token-file or set STORAGED_TOKEN")
+	}
+	return token, nil
+}
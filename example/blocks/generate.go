@@ -0,0 +1,3 @@
+package blocks
+
+//go:generate go run ../../cmd/schema-tfgen generate -definitions ../definitions -out . -package blocks -tests -provider-name tree -column-types ../column_types.yaml -migrations ../migrations -client-out ../treeclient
@@ -2,11 +2,18 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"flag"
 	"log"
+	"net/http"
+	"net/http/pprof"
+	"os"
 
 	"github.com/hashicorp/terraform-plugin-framework/providerserver"
+	"github.com/prometheus/client_golang/prometheus"
 	exampleprovider "github.com/spilliams/tree-terraform-provider/example/provider"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/dynamodb"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/metrics"
 )
 
 var (
@@ -14,20 +21,92 @@ var (
 	commit  string = "unknown"
 )
 
+// runSummary is what the provider did in one run, written to the file named
+// by TREE_RUN_SUMMARY when it shuts down.
+type runSummary struct {
+	Storage  metrics.SummaryReport `json:"storage"`
+	DynamoDB dynamodb.UsageReport  `json:"dynamodb"`
+}
+
 func main() {
 	var debug bool
+	var metricsAddr string
+	var pprofAddr string
 
 	flag.BoolVar(&debug, "debug", false, "set to true to run the provider with support for debuggers like delve")
+	flag.StringVar(&metricsAddr, "metrics-addr", "", "address to serve Prometheus metrics of storage operations on, e.g. :9090")
+	flag.StringVar(&pprofAddr, "pprof-addr", "", "address to serve pprof profiles on, e.g. localhost:6060, labeled by storage operation")
 	flag.Parse()
 
+	recorders := []metrics.Recorder{}
+	if metricsAddr != "" {
+		registry := prometheus.NewRegistry()
+		prom, err := metrics.NewPrometheus(registry)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+		recorders = append(recorders, prom)
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metrics.Handler(registry))
+		go func() {
+			log.Fatal(http.ListenAndServe(metricsAddr, mux).Error())
+		}()
+	}
+
+	if pprofAddr != "" {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/debug/pprof/", pprof.Index)
+		mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+		go func() {
+			log.Fatal(http.ListenAndServe(pprofAddr, mux).Error())
+		}()
+	}
+
+	// the summary goes to a file, since Terraform starts the provider and
+	// reads its output itself
+	summaryPath := os.Getenv("TREE_RUN_SUMMARY")
+	var summary *metrics.Summary
+	var usage *dynamodb.Usage
+	if summaryPath != "" {
+		summary = metrics.NewSummary()
+		usage = dynamodb.NewUsage()
+		recorders = append(recorders, summary)
+	}
+
+	var recorder metrics.Recorder
+	switch len(recorders) {
+	case 0:
+	case 1:
+		recorder = recorders[0]
+	default:
+		recorder = metrics.Multi(recorders...)
+	}
+
 	opts := providerserver.ServeOpts{
 		// for development only
 		Address: "demo.leuco.net/terraform-registry/tree",
 		Debug:   debug,
 	}
 
-	err := providerserver.Serve(context.Background(), exampleprovider.New(version, commit), opts)
+	err := providerserver.Serve(context.Background(), exampleprovider.New(version, commit, recorder, usage), opts)
+	if summary != nil {
+		if err := writeSummary(summaryPath, runSummary{Storage: summary.Report(), DynamoDB: usage.Report()}); err != nil {
+			log.Printf("writing the run summary: %s", err)
+		}
+	}
 	if err != nil {
 		log.Fatal(err.Error())
 	}
 }
+
+func writeSummary(path string, summary runSummary) error {
+	log.Printf("%d storage operations, %d DynamoDB retries, %.1f capacity units consumed",
+		summary.Storage.Total, summary.DynamoDB.Retries, summary.DynamoDB.ConsumedCapacity)
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
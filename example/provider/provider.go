@@ -3,8 +3,13 @@ package provider
 import (
 	"context"
 	"fmt"
+	"os"
+	"os/user"
+	"strings"
+	"time"
 
 	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/provider"
 	"github.com/hashicorp/terraform-plugin-framework/provider/schema"
@@ -12,7 +17,18 @@ import (
 	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-log/tflog"
 	"github.com/spilliams/tree-terraform-provider/example/blocks"
+	"github.com/spilliams/tree-terraform-provider/internal/backends"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/attribution"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/audit"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/deadline"
 	"github.com/spilliams/tree-terraform-provider/pkg/storage/dynamodb"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/encryption"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/integrity"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/metrics"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/policy"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/ratelimit"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/tracing"
 )
 
 const (
@@ -20,6 +36,54 @@ const (
 	providerAttrAWSRegion  = "region"
 	providerAttrTableName  = "table_name"
 	providerAttrKeyARN     = "kms_key_arn"
+	providerAttrSQLite     = "sqlite_path"
+	providerAttrStorageURL = "storage_url"
+	providerAttrStorageTok = "storage_token"
+	providerAttrClientCert = "storage_client_certificate"
+	providerAttrClientKey  = "storage_client_key"
+	providerAttrStorageCA  = "storage_ca_certificate"
+	providerAttrShadow     = "shadow_backend"
+	providerAttrReads      = "read_backend"
+	providerAttrAuditBus   = "audit_event_bus"
+	providerAttrAuditTopic = "audit_sns_topic_arn"
+	providerAttrLogColumns = "log_column_values"
+	providerAttrSlowOp     = "slow_operation_threshold"
+	providerAttrEncrypted  = "encrypted_columns"
+	providerAttrEncryptKey = "encryption_key"
+	providerAttrIntegrity  = "integrity_key_arn"
+	providerAttrRequireSig = "require_signatures"
+	providerAttrAccess     = "access_rules"
+	providerAttrActor      = "actor"
+	providerAttrTenant     = "tenant"
+	providerAttrReadOnly   = "read_only"
+	providerAttrKeyGrants  = "manage_key_grants"
+	providerAttrPrincipals = "table_principals"
+	providerAttrReaders    = "table_read_only_principals"
+	providerAttrMaxColumn  = "max_column_bytes"
+	providerAttrMaxRow     = "max_row_bytes"
+	providerAttrListPages  = "list_concurrency"
+	providerAttrCacheSize  = "read_cache_size"
+	providerAttrCacheTTL   = "read_cache_ttl"
+	providerAttrPrefetch   = "prefetch_children"
+	providerAttrWarmTypes  = "warm_cache_types"
+	providerAttrWarmTrees  = "warm_cache_subtrees"
+	providerAttrJitter     = "retry_jitter"
+	providerAttrRetryBase  = "retry_base_delay"
+	providerAttrRetryMax   = "retry_max_delay"
+	providerAttrCompact    = "compact_columns"
+	providerAttrShards     = "sharded_types"
+	providerAttrRate       = "throttle_rate"
+	providerAttrCapacity   = "throttle_capacity"
+	providerAttrReadRate   = "read_rate_limit"
+	providerAttrWriteRate  = "write_rate_limit"
+	providerAttrBreaker    = "circuit_breaker_failures"
+	providerAttrCooldown   = "circuit_breaker_cooldown"
+	providerAttrIdleConns  = "http_max_idle_connections"
+	providerAttrHostConns  = "http_max_connections_per_host"
+	providerAttrIdleTime   = "http_idle_timeout"
+	providerAttrKeepAlive  = "http_keep_alive"
+	providerAttrReadLimit  = "read_timeout"
+	providerAttrWriteLimit = "write_timeout"
 )
 
 type treeProviderModel struct {
@@ -27,18 +91,78 @@ type treeProviderModel struct {
 	AWSRegion  types.String `tfsdk:"region"`
 	TableName  types.String `tfsdk:"table_name"`
 	KMSKeyARN  types.String `tfsdk:"kms_key_arn"`
+	SQLitePath types.String `tfsdk:"sqlite_path"`
+	StorageURL types.String `tfsdk:"storage_url"`
+	StorageTok types.String `tfsdk:"storage_token"`
+	ClientCert types.String `tfsdk:"storage_client_certificate"`
+	ClientKey  types.String `tfsdk:"storage_client_key"`
+	StorageCA  types.String `tfsdk:"storage_ca_certificate"`
+	Shadow     types.String `tfsdk:"shadow_backend"`
+	Reads      types.String `tfsdk:"read_backend"`
+	AuditBus   types.String `tfsdk:"audit_event_bus"`
+	AuditTopic types.String `tfsdk:"audit_sns_topic_arn"`
+	LogColumns types.List   `tfsdk:"log_column_values"`
+	SlowOp     types.String `tfsdk:"slow_operation_threshold"`
+	Encrypted  types.List   `tfsdk:"encrypted_columns"`
+	EncryptKey types.String `tfsdk:"encryption_key"`
+	Integrity  types.String `tfsdk:"integrity_key_arn"`
+	RequireSig types.Bool   `tfsdk:"require_signatures"`
+	Access     types.List   `tfsdk:"access_rules"`
+	Actor      types.String `tfsdk:"actor"`
+	Tenant     types.String `tfsdk:"tenant"`
+	ReadOnly   types.Bool   `tfsdk:"read_only"`
+	KeyGrants  types.Bool   `tfsdk:"manage_key_grants"`
+	Principals types.List   `tfsdk:"table_principals"`
+	Readers    types.List   `tfsdk:"table_read_only_principals"`
+	MaxColumn  types.Int64  `tfsdk:"max_column_bytes"`
+	MaxRow     types.Int64  `tfsdk:"max_row_bytes"`
+	ListPages  types.Int64  `tfsdk:"list_concurrency"`
+	CacheSize  types.Int64  `tfsdk:"read_cache_size"`
+	CacheTTL   types.String `tfsdk:"read_cache_ttl"`
+	Prefetch   types.Bool   `tfsdk:"prefetch_children"`
+	WarmTypes  types.List   `tfsdk:"warm_cache_types"`
+	WarmTrees  types.List   `tfsdk:"warm_cache_subtrees"`
+	Jitter     types.String `tfsdk:"retry_jitter"`
+	RetryBase  types.String `tfsdk:"retry_base_delay"`
+	RetryMax   types.String `tfsdk:"retry_max_delay"`
+	Compact    types.Bool   `tfsdk:"compact_columns"`
+	Shards     types.Map    `tfsdk:"sharded_types"`
+	Rate       types.Int64  `tfsdk:"throttle_rate"`
+	Capacity   types.Int64  `tfsdk:"throttle_capacity"`
+	ReadRate   types.Int64  `tfsdk:"read_rate_limit"`
+	WriteRate  types.Int64  `tfsdk:"write_rate_limit"`
+	Breaker    types.Int64  `tfsdk:"circuit_breaker_failures"`
+	Cooldown   types.String `tfsdk:"circuit_breaker_cooldown"`
+	IdleConns  types.Int64  `tfsdk:"http_max_idle_connections"`
+	HostConns  types.Int64  `tfsdk:"http_max_connections_per_host"`
+	IdleTime   types.String `tfsdk:"http_idle_timeout"`
+	KeepAlive  types.String `tfsdk:"http_keep_alive"`
+	ReadLimit  types.String `tfsdk:"read_timeout"`
+	WriteLimit types.String `tfsdk:"write_timeout"`
+}
+
+type accessRuleModel struct {
+	Effect     string   `tfsdk:"effect"`
+	Operations []string `tfsdk:"operations"`
+	RowTypes   []string `tfsdk:"row_types"`
+	Subtree    *string  `tfsdk:"subtree"`
 }
 
 type treeProvider struct {
 	version string
 	commit  string
+	// recorder, if set, receives measurements of every storage operation
+	recorder metrics.Recorder
+	// usage, if set, totals the DynamoDB calls of every client
+	usage *dynamodb.Usage
 }
 
 var _ provider.Provider = &treeProvider{}
 
-func New(version, commit string) func() provider.Provider {
+// New returns the provider. recorder and usage may be nil.
+func New(version, commit string, recorder metrics.Recorder, usage *dynamodb.Usage) func() provider.Provider {
 	return func() provider.Provider {
-		return &treeProvider{version, commit}
+		return &treeProvider{version, commit, recorder, usage}
 	}
 }
 
@@ -52,20 +176,243 @@ func (tree *treeProvider) Schema(_ context.Context, _ provider.SchemaRequest, re
 		Description: "Interact with the information architecture of the engineering platform.",
 		Attributes: map[string]schema.Attribute{
 			providerAttrAWSProfile: schema.StringAttribute{
-				Description: "The AWS profile to use for DynamoDB storage.",
-				Required:    true,
+				Description: "The AWS profile to use for DynamoDB storage. Required unless `sqlite_path` or `storage_url` is set.",
+				Optional:    true,
 			},
 			providerAttrAWSRegion: schema.StringAttribute{
-				Description: "The AWS region to use for DynamoDB storage.",
-				Required:    true,
+				Description: "The AWS region to use for DynamoDB storage. Required unless `sqlite_path` or `storage_url` is set.",
+				Optional:    true,
 			},
 			providerAttrTableName: schema.StringAttribute{
-				Description: "The table name to use for DynamoDB storage.",
-				Required:    true,
+				Description: "The table name to use for DynamoDB storage. Required unless `sqlite_path` or `storage_url` is set.",
+				Optional:    true,
 			},
 			providerAttrKeyARN: schema.StringAttribute{
-				Description: "The ARN of the KMS key to use for encrypting the DynamoDB storage.",
-				Required:    true,
+				Description: "The ARN of the KMS key to use for encrypting the DynamoDB storage. Required unless `sqlite_path` or `storage_url` is set.",
+				Optional:    true,
+			},
+			providerAttrSQLite: schema.StringAttribute{
+				Description: "The path of a SQLite database file to store rows in instead of DynamoDB, created if it doesn't exist, or \":memory:\" for rows that last one run. For trying out configurations offline: the DynamoDB settings are ignored, and `manage_key_grants` and cache warm-up can't be used.",
+				Optional:    true,
+			},
+			providerAttrStorageURL: schema.StringAttribute{
+				Description: "The URL of a storage server, such as storaged, to store rows through instead of DynamoDB, for when another team owns the datastore: an https:// URL of its REST API, or grpc://<host>:<port> of its gRPC service. The DynamoDB settings are ignored, and `manage_key_grants` and cache warm-up can't be used.",
+				Optional:    true,
+			},
+			providerAttrStorageTok: schema.StringAttribute{
+				Description: "The bearer token to send the storage server at an https:// `storage_url`. Defaults to the TREE_STORAGE_TOKEN environment variable.",
+				Optional:    true,
+				Sensitive:   true,
+			},
+			providerAttrClientCert: schema.StringAttribute{
+				Description: "The path of the PEM certificate to present to the gRPC storage service at `storage_url`, for mutual TLS.",
+				Optional:    true,
+			},
+			providerAttrClientKey: schema.StringAttribute{
+				Description: "The path of the PEM private key of `storage_client_certificate`.",
+				Optional:    true,
+			},
+			providerAttrStorageCA: schema.StringAttribute{
+				Description: "The path of the PEM certificate authorities to trust the gRPC storage service's certificate by, instead of the system's.",
+				Optional:    true,
+			},
+			providerAttrShadow: schema.StringAttribute{
+				Description: "A backend to migrate to, as schemactl's `-backend` names it, such as postgres://..., that every write is mirrored to and every read compared with. The rows are still read from and written to the provider's backend first, and only the shadow's failures and differences are logged, as warnings. Copy the rows with `schemactl migrate` first.",
+				Optional:    true,
+			},
+			providerAttrReads: schema.StringAttribute{
+				Description: "A backend to read rows from instead, as schemactl's `-backend` names it, such as a read replica of the provider's PostgreSQL database, to take the load of refresh-heavy plans off the backend that's written to. Rows it can't find are read from the provider's backend too, in case it lags behind.",
+				Optional:    true,
+			},
+			providerAttrTenant: schema.StringAttribute{
+				Description: "A tenant to keep rows in a slice of the table for, whose partition keys begin with \"<tenant>#\", so that IAM policies can restrict credentials to it with dynamodb:LeadingKeys conditions.",
+				Optional:    true,
+			},
+			providerAttrReadOnly: schema.BoolAttribute{
+				Description: "Whether the provider may only read, for plans with read-only credentials. Applies that would change rows fail before touching the table.",
+				Optional:    true,
+			},
+			providerAttrPrincipals: schema.ListAttribute{
+				Description: "The IAM role or user ARNs, or account IDs, that the resource-based policy of a table the provider creates allows to read and write rows.",
+				ElementType: types.StringType,
+				Optional:    true,
+			},
+			providerAttrReaders: schema.ListAttribute{
+				Description: "The IAM role or user ARNs, or account IDs, that the resource-based policy of a table the provider creates allows only to read rows.",
+				ElementType: types.StringType,
+				Optional:    true,
+			},
+			providerAttrKeyGrants: schema.BoolAttribute{
+				Description: "Whether to grant the provider's AWS identity the use of the KMS key, if its grants of the key don't already allow it. The identity needs kms:ListGrants and kms:CreateGrant on the key; without them, the error lists what to grant.",
+				Optional:    true,
+			},
+			providerAttrAuditBus: schema.StringAttribute{
+				Description: "The name or ARN of an EventBridge event bus to publish an event to for every row created, updated, or deleted.",
+				Optional:    true,
+			},
+			providerAttrAuditTopic: schema.StringAttribute{
+				Description: "The ARN of an SNS topic to publish an event to for every row created, updated, or deleted.",
+				Optional:    true,
+			},
+			providerAttrLogColumns: schema.ListAttribute{
+				Description: "The names of the columns whose values may appear in debug logs, or \"*\" for all of them but PII columns, which must be named. By default no column values are logged.",
+				ElementType: types.StringType,
+				Optional:    true,
+			},
+			providerAttrReadLimit: schema.StringAttribute{
+				Description: "A duration, such as \"30s\", after which a read of rows fails, retries included, rather than stalling the run. By default reads last as long as Terraform lets them.",
+				Optional:    true,
+			},
+			providerAttrWriteLimit: schema.StringAttribute{
+				Description: "A duration, such as \"1m\", after which a write of a row fails, retries included. A write that times out may still have been made. By default writes last as long as Terraform lets them.",
+				Optional:    true,
+			},
+			providerAttrSlowOp: schema.StringAttribute{
+				Description: "A duration, such as \"2s\", after which a storage operation is logged as slow, with a warning.",
+				Optional:    true,
+			},
+			providerAttrListPages: schema.Int64Attribute{
+				Description: "How many ranges of IDs to list a row type's rows in at once, for plural data sources over types of many thousands of rows. By default rows are listed one page after another.",
+				Optional:    true,
+			},
+			providerAttrCacheSize: schema.Int64Attribute{
+				Description: "How many rows to keep in memory after reading them, so that resources reading the same parent don't each read it from the table. The provider's own writes keep the cache current; other writers' changes aren't seen until the next run. By default rows aren't cached.",
+				Optional:    true,
+			},
+			providerAttrCacheTTL: schema.StringAttribute{
+				Description: "How long rows stay in the read cache, as a duration such as \"5m\", so that a long-lived provider sees other writers' changes. Setting it caches rows whatever the backend, `sqlite_path` and `storage_url` included, but without child prefetching or cache warm-up. Requires `read_cache_size`. By default rows stay until they're evicted.",
+				Optional:    true,
+			},
+			providerAttrPrefetch: schema.BoolAttribute{
+				Description: "Whether reading a row by ID also reads its children into the read cache, in one query alongside it, so that resources under the row find them there. Requires `read_cache_size`.",
+				Optional:    true,
+			},
+			providerAttrWarmTypes: schema.ListAttribute{
+				Description: "Row types to read every row of into the read cache when the provider is configured, a page at a time, so that a plan reading hundreds of them reads them from memory. Requires a `read_cache_size` big enough to hold them.",
+				ElementType: types.StringType,
+				Optional:    true,
+			},
+			providerAttrWarmTrees: schema.ListAttribute{
+				Description: "IDs of rows to read every descendant of into the read cache when the provider is configured, a level at a time. Requires a `read_cache_size` big enough to hold them.",
+				ElementType: types.StringType,
+				Optional:    true,
+			},
+			providerAttrJitter: schema.StringAttribute{
+				Description: "How to randomize the delays before retrying throttled or failed DynamoDB calls, or throttled requests to `storage_url`: \"full\" (anywhere up to the exponential delay), \"equal\" (between half of it and all of it), or \"decorrelated\" (between the base delay and three times the previous delay). Defaults to \"full\" when any retry setting is set, and otherwise to the AWS SDK's own retries of DynamoDB calls.",
+				Optional:    true,
+			},
+			providerAttrRetryBase: schema.StringAttribute{
+				Description: "A duration, such as \"100ms\", to wait before the first retry of a DynamoDB call or storage request, doubling for each retry after it. Defaults to 100ms.",
+				Optional:    true,
+			},
+			providerAttrRetryMax: schema.StringAttribute{
+				Description: "A duration, such as \"20s\", that retries of DynamoDB calls or storage requests wait at most. Defaults to 20s.",
+				Optional:    true,
+			},
+			providerAttrCompact: schema.BoolAttribute{
+				Description: "Whether to store each row's columns as one JSON string, which makes items smaller and allows any column name. Rows are read in either form; once on, keep it on, since clients without it can't update single columns of compact rows.",
+				Optional:    true,
+			},
+			providerAttrShards: schema.MapAttribute{
+				Description: "The number of partition keys to spread the rows of each named type across, for types written to so heavily that one partition throttles them. Finding and listing rows of a sharded type queries every shard. Shard a type before it has rows: rows stored unsharded, or with another number of shards, aren't found.",
+				ElementType: types.Int64Type,
+				Optional:    true,
+			},
+			providerAttrRate: schema.Int64Attribute{
+				Description: "The most DynamoDB calls a second the provider makes. Throttled calls halve the rate, and calls that aren't raise it back, so that a large apply slows down while the table is busy instead of retrying at full speed. By default calls aren't paced.",
+				Optional:    true,
+			},
+			providerAttrCapacity: schema.Int64Attribute{
+				Description: "The most capacity units a second the provider's DynamoDB calls consume, such as its share of the table's provisioned capacity. Calls consuming more delay those after them. Requires throttle_rate.",
+				Optional:    true,
+			},
+			providerAttrReadRate: schema.Int64Attribute{
+				Description: "The most storage reads a second the provider starts, whatever the backend, so that a plan with high parallelism stays within the table's provisioned reads or a storage server's quota. Reads the read cache answers aren't counted. By default reads aren't limited.",
+				Optional:    true,
+			},
+			providerAttrWriteRate: schema.Int64Attribute{
+				Description: "The most storage writes a second the provider starts, whatever the backend. By default writes aren't limited.",
+				Optional:    true,
+			},
+			providerAttrBreaker: schema.Int64Attribute{
+				Description: "How many storage operations may fail in a row, as when the backend is unreachable, before the provider stops calling it for a while, and fails the rest at once with one clear error, rather than each resource waiting for its own to fail. Operations that find no row, conflict, or are refused still count as answers. By default the provider doesn't stop.",
+				Optional:    true,
+			},
+			providerAttrCooldown: schema.StringAttribute{
+				Description: "A duration, such as \"1m\", for which the provider stops calling the backend after `circuit_breaker_failures` failures, before trying it with one operation again. Defaults to 30s.",
+				Optional:    true,
+			},
+			providerAttrIdleConns: schema.Int64Attribute{
+				Description: "How many idle connections to DynamoDB to keep open for later calls, so that an apply running many calls at once reuses connections rather than opening new ones, each with a TLS handshake. Set it to about Terraform's -parallelism. Defaults to 10.",
+				Optional:    true,
+			},
+			providerAttrHostConns: schema.Int64Attribute{
+				Description: "The most connections to open to DynamoDB, idle or not. Calls beyond it wait for a connection. By default there's no limit.",
+				Optional:    true,
+			},
+			providerAttrIdleTime: schema.StringAttribute{
+				Description: "A duration, such as \"5m\", to keep an idle connection open for. Defaults to 90s.",
+				Optional:    true,
+			},
+			providerAttrKeepAlive: schema.StringAttribute{
+				Description: "A duration, such as \"15s\", between the TCP keep-alive probes of an open connection, which find connections the network dropped before a call uses them. Defaults to 30s.",
+				Optional:    true,
+			},
+			providerAttrMaxColumn: schema.Int64Attribute{
+				Description: "The most bytes a column may take, counting its name and value. Writes of larger columns fail before touching the table. By default columns are limited only by the row.",
+				Optional:    true,
+			},
+			providerAttrMaxRow: schema.Int64Attribute{
+				Description: "The most bytes a row may take, counting its label, IDs, and columns. Writes that would make a row larger fail before touching the table. Defaults to DynamoDB's item limit, 409600 (400 KB).",
+				Optional:    true,
+			},
+			providerAttrIntegrity: schema.StringAttribute{
+				Description: "The ARN of a KMS HMAC key to sign every row written with, and verify every row read against, so that rows changed in the table directly are detected.",
+				Optional:    true,
+			},
+			providerAttrRequireSig: schema.BoolAttribute{
+				Description: "Whether reading an unsigned row is an error, rather than a warning. Sign existing rows with `schemactl sign` before setting it.",
+				Optional:    true,
+			},
+			providerAttrActor: schema.StringAttribute{
+				Description: "Who to record as the creator or updater of the rows the provider writes. By default, the ARN of the AWS identity the profile resolves to, or with `sqlite_path` or `storage_url`, the local user's name.",
+				Optional:    true,
+			},
+			providerAttrAccess: schema.ListNestedAttribute{
+				Description: "Rules that allow or deny storage operations, so that, for example, one team's CI role can't change another team's branch of the tree. An operation is allowed if some rule allows it and no rule denies it. By default every operation is allowed.",
+				Optional:    true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"effect": schema.StringAttribute{
+							Description: "Whether the rule allows or denies the operations it matches: \"allow\" or \"deny\".",
+							Required:    true,
+						},
+						"operations": schema.ListAttribute{
+							Description: "The kinds of operation the rule matches: \"read\", \"create\", \"update\", or \"delete\". By default, all of them.",
+							ElementType: types.StringType,
+							Optional:    true,
+						},
+						"row_types": schema.ListAttribute{
+							Description: "The types of row the rule matches. By default, all of them.",
+							ElementType: types.StringType,
+							Optional:    true,
+						},
+						"subtree": schema.StringAttribute{
+							Description: "The ID of a row: the rule matches only that row and its descendants.",
+							Optional:    true,
+						},
+					},
+				},
+			},
+			providerAttrEncrypted: schema.ListAttribute{
+				Description: "The names of columns to encrypt before they are stored, with data keys wrapped by the KMS key, or by `encryption_key`, so that only those who can use the key can read them.",
+				ElementType: types.StringType,
+				Optional:    true,
+			},
+			providerAttrEncryptKey: schema.StringAttribute{
+				Description: "A 256-bit key, in base64, to wrap the data keys of `encrypted_columns` with instead of the KMS key, for backends without KMS, such as `sqlite_path` or `storage_url`. Whoever holds it can read the encrypted columns. Defaults to the TREE_ENCRYPTION_KEY environment variable.",
+				Optional:    true,
+				Sensitive:   true,
 			},
 		},
 	}
@@ -109,30 +456,951 @@ func (tree *treeProvider) Configure(ctx context.Context, req provider.ConfigureR
 			"Cannot configure the provider client with an unknown KMS Key ARN.",
 		)
 	}
+	if config.SQLitePath.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root(providerAttrSQLite),
+			"Unknown SQLite path",
+			"Cannot configure the provider client with an unknown SQLite database path.",
+		)
+	}
+	if config.StorageURL.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root(providerAttrStorageURL),
+			"Unknown storage URL",
+			"Cannot configure the provider client with an unknown storage server URL.",
+		)
+	}
+	if config.Shadow.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root(providerAttrShadow),
+			"Unknown shadow backend",
+			"Cannot configure the provider client with an unknown shadow backend.",
+		)
+	}
+	if config.Reads.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root(providerAttrReads),
+			"Unknown read backend",
+			"Cannot configure the provider client with an unknown read backend.",
+		)
+	}
+	if config.StorageTok.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root(providerAttrStorageTok),
+			"Unknown storage token",
+			"Cannot configure the provider client with an unknown storage server token.",
+		)
+	}
+	for attr, value := range map[string]types.String{
+		providerAttrClientCert: config.ClientCert,
+		providerAttrClientKey:  config.ClientKey,
+		providerAttrStorageCA:  config.StorageCA,
+	} {
+		if value.IsUnknown() {
+			resp.Diagnostics.AddAttributeError(
+				path.Root(attr),
+				"Unknown storage TLS setting",
+				fmt.Sprintf("Cannot configure the provider client with an unknown %s.", attr),
+			)
+		}
+	}
+	storageToken := config.StorageTok.ValueString()
+	if storageToken == "" {
+		storageToken = os.Getenv("TREE_STORAGE_TOKEN")
+	}
+	if !config.SQLitePath.IsNull() && !config.StorageURL.IsNull() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root(providerAttrStorageURL),
+			"Conflicting storage settings",
+			"Rows are stored either in SQLite or through a storage server: unset sqlite_path or storage_url.",
+		)
+	} else if grpcTarget, ok := strings.CutPrefix(config.StorageURL.ValueString(), "grpc://"); ok {
+		if strings.Trim(grpcTarget, "/") == "" {
+			resp.Diagnostics.AddAttributeError(
+				path.Root(providerAttrStorageURL),
+				"Invalid storage URL",
+				"A gRPC storage service's URL needs its host and port, as in grpc://storage.internal:8443.",
+			)
+		}
+		if config.ClientCert.IsNull() != config.ClientKey.IsNull() {
+			resp.Diagnostics.AddAttributeError(
+				path.Root(providerAttrClientKey),
+				"Incomplete client certificate",
+				"storage_client_certificate and storage_client_key must be set together.",
+			)
+		}
+	} else if config.StorageURL.ValueString() != "" && storageToken == "" {
+		resp.Diagnostics.AddAttributeError(
+			path.Root(providerAttrStorageTok),
+			"Missing storage token",
+			"A token is required to store rows through a storage server. Set storage_token, or the TREE_STORAGE_TOKEN environment variable.",
+		)
+	}
+	if config.SQLitePath.IsNull() && config.StorageURL.IsNull() {
+		for attr, value := range map[string]types.String{
+			providerAttrAWSProfile: config.AWSProfile,
+			providerAttrAWSRegion:  config.AWSRegion,
+			providerAttrTableName:  config.TableName,
+			providerAttrKeyARN:     config.KMSKeyARN,
+		} {
+			if value.IsNull() {
+				resp.Diagnostics.AddAttributeError(
+					path.Root(attr),
+					"Missing DynamoDB setting",
+					fmt.Sprintf("The %s is required to store rows in DynamoDB. Set it, or set sqlite_path to store rows in SQLite, or storage_url to store them through a storage server.", attr),
+				)
+			}
+		}
+	} else if config.KeyGrants.ValueBool() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root(providerAttrKeyGrants),
+			"Key grants without DynamoDB",
+			"KMS key grants are managed for the DynamoDB table only: unset manage_key_grants, or sqlite_path and storage_url.",
+		)
+	} else if len(config.WarmTypes.Elements()) > 0 || len(config.WarmTrees.Elements()) > 0 {
+		resp.Diagnostics.AddAttributeError(
+			path.Root(providerAttrWarmTypes),
+			"Cache warm-up without DynamoDB",
+			"The read cache is DynamoDB's: unset warm_cache_types and warm_cache_subtrees, or sqlite_path and storage_url.",
+		)
+	}
+	if config.Tenant.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root(providerAttrTenant),
+			"Unknown tenant",
+			"Cannot configure the provider client with an unknown tenant.",
+		)
+	}
+	ctx = tflog.SetField(ctx, providerAttrTenant, config.Tenant.ValueString())
+	if config.ReadOnly.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root(providerAttrReadOnly),
+			"Unknown read only",
+			"Cannot configure the provider client without knowing whether it is read-only.",
+		)
+	}
+	if config.Principals.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root(providerAttrPrincipals),
+			"Unknown table principals",
+			"Cannot configure the provider client with unknown table principals.",
+		)
+	}
+	if config.Readers.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root(providerAttrReaders),
+			"Unknown table read-only principals",
+			"Cannot configure the provider client with unknown table read-only principals.",
+		)
+	}
+	if config.KeyGrants.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root(providerAttrKeyGrants),
+			"Unknown manage key grants",
+			"Cannot configure the provider client without knowing whether to manage KMS key grants.",
+		)
+	}
+	if config.AuditBus.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root(providerAttrAuditBus),
+			"Unknown audit event bus",
+			"Cannot configure the provider client with an unknown EventBridge event bus.",
+		)
+	}
+	if config.AuditTopic.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root(providerAttrAuditTopic),
+			"Unknown audit SNS topic ARN",
+			"Cannot configure the provider client with an unknown SNS topic ARN.",
+		)
+	}
+	if config.LogColumns.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root(providerAttrLogColumns),
+			"Unknown log column values",
+			"Cannot configure the provider client with unknown column names to log the values of.",
+		)
+	}
+	if config.Encrypted.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root(providerAttrEncrypted),
+			"Unknown encrypted columns",
+			"Cannot configure the provider client with unknown columns to encrypt.",
+		)
+	}
+	var localKey []byte
+	if config.EncryptKey.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root(providerAttrEncryptKey),
+			"Unknown encryption key",
+			"Cannot configure the provider client with an unknown encryption key.",
+		)
+	} else if encoded := config.EncryptKey.ValueString(); encoded != "" || os.Getenv("TREE_ENCRYPTION_KEY") != "" {
+		if encoded == "" {
+			encoded = os.Getenv("TREE_ENCRYPTION_KEY")
+		}
+		var err error
+		localKey, err = encryption.ParseLocalKey(encoded)
+		if err == nil && len(localKey) != 32 {
+			err = fmt.Errorf("it is %d bytes, not 32", len(localKey))
+		}
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(
+				path.Root(providerAttrEncryptKey),
+				"Invalid encryption key",
+				fmt.Sprintf("The encryption key must be 32 random bytes in base64, as from `openssl rand -base64 32`: %s.", err),
+			)
+		}
+	} else if len(config.Encrypted.Elements()) > 0 && config.KMSKeyARN.IsNull() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root(providerAttrEncrypted),
+			"Encrypted columns without a key",
+			"Encrypting columns takes a key to wrap data keys with: set kms_key_arn, or encryption_key or the TREE_ENCRYPTION_KEY environment variable.",
+		)
+	}
+	if config.Integrity.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root(providerAttrIntegrity),
+			"Unknown integrity key ARN",
+			"Cannot configure the provider client with an unknown integrity key ARN.",
+		)
+	}
+	if config.RequireSig.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root(providerAttrRequireSig),
+			"Unknown require signatures",
+			"Cannot configure the provider client without knowing whether to require signatures.",
+		)
+	}
+	if config.Actor.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root(providerAttrActor),
+			"Unknown actor",
+			"Cannot configure the provider client with an unknown actor.",
+		)
+	}
+	if config.Access.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root(providerAttrAccess),
+			"Unknown access rules",
+			"Cannot configure the provider client with unknown access rules.",
+		)
+	}
+	var slowOp time.Duration
+	if config.SlowOp.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root(providerAttrSlowOp),
+			"Unknown slow operation threshold",
+			"Cannot configure the provider client with an unknown slow operation threshold.",
+		)
+	} else if config.SlowOp.ValueString() != "" {
+		var err error
+		slowOp, err = time.ParseDuration(config.SlowOp.ValueString())
+		if err != nil || slowOp <= 0 {
+			resp.Diagnostics.AddAttributeError(
+				path.Root(providerAttrSlowOp),
+				"Invalid slow operation threshold",
+				fmt.Sprintf("The slow operation threshold must be a positive duration, such as \"2s\", not %q.", config.SlowOp.ValueString()),
+			)
+		}
+	}
+	timeouts := deadline.Timeouts{}
+	for attr, timeout := range map[string]struct {
+		value types.String
+		limit *time.Duration
+	}{
+		providerAttrReadLimit:  {config.ReadLimit, &timeouts.Read},
+		providerAttrWriteLimit: {config.WriteLimit, &timeouts.Write},
+	} {
+		if timeout.value.IsUnknown() {
+			resp.Diagnostics.AddAttributeError(
+				path.Root(attr),
+				"Unknown timeout",
+				fmt.Sprintf("Cannot configure the provider client with an unknown %s.", attr),
+			)
+		} else if timeout.value.ValueString() != "" {
+			limit, err := time.ParseDuration(timeout.value.ValueString())
+			if err != nil || limit <= 0 {
+				resp.Diagnostics.AddAttributeError(
+					path.Root(attr),
+					"Invalid timeout",
+					fmt.Sprintf("The %s must be a positive duration, such as \"30s\", not %q.", attr, timeout.value.ValueString()),
+				)
+			}
+			*timeout.limit = limit
+		}
+	}
+	var backoff *dynamodb.Backoff
+	if config.Jitter.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root(providerAttrJitter),
+			"Unknown retry jitter",
+			"Cannot configure the provider client with an unknown retry jitter.",
+		)
+	} else if config.Jitter.ValueString() != "" {
+		jitter, err := dynamodb.ParseJitter(config.Jitter.ValueString())
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(
+				path.Root(providerAttrJitter),
+				"Invalid retry jitter",
+				err.Error(),
+			)
+		}
+		backoff = &dynamodb.Backoff{Jitter: jitter}
+	}
+	for attr, value := range map[string]types.String{providerAttrRetryBase: config.RetryBase, providerAttrRetryMax: config.RetryMax} {
+		if value.IsUnknown() {
+			resp.Diagnostics.AddAttributeError(
+				path.Root(attr),
+				"Unknown retry delay",
+				"Cannot configure the provider client with an unknown retry delay.",
+			)
+			continue
+		}
+		if value.ValueString() == "" {
+			continue
+		}
+		delay, err := time.ParseDuration(value.ValueString())
+		if err != nil || delay <= 0 {
+			resp.Diagnostics.AddAttributeError(
+				path.Root(attr),
+				"Invalid retry delay",
+				fmt.Sprintf("The retry delay must be a positive duration, such as \"100ms\", not %q.", value.ValueString()),
+			)
+			continue
+		}
+		if backoff == nil {
+			backoff = &dynamodb.Backoff{}
+		}
+		if attr == providerAttrRetryBase {
+			backoff.Base = delay
+		} else {
+			backoff.Max = delay
+		}
+	}
+	if backoff != nil && backoff.Base > 0 && backoff.Max > 0 && backoff.Base > backoff.Max {
+		resp.Diagnostics.AddAttributeError(
+			path.Root(providerAttrRetryBase),
+			"Invalid retry delay",
+			"The base retry delay must be no longer than the maximum.",
+		)
+	}
+	var transport *dynamodb.Transport
+	for attr, value := range map[string]types.Int64{providerAttrIdleConns: config.IdleConns, providerAttrHostConns: config.HostConns} {
+		if value.IsUnknown() {
+			resp.Diagnostics.AddAttributeError(
+				path.Root(attr),
+				"Unknown number of connections",
+				fmt.Sprintf("Cannot configure the provider client with an unknown %s.", attr),
+			)
+			continue
+		}
+		if value.IsNull() {
+			continue
+		}
+		if value.ValueInt64() < 1 {
+			resp.Diagnostics.AddAttributeError(
+				path.Root(attr),
+				"Invalid number of connections",
+				fmt.Sprintf("The %s must be positive, not %d.", attr, value.ValueInt64()),
+			)
+			continue
+		}
+		if transport == nil {
+			transport = &dynamodb.Transport{}
+		}
+		if attr == providerAttrIdleConns {
+			transport.MaxIdleConns = int(value.ValueInt64())
+		} else {
+			transport.MaxConnsPerHost = int(value.ValueInt64())
+		}
+	}
+	for attr, value := range map[string]types.String{providerAttrIdleTime: config.IdleTime, providerAttrKeepAlive: config.KeepAlive} {
+		if value.IsUnknown() {
+			resp.Diagnostics.AddAttributeError(
+				path.Root(attr),
+				"Unknown connection duration",
+				fmt.Sprintf("Cannot configure the provider client with an unknown %s.", attr),
+			)
+			continue
+		}
+		if value.ValueString() == "" {
+			continue
+		}
+		d, err := time.ParseDuration(value.ValueString())
+		if err != nil || d <= 0 {
+			resp.Diagnostics.AddAttributeError(
+				path.Root(attr),
+				"Invalid connection duration",
+				fmt.Sprintf("The %s must be a positive duration, such as \"30s\", not %q.", attr, value.ValueString()),
+			)
+			continue
+		}
+		if transport == nil {
+			transport = &dynamodb.Transport{}
+		}
+		if attr == providerAttrIdleTime {
+			transport.IdleTimeout = d
+		} else {
+			transport.KeepAlive = d
+		}
+	}
+	maxRow := int64(dynamodb.MaxItemSize)
+	for attr, value := range map[string]types.Int64{providerAttrMaxColumn: config.MaxColumn, providerAttrMaxRow: config.MaxRow} {
+		if value.IsUnknown() {
+			resp.Diagnostics.AddAttributeError(
+				path.Root(attr),
+				"Unknown size limit",
+				fmt.Sprintf("Cannot configure the provider client with an unknown %s.", attr),
+			)
+		} else if !value.IsNull() && (value.ValueInt64() <= 0 || value.ValueInt64() > dynamodb.MaxItemSize) {
+			resp.Diagnostics.AddAttributeError(
+				path.Root(attr),
+				"Invalid size limit",
+				fmt.Sprintf("The %s must be between 1 and DynamoDB's item limit, %d, not %d.", attr, dynamodb.MaxItemSize, value.ValueInt64()),
+			)
+		}
+	}
+	if !config.MaxRow.IsNull() {
+		maxRow = config.MaxRow.ValueInt64()
+	}
+	if config.ListPages.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root(providerAttrListPages),
+			"Unknown list concurrency",
+			"Cannot configure the provider client with an unknown list concurrency.",
+		)
+	} else if !config.ListPages.IsNull() && config.ListPages.ValueInt64() < 1 {
+		resp.Diagnostics.AddAttributeError(
+			path.Root(providerAttrListPages),
+			"Invalid list concurrency",
+			fmt.Sprintf("The list concurrency must be positive, not %d.", config.ListPages.ValueInt64()),
+		)
+	}
+	if config.CacheSize.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root(providerAttrCacheSize),
+			"Unknown read cache size",
+			"Cannot configure the provider client with an unknown read cache size.",
+		)
+	} else if config.CacheSize.ValueInt64() < 0 {
+		resp.Diagnostics.AddAttributeError(
+			path.Root(providerAttrCacheSize),
+			"Invalid read cache size",
+			fmt.Sprintf("The read cache size must be a number of rows, not %d.", config.CacheSize.ValueInt64()),
+		)
+	}
+	var cacheTTL time.Duration
+	if config.CacheTTL.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root(providerAttrCacheTTL),
+			"Unknown read cache TTL",
+			"Cannot configure the provider client with an unknown read cache TTL.",
+		)
+	} else if config.CacheTTL.ValueString() != "" {
+		var err error
+		cacheTTL, err = time.ParseDuration(config.CacheTTL.ValueString())
+		if err != nil || cacheTTL <= 0 {
+			resp.Diagnostics.AddAttributeError(
+				path.Root(providerAttrCacheTTL),
+				"Invalid read cache TTL",
+				fmt.Sprintf("The read cache TTL must be a positive duration, such as \"5m\", not %q.", config.CacheTTL.ValueString()),
+			)
+		} else if config.CacheSize.ValueInt64() == 0 {
+			resp.Diagnostics.AddAttributeError(
+				path.Root(providerAttrCacheTTL),
+				"Read cache TTL without a read cache",
+				"The TTL is of rows in the read cache: set read_cache_size too.",
+			)
+		}
+	}
+	if config.Compact.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root(providerAttrCompact),
+			"Unknown compact columns",
+			"Cannot configure the provider client without knowing whether columns are compact.",
+		)
+	}
+	if config.Shards.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root(providerAttrShards),
+			"Unknown sharded types",
+			"Cannot configure the provider client with unknown sharded types.",
+		)
+	}
+	if config.Rate.IsUnknown() || config.Capacity.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root(providerAttrRate),
+			"Unknown throttle",
+			"Cannot configure the provider client with an unknown throttle rate or capacity.",
+		)
+	} else if !config.Rate.IsNull() && config.Rate.ValueInt64() < 1 {
+		resp.Diagnostics.AddAttributeError(
+			path.Root(providerAttrRate),
+			"Invalid throttle rate",
+			fmt.Sprintf("The throttle rate must be at least 1 call a second, not %d.", config.Rate.ValueInt64()),
+		)
+	} else if !config.Capacity.IsNull() && (config.Rate.IsNull() || config.Capacity.ValueInt64() < 1) {
+		resp.Diagnostics.AddAttributeError(
+			path.Root(providerAttrCapacity),
+			"Invalid throttle capacity",
+			"The throttle capacity must be a positive number of capacity units a second, along with a throttle_rate.",
+		)
+	}
+	for attr, limit := range map[string]types.Int64{
+		providerAttrReadRate:  config.ReadRate,
+		providerAttrWriteRate: config.WriteRate,
+	} {
+		if limit.IsUnknown() {
+			resp.Diagnostics.AddAttributeError(
+				path.Root(attr),
+				"Unknown rate limit",
+				fmt.Sprintf("Cannot configure the provider client with an unknown %s.", attr),
+			)
+		} else if !limit.IsNull() && limit.ValueInt64() < 1 {
+			resp.Diagnostics.AddAttributeError(
+				path.Root(attr),
+				"Invalid rate limit",
+				fmt.Sprintf("The %s must be at least 1 operation a second, not %d.", attr, limit.ValueInt64()),
+			)
+		}
+	}
+	var cooldown time.Duration
+	if config.Breaker.IsUnknown() || config.Cooldown.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root(providerAttrBreaker),
+			"Unknown circuit breaker",
+			"Cannot configure the provider client with an unknown circuit breaker threshold or cooldown.",
+		)
+	} else if !config.Breaker.IsNull() && config.Breaker.ValueInt64() < 1 {
+		resp.Diagnostics.AddAttributeError(
+			path.Root(providerAttrBreaker),
+			"Invalid circuit breaker threshold",
+			fmt.Sprintf("The circuit breaker threshold must be at least 1 failure, not %d.", config.Breaker.ValueInt64()),
+		)
+	} else if config.Cooldown.ValueString() != "" {
+		var err error
+		cooldown, err = time.ParseDuration(config.Cooldown.ValueString())
+		if err != nil || cooldown <= 0 || config.Breaker.IsNull() {
+			resp.Diagnostics.AddAttributeError(
+				path.Root(providerAttrCooldown),
+				"Invalid circuit breaker cooldown",
+				fmt.Sprintf("The circuit breaker cooldown must be a positive duration, such as \"1m\", along with circuit_breaker_failures, not %q.", config.Cooldown.ValueString()),
+			)
+		}
+	}
+	if config.Prefetch.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root(providerAttrPrefetch),
+			"Unknown child prefetching",
+			"Cannot configure the provider client with unknown child prefetching.",
+		)
+	} else if config.Prefetch.ValueBool() && config.CacheSize.ValueInt64() == 0 {
+		resp.Diagnostics.AddAttributeError(
+			path.Root(providerAttrPrefetch),
+			"Child prefetching without a read cache",
+			"Prefetched children are kept in the read cache: set read_cache_size too.",
+		)
+	} else if config.Prefetch.ValueBool() && cacheTTL > 0 {
+		resp.Diagnostics.AddAttributeError(
+			path.Root(providerAttrPrefetch),
+			"Child prefetching with a read cache TTL",
+			"Prefetched children are kept in DynamoDB's read cache, which has no TTL: unset prefetch_children or read_cache_ttl.",
+		)
+	}
+	if config.WarmTypes.IsUnknown() || config.WarmTrees.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root(providerAttrWarmTypes),
+			"Unknown cache warm-up",
+			"Cannot configure the provider client with unknown row types or subtrees to warm the read cache with.",
+		)
+	} else if (len(config.WarmTypes.Elements()) > 0 || len(config.WarmTrees.Elements()) > 0) && config.CacheSize.ValueInt64() == 0 {
+		resp.Diagnostics.AddAttributeError(
+			path.Root(providerAttrWarmTypes),
+			"Cache warm-up without a read cache",
+			"Warming up reads rows into the read cache: set read_cache_size too.",
+		)
+	} else if (len(config.WarmTypes.Elements()) > 0 || len(config.WarmTrees.Elements()) > 0) && cacheTTL > 0 {
+		resp.Diagnostics.AddAttributeError(
+			path.Root(providerAttrWarmTypes),
+			"Cache warm-up with a read cache TTL",
+			"Warming up reads rows into DynamoDB's read cache, which has no TTL: unset warm_cache_types and warm_cache_subtrees, or read_cache_ttl.",
+		)
+	}
 	if resp.Diagnostics.HasError() {
 		return
 	}
+	ctx = storage.NewLogSubsystems(ctx)
 
-	client, err := dynamodb.NewClient(ctx,
-		config.AWSProfile.ValueString(),
-		config.AWSRegion.ValueString(),
-		config.TableName.ValueString(),
-		config.KMSKeyARN.ValueString(),
-	)
+	logPolicy := storage.LogPolicy{PII: blocks.PIIColumns()}
+	resp.Diagnostics.Append(config.LogColumns.ElementsAs(ctx, &logPolicy.AllowColumns, false)...)
+	var encrypted []string
+	resp.Diagnostics.Append(config.Encrypted.ElementsAs(ctx, &encrypted, false)...)
+	var accessRules []accessRuleModel
+	resp.Diagnostics.Append(config.Access.ElementsAs(ctx, &accessRules, false)...)
+	var principals, readers []string
+	resp.Diagnostics.Append(config.Principals.ElementsAs(ctx, &principals, false)...)
+	resp.Diagnostics.Append(config.Readers.ElementsAs(ctx, &readers, false)...)
+	var shards map[string]int64
+	resp.Diagnostics.Append(config.Shards.ElementsAs(ctx, &shards, false)...)
+	var warmTypes, warmTrees []string
+	resp.Diagnostics.Append(config.WarmTypes.ElementsAs(ctx, &warmTypes, false)...)
+	resp.Diagnostics.Append(config.WarmTrees.ElementsAs(ctx, &warmTrees, false)...)
+	for rowType, n := range shards {
+		if n < 1 {
+			resp.Diagnostics.AddAttributeError(
+				path.Root(providerAttrShards),
+				"Invalid shard count",
+				fmt.Sprintf("The %s type must have at least 1 shard, not %d.", rowType, n),
+			)
+		}
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	storage.SetLogPolicy(logPolicy)
+
+	// tracing is configured by the standard OpenTelemetry variables, since
+	// it belongs to the environment Terraform runs in, not the configuration
+	exporter := os.Getenv("OTEL_TRACES_EXPORTER")
+	tp, err := tracing.NewTracerProvider(ctx, exporter, "terraform-provider-tree")
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to configure tracing",
+			"The OTEL_TRACES_EXPORTER environment variable is invalid.\n\n"+
+				err.Error(),
+		)
+		return
+	}
+	opts := []dynamodb.Option{}
+	if tp != nil {
+		opts = append(opts, dynamodb.WithTracerProvider(tp))
+	}
+	if propagator := tracing.Propagator(exporter); propagator != nil {
+		opts = append(opts, dynamodb.WithTracePropagator(propagator))
+	}
+	if tree.usage != nil {
+		opts = append(opts, dynamodb.WithUsage(tree.usage))
+	}
+	if tenant := config.Tenant.ValueString(); tenant != "" {
+		opts = append(opts, dynamodb.WithTenant(tenant))
+	}
+	if len(principals) > 0 || len(readers) > 0 {
+		opts = append(opts, dynamodb.WithTablePolicy(principals, readers))
+	}
+	if n := config.ListPages.ValueInt64(); n > 1 {
+		opts = append(opts, dynamodb.WithListConcurrency(int(n)))
+	}
+	if n := config.CacheSize.ValueInt64(); n > 0 && cacheTTL == 0 {
+		opts = append(opts, dynamodb.WithReadCache(int(n)))
+	}
+	if config.Prefetch.ValueBool() {
+		opts = append(opts, dynamodb.WithChildPrefetch())
+	}
+	if backoff != nil {
+		opts = append(opts, dynamodb.WithBackoff(*backoff))
+	}
+	if config.Compact.ValueBool() {
+		opts = append(opts, dynamodb.WithCompactColumns())
+	}
+	if !config.Rate.IsNull() {
+		opts = append(opts, dynamodb.WithAdaptiveThrottling(dynamodb.Throttle{
+			Rate:     float64(config.Rate.ValueInt64()),
+			Capacity: float64(config.Capacity.ValueInt64()),
+		}))
+	}
+	if len(shards) > 0 {
+		shardCounts := make(map[string]int, len(shards))
+		for rowType, n := range shards {
+			shardCounts[rowType] = int(n)
+		}
+		opts = append(opts, dynamodb.WithShardedTypes(shardCounts))
+	}
+	if transport != nil {
+		opts = append(opts, dynamodb.WithHTTPTransport(*transport))
+	}
+	// so are CloudWatch embedded metrics: TREE_EMF_LOG names the file the
+	// CloudWatch agent collects them from
+	if emfPath := os.Getenv("TREE_EMF_LOG"); emfPath != "" {
+		emfFile, err := os.OpenFile(emfPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		if err != nil {
+			resp.Diagnostics.AddError(
+				"Unable to configure CloudWatch metrics",
+				"The TREE_EMF_LOG environment variable names a file that can't be opened.\n\n"+
+					err.Error(),
+			)
+			return
+		}
+		opts = append(opts, dynamodb.WithEMF(emfFile, "TreeProvider"))
+	}
+
+	client, err := backends.NewBackend(ctx, backends.Backend{
+		SQLitePath:   config.SQLitePath.ValueString(),
+		StorageURL:   config.StorageURL.ValueString(),
+		StorageToken: storageToken,
+		ClientCert:   config.ClientCert.ValueString(),
+		ClientKey:    config.ClientKey.ValueString(),
+		CA:           config.StorageCA.ValueString(),
+		Profile:      config.AWSProfile.ValueString(),
+		Region:       config.AWSRegion.ValueString(),
+		Table:        config.TableName.ValueString(),
+		KMSKeyARN:    config.KMSKeyARN.ValueString(),
+		DynamoDB:     opts,
+	})
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"Unable to create provider client",
 			"An unexpected error occurred when creating the provider client.\n\n"+
 				err.Error(),
 		)
+		return
 	}
-	if resp.Diagnostics.HasError() {
+	if config.KeyGrants.ValueBool() {
+		resp.Diagnostics.Append(tree.grantKeyAccess(ctx, config, client.(*dynamodb.Client))...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+	if len(warmTypes) > 0 || len(warmTrees) > 0 {
+		// warming up only saves reads, which fail on their own if the table
+		// can't be read
+		read, err := client.(*dynamodb.Client).WarmCache(ctx, warmTypes, warmTrees)
+		if err != nil {
+			resp.Diagnostics.AddWarning(
+				"Unable to warm the read cache",
+				fmt.Sprintf("Reading rows into the read cache failed after %d rows, so the rest are read as they're needed.\n\n%s", read, err.Error()),
+			)
+		} else if n := config.CacheSize.ValueInt64(); int64(read) > n {
+			resp.Diagnostics.AddAttributeWarning(
+				path.Root(providerAttrCacheSize),
+				"Read cache too small to warm up",
+				fmt.Sprintf("Warming up read %d rows, but the read cache keeps only %d, so the first ones read were evicted. Raise read_cache_size to keep them all.", read, n),
+			)
+		}
+	}
+
+	stack := backends.Stack{
+		BreakerFailures:   int(config.Breaker.ValueInt64()),
+		BreakerCooldown:   cooldown,
+		MaxColumnBytes:    int(config.MaxColumn.ValueInt64()),
+		MaxRowBytes:       int(maxRow),
+		RequireSignatures: config.RequireSig.ValueBool(),
+		Encrypted:         encrypted,
+		CacheTTL:          cacheTTL,
+		CacheSize:         int(config.CacheSize.ValueInt64()),
+		TraceParent:       tracing.ParentFromEnv(),
+		SlowOperation:     slowOp,
+		Recorder:          tree.recorder,
+		Timeouts:          timeouts,
+		ReadOnly:          config.ReadOnly.ValueBool(),
+	}
+	if !config.ReadRate.IsNull() || !config.WriteRate.IsNull() {
+		stack.Rates = &ratelimit.Limits{
+			Read:  ratelimit.Limit{Rate: float64(config.ReadRate.ValueInt64())},
+			Write: ratelimit.Limit{Rate: float64(config.WriteRate.ValueInt64())},
+		}
+	}
+	// a storage server's clients don't retry on their own, as the AWS SDK
+	// does for DynamoDB
+	if !config.StorageURL.IsNull() {
+		stack.Retry = &storage.RetryPolicy{}
+		if backoff != nil {
+			stack.Retry.Backoff = storage.Backoff(*backoff)
+		}
+	}
+	if spec := config.Reads.ValueString(); spec != "" {
+		stack.Reads, err = backends.Open(ctx, spec)
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(
+				path.Root(providerAttrReads),
+				"Unable to open the read backend",
+				"An unexpected error occurred when opening the read backend.\n\n"+
+					err.Error(),
+			)
+			return
+		}
+	}
+	if spec := config.Shadow.ValueString(); spec != "" {
+		stack.Shadow, err = backends.Open(ctx, spec)
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(
+				path.Root(providerAttrShadow),
+				"Unable to open the shadow backend",
+				"An unexpected error occurred when opening the shadow backend.\n\n"+
+					err.Error(),
+			)
+			return
+		}
+	}
+	if keyARN := config.Integrity.ValueString(); keyARN != "" {
+		stack.Signer, err = integrity.NewKMSSigner(ctx,
+			config.AWSProfile.ValueString(),
+			config.AWSRegion.ValueString(),
+			keyARN,
+		)
+		if err != nil {
+			resp.Diagnostics.AddError(
+				"Unable to configure row signatures",
+				"An unexpected error occurred when creating the KMS client.\n\n"+
+					err.Error(),
+			)
+			return
+		}
+	}
+	if len(encrypted) > 0 {
+		if localKey != nil {
+			stack.Keys, err = encryption.NewLocalKeys(localKey)
+		} else {
+			stack.Keys, err = encryption.NewKMSKeys(ctx,
+				config.AWSProfile.ValueString(),
+				config.AWSRegion.ValueString(),
+				config.KMSKeyARN.ValueString(),
+			)
+		}
+		if err != nil {
+			resp.Diagnostics.AddError(
+				"Unable to configure column encryption",
+				"An unexpected error occurred when creating the key service.\n\n"+
+					err.Error(),
+			)
+			return
+		}
+	}
+	stack.Actor = config.Actor.ValueString()
+	if stack.Actor == "" && (!config.SQLitePath.IsNull() || !config.StorageURL.IsNull()) {
+		// offline, or through a storage server, there may be no AWS
+		// identity to ask for
+		stack.Actor, err = localUser()
+		if err != nil {
+			resp.Diagnostics.AddError(
+				"Unable to identify the caller",
+				"An unexpected error occurred when getting the local user's name, to record who writes rows. Set the actor attribute to record another name instead.\n\n"+
+					err.Error(),
+			)
+			return
+		}
+	}
+	if stack.Actor == "" {
+		stack.Actor, err = attribution.CallerIdentity(ctx,
+			config.AWSProfile.ValueString(),
+			config.AWSRegion.ValueString(),
+		)
+		if err != nil {
+			resp.Diagnostics.AddError(
+				"Unable to identify the caller",
+				"An unexpected error occurred when getting the caller's AWS identity, to record who writes rows. Set the actor attribute to record another name instead.\n\n"+
+					err.Error(),
+			)
+			return
+		}
+	}
+	ctx = tflog.SetField(ctx, providerAttrActor, stack.Actor)
+	for _, rule := range accessRules {
+		r := policy.Rule{
+			Effect:     rule.Effect,
+			Operations: rule.Operations,
+			RowTypes:   rule.RowTypes,
+		}
+		if rule.Subtree != nil {
+			r.Subtree = *rule.Subtree
+		}
+		stack.Rules = append(stack.Rules, r)
+	}
+	stack.ParentTypes = blocks.ParentTypes()
+	if tp != nil {
+		stack.Tracer = tp
+	}
+	stack.Audit, err = tree.auditPublishers(ctx, config)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to configure audit events",
+			"An unexpected error occurred when creating the audit event publisher.\n\n"+
+				err.Error(),
+		)
+		return
+	}
+	client, err = backends.Wrap(client, stack)
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(
+			path.Root(providerAttrAccess),
+			"Invalid access rules",
+			err.Error(),
+		)
 		return
 	}
 	resp.DataSourceData = client
 	resp.ResourceData = client
 }
 
+// localUser returns the name of the user the provider runs as.
+func localUser() (string, error) {
+	current, err := user.Current()
+	if err != nil {
+		return "", err
+	}
+	return current.Username, nil
+}
+
+// grantKeyAccess grants the provider's own AWS identity the use of the
+// table's KMS key, or explains what it is missing.
+func (tree *treeProvider) grantKeyAccess(ctx context.Context, config treeProviderModel, client *dynamodb.Client) diag.Diagnostics {
+	var diags diag.Diagnostics
+	caller, err := attribution.CallerIdentity(ctx, config.AWSProfile.ValueString(), config.AWSRegion.ValueString())
+	if err != nil {
+		diags.AddError(
+			"Unable to identify the caller",
+			"An unexpected error occurred when getting the caller's AWS identity, to grant it the use of the KMS key.\n\n"+
+				err.Error(),
+		)
+		return diags
+	}
+	principal := dynamodb.GranteePrincipal(caller)
+	granted, err := client.GrantKeyAccess(ctx, principal)
+	if err != nil {
+		missing, listErr := client.MissingKeyGrants(ctx, principal)
+		if listErr != nil {
+			missing = dynamodb.KeyOperations
+		}
+		diags.AddAttributeError(
+			path.Root(providerAttrKeyGrants),
+			"Unable to grant access to the KMS key",
+			fmt.Sprintf("The provider's identity, %s, could not grant itself the use of the KMS key %s. "+
+				"Have an administrator run `schemactl grant-key -grantee %s`, or allow it these actions in the key policy: %s.\n\n%s",
+				principal, config.KMSKeyARN.ValueString(), principal,
+				strings.Join(dynamodb.KeyPermissions(missing), ", "), err.Error()),
+		)
+		return diags
+	}
+	if len(granted) > 0 {
+		// grants can take a few minutes to take effect everywhere
+		tflog.Info(ctx, fmt.Sprintf("Granted %s %s on the KMS key", principal, strings.Join(dynamodb.KeyPermissions(granted), ", ")))
+	}
+	return diags
+}
+
+// auditPublishers returns the publishers of audit events to the configured
+// bus and topic, if any.
+func (tree *treeProvider) auditPublishers(ctx context.Context, config treeProviderModel) ([]audit.Publisher, error) {
+	profile := config.AWSProfile.ValueString()
+	region := config.AWSRegion.ValueString()
+	publishers := []audit.Publisher{}
+	if bus := config.AuditBus.ValueString(); bus != "" {
+		publisher, err := audit.NewEventBridgePublisher(ctx, profile, region, bus)
+		if err != nil {
+			return nil, err
+		}
+		publishers = append(publishers, publisher)
+	}
+	if topic := config.AuditTopic.ValueString(); topic != "" {
+		publisher, err := audit.NewSNSPublisher(ctx, profile, region, topic)
+		if err != nil {
+			return nil, err
+		}
+		publishers = append(publishers, publisher)
+	}
+	return publishers, nil
+}
+
 func (tree *treeProvider) DataSources(_ context.Context) []func() datasource.DataSource {
 	return blocks.AllDataSources()
 }
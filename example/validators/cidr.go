@@ -0,0 +1,41 @@
+// Package validators holds attribute validators for the example's custom
+// column types.
+package validators
+
+import (
+	"context"
+	"net"
+
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+)
+
+type cidrValidator struct{}
+
+var _ validator.String = cidrValidator{}
+
+// CIDR validates that a string is an IPv4 or IPv6 CIDR block, such as
+// 10.0.0.0/16.
+func CIDR() validator.String {
+	return cidrValidator{}
+}
+
+func (v cidrValidator) Description(_ context.Context) string {
+	return "value must be a CIDR block"
+}
+
+func (v cidrValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v cidrValidator) ValidateString(_ context.Context, req validator.StringRequest, resp *validator.StringResponse) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
+	}
+	if _, _, err := net.ParseCIDR(req.ConfigValue.ValueString()); err != nil {
+		resp.Diagnostics.AddAttributeError(
+			req.Path,
+			"Invalid CIDR block",
+			err.Error(),
+		)
+	}
+}
@@ -0,0 +1,321 @@
+// Package backends opens storage backends described by URL-like specs, such
+// as dynamodb://tree?region=us-west-2, for the commands that take one, and
+// the provider's backend and the wrappers around it, in their order.
+package backends
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/secrets"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/bolt"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/dynamodb"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/fsjson"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/gitrepo"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/grpcclient"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/httpclient"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/memory"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/postgres"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/sqlite"
+)
+
+// Usage documents the specs Open accepts.
+const Usage = `backends:
+  dynamodb://<table>?region=<region>&profile=<profile>&kms_key_arn=<arn>&tenant=<tenant>
+      &principal=<arn>&read_only_principal=<arn>   (repeatable; the policy of a table it creates)
+      &list_concurrency=<n>   (ranges of IDs to list a type's rows in at once)
+      &retry_jitter=full|equal|decorrelated&retry_base_delay=<duration>&retry_max_delay=<duration>
+      &compact_columns=true   (write columns as one JSON attribute)
+      &shards=<type>:<n>   (repeatable; spread a type's rows across n partition keys)
+      &throttle_rate=<calls/s>&throttle_capacity=<units/s>   (pace calls, slowing when throttled)
+      &http_max_idle_connections=<n>&http_max_connections_per_host=<n>
+      &http_idle_timeout=<duration>&http_keep_alive=<duration>   (tune connections)
+      &endpoint=<url>   (call another endpoint, such as DynamoDB Local's)
+  postgres://<user>:<password>@<host>/<database>?table=<table>&sslmode=<mode>
+      (any other libpq parameters; the table defaults to tree, and is created if missing)
+  sqlite://<path>   (a local file, created if missing, or :memory:; e.g. sqlite://tree.db)
+  bolt://<path>   (a bbolt database file, created if missing; one process at a time)
+  fsjson://<dir>   (a JSON file per row in a directory, created if missing; e.g. fsjson://tree)
+  gitrepo://<dir>?read_only=true&author_name=<name>&author_email=<email>
+      (a YAML file per row in a directory of a git clone, committing and pushing each write)
+  https://<host>?token=<token>   (a storage server, such as storaged; http:// too)
+  grpc://<host>:<port>?cert=<file>&key=<file>&ca=<file>
+      (a gRPC storage service, such as storaged's, over mutual TLS; &insecure=true for none)
+      https:// and grpc:// take &retry_jitter, &retry_base_delay and &retry_max_delay too
+  memory://    (empty, in-process; for trying commands out)
+
+Any parameter may instead refer to a secret in AWS Secrets Manager or SSM
+Parameter Store, as secretsmanager:<name>[#<key>], ssm:<name>, or by ARN.
+`
+
+// Open connects to the storage backend the spec describes.
+func Open(ctx context.Context, spec string) (storage.RowStorer, error) {
+	if spec == "" {
+		return nil, fmt.Errorf("a backend is required")
+	}
+	// a SQLite path isn't a URL's host and path, as with sqlite://:memory:,
+	// so it's passed to SQLite as written, parameters and all, and other
+	// files' paths likewise
+	if path, ok := strings.CutPrefix(spec, "sqlite://"); ok {
+		return sqlite.NewClient(ctx, path)
+	}
+	if dir, ok := strings.CutPrefix(spec, "fsjson://"); ok {
+		return fsjson.NewClient(ctx, dir)
+	}
+	if path, ok := strings.CutPrefix(spec, "bolt://"); ok {
+		return bolt.NewClient(ctx, path)
+	}
+	if rest, ok := strings.CutPrefix(spec, "gitrepo://"); ok {
+		dir, rawQuery, _ := strings.Cut(rest, "?")
+		query, err := url.ParseQuery(rawQuery)
+		if err != nil {
+			return nil, fmt.Errorf("invalid backend %q: %w", spec, err)
+		}
+		opts := []gitrepo.Option{}
+		if query.Get("read_only") == "true" {
+			opts = append(opts, gitrepo.WithReadOnly())
+		}
+		if query.Has("author_name") || query.Has("author_email") {
+			opts = append(opts, gitrepo.WithAuthor(query.Get("author_name"), query.Get("author_email")))
+		}
+		return gitrepo.NewClient(ctx, dir, opts...)
+	}
+	u, err := url.Parse(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid backend %q: %w", spec, err)
+	}
+	query, err := resolveSecrets(ctx, u.Query())
+	if err != nil {
+		return nil, fmt.Errorf("invalid backend %q: %w", spec, err)
+	}
+
+	switch u.Scheme {
+	case "dynamodb":
+		if u.Host == "" {
+			return nil, fmt.Errorf("invalid backend %q: a table name is required, as in dynamodb://<table>", spec)
+		}
+		opts := []dynamodb.Option{}
+		if tenant := query.Get("tenant"); tenant != "" {
+			opts = append(opts, dynamodb.WithTenant(tenant))
+		}
+		if endpoint := query.Get("endpoint"); endpoint != "" {
+			opts = append(opts, dynamodb.WithEndpoint(endpoint))
+		}
+		if len(query["principal"]) > 0 || len(query["read_only_principal"]) > 0 {
+			opts = append(opts, dynamodb.WithTablePolicy(query["principal"], query["read_only_principal"]))
+		}
+		if n := query.Get("list_concurrency"); n != "" {
+			concurrency, err := strconv.Atoi(n)
+			if err != nil || concurrency < 1 {
+				return nil, fmt.Errorf("invalid backend %q: list_concurrency must be a positive number", spec)
+			}
+			opts = append(opts, dynamodb.WithListConcurrency(concurrency))
+		}
+		if query.Get("compact_columns") == "true" {
+			opts = append(opts, dynamodb.WithCompactColumns())
+		}
+		if len(query["shards"]) > 0 {
+			shards, err := parseShards(query["shards"])
+			if err != nil {
+				return nil, fmt.Errorf("invalid backend %q: %w", spec, err)
+			}
+			opts = append(opts, dynamodb.WithShardedTypes(shards))
+		}
+		if query.Has("throttle_rate") {
+			throttle, err := parseThrottle(query)
+			if err != nil {
+				return nil, fmt.Errorf("invalid backend %q: %w", spec, err)
+			}
+			opts = append(opts, dynamodb.WithAdaptiveThrottling(throttle))
+		}
+		if query.Has("http_max_idle_connections") || query.Has("http_max_connections_per_host") || query.Has("http_idle_timeout") || query.Has("http_keep_alive") {
+			transport, err := parseTransport(query)
+			if err != nil {
+				return nil, fmt.Errorf("invalid backend %q: %w", spec, err)
+			}
+			opts = append(opts, dynamodb.WithHTTPTransport(transport))
+		}
+		if query.Has("retry_jitter") || query.Has("retry_base_delay") || query.Has("retry_max_delay") {
+			backoff, err := parseBackoff(query)
+			if err != nil {
+				return nil, fmt.Errorf("invalid backend %q: %w", spec, err)
+			}
+			opts = append(opts, dynamodb.WithBackoff(dynamodb.Backoff(backoff)))
+		}
+		return dynamodb.NewClient(ctx, query.Get("profile"), query.Get("region"), u.Host, query.Get("kms_key_arn"), opts...)
+	case "postgres", "postgresql":
+		table := query.Get("table")
+		if table == "" {
+			table = "tree"
+		}
+		// the rest of the query is PostgreSQL's, secrets resolved
+		query.Del("table")
+		dsn := *u
+		dsn.RawQuery = query.Encode()
+		return postgres.NewClient(ctx, dsn.String(), table)
+	case "http", "https":
+		// the token, secret resolved, is the client's to send, not part of
+		// the server's URL
+		server := *u
+		server.RawQuery = ""
+		client, err := httpclient.NewClient(server.String(), query.Get("token"))
+		if err != nil {
+			return nil, err
+		}
+		return withRetry(spec, client, query)
+	case "grpc":
+		if u.Host == "" {
+			return nil, fmt.Errorf("invalid backend %q: a host is required, as in grpc://<host>:<port>", spec)
+		}
+		var tlsConfig *tls.Config
+		if query.Get("insecure") != "true" {
+			tlsConfig, err = grpcclient.ClientTLS(query.Get("cert"), query.Get("key"), query.Get("ca"))
+			if err != nil {
+				return nil, fmt.Errorf("invalid backend %q: %w", spec, err)
+			}
+		}
+		client, err := grpcclient.NewClient(u.Host, tlsConfig)
+		if err != nil {
+			return nil, err
+		}
+		return withRetry(spec, client, query)
+	case "memory":
+		return memory.NewClient(), nil
+	}
+	return nil, fmt.Errorf("unknown backend %q\n\n%s", u.Scheme, Usage)
+}
+
+// withRetry wraps the client of a storage server to retry throttled
+// requests, which it doesn't on its own, as the spec's retry parameters say.
+func withRetry(spec string, client storage.RowStorer, query url.Values) (storage.RowStorer, error) {
+	backoff, err := parseBackoff(query)
+	if err != nil {
+		return nil, fmt.Errorf("invalid backend %q: %w", spec, err)
+	}
+	return storage.WithRetry(client, storage.RetryPolicy{Backoff: backoff}), nil
+}
+
+// parseBackoff reads the retry parameters of a backend. Those left out take
+// the defaults.
+func parseBackoff(query url.Values) (storage.Backoff, error) {
+	backoff := storage.Backoff{}
+	if name := query.Get("retry_jitter"); name != "" {
+		jitter, err := storage.ParseJitter(name)
+		if err != nil {
+			return backoff, err
+		}
+		backoff.Jitter = jitter
+	}
+	for param, delay := range map[string]*time.Duration{"retry_base_delay": &backoff.Base, "retry_max_delay": &backoff.Max} {
+		if value := query.Get(param); value != "" {
+			d, err := time.ParseDuration(value)
+			if err != nil || d <= 0 {
+				return backoff, fmt.Errorf("%s must be a positive duration, such as 100ms", param)
+			}
+			*delay = d
+		}
+	}
+	return backoff, nil
+}
+
+// parseShards reads the shards parameters of a DynamoDB backend, each a row
+// type and its number of shards.
+func parseShards(values []string) (map[string]int, error) {
+	shards := map[string]int{}
+	for _, value := range values {
+		rowType, count, ok := strings.Cut(value, ":")
+		n, err := strconv.Atoi(count)
+		if !ok || rowType == "" || err != nil || n < 1 {
+			return nil, fmt.Errorf("shards must be a row type and a positive number, as in widget:8, not %q", value)
+		}
+		shards[rowType] = n
+	}
+	return shards, nil
+}
+
+// parseThrottle reads the throttle parameters of a DynamoDB backend.
+func parseThrottle(query url.Values) (dynamodb.Throttle, error) {
+	throttle := dynamodb.Throttle{}
+	rate, err := strconv.ParseFloat(query.Get("throttle_rate"), 64)
+	if err != nil || rate < 1 {
+		return throttle, fmt.Errorf("throttle_rate must be at least 1 call a second")
+	}
+	throttle.Rate = rate
+	if value := query.Get("throttle_capacity"); value != "" {
+		capacity, err := strconv.ParseFloat(value, 64)
+		if err != nil || capacity <= 0 {
+			return throttle, fmt.Errorf("throttle_capacity must be a positive number of capacity units a second")
+		}
+		throttle.Capacity = capacity
+	}
+	return throttle, nil
+}
+
+// parseTransport reads the connection parameters of a DynamoDB backend. Those
+// left out keep the AWS SDK's defaults.
+func parseTransport(query url.Values) (dynamodb.Transport, error) {
+	transport := dynamodb.Transport{}
+	for param, n := range map[string]*int{"http_max_idle_connections": &transport.MaxIdleConns, "http_max_connections_per_host": &transport.MaxConnsPerHost} {
+		if value := query.Get(param); value != "" {
+			conns, err := strconv.Atoi(value)
+			if err != nil || conns < 1 {
+				return transport, fmt.Errorf("%s must be a positive number", param)
+			}
+			*n = conns
+		}
+	}
+	for param, d := range map[string]*time.Duration{"http_idle_timeout": &transport.IdleTimeout, "http_keep_alive": &transport.KeepAlive} {
+		if value := query.Get(param); value != "" {
+			duration, err := time.ParseDuration(value)
+			if err != nil || duration <= 0 {
+				return transport, fmt.Errorf("%s must be a positive duration, such as 30s", param)
+			}
+			*d = duration
+		}
+	}
+	return transport, nil
+}
+
+// resolveSecrets replaces the backend parameters that refer to secrets with
+// the secrets. Secrets are fetched with the backend's own AWS profile and
+// region, if it has them.
+func resolveSecrets(ctx context.Context, query url.Values) (url.Values, error) {
+	var resolver *secrets.Resolver
+	for name, values := range query {
+		for i, value := range values {
+			if !secrets.IsReference(value) {
+				continue
+			}
+			if resolver == nil {
+				var err error
+				resolver, err = secrets.NewResolver(ctx, query.Get("profile"), query.Get("region"))
+				if err != nil {
+					return nil, err
+				}
+			}
+			secret, err := resolver.Resolve(ctx, value)
+			if err != nil {
+				return nil, err
+			}
+			query[name][i] = secret
+		}
+	}
+	return query, nil
+}
+
+// AWSConfig returns the AWS profile and region of a DynamoDB backend spec,
+// for the other AWS clients a command needs. They are empty for other
+// backends, which leaves the AWS defaults.
+func AWSConfig(spec string) (profile, region string) {
+	u, err := url.Parse(spec)
+	if err != nil || u.Scheme != "dynamodb" {
+		return "", ""
+	}
+	return u.Query().Get("profile"), u.Query().Get("region")
+}
@@ -0,0 +1,198 @@
+package backends
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/attribution"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/audit"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/breaker"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/cache"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/deadline"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/dynamodb"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/encryption"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/grpcclient"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/httpclient"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/integrity"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/limits"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/metrics"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/mirror"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/policy"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/profiling"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/ratelimit"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/readonly"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/slowlog"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/split"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/sqlite"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/tracing"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Backend describes the backend the provider stores rows in: SQLite, a
+// storage server, or, if neither is set, DynamoDB.
+type Backend struct {
+	SQLitePath string
+	// StorageURL is a gRPC storage service's grpc:// URL, or an HTTP storage
+	// server's
+	StorageURL   string
+	StorageToken string
+	// ClientCert, ClientKey, and CA are the files a gRPC storage service's
+	// mutual TLS is configured with
+	ClientCert string
+	ClientKey  string
+	CA         string
+
+	Profile   string
+	Region    string
+	Table     string
+	KMSKeyARN string
+	DynamoDB  []dynamodb.Option
+}
+
+// NewBackend connects to the backend.
+func NewBackend(ctx context.Context, backend Backend) (storage.RowStorer, error) {
+	switch {
+	case backend.SQLitePath != "":
+		return sqlite.NewClient(ctx, backend.SQLitePath)
+	case strings.HasPrefix(backend.StorageURL, "grpc://"):
+		tlsConfig, err := grpcclient.ClientTLS(backend.ClientCert, backend.ClientKey, backend.CA)
+		if err != nil {
+			return nil, err
+		}
+		return grpcclient.NewClient(strings.Trim(strings.TrimPrefix(backend.StorageURL, "grpc://"), "/"), tlsConfig)
+	case backend.StorageURL != "":
+		return httpclient.NewClient(backend.StorageURL, backend.StorageToken)
+	}
+	return dynamodb.NewClient(ctx, backend.Profile, backend.Region, backend.Table, backend.KMSKeyARN, backend.DynamoDB...)
+}
+
+// Stack describes the wrappers around a backend. Wrappers whose fields are
+// left zero are left out, except profiling, which costs next to nothing
+// unless a profile is being taken.
+type Stack struct {
+	// Rates paces operations as they reach the backend
+	Rates *ratelimit.Limits
+	// Retry retries the operations of a backend that doesn't retry on its
+	// own, as a storage server's clients don't
+	Retry *storage.RetryPolicy
+	// Reads is a backend to read rows from before the backend
+	Reads storage.RowStorer
+	// Shadow is a backend to mirror the backend's writes to
+	Shadow storage.RowStorer
+
+	BreakerFailures int
+	BreakerCooldown time.Duration
+
+	MaxColumnBytes int
+	MaxRowBytes    int
+
+	Signer            integrity.Signer
+	RequireSignatures bool
+
+	Keys      encryption.KeyService
+	Encrypted []string
+
+	CacheTTL  time.Duration
+	CacheSize int
+
+	// Actor is recorded as the creator and updater of the rows written
+	Actor string
+	Rules []policy.Rule
+	// ParentTypes are the types of each row type's parents, by which the
+	// rules' subtrees are found
+	ParentTypes storage.ParentTypes
+
+	Tracer      trace.TracerProvider
+	TraceParent trace.SpanContext
+	// SlowOperation is the threshold above which operations are logged
+	SlowOperation time.Duration
+	Recorder      metrics.Recorder
+	// Audit are the publishers of audit events, the first innermost
+	Audit []audit.Publisher
+
+	Timeouts deadline.Timeouts
+	ReadOnly bool
+}
+
+// Wrap wraps the backend in the wrappers of the stack, from the backend out:
+//
+//	ratelimit, retry, split, mirror, breaker, limits, integrity, encryption,
+//	cache, attribution, policy, tracing, slowlog, metrics, profiling, audit,
+//	deadline, readonly
+//
+// It fails only if the stack's rules are invalid.
+func Wrap(client storage.RowStorer, stack Stack) (storage.RowStorer, error) {
+	// operations are paced as they reach the backend, retries included
+	if stack.Rates != nil {
+		client = ratelimit.NewStorer(client, *stack.Rates)
+	}
+	if stack.Retry != nil {
+		client = storage.WithRetry(client, *stack.Retry)
+	}
+	// reads from a read backend aren't paced or retried as the backend's are
+	if stack.Reads != nil {
+		client = split.NewStorer(client, stack.Reads)
+	}
+	// the shadow mirrors what reaches the backend, once retried
+	if stack.Shadow != nil {
+		client = mirror.NewStorer(client, stack.Shadow)
+	}
+	// the breaker counts operations once they've been retried, and timed
+	// out by the deadlines around it
+	if stack.BreakerFailures > 0 {
+		client = breaker.NewStorer(client, stack.BreakerFailures, stack.BreakerCooldown)
+	}
+	// sizes are limited as stored, signatures and ciphertexts included, so
+	// limits wrap the backend before signing and encryption do
+	if stack.MaxColumnBytes > 0 || stack.MaxRowBytes > 0 {
+		client = limits.NewStorer(client, stack.MaxColumnBytes, stack.MaxRowBytes)
+	}
+	// rows are signed as stored, so signing wraps the backend before
+	// encryption does
+	if stack.Signer != nil {
+		client = integrity.NewStorer(client, stack.Signer, stack.RequireSignatures)
+	}
+	if len(stack.Encrypted) > 0 {
+		client = encryption.NewStorer(client, stack.Keys, stack.Encrypted)
+	}
+	// rows are cached as read, checked and decrypted, so reading them again
+	// calls neither the backend nor KMS
+	if stack.CacheTTL > 0 {
+		client = cache.NewStorer(client, stack.CacheTTL, stack.CacheSize)
+	}
+	if stack.Actor != "" {
+		client = attribution.NewStorer(client, stack.Actor)
+	}
+	// the policy sees rows as the provider does, and denials are traced and
+	// measured like other errors
+	if len(stack.Rules) > 0 {
+		var err error
+		client, err = policy.NewStorer(client, stack.Rules, stack.ParentTypes)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if stack.Tracer != nil {
+		client = tracing.NewStorer(client, stack.Tracer, stack.TraceParent)
+	}
+	if stack.SlowOperation > 0 {
+		client = slowlog.NewStorer(client, stack.SlowOperation)
+	}
+	if stack.Recorder != nil {
+		client = metrics.NewStorer(client, stack.Recorder)
+	}
+	client = profiling.NewStorer(client)
+	for _, publisher := range stack.Audit {
+		client = audit.NewStorer(client, publisher)
+	}
+	if stack.Timeouts.Read > 0 || stack.Timeouts.Write > 0 {
+		client = deadline.NewStorer(client, stack.Timeouts)
+	}
+	// refuse writes before any other wrapper does work for them
+	if stack.ReadOnly {
+		client = readonly.NewStorer(client)
+	}
+	return client, nil
+}
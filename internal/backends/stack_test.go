@@ -0,0 +1,311 @@
+package backends_test
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spilliams/tree-terraform-provider/internal/backends"
+	"github.com/spilliams/tree-terraform-provider/pkg/dataset"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/audit"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/deadline"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/encryption"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/integrity"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/memory"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/policy"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/ratelimit"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// discard publishes events nowhere.
+type discard struct{}
+
+func (discard) Publish(context.Context, audit.Event) error { return nil }
+
+// discardMetrics records measurements nowhere.
+type discardMetrics struct{}
+
+func (discardMetrics) ObserveOperation(string, time.Duration, error) {}
+func (discardMetrics) ObserveThrottle(string)                        {}
+func (discardMetrics) ObserveItemSize(string, int)                   {}
+
+// layers names the types of the storers from client in, through Unwrap.
+func layers(client storage.RowStorer) []string {
+	names := []string{}
+	for {
+		names = append(names, fmt.Sprintf("%T", client))
+		wrapper, ok := client.(storage.Wrapper)
+		if !ok {
+			return names
+		}
+		client = wrapper.Unwrap()
+	}
+}
+
+func TestWrapOrder(t *testing.T) {
+	keys, err := encryption.NewLocalKeys(make([]byte, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := backends.Wrap(memory.NewClient(), backends.Stack{
+		Rates:           &ratelimit.Limits{Read: ratelimit.Limit{Rate: 100}},
+		Retry:           &storage.RetryPolicy{},
+		Reads:           memory.NewClient(),
+		Shadow:          memory.NewClient(),
+		BreakerFailures: 5,
+		MaxRowBytes:     1024,
+		Signer:          integrity.NewHMACSigner(make([]byte, 32)),
+		Keys:            keys,
+		Encrypted:       []string{"owner"},
+		CacheTTL:        time.Minute,
+		CacheSize:       10,
+		Actor:           "tester",
+		Rules:           []policy.Rule{{Effect: policy.Allow}},
+		Tracer:          noop.NewTracerProvider(),
+		SlowOperation:   time.Second,
+		Recorder:        discardMetrics{},
+		Audit:           []audit.Publisher{discard{}, discard{}},
+		Timeouts:        deadline.Timeouts{Read: time.Minute},
+		ReadOnly:        true,
+	})
+	if err != nil {
+		t.Fatalf("Wrap: %s", err)
+	}
+	want := []string{
+		"*readonly.Storer",
+		"*deadline.Storer",
+		"*audit.Storer",
+		"*audit.Storer",
+		"*profiling.Storer",
+		"*metrics.Storer",
+		"*slowlog.Storer",
+		"*tracing.Storer",
+		"*policy.Storer",
+		"*attribution.Storer",
+		"*cache.Storer",
+		"*encryption.Storer",
+		"*integrity.Storer",
+		"*limits.Storer",
+		"*breaker.Storer",
+		"*mirror.Storer",
+		"*split.Storer",
+		"*storage.retryStorer",
+		"*ratelimit.Storer",
+		"*memory.Client",
+	}
+	if got := layers(client); !slices.Equal(got, want) {
+		t.Errorf("Wrap stacked\n%v\nnot\n%v", got, want)
+	}
+}
+
+func TestWrapNothing(t *testing.T) {
+	client, err := backends.Wrap(memory.NewClient(), backends.Stack{})
+	if err != nil {
+		t.Fatalf("Wrap: %s", err)
+	}
+	want := []string{"*profiling.Storer", "*memory.Client"}
+	if got := layers(client); !slices.Equal(got, want) {
+		t.Errorf("Wrap of an empty stack stacked %v, not %v", got, want)
+	}
+}
+
+func TestWrapInvalidRules(t *testing.T) {
+	_, err := backends.Wrap(memory.NewClient(), backends.Stack{Rules: []policy.Rule{{Effect: "Maybe"}}})
+	if err == nil {
+		t.Error("Wrap with an invalid rule succeeded")
+	}
+}
+
+// recording is a backend recording the operations that reach it.
+type recording struct {
+	storage.RowStorer
+	mu    sync.Mutex
+	calls []string
+}
+
+func (r *recording) record(op string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, op)
+}
+
+func (r *recording) called(op string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Contains(r.calls, op)
+}
+
+func (r *recording) ScanRows(ctx context.Context, fn func(storage.Row) error) error {
+	r.record("ScanRows")
+	return r.RowStorer.ScanRows(ctx, fn)
+}
+
+func (r *recording) PutRow(ctx context.Context, row storage.Row) error {
+	r.record("PutRow")
+	return r.RowStorer.PutRow(ctx, row)
+}
+
+func (r *recording) PutRows(ctx context.Context, rows []storage.Row) error {
+	r.record("PutRows")
+	for _, row := range rows {
+		if err := r.RowStorer.PutRow(ctx, row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *recording) UpdateColumn(ctx context.Context, rowType, rowID, columnName string, columnValue interface{}) error {
+	r.record("UpdateColumn")
+	return r.RowStorer.UpdateColumn(ctx, rowType, rowID, columnName, columnValue)
+}
+
+func (r *recording) PatchColumns(ctx context.Context, patches []storage.ColumnPatch) error {
+	r.record("PatchColumns")
+	return storage.PatchColumns(ctx, r.RowStorer, patches)
+}
+
+func (r *recording) ListRows(ctx context.Context, rowType, labelFilter, parentIDFilter string) ([]storage.Row, error) {
+	r.record("ListRows")
+	return r.RowStorer.ListRows(ctx, rowType, labelFilter, parentIDFilter)
+}
+
+// IterRows returns the rows a page of one at a time, so that wrappers read
+// several pages.
+func (r *recording) IterRows(ctx context.Context, rowType, labelFilter, parentIDFilter string) *storage.Iter[storage.Row] {
+	r.record("IterRows")
+	var rows []storage.Row
+	return storage.NewIter(ctx, func(ctx context.Context) ([]storage.Row, bool, error) {
+		if rows == nil {
+			var err error
+			if rows, err = r.RowStorer.ListRows(ctx, rowType, labelFilter, parentIDFilter); err != nil || len(rows) == 0 {
+				return nil, false, err
+			}
+		}
+		page := rows[:1]
+		rows = rows[1:]
+		return page, len(rows) > 0, nil
+	})
+}
+
+func (r *recording) ScanChildren(ctx context.Context, parentID string, fn func(storage.Row) error) error {
+	r.record("ScanChildren")
+	return storage.ScanChildren(ctx, r.RowStorer, parentID, fn)
+}
+
+// TestWrapForwards checks that each of the optional operations of the
+// backend reaches it through every wrapper, rather than the wrappers falling
+// back to the operations they're made of.
+func TestWrapForwards(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		op       string
+		call     func(storer storage.RowStorer, org storage.Row) error
+		fallback string
+	}{
+		{"ScanChildren", func(storer storage.RowStorer, org storage.Row) error {
+			children := 0
+			err := storage.ScanChildren(ctx, storer, org.ID(), func(storage.Row) error {
+				children++
+				return nil
+			})
+			if err == nil && children != 1 {
+				err = fmt.Errorf("scanned %d children, not 1", children)
+			}
+			return err
+		}, "ScanRows"},
+		{"PutRows", func(storer storage.RowStorer, org storage.Row) error {
+			team := &dataset.Record{RowType: "team", RowID: "team-ops", RowLabel: "ops", RowParentID: org.ID()}
+			if err := storage.PutRows(ctx, storer, []storage.Row{org, team}); err != nil {
+				return err
+			}
+			_, err := storer.GetRowByID(ctx, "team", team.ID())
+			return err
+		}, "PutRow"},
+		{"PatchColumns", func(storer storage.RowStorer, org storage.Row) error {
+			patches := []storage.ColumnPatch{{Type: "org", ID: org.ID(), Columns: map[string]interface{}{"owner": "ops", "secret": "s3cret"}}}
+			if err := storage.PatchColumns(ctx, storer, patches); err != nil {
+				return err
+			}
+			got, err := storer.GetRowByID(ctx, "org", org.ID())
+			if err == nil && (got.Columns()["owner"] != "ops" || got.Columns()["secret"] != "s3cret") {
+				err = fmt.Errorf("read back columns %v", got.Columns())
+			}
+			return err
+		}, "UpdateColumn"},
+		{"IterRows", func(storer storage.RowStorer, org storage.Row) error {
+			for _, label := range []string{"ops", "qa"} {
+				if _, err := storer.CreateChild(ctx, "team", label, "org", org.ID(), nil); err != nil {
+					return err
+				}
+			}
+			teams, err := storage.IterRows(ctx, storer, "team", "", org.ID()).All()
+			if err == nil && len(teams) != 3 {
+				err = fmt.Errorf("iterated %d teams, not 3", len(teams))
+			}
+			return err
+		}, "ListRows"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.op, func(t *testing.T) {
+			backend := &recording{RowStorer: memory.NewClient()}
+			org, err := backend.CreateRow(ctx, "org", "acme")
+			if err != nil {
+				t.Fatalf("CreateRow: %s", err)
+			}
+			if _, err := backend.CreateChild(ctx, "team", "dev", "org", org.ID(), nil); err != nil {
+				t.Fatalf("CreateChild: %s", err)
+			}
+			client := wrapAll(t, backend)
+			backend.calls = nil
+
+			if err := tt.call(client, org); err != nil {
+				t.Fatalf("%s: %s", tt.op, err)
+			}
+			if !backend.called(tt.op) {
+				t.Errorf("%s didn't reach the backend, which was called with %v", tt.op, backend.calls)
+			}
+			if backend.called(tt.fallback) {
+				t.Errorf("%s fell back to %s", tt.op, tt.fallback)
+			}
+		})
+	}
+}
+
+// wrapAll wraps the backend in every wrapper that lets writes through, with
+// the backend as its read backend too, so that reads also reach it.
+func wrapAll(t *testing.T, backend storage.RowStorer) storage.RowStorer {
+	t.Helper()
+	keys, err := encryption.NewLocalKeys(make([]byte, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := backends.Wrap(backend, backends.Stack{
+		Rates:           &ratelimit.Limits{Read: ratelimit.Limit{Rate: 1000}, Write: ratelimit.Limit{Rate: 1000}},
+		Retry:           &storage.RetryPolicy{},
+		Reads:           backend,
+		Shadow:          memory.NewClient(),
+		BreakerFailures: 5,
+		MaxRowBytes:     1024,
+		Signer:          integrity.NewHMACSigner(make([]byte, 32)),
+		Keys:            keys,
+		Encrypted:       []string{"secret"},
+		CacheTTL:        time.Minute,
+		CacheSize:       10,
+		Actor:           "tester",
+		Rules:           []policy.Rule{{Effect: policy.Allow}},
+		Tracer:          noop.NewTracerProvider(),
+		SlowOperation:   time.Second,
+		Recorder:        discardMetrics{},
+		Audit:           []audit.Publisher{discard{}},
+		Timeouts:        deadline.Timeouts{Read: time.Minute, Write: time.Minute},
+	})
+	if err != nil {
+		t.Fatalf("Wrap: %s", err)
+	}
+	return client
+}
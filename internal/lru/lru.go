@@ -0,0 +1,212 @@
+// Package lru keeps rows in memory, evicting the least recently used, for
+// storage clients and wrappers that read the same rows again and again.
+package lru
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+// Cache is a least-recently-used cache of rows, found by type and ID, by
+// parent and label, or by type and label if the caller found them by it. It
+// hands out copies, so that callers changing a row's columns don't change the
+// cached one. A nil Cache caches nothing.
+type Cache struct {
+	size int
+	ttl  time.Duration
+
+	mu      sync.Mutex
+	order   *list.List
+	byID    map[key]*list.Element
+	byLabel map[key]*list.Element
+	byChild map[key]*list.Element
+}
+
+// New returns a cache of up to size rows, or any number if size is 0, each
+// kept for ttl, or until it's evicted if ttl is 0.
+func New(size int, ttl time.Duration) *Cache {
+	return &Cache{
+		size:    size,
+		ttl:     ttl,
+		order:   list.New(),
+		byID:    map[key]*list.Element{},
+		byLabel: map[key]*list.Element{},
+		byChild: map[key]*list.Element{},
+	}
+}
+
+type key struct {
+	scope string
+	name  string
+}
+
+type entry struct {
+	row     *row
+	expires time.Time
+	// labelKey is the entry's key in byLabel, if GetRow found it
+	labelKey *key
+}
+
+// childKey is the key of a child row in byChild: its parent's ID, and its
+// label, which is unique among the parent's children.
+func childKey(r storage.Row) key {
+	return key{r.ParentID(), r.Label()}
+}
+
+// Size returns how many rows the cache keeps at most, or 0 if any number.
+func (cache *Cache) Size() int {
+	if cache == nil {
+		return 0
+	}
+	return cache.size
+}
+
+// GetByID returns the row cached with the type and ID.
+func (cache *Cache) GetByID(rowType, id string) (storage.Row, bool) {
+	if cache == nil {
+		return nil, false
+	}
+	return cache.get(cache.byID, key{rowType, id})
+}
+
+// GetByLabel returns the row PutLabeled cached with the type and label.
+func (cache *Cache) GetByLabel(rowType, label string) (storage.Row, bool) {
+	if cache == nil {
+		return nil, false
+	}
+	return cache.get(cache.byLabel, key{rowType, label})
+}
+
+// GetChild returns the child row cached with the parent and label.
+func (cache *Cache) GetChild(parentID, label string) (storage.Row, bool) {
+	if cache == nil {
+		return nil, false
+	}
+	return cache.get(cache.byChild, key{parentID, label})
+}
+
+func (cache *Cache) get(index map[key]*list.Element, k key) (storage.Row, bool) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	element, ok := index[k]
+	if !ok {
+		return nil, false
+	}
+	e := element.Value.(*entry)
+	if cache.ttl > 0 && !time.Now().Before(e.expires) {
+		cache.removeElement(element)
+		return nil, false
+	}
+	cache.order.MoveToFront(element)
+	return copyRow(e.row), true
+}
+
+// Put caches the row by its ID, and drops any row cached by its label, since
+// another row may now share it.
+func (cache *Cache) Put(r storage.Row) {
+	if cache == nil {
+		return
+	}
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.remove(key{r.Type(), r.ID()})
+	if element, ok := cache.byLabel[key{r.Type(), r.Label()}]; ok {
+		cache.removeElement(element)
+	}
+	cache.add(&entry{row: copyRow(r)})
+}
+
+// PutLabeled caches the row by its ID, and by its label, which GetRow found
+// it by, and so is unique to it.
+func (cache *Cache) PutLabeled(r storage.Row) {
+	if cache == nil {
+		return
+	}
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	labelKey := key{r.Type(), r.Label()}
+	cache.remove(key{r.Type(), r.ID()})
+	if element, ok := cache.byLabel[labelKey]; ok {
+		cache.removeElement(element)
+	}
+	element := cache.add(&entry{row: copyRow(r), labelKey: &labelKey})
+	cache.byLabel[labelKey] = element
+}
+
+// Invalidate drops the row with the ID, around a write that changes it.
+func (cache *Cache) Invalidate(rowType, id string) {
+	if cache == nil {
+		return
+	}
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.remove(key{rowType, id})
+}
+
+// InvalidateLabel drops the row GetRow found by the label, before a write
+// that may give another row the label.
+func (cache *Cache) InvalidateLabel(rowType, label string) {
+	if cache == nil {
+		return
+	}
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if element, ok := cache.byLabel[key{rowType, label}]; ok {
+		cache.removeElement(element)
+	}
+}
+
+// Purge drops every cached row.
+func (cache *Cache) Purge() {
+	if cache == nil {
+		return
+	}
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.order.Init()
+	cache.byID = map[key]*list.Element{}
+	cache.byLabel = map[key]*list.Element{}
+	cache.byChild = map[key]*list.Element{}
+}
+
+// add caches an entry by its ID, and by its parent and label if it has a
+// parent, evicting the least recently used entry if the cache is full.
+// Callers must hold the lock.
+func (cache *Cache) add(e *entry) *list.Element {
+	e.expires = time.Now().Add(cache.ttl)
+	if e.row.ParentID() != "" {
+		if element, ok := cache.byChild[childKey(e.row)]; ok {
+			cache.removeElement(element)
+		}
+	}
+	element := cache.order.PushFront(e)
+	cache.byID[key{e.row.Type(), e.row.ID()}] = element
+	if e.row.ParentID() != "" {
+		cache.byChild[childKey(e.row)] = element
+	}
+	if cache.size > 0 && cache.order.Len() > cache.size {
+		cache.removeElement(cache.order.Back())
+	}
+	return element
+}
+
+func (cache *Cache) remove(k key) {
+	if element, ok := cache.byID[k]; ok {
+		cache.removeElement(element)
+	}
+}
+
+func (cache *Cache) removeElement(element *list.Element) {
+	e := element.Value.(*entry)
+	cache.order.Remove(element)
+	delete(cache.byID, key{e.row.Type(), e.row.ID()})
+	if e.row.ParentID() != "" && cache.byChild[childKey(e.row)] == element {
+		delete(cache.byChild, childKey(e.row))
+	}
+	if e.labelKey != nil {
+		delete(cache.byLabel, *e.labelKey)
+	}
+}
@@ -0,0 +1,130 @@
+package lru_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spilliams/tree-terraform-provider/internal/lru"
+)
+
+type row struct {
+	rowType, id, label, parentID string
+	columns                      map[string]interface{}
+}
+
+func (r *row) Type() string                    { return r.rowType }
+func (r *row) ID() string                      { return r.id }
+func (r *row) Label() string                   { return r.label }
+func (r *row) ParentID() string                { return r.parentID }
+func (r *row) Columns() map[string]interface{} { return r.columns }
+
+func TestEvictsLeastRecentlyUsed(t *testing.T) {
+	cache := lru.New(2, 0)
+	cache.Put(&row{rowType: "org", id: "a"})
+	cache.Put(&row{rowType: "org", id: "b"})
+	if _, ok := cache.GetByID("org", "a"); !ok {
+		t.Fatal("GetByID didn't find a")
+	}
+	cache.Put(&row{rowType: "org", id: "c"})
+
+	if _, ok := cache.GetByID("org", "b"); ok {
+		t.Error("the least recently used row wasn't evicted")
+	}
+	for _, id := range []string{"a", "c"} {
+		if _, ok := cache.GetByID("org", id); !ok {
+			t.Errorf("%s was evicted", id)
+		}
+	}
+}
+
+func TestIndexes(t *testing.T) {
+	cache := lru.New(0, 0)
+	cache.PutLabeled(&row{rowType: "org", id: "org-1", label: "acme"})
+	cache.Put(&row{rowType: "team", id: "team-1", label: "dev", parentID: "org-1"})
+
+	if got, ok := cache.GetByLabel("org", "acme"); !ok || got.ID() != "org-1" {
+		t.Errorf("GetByLabel returned %v, %t", got, ok)
+	}
+	if got, ok := cache.GetChild("org-1", "dev"); !ok || got.ID() != "team-1" {
+		t.Errorf("GetChild returned %v, %t", got, ok)
+	}
+	// Put doesn't know the label is unique, so doesn't cache by it
+	if _, ok := cache.GetByLabel("team", "dev"); ok {
+		t.Error("GetByLabel found a row Put cached")
+	}
+
+	// a row of the label that replaces another drops it
+	cache.Put(&row{rowType: "org", id: "org-2", label: "acme"})
+	if _, ok := cache.GetByLabel("org", "acme"); ok {
+		t.Error("GetByLabel found the label's old row after another took it")
+	}
+	cache.Put(&row{rowType: "team", id: "team-2", label: "dev", parentID: "org-1"})
+	if got, ok := cache.GetChild("org-1", "dev"); !ok || got.ID() != "team-2" {
+		t.Errorf("GetChild after another child took the label returned %v, %t", got, ok)
+	}
+}
+
+func TestInvalidate(t *testing.T) {
+	cache := lru.New(0, 0)
+	cache.PutLabeled(&row{rowType: "org", id: "org-1", label: "acme"})
+	cache.Put(&row{rowType: "team", id: "team-1", label: "dev", parentID: "org-1"})
+
+	cache.Invalidate("team", "team-1")
+	if _, ok := cache.GetByID("team", "team-1"); ok {
+		t.Error("GetByID found an invalidated row")
+	}
+	if _, ok := cache.GetChild("org-1", "dev"); ok {
+		t.Error("GetChild found an invalidated row")
+	}
+
+	cache.InvalidateLabel("org", "acme")
+	if _, ok := cache.GetByLabel("org", "acme"); ok {
+		t.Error("GetByLabel found an invalidated label")
+	}
+	if _, ok := cache.GetByID("org", "org-1"); ok {
+		t.Error("GetByID found the row of an invalidated label")
+	}
+
+	cache.Put(&row{rowType: "org", id: "org-1"})
+	cache.Purge()
+	if _, ok := cache.GetByID("org", "org-1"); ok {
+		t.Error("GetByID found a row after Purge")
+	}
+}
+
+func TestExpires(t *testing.T) {
+	cache := lru.New(0, 50*time.Millisecond)
+	cache.Put(&row{rowType: "org", id: "org-1"})
+	if _, ok := cache.GetByID("org", "org-1"); !ok {
+		t.Fatal("GetByID didn't find a row before its TTL")
+	}
+	time.Sleep(80 * time.Millisecond)
+	if _, ok := cache.GetByID("org", "org-1"); ok {
+		t.Error("GetByID found a row after its TTL")
+	}
+}
+
+func TestCopies(t *testing.T) {
+	cache := lru.New(0, 0)
+	stored := &row{rowType: "org", id: "org-1", columns: map[string]interface{}{"owner": "ops"}}
+	cache.Put(stored)
+	stored.columns["owner"] = "dev"
+
+	got, _ := cache.GetByID("org", "org-1")
+	got.Columns()["owner"] = "qa"
+	again, _ := cache.GetByID("org", "org-1")
+	if owner := again.Columns()["owner"]; owner != "ops" {
+		t.Errorf("the cached row's owner is %v, changed by a caller", owner)
+	}
+}
+
+func TestNil(t *testing.T) {
+	var cache *lru.Cache
+	cache.Put(&row{rowType: "org", id: "org-1"})
+	if _, ok := cache.GetByID("org", "org-1"); ok {
+		t.Error("a nil cache cached a row")
+	}
+	if cache.Size() != 0 {
+		t.Errorf("a nil cache's size is %d", cache.Size())
+	}
+}
@@ -0,0 +1,36 @@
+package lru
+
+import "github.com/spilliams/tree-terraform-provider/pkg/storage"
+
+type row struct {
+	RowType     string
+	RowID       string
+	RowLabel    string
+	RowParentID string
+	RowColumns  map[string]interface{}
+}
+
+// copyRow copies a row and its columns, so that callers changing a row's
+// columns don't change the cached one.
+func copyRow(r storage.Row) *row {
+	var columns map[string]interface{}
+	if r.Columns() != nil {
+		columns = make(map[string]interface{}, len(r.Columns()))
+		for name, value := range r.Columns() {
+			columns[name] = value
+		}
+	}
+	return &row{
+		RowType:     r.Type(),
+		RowID:       r.ID(),
+		RowLabel:    r.Label(),
+		RowParentID: r.ParentID(),
+		RowColumns:  columns,
+	}
+}
+
+func (r *row) Type() string                    { return r.RowType }
+func (r *row) ID() string                      { return r.RowID }
+func (r *row) Label() string                   { return r.RowLabel }
+func (r *row) ParentID() string                { return r.RowParentID }
+func (r *row) Columns() map[string]interface{} { return r.RowColumns }
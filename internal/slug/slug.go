@@ -1,23 +1,28 @@
 // Package slug provides helper functions that generate random suffix "slugs".
 package slug
 
-import (
-	"fmt"
-	"math/rand"
-)
+import "math/rand/v2"
 
 const letters = "abcdefghijklmnopqrstuvwxyz"
 
-// not terribly fast, but only used when generating new IDs.
-// also not cryptographically secure, but we don't need that.
+// slugLength is how many letters a slug has: 26^10 is plenty for IDs that
+// only need to be unique by type.
+const slugLength = 10
+
+// randSeq returns n random letters. It draws from math/rand/v2's top-level
+// source, which no package can seed, as rand.Seed would math/rand's, switching
+// every caller to a source behind one lock. Not cryptographically secure, but
+// we don't need that.
 func randSeq(n int) string {
 	b := make([]byte, n)
 	for i := range b {
-		b[i] = letters[rand.Int63()%int64(len(letters))]
+		b[i] = letters[rand.IntN(len(letters))]
 	}
 	return string(b)
 }
 
+// Generate returns an ID of the prefix and a random slug. It's safe to call
+// from many goroutines at once.
 func Generate(prefix string) string {
-	return fmt.Sprintf("%s_%s", prefix, randSeq(10))
+	return prefix + "_" + randSeq(slugLength)
 }
@@ -0,0 +1,340 @@
+// Package bench measures a storage backend under a mix of operations like a
+// provider's, so that backends, and versions of one, can be compared, and
+// regressions in the storage layer caught before they slow down applies.
+package bench
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+// The row types a run creates, and deletes again when it's done.
+const (
+	ParentType = "bench_parent"
+	ChildType  = "bench_child"
+)
+
+// Mix weighs the operations of a run: each operation is one of these, with a
+// chance proportional to its weight.
+type Mix struct {
+	GetRowByID    int
+	GetChild      int
+	ListRows      int
+	CreateChild   int
+	UpdateColumns int
+}
+
+// Mixes are the named mixes.
+var Mixes = map[string]Mix{
+	// a plan refreshes each resource, and plural data sources list
+	"plan": {GetRowByID: 70, GetChild: 20, ListRows: 10},
+	// an apply also creates and changes rows, reading them back
+	"apply": {GetRowByID: 40, GetChild: 10, ListRows: 5, CreateChild: 25, UpdateColumns: 20},
+	// an import or seed is mostly writes
+	"write": {GetRowByID: 10, CreateChild: 50, UpdateColumns: 40},
+}
+
+// ParseMix returns the named mix.
+func ParseMix(name string) (Mix, error) {
+	mix, ok := Mixes[name]
+	if !ok {
+		names := make([]string, 0, len(Mixes))
+		for name := range Mixes {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return Mix{}, fmt.Errorf("unknown mix %q: use one of %v", name, names)
+	}
+	return mix, nil
+}
+
+func (mix Mix) total() int {
+	return mix.GetRowByID + mix.GetChild + mix.ListRows + mix.CreateChild + mix.UpdateColumns
+}
+
+// pick chooses an operation by its weight, given n in [0, total).
+func (mix Mix) pick(n int) string {
+	for _, op := range []struct {
+		name   string
+		weight int
+	}{
+		{"GetRowByID", mix.GetRowByID},
+		{"GetChild", mix.GetChild},
+		{"ListRows", mix.ListRows},
+		{"CreateChild", mix.CreateChild},
+		{"UpdateColumns", mix.UpdateColumns},
+	} {
+		if n < op.weight {
+			return op.name
+		}
+		n -= op.weight
+	}
+	return ""
+}
+
+type Options struct {
+	Mix Mix
+	// Operations is how many operations to run, after creating the rows
+	// they work on.
+	Operations int
+	// Concurrency is how many operations run at once, as Terraform's
+	// -parallelism runs resources. It defaults to 10.
+	Concurrency int
+	// Parents is how many parent rows to create, and Children how many
+	// children to create under each, before the operations begin. They
+	// default to 10 and 10.
+	Parents  int
+	Children int
+}
+
+// Stats are the measurements of one operation.
+type Stats struct {
+	Count  int
+	Errors int
+	// the latencies of the operations, successful or not, in order
+	latencies []time.Duration
+}
+
+// Percentile returns the latency that p percent of operations took no
+// longer than.
+func (stats *Stats) Percentile(p float64) time.Duration {
+	if len(stats.latencies) == 0 {
+		return 0
+	}
+	i := int(float64(len(stats.latencies)-1) * p / 100)
+	return stats.latencies[i]
+}
+
+// Mean returns the mean latency.
+func (stats *Stats) Mean() time.Duration {
+	if len(stats.latencies) == 0 {
+		return 0
+	}
+	var total time.Duration
+	for _, latency := range stats.latencies {
+		total += latency
+	}
+	return total / time.Duration(len(stats.latencies))
+}
+
+// Result is what a run measured. The rows it created before its operations
+// aren't counted.
+type Result struct {
+	Elapsed    time.Duration
+	Operations map[string]*Stats
+	// FirstError is the first error an operation returned, to explain
+	// errors in the counts.
+	FirstError error
+}
+
+// Total returns the number of operations.
+func (result *Result) Total() int {
+	total := 0
+	for _, stats := range result.Operations {
+		total += stats.Count
+	}
+	return total
+}
+
+// Throughput returns operations per second.
+func (result *Result) Throughput() float64 {
+	if result.Elapsed == 0 {
+		return 0
+	}
+	return float64(result.Total()) / result.Elapsed.Seconds()
+}
+
+// run is the state of one run: the rows it has created, which its operations
+// choose among.
+type run struct {
+	storer   storage.RowStorer
+	prefix   string
+	mu       sync.Mutex
+	parents  []storage.Row
+	children []storage.Row
+	created  int
+}
+
+func (r *run) addChild(child storage.Row) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.children = append(r.children, child)
+}
+
+func (r *run) nextLabel() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.created++
+	return r.prefix + "-" + strconv.Itoa(r.created)
+}
+
+func (r *run) randomParent(random *rand.Rand) storage.Row {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.parents[random.Intn(len(r.parents))]
+}
+
+func (r *run) randomChild(random *rand.Rand) storage.Row {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.children[random.Intn(len(r.children))]
+}
+
+// columns returns the columns of a bench child: a typical handful, of a
+// typical size.
+func columns(random *rand.Rand) map[string]interface{} {
+	return map[string]interface{}{
+		"description": fmt.Sprintf("benchmark row %d", random.Int()),
+		"owner":       "bench",
+		"tags":        []string{"a", "b", "c"},
+	}
+}
+
+// Run creates parents and children of the bench types, runs the operations
+// of the mix on them, and deletes them again. Labels are unique to the run,
+// so runs against a shared backend don't collide. Rows it fails to delete are
+// left for deleting by hand, and it returns the error.
+func Run(ctx context.Context, storer storage.RowStorer, opts Options) (result *Result, err error) {
+	if opts.Mix.total() <= 0 {
+		return nil, fmt.Errorf("the mix has no operations")
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 10
+	}
+	if opts.Parents <= 0 {
+		opts.Parents = 10
+	}
+	if opts.Children <= 0 {
+		opts.Children = 10
+	}
+
+	r := &run{storer: storer, prefix: "bench-" + strconv.FormatInt(time.Now().UnixNano(), 36)}
+	defer func() {
+		// clean up after a cancelled run too
+		if cleanUpErr := r.cleanUp(context.WithoutCancel(ctx)); cleanUpErr != nil && err == nil {
+			err = fmt.Errorf("deleting the rows of the run, which start with %q: %w", r.prefix, cleanUpErr)
+		}
+	}()
+	if err := r.seed(ctx, opts); err != nil {
+		return nil, fmt.Errorf("creating rows to work on: %w", err)
+	}
+
+	result = &Result{Operations: map[string]*Stats{}}
+	var mu sync.Mutex
+	record := func(op string, latency time.Duration, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		stats, ok := result.Operations[op]
+		if !ok {
+			stats = &Stats{}
+			result.Operations[op] = stats
+		}
+		stats.Count++
+		stats.latencies = append(stats.latencies, latency)
+		if err != nil {
+			stats.Errors++
+			if result.FirstError == nil {
+				result.FirstError = fmt.Errorf("%s: %w", op, err)
+			}
+		}
+	}
+
+	ops := make(chan struct{})
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i := 0; i < opts.Concurrency; i++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			random := rand.New(rand.NewSource(seed))
+			for range ops {
+				op := opts.Mix.pick(random.Intn(opts.Mix.total()))
+				opStart := time.Now()
+				err := r.do(ctx, op, random)
+				record(op, time.Since(opStart), err)
+			}
+		}(start.UnixNano() + int64(i))
+	}
+	for i := 0; i < opts.Operations && ctx.Err() == nil; i++ {
+		ops <- struct{}{}
+	}
+	close(ops)
+	wg.Wait()
+	result.Elapsed = time.Since(start)
+
+	for _, stats := range result.Operations {
+		sort.Slice(stats.latencies, func(i, j int) bool { return stats.latencies[i] < stats.latencies[j] })
+	}
+	return result, ctx.Err()
+}
+
+func (r *run) seed(ctx context.Context, opts Options) error {
+	random := rand.New(rand.NewSource(time.Now().UnixNano()))
+	for i := 0; i < opts.Parents; i++ {
+		parent, err := r.storer.CreateRow(ctx, ParentType, r.nextLabel())
+		if err != nil {
+			return err
+		}
+		r.parents = append(r.parents, parent)
+		for j := 0; j < opts.Children; j++ {
+			child, err := r.storer.CreateChild(ctx, ChildType, r.nextLabel(), ParentType, parent.ID(), columns(random))
+			if err != nil {
+				return err
+			}
+			r.children = append(r.children, child)
+		}
+	}
+	return nil
+}
+
+func (r *run) do(ctx context.Context, op string, random *rand.Rand) error {
+	switch op {
+	case "GetRowByID":
+		child := r.randomChild(random)
+		_, err := r.storer.GetRowByID(ctx, ChildType, child.ID())
+		return err
+	case "GetChild":
+		child := r.randomChild(random)
+		_, err := r.storer.GetChild(ctx, child.Label(), child.ParentID())
+		return err
+	case "ListRows":
+		parent := r.randomParent(random)
+		_, err := r.storer.ListRows(ctx, ChildType, "", parent.ID())
+		return err
+	case "CreateChild":
+		parent := r.randomParent(random)
+		child, err := r.storer.CreateChild(ctx, ChildType, r.nextLabel(), ParentType, parent.ID(), columns(random))
+		if err != nil {
+			return err
+		}
+		r.addChild(child)
+		return nil
+	case "UpdateColumns":
+		child := r.randomChild(random)
+		return r.storer.UpdateColumns(ctx, ChildType, child.ID(), columns(random))
+	}
+	return fmt.Errorf("unknown operation %q", op)
+}
+
+// cleanUp deletes the rows the run created, children first.
+func (r *run) cleanUp(ctx context.Context) error {
+	for _, child := range r.children {
+		if err := r.storer.DeleteRow(ctx, ChildType, "", child.ID()); err != nil && !errors.Is(err, storage.ErrNotFoundRow) {
+			return err
+		}
+	}
+	for _, parent := range r.parents {
+		if err := r.storer.DeleteRow(ctx, ParentType, ChildType, parent.ID()); err != nil && !errors.Is(err, storage.ErrNotFoundRow) {
+			return err
+		}
+	}
+	return nil
+}
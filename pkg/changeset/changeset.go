@@ -0,0 +1,321 @@
+// Package changeset applies a batch of row creations, updates, and deletions
+// described in JSON, after checking the whole batch against the stored rows:
+//
+//	{"changes": [
+//	  {"op": "create", "type": "team", "ref": "payments", "label": "payments",
+//	   "parent_type": "organization", "parent_id": "organization_abcdefghij",
+//	   "columns": {"owners": ["carol"]}},
+//	  {"op": "create", "type": "environment", "label": "production",
+//	   "parent_ref": "payments", "columns": {"cidr": "10.2.0.0/16"}},
+//	  {"op": "update", "type": "team", "id": "team_abcdefghij", "label": "core"},
+//	  {"op": "delete", "type": "environment", "id": "environment_abcdefghij"}
+//	]}
+//
+// A created row can be given a ref, which later changes use in place of its ID
+// (as ref and parent_ref), since the ID isn't known until it is created.
+package changeset
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/dataset"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/memory"
+)
+
+var ErrInvalidChangeset = errors.New("invalid changeset")
+
+type Op string
+
+const (
+	OpCreate Op = "create"
+	OpUpdate Op = "update"
+	OpDelete Op = "delete"
+)
+
+type Changeset struct {
+	Changes []*Change `json:"changes"`
+}
+
+// Change is one write. Updates change only the fields they set; columns, if
+// set, replace all of the row's columns.
+type Change struct {
+	Op   Op     `json:"op"`
+	Type string `json:"type"`
+	// ID is the row to update or delete. Ref may name a row created earlier
+	// in the changeset instead, and names the row a create makes.
+	ID         string                 `json:"id,omitempty"`
+	Ref        string                 `json:"ref,omitempty"`
+	Label      string                 `json:"label,omitempty"`
+	ParentType string                 `json:"parent_type,omitempty"`
+	ParentID   string                 `json:"parent_id,omitempty"`
+	ParentRef  string                 `json:"parent_ref,omitempty"`
+	Columns    map[string]interface{} `json:"columns,omitempty"`
+}
+
+func (change *Change) String() string {
+	target := change.ID
+	if target == "" {
+		target = change.Ref
+	}
+	if target == "" {
+		target = fmt.Sprintf("%q", change.Label)
+	}
+	return fmt.Sprintf("%s %s %s", change.Op, change.Type, target)
+}
+
+// Conflict is a change that can't be applied.
+type Conflict struct {
+	// Index is the change's position in the changeset, from 0.
+	Index  int
+	Change *Change
+	Err    error
+}
+
+func (conflict Conflict) Error() string {
+	return fmt.Sprintf("change %d (%s): %s", conflict.Index+1, conflict.Change, conflict.Err)
+}
+
+func (conflict Conflict) Unwrap() error {
+	return conflict.Err
+}
+
+// Read decodes a changeset, and checks that each change is well-formed.
+func Read(r io.Reader) (*Changeset, error) {
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	changeset := &Changeset{}
+	if err := decoder.Decode(changeset); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidChangeset, err)
+	}
+	refs := map[string]bool{}
+	for i, change := range changeset.Changes {
+		if err := change.validate(refs); err != nil {
+			return nil, fmt.Errorf("%w: change %d: %s", ErrInvalidChangeset, i+1, err)
+		}
+	}
+	return changeset, nil
+}
+
+func (change *Change) validate(refs map[string]bool) error {
+	if change.Type == "" {
+		return fmt.Errorf("type is required")
+	}
+	if change.ParentID != "" && change.ParentRef != "" {
+		return fmt.Errorf("set parent_id or parent_ref, not both")
+	}
+	if change.ParentID != "" && change.ParentType == "" {
+		return fmt.Errorf("parent_id needs parent_type")
+	}
+	if change.ParentRef != "" && !refs[change.ParentRef] {
+		return fmt.Errorf("parent_ref %q isn't the ref of an earlier create", change.ParentRef)
+	}
+	switch change.Op {
+	case OpCreate:
+		if change.Label == "" {
+			return fmt.Errorf("a create needs a label")
+		}
+		if change.ID != "" {
+			return fmt.Errorf("a create can't set an id; the backend chooses it")
+		}
+		if change.Ref != "" {
+			if refs[change.Ref] {
+				return fmt.Errorf("ref %q is used twice", change.Ref)
+			}
+			refs[change.Ref] = true
+		}
+	case OpUpdate, OpDelete:
+		if (change.ID == "") == (change.Ref == "") {
+			return fmt.Errorf("an %s needs an id or a ref", change.Op)
+		}
+		if change.Ref != "" && !refs[change.Ref] {
+			return fmt.Errorf("ref %q isn't the ref of an earlier create", change.Ref)
+		}
+		if change.Op == OpDelete && (change.Label != "" || change.ParentID != "" || change.ParentRef != "" || change.Columns != nil) {
+			return fmt.Errorf("a delete sets only type and id or ref")
+		}
+	default:
+		return fmt.Errorf("unknown op %q: use %s, %s, or %s", change.Op, OpCreate, OpUpdate, OpDelete)
+	}
+	return normalizeColumns(change.Columns)
+}
+
+// normalizeColumns converts JSON arrays of strings to string sets, the only
+// kind of array rows store.
+func normalizeColumns(columns map[string]interface{}) error {
+	for name, value := range columns {
+		switch v := value.(type) {
+		case string:
+		case []interface{}:
+			set := make([]string, len(v))
+			for i, elem := range v {
+				s, ok := elem.(string)
+				if !ok {
+					return fmt.Errorf("column %q has a non-string element", name)
+				}
+				set[i] = s
+			}
+			columns[name] = set
+		default:
+			return fmt.Errorf("column %q is neither a string nor a string set", name)
+		}
+	}
+	return nil
+}
+
+// Validate applies the changeset to an in-memory copy of the stored rows, and
+// returns every change that conflicts with them or with an earlier change.
+// Nothing is written to storer.
+func Validate(ctx context.Context, storer storage.RowStorer, changeset *Changeset) ([]Conflict, error) {
+	scratch := memory.NewClient()
+	if _, err := dataset.Copy(ctx, storer, scratch, nil); err != nil {
+		return nil, err
+	}
+	a := newApplier(scratch)
+	conflicts := []Conflict{}
+	for i, change := range changeset.Changes {
+		if err := a.apply(ctx, change, true); err != nil {
+			conflicts = append(conflicts, Conflict{Index: i, Change: change, Err: err})
+		}
+	}
+	return conflicts, nil
+}
+
+// Apply validates the changeset, and applies it if nothing conflicts. It
+// returns how many changes it applied. If the storer is
+// storage.Transactional, the changes are applied in one transaction, all or
+// none; otherwise, or if they're too many for one, a failure partway, from a
+// write made since validating, leaves the earlier changes applied.
+func Apply(ctx context.Context, storer storage.RowStorer, changeset *Changeset) (int, error) {
+	conflicts, err := Validate(ctx, storer, changeset)
+	if err != nil {
+		return 0, err
+	}
+	if len(conflicts) > 0 {
+		return 0, conflicts[0]
+	}
+	if storage.Transactional(storer) {
+		applied := 0
+		err := storage.WithinTx(ctx, storer, func(tx storage.RowStorer) error {
+			var err error
+			applied, err = applyAll(ctx, tx, changeset)
+			return err
+		})
+		switch {
+		case err == nil:
+			return applied, nil
+		case !errors.Is(err, storage.ErrTxTooLarge):
+			return 0, err
+		}
+	}
+	return applyAll(ctx, storer, changeset)
+}
+
+// applyAll applies the changes one at a time, stopping at the first that
+// fails.
+func applyAll(ctx context.Context, storer storage.RowStorer, changeset *Changeset) (int, error) {
+	a := newApplier(storer)
+	for i, change := range changeset.Changes {
+		if err := a.apply(ctx, change, false); err != nil {
+			return i, Conflict{Index: i, Change: change, Err: err}
+		}
+	}
+	return len(changeset.Changes), nil
+}
+
+type ref struct {
+	rowType string
+	id      string
+}
+
+type applier struct {
+	storer storage.RowStorer
+	refs   map[string]ref
+}
+
+func newApplier(storer storage.RowStorer) *applier {
+	return &applier{storer: storer, refs: map[string]ref{}}
+}
+
+// apply makes one change. Only backends that hold every row, like the
+// in-memory copy, can check a deleted row for children of any type, so
+// checkChildren is set only when validating.
+func (a *applier) apply(ctx context.Context, change *Change, checkChildren bool) error {
+	parentType, parentID := change.ParentType, change.ParentID
+	if change.ParentRef != "" {
+		parent, ok := a.refs[change.ParentRef]
+		if !ok {
+			return fmt.Errorf("parent_ref %q wasn't created", change.ParentRef)
+		}
+		parentType, parentID = parent.rowType, parent.id
+	}
+	id := change.ID
+	if change.Ref != "" && change.Op != OpCreate {
+		target, ok := a.refs[change.Ref]
+		if !ok {
+			return fmt.Errorf("ref %q wasn't created", change.Ref)
+		}
+		id = target.id
+	}
+
+	switch change.Op {
+	case OpCreate:
+		var created storage.Row
+		var err error
+		if parentID == "" {
+			created, err = a.storer.CreateRow(ctx, change.Type, change.Label)
+			if err == nil && change.Columns != nil {
+				err = a.storer.UpdateColumns(ctx, change.Type, created.ID(), change.Columns)
+			}
+		} else {
+			created, err = a.storer.CreateChild(ctx, change.Type, change.Label, parentType, parentID, change.Columns)
+		}
+		if err != nil {
+			return err
+		}
+		if change.Ref != "" {
+			a.refs[change.Ref] = ref{rowType: change.Type, id: created.ID()}
+		}
+		return nil
+
+	case OpUpdate:
+		current, err := a.storer.GetRowByID(ctx, change.Type, id)
+		if err != nil {
+			return err
+		}
+		label := change.Label
+		if label == "" {
+			label = current.Label()
+		}
+		switch {
+		case parentID != "" && parentID != current.ParentID():
+			_, err = a.storer.UpdateChild(ctx, change.Type, id, label, parentType, parentID)
+		case label != current.Label():
+			_, err = a.storer.UpdateRow(ctx, change.Type, id, label)
+		}
+		if err == nil && change.Columns != nil {
+			err = a.storer.UpdateColumns(ctx, change.Type, id, change.Columns)
+		}
+		return err
+
+	case OpDelete:
+		if _, err := a.storer.GetRowByID(ctx, change.Type, id); err != nil {
+			return err
+		}
+		if checkChildren {
+			children, err := storage.FindChildren(ctx, a.storer, id)
+			if err != nil {
+				return err
+			}
+			if len(children) > 0 {
+				return fmt.Errorf("%w: it has %d children", storage.ErrCannotDeleteRow, len(children))
+			}
+		}
+		return a.storer.DeleteRow(ctx, change.Type, "", id)
+	}
+	return fmt.Errorf("unknown op %q", change.Op)
+}
@@ -0,0 +1,96 @@
+package dataset
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+// importBatchSize is how many rows an import writes at once, to backends
+// that can write several in one go.
+const importBatchSize = 100
+
+// importWriter writes the rows of an import, in batches if the storer is a
+// storage.BatchPutter, and reports its progress as writes land.
+type importWriter struct {
+	storer   storage.RowStorer
+	batcher  storage.BatchPutter
+	progress func(int)
+	pending  []storage.Row
+	// pendingKeys are the keys of the pending rows that a lookup could find
+	// them by.
+	pendingKeys map[string]bool
+	// done counts the records handled, and reported those whose writes
+	// have landed.
+	done     int
+	reported int
+}
+
+func newImportWriter(storer storage.RowStorer, progress func(int)) *importWriter {
+	writer := &importWriter{storer: storer, progress: progress, pendingKeys: map[string]bool{}}
+	writer.batcher, _ = storer.(storage.BatchPutter)
+	return writer
+}
+
+// lookupKeys returns the keys an import finds the row by: its type and ID,
+// and its label among the roots of its type or the children of its parent.
+func lookupKeys(row *Record) []string {
+	labelKey := fmt.Sprintf("root %s %s", row.RowType, row.RowLabel)
+	if row.RowParentID != "" {
+		labelKey = fmt.Sprintf("child %s %s", row.RowParentID, row.RowLabel)
+	}
+	return []string{fmt.Sprintf("id %s %s", row.RowType, row.RowID), labelKey}
+}
+
+func (writer *importWriter) put(ctx context.Context, row *Record) error {
+	if writer.batcher == nil {
+		return writer.storer.PutRow(ctx, row)
+	}
+	writer.pending = append(writer.pending, row)
+	for _, key := range lookupKeys(row) {
+		writer.pendingKeys[key] = true
+	}
+	if len(writer.pending) >= importBatchSize {
+		return writer.flush(ctx)
+	}
+	return nil
+}
+
+// flushIfPending writes the pending rows if the row's lookups would find
+// one of them.
+func (writer *importWriter) flushIfPending(ctx context.Context, row *Record) error {
+	for _, key := range lookupKeys(row) {
+		if writer.pendingKeys[key] {
+			return writer.flush(ctx)
+		}
+	}
+	return nil
+}
+
+func (writer *importWriter) flush(ctx context.Context) error {
+	if len(writer.pending) > 0 {
+		if err := writer.batcher.PutRows(ctx, writer.pending); err != nil {
+			return fmt.Errorf("writing a batch of %d rows: %w", len(writer.pending), err)
+		}
+		writer.pending = writer.pending[:0]
+		clear(writer.pendingKeys)
+	}
+	writer.report()
+	return nil
+}
+
+// imported counts a record handled, once its row is written or pending.
+func (writer *importWriter) imported() {
+	writer.done++
+	if len(writer.pending) == 0 {
+		writer.report()
+	}
+}
+
+func (writer *importWriter) report() {
+	if writer.progress != nil && writer.done > writer.reported {
+		writer.reported = writer.done
+		writer.progress(writer.done)
+	}
+}
@@ -0,0 +1,92 @@
+package dataset
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression is how a dataset is compressed, for moving large exports
+// between environments.
+type Compression string
+
+const (
+	CompressionNone Compression = "none"
+	CompressionGzip Compression = "gzip"
+	// CompressionZstd compresses faster than gzip, and smaller.
+	CompressionZstd Compression = "zstd"
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// ParseCompression returns the named compression.
+func ParseCompression(name string) (Compression, error) {
+	switch compression := Compression(name); compression {
+	case CompressionNone, CompressionGzip, CompressionZstd:
+		return compression, nil
+	}
+	return "", fmt.Errorf("unknown compression %q: use %s, %s, or %s", name, CompressionNone, CompressionGzip, CompressionZstd)
+}
+
+// CompressionForPath returns the compression a file's extension names: .gz
+// for gzip, and .zst for zstd.
+func CompressionForPath(path string) Compression {
+	switch filepath.Ext(path) {
+	case ".gz":
+		return CompressionGzip
+	case ".zst":
+		return CompressionZstd
+	}
+	return CompressionNone
+}
+
+// NewCompressor returns a writer compressing what's written to it into w.
+// Closing it flushes the compressed stream, but doesn't close w.
+func NewCompressor(w io.Writer, compression Compression) (io.WriteCloser, error) {
+	switch compression {
+	case CompressionGzip:
+		return gzip.NewWriter(w), nil
+	case CompressionZstd:
+		return zstd.NewWriter(w)
+	case CompressionNone, "":
+		return nopWriteCloser{w}, nil
+	}
+	return nil, fmt.Errorf("unknown compression %q", compression)
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// NewDecompressor returns a reader of r decompressed, finding its compression
+// from its first bytes, so that imports needn't be told it.
+func NewDecompressor(r io.Reader) (io.ReadCloser, error) {
+	buffered := bufio.NewReader(r)
+	magic, err := buffered.Peek(len(zstdMagic))
+	// shorter datasets can't be compressed, and read as they are
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		return gzip.NewReader(buffered)
+	case bytes.HasPrefix(magic, zstdMagic):
+		decoder, err := zstd.NewReader(buffered)
+		if err != nil {
+			return nil, err
+		}
+		return decoder.IOReadCloser(), nil
+	}
+	return io.NopCloser(buffered), nil
+}
@@ -0,0 +1,162 @@
+package dataset
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+type rowKey struct {
+	rowType string
+	id      string
+}
+
+// Copy streams every row from one storage backend to another, keeping IDs,
+// and returns how many it copied. Rows are written as they are read, so
+// progress, if set, is called after each one with the count so far. Rows with
+// the same type and ID in the destination are replaced.
+func Copy(ctx context.Context, from, to storage.RowStorer, progress func(copied int)) (int, error) {
+	copied := 0
+	err := from.ScanRows(ctx, func(row storage.Row) error {
+		if err := to.PutRow(ctx, row); err != nil {
+			return fmt.Errorf("%s %s: %w", row.Type(), row.ID(), err)
+		}
+		copied++
+		if progress != nil {
+			progress(copied)
+		}
+		return nil
+	})
+	return copied, err
+}
+
+// Difference describes how one storage backend's rows differ from another's.
+// Rows are named "type/id".
+type Difference struct {
+	// Missing lists rows of the source that the destination lacks.
+	Missing []string
+	// Changed lists rows whose label, parent, or columns differ.
+	Changed []Change
+	// Extra lists rows only the destination has.
+	Extra []string
+}
+
+// Change describes how a row differs between two storage backends.
+type Change struct {
+	Row string
+	// Fields describes each differing field, as its source value, then its
+	// destination value.
+	Fields []string
+}
+
+func (change Change) String() string {
+	return change.Row + ": " + strings.Join(change.Fields, "; ")
+}
+
+// Empty reports whether the destination has every source row as it is.
+// Extra rows don't count.
+func (diff Difference) Empty() bool {
+	return len(diff.Missing) == 0 && len(diff.Changed) == 0
+}
+
+func (diff Difference) String() string {
+	return fmt.Sprintf("%d missing, %d changed, %d extra", len(diff.Missing), len(diff.Changed), len(diff.Extra))
+}
+
+// Compare scans both storage backends, and returns how the destination's rows
+// differ from the source's. String sets are compared without regard to order,
+// since not every backend keeps it.
+func Compare(ctx context.Context, from, to storage.RowStorer) (Difference, error) {
+	source := map[rowKey]*Record{}
+	err := from.ScanRows(ctx, func(row storage.Row) error {
+		source[rowKey{row.Type(), row.ID()}] = FromRow(row)
+		return nil
+	})
+	if err != nil {
+		return Difference{}, err
+	}
+
+	diff := Difference{}
+	seen := map[rowKey]bool{}
+	err = to.ScanRows(ctx, func(row storage.Row) error {
+		key := rowKey{row.Type(), row.ID()}
+		want, ok := source[key]
+		if !ok {
+			diff.Extra = append(diff.Extra, key.rowType+"/"+key.id)
+			return nil
+		}
+		seen[key] = true
+		if fields := compareRecords(want, FromRow(row)); len(fields) > 0 {
+			diff.Changed = append(diff.Changed, Change{Row: key.rowType + "/" + key.id, Fields: fields})
+		}
+		return nil
+	})
+	if err != nil {
+		return Difference{}, err
+	}
+
+	for key := range source {
+		if !seen[key] {
+			diff.Missing = append(diff.Missing, key.rowType+"/"+key.id)
+		}
+	}
+	sort.Strings(diff.Missing)
+	sort.Strings(diff.Extra)
+	sort.Slice(diff.Changed, func(i, j int) bool { return diff.Changed[i].Row < diff.Changed[j].Row })
+	return diff, nil
+}
+
+// DiffRows describes each field that differs between two rows, as its value
+// in a, then its value in b.
+func DiffRows(a, b storage.Row) []string {
+	return compareRecords(FromRow(a), FromRow(b))
+}
+
+// compareRecords describes each field that differs between two records.
+func compareRecords(a, b *Record) []string {
+	fields := []string{}
+	if a.RowLabel != b.RowLabel {
+		fields = append(fields, fmt.Sprintf("label %q -> %q", a.RowLabel, b.RowLabel))
+	}
+	if a.RowParentID != b.RowParentID {
+		fields = append(fields, fmt.Sprintf("parent_id %q -> %q", a.RowParentID, b.RowParentID))
+	}
+	names := map[string]bool{}
+	for name := range a.RowColumns {
+		names[name] = true
+	}
+	for name := range b.RowColumns {
+		names[name] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+	for _, name := range sorted {
+		av, aok := a.RowColumns[name]
+		bv, bok := b.RowColumns[name]
+		if aok && bok && storage.EqualColumns(map[string]interface{}{name: av}, map[string]interface{}{name: bv}) {
+			continue
+		}
+		fields = append(fields, fmt.Sprintf("%s %s -> %s", name, formatValue(av, aok), formatValue(bv, bok)))
+	}
+	return fields
+}
+
+func formatValue(value interface{}, ok bool) string {
+	if !ok {
+		return "(unset)"
+	}
+	if set, isSet := value.([]string); isSet {
+		quoted := make([]string, len(set))
+		for i, elem := range set {
+			quoted[i] = fmt.Sprintf("%q", elem)
+		}
+		return "[" + strings.Join(quoted, ", ") + "]"
+	}
+	return fmt.Sprintf("%q", fmt.Sprint(value))
+}
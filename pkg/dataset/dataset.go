@@ -0,0 +1,147 @@
+// Package dataset reads and writes rows as NDJSON: one JSON record per line,
+// parents before their children. It is the format of schemactl's export and
+// import, for backups and for copying hierarchies between accounts and
+// backends.
+package dataset
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+var ErrInvalidRecord = errors.New("invalid dataset record")
+
+// Record is one row in a dataset. It implements storage.Row.
+type Record struct {
+	RowType  string `json:"type"`
+	RowID    string `json:"id"`
+	RowLabel string `json:"label"`
+	// RowParentType is the type of the row's parent, so that imports can
+	// find parents that aren't in the dataset.
+	RowParentType string                 `json:"parent_type,omitempty"`
+	RowParentID   string                 `json:"parent_id,omitempty"`
+	RowColumns    map[string]interface{} `json:"columns,omitempty"`
+}
+
+// FromRow copies a storage row into a record. The parent type isn't known
+// from the row alone, so it is left for the caller to fill in.
+func FromRow(row storage.Row) *Record {
+	return &Record{
+		RowType:     row.Type(),
+		RowID:       row.ID(),
+		RowLabel:    row.Label(),
+		RowParentID: row.ParentID(),
+		RowColumns:  row.Columns(),
+	}
+}
+
+func (r *Record) Type() string                    { return r.RowType }
+func (r *Record) ID() string                      { return r.RowID }
+func (r *Record) Label() string                   { return r.RowLabel }
+func (r *Record) ParentID() string                { return r.RowParentID }
+func (r *Record) Columns() map[string]interface{} { return r.RowColumns }
+
+func (r *Record) validate() error {
+	if r.RowType == "" || r.RowID == "" {
+		return fmt.Errorf("%w: type and id are required", ErrInvalidRecord)
+	}
+	if r.RowParentType != "" && r.RowParentID == "" {
+		return fmt.Errorf("%w: %s %s: parent_type is set without parent_id", ErrInvalidRecord, r.RowType, r.RowID)
+	}
+	return nil
+}
+
+// normalizeColumns converts JSON arrays of strings back to string sets, the
+// only kind of array rows store.
+func (r *Record) normalizeColumns() error {
+	for name, value := range r.RowColumns {
+		switch v := value.(type) {
+		case string:
+		case []interface{}:
+			set := make([]string, len(v))
+			for i, elem := range v {
+				s, ok := elem.(string)
+				if !ok {
+					return fmt.Errorf("%w: %s %s: column %q has a non-string element", ErrInvalidRecord, r.RowType, r.RowID, name)
+				}
+				set[i] = s
+			}
+			r.RowColumns[name] = set
+		default:
+			return fmt.Errorf("%w: %s %s: column %q is neither a string nor a string set", ErrInvalidRecord, r.RowType, r.RowID, name)
+		}
+	}
+	return nil
+}
+
+// Writer writes records as NDJSON.
+type Writer struct {
+	encoder *json.Encoder
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{encoder: json.NewEncoder(w)}
+}
+
+func (w *Writer) Write(record *Record) error {
+	return w.encoder.Encode(record)
+}
+
+// Reader reads NDJSON records.
+type Reader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func NewReader(r io.Reader) *Reader {
+	scanner := bufio.NewScanner(r)
+	// rows with large string sets make for long lines
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	return &Reader{scanner: scanner}
+}
+
+// Read returns the next record, or io.EOF at the end of the dataset. Blank
+// lines are skipped.
+func (r *Reader) Read() (*Record, error) {
+	for r.scanner.Scan() {
+		r.line++
+		if len(r.scanner.Bytes()) == 0 {
+			continue
+		}
+		record := &Record{}
+		if err := json.Unmarshal(r.scanner.Bytes(), record); err != nil {
+			return nil, fmt.Errorf("line %d: %w: %s", r.line, ErrInvalidRecord, err)
+		}
+		if err := record.validate(); err != nil {
+			return nil, fmt.Errorf("line %d: %w", r.line, err)
+		}
+		if err := record.normalizeColumns(); err != nil {
+			return nil, fmt.Errorf("line %d: %w", r.line, err)
+		}
+		return record, nil
+	}
+	if err := r.scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+// ReadAll reads every remaining record.
+func (r *Reader) ReadAll() ([]*Record, error) {
+	records := []*Record{}
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+}
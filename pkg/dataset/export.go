@@ -0,0 +1,189 @@
+package dataset
+
+import (
+	"context"
+	"io"
+	"sort"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+// Export writes every row in storage to w, parents before their children, and
+// returns how many it wrote. Rows whose parent no longer exists are written
+// last, without a parent type.
+func Export(ctx context.Context, storer storage.RowStorer, w io.Writer) (int, error) {
+	records := []*Record{}
+	err := storer.ScanRows(ctx, func(row storage.Row) error {
+		records = append(records, FromRow(row))
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	typeOfID := map[string]string{}
+	for _, record := range records {
+		typeOfID[record.RowID] = record.RowType
+	}
+	for _, record := range records {
+		record.RowParentType = typeOfID[record.RowParentID]
+	}
+	Sort(records)
+
+	writer := NewWriter(w)
+	for i, record := range records {
+		if err := writer.Write(record); err != nil {
+			return i, err
+		}
+	}
+	return len(records), nil
+}
+
+// ExportType writes the rows of a type to w, as they're read, a page at a
+// time, and returns how many it wrote. Children are written without their
+// parents' types, which it would take a scan to find.
+func ExportType(ctx context.Context, storer storage.RowStorer, rowType string, w io.Writer) (int, error) {
+	writer := NewWriter(w)
+	written := 0
+	rows := storage.IterRows(ctx, storer, rowType, "", "")
+	for rows.Next() {
+		if err := writer.Write(FromRow(rows.Value())); err != nil {
+			return written, err
+		}
+		written++
+	}
+	return written, rows.Err()
+}
+
+// ExportTree writes the rows of a tree to w, parents before their children,
+// and returns how many it wrote.
+func ExportTree(root *storage.Node, w io.Writer) (int, error) {
+	writer := NewWriter(w)
+	written := 0
+	err := root.Walk(func(node *storage.Node, depth int) error {
+		record := FromRow(node.Row)
+		record.RowParentType = node.ParentType
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+		written++
+		return nil
+	})
+	return written, err
+}
+
+// ExportSubtree writes the row with the type and ID, and its descendants, to
+// w, breadth first, and returns how many it wrote. It reads each level of the
+// subtree a page at a time and writes rows as they're read, so that it holds
+// only the IDs of two levels, not the rows, however large the subtree. Each
+// level is read by parent if the storer is a storage.ChildScanner, or else in
+// one scan of every row. The root is written without its parent's type, which
+// it would take a scan to find.
+func ExportSubtree(ctx context.Context, storer storage.RowStorer, rootType, rootID string, w io.Writer) (int, error) {
+	root, err := storer.GetRowByID(ctx, rootType, rootID)
+	if err != nil {
+		return 0, err
+	}
+	writer := NewWriter(w)
+	if err := writer.Write(FromRow(root)); err != nil {
+		return 0, err
+	}
+	written := 1
+
+	// level maps the IDs of the rows of one level to their types
+	level := map[string]string{root.ID(): root.Type()}
+	for len(level) > 0 {
+		next := map[string]string{}
+		err := scanLevel(ctx, storer, level, func(child storage.Row) error {
+			if child.ID() == rootID {
+				// a parent cycle; any must pass through the root
+				return nil
+			}
+			record := FromRow(child)
+			record.RowParentType = level[child.ParentID()]
+			if err := writer.Write(record); err != nil {
+				return err
+			}
+			written++
+			next[child.ID()] = child.Type()
+			return nil
+		})
+		if err != nil {
+			return written, err
+		}
+		level = next
+	}
+	return written, nil
+}
+
+// scanLevel calls fn on the children of the rows of one level of a tree.
+func scanLevel(ctx context.Context, storer storage.RowStorer, level map[string]string, fn func(storage.Row) error) error {
+	scanner, ok := storer.(storage.ChildScanner)
+	if !ok {
+		return storer.ScanRows(ctx, func(row storage.Row) error {
+			if _, ok := level[row.ParentID()]; !ok {
+				return nil
+			}
+			return fn(row)
+		})
+	}
+	parentIDs := make([]string, 0, len(level))
+	for id := range level {
+		parentIDs = append(parentIDs, id)
+	}
+	sort.Strings(parentIDs)
+	for _, id := range parentIDs {
+		if err := scanner.ScanChildren(ctx, id, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Sort orders records so that parents come before their children: by depth in
+// the hierarchy, then by type, label, and ID. Records whose parent isn't among
+// them count as roots, unless their parent type is unknown too, in which case
+// they come last.
+func Sort(records []*Record) {
+	byID := map[string]*Record{}
+	for _, record := range records {
+		byID[record.RowID] = record
+	}
+	depths := map[*Record]int{}
+	var depth func(record *Record, seen map[string]bool) int
+	depth = func(record *Record, seen map[string]bool) int {
+		if d, ok := depths[record]; ok {
+			return d
+		}
+		d := 0
+		parent, ok := byID[record.RowParentID]
+		switch {
+		case record.RowParentID == "":
+		case ok && !seen[parent.RowID]:
+			seen[record.RowID] = true
+			d = depth(parent, seen) + 1
+		case !ok && record.RowParentType == "":
+			// an orphan
+			d = len(records)
+		}
+		depths[record] = d
+		return d
+	}
+	for _, record := range records {
+		depth(record, map[string]bool{})
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		a, b := records[i], records[j]
+		if depths[a] != depths[b] {
+			return depths[a] < depths[b]
+		}
+		if a.RowType != b.RowType {
+			return a.RowType < b.RowType
+		}
+		if a.RowLabel != b.RowLabel {
+			return a.RowLabel < b.RowLabel
+		}
+		return a.RowID < b.RowID
+	})
+}
@@ -0,0 +1,208 @@
+package dataset
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+var ErrConflict = errors.New("conflicting row")
+
+// ConflictStrategy decides what an import does with a record that conflicts
+// with a stored row: one with the same type and ID, or one whose label it
+// would collide with.
+type ConflictStrategy string
+
+const (
+	// ConflictFail stops the import. Conflicts are found before anything is
+	// written, so a failed import changes nothing.
+	ConflictFail ConflictStrategy = "fail"
+	// ConflictSkip keeps the stored row. Children of a skipped record are
+	// imported under the stored row instead.
+	ConflictSkip ConflictStrategy = "skip"
+	// ConflictOverwrite replaces the stored row with the record. A stored row
+	// with a colliding label keeps its ID, but takes the record's columns.
+	ConflictOverwrite ConflictStrategy = "overwrite"
+)
+
+// ParseConflictStrategy returns the named strategy.
+func ParseConflictStrategy(name string) (ConflictStrategy, error) {
+	switch strategy := ConflictStrategy(name); strategy {
+	case ConflictFail, ConflictSkip, ConflictOverwrite:
+		return strategy, nil
+	}
+	return "", fmt.Errorf("unknown conflict strategy %q: use %s, %s, or %s", name, ConflictFail, ConflictSkip, ConflictOverwrite)
+}
+
+type ImportOptions struct {
+	OnConflict ConflictStrategy
+	// DryRun finds conflicts and counts what would change, without writing.
+	DryRun bool
+	// Progress, if set, is called as records are imported, with how many
+	// have been, counting skipped ones.
+	Progress func(imported int)
+}
+
+// ImportResult counts what an import did with each record.
+type ImportResult struct {
+	Created     int
+	Overwritten int
+	Skipped     int
+}
+
+func (result ImportResult) String() string {
+	return fmt.Sprintf("%d created, %d overwritten, %d skipped", result.Created, result.Overwritten, result.Skipped)
+}
+
+// Import stores the records, keeping their IDs. A record's parent must be
+// among the records, or already stored as the record's parent type.
+func Import(ctx context.Context, storer storage.RowStorer, records []*Record, opts ImportOptions) (ImportResult, error) {
+	if opts.OnConflict == "" {
+		opts.OnConflict = ConflictFail
+	}
+	if _, err := ParseConflictStrategy(string(opts.OnConflict)); err != nil {
+		return ImportResult{}, err
+	}
+	Sort(records)
+
+	if opts.OnConflict == ConflictFail && !opts.DryRun {
+		if _, err := importRecords(ctx, storer, records, opts.OnConflict, true, nil); err != nil {
+			return ImportResult{}, err
+		}
+	}
+	return importRecords(ctx, storer, records, opts.OnConflict, opts.DryRun, opts.Progress)
+}
+
+func importRecords(ctx context.Context, storer storage.RowStorer, records []*Record, onConflict ConflictStrategy, dryRun bool, progress func(int)) (ImportResult, error) {
+	result := ImportResult{}
+	writer := newImportWriter(storer, progress)
+	inDataset := map[string]bool{}
+	for _, record := range records {
+		inDataset[record.RowID] = true
+	}
+	// the stored ID of each record, where a skipped or overwritten record
+	// maps to a stored row with a different ID
+	storedIDs := map[string]string{}
+
+	for _, record := range records {
+		row := *record
+		if row.RowParentID != "" {
+			if inDataset[row.RowParentID] {
+				parentID, ok := storedIDs[row.RowParentID]
+				if !ok {
+					return result, fmt.Errorf("%w: %s %s: its parent %s is its own descendant", ErrInvalidRecord, row.RowType, row.RowID, row.RowParentID)
+				}
+				row.RowParentID = parentID
+			} else if err := checkParent(ctx, storer, &row); err != nil {
+				return result, err
+			}
+		}
+
+		// rows waiting to be written that the lookups would find must be
+		// written first
+		if err := writer.flushIfPending(ctx, &row); err != nil {
+			return result, err
+		}
+		byID, err := getRow(ctx, storer, row.RowType, row.RowID)
+		if err != nil {
+			return result, err
+		}
+		byLabel, err := getByLabel(ctx, storer, &row)
+		if err != nil {
+			return result, err
+		}
+		if byLabel != nil && byLabel.ID() == row.RowID {
+			byLabel = nil
+		}
+		if byLabel != nil && (byID != nil || byLabel.Type() != row.RowType) {
+			// no strategy can resolve a record that collides with two rows,
+			// or with a row of another type
+			return result, fmt.Errorf("%w: %s %s: its label %q is taken by %s %s", ErrConflict, row.RowType, row.RowID, row.RowLabel, byLabel.Type(), byLabel.ID())
+		}
+
+		switch {
+		case byID == nil && byLabel == nil:
+			storedIDs[record.RowID] = row.RowID
+			result.Created++
+			if !dryRun {
+				err = writer.put(ctx, &row)
+			}
+		case onConflict == ConflictFail:
+			return result, fmt.Errorf("%w: %s %s already exists", ErrConflict, row.RowType, describeConflict(&row, byLabel))
+		case onConflict == ConflictSkip:
+			storedIDs[record.RowID] = row.RowID
+			if byLabel != nil {
+				storedIDs[record.RowID] = byLabel.ID()
+			}
+			result.Skipped++
+		case byLabel != nil:
+			storedIDs[record.RowID] = byLabel.ID()
+			result.Overwritten++
+			if !dryRun {
+				err = storer.UpdateColumns(ctx, byLabel.Type(), byLabel.ID(), row.RowColumns)
+			}
+		default:
+			storedIDs[record.RowID] = row.RowID
+			result.Overwritten++
+			if !dryRun {
+				err = writer.put(ctx, &row)
+			}
+		}
+		if err != nil {
+			return result, fmt.Errorf("%s %s: %w", row.RowType, row.RowID, err)
+		}
+		writer.imported()
+	}
+	return result, writer.flush(ctx)
+}
+
+func describeConflict(row *Record, byLabel storage.Row) string {
+	if byLabel != nil {
+		return fmt.Sprintf("labeled %q, as %s", row.RowLabel, byLabel.ID())
+	}
+	return row.RowID
+}
+
+// checkParent makes sure the parent of a record that isn't in the dataset is
+// already stored.
+func checkParent(ctx context.Context, storer storage.RowStorer, row *Record) error {
+	if row.RowParentType == "" {
+		return fmt.Errorf("%w: %s %s: parent %s is neither in the dataset nor of a known type", storage.ErrNotFoundRow, row.RowType, row.RowID, row.RowParentID)
+	}
+	parent, err := getRow(ctx, storer, row.RowParentType, row.RowParentID)
+	if err != nil {
+		return err
+	}
+	if parent == nil {
+		return fmt.Errorf("%w: %s %s: parent %s %s is neither in the dataset nor stored", storage.ErrNotFoundRow, row.RowType, row.RowID, row.RowParentType, row.RowParentID)
+	}
+	return nil
+}
+
+// getRow returns the stored row with the type and ID, or nil. Imports only
+// need to know whether it exists, so it's read without its columns.
+func getRow(ctx context.Context, storer storage.RowStorer, rowType, id string) (storage.Row, error) {
+	row, err := storage.GetRowColumns(ctx, storer, rowType, id, nil)
+	if errors.Is(err, storage.ErrNotFoundRow) {
+		return nil, nil
+	}
+	return row, err
+}
+
+// getByLabel returns the stored row the record's label would collide with, or
+// nil: a root row of the same type, or a child of the same parent.
+func getByLabel(ctx context.Context, storer storage.RowStorer, row *Record) (storage.Row, error) {
+	var found storage.Row
+	var err error
+	if row.RowParentID == "" {
+		found, err = storer.GetRow(ctx, row.RowType, row.RowLabel)
+	} else {
+		found, err = storer.GetChild(ctx, row.RowLabel, row.RowParentID)
+	}
+	if errors.Is(err, storage.ErrNotFoundRow) {
+		return nil, nil
+	}
+	return found, err
+}
@@ -0,0 +1,180 @@
+// Package fixture creates hierarchies of rows from declarative YAML files, for
+// demo and test environments. A fixture file is a list of root rows, each with
+// its children nested under it:
+//
+//	# fixtures/acme.yaml
+//	- type: organization
+//	  label: acme
+//	  columns:
+//	    domain: acme.com
+//	  children:
+//	    - type: team
+//	      label: platform
+//	      columns:
+//	        owners: [alice, bob]
+//
+// Seeding is idempotent: rows are found by label under their parent (or by
+// type and label, for roots) before any are created.
+package fixture
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+	"gopkg.in/yaml.v3"
+)
+
+var ErrInvalidFixture = errors.New("invalid fixture")
+
+// Row is one row of a fixture, and its children.
+type Row struct {
+	Type  string `yaml:"type"`
+	Label string `yaml:"label"`
+	// Columns are set on the row, replacing any it has. Rows without columns
+	// keep the ones they have.
+	Columns  map[string]Value `yaml:"columns"`
+	Children []*Row           `yaml:"children"`
+}
+
+// Value is a column value: a string, or a list of strings for a string set.
+// Scalars of other kinds are read as strings.
+type Value struct {
+	value interface{}
+}
+
+func (v *Value) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		v.value = node.Value
+		return nil
+	case yaml.SequenceNode:
+		set := make([]string, len(node.Content))
+		for i, elem := range node.Content {
+			if elem.Kind != yaml.ScalarNode {
+				return fmt.Errorf("line %d: %w: string sets can only hold strings", elem.Line, ErrInvalidFixture)
+			}
+			set[i] = elem.Value
+		}
+		v.value = set
+		return nil
+	}
+	return fmt.Errorf("line %d: %w: a column must be a string or a list of strings", node.Line, ErrInvalidFixture)
+}
+
+// Load reads fixture files, in order.
+func Load(paths ...string) ([]*Row, error) {
+	rows := []*Row{}
+	for _, path := range paths {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		fileRows := []*Row{}
+		decoder := yaml.NewDecoder(bytes.NewReader(b))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&fileRows); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		for _, row := range fileRows {
+			if err := row.validate(); err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+		}
+		rows = append(rows, fileRows...)
+	}
+	return rows, nil
+}
+
+func (row *Row) validate() error {
+	if row.Type == "" || row.Label == "" {
+		return fmt.Errorf("%w: every row needs a type and a label", ErrInvalidFixture)
+	}
+	for _, child := range row.Children {
+		if err := child.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (row *Row) columns() map[string]interface{} {
+	if row.Columns == nil {
+		return nil
+	}
+	columns := make(map[string]interface{}, len(row.Columns))
+	for name, v := range row.Columns {
+		columns[name] = v.value
+	}
+	return columns
+}
+
+// SeedResult counts what seeding did with each fixture row.
+type SeedResult struct {
+	Created   int
+	Updated   int
+	Unchanged int
+}
+
+func (result SeedResult) String() string {
+	return fmt.Sprintf("%d created, %d updated, %d unchanged", result.Created, result.Updated, result.Unchanged)
+}
+
+// Seed creates every fixture row that doesn't exist yet, and sets the columns
+// of those that do.
+func Seed(ctx context.Context, storer storage.RowStorer, rows []*Row) (SeedResult, error) {
+	result := SeedResult{}
+	for _, row := range rows {
+		if err := seed(ctx, storer, row, nil, &result); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+func seed(ctx context.Context, storer storage.RowStorer, row *Row, parent storage.Row, result *SeedResult) error {
+	var existing storage.Row
+	var err error
+	if parent == nil {
+		existing, err = storer.GetRow(ctx, row.Type, row.Label)
+	} else {
+		existing, err = storer.GetChild(ctx, row.Label, parent.ID())
+		if err == nil && existing.Type() != row.Type {
+			return fmt.Errorf("%s %q: the child of %s labeled %q already exists with type %q", row.Type, row.Label, parent.ID(), row.Label, existing.Type())
+		}
+	}
+	if err != nil && !errors.Is(err, storage.ErrNotFoundRow) {
+		return fmt.Errorf("%s %q: %w", row.Type, row.Label, err)
+	}
+
+	columns := row.columns()
+	switch {
+	case existing == nil && parent == nil:
+		existing, err = storer.CreateRow(ctx, row.Type, row.Label)
+		if err == nil && columns != nil {
+			err = storer.UpdateColumns(ctx, row.Type, existing.ID(), columns)
+		}
+		result.Created++
+	case existing == nil:
+		existing, err = storer.CreateChild(ctx, row.Type, row.Label, parent.Type(), parent.ID(), columns)
+		result.Created++
+	case columns != nil && !storage.EqualColumns(existing.Columns(), columns):
+		err = storer.UpdateColumns(ctx, row.Type, existing.ID(), columns)
+		result.Updated++
+	default:
+		result.Unchanged++
+	}
+	if err != nil {
+		return fmt.Errorf("%s %q: %w", row.Type, row.Label, err)
+	}
+
+	for _, child := range row.Children {
+		if err := seed(ctx, storer, child, existing, result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
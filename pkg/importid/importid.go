@@ -0,0 +1,97 @@
+// Package importid formats and parses the IDs used to import existing rows
+// into Terraform state.
+//
+// A row can be imported by its ID, as "type/id", or by its label. Labels are
+// unique per type for root rows, so those import as "type:label". Labels of
+// child rows are unique per parent, so those import as
+// "type:parent_id:label".
+package importid
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	idSeparator    = "/"
+	labelSeparator = ":"
+)
+
+var ErrInvalid = errors.New("invalid import ID")
+
+// ID identifies an existing row. Either RowID is set, or Label is (along with
+// ParentID, for child rows).
+type ID struct {
+	RowType  string
+	RowID    string
+	ParentID string
+	Label    string
+}
+
+// Format returns the import ID of a row, by its ID.
+func Format(rowType, rowID string) string {
+	return rowType + idSeparator + rowID
+}
+
+// FormatLabel returns the import ID of a row, by its label. parentID should be
+// empty for root rows.
+func FormatLabel(rowType, parentID, label string) string {
+	if parentID == "" {
+		return rowType + labelSeparator + label
+	}
+	return rowType + labelSeparator + parentID + labelSeparator + label
+}
+
+// Formats describes the import IDs accepted for a row type, for use in error
+// messages and documentation.
+func Formats(rowType string, child bool) string {
+	if child {
+		return fmt.Sprintf("%q or %q", Format(rowType, "<id>"), FormatLabel(rowType, "<parent_id>", "<label>"))
+	}
+	return fmt.Sprintf("%q or %q", Format(rowType, "<id>"), FormatLabel(rowType, "", "<label>"))
+}
+
+// Parse parses an import ID for the given row type.
+func Parse(rowType string, child bool, raw string) (ID, error) {
+	id := ID{RowType: rowType}
+
+	if rest, ok := strings.CutPrefix(raw, rowType+idSeparator); ok {
+		if rest == "" {
+			return id, invalid(rowType, child, raw)
+		}
+		id.RowID = rest
+		return id, nil
+	}
+
+	rest, ok := strings.CutPrefix(raw, rowType+labelSeparator)
+	if !ok {
+		return id, invalid(rowType, child, raw)
+	}
+	if child {
+		parentID, label, ok := strings.Cut(rest, labelSeparator)
+		if !ok || parentID == "" || label == "" {
+			return id, invalid(rowType, child, raw)
+		}
+		id.ParentID = parentID
+		id.Label = label
+		return id, nil
+	}
+	if rest == "" {
+		return id, invalid(rowType, child, raw)
+	}
+	id.Label = rest
+	return id, nil
+}
+
+func invalid(rowType string, child bool, raw string) error {
+	return fmt.Errorf("%w %q: expected %s", ErrInvalid, raw, Formats(rowType, child))
+}
+
+// String formats the ID the way it was most likely parsed.
+func (id ID) String() string {
+	if id.RowID != "" {
+		return Format(id.RowType, id.RowID)
+	}
+	return FormatLabel(id.RowType, id.ParentID, id.Label)
+}
@@ -0,0 +1,161 @@
+// Package secrets resolves references to secrets kept in AWS Secrets Manager
+// or SSM Parameter Store, so that backend connection settings such as
+// passwords and API tokens need not be written inline.
+//
+// A reference is one of:
+//
+//   - secretsmanager:<name or ARN>, or a Secrets Manager secret ARN
+//   - ssm:<parameter name>, or an SSM parameter ARN
+//
+// A Secrets Manager reference may end in #<key> to take one key of a secret
+// stored as a JSON object, as database credentials usually are.
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+)
+
+const (
+	prefixSecretsManager = "secretsmanager:"
+	prefixSSM            = "ssm:"
+	arnSecretsManager    = "arn:aws:secretsmanager:"
+	arnSSM               = "arn:aws:ssm:"
+)
+
+var (
+	ErrNotReference = errors.New("not a secret reference")
+	ErrNoKey        = errors.New("the secret has no such key")
+)
+
+// IsReference reports whether value refers to a secret, rather than being
+// one.
+func IsReference(value string) bool {
+	for _, prefix := range []string{prefixSecretsManager, prefixSSM, arnSecretsManager, arnSSM} {
+		if strings.HasPrefix(value, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Resolver fetches the secrets references refer to. It keeps each one it
+// fetches, so that a secret used by many backends is fetched once.
+type Resolver struct {
+	secretsManager *secretsmanager.Client
+	ssm            *ssm.Client
+
+	mu      sync.Mutex
+	fetched map[string]string
+}
+
+// NewResolver returns a resolver using the profile's credentials, fetching
+// secrets from the region.
+func NewResolver(ctx context.Context, profile, region string) (*Resolver, error) {
+	cfg, err := config.LoadDefaultConfig(ctx,
+		config.WithSharedConfigProfile(profile),
+		config.WithRegion(region),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &Resolver{
+		secretsManager: secretsmanager.NewFromConfig(cfg),
+		ssm:            ssm.NewFromConfig(cfg),
+		fetched:        map[string]string{},
+	}, nil
+}
+
+// Resolve returns the secret ref refers to, or ref itself if it isn't a
+// reference, so that settings may be given either way.
+func (resolver *Resolver) Resolve(ctx context.Context, ref string) (string, error) {
+	if !IsReference(ref) {
+		return ref, nil
+	}
+	resolver.mu.Lock()
+	defer resolver.mu.Unlock()
+	if value, ok := resolver.fetched[ref]; ok {
+		return value, nil
+	}
+	value, err := resolver.fetch(ctx, ref)
+	if err != nil {
+		// the reference isn't secret, and says which secret failed
+		return "", fmt.Errorf("resolving %s: %w", ref, err)
+	}
+	resolver.fetched[ref] = value
+	return value, nil
+}
+
+func (resolver *Resolver) fetch(ctx context.Context, ref string) (string, error) {
+	switch {
+	case strings.HasPrefix(ref, prefixSSM):
+		return resolver.parameter(ctx, strings.TrimPrefix(ref, prefixSSM))
+	case strings.HasPrefix(ref, arnSSM):
+		return resolver.parameter(ctx, ref)
+	case strings.HasPrefix(ref, prefixSecretsManager):
+		return resolver.secret(ctx, strings.TrimPrefix(ref, prefixSecretsManager))
+	case strings.HasPrefix(ref, arnSecretsManager):
+		return resolver.secret(ctx, ref)
+	}
+	return "", fmt.Errorf("%w: %q", ErrNotReference, ref)
+}
+
+// parameter fetches an SSM parameter, decrypting it if it is a SecureString.
+func (resolver *Resolver) parameter(ctx context.Context, name string) (string, error) {
+	output, err := resolver.ssm.GetParameter(ctx, &ssm.GetParameterInput{
+		Name:           aws.String(name),
+		WithDecryption: aws.Bool(true),
+	})
+	if err != nil {
+		return "", err
+	}
+	return aws.ToString(output.Parameter.Value), nil
+}
+
+// secret fetches a Secrets Manager secret's current string value, or one key
+// of it, if id ends in #<key>. Callers must hold the lock.
+func (resolver *Resolver) secret(ctx context.Context, id string) (string, error) {
+	id, key, hasKey := strings.Cut(id, "#")
+	// keys of the same secret share one fetch of it
+	whole := prefixSecretsManager + id
+	value, ok := resolver.fetched[whole]
+	if !ok {
+		output, err := resolver.secretsManager.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{
+			SecretId: aws.String(id),
+		})
+		if err != nil {
+			return "", err
+		}
+		value = aws.ToString(output.SecretString)
+		resolver.fetched[whole] = value
+	}
+	if !hasKey {
+		return value, nil
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(value), &fields); err != nil {
+		return "", fmt.Errorf("%w: %q: the secret isn't a JSON object", ErrNoKey, key)
+	}
+	field, ok := fields[key]
+	if !ok {
+		return "", fmt.Errorf("%w: %q", ErrNoKey, key)
+	}
+	if s, ok := field.(string); ok {
+		return s, nil
+	}
+	// numbers, such as ports, are given as they are written
+	encoded, err := json.Marshal(field)
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
@@ -0,0 +1,63 @@
+package snapshot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LocalStore keeps snapshots as files in a directory.
+type LocalStore struct {
+	Dir string
+}
+
+func (store *LocalStore) Put(ctx context.Context, id string, data []byte) error {
+	if err := os.MkdirAll(store.Dir, 0o755); err != nil {
+		return err
+	}
+	// write to a temporary file first, so a failed write leaves no partial
+	// snapshot behind
+	path := filepath.Join(store.Dir, id+extension)
+	if err := os.WriteFile(path+".tmp", data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+func (store *LocalStore) Get(ctx context.Context, id string) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(store.Dir, id+extension))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %q", ErrNotFound, id)
+	}
+	return data, err
+}
+
+func (store *LocalStore) List(ctx context.Context) ([]Info, error) {
+	entries, err := os.ReadDir(store.Dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	infos := []Info{}
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), extension)
+		if !ok || entry.IsDir() {
+			continue
+		}
+		info, ok := parseID(id)
+		if !ok {
+			continue
+		}
+		if fi, err := entry.Info(); err == nil {
+			info.Size = fi.Size()
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
@@ -0,0 +1,101 @@
+package snapshot
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// S3Store keeps snapshots as objects in an S3 bucket, under a key prefix.
+type S3Store struct {
+	s3     *s3.Client
+	bucket string
+	prefix string
+}
+
+// NewS3Store returns a store of the snapshots in the bucket, under prefix.
+func NewS3Store(ctx context.Context, profile, region, bucket, prefix string) (*S3Store, error) {
+	cfg, err := config.LoadDefaultConfig(ctx,
+		config.WithSharedConfigProfile(profile),
+		config.WithRegion(region),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &S3Store{
+		s3:     s3.NewFromConfig(cfg),
+		bucket: bucket,
+		prefix: strings.Trim(prefix, "/"),
+	}, nil
+}
+
+func (store *S3Store) key(id string) string {
+	return path.Join(store.prefix, id+extension)
+}
+
+func (store *S3Store) Put(ctx context.Context, id string, data []byte) error {
+	_, err := store.s3.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(store.bucket),
+		Key:         aws.String(store.key(id)),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String("application/x-ndjson"),
+	})
+	return err
+}
+
+func (store *S3Store) Get(ctx context.Context, id string) ([]byte, error) {
+	output, err := store.s3.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(store.bucket),
+		Key:    aws.String(store.key(id)),
+	})
+	var noSuchKey *types.NoSuchKey
+	if errors.As(err, &noSuchKey) {
+		return nil, fmt.Errorf("%w: %q", ErrNotFound, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer output.Body.Close()
+	return io.ReadAll(output.Body)
+}
+
+func (store *S3Store) List(ctx context.Context) ([]Info, error) {
+	prefix := ""
+	if store.prefix != "" {
+		prefix = store.prefix + "/"
+	}
+	infos := []Info{}
+	paginator := s3.NewListObjectsV2Paginator(store.s3, &s3.ListObjectsV2Input{
+		Bucket:    aws.String(store.bucket),
+		Prefix:    aws.String(prefix),
+		Delimiter: aws.String("/"),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, object := range page.Contents {
+			id, ok := strings.CutSuffix(strings.TrimPrefix(aws.ToString(object.Key), prefix), extension)
+			if !ok {
+				continue
+			}
+			info, ok := parseID(id)
+			if !ok {
+				continue
+			}
+			info.Size = aws.ToInt64(object.Size)
+			infos = append(infos, info)
+		}
+	}
+	return infos, nil
+}
@@ -0,0 +1,124 @@
+// Package snapshot keeps named, timestamped exports of every row, locally or
+// in S3, and restores them. Snapshots are NDJSON datasets, as written by
+// package dataset.
+package snapshot
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/dataset"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+var (
+	ErrInvalidName = errors.New("invalid snapshot name")
+	ErrNotFound    = errors.New("snapshot not found")
+)
+
+const (
+	timeFormat = "20060102T150405Z"
+	extension  = ".ndjson"
+)
+
+var validName = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Info describes a stored snapshot.
+type Info struct {
+	// ID is the snapshot's name and creation time, as
+	// "<name>-<20060102T150405Z>".
+	ID      string
+	Name    string
+	Created time.Time
+	Size    int64
+}
+
+// Store keeps snapshots.
+type Store interface {
+	Put(ctx context.Context, id string, data []byte) error
+	Get(ctx context.Context, id string) ([]byte, error)
+	// List returns every snapshot in the store, in any order.
+	List(ctx context.Context) ([]Info, error)
+}
+
+// parseID splits a snapshot ID into its name and creation time. ok is false
+// for objects that aren't snapshots.
+func parseID(id string) (info Info, ok bool) {
+	i := strings.LastIndex(id, "-")
+	if i < 0 {
+		return Info{}, false
+	}
+	created, err := time.Parse(timeFormat, id[i+1:])
+	if err != nil || !validName.MatchString(id[:i]) {
+		return Info{}, false
+	}
+	return Info{ID: id, Name: id[:i], Created: created}, true
+}
+
+// Create exports every row to a new snapshot with the given name, and returns
+// it with the number of rows it holds.
+func Create(ctx context.Context, storer storage.RowStorer, store Store, name string) (Info, int, error) {
+	if !validName.MatchString(name) {
+		return Info{}, 0, fmt.Errorf("%w: %q: use letters, digits, dashes, and underscores", ErrInvalidName, name)
+	}
+	var buf bytes.Buffer
+	n, err := dataset.Export(ctx, storer, &buf)
+	if err != nil {
+		return Info{}, 0, err
+	}
+	created := time.Now().UTC().Truncate(time.Second)
+	info := Info{ID: name + "-" + created.Format(timeFormat), Name: name, Created: created, Size: int64(buf.Len())}
+	if err := store.Put(ctx, info.ID, buf.Bytes()); err != nil {
+		return Info{}, 0, err
+	}
+	return info, n, nil
+}
+
+// List returns the snapshots in the store, newest first.
+func List(ctx context.Context, store Store) ([]Info, error) {
+	infos, err := store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		if !infos[i].Created.Equal(infos[j].Created) {
+			return infos[i].Created.After(infos[j].Created)
+		}
+		return infos[i].ID < infos[j].ID
+	})
+	return infos, nil
+}
+
+// Find returns the snapshot with the given ID, or the newest one with the
+// given name.
+func Find(ctx context.Context, store Store, idOrName string) (Info, error) {
+	infos, err := List(ctx, store)
+	if err != nil {
+		return Info{}, err
+	}
+	for _, info := range infos {
+		if info.ID == idOrName || info.Name == idOrName {
+			return info, nil
+		}
+	}
+	return Info{}, fmt.Errorf("%w: %q", ErrNotFound, idOrName)
+}
+
+// Read returns the records of a snapshot.
+func Read(ctx context.Context, store Store, id string) ([]*dataset.Record, error) {
+	data, err := store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	records, err := dataset.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("snapshot %s: %w", id, err)
+	}
+	return records, nil
+}
@@ -0,0 +1,231 @@
+// Package attribution wraps a storage.RowStorer to record who created and who
+// last updated each row, for accountability. The actor is fixed when the
+// storer is made: typically the ARN of the caller's AWS identity.
+package attribution
+
+import (
+	"context"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+// The columns the actors are kept in.
+const (
+	CreatedByColumn = "__created_by"
+	UpdatedByColumn = "__updated_by"
+)
+
+// Storer is a storage.RowStorer that records its actor on every row it
+// creates or updates.
+type Storer struct {
+	next  storage.RowStorer
+	actor string
+}
+
+var (
+	_ storage.RowStorer     = &Storer{}
+	_ storage.Transactor    = &Storer{}
+	_ storage.ColumnDeleter = &Storer{}
+	_ storage.BatchGetter   = &Storer{}
+	_ storage.ColumnQuerier = &Storer{}
+	_ storage.ChildScanner  = &Storer{}
+	_ storage.BatchPutter   = &Storer{}
+	_ storage.ColumnPatcher = &Storer{}
+	_ storage.RowIterator   = &Storer{}
+)
+
+// NewStorer wraps next, recording actor on every write.
+func NewStorer(next storage.RowStorer, actor string) *Storer {
+	return &Storer{next: next, actor: actor}
+}
+
+// Unwrap returns the wrapped storer.
+func (client *Storer) Unwrap() storage.RowStorer {
+	return client.next
+}
+
+// attributedRow is a row with some of its columns overridden, to return the
+// attribution written after the row was.
+type attributedRow struct {
+	storage.Row
+	columns map[string]interface{}
+}
+
+func (row *attributedRow) Columns() map[string]interface{} { return row.columns }
+
+func withColumns(row storage.Row, extra map[string]interface{}) storage.Row {
+	columns := make(map[string]interface{}, len(row.Columns())+len(extra))
+	for name, value := range row.Columns() {
+		columns[name] = value
+	}
+	for name, value := range extra {
+		columns[name] = value
+	}
+	return &attributedRow{Row: row, columns: columns}
+}
+
+func (client *Storer) GetRowByID(ctx context.Context, rowType, rowID string) (storage.Row, error) {
+	return client.next.GetRowByID(ctx, rowType, rowID)
+}
+
+func (client *Storer) GetRowsByIDs(ctx context.Context, rowType string, ids []string) ([]storage.Row, error) {
+	return storage.GetRowsByIDs(ctx, client.next, rowType, ids)
+}
+
+func (client *Storer) GetRow(ctx context.Context, rowType, rowLabel string) (storage.Row, error) {
+	return client.next.GetRow(ctx, rowType, rowLabel)
+}
+
+// CreateRow takes no columns, so the attribution is a second write.
+func (client *Storer) CreateRow(ctx context.Context, rowType, rowLabel string) (storage.Row, error) {
+	row, err := client.next.CreateRow(ctx, rowType, rowLabel)
+	if err != nil {
+		return nil, err
+	}
+	attribution := map[string]interface{}{
+		CreatedByColumn: client.actor,
+		UpdatedByColumn: client.actor,
+	}
+	columns := withColumns(row, attribution).Columns()
+	if err := client.next.UpdateColumns(ctx, rowType, row.ID(), columns); err != nil {
+		return nil, err
+	}
+	return withColumns(row, attribution), nil
+}
+
+func (client *Storer) CreateChild(ctx context.Context, rowType, rowLabel, parentType, parentID string, columns map[string]interface{}) (storage.Row, error) {
+	attributed := make(map[string]interface{}, len(columns)+2)
+	for name, value := range columns {
+		attributed[name] = value
+	}
+	attributed[CreatedByColumn] = client.actor
+	attributed[UpdatedByColumn] = client.actor
+	return client.next.CreateChild(ctx, rowType, rowLabel, parentType, parentID, attributed)
+}
+
+func (client *Storer) GetChild(ctx context.Context, childLabel, parentID string) (storage.Row, error) {
+	return client.next.GetChild(ctx, childLabel, parentID)
+}
+
+func (client *Storer) ListRows(ctx context.Context, rowType, labelFilter, parentIDFilter string) ([]storage.Row, error) {
+	return client.next.ListRows(ctx, rowType, labelFilter, parentIDFilter)
+}
+
+func (client *Storer) IterRows(ctx context.Context, rowType, labelFilter, parentIDFilter string) *storage.Iter[storage.Row] {
+	return storage.IterRows(ctx, client.next, rowType, labelFilter, parentIDFilter)
+}
+
+func (client *Storer) QueryByColumn(ctx context.Context, rowType, columnName string, value interface{}) ([]storage.Row, error) {
+	return storage.QueryByColumn(ctx, client.next, rowType, columnName, value)
+}
+
+func (client *Storer) UpdateRow(ctx context.Context, rowType, rowID, newLabel string) (storage.Row, error) {
+	row, err := client.next.UpdateRow(ctx, rowType, rowID, newLabel)
+	if err != nil {
+		return nil, err
+	}
+	if err := client.next.UpdateColumn(ctx, rowType, rowID, UpdatedByColumn, client.actor); err != nil {
+		return nil, err
+	}
+	return withColumns(row, map[string]interface{}{UpdatedByColumn: client.actor}), nil
+}
+
+func (client *Storer) UpdateChild(ctx context.Context, childType, childID, newChildLabel, parentType, newParentID string) (storage.Row, error) {
+	row, err := client.next.UpdateChild(ctx, childType, childID, newChildLabel, parentType, newParentID)
+	if err != nil {
+		return nil, err
+	}
+	if err := client.next.UpdateColumn(ctx, childType, childID, UpdatedByColumn, client.actor); err != nil {
+		return nil, err
+	}
+	return withColumns(row, map[string]interface{}{UpdatedByColumn: client.actor}), nil
+}
+
+func (client *Storer) UpdateColumn(ctx context.Context, rowType, rowID, columnName string, columnValue interface{}) error {
+	if err := client.next.UpdateColumn(ctx, rowType, rowID, columnName, columnValue); err != nil {
+		return err
+	}
+	return client.next.UpdateColumn(ctx, rowType, rowID, UpdatedByColumn, client.actor)
+}
+
+// PatchColumns stamps each patch with the actor, in the same write as its
+// columns.
+func (client *Storer) PatchColumns(ctx context.Context, patches []storage.ColumnPatch) error {
+	attributed := make([]storage.ColumnPatch, len(patches))
+	for i, patch := range patches {
+		columns := make(map[string]interface{}, len(patch.Columns)+1)
+		for name, value := range patch.Columns {
+			columns[name] = value
+		}
+		columns[UpdatedByColumn] = client.actor
+		attributed[i] = storage.ColumnPatch{Type: patch.Type, ID: patch.ID, Columns: columns}
+	}
+	return storage.PatchColumns(ctx, client.next, attributed)
+}
+
+// UpdateColumns replaces every column, so it reads the row first to keep who
+// created it.
+func (client *Storer) UpdateColumns(ctx context.Context, rowType, rowID string, columns map[string]interface{}) error {
+	row, err := client.next.GetRowByID(ctx, rowType, rowID)
+	if err != nil {
+		return err
+	}
+	attributed := make(map[string]interface{}, len(columns)+2)
+	for name, value := range columns {
+		attributed[name] = value
+	}
+	if createdBy, ok := row.Columns()[CreatedByColumn]; ok {
+		attributed[CreatedByColumn] = createdBy
+	}
+	attributed[UpdatedByColumn] = client.actor
+	return client.next.UpdateColumns(ctx, rowType, rowID, attributed)
+}
+
+func (client *Storer) DeleteRow(ctx context.Context, rowType, childType, rowID string) error {
+	return client.next.DeleteRow(ctx, rowType, childType, rowID)
+}
+
+// PutRow keeps the row's creator, if it has one, since it is for restoring
+// and copying rows, and records the actor as its updater.
+func (client *Storer) PutRow(ctx context.Context, row storage.Row) error {
+	attribution := map[string]interface{}{UpdatedByColumn: client.actor}
+	if _, ok := row.Columns()[CreatedByColumn]; !ok {
+		attribution[CreatedByColumn] = client.actor
+	}
+	return client.next.PutRow(ctx, withColumns(row, attribution))
+}
+
+func (client *Storer) PutRows(ctx context.Context, rows []storage.Row) error {
+	attributed := make([]storage.Row, len(rows))
+	for i, row := range rows {
+		attribution := map[string]interface{}{UpdatedByColumn: client.actor}
+		if _, ok := row.Columns()[CreatedByColumn]; !ok {
+			attribution[CreatedByColumn] = client.actor
+		}
+		attributed[i] = withColumns(row, attribution)
+	}
+	return storage.PutRows(ctx, client.next, attributed)
+}
+
+func (client *Storer) ScanRows(ctx context.Context, fn func(storage.Row) error) error {
+	return client.next.ScanRows(ctx, fn)
+}
+
+func (client *Storer) ScanChildren(ctx context.Context, parentID string, fn func(storage.Row) error) error {
+	return storage.ScanChildren(ctx, client.next, parentID, fn)
+}
+
+// DeleteColumn records the actor as the row's updater, as UpdateColumn does.
+func (client *Storer) DeleteColumn(ctx context.Context, rowType, rowID, columnName string) error {
+	if err := storage.DeleteColumn(ctx, client.next, rowType, rowID, columnName); err != nil {
+		return err
+	}
+	return client.next.UpdateColumn(ctx, rowType, rowID, UpdatedByColumn, client.actor)
+}
+
+// WithinTx records the actor on the rows the transaction writes, too.
+func (client *Storer) WithinTx(ctx context.Context, fn func(tx storage.RowStorer) error) error {
+	return storage.WithinTx(ctx, client.next, func(tx storage.RowStorer) error {
+		return fn(NewStorer(tx, client.actor))
+	})
+}
@@ -0,0 +1,95 @@
+package attribution_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/dataset"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/attribution"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/memory"
+)
+
+// attributed fails the test unless the stored row was created by createdBy
+// and last updated by updatedBy.
+func attributed(t *testing.T, backend storage.RowStorer, rowType, rowID, createdBy, updatedBy string) {
+	t.Helper()
+	row, err := backend.GetRowByID(context.Background(), rowType, rowID)
+	if err != nil {
+		t.Fatalf("GetRowByID: %s", err)
+	}
+	columns := row.Columns()
+	if columns[attribution.CreatedByColumn] != createdBy || columns[attribution.UpdatedByColumn] != updatedBy {
+		t.Errorf("%s was created by %v and updated by %v, not %s and %s", rowID,
+			columns[attribution.CreatedByColumn], columns[attribution.UpdatedByColumn], createdBy, updatedBy)
+	}
+}
+
+func TestCreates(t *testing.T) {
+	ctx := context.Background()
+	backend := memory.NewClient()
+	storer := attribution.NewStorer(backend, "alice")
+
+	org, err := storer.CreateRow(ctx, "org", "acme")
+	if err != nil {
+		t.Fatalf("CreateRow: %s", err)
+	}
+	if org.Columns()[attribution.CreatedByColumn] != "alice" {
+		t.Errorf("CreateRow returned columns %v, without its creator", org.Columns())
+	}
+	attributed(t, backend, "org", org.ID(), "alice", "alice")
+
+	team, err := storer.CreateChild(ctx, "team", "dev", "org", org.ID(), map[string]interface{}{"owner": "ops"})
+	if err != nil {
+		t.Fatalf("CreateChild: %s", err)
+	}
+	attributed(t, backend, "team", team.ID(), "alice", "alice")
+	if team.Columns()["owner"] != "ops" {
+		t.Errorf("CreateChild lost the child's own columns: %v", team.Columns())
+	}
+}
+
+func TestUpdatesKeepCreator(t *testing.T) {
+	ctx := context.Background()
+	backend := memory.NewClient()
+	alice := attribution.NewStorer(backend, "alice")
+	bob := attribution.NewStorer(backend, "bob")
+	carol := attribution.NewStorer(backend, "carol")
+	org, err := alice.CreateRow(ctx, "org", "acme")
+	if err != nil {
+		t.Fatalf("CreateRow: %s", err)
+	}
+
+	if err := bob.UpdateColumn(ctx, "org", org.ID(), "owner", "ops"); err != nil {
+		t.Fatalf("UpdateColumn: %s", err)
+	}
+	attributed(t, backend, "org", org.ID(), "alice", "bob")
+
+	patch := []storage.ColumnPatch{{Type: "org", ID: org.ID(), Columns: map[string]interface{}{"owner": "dev"}}}
+	if err := carol.PatchColumns(ctx, patch); err != nil {
+		t.Fatalf("PatchColumns: %s", err)
+	}
+	attributed(t, backend, "org", org.ID(), "alice", "carol")
+
+	// replacing every column still keeps who created the row
+	if err := bob.UpdateColumns(ctx, "org", org.ID(), map[string]interface{}{"owner": "qa"}); err != nil {
+		t.Fatalf("UpdateColumns: %s", err)
+	}
+	attributed(t, backend, "org", org.ID(), "alice", "bob")
+}
+
+func TestPutRowsKeepCreator(t *testing.T) {
+	ctx := context.Background()
+	backend := memory.NewClient()
+	storer := attribution.NewStorer(backend, "bob")
+	rows := []storage.Row{
+		&dataset.Record{RowType: "org", RowID: "org-acme", RowLabel: "acme", RowColumns: map[string]interface{}{attribution.CreatedByColumn: "alice"}},
+		&dataset.Record{RowType: "org", RowID: "org-globex", RowLabel: "globex"},
+	}
+
+	if err := storer.PutRows(ctx, rows); err != nil {
+		t.Fatalf("PutRows: %s", err)
+	}
+	attributed(t, backend, "org", "org-acme", "alice", "bob")
+	attributed(t, backend, "org", "org-globex", "bob", "bob")
+}
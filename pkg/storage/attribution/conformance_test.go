@@ -0,0 +1,16 @@
+package attribution_test
+
+import (
+	"testing"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/attribution"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/memory"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/storagetest"
+)
+
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.RowStorer {
+		return attribution.NewStorer(memory.NewClient(), "storagetest")
+	})
+}
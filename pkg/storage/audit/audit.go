@@ -0,0 +1,268 @@
+// Package audit publishes an event for every row a storage backend creates,
+// updates, or deletes, so that downstream automation and SIEM systems can
+// follow changes to the catalog.
+package audit
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+// Source names the publisher of audit events.
+const Source = "tree.storage"
+
+// Event describes one change to a row. Column values may be sensitive, so
+// events carry only the names of the columns written.
+type Event struct {
+	Kind     storage.ChangeKind `json:"kind"`
+	Time     time.Time          `json:"time"`
+	RowType  string             `json:"type"`
+	RowID    string             `json:"id"`
+	Label    string             `json:"label,omitempty"`
+	ParentID string             `json:"parent_id,omitempty"`
+	Columns  []string           `json:"columns,omitempty"`
+	// Operation is the RowStorer method that made the change.
+	Operation string `json:"operation"`
+	// CorrelationID identifies the provider operation that made the change,
+	// in its logs.
+	CorrelationID string `json:"correlation_id,omitempty"`
+}
+
+// Publisher sends events somewhere.
+type Publisher interface {
+	Publish(ctx context.Context, event Event) error
+}
+
+// Storer is a storage.RowStorer that publishes an event for each write the
+// one it wraps makes. A failed publication is logged, and doesn't fail the
+// write, which has already happened.
+type Storer struct {
+	next      storage.RowStorer
+	publisher Publisher
+}
+
+var (
+	_ storage.RowStorer     = &Storer{}
+	_ storage.Transactor    = &Storer{}
+	_ storage.ColumnDeleter = &Storer{}
+	_ storage.BatchGetter   = &Storer{}
+	_ storage.ColumnQuerier = &Storer{}
+	_ storage.ChildScanner  = &Storer{}
+	_ storage.BatchPutter   = &Storer{}
+	_ storage.ColumnPatcher = &Storer{}
+	_ storage.RowIterator   = &Storer{}
+)
+
+func NewStorer(next storage.RowStorer, publisher Publisher) *Storer {
+	return &Storer{next: next, publisher: publisher}
+}
+
+// Unwrap returns the wrapped storer.
+func (client *Storer) Unwrap() storage.RowStorer {
+	return client.next
+}
+
+func (client *Storer) publish(ctx context.Context, operation string, kind storage.ChangeKind, row storage.Row, columns map[string]interface{}) {
+	event := Event{
+		Kind:          kind,
+		Time:          time.Now().UTC(),
+		RowType:       row.Type(),
+		RowID:         row.ID(),
+		Label:         row.Label(),
+		ParentID:      row.ParentID(),
+		Operation:     operation,
+		CorrelationID: storage.CorrelationID(ctx),
+	}
+	for name := range columns {
+		event.Columns = append(event.Columns, name)
+	}
+	sort.Strings(event.Columns)
+	client.send(ctx, event)
+}
+
+// send publishes an event, logging a failure.
+func (client *Storer) send(ctx context.Context, event Event) {
+	if err := client.publisher.Publish(ctx, event); err != nil {
+		tflog.SubsystemWarn(ctx, storage.LogSubsystemStorage, fmt.Sprintf("Publishing the audit event of %s %s %s failed: %s", event.Operation, event.RowType, event.RowID, err))
+	}
+}
+
+// rowRef stands in for a row the operation doesn't return.
+type rowRef struct {
+	rowType string
+	id      string
+}
+
+func (r rowRef) Type() string                    { return r.rowType }
+func (r rowRef) ID() string                      { return r.id }
+func (r rowRef) Label() string                   { return "" }
+func (r rowRef) ParentID() string                { return "" }
+func (r rowRef) Columns() map[string]interface{} { return nil }
+
+func (client *Storer) GetRowByID(ctx context.Context, rowType, rowID string) (storage.Row, error) {
+	return client.next.GetRowByID(ctx, rowType, rowID)
+}
+
+func (client *Storer) GetRowsByIDs(ctx context.Context, rowType string, ids []string) ([]storage.Row, error) {
+	return storage.GetRowsByIDs(ctx, client.next, rowType, ids)
+}
+
+func (client *Storer) GetRow(ctx context.Context, rowType, rowLabel string) (storage.Row, error) {
+	return client.next.GetRow(ctx, rowType, rowLabel)
+}
+
+func (client *Storer) GetChild(ctx context.Context, childLabel, parentID string) (storage.Row, error) {
+	return client.next.GetChild(ctx, childLabel, parentID)
+}
+
+func (client *Storer) ListRows(ctx context.Context, rowType, labelFilter, parentIDFilter string) ([]storage.Row, error) {
+	return client.next.ListRows(ctx, rowType, labelFilter, parentIDFilter)
+}
+
+func (client *Storer) IterRows(ctx context.Context, rowType, labelFilter, parentIDFilter string) *storage.Iter[storage.Row] {
+	return storage.IterRows(ctx, client.next, rowType, labelFilter, parentIDFilter)
+}
+
+func (client *Storer) QueryByColumn(ctx context.Context, rowType, columnName string, value interface{}) ([]storage.Row, error) {
+	return storage.QueryByColumn(ctx, client.next, rowType, columnName, value)
+}
+
+func (client *Storer) ScanRows(ctx context.Context, fn func(storage.Row) error) error {
+	return client.next.ScanRows(ctx, fn)
+}
+
+func (client *Storer) ScanChildren(ctx context.Context, parentID string, fn func(storage.Row) error) error {
+	return storage.ScanChildren(ctx, client.next, parentID, fn)
+}
+
+func (client *Storer) CreateRow(ctx context.Context, rowType, rowLabel string) (storage.Row, error) {
+	row, err := client.next.CreateRow(ctx, rowType, rowLabel)
+	if err == nil {
+		client.publish(ctx, "CreateRow", storage.ChangeCreated, row, nil)
+	}
+	return row, err
+}
+
+func (client *Storer) CreateChild(ctx context.Context, rowType, rowLabel, parentType, parentID string, columns map[string]interface{}) (storage.Row, error) {
+	row, err := client.next.CreateChild(ctx, rowType, rowLabel, parentType, parentID, columns)
+	if err == nil {
+		client.publish(ctx, "CreateChild", storage.ChangeCreated, row, columns)
+	}
+	return row, err
+}
+
+func (client *Storer) UpdateRow(ctx context.Context, rowType, rowID, newLabel string) (storage.Row, error) {
+	row, err := client.next.UpdateRow(ctx, rowType, rowID, newLabel)
+	if err == nil {
+		client.publish(ctx, "UpdateRow", storage.ChangeUpdated, row, nil)
+	}
+	return row, err
+}
+
+func (client *Storer) UpdateChild(ctx context.Context, childType, childID, newChildLabel, parentType, newParentID string) (storage.Row, error) {
+	row, err := client.next.UpdateChild(ctx, childType, childID, newChildLabel, parentType, newParentID)
+	if err == nil {
+		client.publish(ctx, "UpdateChild", storage.ChangeUpdated, row, nil)
+	}
+	return row, err
+}
+
+func (client *Storer) UpdateColumn(ctx context.Context, rowType, rowID, columnName string, columnValue interface{}) error {
+	err := client.next.UpdateColumn(ctx, rowType, rowID, columnName, columnValue)
+	if err == nil {
+		client.publish(ctx, "UpdateColumn", storage.ChangeUpdated, rowRef{rowType, rowID}, map[string]interface{}{columnName: columnValue})
+	}
+	return err
+}
+
+// PatchColumns publishes an event for each patched row once all of the
+// patches are applied.
+func (client *Storer) PatchColumns(ctx context.Context, patches []storage.ColumnPatch) error {
+	err := storage.PatchColumns(ctx, client.next, patches)
+	if err == nil {
+		for _, patch := range patches {
+			client.publish(ctx, "PatchColumns", storage.ChangeUpdated, rowRef{patch.Type, patch.ID}, patch.Columns)
+		}
+	}
+	return err
+}
+
+func (client *Storer) UpdateColumns(ctx context.Context, rowType, rowID string, columns map[string]interface{}) error {
+	err := client.next.UpdateColumns(ctx, rowType, rowID, columns)
+	if err == nil {
+		client.publish(ctx, "UpdateColumns", storage.ChangeUpdated, rowRef{rowType, rowID}, columns)
+	}
+	return err
+}
+
+func (client *Storer) DeleteRow(ctx context.Context, rowType, childType, rowID string) error {
+	err := client.next.DeleteRow(ctx, rowType, childType, rowID)
+	if err == nil {
+		client.publish(ctx, "DeleteRow", storage.ChangeDeleted, rowRef{rowType, rowID}, nil)
+	}
+	return err
+}
+
+func (client *Storer) PutRow(ctx context.Context, row storage.Row) error {
+	err := client.next.PutRow(ctx, row)
+	if err == nil {
+		client.publish(ctx, "PutRow", storage.ChangeUpdated, row, row.Columns())
+	}
+	return err
+}
+
+// PutRows publishes an event for each row once all of them are put.
+func (client *Storer) PutRows(ctx context.Context, rows []storage.Row) error {
+	err := storage.PutRows(ctx, client.next, rows)
+	if err == nil {
+		for _, row := range rows {
+			client.publish(ctx, "PutRows", storage.ChangeUpdated, row, row.Columns())
+		}
+	}
+	return err
+}
+
+// held holds the events of a transaction's writes until it commits.
+type held struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (h *held) Publish(_ context.Context, event Event) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.events = append(h.events, event)
+	return nil
+}
+
+func (client *Storer) DeleteColumn(ctx context.Context, rowType, rowID, columnName string) error {
+	err := storage.DeleteColumn(ctx, client.next, rowType, rowID, columnName)
+	if err == nil {
+		client.publish(ctx, "DeleteColumn", storage.ChangeUpdated, rowRef{rowType, rowID}, map[string]interface{}{columnName: nil})
+	}
+	return err
+}
+
+// WithinTx publishes the events of the transaction's writes once it commits,
+// and none if it doesn't.
+func (client *Storer) WithinTx(ctx context.Context, fn func(tx storage.RowStorer) error) error {
+	var events *held
+	err := storage.WithinTx(ctx, client.next, func(tx storage.RowStorer) error {
+		// a transaction that's retried starts over
+		events = &held{}
+		return fn(NewStorer(tx, events))
+	})
+	if err != nil {
+		return err
+	}
+	for _, event := range events.events {
+		client.send(ctx, event)
+	}
+	return nil
+}
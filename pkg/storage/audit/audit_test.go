@@ -0,0 +1,124 @@
+package audit_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/audit"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/memory"
+)
+
+// collecting keeps the events it's given, or fails to publish them with err.
+type collecting struct {
+	events []audit.Event
+	err    error
+}
+
+func (c *collecting) Publish(_ context.Context, event audit.Event) error {
+	if c.err != nil {
+		return c.err
+	}
+	c.events = append(c.events, event)
+	return nil
+}
+
+// summary describes events by their operation, kind and columns.
+func summary(events []audit.Event) []string {
+	described := make([]string, len(events))
+	for i, event := range events {
+		described[i] = fmt.Sprintf("%s %s %v", event.Operation, event.Kind, event.Columns)
+	}
+	return described
+}
+
+func TestPublishesWrites(t *testing.T) {
+	ctx := storage.WithCorrelationID(context.Background())
+	publisher := &collecting{}
+	storer := audit.NewStorer(memory.NewClient(), publisher)
+	org, err := storer.CreateRow(ctx, "org", "acme")
+	if err != nil {
+		t.Fatalf("CreateRow: %s", err)
+	}
+	team, err := storer.CreateChild(ctx, "team", "dev", "org", org.ID(), map[string]interface{}{"secret": "hunter2", "owner": "ops"})
+	if err != nil {
+		t.Fatalf("CreateChild: %s", err)
+	}
+	if err := storer.UpdateColumn(ctx, "team", team.ID(), "owner", "dev"); err != nil {
+		t.Fatalf("UpdateColumn: %s", err)
+	}
+	if err := storer.DeleteRow(ctx, "team", "", team.ID()); err != nil {
+		t.Fatalf("DeleteRow: %s", err)
+	}
+	// a failed write isn't published
+	if err := storer.UpdateColumn(ctx, "team", "team-missing", "owner", "dev"); err == nil {
+		t.Fatal("UpdateColumn of a missing row succeeded")
+	}
+
+	want := fmt.Sprint([]string{
+		fmt.Sprintf("CreateRow %s []", storage.ChangeCreated),
+		fmt.Sprintf("CreateChild %s [owner secret]", storage.ChangeCreated),
+		fmt.Sprintf("UpdateColumn %s [owner]", storage.ChangeUpdated),
+		fmt.Sprintf("DeleteRow %s []", storage.ChangeDeleted),
+	})
+	if got := fmt.Sprint(summary(publisher.events)); got != want {
+		t.Errorf("published %s, not %s", got, want)
+	}
+	for _, event := range publisher.events {
+		if event.CorrelationID != storage.CorrelationID(ctx) || event.Time.IsZero() {
+			t.Errorf("the %s event has correlation ID %q and time %s", event.Operation, event.CorrelationID, event.Time)
+		}
+	}
+	if created := publisher.events[1]; created.RowID != team.ID() || created.ParentID != org.ID() {
+		t.Errorf("the CreateChild event is of %s, child of %s", created.RowID, created.ParentID)
+	}
+}
+
+func TestPublishFails(t *testing.T) {
+	ctx := context.Background()
+	storer := audit.NewStorer(memory.NewClient(), &collecting{err: errors.New("the bus is down")})
+
+	// the write has happened, so it succeeds
+	if _, err := storer.CreateRow(ctx, "org", "acme"); err != nil {
+		t.Errorf("CreateRow failed with its event: %s", err)
+	}
+}
+
+func TestTransactions(t *testing.T) {
+	ctx := context.Background()
+	publisher := &collecting{}
+	storer := audit.NewStorer(memory.NewClient(), publisher)
+
+	rolledBack := errors.New("rolled back")
+	err := storer.WithinTx(ctx, func(tx storage.RowStorer) error {
+		if _, err := tx.CreateRow(ctx, "org", "acme"); err != nil {
+			return err
+		}
+		return rolledBack
+	})
+	if !errors.Is(err, rolledBack) {
+		t.Fatalf("WithinTx failed with %v, not %q", err, rolledBack)
+	}
+	if len(publisher.events) != 0 {
+		t.Errorf("a transaction rolled back published %v", summary(publisher.events))
+	}
+
+	err = storer.WithinTx(ctx, func(tx storage.RowStorer) error {
+		if _, err := tx.CreateRow(ctx, "org", "acme"); err != nil {
+			return err
+		}
+		if len(publisher.events) != 0 {
+			t.Error("a write was published before its transaction committed")
+		}
+		_, err := tx.CreateRow(ctx, "org", "globex")
+		return err
+	})
+	if err != nil {
+		t.Fatalf("WithinTx: %s", err)
+	}
+	if len(publisher.events) != 2 {
+		t.Errorf("a transaction of 2 writes published %v", summary(publisher.events))
+	}
+}
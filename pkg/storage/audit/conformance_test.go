@@ -0,0 +1,22 @@
+package audit_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/audit"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/memory"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/storagetest"
+)
+
+// discard publishes events nowhere.
+type discard struct{}
+
+func (discard) Publish(context.Context, audit.Event) error { return nil }
+
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.RowStorer {
+		return audit.NewStorer(memory.NewClient(), discard{})
+	})
+}
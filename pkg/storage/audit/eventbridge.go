@@ -0,0 +1,64 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge/types"
+)
+
+// detailType is the EventBridge detail-type of every audit event.
+const detailType = "Tree Row Change"
+
+// EventBridgePublisher puts events on an EventBridge event bus, with source
+// Source and detail-type "Tree Row Change". Rules on the bus can match on the
+// detail's kind and type.
+type EventBridgePublisher struct {
+	eventbridge *eventbridge.Client
+	bus         string
+}
+
+// NewEventBridgePublisher returns a publisher to the named bus, which may also
+// be given by ARN.
+func NewEventBridgePublisher(ctx context.Context, profile, region, bus string) (*EventBridgePublisher, error) {
+	cfg, err := config.LoadDefaultConfig(ctx,
+		config.WithSharedConfigProfile(profile),
+		config.WithRegion(region),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &EventBridgePublisher{
+		eventbridge: eventbridge.NewFromConfig(cfg),
+		bus:         bus,
+	}, nil
+}
+
+func (publisher *EventBridgePublisher) Publish(ctx context.Context, event Event) error {
+	detail, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	output, err := publisher.eventbridge.PutEvents(ctx, &eventbridge.PutEventsInput{
+		Entries: []types.PutEventsRequestEntry{{
+			EventBusName: aws.String(publisher.bus),
+			Source:       aws.String(Source),
+			DetailType:   aws.String(detailType),
+			Detail:       aws.String(string(detail)),
+			Time:         aws.Time(event.Time),
+		}},
+	})
+	if err != nil {
+		return err
+	}
+	// PutEvents reports a rejected entry in its output, not as an error.
+	if output.FailedEntryCount > 0 && len(output.Entries) > 0 {
+		entry := output.Entries[0]
+		return fmt.Errorf("event rejected: %s: %s", aws.ToString(entry.ErrorCode), aws.ToString(entry.ErrorMessage))
+	}
+	return nil
+}
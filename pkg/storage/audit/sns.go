@@ -0,0 +1,50 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go-v2/service/sns/types"
+)
+
+// SNSPublisher publishes events to an SNS topic, as JSON messages. The kind
+// and type are also set as message attributes, for subscription filter
+// policies.
+type SNSPublisher struct {
+	sns      *sns.Client
+	topicARN string
+}
+
+// NewSNSPublisher returns a publisher to the topic.
+func NewSNSPublisher(ctx context.Context, profile, region, topicARN string) (*SNSPublisher, error) {
+	cfg, err := config.LoadDefaultConfig(ctx,
+		config.WithSharedConfigProfile(profile),
+		config.WithRegion(region),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &SNSPublisher{
+		sns:      sns.NewFromConfig(cfg),
+		topicARN: topicARN,
+	}, nil
+}
+
+func (publisher *SNSPublisher) Publish(ctx context.Context, event Event) error {
+	message, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = publisher.sns.Publish(ctx, &sns.PublishInput{
+		TopicArn: aws.String(publisher.topicARN),
+		Message:  aws.String(string(message)),
+		MessageAttributes: map[string]types.MessageAttributeValue{
+			"kind": {DataType: aws.String("String"), StringValue: aws.String(string(event.Kind))},
+			"type": {DataType: aws.String("String"), StringValue: aws.String(event.RowType)},
+		},
+	})
+	return err
+}
@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
+
+// Jitter is how the delays before retries are randomized, so that many
+// clients throttled at once, such as the workers of a parallel apply, don't
+// all retry at once again.
+type Jitter string
+
+const (
+	// JitterFull waits a random time up to the exponential delay. It
+	// spreads retries the most.
+	JitterFull Jitter = "full"
+	// JitterEqual waits half the exponential delay, and a random time up to
+	// the other half, so that no retry comes too soon.
+	JitterEqual Jitter = "equal"
+	// JitterDecorrelated waits a random time between the base delay and
+	// three times the previous delay, up to Max, so that delays grow more
+	// slowly, and retries drift apart.
+	JitterDecorrelated Jitter = "decorrelated"
+)
+
+// ParseJitter returns the named jitter.
+func ParseJitter(name string) (Jitter, error) {
+	switch jitter := Jitter(name); jitter {
+	case JitterFull, JitterEqual, JitterDecorrelated:
+		return jitter, nil
+	}
+	return "", fmt.Errorf("unknown jitter %q: use %s, %s, or %s", name, JitterFull, JitterEqual, JitterDecorrelated)
+}
+
+// The defaults of a Backoff.
+const (
+	DefaultBackoffBase = 100 * time.Millisecond
+	DefaultBackoffMax  = 20 * time.Second
+)
+
+// Backoff is the delay before each retry: exponential, from Base for the
+// first retry, doubling for each after it up to Max, and randomized by
+// Jitter. Zero values take the defaults: full jitter, from 100ms up to 20s.
+type Backoff struct {
+	Jitter Jitter
+	Base   time.Duration
+	Max    time.Duration
+}
+
+func (backoff Backoff) withDefaults() Backoff {
+	if backoff.Jitter == "" {
+		backoff.Jitter = JitterFull
+	}
+	if backoff.Base <= 0 {
+		backoff.Base = DefaultBackoffBase
+	}
+	if backoff.Max <= 0 {
+		backoff.Max = DefaultBackoffMax
+	}
+	return backoff
+}
+
+// Delay returns the delay before the attempt'th retry, counting from 1, given
+// prev, the delay before the retry before it, or 0 before the first. Only
+// decorrelated jitter uses prev: its delays follow from each other rather
+// than from the attempt, so callers keep the last one.
+func (backoff Backoff) Delay(attempt int, prev time.Duration) time.Duration {
+	backoff = backoff.withDefaults()
+	if backoff.Jitter == JitterDecorrelated {
+		// sleep = min(cap, random_between(base, sleep * 3))
+		prev = min(max(prev, backoff.Base), backoff.Max)
+		return min(backoff.Base+randomDuration(prev*3-backoff.Base), backoff.Max)
+	}
+	if attempt < 1 {
+		attempt = 1
+	}
+	// the exponential delay, stopping at Max before it can overflow
+	delay := backoff.Base
+	for i := 1; i < attempt && delay < backoff.Max; i++ {
+		delay *= 2
+	}
+	delay = min(delay, backoff.Max)
+	if backoff.Jitter == JitterEqual {
+		return delay/2 + randomDuration(delay-delay/2)
+	}
+	return randomDuration(delay)
+}
+
+// randomDuration returns a random duration from 0 up to and including d.
+func randomDuration(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int64N(int64(d) + 1))
+}
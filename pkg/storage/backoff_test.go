@@ -0,0 +1,82 @@
+package storage_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/memory"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	base, limit := 10*time.Millisecond, 100*time.Millisecond
+	tests := []struct {
+		jitter   storage.Jitter
+		attempt  int
+		min, max time.Duration
+	}{
+		{storage.JitterFull, 1, 0, base},
+		{storage.JitterFull, 3, 0, 4 * base},
+		{storage.JitterFull, 10, 0, limit},
+		{storage.JitterEqual, 1, base / 2, base},
+		{storage.JitterEqual, 3, 2 * base, 4 * base},
+		{storage.JitterEqual, 10, limit / 2, limit},
+		// however many attempts there have been, it doesn't overflow
+		{storage.JitterEqual, 100, limit / 2, limit},
+	}
+	for _, test := range tests {
+		backoff := storage.Backoff{Jitter: test.jitter, Base: base, Max: limit}
+		for i := 0; i < 100; i++ {
+			if delay := backoff.Delay(test.attempt, 0); delay < test.min || delay > test.max {
+				t.Errorf("%s jitter's delay before retry %d is %s, not between %s and %s", test.jitter, test.attempt, delay, test.min, test.max)
+				break
+			}
+		}
+	}
+}
+
+func TestBackoffDecorrelated(t *testing.T) {
+	base, limit := 10*time.Millisecond, 100*time.Millisecond
+	backoff := storage.Backoff{Jitter: storage.JitterDecorrelated, Base: base, Max: limit}
+	var delay time.Duration
+	for attempt := 1; attempt <= 100; attempt++ {
+		prev := delay
+		delay = backoff.Delay(attempt, prev)
+		if delay < base || delay > limit || delay > 3*max(prev, base) {
+			t.Fatalf("the delay after %s is %s", prev, delay)
+		}
+	}
+}
+
+func TestParseJitter(t *testing.T) {
+	if jitter, err := storage.ParseJitter("equal"); err != nil || jitter != storage.JitterEqual {
+		t.Errorf("ParseJitter(%q) is %q, %v", "equal", jitter, err)
+	}
+	if _, err := storage.ParseJitter("none"); err == nil {
+		t.Error("ParseJitter of an unknown jitter succeeded")
+	}
+}
+
+func TestWithRetryCanceled(t *testing.T) {
+	backend := memory.NewClient()
+	created, err := backend.CreateRow(context.Background(), "org", "initech")
+	if err != nil {
+		t.Fatalf("CreateRow: %s", err)
+	}
+	next := &failing{RowStorer: backend, err: storage.ErrThrottled, failures: 10}
+	policy := storage.RetryPolicy{Attempts: 10, Backoff: storage.Backoff{Jitter: storage.JitterEqual, Base: time.Second, Max: time.Second}}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	// it stops waiting, rather than sleeping out the backoff
+	start := time.Now()
+	_, err = storage.WithRetry(next, policy).GetRowByID(ctx, "org", created.ID())
+	if !errors.Is(err, storage.ErrThrottled) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("GetRowByID retrying past its deadline failed with %v", err)
+	}
+	if took := time.Since(start); took > 400*time.Millisecond || next.calls != 1 {
+		t.Errorf("GetRowByID was tried %d times over %s, past its deadline", next.calls, took)
+	}
+}
@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"context"
+	"errors"
+)
+
+// BatchPutter is implemented by storage backends that can put many rows in
+// fewer requests than putting them one at a time, for bulk imports.
+type BatchPutter interface {
+	// PutRows puts each row as PutRow does. The rows aren't put atomically:
+	// after an error, some of them may have been put. A row's ID may appear
+	// only once among the rows.
+	PutRows(ctx context.Context, rows []Row) error
+}
+
+// PutRows puts the rows as BatchPutter does, putting them one at a time if
+// the storer isn't a BatchPutter.
+func PutRows(ctx context.Context, storer RowStorer, rows []Row) error {
+	if putter, ok := storer.(BatchPutter); ok {
+		return putter.PutRows(ctx, rows)
+	}
+	for _, row := range rows {
+		if err := storer.PutRow(ctx, row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BatchGetter is implemented by storage backends that can read many rows by
+// ID in fewer requests than reading them one at a time.
+type BatchGetter interface {
+	// GetRowsByIDs returns the rows of the type with the IDs, in the order
+	// of ids, each once, leaving out IDs that no row of the type has.
+	GetRowsByIDs(ctx context.Context, rowType string, ids []string) ([]Row, error)
+}
+
+// GetRowsByIDs returns the rows of the type with the IDs, as BatchGetter
+// does, reading them one at a time if the storer isn't a BatchGetter.
+func GetRowsByIDs(ctx context.Context, storer RowStorer, rowType string, ids []string) ([]Row, error) {
+	if getter, ok := storer.(BatchGetter); ok {
+		return getter.GetRowsByIDs(ctx, rowType, ids)
+	}
+	rows := []Row{}
+	seen := map[string]bool{}
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		row, err := storer.GetRowByID(ctx, rowType, id)
+		if errors.Is(err, ErrNotFoundRow) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
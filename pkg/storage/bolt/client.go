@@ -0,0 +1,716 @@
+// Package bolt implements storage.RowStorer in a bbolt database file, for
+// small teams that want the provider and schemactl as single binaries, with
+// no database server and no cgo. Each type's rows are kept in a bucket of
+// their own, by ID, beside index buckets of rows by label and by parent,
+// which keep the same uniqueness rules as the in-memory backend. Labels and
+// IDs may not contain NUL bytes, which separate the parts of index keys.
+//
+// One process at a time may open a database file.
+package bolt
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/spilliams/tree-terraform-provider/internal/slug"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+	bbolt "go.etcd.io/bbolt"
+)
+
+var (
+	// rowsBucket holds a bucket per type, of its rows' values by ID.
+	rowsBucket = []byte("rows")
+	// labelsBucket holds a bucket per type, of keys "<label>\x00<id>".
+	labelsBucket = []byte("labels")
+	// childrenBucket holds keys "<parent ID>\x00<label>\x00<type>\x00<id>",
+	// for every row with a parent.
+	childrenBucket = []byte("children")
+)
+
+// separator separates the parts of index keys.
+const separator = "\x00"
+
+// pageSize is how many rows ScanRows reads in one transaction, before calling
+// fn on them outside of it.
+const pageSize = 1000
+
+// openTimeout is how long NewClient waits for another process to close the
+// database.
+const openTimeout = time.Second
+
+type key struct {
+	rowType string
+	id      string
+}
+
+type Client struct {
+	db *bbolt.DB
+	// tx is the transaction of a client given to WithinTx's fn, which its
+	// reads and writes use rather than their own
+	tx *bbolt.Tx
+}
+
+var (
+	_ storage.RowStorer          = &Client{}
+	_ storage.ChildScanner       = &Client{}
+	_ storage.ColumnPatcher      = &Client{}
+	_ storage.ColumnDeleter      = &Client{}
+	_ storage.ApproximateCounter = &Client{}
+	_ storage.Transactor         = &Client{}
+)
+
+// NewClient opens the database file at path, creating it and its buckets if
+// they don't exist. It fails with ErrConflict if another process has the
+// file open.
+func NewClient(ctx context.Context, path string) (storage.RowStorer, error) {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemBolt, fmt.Sprintf("NewClient %q", path))
+	if path == "" {
+		return nil, fmt.Errorf("%w: a database path is required", storage.ErrInvalid)
+	}
+	db, err := bbolt.Open(path, 0o600, &bbolt.Options{Timeout: openTimeout})
+	if errors.Is(err, bbolt.ErrTimeout) {
+		return nil, fmt.Errorf("%w: %s is open in another process", storage.ErrConflict, path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bbolt.Tx) error {
+		for _, name := range [][]byte{rowsBucket, labelsBucket, childrenBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Client{db: db}, nil
+}
+
+// Close closes the database file, for another process to open.
+func (client *Client) Close() error {
+	return client.db.Close()
+}
+
+// view calls fn with the client's transaction, if it has one, or with a new
+// read-only one.
+func (client *Client) view(fn func(tx *bbolt.Tx) error) error {
+	if client.tx != nil {
+		return fn(client.tx)
+	}
+	return client.db.View(fn)
+}
+
+// update calls fn with the client's transaction, if it has one, or with a new
+// one, which it commits if fn returns nil.
+func (client *Client) update(fn func(tx *bbolt.Tx) error) error {
+	if client.tx != nil {
+		return fn(client.tx)
+	}
+	return client.db.Update(fn)
+}
+
+// WithinTx calls fn with a client whose reads and writes are made in one
+// transaction. bbolt writes one transaction at a time, so fn must not use
+// this client meanwhile, or it waits forever. Within a transaction, WithinTx
+// joins it.
+func (client *Client) WithinTx(ctx context.Context, fn func(tx storage.RowStorer) error) error {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemBolt, "WithinTx")
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return client.update(func(tx *bbolt.Tx) error {
+		return fn(&Client{db: client.db, tx: tx})
+	})
+}
+
+func (client *Client) GetRowByID(ctx context.Context, rowType, id string) (storage.Row, error) {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemBolt, fmt.Sprintf("GetRowByID %q", id))
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var r *row
+	err := client.view(func(tx *bbolt.Tx) error {
+		var err error
+		r, err = getRow(tx, rowType, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (client *Client) GetRow(ctx context.Context, rowType, label string) (storage.Row, error) {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemBolt, fmt.Sprintf("GetRow %q %q", rowType, label))
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var r *row
+	err := client.view(func(tx *bbolt.Tx) error {
+		ids := labeled(tx, rowType, label)
+		if len(ids) == 0 {
+			return fmt.Errorf("%w: type %q and label %q", storage.ErrNotFoundRow, rowType, label)
+		}
+		if len(ids) > 1 {
+			return fmt.Errorf("%w: type %q and label %q", storage.ErrTooManyFound, rowType, label)
+		}
+		var err error
+		r, err = getRow(tx, rowType, ids[0])
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (client *Client) CreateRow(ctx context.Context, rowType, label string) (storage.Row, error) {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemBolt, fmt.Sprintf("CreateRow %q %q", rowType, label))
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var r *row
+	err := client.update(func(tx *bbolt.Tx) error {
+		// make sure type+name doesn't collide
+		if len(labeled(tx, rowType, label)) > 0 {
+			return storage.ErrCollisionTypeLabel
+		}
+		r = &row{
+			RowType:    rowType,
+			RowID:      newID(ctx, tx, rowType),
+			RowLabel:   label,
+			RowColumns: map[string]interface{}{},
+		}
+		return putRow(tx, r, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (client *Client) CreateChild(ctx context.Context, rowType, label, parentType, parentID string, columns map[string]interface{}) (storage.Row, error) {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemBolt, fmt.Sprintf("CreateChild %q %q %q %q", rowType, label, parentType, parentID))
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var r *row
+	err := client.update(func(tx *bbolt.Tx) error {
+		// make sure parent exists
+		if _, err := getRow(tx, parentType, parentID); err != nil {
+			return err
+		}
+		// make sure label is unique within the parent
+		if len(labeledChildren(tx, parentID, label)) > 0 {
+			return storage.ErrCollisionParentLabel
+		}
+		r = &row{
+			RowType:     rowType,
+			RowID:       newID(ctx, tx, rowType),
+			RowLabel:    label,
+			RowParentID: parentID,
+			RowColumns:  columns,
+		}
+		if err := putRow(tx, r, nil); err != nil {
+			return err
+		}
+		// the row read back has its own columns, as JSON decodes them
+		var err error
+		r, err = getRow(tx, rowType, r.RowID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (client *Client) GetChild(ctx context.Context, label, parentID string) (storage.Row, error) {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemBolt, fmt.Sprintf("GetChild %q %q", label, parentID))
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var r *row
+	err := client.view(func(tx *bbolt.Tx) error {
+		found := labeledChildren(tx, parentID, label)
+		if len(found) == 0 {
+			return fmt.Errorf("%w with parent ID %q and label %q", storage.ErrNotFoundRow, parentID, label)
+		}
+		if len(found) > 1 {
+			return fmt.Errorf("%w: parent ID %q and label %q", storage.ErrTooManyFound, parentID, label)
+		}
+		var err error
+		r, err = getRow(tx, found[0].rowType, found[0].id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// ListRows lists the type's rows in order of ID, or, with parentIDFilter,
+// the parent's children of the type, read through the parent index.
+func (client *Client) ListRows(ctx context.Context, rowType, labelFilter, parentIDFilter string) ([]storage.Row, error) {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemBolt, fmt.Sprintf("ListRows %q %q %q", rowType, labelFilter, parentIDFilter))
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	found := []storage.Row{}
+	err := client.view(func(tx *bbolt.Tx) error {
+		if parentIDFilter != "" {
+			for _, k := range children(tx, parentIDFilter) {
+				if k.rowType != rowType {
+					continue
+				}
+				r, err := getRow(tx, k.rowType, k.id)
+				if err != nil {
+					return err
+				}
+				if strings.Contains(r.RowLabel, labelFilter) {
+					found = append(found, r)
+				}
+			}
+			return nil
+		}
+		rows := tx.Bucket(rowsBucket).Bucket([]byte(rowType))
+		if rows == nil {
+			return nil
+		}
+		return rows.ForEach(func(id, encoded []byte) error {
+			r, err := decodeRow(rowType, string(id), encoded)
+			if err != nil {
+				return err
+			}
+			if strings.Contains(r.RowLabel, labelFilter) {
+				found = append(found, r)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	if parentIDFilter != "" {
+		// the parent index orders children by label
+		sort.Slice(found, func(i, j int) bool { return found[i].ID() < found[j].ID() })
+	}
+	return found, nil
+}
+
+func (client *Client) UpdateRow(ctx context.Context, rowType, id, newLabel string) (storage.Row, error) {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemBolt, fmt.Sprintf("UpdateRow %q %q %q", rowType, id, newLabel))
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var r *row
+	err := client.update(func(tx *bbolt.Tx) error {
+		old, err := getRow(tx, rowType, id)
+		if err != nil {
+			return err
+		}
+
+		// ensure new label is available
+		if old.RowParentID == "" {
+			for _, other := range labeled(tx, rowType, newLabel) {
+				if other != id {
+					return storage.ErrCollisionTypeLabel
+				}
+			}
+		} else {
+			for _, other := range labeledChildren(tx, old.RowParentID, newLabel) {
+				if other != (key{rowType, id}) {
+					return storage.ErrCollisionParentLabel
+				}
+			}
+		}
+
+		updated := *old
+		updated.RowLabel = newLabel
+		r = &updated
+		return putRow(tx, r, old)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (client *Client) UpdateChild(ctx context.Context, childType, childID, newChildLabel, parentType, newParentID string) (storage.Row, error) {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemBolt, fmt.Sprintf("UpdateChild %q %q %q %q %q", childType, childID, newChildLabel, parentType, newParentID))
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var r *row
+	err := client.update(func(tx *bbolt.Tx) error {
+		// ensure new parent exists
+		if _, err := getRow(tx, parentType, newParentID); err != nil {
+			return err
+		}
+		old, err := getRow(tx, childType, childID)
+		if err != nil {
+			return err
+		}
+
+		// ensure new label is available
+		for _, other := range labeledChildren(tx, newParentID, newChildLabel) {
+			if other != (key{childType, childID}) {
+				return storage.ErrCollisionParentLabel
+			}
+		}
+
+		updated := *old
+		updated.RowLabel = newChildLabel
+		updated.RowParentID = newParentID
+		r = &updated
+		return putRow(tx, r, old)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (client *Client) UpdateColumn(ctx context.Context, rowType, rowID, columnName string, columnValue interface{}) error {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemBolt, fmt.Sprintf("UpdateColumn %q %q %q %s", rowType, rowID, columnName, storage.LogColumnValue(rowType, columnName, columnValue)))
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return client.update(func(tx *bbolt.Tx) error {
+		return patchColumns(tx, rowType, rowID, map[string]interface{}{columnName: columnValue})
+	})
+}
+
+// PatchColumns applies every patch in one transaction, or none of them if a
+// row is missing.
+func (client *Client) PatchColumns(ctx context.Context, patches []storage.ColumnPatch) error {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemBolt, fmt.Sprintf("PatchColumns %d", len(patches)))
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return client.update(func(tx *bbolt.Tx) error {
+		for _, patch := range patches {
+			if err := patchColumns(tx, patch.Type, patch.ID, patch.Columns); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// patchColumns sets some of a row's columns, leaving its others as they are.
+func patchColumns(tx *bbolt.Tx, rowType, rowID string, patch map[string]interface{}) error {
+	r, err := getRow(tx, rowType, rowID)
+	if err != nil {
+		return err
+	}
+	for name, value := range patch {
+		r.RowColumns[name] = value
+	}
+	return putRow(tx, r, r)
+}
+
+func (client *Client) UpdateColumns(ctx context.Context, rowType, rowID string, columns map[string]interface{}) error {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemBolt, fmt.Sprintf("UpdateColumns %q %q %s", rowType, rowID, storage.LogColumns(rowType, columns)))
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return client.update(func(tx *bbolt.Tx) error {
+		r, err := getRow(tx, rowType, rowID)
+		if err != nil {
+			return err
+		}
+		r.RowColumns = columns
+		return putRow(tx, r, r)
+	})
+}
+
+func (client *Client) DeleteColumn(ctx context.Context, rowType, rowID, columnName string) error {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemBolt, fmt.Sprintf("DeleteColumn %q %q %q", rowType, rowID, columnName))
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return client.update(func(tx *bbolt.Tx) error {
+		r, err := getRow(tx, rowType, rowID)
+		if err != nil {
+			return err
+		}
+		delete(r.RowColumns, columnName)
+		return putRow(tx, r, r)
+	})
+}
+
+func (client *Client) DeleteRow(ctx context.Context, rowType, childType, id string) error {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemBolt, fmt.Sprintf("DeleteRow %q %q %q", rowType, childType, id))
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return client.update(func(tx *bbolt.Tx) error {
+		// ensure this row does not have any children
+		if len(childType) > 0 {
+			for _, k := range children(tx, id) {
+				if k.rowType == childType {
+					return fmt.Errorf("%s %s has children: %w", rowType, id, storage.ErrCannotDeleteRow)
+				}
+			}
+		}
+
+		r, err := getRow(tx, rowType, id)
+		if err != nil {
+			return err
+		}
+		if err := unindex(tx, r); err != nil {
+			return err
+		}
+		return tx.Bucket(rowsBucket).Bucket([]byte(rowType)).Delete([]byte(id))
+	})
+}
+
+func (client *Client) PutRow(ctx context.Context, r storage.Row) error {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemBolt, fmt.Sprintf("PutRow %q %q", r.Type(), r.ID()))
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return client.update(func(tx *bbolt.Tx) error {
+		old, err := getRow(tx, r.Type(), r.ID())
+		if errors.Is(err, storage.ErrNotFoundRow) {
+			old = nil
+		} else if err != nil {
+			return err
+		}
+		return putRow(tx, &row{
+			RowType:     r.Type(),
+			RowID:       r.ID(),
+			RowLabel:    r.Label(),
+			RowParentID: r.ParentID(),
+			RowColumns:  r.Columns(),
+		}, old)
+	})
+}
+
+// ScanRows calls fn on every row, in order of type and ID, reading them a
+// page at a time, so that fn may use the client.
+func (client *Client) ScanRows(ctx context.Context, fn func(storage.Row) error) error {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemBolt, "ScanRows")
+	after := key{}
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		page := []*row{}
+		err := client.view(func(tx *bbolt.Tx) error {
+			types := tx.Bucket(rowsBucket).Cursor()
+			for name, _ := types.Seek([]byte(after.rowType)); name != nil && len(page) < pageSize; name, _ = types.Next() {
+				rowType := string(name)
+				rows := types.Bucket().Bucket(name).Cursor()
+				id, encoded := rows.First()
+				if rowType == after.rowType {
+					// carry on after the last row of the page before
+					id, encoded = rows.Seek([]byte(after.id))
+					if id != nil && string(id) == after.id {
+						id, encoded = rows.Next()
+					}
+				}
+				for ; id != nil && len(page) < pageSize; id, encoded = rows.Next() {
+					r, err := decodeRow(rowType, string(id), encoded)
+					if err != nil {
+						return err
+					}
+					page = append(page, r)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, r := range page {
+			if err := fn(r); err != nil {
+				return err
+			}
+		}
+		if len(page) < pageSize {
+			return nil
+		}
+		last := page[len(page)-1]
+		after = key{last.RowType, last.RowID}
+	}
+}
+
+// ScanChildren calls fn on each child of the row with parentID, in order of
+// label, reading them from the parent index in one transaction and calling
+// fn outside of it.
+func (client *Client) ScanChildren(ctx context.Context, parentID string, fn func(storage.Row) error) error {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemBolt, fmt.Sprintf("ScanChildren %q", parentID))
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	found := []*row{}
+	err := client.view(func(tx *bbolt.Tx) error {
+		for _, k := range children(tx, parentID) {
+			r, err := getRow(tx, k.rowType, k.id)
+			if err != nil {
+				return err
+			}
+			found = append(found, r)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, r := range found {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ApproximateCount counts the keys of the types' buckets, and returns the
+// size of the database file. Both are exact.
+func (client *Client) ApproximateCount(ctx context.Context) (storage.ApproximateCount, error) {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemBolt, "ApproximateCount")
+	var count storage.ApproximateCount
+	err := client.view(func(tx *bbolt.Tx) error {
+		count.Bytes = tx.Size()
+		rows := tx.Bucket(rowsBucket)
+		return rows.ForEachBucket(func(name []byte) error {
+			count.Rows += int64(rows.Bucket(name).Stats().KeyN)
+			return nil
+		})
+	})
+	return count, err
+}
+
+// getRow reads the row with the type and ID.
+func getRow(tx *bbolt.Tx, rowType, id string) (*row, error) {
+	rows := tx.Bucket(rowsBucket).Bucket([]byte(rowType))
+	if rows == nil {
+		return nil, fmt.Errorf("%w: %q", storage.ErrNotFoundRow, id)
+	}
+	encoded := rows.Get([]byte(id))
+	if encoded == nil {
+		return nil, fmt.Errorf("%w: %q", storage.ErrNotFoundRow, id)
+	}
+	return decodeRow(rowType, id, encoded)
+}
+
+// putRow stores the row, replacing old, the row stored with its type and ID
+// if there is one, in the indexes.
+func putRow(tx *bbolt.Tx, r, old *row) error {
+	if strings.Contains(r.RowLabel+r.RowID+r.RowParentID, separator) {
+		return fmt.Errorf("%w: labels and IDs can't contain NUL bytes", storage.ErrInvalid)
+	}
+	if r.RowType == "" || r.RowID == "" {
+		return fmt.Errorf("%w: a row needs a type and an ID", storage.ErrInvalid)
+	}
+	encoded, err := encodeRow(r)
+	if err != nil {
+		return err
+	}
+	if old != nil {
+		if err := unindex(tx, old); err != nil {
+			return err
+		}
+	}
+	rows, err := tx.Bucket(rowsBucket).CreateBucketIfNotExists([]byte(r.RowType))
+	if err != nil {
+		return err
+	}
+	if err := rows.Put([]byte(r.RowID), encoded); err != nil {
+		return err
+	}
+	labels, err := tx.Bucket(labelsBucket).CreateBucketIfNotExists([]byte(r.RowType))
+	if err != nil {
+		return err
+	}
+	if err := labels.Put(labelKey(r.RowLabel, r.RowID), nil); err != nil {
+		return err
+	}
+	if r.RowParentID == "" {
+		return nil
+	}
+	return tx.Bucket(childrenBucket).Put(childKey(r), nil)
+}
+
+// unindex removes the row from the indexes.
+func unindex(tx *bbolt.Tx, r *row) error {
+	if labels := tx.Bucket(labelsBucket).Bucket([]byte(r.RowType)); labels != nil {
+		if err := labels.Delete(labelKey(r.RowLabel, r.RowID)); err != nil {
+			return err
+		}
+	}
+	if r.RowParentID == "" {
+		return nil
+	}
+	return tx.Bucket(childrenBucket).Delete(childKey(r))
+}
+
+func labelKey(label, id string) []byte {
+	return []byte(label + separator + id)
+}
+
+func childKey(r *row) []byte {
+	return []byte(strings.Join([]string{r.RowParentID, r.RowLabel, r.RowType, r.RowID}, separator))
+}
+
+// labeled returns the IDs of the type's rows with the label, in order.
+func labeled(tx *bbolt.Tx, rowType, label string) []string {
+	labels := tx.Bucket(labelsBucket).Bucket([]byte(rowType))
+	if labels == nil {
+		return nil
+	}
+	ids := []string{}
+	prefix := []byte(label + separator)
+	c := labels.Cursor()
+	for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+		ids = append(ids, string(k[len(prefix):]))
+	}
+	return ids
+}
+
+// children returns the keys of the parent's children, in order of label.
+func children(tx *bbolt.Tx, parentID string) []key {
+	return childKeys(tx, []byte(parentID+separator))
+}
+
+// labeledChildren returns the keys of the parent's children with the label.
+func labeledChildren(tx *bbolt.Tx, parentID, label string) []key {
+	return childKeys(tx, []byte(parentID+separator+label+separator))
+}
+
+// childKeys returns the keys of the children whose keys in the parent index
+// begin with prefix.
+func childKeys(tx *bbolt.Tx, prefix []byte) []key {
+	found := []key{}
+	c := tx.Bucket(childrenBucket).Cursor()
+	for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+		parts := strings.Split(string(k), separator)
+		found = append(found, key{parts[2], parts[3]})
+	}
+	return found
+}
+
+// newID generates an ID that isn't in use.
+func newID(ctx context.Context, tx *bbolt.Tx, rowType string) string {
+	for {
+		id := slug.Generate(rowType)
+		if _, err := getRow(tx, rowType, id); err != nil {
+			tflog.SubsystemTrace(ctx, storage.LogSubsystemSlug, fmt.Sprintf("Generated ID %q", id))
+			return id
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package bolt_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/bolt"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/storagetest"
+)
+
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.RowStorer {
+		client, err := bolt.NewClient(context.Background(), filepath.Join(t.TempDir(), "rows.db"))
+		if err != nil {
+			t.Fatalf("NewClient: %s", err)
+		}
+		t.Cleanup(func() { client.(*bolt.Client).Close() })
+		return client
+	})
+}
@@ -0,0 +1,75 @@
+package bolt
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+type row struct {
+	RowType     string
+	RowID       string
+	RowLabel    string
+	RowParentID string
+	RowColumns  map[string]interface{}
+}
+
+// value is what a row is stored as, under its ID in its type's bucket.
+type value struct {
+	Label    string                 `json:"label"`
+	ParentID string                 `json:"parent_id,omitempty"`
+	Columns  map[string]interface{} `json:"columns"`
+}
+
+func encodeRow(r *row) ([]byte, error) {
+	columns := r.RowColumns
+	if columns == nil {
+		columns = map[string]interface{}{}
+	}
+	b, err := json.Marshal(value{Label: r.RowLabel, ParentID: r.RowParentID, Columns: columns})
+	if err != nil {
+		return nil, fmt.Errorf("%w: encoding columns: %s", storage.ErrInvalid, err)
+	}
+	return b, nil
+}
+
+// decodeRow returns the row with the type and ID stored as encoded, with JSON
+// arrays as string sets, the only kind of array rows store.
+func decodeRow(rowType, id string, encoded []byte) (*row, error) {
+	var v value
+	if err := json.Unmarshal(encoded, &v); err != nil {
+		return nil, fmt.Errorf("decoding %s %s: %w", rowType, id, err)
+	}
+	if v.Columns == nil {
+		v.Columns = map[string]interface{}{}
+	}
+	for name, column := range v.Columns {
+		list, ok := column.([]interface{})
+		if !ok {
+			continue
+		}
+		set := make([]string, 0, len(list))
+		for _, element := range list {
+			s, ok := element.(string)
+			if !ok {
+				return nil, fmt.Errorf("decoding %s %s: column %q isn't a set of strings", rowType, id, name)
+			}
+			set = append(set, s)
+		}
+		v.Columns[name] = set
+	}
+	return &row{
+		RowType:     rowType,
+		RowID:       id,
+		RowLabel:    v.Label,
+		RowParentID: v.ParentID,
+		RowColumns:  v.Columns,
+	}, nil
+}
+
+func (r *row) Type() string                    { return r.RowType }
+func (r *row) ID() string                      { return r.RowID }
+func (r *row) Label() string                   { return r.RowLabel }
+func (r *row) ParentID() string                { return r.RowParentID }
+func (r *row) Columns() map[string]interface{} { return r.RowColumns }
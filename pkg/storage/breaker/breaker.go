@@ -0,0 +1,327 @@
+// Package breaker wraps a storage.RowStorer with a circuit breaker, so that
+// when the backend fails again and again, as when it's unreachable, the
+// operations of a large plan fail fast with one clear error, rather than each
+// resource waiting for its own to fail.
+package breaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+// The defaults of NewStorer.
+const (
+	DefaultThreshold = 5
+	DefaultCooldown  = 30 * time.Second
+)
+
+// Storer is a storage.RowStorer that counts the failures of the one it wraps
+// in a row. After threshold of them, the breaker opens: operations fail at
+// once, with an error wrapping storage.ErrCircuitOpen, until the cooldown has
+// passed. Then one operation is let through to try the backend: if it
+// succeeds, the breaker closes again, and if it fails, it stays open for
+// another cooldown.
+//
+// Failures are errors of no kind, such as network errors and timeouts, and
+// throttling. Errors of other kinds, such as a row not found, are answers
+// from a backend that works, and so are successes. Operations canceled by
+// their context, rather than timed out, count as neither.
+type Storer struct {
+	next      storage.RowStorer
+	threshold int
+	cooldown  time.Duration
+
+	mu        sync.Mutex
+	failures  int
+	lastErr   error
+	openUntil time.Time
+	trying    bool
+}
+
+var (
+	_ storage.RowStorer     = &Storer{}
+	_ storage.Transactor    = &Storer{}
+	_ storage.ColumnDeleter = &Storer{}
+	_ storage.BatchGetter   = &Storer{}
+	_ storage.ColumnQuerier = &Storer{}
+	_ storage.ChildScanner  = &Storer{}
+	_ storage.BatchPutter   = &Storer{}
+	_ storage.ColumnPatcher = &Storer{}
+	_ storage.RowIterator   = &Storer{}
+)
+
+// NewStorer opens the breaker after threshold failures in a row, for cooldown
+// at a time. Zero values mean DefaultThreshold and DefaultCooldown.
+func NewStorer(next storage.RowStorer, threshold int, cooldown time.Duration) *Storer {
+	if threshold <= 0 {
+		threshold = DefaultThreshold
+	}
+	if cooldown <= 0 {
+		cooldown = DefaultCooldown
+	}
+	return &Storer{next: next, threshold: threshold, cooldown: cooldown}
+}
+
+// Unwrap returns the wrapped storer.
+func (client *Storer) Unwrap() storage.RowStorer {
+	return client.next
+}
+
+// allow returns an error if the breaker is open, and whether the operation
+// is the one let through to try the backend.
+func (client *Storer) allow(ctx context.Context, op string) (bool, error) {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	if client.failures < client.threshold {
+		return false, nil
+	}
+	if wait := time.Until(client.openUntil); client.trying || wait > 0 {
+		return false, fmt.Errorf("%w: %s not tried after %d failures of the storage backend in a row, the last: %v", storage.ErrCircuitOpen, op, client.failures, client.lastErr)
+	}
+	client.trying = true
+	tflog.SubsystemInfo(ctx, storage.LogSubsystemStorage, fmt.Sprintf("Circuit breaker trying the storage backend again with %s", op))
+	return true, nil
+}
+
+// done records the result of an operation the breaker let through.
+func (client *Storer) done(ctx context.Context, op string, trying bool, err error) {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	if trying {
+		client.trying = false
+	}
+	switch {
+	case err != nil && errors.Is(ctx.Err(), context.Canceled):
+		// canceled, which says nothing of the backend, unlike a timeout
+	case err != nil && (storage.ErrorKind(err) == nil || storage.ErrorKind(err) == storage.ErrThrottled):
+		client.failures++
+		client.lastErr = err
+		if client.failures == client.threshold || trying {
+			client.openUntil = time.Now().Add(client.cooldown)
+			tflog.SubsystemWarn(ctx, storage.LogSubsystemStorage, fmt.Sprintf("Circuit breaker open for %s after %d failures of the storage backend in a row", client.cooldown, client.failures), map[string]interface{}{
+				"operation": op,
+				"error":     err.Error(),
+			})
+		}
+	default:
+		if client.failures >= client.threshold {
+			tflog.SubsystemInfo(ctx, storage.LogSubsystemStorage, "Circuit breaker closed: the storage backend answered")
+		}
+		client.failures = 0
+		client.lastErr = nil
+	}
+}
+
+// call calls fn unless the breaker is open, and records its result.
+func (client *Storer) call(ctx context.Context, op string, fn func() error) error {
+	trying, err := client.allow(ctx, op)
+	if err != nil {
+		return err
+	}
+	err = fn()
+	client.done(ctx, op, trying, err)
+	return err
+}
+
+func (client *Storer) GetRowByID(ctx context.Context, rowType, rowID string) (row storage.Row, err error) {
+	err = client.call(ctx, "GetRowByID", func() (err error) {
+		row, err = client.next.GetRowByID(ctx, rowType, rowID)
+		return err
+	})
+	return row, err
+}
+
+func (client *Storer) GetRowsByIDs(ctx context.Context, rowType string, ids []string) (rows []storage.Row, err error) {
+	err = client.call(ctx, "GetRowsByIDs", func() (err error) {
+		rows, err = storage.GetRowsByIDs(ctx, client.next, rowType, ids)
+		return err
+	})
+	return rows, err
+}
+
+func (client *Storer) GetRow(ctx context.Context, rowType, rowLabel string) (row storage.Row, err error) {
+	err = client.call(ctx, "GetRow", func() (err error) {
+		row, err = client.next.GetRow(ctx, rowType, rowLabel)
+		return err
+	})
+	return row, err
+}
+
+func (client *Storer) CreateRow(ctx context.Context, rowType, rowLabel string) (row storage.Row, err error) {
+	err = client.call(ctx, "CreateRow", func() (err error) {
+		row, err = client.next.CreateRow(ctx, rowType, rowLabel)
+		return err
+	})
+	return row, err
+}
+
+func (client *Storer) CreateChild(ctx context.Context, rowType, rowLabel, parentType, parentID string, columns map[string]interface{}) (row storage.Row, err error) {
+	err = client.call(ctx, "CreateChild", func() (err error) {
+		row, err = client.next.CreateChild(ctx, rowType, rowLabel, parentType, parentID, columns)
+		return err
+	})
+	return row, err
+}
+
+func (client *Storer) GetChild(ctx context.Context, childLabel, parentID string) (row storage.Row, err error) {
+	err = client.call(ctx, "GetChild", func() (err error) {
+		row, err = client.next.GetChild(ctx, childLabel, parentID)
+		return err
+	})
+	return row, err
+}
+
+func (client *Storer) ListRows(ctx context.Context, rowType, labelFilter, parentIDFilter string) (rows []storage.Row, err error) {
+	err = client.call(ctx, "ListRows", func() (err error) {
+		rows, err = client.next.ListRows(ctx, rowType, labelFilter, parentIDFilter)
+		return err
+	})
+	return rows, err
+}
+
+func (client *Storer) IterRows(ctx context.Context, rowType, labelFilter, parentIDFilter string) *storage.Iter[storage.Row] {
+	return storage.WrapPages(storage.IterRows(ctx, client.next, rowType, labelFilter, parentIDFilter), func(ctx context.Context, fetch storage.PageFunc[storage.Row]) (page []storage.Row, more bool, err error) {
+		err = client.call(ctx, "IterRows", func() (err error) {
+			page, more, err = fetch(ctx)
+			return err
+		})
+		return page, more, err
+	})
+}
+
+func (client *Storer) QueryByColumn(ctx context.Context, rowType, columnName string, value interface{}) (rows []storage.Row, err error) {
+	err = client.call(ctx, "QueryByColumn", func() (err error) {
+		rows, err = storage.QueryByColumn(ctx, client.next, rowType, columnName, value)
+		return err
+	})
+	return rows, err
+}
+
+func (client *Storer) UpdateRow(ctx context.Context, rowType, rowID, newLabel string) (row storage.Row, err error) {
+	err = client.call(ctx, "UpdateRow", func() (err error) {
+		row, err = client.next.UpdateRow(ctx, rowType, rowID, newLabel)
+		return err
+	})
+	return row, err
+}
+
+func (client *Storer) UpdateChild(ctx context.Context, childType, childID, newChildLabel, parentType, newParentID string) (row storage.Row, err error) {
+	err = client.call(ctx, "UpdateChild", func() (err error) {
+		row, err = client.next.UpdateChild(ctx, childType, childID, newChildLabel, parentType, newParentID)
+		return err
+	})
+	return row, err
+}
+
+func (client *Storer) UpdateColumn(ctx context.Context, rowType, rowID, columnName string, columnValue interface{}) error {
+	return client.call(ctx, "UpdateColumn", func() error {
+		return client.next.UpdateColumn(ctx, rowType, rowID, columnName, columnValue)
+	})
+}
+
+func (client *Storer) PatchColumns(ctx context.Context, patches []storage.ColumnPatch) error {
+	return client.call(ctx, "PatchColumns", func() error {
+		return storage.PatchColumns(ctx, client.next, patches)
+	})
+}
+
+func (client *Storer) UpdateColumns(ctx context.Context, rowType, rowID string, columns map[string]interface{}) error {
+	return client.call(ctx, "UpdateColumns", func() error {
+		return client.next.UpdateColumns(ctx, rowType, rowID, columns)
+	})
+}
+
+func (client *Storer) DeleteRow(ctx context.Context, rowType, childType, rowID string) error {
+	return client.call(ctx, "DeleteRow", func() error {
+		return client.next.DeleteRow(ctx, rowType, childType, rowID)
+	})
+}
+
+func (client *Storer) PutRow(ctx context.Context, row storage.Row) error {
+	return client.call(ctx, "PutRow", func() error {
+		return client.next.PutRow(ctx, row)
+	})
+}
+
+func (client *Storer) PutRows(ctx context.Context, rows []storage.Row) error {
+	return client.call(ctx, "PutRows", func() error {
+		return storage.PutRows(ctx, client.next, rows)
+	})
+}
+
+func (client *Storer) ScanRows(ctx context.Context, fn func(storage.Row) error) error {
+	var fnFailed bool
+	var scanErr error
+	err := client.call(ctx, "ScanRows", func() error {
+		scanErr = client.next.ScanRows(ctx, func(row storage.Row) error {
+			err := fn(row)
+			fnFailed = err != nil
+			return err
+		})
+		if fnFailed {
+			// fn's own error says nothing of the backend
+			return nil
+		}
+		return scanErr
+	})
+	if err != nil {
+		return err
+	}
+	return scanErr
+}
+
+// ScanChildren counts fn's errors as ScanRows does.
+func (client *Storer) ScanChildren(ctx context.Context, parentID string, fn func(storage.Row) error) error {
+	var fnFailed bool
+	var scanErr error
+	err := client.call(ctx, "ScanChildren", func() error {
+		scanErr = storage.ScanChildren(ctx, client.next, parentID, func(row storage.Row) error {
+			err := fn(row)
+			fnFailed = err != nil
+			return err
+		})
+		if fnFailed {
+			return nil
+		}
+		return scanErr
+	})
+	if err != nil {
+		return err
+	}
+	return scanErr
+}
+
+func (client *Storer) DeleteColumn(ctx context.Context, rowType, rowID, columnName string) error {
+	return client.call(ctx, "DeleteColumn", func() error {
+		return storage.DeleteColumn(ctx, client.next, rowType, rowID, columnName)
+	})
+}
+
+// WithinTx counts the transaction as one operation, which fails only if
+// making it does: fn's error may be its own, which says nothing of the
+// backend, as in ScanRows.
+func (client *Storer) WithinTx(ctx context.Context, fn func(tx storage.RowStorer) error) error {
+	var fnFailed bool
+	var txErr error
+	err := client.call(ctx, "WithinTx", func() error {
+		txErr = storage.WithinTx(ctx, client.next, func(tx storage.RowStorer) error {
+			err := fn(tx)
+			fnFailed = err != nil
+			return err
+		})
+		if fnFailed {
+			return nil
+		}
+		return txErr
+	})
+	if err != nil {
+		return err
+	}
+	return txErr
+}
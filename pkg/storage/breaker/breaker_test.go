@@ -0,0 +1,118 @@
+package breaker_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/breaker"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/memory"
+)
+
+var errUnreachable = errors.New("unreachable")
+
+// flaky fails its reads by ID with err while it's set, counting the reads
+// that reach it.
+type flaky struct {
+	storage.RowStorer
+	err   error
+	calls int
+}
+
+func (client *flaky) GetRowByID(ctx context.Context, rowType, rowID string) (storage.Row, error) {
+	client.calls++
+	if client.err != nil {
+		return nil, client.err
+	}
+	return client.RowStorer.GetRowByID(ctx, rowType, rowID)
+}
+
+// newFlaky returns a backend storing an org, and a breaker around it opening
+// after 3 failures for 50ms.
+func newFlaky(t *testing.T) (*flaky, *breaker.Storer, storage.Row) {
+	t.Helper()
+	backend := &flaky{RowStorer: memory.NewClient()}
+	org, err := backend.CreateRow(context.Background(), "org", "acme")
+	if err != nil {
+		t.Fatalf("CreateRow: %s", err)
+	}
+	return backend, breaker.NewStorer(backend, 3, 50*time.Millisecond), org
+}
+
+func TestOpens(t *testing.T) {
+	ctx := context.Background()
+	backend, storer, org := newFlaky(t)
+
+	backend.err = errUnreachable
+	for i := 0; i < 3; i++ {
+		if _, err := storer.GetRowByID(ctx, "org", org.ID()); !errors.Is(err, errUnreachable) {
+			t.Fatalf("GetRowByID %d failed with %v, not the backend's error", i+1, err)
+		}
+	}
+	_, err := storer.GetRowByID(ctx, "org", org.ID())
+	if !errors.Is(err, storage.ErrCircuitOpen) {
+		t.Errorf("GetRowByID after 3 failures failed with %v, not %q", err, storage.ErrCircuitOpen)
+	}
+	if backend.calls != 3 {
+		t.Errorf("the open breaker called the backend: %d calls, not 3", backend.calls)
+	}
+}
+
+func TestHalfOpens(t *testing.T) {
+	ctx := context.Background()
+	backend, storer, org := newFlaky(t)
+	backend.err = errUnreachable
+	for i := 0; i < 3; i++ {
+		storer.GetRowByID(ctx, "org", org.ID())
+	}
+
+	// after the cooldown, one failing try opens it again
+	time.Sleep(80 * time.Millisecond)
+	if _, err := storer.GetRowByID(ctx, "org", org.ID()); !errors.Is(err, errUnreachable) {
+		t.Fatalf("GetRowByID after the cooldown failed with %v, not the backend's error", err)
+	}
+	if _, err := storer.GetRowByID(ctx, "org", org.ID()); !errors.Is(err, storage.ErrCircuitOpen) {
+		t.Errorf("GetRowByID after a failed try failed with %v, not %q", err, storage.ErrCircuitOpen)
+	}
+	if backend.calls != 4 {
+		t.Errorf("the backend was called %d times, not 4", backend.calls)
+	}
+
+	// and one succeeding try closes it
+	time.Sleep(80 * time.Millisecond)
+	backend.err = nil
+	for i := 0; i < 2; i++ {
+		if _, err := storer.GetRowByID(ctx, "org", org.ID()); err != nil {
+			t.Errorf("GetRowByID %d after a successful try: %s", i+1, err)
+		}
+	}
+}
+
+func TestAnswersDontCount(t *testing.T) {
+	ctx := context.Background()
+	backend, storer, org := newFlaky(t)
+
+	backend.err = errUnreachable
+	for i := 0; i < 2; i++ {
+		storer.GetRowByID(ctx, "org", org.ID())
+	}
+	// a row not found is an answer, which resets the count
+	backend.err = storage.ErrNotFoundRow
+	storer.GetRowByID(ctx, "org", org.ID())
+	backend.err = errUnreachable
+	for i := 0; i < 2; i++ {
+		storer.GetRowByID(ctx, "org", org.ID())
+	}
+	// and a canceled operation is neither
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	backend.err = context.Canceled
+	storer.GetRowByID(canceled, "org", org.ID())
+
+	backend.err = nil
+	if _, err := storer.GetRowByID(ctx, "org", org.ID()); err != nil {
+		t.Errorf("GetRowByID after failures that weren't in a row: %s", err)
+	}
+}
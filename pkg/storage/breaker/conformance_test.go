@@ -0,0 +1,17 @@
+package breaker_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/breaker"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/memory"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/storagetest"
+)
+
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.RowStorer {
+		return breaker.NewStorer(memory.NewClient(), 5, time.Second)
+	})
+}
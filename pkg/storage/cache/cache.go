@@ -0,0 +1,225 @@
+// Package cache wraps a storage.RowStorer to keep the rows it reads in memory
+// for a while, so that a plan with hundreds of data sources reading the same
+// rows reads each from the backend once. Unlike DynamoDB's own read cache, it
+// works with any backend, and forgets rows after a time to live, so that a
+// long-lived client sees other writers' changes eventually.
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/spilliams/tree-terraform-provider/internal/lru"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+// Storer is a storage.RowStorer that caches the rows GetRowByID, GetRow and
+// GetChild return from the one it wraps. Writes through it update or drop
+// the rows they change; writes by other clients aren't seen until a row
+// expires.
+type Storer struct {
+	next storage.RowStorer
+	rows *lru.Cache
+}
+
+var (
+	_ storage.RowStorer     = &Storer{}
+	_ storage.Transactor    = &Storer{}
+	_ storage.ColumnDeleter = &Storer{}
+	_ storage.BatchGetter   = &Storer{}
+	_ storage.ColumnQuerier = &Storer{}
+	_ storage.ChildScanner  = &Storer{}
+	_ storage.BatchPutter   = &Storer{}
+	_ storage.ColumnPatcher = &Storer{}
+	_ storage.RowIterator   = &Storer{}
+)
+
+// NewStorer wraps next, caching the rows it reads for ttl, or until they're
+// evicted if ttl is 0. It keeps up to size rows, evicting the least recently
+// used first, or any number if size is 0.
+func NewStorer(next storage.RowStorer, ttl time.Duration, size int) *Storer {
+	return &Storer{next: next, rows: lru.New(size, ttl)}
+}
+
+// Unwrap returns the wrapped storer.
+func (client *Storer) Unwrap() storage.RowStorer {
+	return client.next
+}
+
+func (client *Storer) GetRowByID(ctx context.Context, rowType, rowID string) (storage.Row, error) {
+	if cached, ok := client.rows.GetByID(rowType, rowID); ok {
+		return cached, nil
+	}
+	r, err := client.next.GetRowByID(ctx, rowType, rowID)
+	if err != nil {
+		return nil, err
+	}
+	client.rows.Put(r)
+	return r, nil
+}
+
+// GetRowsByIDs reads the rows that aren't cached together, and caches them.
+func (client *Storer) GetRowsByIDs(ctx context.Context, rowType string, ids []string) ([]storage.Row, error) {
+	cached := map[string]storage.Row{}
+	missing := []string{}
+	for _, id := range ids {
+		if row, ok := client.rows.GetByID(rowType, id); ok {
+			cached[id] = row
+		} else {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) > 0 {
+		read, err := storage.GetRowsByIDs(ctx, client.next, rowType, missing)
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range read {
+			client.rows.Put(row)
+			cached[row.ID()] = row
+		}
+	}
+	rows := []storage.Row{}
+	for _, id := range ids {
+		if row, ok := cached[id]; ok {
+			rows = append(rows, row)
+			delete(cached, id)
+		}
+	}
+	return rows, nil
+}
+
+func (client *Storer) GetRow(ctx context.Context, rowType, rowLabel string) (storage.Row, error) {
+	if cached, ok := client.rows.GetByLabel(rowType, rowLabel); ok {
+		return cached, nil
+	}
+	r, err := client.next.GetRow(ctx, rowType, rowLabel)
+	if err != nil {
+		return nil, err
+	}
+	client.rows.PutLabeled(r)
+	return r, nil
+}
+
+func (client *Storer) CreateRow(ctx context.Context, rowType, rowLabel string) (storage.Row, error) {
+	r, err := client.next.CreateRow(ctx, rowType, rowLabel)
+	if err != nil {
+		return nil, err
+	}
+	client.rows.Put(r)
+	return r, nil
+}
+
+func (client *Storer) CreateChild(ctx context.Context, rowType, rowLabel, parentType, parentID string, columns map[string]interface{}) (storage.Row, error) {
+	r, err := client.next.CreateChild(ctx, rowType, rowLabel, parentType, parentID, columns)
+	if err != nil {
+		return nil, err
+	}
+	client.rows.Put(r)
+	return r, nil
+}
+
+func (client *Storer) GetChild(ctx context.Context, childLabel, parentID string) (storage.Row, error) {
+	if cached, ok := client.rows.GetChild(parentID, childLabel); ok {
+		return cached, nil
+	}
+	r, err := client.next.GetChild(ctx, childLabel, parentID)
+	if err != nil {
+		return nil, err
+	}
+	client.rows.Put(r)
+	return r, nil
+}
+
+// ListRows isn't cached: the rows a filter matches change with every write
+// of a row of the type.
+func (client *Storer) ListRows(ctx context.Context, rowType, labelFilter, parentIDFilter string) ([]storage.Row, error) {
+	return client.next.ListRows(ctx, rowType, labelFilter, parentIDFilter)
+}
+
+func (client *Storer) IterRows(ctx context.Context, rowType, labelFilter, parentIDFilter string) *storage.Iter[storage.Row] {
+	return storage.IterRows(ctx, client.next, rowType, labelFilter, parentIDFilter)
+}
+
+func (client *Storer) QueryByColumn(ctx context.Context, rowType, columnName string, value interface{}) ([]storage.Row, error) {
+	return storage.QueryByColumn(ctx, client.next, rowType, columnName, value)
+}
+
+func (client *Storer) UpdateRow(ctx context.Context, rowType, rowID, newLabel string) (storage.Row, error) {
+	// the row is dropped even if the update fails, since it may have failed
+	// because the cached row is out of date
+	client.rows.Invalidate(rowType, rowID)
+	r, err := client.next.UpdateRow(ctx, rowType, rowID, newLabel)
+	if err != nil {
+		return nil, err
+	}
+	client.rows.Put(r)
+	return r, nil
+}
+
+func (client *Storer) UpdateChild(ctx context.Context, childType, childID, newChildLabel, parentType, newParentID string) (storage.Row, error) {
+	client.rows.Invalidate(childType, childID)
+	r, err := client.next.UpdateChild(ctx, childType, childID, newChildLabel, parentType, newParentID)
+	if err != nil {
+		return nil, err
+	}
+	client.rows.Put(r)
+	return r, nil
+}
+
+func (client *Storer) UpdateColumn(ctx context.Context, rowType, rowID, columnName string, columnValue interface{}) error {
+	client.rows.Invalidate(rowType, rowID)
+	return client.next.UpdateColumn(ctx, rowType, rowID, columnName, columnValue)
+}
+
+func (client *Storer) PatchColumns(ctx context.Context, patches []storage.ColumnPatch) error {
+	for _, patch := range patches {
+		client.rows.Invalidate(patch.Type, patch.ID)
+	}
+	return storage.PatchColumns(ctx, client.next, patches)
+}
+
+func (client *Storer) UpdateColumns(ctx context.Context, rowType, rowID string, columns map[string]interface{}) error {
+	client.rows.Invalidate(rowType, rowID)
+	return client.next.UpdateColumns(ctx, rowType, rowID, columns)
+}
+
+func (client *Storer) DeleteRow(ctx context.Context, rowType, childType, rowID string) error {
+	client.rows.Invalidate(rowType, rowID)
+	return client.next.DeleteRow(ctx, rowType, childType, rowID)
+}
+
+func (client *Storer) PutRow(ctx context.Context, r storage.Row) error {
+	client.rows.Invalidate(r.Type(), r.ID())
+	client.rows.InvalidateLabel(r.Type(), r.Label())
+	return client.next.PutRow(ctx, r)
+}
+
+func (client *Storer) PutRows(ctx context.Context, rows []storage.Row) error {
+	for _, r := range rows {
+		client.rows.Invalidate(r.Type(), r.ID())
+		client.rows.InvalidateLabel(r.Type(), r.Label())
+	}
+	return storage.PutRows(ctx, client.next, rows)
+}
+
+func (client *Storer) ScanRows(ctx context.Context, fn func(storage.Row) error) error {
+	return client.next.ScanRows(ctx, fn)
+}
+
+func (client *Storer) ScanChildren(ctx context.Context, parentID string, fn func(storage.Row) error) error {
+	return storage.ScanChildren(ctx, client.next, parentID, fn)
+}
+
+func (client *Storer) DeleteColumn(ctx context.Context, rowType, rowID, columnName string) error {
+	client.rows.Invalidate(rowType, rowID)
+	return storage.DeleteColumn(ctx, client.next, rowType, rowID, columnName)
+}
+
+// WithinTx reads and writes past the cache, since the transaction's writes
+// aren't made until it commits, and then empties it, since it doesn't know
+// which rows they changed.
+func (client *Storer) WithinTx(ctx context.Context, fn func(tx storage.RowStorer) error) error {
+	defer client.rows.Purge()
+	return storage.WithinTx(ctx, client.next, fn)
+}
@@ -0,0 +1,161 @@
+package cache_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/cache"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/memory"
+)
+
+// counting counts the reads by ID that reach it.
+type counting struct {
+	storage.RowStorer
+	reads int
+}
+
+func (client *counting) GetRowByID(ctx context.Context, rowType, rowID string) (storage.Row, error) {
+	client.reads++
+	return client.RowStorer.GetRowByID(ctx, rowType, rowID)
+}
+
+func (client *counting) WithinTx(ctx context.Context, fn func(tx storage.RowStorer) error) error {
+	return storage.WithinTx(ctx, client.RowStorer, fn)
+}
+
+// newCounting returns a backend storing the orgs.
+func newCounting(t *testing.T, labels ...string) (*counting, []storage.Row) {
+	t.Helper()
+	backend := &counting{RowStorer: memory.NewClient()}
+	orgs := make([]storage.Row, len(labels))
+	for i, label := range labels {
+		org, err := backend.CreateRow(context.Background(), "org", label)
+		if err != nil {
+			t.Fatalf("CreateRow: %s", err)
+		}
+		orgs[i] = org
+	}
+	return backend, orgs
+}
+
+func read(t *testing.T, storer storage.RowStorer, row storage.Row) storage.Row {
+	t.Helper()
+	got, err := storer.GetRowByID(context.Background(), row.Type(), row.ID())
+	if err != nil {
+		t.Fatalf("GetRowByID: %s", err)
+	}
+	return got
+}
+
+func TestCaches(t *testing.T) {
+	backend, orgs := newCounting(t, "acme")
+	storer := cache.NewStorer(backend, time.Minute, 0)
+	for i := 0; i < 3; i++ {
+		read(t, storer, orgs[0])
+	}
+	if backend.reads != 1 {
+		t.Errorf("3 reads of a row read the backend %d times, not once", backend.reads)
+	}
+}
+
+func TestExpires(t *testing.T) {
+	ctx := context.Background()
+	backend, orgs := newCounting(t, "acme")
+	storer := cache.NewStorer(backend, 50*time.Millisecond, 0)
+	read(t, storer, orgs[0])
+
+	// another client's write isn't seen until the row expires
+	if err := backend.UpdateColumn(ctx, "org", orgs[0].ID(), "owner", "ops"); err != nil {
+		t.Fatalf("UpdateColumn: %s", err)
+	}
+	if got := read(t, storer, orgs[0]); got.Columns()["owner"] != nil {
+		t.Errorf("a cached row has another client's write before it expired: %v", got.Columns())
+	}
+	time.Sleep(80 * time.Millisecond)
+	if got := read(t, storer, orgs[0]); got.Columns()["owner"] != "ops" {
+		t.Errorf("an expired row was read from the cache: %v", got.Columns())
+	}
+	if backend.reads != 2 {
+		t.Errorf("the backend was read %d times, not twice", backend.reads)
+	}
+}
+
+func TestEvicts(t *testing.T) {
+	backend, orgs := newCounting(t, "acme", "globex", "initech")
+	storer := cache.NewStorer(backend, time.Minute, 2)
+	read(t, storer, orgs[0])
+	read(t, storer, orgs[1])
+	read(t, storer, orgs[0])
+	read(t, storer, orgs[2])
+	backend.reads = 0
+
+	read(t, storer, orgs[0])
+	read(t, storer, orgs[2])
+	if backend.reads != 0 {
+		t.Errorf("reading the 2 most recently used rows read the backend %d times", backend.reads)
+	}
+	read(t, storer, orgs[1])
+	if backend.reads != 1 {
+		t.Errorf("reading the least recently used row didn't read the backend")
+	}
+}
+
+func TestWritesInvalidate(t *testing.T) {
+	ctx := context.Background()
+	backend, orgs := newCounting(t, "acme")
+	storer := cache.NewStorer(backend, time.Minute, 0)
+	org := orgs[0]
+
+	read(t, storer, org)
+	if err := storer.UpdateColumn(ctx, "org", org.ID(), "owner", "ops"); err != nil {
+		t.Fatalf("UpdateColumn: %s", err)
+	}
+	if got := read(t, storer, org); got.Columns()["owner"] != "ops" {
+		t.Errorf("UpdateColumn left the cached row: %v", got.Columns())
+	}
+
+	patch := []storage.ColumnPatch{{Type: "org", ID: org.ID(), Columns: map[string]interface{}{"owner": "dev"}}}
+	if err := storer.PatchColumns(ctx, patch); err != nil {
+		t.Fatalf("PatchColumns: %s", err)
+	}
+	if got := read(t, storer, org); got.Columns()["owner"] != "dev" {
+		t.Errorf("PatchColumns left the cached row: %v", got.Columns())
+	}
+
+	if _, err := storer.GetRow(ctx, "org", "acme"); err != nil {
+		t.Fatalf("GetRow: %s", err)
+	}
+	if _, err := storer.UpdateRow(ctx, "org", org.ID(), "umbrella"); err != nil {
+		t.Fatalf("UpdateRow: %s", err)
+	}
+	if _, err := storer.GetRow(ctx, "org", "acme"); err == nil {
+		t.Error("GetRow found the row by the label UpdateRow changed")
+	}
+
+	if err := storer.DeleteRow(ctx, "org", "", org.ID()); err != nil {
+		t.Fatalf("DeleteRow: %s", err)
+	}
+	if _, err := storer.GetRowByID(ctx, "org", org.ID()); err == nil {
+		t.Error("GetRowByID found a deleted row")
+	}
+}
+
+func TestTransactionPurges(t *testing.T) {
+	ctx := context.Background()
+	backend, orgs := newCounting(t, "acme")
+	storer := cache.NewStorer(backend, time.Minute, 0)
+	org := orgs[0]
+	read(t, storer, org)
+
+	err := storer.WithinTx(ctx, func(tx storage.RowStorer) error {
+		return tx.UpdateColumn(ctx, "org", org.ID(), "owner", "ops")
+	})
+	if err != nil {
+		t.Fatalf("WithinTx: %s", err)
+	}
+	if got := read(t, storer, org); got.Columns()["owner"] != "ops" {
+		t.Errorf("a transaction's write left the cached row: %v", got.Columns())
+	}
+}
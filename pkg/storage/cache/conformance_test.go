@@ -0,0 +1,17 @@
+package cache_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/cache"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/memory"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/storagetest"
+)
+
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.RowStorer {
+		return cache.NewStorer(memory.NewClient(), time.Minute, 1000)
+	})
+}
@@ -0,0 +1,43 @@
+package storage
+
+import "context"
+
+// ChildScanner is implemented by storage backends that can find a row's
+// children, of every type, without scanning every row.
+type ChildScanner interface {
+	// ScanChildren calls fn on each child of the row with parentID, in no
+	// particular order, reading them a page at a time. An error from fn
+	// stops the scan, and is returned.
+	ScanChildren(ctx context.Context, parentID string, fn func(Row) error) error
+}
+
+// ScanChildren calls fn on each child of the row with parentID, scanning
+// every row for them if the storer isn't a ChildScanner.
+func ScanChildren(ctx context.Context, storer RowStorer, parentID string, fn func(Row) error) error {
+	if scanner, ok := storer.(ChildScanner); ok {
+		return scanner.ScanChildren(ctx, parentID, fn)
+	}
+	return storer.ScanRows(ctx, func(row Row) error {
+		if row.ParentID() != parentID {
+			return nil
+		}
+		return fn(row)
+	})
+}
+
+// ListChildren returns the children of the type of the row with parentID,
+// whatever their labels, collected by ScanChildren, so that a ChildScanner
+// finds them from its index of rows by parent.
+func ListChildren(ctx context.Context, storer RowStorer, childType, parentID string) ([]Row, error) {
+	children := []Row{}
+	err := ScanChildren(ctx, storer, parentID, func(row Row) error {
+		if row.Type() == childType {
+			children = append(children, row)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return children, nil
+}
@@ -0,0 +1,107 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sort"
+)
+
+// EqualColumns reports whether two rows' columns hold the same values. String
+// sets are compared without regard to order, since not every backend keeps
+// it, and no columns equal empty columns.
+func EqualColumns(a, b map[string]interface{}) bool {
+	return reflect.DeepEqual(normalColumns(a), normalColumns(b))
+}
+
+func normalColumns(columns map[string]interface{}) map[string]interface{} {
+	out := map[string]interface{}{}
+	for name, value := range columns {
+		if set, ok := value.([]string); ok {
+			sorted := append([]string(nil), set...)
+			sort.Strings(sorted)
+			value = sorted
+		}
+		out[name] = value
+	}
+	return out
+}
+
+// ColumnSize estimates the bytes a column takes in storage, as DynamoDB
+// counts them: the name, and the value's UTF-8 bytes, or for a string set, the
+// bytes of each string. Other values count as their JSON encoding.
+func ColumnSize(name string, value interface{}) int {
+	size := len(name)
+	switch v := value.(type) {
+	case string:
+		size += len(v)
+	case []string:
+		for _, elem := range v {
+			size += len(elem)
+		}
+	default:
+		encoded, _ := json.Marshal(v)
+		size += len(encoded)
+	}
+	return size
+}
+
+// RowSize estimates the bytes a row takes in storage: its type, ID, label,
+// parent ID, and columns, with their attribute names.
+func RowSize(row Row) int {
+	size := ColumnSize("type", row.Type()) + ColumnSize("id", row.ID()) + ColumnSize("label", row.Label())
+	if row.ParentID() != "" {
+		size += ColumnSize("parent_id", row.ParentID())
+	}
+	size += len("columns")
+	for name, value := range row.Columns() {
+		size += ColumnSize(name, value)
+	}
+	return size
+}
+
+// ColumnReader is implemented by storage backends that can read some of a
+// row's columns without the rest, which costs less than reading a row whose
+// other columns are large.
+type ColumnReader interface {
+	// GetRowColumns returns the row with the type and ID, with only the
+	// named columns it has. With no names it has no columns, which is
+	// enough to check that it exists, or to read its label or parent.
+	GetRowColumns(ctx context.Context, rowType, rowID string, columnNames []string) (Row, error)
+}
+
+// GetRowColumns returns the row with only the named columns, reading only
+// those if the storer is a ColumnReader.
+func GetRowColumns(ctx context.Context, storer RowStorer, rowType, rowID string, columnNames []string) (Row, error) {
+	if reader, ok := storer.(ColumnReader); ok {
+		return reader.GetRowColumns(ctx, rowType, rowID, columnNames)
+	}
+	row, err := storer.GetRowByID(ctx, rowType, rowID)
+	if err != nil {
+		return nil, err
+	}
+	return ProjectColumns(row, columnNames), nil
+}
+
+// ProjectColumns returns the row with only the named columns it has.
+func ProjectColumns(row Row, columnNames []string) Row {
+	var columns map[string]interface{}
+	for _, name := range columnNames {
+		if value, ok := row.Columns()[name]; ok {
+			if columns == nil {
+				columns = map[string]interface{}{}
+			}
+			columns[name] = value
+		}
+	}
+	return &projectedRow{Row: row, columns: columns}
+}
+
+type projectedRow struct {
+	Row
+	columns map[string]interface{}
+}
+
+func (row *projectedRow) Columns() map[string]interface{} {
+	return row.columns
+}
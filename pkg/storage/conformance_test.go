@@ -0,0 +1,15 @@
+package storage_test
+
+import (
+	"testing"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/memory"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/storagetest"
+)
+
+func TestRetryConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.RowStorer {
+		return storage.WithRetry(memory.NewClient(), storage.RetryPolicy{})
+	})
+}
@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// CorrelationIDField is the log field, and the name in backends' request
+// metadata, of an operation's correlation ID.
+const CorrelationIDField = "correlation_id"
+
+type correlationIDKey struct{}
+
+// WithCorrelationID gives the operation of ctx a new correlation ID, unless it
+// has one already, and sets it as a log field. Backends send it with their
+// requests where they can, so that logs can be joined with the records of
+// those requests, such as CloudTrail's.
+func WithCorrelationID(ctx context.Context) context.Context {
+	if CorrelationID(ctx) != "" {
+		return ctx
+	}
+	b := make([]byte, 8)
+	// crypto/rand's Read never returns an error
+	_, _ = rand.Read(b)
+	id := hex.EncodeToString(b)
+	ctx = context.WithValue(ctx, correlationIDKey{}, id)
+	return tflog.SetField(ctx, CorrelationIDField, id)
+}
+
+type operationKey struct{}
+
+// WithOperation names the Terraform operation of ctx, such as "Read team",
+// for traces of the storage operations it makes.
+func WithOperation(ctx context.Context, operation string) context.Context {
+	return context.WithValue(ctx, operationKey{}, operation)
+}
+
+// Operation returns the name of the Terraform operation of ctx, or "".
+func Operation(ctx context.Context) string {
+	operation, _ := ctx.Value(operationKey{}).(string)
+	return operation
+}
+
+// CorrelationID returns the correlation ID of the operation of ctx, or "".
+func CorrelationID(ctx context.Context) string {
+	id, _ := ctx.Value(correlationIDKey{}).(string)
+	return id
+}
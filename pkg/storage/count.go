@@ -0,0 +1,47 @@
+package storage
+
+import "context"
+
+// ApproximateCount is about how many rows are stored, and how much space they
+// take, as a backend keeps track of them without reading the rows.
+type ApproximateCount struct {
+	// Rows is about how many rows are stored, of every type.
+	Rows int64
+	// Bytes is about how many bytes the stored items take, bookkeeping
+	// items such as label guards included.
+	Bytes int64
+}
+
+// ApproximateCounter is implemented by storage backends that keep counts of
+// their rows, so that summaries of huge stores needn't read every row.
+type ApproximateCounter interface {
+	// ApproximateCount returns the backend's counts. They're eventually
+	// consistent: rows written since the backend last updated them aren't
+	// counted, however long ago that was.
+	ApproximateCount(ctx context.Context) (ApproximateCount, error)
+}
+
+// CountApproximately returns the counts kept by the storer's backend, looking
+// through Wrappers, since the counts are of every row stored, whatever a
+// wrapper does with them. Backends that keep no counts are counted exactly, by
+// scanning the storer's rows, which takes as long as reading every row.
+func CountApproximately(ctx context.Context, storer RowStorer) (ApproximateCount, error) {
+	for next := storer; next != nil; {
+		if counter, ok := next.(ApproximateCounter); ok {
+			return counter.ApproximateCount(ctx)
+		}
+		wrapper, ok := next.(Wrapper)
+		if !ok {
+			break
+		}
+		next = wrapper.Unwrap()
+	}
+
+	var count ApproximateCount
+	err := storer.ScanRows(ctx, func(row Row) error {
+		count.Rows++
+		count.Bytes += int64(RowSize(row))
+		return nil
+	})
+	return count, err
+}
@@ -0,0 +1,17 @@
+package deadline_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/deadline"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/memory"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/storagetest"
+)
+
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.RowStorer {
+		return deadline.NewStorer(memory.NewClient(), deadline.Timeouts{Read: time.Minute, Write: time.Minute})
+	})
+}
@@ -0,0 +1,204 @@
+// Package deadline wraps a storage.RowStorer to give each operation a
+// deadline, so that a backend call that hangs fails the one operation rather
+// than stalling a Terraform run until it's killed.
+package deadline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+// Timeouts are how long operations may take, retries included. A timeout of
+// 0 doesn't limit those operations.
+type Timeouts struct {
+	// Read limits GetRowByID, GetRow, GetChild, and ListRows, and each page
+	// IterRows reads.
+	Read time.Duration
+	// Write limits the operations that create, update, delete, or put rows.
+	Write time.Duration
+}
+
+// Storer is a storage.RowStorer whose operations fail with an error wrapping
+// context.DeadlineExceeded once they take longer than their timeout. ScanRows
+// isn't limited, since it reads every row, and callers bound it with their
+// own context.
+type Storer struct {
+	next     storage.RowStorer
+	timeouts Timeouts
+}
+
+var (
+	_ storage.RowStorer     = &Storer{}
+	_ storage.Transactor    = &Storer{}
+	_ storage.ColumnDeleter = &Storer{}
+	_ storage.BatchGetter   = &Storer{}
+	_ storage.ColumnQuerier = &Storer{}
+	_ storage.ChildScanner  = &Storer{}
+	_ storage.BatchPutter   = &Storer{}
+	_ storage.ColumnPatcher = &Storer{}
+	_ storage.RowIterator   = &Storer{}
+)
+
+func NewStorer(next storage.RowStorer, timeouts Timeouts) *Storer {
+	return &Storer{next: next, timeouts: timeouts}
+}
+
+// Unwrap returns the wrapped storer.
+func (client *Storer) Unwrap() storage.RowStorer {
+	return client.next
+}
+
+// withTimeout returns ctx limited to timeout, and a function that releases
+// it and names the operation in an error from its deadline passing.
+func withTimeout(ctx context.Context, op string, timeout time.Duration) (context.Context, func(error) error) {
+	if timeout <= 0 {
+		return ctx, func(err error) error { return err }
+	}
+	limited, cancel := context.WithTimeout(ctx, timeout)
+	return limited, func(err error) error {
+		cancel()
+		if err != nil && errors.Is(limited.Err(), context.DeadlineExceeded) && ctx.Err() == nil {
+			return fmt.Errorf("%s took longer than %s: %w", op, timeout, err)
+		}
+		return err
+	}
+}
+
+func (client *Storer) read(ctx context.Context, op string, fn func(context.Context) (storage.Row, error)) (storage.Row, error) {
+	ctx, done := withTimeout(ctx, op, client.timeouts.Read)
+	row, err := fn(ctx)
+	return row, done(err)
+}
+
+func (client *Storer) write(ctx context.Context, op string, fn func(context.Context) (storage.Row, error)) (storage.Row, error) {
+	ctx, done := withTimeout(ctx, op, client.timeouts.Write)
+	row, err := fn(ctx)
+	return row, done(err)
+}
+
+func (client *Storer) GetRowByID(ctx context.Context, rowType, rowID string) (storage.Row, error) {
+	return client.read(ctx, "GetRowByID", func(ctx context.Context) (storage.Row, error) {
+		return client.next.GetRowByID(ctx, rowType, rowID)
+	})
+}
+
+func (client *Storer) GetRowsByIDs(ctx context.Context, rowType string, ids []string) ([]storage.Row, error) {
+	ctx, done := withTimeout(ctx, "GetRowsByIDs", client.timeouts.Read)
+	rows, err := storage.GetRowsByIDs(ctx, client.next, rowType, ids)
+	return rows, done(err)
+}
+
+func (client *Storer) GetRow(ctx context.Context, rowType, rowLabel string) (storage.Row, error) {
+	return client.read(ctx, "GetRow", func(ctx context.Context) (storage.Row, error) {
+		return client.next.GetRow(ctx, rowType, rowLabel)
+	})
+}
+
+func (client *Storer) CreateRow(ctx context.Context, rowType, rowLabel string) (storage.Row, error) {
+	return client.write(ctx, "CreateRow", func(ctx context.Context) (storage.Row, error) {
+		return client.next.CreateRow(ctx, rowType, rowLabel)
+	})
+}
+
+func (client *Storer) CreateChild(ctx context.Context, rowType, rowLabel, parentType, parentID string, columns map[string]interface{}) (storage.Row, error) {
+	return client.write(ctx, "CreateChild", func(ctx context.Context) (storage.Row, error) {
+		return client.next.CreateChild(ctx, rowType, rowLabel, parentType, parentID, columns)
+	})
+}
+
+func (client *Storer) GetChild(ctx context.Context, childLabel, parentID string) (storage.Row, error) {
+	return client.read(ctx, "GetChild", func(ctx context.Context) (storage.Row, error) {
+		return client.next.GetChild(ctx, childLabel, parentID)
+	})
+}
+
+func (client *Storer) ListRows(ctx context.Context, rowType, labelFilter, parentIDFilter string) ([]storage.Row, error) {
+	ctx, done := withTimeout(ctx, "ListRows", client.timeouts.Read)
+	rows, err := client.next.ListRows(ctx, rowType, labelFilter, parentIDFilter)
+	return rows, done(err)
+}
+
+// IterRows limits each page it reads to the read timeout, since the whole
+// iteration lasts as long as its caller takes.
+func (client *Storer) IterRows(ctx context.Context, rowType, labelFilter, parentIDFilter string) *storage.Iter[storage.Row] {
+	return storage.WrapPages(storage.IterRows(ctx, client.next, rowType, labelFilter, parentIDFilter), func(ctx context.Context, fetch storage.PageFunc[storage.Row]) ([]storage.Row, bool, error) {
+		ctx, done := withTimeout(ctx, "IterRows", client.timeouts.Read)
+		page, more, err := fetch(ctx)
+		return page, more, done(err)
+	})
+}
+
+func (client *Storer) QueryByColumn(ctx context.Context, rowType, columnName string, value interface{}) ([]storage.Row, error) {
+	ctx, done := withTimeout(ctx, "QueryByColumn", client.timeouts.Read)
+	rows, err := storage.QueryByColumn(ctx, client.next, rowType, columnName, value)
+	return rows, done(err)
+}
+
+func (client *Storer) UpdateRow(ctx context.Context, rowType, rowID, newLabel string) (storage.Row, error) {
+	return client.write(ctx, "UpdateRow", func(ctx context.Context) (storage.Row, error) {
+		return client.next.UpdateRow(ctx, rowType, rowID, newLabel)
+	})
+}
+
+func (client *Storer) UpdateChild(ctx context.Context, childType, childID, newChildLabel, parentType, newParentID string) (storage.Row, error) {
+	return client.write(ctx, "UpdateChild", func(ctx context.Context) (storage.Row, error) {
+		return client.next.UpdateChild(ctx, childType, childID, newChildLabel, parentType, newParentID)
+	})
+}
+
+func (client *Storer) UpdateColumn(ctx context.Context, rowType, rowID, columnName string, columnValue interface{}) error {
+	ctx, done := withTimeout(ctx, "UpdateColumn", client.timeouts.Write)
+	return done(client.next.UpdateColumn(ctx, rowType, rowID, columnName, columnValue))
+}
+
+func (client *Storer) PatchColumns(ctx context.Context, patches []storage.ColumnPatch) error {
+	ctx, done := withTimeout(ctx, "PatchColumns", client.timeouts.Write)
+	return done(storage.PatchColumns(ctx, client.next, patches))
+}
+
+func (client *Storer) UpdateColumns(ctx context.Context, rowType, rowID string, columns map[string]interface{}) error {
+	ctx, done := withTimeout(ctx, "UpdateColumns", client.timeouts.Write)
+	return done(client.next.UpdateColumns(ctx, rowType, rowID, columns))
+}
+
+func (client *Storer) DeleteRow(ctx context.Context, rowType, childType, rowID string) error {
+	ctx, done := withTimeout(ctx, "DeleteRow", client.timeouts.Write)
+	return done(client.next.DeleteRow(ctx, rowType, childType, rowID))
+}
+
+func (client *Storer) PutRow(ctx context.Context, row storage.Row) error {
+	ctx, done := withTimeout(ctx, "PutRow", client.timeouts.Write)
+	return done(client.next.PutRow(ctx, row))
+}
+
+func (client *Storer) PutRows(ctx context.Context, rows []storage.Row) error {
+	ctx, done := withTimeout(ctx, "PutRows", client.timeouts.Write)
+	return done(storage.PutRows(ctx, client.next, rows))
+}
+
+func (client *Storer) ScanRows(ctx context.Context, fn func(storage.Row) error) error {
+	return client.next.ScanRows(ctx, fn)
+}
+
+// ScanChildren isn't limited, since fn's time is part of it, as ScanRows
+// isn't.
+func (client *Storer) ScanChildren(ctx context.Context, parentID string, fn func(storage.Row) error) error {
+	return storage.ScanChildren(ctx, client.next, parentID, fn)
+}
+
+func (client *Storer) DeleteColumn(ctx context.Context, rowType, rowID, columnName string) error {
+	ctx, done := withTimeout(ctx, "DeleteColumn", client.timeouts.Write)
+	return done(storage.DeleteColumn(ctx, client.next, rowType, rowID, columnName))
+}
+
+// WithinTx gives each of the transaction's operations its timeout, too. The
+// transaction as a whole isn't limited, since it may make any number of them.
+func (client *Storer) WithinTx(ctx context.Context, fn func(tx storage.RowStorer) error) error {
+	return storage.WithinTx(ctx, client.next, func(tx storage.RowStorer) error {
+		return fn(NewStorer(tx, client.timeouts))
+	})
+}
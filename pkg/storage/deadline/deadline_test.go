@@ -0,0 +1,125 @@
+package deadline_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/deadline"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/memory"
+)
+
+// slow takes delay over each read by ID, each column update, and each page of
+// rows, one row to a page, unless the operation's context is done first.
+type slow struct {
+	storage.RowStorer
+	delay time.Duration
+}
+
+func (client *slow) wait(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(client.delay):
+		return nil
+	}
+}
+
+func (client *slow) GetRowByID(ctx context.Context, rowType, rowID string) (storage.Row, error) {
+	if err := client.wait(ctx); err != nil {
+		return nil, err
+	}
+	return client.RowStorer.GetRowByID(ctx, rowType, rowID)
+}
+
+func (client *slow) UpdateColumn(ctx context.Context, rowType, rowID, columnName string, columnValue interface{}) error {
+	if err := client.wait(ctx); err != nil {
+		return err
+	}
+	return client.RowStorer.UpdateColumn(ctx, rowType, rowID, columnName, columnValue)
+}
+
+func (client *slow) IterRows(ctx context.Context, rowType, labelFilter, parentIDFilter string) *storage.Iter[storage.Row] {
+	var rows []storage.Row
+	return storage.NewIter(ctx, func(ctx context.Context) ([]storage.Row, bool, error) {
+		if err := client.wait(ctx); err != nil {
+			return nil, false, err
+		}
+		if rows == nil {
+			var err error
+			if rows, err = client.RowStorer.ListRows(ctx, rowType, labelFilter, parentIDFilter); err != nil || len(rows) == 0 {
+				return nil, false, err
+			}
+		}
+		page := rows[:1]
+		rows = rows[1:]
+		return page, len(rows) > 0, nil
+	})
+}
+
+func newSlow(t *testing.T, delay time.Duration, labels ...string) (*slow, []storage.Row) {
+	t.Helper()
+	backend := &slow{RowStorer: memory.NewClient(), delay: delay}
+	orgs := make([]storage.Row, len(labels))
+	for i, label := range labels {
+		org, err := backend.CreateRow(context.Background(), "org", label)
+		if err != nil {
+			t.Fatalf("CreateRow: %s", err)
+		}
+		orgs[i] = org
+	}
+	return backend, orgs
+}
+
+func TestTimesOut(t *testing.T) {
+	ctx := context.Background()
+	backend, orgs := newSlow(t, time.Second, "acme")
+	storer := deadline.NewStorer(backend, deadline.Timeouts{Read: 20 * time.Millisecond, Write: 30 * time.Millisecond})
+
+	_, err := storer.GetRowByID(ctx, "org", orgs[0].ID())
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "GetRowByID took longer than 20ms") {
+		t.Errorf("a slow GetRowByID failed with %v, not one that took longer than the read timeout", err)
+	}
+	err = storer.UpdateColumn(ctx, "org", orgs[0].ID(), "owner", "ops")
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "UpdateColumn took longer than 30ms") {
+		t.Errorf("a slow UpdateColumn failed with %v, not one that took longer than the write timeout", err)
+	}
+}
+
+func TestUnlimited(t *testing.T) {
+	backend, orgs := newSlow(t, 30*time.Millisecond, "acme")
+	storer := deadline.NewStorer(backend, deadline.Timeouts{Write: 10 * time.Millisecond})
+	if _, err := storer.GetRowByID(context.Background(), "org", orgs[0].ID()); err != nil {
+		t.Errorf("GetRowByID without a read timeout: %s", err)
+	}
+}
+
+func TestCallerDeadline(t *testing.T) {
+	backend, orgs := newSlow(t, time.Second, "acme")
+	storer := deadline.NewStorer(backend, deadline.Timeouts{Read: time.Minute})
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	// the caller's own deadline isn't the storer's to explain
+	_, err := storer.GetRowByID(ctx, "org", orgs[0].ID())
+	if !errors.Is(err, context.DeadlineExceeded) || strings.Contains(err.Error(), "took longer than") {
+		t.Errorf("GetRowByID past its caller's deadline failed with %v", err)
+	}
+}
+
+func TestIterRowsPages(t *testing.T) {
+	backend, _ := newSlow(t, 20*time.Millisecond, "acme", "globex", "initech")
+	storer := deadline.NewStorer(backend, deadline.Timeouts{Read: 50 * time.Millisecond})
+
+	// each page is limited, rather than the whole iteration
+	rows, err := storage.IterRows(context.Background(), storer, "org", "", "").All()
+	if err != nil {
+		t.Fatalf("IterRows of pages each within the read timeout: %s", err)
+	}
+	if len(rows) != 3 {
+		t.Errorf("IterRows read %d rows, not 3", len(rows))
+	}
+}
@@ -0,0 +1,102 @@
+package dynamodb
+
+import (
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/ratelimit"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/smithy-go/middleware"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+// Jitter is storage.Jitter, for the client's retries.
+type Jitter = storage.Jitter
+
+const (
+	JitterFull         = storage.JitterFull
+	JitterEqual        = storage.JitterEqual
+	JitterDecorrelated = storage.JitterDecorrelated
+)
+
+// ParseJitter returns the named jitter.
+func ParseJitter(name string) (Jitter, error) {
+	return storage.ParseJitter(name)
+}
+
+// The defaults of a Backoff.
+const (
+	DefaultBackoffBase = storage.DefaultBackoffBase
+	DefaultBackoffMax  = storage.DefaultBackoffMax
+)
+
+// Backoff is a storage.Backoff for the client's retries of DynamoDB calls,
+// which the AWS SDK makes.
+type Backoff storage.Backoff
+
+// WithBackoff has the client's retries of throttled and failed calls, and
+// its pacing of batch writes, wait as backoff says, rather than as the AWS
+// SDK does by default.
+func WithBackoff(backoff Backoff) Option {
+	return func(o *options) { o.backoff = &backoff }
+}
+
+// Delay returns the delay before the attempt'th retry, counting from 1, given
+// the delay before the retry before it, as storage.Backoff.Delay does.
+func (backoff Backoff) Delay(attempt int, prev time.Duration) time.Duration {
+	return storage.Backoff(backoff).Delay(attempt, prev)
+}
+
+// retryer returns an AWS SDK retryer that waits as the backoff says,
+// drawing from limiter's retry quota.
+func (backoff Backoff) retryer(limiter retry.RateLimiter) aws.Retryer {
+	maxBackoff := backoff.Max
+	if maxBackoff <= 0 {
+		maxBackoff = DefaultBackoffMax
+	}
+	return retry.NewStandard(func(o *retry.StandardOptions) {
+		o.Backoff = &backoffDelays{backoff: backoff}
+		o.MaxBackoff = maxBackoff
+		o.RateLimiter = limiter
+	})
+}
+
+// retryLimiter returns the retry quota shared by every call of a client, as
+// the AWS SDK's standard retryer's own is.
+func retryLimiter() retry.RateLimiter {
+	return ratelimit.NewTokenRateLimit(retry.DefaultRetryRateTokens)
+}
+
+// perCallRetries has each call retry with a retryer of its own, so that
+// decorrelated jitter can draw each delay from the one before, which the
+// SDK doesn't pass to a retryer shared by every call.
+func (backoff Backoff) perCallRetries(limiter retry.RateLimiter) func(*middleware.Stack) error {
+	return func(stack *middleware.Stack) error {
+		if _, ok := stack.Finalize.Get("Retry"); !ok {
+			return nil
+		}
+		_, err := stack.Finalize.Swap("Retry", retry.NewAttemptMiddleware(backoff.retryer(limiter), smithyhttp.RequestCloner))
+		return err
+	}
+}
+
+// backoffDelays are the delays before the retries of one call, for the
+// AWS SDK's retryer.
+type backoffDelays struct {
+	backoff Backoff
+	mu      sync.Mutex
+	prev    time.Duration
+}
+
+var _ retry.BackoffDelayer = &backoffDelays{}
+
+// BackoffDelay returns the delay before the attempt'th retry of a DynamoDB
+// call.
+func (delays *backoffDelays) BackoffDelay(attempt int, err error) (time.Duration, error) {
+	delays.mu.Lock()
+	defer delays.mu.Unlock()
+	delays.prev = delays.backoff.Delay(attempt, delays.prev)
+	return delays.prev, nil
+}
@@ -0,0 +1,172 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+// batchWriteSize is the most items one BatchWriteItem call may write.
+const batchWriteSize = 25
+
+// batchBackoff paces batch writes unless WithBackoff says otherwise. Equal
+// jitter keeps the pauses near their exponential delay, while still
+// spreading out imports throttled together.
+var batchBackoff = Backoff{Jitter: JitterEqual, Base: 50 * time.Millisecond, Max: 5 * time.Second}
+
+const (
+	// maxThrottledBatches is how many batch writes in a row may write
+	// nothing before PutRows gives up.
+	maxThrottledBatches = 20
+)
+
+var _ storage.BatchPutter = &Client{}
+
+// PutRows puts the rows with BatchWriteItem, 25 items at a time, root rows'
+// label guards among them. Items DynamoDB leaves unprocessed, and writes it
+// throttles outright, are written again after a pause that backs off while it
+// keeps throttling them and comes back down as writes go through, so that a
+// bulk import runs as fast as the table's capacity allows rather than failing
+// when it hits it.
+func (client *Client) PutRows(ctx context.Context, rows []storage.Row) error {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemDynamoDB, fmt.Sprintf("PutRows %d", len(rows)))
+	if err := client.ensureTable(ctx); err != nil {
+		return err
+	}
+	pacer := &batchPacer{backoff: batchBackoff}
+	if client.backoff != nil {
+		pacer.backoff = *client.backoff
+	}
+	requests := []types.WriteRequest{}
+	chunk := []storage.Row{}
+	// guarded is the labels chunk claims, since one batch can't write an
+	// item twice
+	guarded := map[cacheKey]bool{}
+	flush := func() error {
+		err := client.batchWrite(ctx, pacer, requests)
+		for _, r := range chunk {
+			client.cache.Invalidate(r.Type(), r.ID())
+		}
+		requests, chunk = requests[:0], chunk[:0]
+		clear(guarded)
+		return err
+	}
+	for _, r := range rows {
+		item, err := client.rowToItem(r)
+		if err != nil {
+			return err
+		}
+		write := []types.WriteRequest{{PutRequest: &types.PutRequest{Item: item}}}
+		guard := cacheKey{r.Type(), r.Label()}
+		if r.ParentID() == "" {
+			// root rows claim their labels, as PutRow's do
+			write = append(write, types.WriteRequest{PutRequest: &types.PutRequest{Item: client.labelGuard(r.Type(), r.Label(), r.ID())}})
+		}
+		if len(requests)+len(write) > batchWriteSize || (len(write) > 1 && guarded[guard]) {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+		requests = append(requests, write...)
+		chunk = append(chunk, r)
+		if len(write) > 1 {
+			guarded[guard] = true
+		}
+	}
+	if len(requests) == 0 {
+		return nil
+	}
+	return flush()
+}
+
+// batchWrite writes the requests, writing again what DynamoDB leaves
+// unprocessed until none is left.
+func (client *Client) batchWrite(ctx context.Context, pacer *batchPacer, requests []types.WriteRequest) error {
+	for len(requests) > 0 {
+		if err := pacer.wait(ctx); err != nil {
+			return err
+		}
+		output, err := client.ddb.BatchWriteItem(ctx, &dynamodb.BatchWriteItemInput{
+			RequestItems: map[string][]types.WriteRequest{client.tableName: requests},
+		})
+		switch {
+		case errors.Is(err, storage.ErrThrottled):
+			// the SDK's own retries ran out, but nothing was written, so
+			// it's safe to keep trying
+			if pacer.throttled(false) > maxThrottledBatches {
+				return err
+			}
+			tflog.SubsystemDebug(ctx, storage.LogSubsystemDynamoDB, fmt.Sprintf("BatchWriteItem throttled %d times in a row", pacer.stalled))
+			continue
+		case err != nil:
+			return err
+		}
+		unprocessed := output.UnprocessedItems[client.tableName]
+		switch {
+		case len(unprocessed) == 0:
+			pacer.wrote()
+		case len(unprocessed) < len(requests):
+			pacer.throttled(true)
+		case pacer.throttled(false) > maxThrottledBatches:
+			return fmt.Errorf("%w: DynamoDB left %d items unprocessed %d times in a row", storage.ErrThrottled, len(unprocessed), maxThrottledBatches)
+		}
+		requests = unprocessed
+	}
+	return nil
+}
+
+// batchPacer paces batch writes to the capacity of the table.
+type batchPacer struct {
+	backoff Backoff
+	// level is the retry whose delay the pause before each write is: one
+	// more for each write DynamoDB throttles, and one less for each it
+	// doesn't.
+	level int
+	// delay is the last pause, from which a decorrelated pause follows.
+	delay time.Duration
+	// stalled counts the writes in a row that wrote nothing.
+	stalled int
+}
+
+func (pacer *batchPacer) wait(ctx context.Context) error {
+	if pacer.level == 0 {
+		pacer.delay = 0
+		return nil
+	}
+	pacer.delay = pacer.backoff.Delay(pacer.level, pacer.delay)
+	timer := time.NewTimer(pacer.delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// throttled lengthens the pause after a write that left items unwritten, and
+// returns how many writes in a row have written nothing, counting this one
+// unless it wrote some.
+func (pacer *batchPacer) throttled(wroteSome bool) int {
+	pacer.level++
+	if wroteSome {
+		pacer.stalled = 0
+	} else {
+		pacer.stalled++
+	}
+	return pacer.stalled
+}
+
+// wrote shortens the pause after a write that wrote every item.
+func (pacer *batchPacer) wrote() {
+	pacer.stalled = 0
+	if pacer.level > 0 {
+		pacer.level--
+	}
+}
@@ -0,0 +1,114 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+// batchGetSize is the most items one BatchGetItem call may read.
+const batchGetSize = 100
+
+var _ storage.BatchGetter = &Client{}
+
+// GetRowsByIDs reads the rows with BatchGetItem, 100 items at a time, rather
+// than with a GetItem call for each, taking those in the read cache from it.
+// Reads are strongly consistent, as GetRowByID's are. Items DynamoDB leaves
+// unprocessed are read again, paced as PutRows paces writes.
+func (client *Client) GetRowsByIDs(ctx context.Context, rowType string, ids []string) ([]storage.Row, error) {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemDynamoDB, fmt.Sprintf("GetRowsByIDs %q %d", rowType, len(ids)))
+	found := make(map[string]storage.Row, len(ids))
+	keys := []map[string]types.AttributeValue{}
+	requested := map[string]bool{}
+	for _, id := range ids {
+		if requested[id] {
+			continue
+		}
+		requested[id] = true
+		if cached, ok := client.cache.GetByID(rowType, id); ok {
+			found[id] = cached
+			continue
+		}
+		keys = append(keys, map[string]types.AttributeValue{
+			storageKeyType: &types.AttributeValueMemberS{Value: client.itemTypeKey(rowType, id)},
+			storageKeyID:   &types.AttributeValueMemberS{Value: id},
+		})
+	}
+
+	pacer := &batchPacer{backoff: batchBackoff}
+	if client.backoff != nil {
+		pacer.backoff = *client.backoff
+	}
+	for start := 0; start < len(keys); start += batchGetSize {
+		items, err := client.batchGet(ctx, pacer, keys[start:min(start+batchGetSize, len(keys))])
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			r, err := client.itemToRow(item)
+			if err != nil {
+				return nil, err
+			}
+			client.cache.Put(r)
+			found[r.ID()] = r
+		}
+	}
+
+	rows := make([]storage.Row, 0, len(found))
+	for _, id := range ids {
+		if r, ok := found[id]; ok {
+			rows = append(rows, r)
+			delete(found, id)
+		}
+	}
+	return rows, nil
+}
+
+// batchGet reads the items with the keys, reading again what DynamoDB leaves
+// unprocessed until none is left. Items that don't exist aren't returned.
+func (client *Client) batchGet(ctx context.Context, pacer *batchPacer, keys []map[string]types.AttributeValue) ([]map[string]types.AttributeValue, error) {
+	items := []map[string]types.AttributeValue{}
+	for len(keys) > 0 {
+		if err := pacer.wait(ctx); err != nil {
+			return nil, err
+		}
+		output, err := client.ddb.BatchGetItem(ctx, &dynamodb.BatchGetItemInput{
+			RequestItems: map[string]types.KeysAndAttributes{client.tableName: {
+				Keys:           keys,
+				ConsistentRead: aws.Bool(true),
+			}},
+		})
+		switch {
+		case isTableMissing(err):
+			// the table is created by the first write
+			return items, nil
+		case errors.Is(err, storage.ErrThrottled):
+			// the SDK's own retries ran out, but reads are safe to try again
+			if pacer.throttled(false) > maxThrottledBatches {
+				return nil, err
+			}
+			tflog.SubsystemDebug(ctx, storage.LogSubsystemDynamoDB, fmt.Sprintf("BatchGetItem throttled %d times in a row", pacer.stalled))
+			continue
+		case err != nil:
+			return nil, err
+		}
+		items = append(items, output.Responses[client.tableName]...)
+		unprocessed := output.UnprocessedKeys[client.tableName].Keys
+		switch {
+		case len(unprocessed) == 0:
+			pacer.wrote()
+		case len(unprocessed) < len(keys):
+			pacer.throttled(true)
+		case pacer.throttled(false) > maxThrottledBatches:
+			return nil, fmt.Errorf("%w: DynamoDB left %d keys unprocessed %d times in a row", storage.ErrThrottled, len(unprocessed), maxThrottledBatches)
+		}
+		keys = unprocessed
+	}
+	return items, nil
+}
@@ -0,0 +1,61 @@
+package dynamodb
+
+import (
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/spilliams/tree-terraform-provider/internal/lru"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+// WithReadCache keeps up to size of the rows the client reads or writes in
+// memory, so that reading the same row again, as the resources of one parent
+// each do during a plan, doesn't call DynamoDB. Rows are found by type and ID,
+// by parent and label, or by type and label if GetRow read them. The client's
+// own writes update or drop the rows they change, but writes by other clients
+// aren't seen until a row is evicted, so the cache suits short-lived clients,
+// such as a provider's during one plan or apply.
+func WithReadCache(size int) Option {
+	return func(o *options) { o.cacheSize = size }
+}
+
+type cacheKey struct {
+	rowType string
+	key     string
+}
+
+// updated caches the row an update returned, in place of the one it changed.
+func (client *Client) updated(item map[string]types.AttributeValue) (storage.Row, error) {
+	r, err := client.itemToRow(item)
+	if err != nil {
+		return nil, err
+	}
+	client.cache.Put(r)
+	return r, nil
+}
+
+// newReadCache returns the cache WithReadCache asks for, or nil, which caches
+// nothing, if it doesn't.
+func newReadCache(size int) *lru.Cache {
+	if size <= 0 {
+		return nil
+	}
+	return lru.New(size, 0)
+}
+
+// copyRow copies a row and its columns, so that callers changing a row's
+// columns don't change the one it copies.
+func copyRow(r storage.Row) *row {
+	var columns map[string]interface{}
+	if r.Columns() != nil {
+		columns = make(map[string]interface{}, len(r.Columns()))
+		for name, value := range r.Columns() {
+			columns[name] = value
+		}
+	}
+	return &row{
+		RowType:     r.Type(),
+		RowID:       r.ID(),
+		RowLabel:    r.Label(),
+		RowParentID: r.ParentID(),
+		RowColumns:  columns,
+	}
+}
@@ -0,0 +1,50 @@
+package dynamodb
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+var _ storage.ChildScanner = &Client{}
+
+// ScanChildren queries the ByParentAndLabel index for the row's children, and
+// calls fn on each, a page at a time, so that only one page is held at once.
+func (client *Client) ScanChildren(ctx context.Context, parentID string, fn func(storage.Row) error) error {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemDynamoDB, fmt.Sprintf("ScanChildren %q", parentID))
+	paginator := dynamodb.NewQueryPaginator(client.ddb, &dynamodb.QueryInput{
+		TableName:              aws.String(client.tableName),
+		IndexName:              aws.String(storageGSIByParentAndLabel),
+		KeyConditionExpression: aws.String("#parent_id = :parent_id"),
+		ExpressionAttributeNames: map[string]string{
+			"#parent_id": storageAttrParentID,
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":parent_id": &types.AttributeValueMemberS{Value: client.parentKey(parentID)},
+		},
+	})
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+		if isTableMissing(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		for _, item := range output.Items {
+			r, err := client.itemToRow(item)
+			if err != nil {
+				return err
+			}
+			if err := fn(r); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -5,46 +5,164 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
-	"strings"
+	"sync"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodbstreams"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
 	smithyhttp "github.com/aws/smithy-go/transport/http"
 	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/spilliams/tree-terraform-provider/internal/lru"
 	"github.com/spilliams/tree-terraform-provider/internal/slug"
 	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/aws/aws-sdk-go-v2/otelaws"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/sync/singleflight"
 )
 
 type Client struct {
+	profile   string
 	region    string
 	tableName string
 	keyARN    string
+	tenant    string
+
+	ddb     *dynamodb.Client
+	streams *dynamodbstreams.Client
+	kms     *kms.Client
+	sts     *sts.Client
+
+	tablePolicy     *tablePolicy
+	listConcurrency int
+	cache           *lru.Cache
+	prefetch        bool
+	backoff         *Backoff
+	compact         bool
+	shards          map[string]int
+	reads           singleflight.Group
+	// tableMu keeps the client from creating the table twice at once
+	tableMu sync.Mutex
+}
+
+// Option configures a Client.
+type Option func(*options)
+
+type options struct {
+	tracerProvider   trace.TracerProvider
+	propagator       propagation.TextMapPropagator
+	emf              *emfWriter
+	usage            *Usage
+	tenant           string
+	tablePolicy      *tablePolicy
+	listConcurrency  int
+	cacheSize        int
+	prefetchChildren bool
+	backoff          *Backoff
+	compactColumns   bool
+	shards           map[string]int
+	throttle         *Throttle
+	endpoint         string
+	transport        *Transport
+}
+
+// WithTracerProvider records a span for each DynamoDB API call, with the
+// provider's tracer.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(o *options) { o.tracerProvider = tp }
+}
+
+// WithTracePropagator carries the trace of each DynamoDB API call in its
+// request with propagator, such as X-Ray's, instead of the global one. It
+// only matters along with WithTracerProvider.
+func WithTracePropagator(propagator propagation.TextMapPropagator) Option {
+	return func(o *options) { o.propagator = propagator }
+}
 
-	ddb *dynamodb.Client
+// WithEndpoint sends the client's DynamoDB and stream calls to endpoint, such
+// as that of a DynamoDB Local for tests, rather than to AWS's in its region.
+func WithEndpoint(endpoint string) Option {
+	return func(o *options) { o.endpoint = endpoint }
 }
 
-func NewClient(ctx context.Context, profile, region, tableName, keyARN string) (storage.RowStorer, error) {
+func NewClient(ctx context.Context, profile, region, tableName, keyARN string, opts ...Option) (storage.RowStorer, error) {
 	this := &Client{
+		profile:   profile,
 		region:    region,
 		tableName: tableName,
 		keyARN:    keyARN,
 	}
-
-	cfg, err := config.LoadDefaultConfig(ctx,
-		config.WithSharedConfigProfile(profile),
-		config.WithRegion(region),
-	)
-	if err != nil {
+	o := options{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if err := validTenant(o.tenant); err != nil {
+		return nil, err
+	}
+	if err := validShards(o.shards); err != nil {
+		return nil, err
+	}
+	if err := validThrottle(o.throttle); err != nil {
 		return nil, err
 	}
-	this.ddb = dynamodb.NewFromConfig(cfg)
+	if err := validTransport(o.transport); err != nil {
+		return nil, err
+	}
+	this.tenant = o.tenant
+	this.tablePolicy = o.tablePolicy
+	this.listConcurrency = o.listConcurrency
+	this.cache = newReadCache(o.cacheSize)
+	this.prefetch = o.prefetchChildren
+	this.backoff = o.backoff
+	this.compact = o.compactColumns
+	this.shards = o.shards
 
-	err = this.createTableIfNotExists(ctx)
+	cfg, err := sharedConfig(ctx, profile, region)
 	if err != nil {
 		return nil, err
 	}
+	if o.transport != nil {
+		cfg.HTTPClient = o.transport.httpClient()
+	}
+	if o.tracerProvider != nil {
+		otelOptions := []otelaws.Option{otelaws.WithTracerProvider(o.tracerProvider)}
+		if o.propagator != nil {
+			otelOptions = append(otelOptions, otelaws.WithTextMapPropagator(o.propagator))
+		}
+		otelaws.AppendMiddlewares(&cfg.APIOptions, otelOptions...)
+	}
+	this.ddb = dynamodb.NewFromConfig(cfg, func(ddbOptions *dynamodb.Options) {
+		ddbOptions.APIOptions = append(ddbOptions.APIOptions, addCorrelationID, addErrorKinds)
+		if o.emf != nil {
+			ddbOptions.APIOptions = append(ddbOptions.APIOptions, o.emf.middleware(tableName))
+		}
+		if o.usage != nil {
+			ddbOptions.APIOptions = append(ddbOptions.APIOptions, o.usage.middleware)
+		}
+		if o.backoff != nil {
+			limiter := retryLimiter()
+			ddbOptions.Retryer = o.backoff.retryer(limiter)
+			if o.backoff.Jitter == JitterDecorrelated {
+				ddbOptions.APIOptions = append(ddbOptions.APIOptions, o.backoff.perCallRetries(limiter))
+			}
+		}
+		if o.throttle != nil {
+			ddbOptions.APIOptions = append(ddbOptions.APIOptions, newThrottler(*o.throttle).middleware)
+		}
+		if o.endpoint != "" {
+			ddbOptions.BaseEndpoint = aws.String(o.endpoint)
+		}
+	})
+	this.streams = dynamodbstreams.NewFromConfig(cfg, func(streamsOptions *dynamodbstreams.Options) {
+		if o.endpoint != "" {
+			streamsOptions.BaseEndpoint = aws.String(o.endpoint)
+		}
+	})
+	this.kms = kms.NewFromConfig(cfg)
+	this.sts = sts.NewFromConfig(cfg)
 
 	return this, nil
 }
@@ -65,6 +183,10 @@ const (
 	storageLSIByTypeAndParent = "ByTypeAndParent"
 )
 
+// MaxItemSize is the most bytes DynamoDB stores in one item, and so in one
+// row.
+const MaxItemSize = 400 * 1024
+
 func (client *Client) createTableIfNotExists(ctx context.Context) error {
 	describeTableOutput, err := client.ddb.DescribeTable(ctx,
 		&dynamodb.DescribeTableInput{
@@ -74,7 +196,7 @@ func (client *Client) createTableIfNotExists(ctx context.Context) error {
 	if err == nil {
 		// table already exists
 		if describeTableOutput != nil {
-			tflog.Debug(ctx, fmt.Sprintf("table %s exists", client.tableName), map[string]interface{}{"tableID": *describeTableOutput.Table.TableId})
+			tflog.SubsystemDebug(ctx, storage.LogSubsystemDynamoDB, fmt.Sprintf("table %s exists", client.tableName), map[string]interface{}{"tableID": *describeTableOutput.Table.TableId})
 		}
 		return nil
 	}
@@ -83,141 +205,85 @@ func (client *Client) createTableIfNotExists(ctx context.Context) error {
 	if ok := errors.As(err, &respErr); ok && respErr.Response != nil {
 		statusCode := respErr.Response.StatusCode
 		if statusCode != http.StatusBadRequest {
-			tflog.Warn(ctx, fmt.Sprintf("DescribeTable failed with HTTP status %d: %s", statusCode, err.Error()))
+			tflog.SubsystemWarn(ctx, storage.LogSubsystemDynamoDB, fmt.Sprintf("DescribeTable failed with HTTP status %d: %s", statusCode, err.Error()))
 		}
 	} else {
-		tflog.Warn(ctx, fmt.Sprintf("unexpected error during DescribeTable: %s", err.Error()))
+		tflog.SubsystemWarn(ctx, storage.LogSubsystemDynamoDB, fmt.Sprintf("unexpected error during DescribeTable: %s", err.Error()))
 		return err
 	}
 
-	input := &dynamodb.CreateTableInput{
-		TableName: aws.String(client.tableName),
-		AttributeDefinitions: []types.AttributeDefinition{
-			{
-				AttributeName: aws.String(storageKeyType),
-				AttributeType: types.ScalarAttributeTypeS,
-			},
-			{
-				AttributeName: aws.String(storageKeyID),
-				AttributeType: types.ScalarAttributeTypeS,
-			},
-			{
-				AttributeName: aws.String(storageAttrParentID),
-				AttributeType: types.ScalarAttributeTypeS,
-			},
-			{
-				AttributeName: aws.String(storageAttrLabel),
-				AttributeType: types.ScalarAttributeTypeS,
-			},
-		},
-		KeySchema: []types.KeySchemaElement{
-			{
-				AttributeName: aws.String(storageKeyType),
-				KeyType:       types.KeyTypeHash,
-			},
-			{
-				AttributeName: aws.String(storageKeyID),
-				KeyType:       types.KeyTypeRange,
-			},
-		},
-		GlobalSecondaryIndexes: []types.GlobalSecondaryIndex{
-			{
-				IndexName: aws.String(storageGSIByParentAndLabel),
-				KeySchema: []types.KeySchemaElement{
-					{
-						AttributeName: aws.String(storageAttrParentID),
-						KeyType:       types.KeyTypeHash,
-					},
-					{
-						AttributeName: aws.String(storageAttrLabel),
-						KeyType:       types.KeyTypeRange,
-					},
-				},
-				Projection: &types.Projection{ProjectionType: types.ProjectionTypeAll},
-			},
-			{
-				IndexName: aws.String(storageGSIByType),
-				KeySchema: []types.KeySchemaElement{
-					{
-						AttributeName: aws.String(storageKeyType),
-						KeyType:       types.KeyTypeHash,
-					},
-				},
-				Projection: &types.Projection{ProjectionType: types.ProjectionTypeAll},
-			},
-		},
-		LocalSecondaryIndexes: []types.LocalSecondaryIndex{
-			{
-				IndexName: aws.String(storageLSIByTypeAndLabel),
-				KeySchema: []types.KeySchemaElement{
-					{
-						AttributeName: aws.String(storageKeyType),
-						KeyType:       types.KeyTypeHash,
-					},
-					{
-						AttributeName: aws.String(storageAttrLabel),
-						KeyType:       types.KeyTypeRange,
-					},
-				},
-				Projection: &types.Projection{ProjectionType: types.ProjectionTypeAll},
-			},
-			{
-				IndexName: aws.String(storageLSIByTypeAndParent),
-				KeySchema: []types.KeySchemaElement{
-					{
-						AttributeName: aws.String(storageKeyType),
-						KeyType:       types.KeyTypeHash,
-					},
-					{
-						AttributeName: aws.String(storageAttrParentID),
-						KeyType:       types.KeyTypeRange,
-					},
-				},
-				Projection: &types.Projection{ProjectionType: types.ProjectionTypeAll},
-			},
-		},
-		BillingMode: types.BillingModePayPerRequest,
-		SSESpecification: &types.SSESpecification{
-			Enabled:        aws.Bool(true),
-			SSEType:        types.SSETypeKms,
-			KMSMasterKeyId: aws.String(client.keyARN),
-		},
+	input := client.tableInput()
+	input.ResourcePolicy, err = client.resourcePolicy(ctx)
+	if err != nil {
+		return err
 	}
 	_, err = client.ddb.CreateTable(ctx, input)
-	return err
+	if err != nil {
+		return err
+	}
+	return client.waitForTable(ctx)
 }
 
 var (
-	ErrCannotDeleteRow      = errors.New("cannot delete row")
-	ErrCollisionParentLabel = errors.New("a row with that parent and label already exists")
-	ErrCollisionTypeLabel   = errors.New("a row with that type and label already exists")
+	ErrCannotDeleteRow      = storage.ErrCannotDeleteRow
+	ErrCollisionParentLabel = storage.ErrCollisionParentLabel
+	ErrCollisionTypeLabel   = storage.ErrCollisionTypeLabel
 	ErrNilQueryOutput       = errors.New("something went wrong: the query output was nil")
-	ErrNotFoundRow          = errors.New("row not found")
-	ErrTooManyFound         = errors.New("multiple exist where there must only be one")
+	ErrNotFoundRow          = storage.ErrNotFoundRow
+	ErrTooManyFound         = storage.ErrTooManyFound
 )
 
 func (client *Client) GetRowByID(ctx context.Context, rowType, id string) (storage.Row, error) {
-	tflog.Debug(ctx, fmt.Sprintf("GetRowByID %q", id))
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemDynamoDB, fmt.Sprintf("GetRowByID %q", id))
+	if cached, ok := client.cache.GetByID(rowType, id); ok {
+		return cached, nil
+	}
+	return client.readOnce(ctx, readKey("id", rowType, id), func() (storage.Row, error) {
+		return client.getRowByID(ctx, rowType, id)
+	})
+}
+
+func (client *Client) getRowByID(ctx context.Context, rowType, id string) (storage.Row, error) {
+	waitForChildren := client.prefetchChildren(ctx, id)
+	defer waitForChildren()
 	output, err := client.ddb.GetItem(ctx, &dynamodb.GetItemInput{
 		TableName: aws.String(client.tableName),
 		Key: map[string]types.AttributeValue{
-			storageKeyType: &types.AttributeValueMemberS{Value: rowType},
+			storageKeyType: &types.AttributeValueMemberS{Value: client.itemTypeKey(rowType, id)},
 			storageKeyID:   &types.AttributeValueMemberS{Value: id},
 		},
 		ConsistentRead: aws.Bool(true),
 	})
+	if isTableMissing(err) {
+		// the table is created by the first write
+		output, err = &dynamodb.GetItemOutput{}, nil
+	}
 	if err != nil {
 		return nil, err
 	}
 	if output.Item == nil {
 		return nil, fmt.Errorf("%w: %q", ErrNotFoundRow, id)
 	}
-	return itemToRow(output.Item)
+	r, err := client.itemToRow(output.Item)
+	if err != nil {
+		return nil, err
+	}
+	client.cache.Put(r)
+	return r, nil
 }
 
 func (client *Client) GetRow(ctx context.Context, rowType, label string) (storage.Row, error) {
-	tflog.Debug(ctx, fmt.Sprintf("GetRow %q %q", rowType, label))
-	output, err := client.ddb.Query(ctx, &dynamodb.QueryInput{
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemDynamoDB, fmt.Sprintf("GetRow %q %q", rowType, label))
+	if cached, ok := client.cache.GetByLabel(rowType, label); ok {
+		return cached, nil
+	}
+	return client.readOnce(ctx, readKey("label", rowType, label), func() (storage.Row, error) {
+		return client.getRow(ctx, rowType, label)
+	})
+}
+
+func (client *Client) getRow(ctx context.Context, rowType, label string) (storage.Row, error) {
+	items, err := client.queryShards(ctx, rowType, &dynamodb.QueryInput{
 		TableName:              aws.String(client.tableName),
 		IndexName:              aws.String(storageLSIByTypeAndLabel),
 		KeyConditionExpression: aws.String("#type = :type AND #label = :label"),
@@ -226,30 +292,34 @@ func (client *Client) GetRow(ctx context.Context, rowType, label string) (storag
 			"#label": storageAttrLabel,
 		},
 		ExpressionAttributeValues: map[string]types.AttributeValue{
-			":type":  &types.AttributeValueMemberS{Value: rowType},
 			":label": &types.AttributeValueMemberS{Value: label},
 		},
-	})
+	}, client.queryPage)
 	if err != nil {
 		return nil, err
 	}
-	if output == nil || output.Items == nil {
-		return nil, ErrNilQueryOutput
-	}
-	if len(output.Items) == 0 {
+	if len(items) == 0 {
 		return nil, fmt.Errorf("%w: type %q and label %q", ErrNotFoundRow, rowType, label)
 	}
-	if len(output.Items) > 1 {
+	if len(items) > 1 {
 		return nil, fmt.Errorf("%w: type %q and label %q", ErrTooManyFound, rowType, label)
 	}
 
-	return itemToRow(output.Items[0])
+	r, err := client.itemToRow(items[0])
+	if err != nil {
+		return nil, err
+	}
+	client.cache.PutLabeled(r)
+	return r, nil
 }
 
 func (client *Client) CreateRow(ctx context.Context, rowType, label string) (storage.Row, error) {
-	tflog.Debug(ctx, fmt.Sprintf("CreateRow %q %q", rowType, label))
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemDynamoDB, fmt.Sprintf("CreateRow %q %q", rowType, label))
+	if err := client.ensureTable(ctx); err != nil {
+		return nil, err
+	}
 	// make sure type+name doesn't collide
-	output, err := client.ddb.Query(ctx, &dynamodb.QueryInput{
+	items, err := client.queryShards(ctx, rowType, keysOnly(&dynamodb.QueryInput{
 		TableName: aws.String(client.tableName),
 		IndexName: aws.String(storageLSIByTypeAndLabel),
 
@@ -259,50 +329,55 @@ func (client *Client) CreateRow(ctx context.Context, rowType, label string) (sto
 			"#label": storageAttrLabel,
 		},
 		ExpressionAttributeValues: map[string]types.AttributeValue{
-			":type":  &types.AttributeValueMemberS{Value: rowType},
 			":label": &types.AttributeValueMemberS{Value: label},
 		},
-	})
+	}), client.queryPage)
 	if err != nil {
 		return nil, err
 	}
-	if output == nil || output.Items == nil {
-		return nil, ErrNilQueryOutput
-	}
-	if len(output.Items) > 0 {
+	if len(items) > 0 {
 		return nil, ErrCollisionTypeLabel
 	}
 
 	id := slug.Generate(rowType)
-
-	// create item as long as type+ID doesn't collide
-	_, err = client.ddb.PutItem(ctx, &dynamodb.PutItemInput{
-		TableName: aws.String(client.tableName),
-		Item: map[string]types.AttributeValue{
-			storageKeyType:   &types.AttributeValueMemberS{Value: rowType},
-			storageKeyID:     &types.AttributeValueMemberS{Value: id},
-			storageAttrLabel: &types.AttributeValueMemberS{Value: label},
-		},
-		ExpressionAttributeNames: map[string]string{
-			"#type": storageKeyType,
-			"#id":   storageKeyID,
-		},
-		ConditionExpression: aws.String("attribute_not_exists(#type) AND attribute_not_exists(#id)"),
+	tflog.SubsystemTrace(ctx, storage.LogSubsystemSlug, fmt.Sprintf("Generated ID %q", id))
+
+	// create item as long as type+ID doesn't collide, and claim its label
+	err = client.writeClaimingLabel(ctx, rowType, label, id, 1, func(staleOwner string) []types.TransactWriteItem {
+		return []types.TransactWriteItem{
+			{Put: &types.Put{
+				TableName: aws.String(client.tableName),
+				Item: map[string]types.AttributeValue{
+					storageKeyType:   &types.AttributeValueMemberS{Value: client.itemTypeKey(rowType, id)},
+					storageKeyID:     &types.AttributeValueMemberS{Value: id},
+					storageAttrLabel: &types.AttributeValueMemberS{Value: label},
+				},
+				ExpressionAttributeNames: map[string]string{
+					"#type": storageKeyType,
+					"#id":   storageKeyID,
+				},
+				ConditionExpression: aws.String("attribute_not_exists(#type) AND attribute_not_exists(#id)"),
+			}},
+			client.claimLabel(rowType, label, id, staleOwner),
+		}
 	})
 	if err != nil {
 		return nil, err
 	}
 
-	return &row{
+	created := &row{
 		RowType:  rowType,
 		RowID:    id,
 		RowLabel: label,
-	}, nil
+	}
+	client.cache.Put(created)
+	return created, nil
 }
 
 func (client *Client) CreateChild(ctx context.Context, rowType, label, parentType, parentID string, columns map[string]interface{}) (storage.Row, error) {
-	tflog.Debug(ctx, fmt.Sprintf("CreateChild %q %q %q %q", rowType, label, parentType, parentID))
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemDynamoDB, fmt.Sprintf("CreateChild %q %q %q %q", rowType, label, parentType, parentID))
 	id := slug.Generate(rowType)
+	tflog.SubsystemTrace(ctx, storage.LogSubsystemSlug, fmt.Sprintf("Generated ID %q", id))
 	object := &row{
 		RowType:    rowType,
 		RowID:      id,
@@ -311,7 +386,7 @@ func (client *Client) CreateChild(ctx context.Context, rowType, label, parentTyp
 	}
 
 	// make sure parent exists
-	parent, err := client.GetRowByID(ctx, parentType, parentID)
+	parent, err := client.GetRowColumns(ctx, parentType, parentID, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -319,7 +394,7 @@ func (client *Client) CreateChild(ctx context.Context, rowType, label, parentTyp
 	object.RowParentID = parent.ID()
 
 	// make sure label is unique within the parent
-	output, err := client.ddb.Query(ctx, &dynamodb.QueryInput{
+	output, err := client.ddb.Query(ctx, keysOnly(&dynamodb.QueryInput{
 		TableName:              aws.String(client.tableName),
 		IndexName:              aws.String(storageGSIByParentAndLabel),
 		KeyConditionExpression: aws.String("#parent_id = :parent_id AND #label = :label"),
@@ -328,10 +403,10 @@ func (client *Client) CreateChild(ctx context.Context, rowType, label, parentTyp
 			"#label":     storageAttrLabel,
 		},
 		ExpressionAttributeValues: map[string]types.AttributeValue{
-			":parent_id": &types.AttributeValueMemberS{Value: parentID},
+			":parent_id": &types.AttributeValueMemberS{Value: client.parentKey(parentID)},
 			":label":     &types.AttributeValueMemberS{Value: label},
 		},
-	})
+	}))
 	if err != nil {
 		return nil, err
 	}
@@ -342,14 +417,18 @@ func (client *Client) CreateChild(ctx context.Context, rowType, label, parentTyp
 		return nil, ErrCollisionParentLabel
 	}
 
+	columnsName, columnsValue, _, err := client.columnsAttribute(columns)
+	if err != nil {
+		return nil, err
+	}
 	_, err = client.ddb.PutItem(ctx, &dynamodb.PutItemInput{
 		TableName: aws.String(client.tableName),
 		Item: map[string]types.AttributeValue{
-			storageKeyType:      &types.AttributeValueMemberS{Value: rowType},
+			storageKeyType:      &types.AttributeValueMemberS{Value: client.itemTypeKey(rowType, id)},
 			storageKeyID:        &types.AttributeValueMemberS{Value: id},
 			storageAttrLabel:    &types.AttributeValueMemberS{Value: label},
-			storageAttrParentID: &types.AttributeValueMemberS{Value: parentID},
-			storageAttrColumns:  &types.AttributeValueMemberM{Value: columnsToMap(columns)},
+			storageAttrParentID: &types.AttributeValueMemberS{Value: client.parentKey(parentID)},
+			columnsName:         columnsValue,
 		},
 		ExpressionAttributeNames: map[string]string{
 			"#type": storageKeyType,
@@ -361,11 +440,21 @@ func (client *Client) CreateChild(ctx context.Context, rowType, label, parentTyp
 		return nil, err
 	}
 
+	client.cache.Put(object)
 	return object, nil
 }
 
 func (client *Client) GetChild(ctx context.Context, label, parentID string) (storage.Row, error) {
-	tflog.Debug(ctx, fmt.Sprintf("GetChild %q %q", label, parentID))
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemDynamoDB, fmt.Sprintf("GetChild %q %q", label, parentID))
+	if cached, ok := client.cache.GetChild(parentID, label); ok {
+		return cached, nil
+	}
+	return client.readOnce(ctx, readKey("child", parentID, label), func() (storage.Row, error) {
+		return client.getChild(ctx, label, parentID)
+	})
+}
+
+func (client *Client) getChild(ctx context.Context, label, parentID string) (storage.Row, error) {
 	output, err := client.ddb.Query(ctx, &dynamodb.QueryInput{
 		TableName:              aws.String(client.tableName),
 		IndexName:              aws.String(storageGSIByParentAndLabel),
@@ -375,10 +464,13 @@ func (client *Client) GetChild(ctx context.Context, label, parentID string) (sto
 			"#label":     storageAttrLabel,
 		},
 		ExpressionAttributeValues: map[string]types.AttributeValue{
-			":parent_id": &types.AttributeValueMemberS{Value: parentID},
+			":parent_id": &types.AttributeValueMemberS{Value: client.parentKey(parentID)},
 			":label":     &types.AttributeValueMemberS{Value: label},
 		},
 	})
+	if isTableMissing(err) {
+		output, err = &dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{}}, nil
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -392,11 +484,39 @@ func (client *Client) GetChild(ctx context.Context, label, parentID string) (sto
 		return nil, fmt.Errorf("%w: parent ID %q and label %q", ErrTooManyFound, parentID, label)
 	}
 
-	return itemToRow(output.Items[0])
+	r, err := client.itemToRow(output.Items[0])
+	if err != nil {
+		return nil, err
+	}
+	client.cache.Put(r)
+	return r, nil
 }
 
 func (client *Client) ListRows(ctx context.Context, rowType, labelFilter, parentIDFilter string) ([]storage.Row, error) {
-	tflog.Debug(ctx, fmt.Sprintf("ListRows %q %q %q", rowType, labelFilter, parentIDFilter))
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemDynamoDB, fmt.Sprintf("ListRows %q %q %q", rowType, labelFilter, parentIDFilter))
+	input := client.listInput(rowType, labelFilter, parentIDFilter)
+	if client.listConcurrency > 1 && client.shards[rowType] <= 1 && parentIDFilter == "" {
+		return client.querySegments(ctx, input, rowType)
+	}
+	items, err := client.queryShards(ctx, rowType, input, client.queryPages)
+	if err != nil {
+		return nil, err
+	}
+	rows := make([]storage.Row, len(items))
+	for i, item := range items {
+		rows[i], err = client.itemToRow(item)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return rows, nil
+}
+
+// listInput returns the query for ListRows, whose :type is the stored type of
+// the row type, or of one of its shards. With a parent filter, it queries the
+// ByTypeAndParent index for the parent's children alone; without one, the
+// ByType index for every row of the type.
+func (client *Client) listInput(rowType, labelFilter, parentIDFilter string) *dynamodb.QueryInput {
 	input := &dynamodb.QueryInput{
 		TableName:              aws.String(client.tableName),
 		IndexName:              aws.String(storageGSIByType),
@@ -405,50 +525,91 @@ func (client *Client) ListRows(ctx context.Context, rowType, labelFilter, parent
 			"#type": storageKeyType,
 		},
 		ExpressionAttributeValues: map[string]types.AttributeValue{
-			":type": &types.AttributeValueMemberS{Value: rowType},
+			":type": &types.AttributeValueMemberS{Value: client.typeKey(rowType)},
 		},
 	}
-
-	filterExprs := []string{}
-	if labelFilter != "" {
-		filterExprs = append(filterExprs, "contains(#label, :label)")
-		input.ExpressionAttributeNames["#label"] = storageAttrLabel
-		input.ExpressionAttributeValues[":label"] = &types.AttributeValueMemberS{Value: labelFilter}
-	}
 	if parentIDFilter != "" {
-		filterExprs = append(filterExprs, "#parent_id = :parent_id")
+		input.IndexName = aws.String(storageLSIByTypeAndParent)
+		input.KeyConditionExpression = aws.String("#type = :type AND #parent_id = :parent_id")
 		input.ExpressionAttributeNames["#parent_id"] = storageAttrParentID
-		input.ExpressionAttributeValues[":parent_id"] = &types.AttributeValueMemberS{Value: parentIDFilter}
+		input.ExpressionAttributeValues[":parent_id"] = &types.AttributeValueMemberS{Value: client.parentKey(parentIDFilter)}
 	}
-	if len(filterExprs) > 0 {
-		input.FilterExpression = aws.String(strings.Join(filterExprs, " AND "))
+	if labelFilter != "" {
+		input.FilterExpression = aws.String("contains(#label, :label)")
+		input.ExpressionAttributeNames["#label"] = storageAttrLabel
+		input.ExpressionAttributeValues[":label"] = &types.AttributeValueMemberS{Value: labelFilter}
 	}
+	return input
+}
 
-	output, err := client.ddb.Query(ctx, input)
-	if err != nil {
-		return nil, err
-	}
-	if output == nil || output.Items == nil {
-		return nil, ErrNilQueryOutput
-	}
-	rows := make([]storage.Row, len(output.Items))
-	for i, item := range output.Items {
-		rows[i], err = itemToRow(item)
+// UpdateRow relabels a root row in one transaction, which fails if another
+// row of its type claims the label, or if the row changed since it was read,
+// in which case it's read again and tried once more. Rows with parents are
+// relabeled as UpdateChild would, checking their siblings' labels first.
+func (client *Client) UpdateRow(ctx context.Context, rowType, id, newLabel string) (storage.Row, error) {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemDynamoDB, fmt.Sprintf("UpdateRow %q %q %q", rowType, id, newLabel))
+	for attempt := 0; ; attempt++ {
+		this, err := client.GetRowByID(ctx, rowType, id)
 		if err != nil {
 			return nil, err
 		}
+		if this.Label() == newLabel {
+			return this, nil
+		}
+		if this.ParentID() != "" {
+			return client.relabelChild(ctx, this, newLabel)
+		}
+		err = client.relabelRoot(ctx, this, newLabel)
+		if _, failed := conditionFailed(err, 0); failed {
+			// the row was read from the cache, or changed since
+			client.cache.Invalidate(rowType, id)
+			if attempt == 0 {
+				continue
+			}
+			return nil, fmt.Errorf("%w: %s %q was changed or deleted while being relabeled", storage.ErrConflict, rowType, id)
+		}
+		if err != nil {
+			return nil, err
+		}
+		updated := copyRow(this)
+		updated.RowLabel = newLabel
+		client.cache.Put(updated)
+		return updated, nil
 	}
-	return rows, nil
 }
 
-func (client *Client) UpdateRow(ctx context.Context, rowType, id, newLabel string) (storage.Row, error) {
-	tflog.Debug(ctx, fmt.Sprintf("UpdatRow %q %q %q", rowType, id, newLabel))
-	// ensure new label is available
-	this, err := client.GetRowByID(ctx, rowType, id)
-	if err != nil {
-		return nil, err
-	}
-	_, err = client.GetChild(ctx, newLabel, this.ParentID())
+// relabelRoot relabels a root row, claiming its new label and releasing its
+// old one, on condition that its label hasn't changed since it was read.
+func (client *Client) relabelRoot(ctx context.Context, this storage.Row, newLabel string) error {
+	return client.writeClaimingLabel(ctx, this.Type(), newLabel, this.ID(), 1, func(staleOwner string) []types.TransactWriteItem {
+		return []types.TransactWriteItem{
+			{Update: &types.Update{
+				TableName: aws.String(client.tableName),
+				Key: map[string]types.AttributeValue{
+					storageKeyType: &types.AttributeValueMemberS{Value: client.itemTypeKey(this.Type(), this.ID())},
+					storageKeyID:   &types.AttributeValueMemberS{Value: this.ID()},
+				},
+				UpdateExpression: aws.String("SET #label = :new_label"),
+				ExpressionAttributeNames: map[string]string{
+					"#label": storageAttrLabel,
+					"#id":    storageKeyID,
+				},
+				ExpressionAttributeValues: map[string]types.AttributeValue{
+					":new_label": &types.AttributeValueMemberS{Value: newLabel},
+					":old_label": &types.AttributeValueMemberS{Value: this.Label()},
+				},
+				ConditionExpression: aws.String("attribute_exists(#id) AND #label = :old_label"),
+			}},
+			client.claimLabel(this.Type(), newLabel, this.ID(), staleOwner),
+			client.releaseLabel(this.Type(), this.Label(), this.ID()),
+		}
+	})
+}
+
+// relabelChild relabels a row with a parent, on condition that its label
+// hasn't changed since it was read.
+func (client *Client) relabelChild(ctx context.Context, this storage.Row, newLabel string) (storage.Row, error) {
+	_, err := client.GetChild(ctx, newLabel, this.ParentID())
 	if err == nil {
 		return nil, ErrCollisionParentLabel
 	}
@@ -459,32 +620,33 @@ func (client *Client) UpdateRow(ctx context.Context, rowType, id, newLabel strin
 	output, err := client.ddb.UpdateItem(ctx, &dynamodb.UpdateItemInput{
 		TableName: aws.String(client.tableName),
 		Key: map[string]types.AttributeValue{
-			storageKeyType: &types.AttributeValueMemberS{Value: rowType},
-			storageKeyID:   &types.AttributeValueMemberS{Value: id},
+			storageKeyType: &types.AttributeValueMemberS{Value: client.itemTypeKey(this.Type(), this.ID())},
+			storageKeyID:   &types.AttributeValueMemberS{Value: this.ID()},
 		},
 		UpdateExpression: aws.String("SET #label = :new_label"),
 		ExpressionAttributeNames: map[string]string{
 			"#label": storageAttrLabel,
-			"#type":  storageKeyType,
 			"#id":    storageKeyID,
 		},
 		ExpressionAttributeValues: map[string]types.AttributeValue{
 			":new_label": &types.AttributeValueMemberS{Value: newLabel},
+			":old_label": &types.AttributeValueMemberS{Value: this.Label()},
 		},
-		ConditionExpression: aws.String("attribute_not_exists(#type) AND attribute_not_exists(#id)"),
+		ConditionExpression: aws.String("attribute_exists(#id) AND #label = :old_label"),
 		ReturnValues:        types.ReturnValueAllNew,
 	})
 	if err != nil {
+		client.cache.Invalidate(this.Type(), this.ID())
 		return nil, err
 	}
 	if output == nil || output.Attributes == nil {
 		return nil, ErrNilQueryOutput
 	}
-	return itemToRow(output.Attributes)
+	return client.updated(output.Attributes)
 }
 
 func (client *Client) UpdateChild(ctx context.Context, childType, childID, newChildLabel, parentType, newParentID string) (storage.Row, error) {
-	tflog.Debug(ctx, fmt.Sprintf("UpdateChild %q %q %q %q %q", childType, childID, newChildLabel, parentType, newParentID))
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemDynamoDB, fmt.Sprintf("UpdateChild %q %q %q %q %q", childType, childID, newChildLabel, parentType, newParentID))
 	// ensure new parent exists
 	_, err := client.GetRowByID(ctx, parentType, newParentID)
 	if err != nil {
@@ -504,7 +666,7 @@ func (client *Client) UpdateChild(ctx context.Context, childType, childID, newCh
 	output, err := client.ddb.UpdateItem(ctx, &dynamodb.UpdateItemInput{
 		TableName: aws.String(client.tableName),
 		Key: map[string]types.AttributeValue{
-			storageKeyType: &types.AttributeValueMemberS{Value: childType},
+			storageKeyType: &types.AttributeValueMemberS{Value: client.itemTypeKey(childType, childID)},
 			storageKeyID:   &types.AttributeValueMemberS{Value: childID},
 		},
 		UpdateExpression: aws.String("SET #label = :new_label, #parent_id = :new_parent_id"),
@@ -516,9 +678,9 @@ func (client *Client) UpdateChild(ctx context.Context, childType, childID, newCh
 		},
 		ExpressionAttributeValues: map[string]types.AttributeValue{
 			":new_label":     &types.AttributeValueMemberS{Value: newChildLabel},
-			":new_parent_id": &types.AttributeValueMemberS{Value: newParentID},
+			":new_parent_id": &types.AttributeValueMemberS{Value: client.parentKey(newParentID)},
 		},
-		ConditionExpression: aws.String("attribute_not_exists(#type) AND attribute_not_exists(#id)"),
+		ConditionExpression: aws.String("attribute_exists(#type) AND attribute_exists(#id)"),
 		ReturnValues:        types.ReturnValueAllNew,
 	})
 	if err != nil {
@@ -527,62 +689,72 @@ func (client *Client) UpdateChild(ctx context.Context, childType, childID, newCh
 	if output == nil || output.Attributes == nil {
 		return nil, ErrNilQueryOutput
 	}
-	return itemToRow(output.Attributes)
+	return client.updated(output.Attributes)
 }
 
 func (client *Client) UpdateColumn(ctx context.Context, rowType, rowID, columnName string, columnValue interface{}) error {
-	tflog.Debug(ctx, fmt.Sprintf("UpdateColumn %q %q %q %q", rowType, rowID, columnName, columnValue))
-
-	value := ifaceToAttributeValue(columnValue)
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemDynamoDB, fmt.Sprintf("UpdateColumn %q %q %q %s", rowType, rowID, columnName, storage.LogColumnValue(rowType, columnName, columnValue)))
 
-	_, err := client.ddb.UpdateItem(ctx, &dynamodb.UpdateItemInput{
-		TableName: aws.String(client.tableName),
-		Key: map[string]types.AttributeValue{
-			storageKeyType: &types.AttributeValueMemberS{Value: rowType},
-			storageKeyID:   &types.AttributeValueMemberS{Value: rowID},
-		},
-		UpdateExpression: aws.String("SET #columns.#key = :value"),
-		ExpressionAttributeNames: map[string]string{
-			"#columns": storageAttrColumns,
-			"#key":     columnName,
-			"#type":    storageKeyType,
-			"#id":      storageKeyID,
-		},
-		ExpressionAttributeValues: map[string]types.AttributeValue{
-			":value": value,
-		},
-		ConditionExpression: aws.String("attribute_exists(#type) AND attribute_exists(#id)"),
-	})
+	if client.compact {
+		err := client.updateCompactColumns(ctx, rowType, rowID, map[string]interface{}{columnName: columnValue})
+		client.cache.Invalidate(rowType, rowID)
+		return err
+	}
+	columns := map[string]interface{}{columnName: columnValue}
+	var err error
+	for attempt := 0; attempt < columnWriteAttempts; attempt++ {
+		// a row without a columns map gets one, with the column in it
+		update := client.columnsUpdate(rowType, rowID, columns, attempt%2 == 1)
+		_, err = client.ddb.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+			TableName:                 update.TableName,
+			Key:                       update.Key,
+			UpdateExpression:          update.UpdateExpression,
+			ExpressionAttributeNames:  update.ExpressionAttributeNames,
+			ExpressionAttributeValues: update.ExpressionAttributeValues,
+			ConditionExpression:       update.ConditionExpression,
+		})
+		if !errors.Is(err, storage.ErrConflict) {
+			break
+		}
+	}
+	client.cache.Invalidate(rowType, rowID)
 	return err
 }
 
 func (client *Client) UpdateColumns(ctx context.Context, rowType, rowID string, columns map[string]interface{}) error {
-	tflog.Debug(ctx, fmt.Sprintf("UpdateColumns %q %q", rowType, rowID))
-	_, err := client.ddb.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemDynamoDB, fmt.Sprintf("UpdateColumns %q %q %s", rowType, rowID, storage.LogColumns(rowType, columns)))
+	// the columns replace those in either form
+	columnsName, columnsValue, otherName, err := client.columnsAttribute(columns)
+	if err != nil {
+		return err
+	}
+	_, err = client.ddb.UpdateItem(ctx, &dynamodb.UpdateItemInput{
 		TableName: aws.String(client.tableName),
 		Key: map[string]types.AttributeValue{
-			storageKeyType: &types.AttributeValueMemberS{Value: rowType},
+			storageKeyType: &types.AttributeValueMemberS{Value: client.itemTypeKey(rowType, rowID)},
 			storageKeyID:   &types.AttributeValueMemberS{Value: rowID},
 		},
-		UpdateExpression: aws.String("SET #columns = :new_columns"),
+		UpdateExpression: aws.String("SET #columns = :new_columns REMOVE #other_columns"),
 		ExpressionAttributeNames: map[string]string{
-			"#columns": storageAttrColumns,
-			"#type":    storageKeyType,
-			"#id":      storageKeyID,
+			"#columns":       columnsName,
+			"#other_columns": otherName,
+			"#type":          storageKeyType,
+			"#id":            storageKeyID,
 		},
 		ExpressionAttributeValues: map[string]types.AttributeValue{
-			":new_columns": &types.AttributeValueMemberM{Value: columnsToMap(columns)},
+			":new_columns": columnsValue,
 		},
 		ConditionExpression: aws.String("attribute_exists(#type) AND attribute_exists(#id)"),
 	})
+	client.cache.Invalidate(rowType, rowID)
 	return err
 }
 
 func (client *Client) DeleteRow(ctx context.Context, rowType, childType, id string) error {
-	tflog.Debug(ctx, fmt.Sprintf("DeleteRow %q %q %q", rowType, childType, id))
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemDynamoDB, fmt.Sprintf("DeleteRow %q %q %q", rowType, childType, id))
 	// ensure this row does not have any children
 	if len(childType) > 0 {
-		output, err := client.ddb.Query(ctx, &dynamodb.QueryInput{
+		items, err := client.queryShards(ctx, childType, keysOnly(&dynamodb.QueryInput{
 			TableName:              aws.String(client.tableName),
 			IndexName:              aws.String(storageLSIByTypeAndParent),
 			KeyConditionExpression: aws.String("#type = :type AND #parent_id = :parent_id"),
@@ -591,25 +763,21 @@ func (client *Client) DeleteRow(ctx context.Context, rowType, childType, id stri
 				"#parent_id": storageAttrParentID,
 			},
 			ExpressionAttributeValues: map[string]types.AttributeValue{
-				":type":      &types.AttributeValueMemberS{Value: childType},
-				":parent_id": &types.AttributeValueMemberS{Value: id},
+				":parent_id": &types.AttributeValueMemberS{Value: client.parentKey(id)},
 			},
-		})
+		}), client.queryPage)
 		if err != nil {
 			return err
 		}
-		if output == nil || output.Items == nil {
-			return ErrNilQueryOutput
-		}
-		if len(output.Items) > 0 {
+		if len(items) > 0 {
 			return fmt.Errorf("%s %s has children: %w", rowType, id, ErrCannotDeleteRow)
 		}
 	}
 
-	_, err := client.ddb.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+	output, err := client.ddb.DeleteItem(ctx, &dynamodb.DeleteItemInput{
 		TableName: aws.String(client.tableName),
 		Key: map[string]types.AttributeValue{
-			storageKeyType: &types.AttributeValueMemberS{Value: rowType},
+			storageKeyType: &types.AttributeValueMemberS{Value: client.itemTypeKey(rowType, id)},
 			storageKeyID:   &types.AttributeValueMemberS{Value: id},
 		},
 		ExpressionAttributeNames: map[string]string{
@@ -617,6 +785,113 @@ func (client *Client) DeleteRow(ctx context.Context, rowType, childType, id stri
 			"#id":   storageKeyID,
 		},
 		ConditionExpression: aws.String("attribute_exists(#type) and attribute_exists(#id)"),
+		ReturnValues:        types.ReturnValueAllOld,
 	})
+	client.cache.Invalidate(rowType, id)
+	if err != nil {
+		return err
+	}
+	return client.releaseDeletedLabel(ctx, output.Attributes)
+}
+
+// releaseDeletedLabel releases the label of a deleted root row. Releasing it
+// is only tidying up, since a claim whose owner is gone is taken over, so a
+// claim that has already moved on isn't an error.
+func (client *Client) releaseDeletedLabel(ctx context.Context, item map[string]types.AttributeValue) error {
+	if len(item) == 0 {
+		return nil
+	}
+	deleted, err := client.itemToRow(item)
+	if err != nil || deleted.ParentID() != "" {
+		return err
+	}
+	release := client.releaseLabel(deleted.Type(), deleted.Label(), deleted.ID()).Delete
+	_, err = client.ddb.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName:                 release.TableName,
+		Key:                       release.Key,
+		ConditionExpression:       release.ConditionExpression,
+		ExpressionAttributeNames:  release.ExpressionAttributeNames,
+		ExpressionAttributeValues: release.ExpressionAttributeValues,
+	})
+	if errors.Is(err, storage.ErrConflict) {
+		return nil
+	}
 	return err
 }
+
+func (client *Client) ScanRows(ctx context.Context, fn func(storage.Row) error) error {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemDynamoDB, "ScanRows")
+	paginator := dynamodb.NewScanPaginator(client.ddb, &dynamodb.ScanInput{
+		TableName: aws.String(client.tableName),
+	})
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+		if isTableMissing(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		for _, item := range output.Items {
+			if !client.inTenant(item) || isLabelGuard(item) {
+				continue
+			}
+			r, err := client.itemToRow(item)
+			if err != nil {
+				return err
+			}
+			if err := fn(r); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (client *Client) PutRow(ctx context.Context, r storage.Row) error {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemDynamoDB, fmt.Sprintf("PutRow %q %q", r.Type(), r.ID()))
+	if err := client.ensureTable(ctx); err != nil {
+		return err
+	}
+	item, err := client.rowToItem(r)
+	if err != nil {
+		return err
+	}
+	if r.ParentID() == "" {
+		// the row takes its label, from whichever row claimed it
+		_, err = client.ddb.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+			TransactItems: []types.TransactWriteItem{
+				{Put: &types.Put{TableName: aws.String(client.tableName), Item: item}},
+				{Put: &types.Put{TableName: aws.String(client.tableName), Item: client.labelGuard(r.Type(), r.Label(), r.ID())}},
+			},
+		})
+	} else {
+		_, err = client.ddb.PutItem(ctx, &dynamodb.PutItemInput{
+			TableName: aws.String(client.tableName),
+			Item:      item,
+		})
+	}
+	client.cache.Invalidate(r.Type(), r.ID())
+	return err
+}
+
+// rowToItem returns the item that stores the row as it is.
+func (client *Client) rowToItem(r storage.Row) (map[string]types.AttributeValue, error) {
+	item := map[string]types.AttributeValue{
+		storageKeyType:   &types.AttributeValueMemberS{Value: client.itemTypeKey(r.Type(), r.ID())},
+		storageKeyID:     &types.AttributeValueMemberS{Value: r.ID()},
+		storageAttrLabel: &types.AttributeValueMemberS{Value: r.Label()},
+	}
+	// root rows have no parent_id, since index keys can't be empty
+	if r.ParentID() != "" {
+		item[storageAttrParentID] = &types.AttributeValueMemberS{Value: client.parentKey(r.ParentID())}
+	}
+	if len(r.Columns()) > 0 {
+		name, value, _, err := client.columnsAttribute(r.Columns())
+		if err != nil {
+			return nil, err
+		}
+		item[name] = value
+	}
+	return item, nil
+}
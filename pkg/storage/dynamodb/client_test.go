@@ -0,0 +1,136 @@
+package dynamodb_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/dynamodb"
+)
+
+// TestUpdateRowStale checks that UpdateRow of a root row whose label changed
+// since the client cached it reads it again, and relabels it.
+func TestUpdateRowStale(t *testing.T) {
+	ctx := context.Background()
+	f := newFake(t)
+	client, table := f.newClient(t, dynamodb.WithReadCache(10))
+	org, err := client.CreateRow(ctx, "org", "acme")
+	if err != nil {
+		t.Fatalf("CreateRow: %s", err)
+	}
+	if _, err := client.GetRowByID(ctx, "org", org.ID()); err != nil {
+		t.Fatalf("GetRowByID: %s", err)
+	}
+	// another client relabels it
+	other := f.clientOf(t, table)
+	if _, err := other.UpdateRow(ctx, "org", org.ID(), "umbrella"); err != nil {
+		t.Fatalf("UpdateRow of the other client: %s", err)
+	}
+
+	updated, err := client.UpdateRow(ctx, "org", org.ID(), "globex")
+	if err != nil {
+		t.Fatalf("UpdateRow of a cached row: %s", err)
+	}
+	if updated.Label() != "globex" {
+		t.Errorf("UpdateRow returned label %q, not %q", updated.Label(), "globex")
+	}
+	if got := f.item(table, "org", org.ID()).str("label"); got != "globex" {
+		t.Errorf("stored label is %q, not %q", got, "globex")
+	}
+	checkGuard(t, f, table, "org#label", "globex", org.ID())
+	if guard := f.item(table, "org#label", "umbrella"); guard != nil {
+		t.Errorf("UpdateRow left the guard of the label it replaced: %v", guard)
+	}
+}
+
+// TestUpdateRowChanging checks that UpdateRow gives up on a root row whose
+// label keeps changing.
+func TestUpdateRowChanging(t *testing.T) {
+	ctx := context.Background()
+	f := newFake(t)
+	client, table := f.newClient(t)
+	org, err := client.CreateRow(ctx, "org", "acme")
+	if err != nil {
+		t.Fatalf("CreateRow: %s", err)
+	}
+
+	// each time it's about to be relabeled, another writer relabels it
+	changes := 0
+	f.before = func(op string) *fakeError {
+		if op != "TransactWriteItems" {
+			return nil
+		}
+		changes++
+		changed := f.item(table, "org", org.ID())
+		changed["label"] = s(fmt.Sprintf("changed-%d", changes))
+		f.put(table, changed)
+		return nil
+	}
+	_, err = client.UpdateRow(ctx, "org", org.ID(), "globex")
+	if !errors.Is(err, storage.ErrConflict) {
+		t.Errorf("UpdateRow of a row that kept changing returned %v, not %s", err, storage.ErrConflict)
+	}
+	if changes != 2 {
+		t.Errorf("UpdateRow tried %d times, not 2", changes)
+	}
+}
+
+// TestColumnsOfRootRows checks that columns can be set on root rows, which
+// are created without a columns map to set them in, alone and in a patch
+// with rows that have one.
+func TestColumnsOfRootRows(t *testing.T) {
+	ctx := context.Background()
+	f := newFake(t)
+	client, _ := f.newClient(t)
+	acme, err := client.CreateRow(ctx, "org", "acme")
+	if err != nil {
+		t.Fatalf("CreateRow: %s", err)
+	}
+	globex, err := client.CreateRow(ctx, "org", "globex")
+	if err != nil {
+		t.Fatalf("CreateRow: %s", err)
+	}
+	team, err := client.CreateChild(ctx, "team", "infra", "org", acme.ID(), map[string]interface{}{"owner": "ops"})
+	if err != nil {
+		t.Fatalf("CreateChild: %s", err)
+	}
+
+	if err := client.UpdateColumn(ctx, "org", acme.ID(), "owner", "ops"); err != nil {
+		t.Fatalf("UpdateColumn of a root row: %s", err)
+	}
+	patches := []storage.ColumnPatch{
+		{Type: "org", ID: globex.ID(), Columns: map[string]interface{}{"owner": "dev"}},
+		{Type: "team", ID: team.ID(), Columns: map[string]interface{}{"tier": "gold"}},
+	}
+	if err := client.PatchColumns(ctx, patches); err != nil {
+		t.Fatalf("PatchColumns of a root row: %s", err)
+	}
+
+	for id, want := range map[string]map[string]interface{}{
+		acme.ID():   {"owner": "ops"},
+		globex.ID(): {"owner": "dev"},
+	} {
+		got, err := client.GetRowByID(ctx, "org", id)
+		if err != nil {
+			t.Fatalf("GetRowByID: %s", err)
+		}
+		if fmt.Sprint(got.Columns()) != fmt.Sprint(want) {
+			t.Errorf("%s has columns %v, not %v", id, got.Columns(), want)
+		}
+	}
+	got, err := client.GetRowByID(ctx, "team", team.ID())
+	if err != nil {
+		t.Fatalf("GetRowByID: %s", err)
+	}
+	if fmt.Sprint(got.Columns()) != fmt.Sprint(map[string]interface{}{"owner": "ops", "tier": "gold"}) {
+		t.Errorf("the team has columns %v", got.Columns())
+	}
+
+	// a missing row is still an error
+	err = client.UpdateColumn(ctx, "org", "missing", "owner", "ops")
+	if !errors.Is(err, storage.ErrConflict) && !errors.Is(err, storage.ErrNotFoundRow) {
+		t.Errorf("UpdateColumn of a missing row returned %v", err)
+	}
+}
@@ -0,0 +1,173 @@
+package dynamodb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+// storageAttrColumnsJSON holds a row's columns as one JSON object, in place
+// of storageAttrColumns, for clients with WithCompactColumns.
+const storageAttrColumnsJSON = "columns_json"
+
+// compactUpdateAttempts is how many times UpdateColumn reads and writes a
+// compact row's columns before giving up on writers changing them at once.
+const compactUpdateAttempts = 5
+
+// WithCompactColumns stores each row's columns as one JSON string attribute,
+// rather than as a map of attributes. Items are smaller, since the map's type
+// descriptors aren't stored, and column names are only JSON keys, never
+// document paths, so names DynamoDB can't address are fine. Updating one
+// column reads the row's columns and writes them all back, on condition that
+// no other writer changed them in between.
+//
+// Clients read rows in either form, whether or not they have the option, and
+// the option's writes convert rows they write whole. Clients without it can't
+// update single columns of compact rows, so once a table's writers have the
+// option, keep it.
+func WithCompactColumns() Option {
+	return func(o *options) { o.compactColumns = true }
+}
+
+// columnsAttribute returns the name and value of the attribute a row's
+// columns are stored in, and the name of the other, which a write of them
+// must remove.
+func (client *Client) columnsAttribute(columns map[string]interface{}) (string, types.AttributeValue, string, error) {
+	if !client.compact {
+		return storageAttrColumns, &types.AttributeValueMemberM{Value: columnsToMap(columns)}, storageAttrColumnsJSON, nil
+	}
+	encoded, err := encodeColumns(columns)
+	if err != nil {
+		return "", nil, "", err
+	}
+	return storageAttrColumnsJSON, encoded, storageAttrColumns, nil
+}
+
+func encodeColumns(columns map[string]interface{}) (types.AttributeValue, error) {
+	if columns == nil {
+		columns = map[string]interface{}{}
+	}
+	b, err := json.Marshal(columns)
+	if err != nil {
+		return nil, fmt.Errorf("%w: encoding columns: %s", storage.ErrInvalid, err)
+	}
+	return &types.AttributeValueMemberS{Value: string(b)}, nil
+}
+
+// decodeColumns returns the columns of a compact item, with JSON arrays as
+// string sets, as the map form reads them.
+func decodeColumns(encoded string) (map[string]interface{}, error) {
+	columns := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(encoded), &columns); err != nil {
+		return nil, fmt.Errorf("decoding columns: %w", err)
+	}
+	for name, value := range columns {
+		list, ok := value.([]interface{})
+		if !ok {
+			continue
+		}
+		set := make([]string, 0, len(list))
+		for _, element := range list {
+			s, ok := element.(string)
+			if !ok {
+				return nil, fmt.Errorf("decoding columns: column %q isn't a set of strings", name)
+			}
+			set = append(set, s)
+		}
+		columns[name] = set
+	}
+	return columns, nil
+}
+
+// updateCompactColumns sets some columns of a row by rewriting all of its
+// columns, reading them first, and writing them back on condition that they
+// haven't changed. Columns the patch sets to nil are removed.
+func (client *Client) updateCompactColumns(ctx context.Context, rowType, rowID string, patch map[string]interface{}) error {
+	key := map[string]types.AttributeValue{
+		storageKeyType: &types.AttributeValueMemberS{Value: client.itemTypeKey(rowType, rowID)},
+		storageKeyID:   &types.AttributeValueMemberS{Value: rowID},
+	}
+	var err error
+	for attempt := 0; attempt < compactUpdateAttempts; attempt++ {
+		var retry bool
+		retry, err = client.tryCompactColumnsUpdate(ctx, rowID, key, patch)
+		if !retry {
+			break
+		}
+	}
+	return err
+}
+
+// tryCompactColumnsUpdate makes one attempt at updateCompactColumns, and
+// reports whether to try again, because another writer changed the columns.
+func (client *Client) tryCompactColumnsUpdate(ctx context.Context, rowID string, key map[string]types.AttributeValue, patch map[string]interface{}) (bool, error) {
+	output, err := client.ddb.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(client.tableName),
+		Key:       key,
+		// the type, so that a row without columns still has an item
+		ProjectionExpression: aws.String("#type, #columns, #columns_json"),
+		ExpressionAttributeNames: map[string]string{
+			"#type":         storageKeyType,
+			"#columns":      storageAttrColumns,
+			"#columns_json": storageAttrColumnsJSON,
+		},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return false, err
+	}
+	if output.Item == nil {
+		return false, fmt.Errorf("%w: %q", ErrNotFoundRow, rowID)
+	}
+	stored, err := itemToRow(output.Item)
+	if err != nil {
+		return false, err
+	}
+	columns := map[string]interface{}{}
+	for name, value := range stored.RowColumns {
+		columns[name] = value
+	}
+	for name, value := range patch {
+		if value == nil {
+			delete(columns, name)
+			continue
+		}
+		columns[name] = value
+	}
+	encoded, err := encodeColumns(columns)
+	if err != nil {
+		return false, err
+	}
+
+	input := &dynamodb.UpdateItemInput{
+		TableName:        aws.String(client.tableName),
+		Key:              key,
+		UpdateExpression: aws.String("SET #columns_json = :columns_json REMOVE #columns"),
+		ExpressionAttributeNames: map[string]string{
+			"#columns":      storageAttrColumns,
+			"#columns_json": storageAttrColumnsJSON,
+			"#type":         storageKeyType,
+			"#id":           storageKeyID,
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":columns_json": encoded,
+		},
+		ConditionExpression: aws.String("attribute_exists(#type) AND attribute_exists(#id) AND attribute_not_exists(#columns) AND attribute_not_exists(#columns_json)"),
+	}
+	// the condition is that the columns are as read
+	if old, ok := output.Item[storageAttrColumnsJSON]; ok {
+		input.ExpressionAttributeValues[":old_columns_json"] = old
+		input.ConditionExpression = aws.String("attribute_exists(#type) AND attribute_exists(#id) AND attribute_not_exists(#columns) AND #columns_json = :old_columns_json")
+	} else if old, ok := output.Item[storageAttrColumns]; ok {
+		input.ExpressionAttributeValues[":old_columns"] = old
+		input.ConditionExpression = aws.String("attribute_exists(#type) AND attribute_exists(#id) AND #columns = :old_columns AND attribute_not_exists(#columns_json)")
+	}
+	_, err = client.ddb.UpdateItem(ctx, input)
+	return errors.Is(err, storage.ErrConflict), err
+}
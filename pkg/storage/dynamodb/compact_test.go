@@ -0,0 +1,64 @@
+package dynamodb_test
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/dynamodb"
+)
+
+func TestCompactColumns(t *testing.T) {
+	ctx := context.Background()
+	f := newFake(t)
+	mapped, table := f.newClient(t)
+	compact := f.clientOf(t, table, dynamodb.WithCompactColumns())
+
+	org, err := mapped.CreateRow(ctx, "org", "acme")
+	if err != nil {
+		t.Fatalf("CreateRow: %s", err)
+	}
+	team, err := mapped.CreateChild(ctx, "team", "dev", "org", org.ID(), map[string]interface{}{"owner": "ops"})
+	if err != nil {
+		t.Fatalf("CreateChild: %s", err)
+	}
+	if item := f.item(table, "team", team.ID()); item["columns"] == nil {
+		t.Errorf("a client without the option didn't store columns as a map: %v", item)
+	}
+
+	// updating a column of a row in map form converts it
+	if err := compact.UpdateColumn(ctx, "team", team.ID(), "tags", []string{"b", "a"}); err != nil {
+		t.Fatalf("UpdateColumn: %s", err)
+	}
+	item := f.item(table, "team", team.ID())
+	if item["columns"] != nil {
+		t.Errorf("UpdateColumn left the map of columns: %v", item)
+	}
+	var stored map[string]interface{}
+	if err := json.Unmarshal([]byte(item.str("columns_json")), &stored); err != nil {
+		t.Fatalf("decoding the stored columns %q: %s", item.str("columns_json"), err)
+	}
+	want := map[string]interface{}{"owner": "ops", "tags": []interface{}{"b", "a"}}
+	if !reflect.DeepEqual(stored, want) {
+		t.Errorf("stored columns are %v, not %v", stored, want)
+	}
+
+	// both read either form
+	for name, client := range map[string]*dynamodb.Client{"with the option": compact, "without it": mapped} {
+		got, err := client.GetRowByID(ctx, "team", team.ID())
+		if err != nil {
+			t.Fatalf("GetRowByID %s: %s", name, err)
+		}
+		if got.Columns()["owner"] != "ops" || !reflect.DeepEqual(got.Columns()["tags"], []string{"b", "a"}) {
+			t.Errorf("GetRowByID %s read columns %v", name, got.Columns())
+		}
+	}
+
+	if err := compact.DeleteColumn(ctx, "team", team.ID(), "owner"); err != nil {
+		t.Fatalf("DeleteColumn: %s", err)
+	}
+	if got := f.item(table, "team", team.ID()).str("columns_json"); got != `{"tags":["b","a"]}` {
+		t.Errorf("DeleteColumn left columns %s", got)
+	}
+}
@@ -0,0 +1,59 @@
+package dynamodb_test
+
+import (
+	"testing"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/dynamodb"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/dynamodb/dynamotest"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/storagetest"
+)
+
+// The conformance tests run against DynamoDB Local, and are skipped where
+// Docker can't start it, unless DYNAMODB_LOCAL_ENDPOINT names one running.
+
+func TestConformance(t *testing.T) {
+	dynamotest.RunConformance(t)
+}
+
+func TestCompactConformance(t *testing.T) {
+	dynamotest.RunConformance(t, dynamodb.WithCompactColumns())
+}
+
+func TestShardedConformance(t *testing.T) {
+	dynamotest.RunConformance(t, dynamodb.WithShardedTypes(map[string]int{"org": 2, "team": 4}))
+}
+
+func TestTenantConformance(t *testing.T) {
+	dynamotest.RunConformance(t, dynamodb.WithTenant("acme"))
+}
+
+// The conformance tests also run against a fake DynamoDB, so that they run
+// everywhere, if not against DynamoDB itself.
+
+func TestFakeConformance(t *testing.T) {
+	runFakeConformance(t)
+}
+
+func TestFakeCompactConformance(t *testing.T) {
+	runFakeConformance(t, dynamodb.WithCompactColumns())
+}
+
+func TestFakeShardedConformance(t *testing.T) {
+	runFakeConformance(t, dynamodb.WithShardedTypes(map[string]int{"org": 2, "team": 4}))
+}
+
+func TestFakeTenantConformance(t *testing.T) {
+	runFakeConformance(t, dynamodb.WithTenant("acme"))
+}
+
+// runFakeConformance runs the conformance tests against a fake serving a few
+// items a page, so that reads follow LastEvaluatedKey.
+func runFakeConformance(t *testing.T, opts ...dynamodb.Option) {
+	f := newFake(t)
+	f.pageSize = 3
+	storagetest.Run(t, func(t *testing.T) storage.RowStorer {
+		client, _ := f.newClient(t, opts...)
+		return client
+	})
+}
@@ -0,0 +1,27 @@
+package dynamodb
+
+import (
+	"context"
+
+	"github.com/aws/smithy-go/middleware"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+// correlationMiddlewareID names the correlation middleware in the client's
+// stack.
+const correlationMiddlewareID = "TreeCorrelationID"
+
+// addCorrelationID appends the correlation ID of a call's context, if it has
+// one, to the user agent of the request. CloudTrail records user agents, so
+// the ID joins provider logs with the API calls they describe.
+func addCorrelationID(stack *middleware.Stack) error {
+	return stack.Build.Add(middleware.BuildMiddlewareFunc(correlationMiddlewareID, func(ctx context.Context, in middleware.BuildInput, next middleware.BuildHandler) (middleware.BuildOutput, middleware.Metadata, error) {
+		id := storage.CorrelationID(ctx)
+		if request, ok := in.Request.(*smithyhttp.Request); ok && id != "" {
+			userAgent := request.Header.Get("User-Agent")
+			request.Header.Set("User-Agent", userAgent+" tree-"+storage.CorrelationIDField+"/"+id)
+		}
+		return next.HandleBuild(ctx, in)
+	}), middleware.After)
+}
@@ -0,0 +1,46 @@
+package dynamodb
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+var _ storage.ApproximateCounter = &Client{}
+
+// ApproximateCount returns the counts DescribeTable reports, in one call
+// however large the table, rather than reading every item as a Scan with
+// Select COUNT would. DynamoDB updates them about every six hours, so they're
+// eventually consistent, and can miss hours of writes.
+//
+// Rows are counted by the ByTypeAndLabel index, which has every row but no
+// label guards; the table's own count, which has both, stands in if the index
+// is missing. The counts are of the whole table, so a client WithTenant
+// counts every tenant's rows.
+func (client *Client) ApproximateCount(ctx context.Context) (storage.ApproximateCount, error) {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemDynamoDB, "ApproximateCount")
+	output, err := client.ddb.DescribeTable(ctx, &dynamodb.DescribeTableInput{
+		TableName: aws.String(client.tableName),
+	})
+	if isTableMissing(err) {
+		// the table is created by the first write
+		return storage.ApproximateCount{}, nil
+	}
+	if err != nil {
+		return storage.ApproximateCount{}, err
+	}
+	table := output.Table
+	count := storage.ApproximateCount{
+		Rows:  aws.ToInt64(table.ItemCount),
+		Bytes: aws.ToInt64(table.TableSizeBytes),
+	}
+	for _, lsi := range table.LocalSecondaryIndexes {
+		if aws.ToString(lsi.IndexName) == storageLSIByTypeAndLabel {
+			count.Rows = aws.ToInt64(lsi.ItemCount)
+		}
+	}
+	return count, nil
+}
@@ -0,0 +1,179 @@
+// Package dynamotest runs DynamoDB Local in a container, for integration tests
+// of the DynamoDB backend against a real table rather than a fake:
+//
+//	func TestBackend(t *testing.T) {
+//		local := dynamotest.Start(t)
+//		storer := local.NewClient(t)
+//		...
+//	}
+//
+// RunConformance runs the conformance tests every backend must pass against
+// it, with the client options under test:
+//
+//	func TestCompactConformance(t *testing.T) {
+//		dynamotest.RunConformance(t, dynamodb.WithCompactColumns())
+//	}
+//
+// Start needs Docker, which it runs the container with through
+// testcontainers-go. Where there's none, as on some CI runners, the tests
+// that call it are skipped, unless DYNAMODB_LOCAL_ENDPOINT names a DynamoDB
+// Local that's already running.
+package dynamotest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	awsdynamodb "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/dynamodb"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/storagetest"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+const (
+	// Image is the DynamoDB Local image Start runs.
+	Image = "amazon/dynamodb-local:2.5.2"
+	// EndpointEnv names the environment variable of the endpoint of a
+	// DynamoDB Local already running, which Start uses rather than
+	// starting one.
+	EndpointEnv = "DYNAMODB_LOCAL_ENDPOINT"
+	// Region is the region of the clients NewClient returns. DynamoDB Local
+	// keeps one set of tables for every region, so it only matters to the
+	// clients, which share an AWS configuration with no other.
+	Region = "local"
+	// port is the port DynamoDB Local listens on in its container.
+	port = "8000/tcp"
+	// startTimeout is how long DynamoDB Local may take to answer after its
+	// container starts.
+	startTimeout = 30 * time.Second
+)
+
+// tables numbers the tables NewClient creates, so that each test has its own.
+var tables atomic.Int64
+
+// Local is a running DynamoDB Local.
+type Local struct {
+	// Endpoint is DynamoDB Local's URL.
+	Endpoint string
+	ddb      *awsdynamodb.Client
+}
+
+// Start starts DynamoDB Local in a container, which is removed when the test
+// and its subtests finish, and waits for it to answer. The test is skipped if
+// Docker can't be reached.
+func Start(tb testing.TB) *Local {
+	tb.Helper()
+	if endpoint := os.Getenv(EndpointEnv); endpoint != "" {
+		return connect(tb, endpoint)
+	}
+	ctx := context.Background()
+	if err := dockerHealthy(ctx); err != nil {
+		tb.Skipf("starting DynamoDB Local needs Docker, or %s: %s", EndpointEnv, err)
+	}
+	container, err := testcontainers.Run(ctx, Image,
+		testcontainers.WithCmd("-jar", "DynamoDBLocal.jar", "-inMemory", "-sharedDb"),
+		testcontainers.WithExposedPorts(port),
+		testcontainers.WithWaitStrategy(wait.ForListeningPort(port).WithStartupTimeout(startTimeout)),
+	)
+	testcontainers.CleanupContainer(tb, container)
+	if err != nil {
+		tb.Fatalf("starting DynamoDB Local: %s", err)
+	}
+	endpoint, err := container.PortEndpoint(ctx, port, "http")
+	if err != nil {
+		tb.Fatalf("finding DynamoDB Local's port: %s", err)
+	}
+	return connect(tb, endpoint)
+}
+
+// dockerHealthy returns an error if testcontainers-go can't reach Docker.
+// It finds Docker's host by panicking where there's none, so the panic is
+// returned as an error, too.
+func dockerHealthy(ctx context.Context) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	provider, err := testcontainers.ProviderDocker.GetProvider()
+	if err != nil {
+		return err
+	}
+	defer provider.Close()
+	return provider.Health(ctx)
+}
+
+// connect waits for the DynamoDB Local at endpoint to answer.
+func connect(tb testing.TB, endpoint string) *Local {
+	tb.Helper()
+	local := &Local{
+		Endpoint: endpoint,
+		ddb: awsdynamodb.New(awsdynamodb.Options{
+			Region:       Region,
+			BaseEndpoint: aws.String(endpoint),
+			Credentials:  credentials.NewStaticCredentialsProvider("local", "local", ""),
+		}),
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), startTimeout)
+	defer cancel()
+	for {
+		_, err := local.ddb.ListTables(ctx, &awsdynamodb.ListTablesInput{Limit: aws.Int32(1)})
+		if err == nil {
+			return local
+		}
+		select {
+		case <-ctx.Done():
+			tb.Fatalf("DynamoDB Local at %s didn't answer within %s: %s", endpoint, startTimeout, err)
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+}
+
+// NewClient returns a DynamoDB backend of DynamoDB Local, with a table of its
+// own, which its first write creates and which is deleted when the test
+// finishes. DynamoDB Local accepts any credentials, but the AWS SDK needs
+// some, so if the environment has none, NewClient sets dummy ones for the
+// test, and so can't be called from parallel tests; call it before
+// t.Parallel.
+func (local *Local) NewClient(tb testing.TB, opts ...dynamodb.Option) storage.RowStorer {
+	tb.Helper()
+	if os.Getenv("AWS_ACCESS_KEY_ID") == "" {
+		tb.Setenv("AWS_ACCESS_KEY_ID", "local")
+		tb.Setenv("AWS_SECRET_ACCESS_KEY", "local")
+	}
+	tableName := fmt.Sprintf("dynamotest-%d-%d", os.Getpid(), tables.Add(1))
+	opts = append([]dynamodb.Option{dynamodb.WithEndpoint(local.Endpoint)}, opts...)
+	storer, err := dynamodb.NewClient(context.Background(), "", Region, tableName, "", opts...)
+	if err != nil {
+		tb.Fatalf("creating a DynamoDB Local client: %s", err)
+	}
+	tb.Cleanup(func() {
+		_, err := local.ddb.DeleteTable(context.Background(), &awsdynamodb.DeleteTableInput{TableName: aws.String(tableName)})
+		// tests that never wrote never created it
+		var notFound *types.ResourceNotFoundException
+		if err != nil && !errors.As(err, &notFound) {
+			tb.Logf("deleting DynamoDB Local table %s: %s", tableName, err)
+		}
+	})
+	return storer
+}
+
+// RunConformance runs the storagetest conformance tests against DynamoDB
+// Local, each with a client of the options and a table of its own.
+func RunConformance(t *testing.T, opts ...dynamodb.Option) {
+	t.Helper()
+	local := Start(t)
+	storagetest.Run(t, func(t *testing.T) storage.RowStorer {
+		return local.NewClient(t, opts...)
+	})
+}
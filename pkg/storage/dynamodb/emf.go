@@ -0,0 +1,192 @@
+package dynamodb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/smithy-go"
+	"github.com/aws/smithy-go/middleware"
+)
+
+// emfMiddlewareID names the EMF middleware in the client's stack.
+const emfMiddlewareID = "TreeEMFMetrics"
+
+// WithEMF writes a CloudWatch embedded metric format record to w for each
+// DynamoDB API call, in the given namespace. Records carry the call's latency,
+// the capacity it consumed, and the class of its error, if any, with the
+// table name and operation as dimensions. w is typically a file the
+// CloudWatch agent tails, or standard output in Lambda.
+func WithEMF(w io.Writer, namespace string) Option {
+	return func(o *options) {
+		o.emf = &emfWriter{w: w, namespace: namespace}
+	}
+}
+
+// emfErrorNone is the error class of a call that succeeded.
+const emfErrorNone = "None"
+
+type emfWriter struct {
+	mu        sync.Mutex
+	w         io.Writer
+	namespace string
+}
+
+type emfMetric struct {
+	Name string `json:"Name"`
+	Unit string `json:"Unit"`
+}
+
+type emfDirective struct {
+	Namespace  string      `json:"Namespace"`
+	Dimensions [][]string  `json:"Dimensions"`
+	Metrics    []emfMetric `json:"Metrics"`
+}
+
+type emfMetadata struct {
+	Timestamp         int64          `json:"Timestamp"`
+	CloudWatchMetrics []emfDirective `json:"CloudWatchMetrics"`
+}
+
+type emfRecord struct {
+	AWS              emfMetadata `json:"_aws"`
+	TableName        string      `json:"TableName"`
+	Operation        string      `json:"Operation"`
+	ErrorClass       string      `json:"ErrorClass"`
+	Latency          float64     `json:"Latency"`
+	ConsumedCapacity float64     `json:"ConsumedCapacity"`
+	Errors           int         `json:"Errors"`
+}
+
+func (emf *emfWriter) write(record emfRecord) error {
+	record.AWS.CloudWatchMetrics = []emfDirective{{
+		Namespace: emf.namespace,
+		Dimensions: [][]string{
+			{"TableName", "Operation"},
+			{"TableName", "Operation", "ErrorClass"},
+		},
+		Metrics: []emfMetric{
+			{Name: "Latency", Unit: "Milliseconds"},
+			{Name: "ConsumedCapacity", Unit: "Count"},
+			{Name: "Errors", Unit: "Count"},
+		},
+	}}
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	emf.mu.Lock()
+	defer emf.mu.Unlock()
+	_, err = emf.w.Write(append(line, '\n'))
+	return err
+}
+
+// middleware asks DynamoDB to return the consumed capacity of every call, and
+// writes a record of each once it returns.
+func (emf *emfWriter) middleware(tableName string) func(*middleware.Stack) error {
+	return func(stack *middleware.Stack) error {
+		return stack.Initialize.Add(middleware.InitializeMiddlewareFunc(emfMiddlewareID, func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+			returnConsumedCapacity(in.Parameters)
+			start := time.Now()
+			out, metadata, err := next.HandleInitialize(ctx, in)
+			record := emfRecord{
+				TableName:        tableName,
+				Operation:        awsmiddleware.GetOperationName(ctx),
+				ErrorClass:       emfErrorClass(err),
+				Latency:          float64(time.Since(start)) / float64(time.Millisecond),
+				ConsumedCapacity: consumedCapacity(out.Result),
+			}
+			record.AWS.Timestamp = start.UnixMilli()
+			if err != nil {
+				record.Errors = 1
+			}
+			// metrics are best-effort, and mustn't fail the call
+			_ = emf.write(record)
+			return out, metadata, err
+		}), middleware.After)
+	}
+}
+
+// emfErrorClass is the DynamoDB error code of err, so that throttling,
+// conditional check failures, and the like are told apart.
+func emfErrorClass(err error) string {
+	if err == nil {
+		return emfErrorNone
+	}
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.ErrorCode()
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return "Canceled"
+	}
+	return "ClientError"
+}
+
+func returnConsumedCapacity(params interface{}) {
+	switch input := params.(type) {
+	case *dynamodb.GetItemInput:
+		input.ReturnConsumedCapacity = types.ReturnConsumedCapacityTotal
+	case *dynamodb.PutItemInput:
+		input.ReturnConsumedCapacity = types.ReturnConsumedCapacityTotal
+	case *dynamodb.UpdateItemInput:
+		input.ReturnConsumedCapacity = types.ReturnConsumedCapacityTotal
+	case *dynamodb.DeleteItemInput:
+		input.ReturnConsumedCapacity = types.ReturnConsumedCapacityTotal
+	case *dynamodb.QueryInput:
+		input.ReturnConsumedCapacity = types.ReturnConsumedCapacityTotal
+	case *dynamodb.ScanInput:
+		input.ReturnConsumedCapacity = types.ReturnConsumedCapacityTotal
+	case *dynamodb.BatchGetItemInput:
+		input.ReturnConsumedCapacity = types.ReturnConsumedCapacityTotal
+	case *dynamodb.BatchWriteItemInput:
+		input.ReturnConsumedCapacity = types.ReturnConsumedCapacityTotal
+	case *dynamodb.TransactGetItemsInput:
+		input.ReturnConsumedCapacity = types.ReturnConsumedCapacityTotal
+	case *dynamodb.TransactWriteItemsInput:
+		input.ReturnConsumedCapacity = types.ReturnConsumedCapacityTotal
+	}
+}
+
+func consumedCapacity(result interface{}) float64 {
+	var single *types.ConsumedCapacity
+	var multiple []types.ConsumedCapacity
+	switch output := result.(type) {
+	case *dynamodb.GetItemOutput:
+		single = output.ConsumedCapacity
+	case *dynamodb.PutItemOutput:
+		single = output.ConsumedCapacity
+	case *dynamodb.UpdateItemOutput:
+		single = output.ConsumedCapacity
+	case *dynamodb.DeleteItemOutput:
+		single = output.ConsumedCapacity
+	case *dynamodb.QueryOutput:
+		single = output.ConsumedCapacity
+	case *dynamodb.ScanOutput:
+		single = output.ConsumedCapacity
+	case *dynamodb.BatchGetItemOutput:
+		multiple = output.ConsumedCapacity
+	case *dynamodb.BatchWriteItemOutput:
+		multiple = output.ConsumedCapacity
+	case *dynamodb.TransactGetItemsOutput:
+		multiple = output.ConsumedCapacity
+	case *dynamodb.TransactWriteItemsOutput:
+		multiple = output.ConsumedCapacity
+	}
+	if single != nil {
+		multiple = append(multiple, *single)
+	}
+	total := 0.0
+	for _, capacity := range multiple {
+		if capacity.CapacityUnits != nil {
+			total += *capacity.CapacityUnits
+		}
+	}
+	return total
+}
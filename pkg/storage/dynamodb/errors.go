@@ -0,0 +1,100 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/smithy-go"
+	"github.com/aws/smithy-go/middleware"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+// errorKindMiddlewareID names the error kind middleware in the client's
+// stack.
+const errorKindMiddlewareID = "TreeErrorKind"
+
+// errorKinds maps DynamoDB error codes to the kinds of storage error. A
+// failed condition is a conflict: every condition this backend sets checks
+// that a row does or doesn't exist, and some other writer got there first.
+// DynamoDB's own failures, which the AWS SDK has already retried, are
+// storage.ErrServerError and storage.ErrUnavailable, so that WithRetry may
+// retry them again.
+var errorKinds = map[string]error{
+	"ConditionalCheckFailedException":          storage.ErrConflict,
+	"TransactionConflictException":             storage.ErrConflict,
+	"ProvisionedThroughputExceededException":   storage.ErrThrottled,
+	"ThrottlingException":                      storage.ErrThrottled,
+	"RequestLimitExceeded":                     storage.ErrThrottled,
+	"InternalServerError":                      storage.ErrServerError,
+	"ServiceUnavailable":                       storage.ErrUnavailable,
+	"AccessDeniedException":                    storage.ErrPermissionDenied,
+	"UnrecognizedClientException":              storage.ErrPermissionDenied,
+	"KMSAccessDeniedException":                 storage.ErrPermissionDenied,
+	"ValidationException":                      storage.ErrInvalid,
+	"ItemCollectionSizeLimitExceededException": storage.ErrInvalid,
+}
+
+// cancellationKinds maps the reasons DynamoDB gives for cancelling a
+// transaction to the kinds of storage error.
+var cancellationKinds = map[string]error{
+	"ConditionalCheckFailed":        storage.ErrConflict,
+	"TransactionConflict":           storage.ErrConflict,
+	"ProvisionedThroughputExceeded": storage.ErrThrottled,
+	"ThrottlingError":               storage.ErrThrottled,
+	"InternalServerError":           storage.ErrServerError,
+	"ValidationError":               storage.ErrInvalid,
+}
+
+// cancellationKind returns the kind of a cancelled transaction's error: that
+// of the first of its writes' reasons that has one.
+func cancellationKind(err error) (error, bool) {
+	var canceled *types.TransactionCanceledException
+	if !errors.As(err, &canceled) {
+		return nil, false
+	}
+	for _, reason := range canceled.CancellationReasons {
+		if kind, ok := cancellationKinds[aws.ToString(reason.Code)]; ok {
+			return kind, true
+		}
+	}
+	return nil, false
+}
+
+// isWrite reports whether the parameters are of a DynamoDB API call that
+// writes. Access denied to one is a read-only error.
+func isWrite(params interface{}) bool {
+	switch params.(type) {
+	case *dynamodb.PutItemInput, *dynamodb.UpdateItemInput, *dynamodb.DeleteItemInput,
+		*dynamodb.BatchWriteItemInput, *dynamodb.TransactWriteItemsInput, *dynamodb.CreateTableInput:
+		return true
+	}
+	return false
+}
+
+// addErrorKinds wraps the errors of DynamoDB API calls with their kinds, so
+// that callers can tell them apart with errors.Is. Writes denied access are
+// storage.ErrReadOnly, which is also of kind storage.ErrPermissionDenied. The
+// DynamoDB error stays in the chain, for errors.As.
+func addErrorKinds(stack *middleware.Stack) error {
+	return stack.Initialize.Add(middleware.InitializeMiddlewareFunc(errorKindMiddlewareID, func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+		out, metadata, err := next.HandleInitialize(ctx, in)
+		var apiErr smithy.APIError
+		if errors.As(err, &apiErr) {
+			if kind, ok := errorKinds[apiErr.ErrorCode()]; ok {
+				if apiErr.ErrorCode() == "AccessDeniedException" && isWrite(in.Parameters) {
+					// a denied write most often means credentials meant
+					// for plans, which may only read
+					kind = storage.ErrReadOnly
+				}
+				err = fmt.Errorf("%w: %w", kind, err)
+			} else if kind, ok := cancellationKind(err); ok {
+				err = fmt.Errorf("%w: %w", kind, err)
+			}
+		}
+		return out, metadata, err
+	}), middleware.Before)
+}
@@ -0,0 +1,50 @@
+package dynamodb_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/dynamodb"
+)
+
+// TestInternalServerError checks that DynamoDB's InternalServerError comes
+// back as a server error, which is transient, after the SDK's retries.
+func TestInternalServerError(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+	t.Setenv("AWS_CONFIG_FILE", "/dev/null")
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", "/dev/null")
+
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"__type":"com.amazonaws.dynamodb.v20120810#InternalServerError","message":"Internal server error"}`))
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	client, err := dynamodb.NewClient(ctx, "", "us-east-1", "rows", "",
+		dynamodb.WithEndpoint(server.URL),
+		dynamodb.WithBackoff(dynamodb.Backoff{Base: time.Millisecond, Max: time.Millisecond}))
+	if err != nil {
+		t.Fatalf("NewClient: %s", err)
+	}
+	_, err = client.GetRowByID(ctx, "org", "org-1")
+	if !errors.Is(err, storage.ErrServerError) {
+		t.Fatalf("GetRowByID failed with %v, not %q", err, storage.ErrServerError)
+	}
+	if !storage.IsTransient(err) {
+		t.Errorf("GetRowByID failed with %v, which isn't transient", err)
+	}
+	if calls.Load() < 2 {
+		t.Errorf("the client made %d calls, not retrying the server error", calls.Load())
+	}
+}
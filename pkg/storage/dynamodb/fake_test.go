@@ -0,0 +1,840 @@
+package dynamodb_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/dynamodb"
+)
+
+// fake is an in-memory DynamoDB, serving the calls the backend makes over
+// HTTP, so that the backend's tests need neither AWS nor Docker. It knows
+// only as much of DynamoDB's expressions as the backend writes, and fails
+// the rest with a ValidationException, so that a test notices.
+type fake struct {
+	server *httptest.Server
+
+	mu     sync.Mutex
+	tables map[string]*fakeTable
+	// before, if set, is called before each call is served, with the
+	// operation's name, as in "GetItem". An error it returns is the
+	// call's.
+	before func(op string) *fakeError
+	// pageSize, if set, is the most items a Query or Scan returns a page.
+	pageSize int
+}
+
+type fakeTable struct {
+	// indexes are the key attributes of the table, by "", and of its
+	// indexes, by name: the hash key, then the range key, if any
+	indexes map[string][]string
+	items   map[string]fakeItem
+}
+
+// A fakeItem is an item in DynamoDB's JSON, by attribute name: each value
+// is a map of one type, such as "S", to the value.
+type fakeItem map[string]interface{}
+
+// fakeError is a DynamoDB error, with its HTTP status.
+type fakeError struct {
+	status  int
+	code    string
+	message string
+	// reasons are a canceled transaction's
+	reasons []interface{}
+}
+
+func conditionFailed() *fakeError {
+	return &fakeError{http.StatusBadRequest, "ConditionalCheckFailedException", "The conditional request failed", nil}
+}
+
+func invalid(format string, args ...interface{}) *fakeError {
+	return &fakeError{http.StatusBadRequest, "ValidationException", fmt.Sprintf(format, args...), nil}
+}
+
+// newFake starts a fake DynamoDB, which is stopped when the test ends.
+func newFake(t *testing.T) *fake {
+	t.Helper()
+	f := &fake{tables: map[string]*fakeTable{}}
+	f.server = httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+// fakeTables numbers the tables of the clients newClient returns.
+var fakeTables atomic.Int64
+
+// newClient returns a client of a table of its own in the fake, and the
+// table's name. The AWS SDK needs credentials, which the fake doesn't check,
+// so newClient sets dummy ones for the test, and so can't be called from
+// parallel tests.
+func (f *fake) newClient(t *testing.T, opts ...dynamodb.Option) (*dynamodb.Client, string) {
+	t.Helper()
+	tableName := fmt.Sprintf("fake-%d", fakeTables.Add(1))
+	return f.clientOf(t, tableName, opts...), tableName
+}
+
+// clientOf returns a client of the table in the fake.
+func (f *fake) clientOf(t *testing.T, tableName string, opts ...dynamodb.Option) *dynamodb.Client {
+	t.Helper()
+	t.Setenv("AWS_ACCESS_KEY_ID", "fake")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "fake")
+	opts = append([]dynamodb.Option{dynamodb.WithEndpoint(f.server.URL)}, opts...)
+	storer, err := dynamodb.NewClient(context.Background(), "", "fake", tableName, "", opts...)
+	if err != nil {
+		t.Fatalf("NewClient: %s", err)
+	}
+	return storer.(*dynamodb.Client)
+}
+
+// item returns the stored item with the type and ID keys, or nil.
+func (f *fake) item(tableName, typeKey, id string) fakeItem {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	table, ok := f.tables[tableName]
+	if !ok {
+		return nil
+	}
+	return table.items[typeKey+"\x00"+id]
+}
+
+// items returns the table's stored items, in key order.
+func (f *fake) items(tableName string) []fakeItem {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	table, ok := f.tables[tableName]
+	if !ok {
+		return nil
+	}
+	return table.sorted(table.all(), "")
+}
+
+// put stores an item as it is, behind the client's back.
+func (f *fake) put(tableName string, item fakeItem) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.tables[tableName].items[itemKey(item)] = item
+}
+
+func s(value string) map[string]interface{} {
+	return map[string]interface{}{"S": value}
+}
+
+// str returns the item's string attribute, or "".
+func (item fakeItem) str(name string) string {
+	return stringOf(item[name])
+}
+
+func itemKey(item fakeItem) string {
+	return item.str("type") + "\x00" + item.str("id")
+}
+
+func (f *fake) serve(w http.ResponseWriter, r *http.Request) {
+	op := strings.TrimPrefix(r.Header.Get("X-Amz-Target"), "DynamoDB_20120810.")
+	var input map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeFakeError(w, invalid("decoding the request: %s", err))
+		return
+	}
+	if f.before != nil {
+		if err := f.before(op); err != nil {
+			writeFakeError(w, err)
+			return
+		}
+	}
+	output, err := f.locked(op, request(input))
+	if err != nil {
+		writeFakeError(w, err)
+		return
+	}
+	writeFakeJSON(w, http.StatusOK, output)
+}
+
+func (f *fake) locked(op string, req request) (interface{}, *fakeError) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.call(op, req)
+}
+
+func writeFakeError(w http.ResponseWriter, err *fakeError) {
+	body := map[string]interface{}{
+		"__type":  "com.amazonaws.dynamodb.v20120810#" + err.code,
+		"message": err.message,
+	}
+	if err.reasons != nil {
+		body["CancellationReasons"] = err.reasons
+	}
+	writeFakeJSON(w, err.status, body)
+}
+
+// writeFakeJSON writes a response, with the checksum the AWS SDK checks.
+func writeFakeJSON(w http.ResponseWriter, status int, body interface{}) {
+	b, _ := json.Marshal(body)
+	w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+	w.Header().Set("X-Amz-Crc32", strconv.FormatUint(uint64(crc32.ChecksumIEEE(b)), 10))
+	w.WriteHeader(status)
+	w.Write(b)
+}
+
+// request is the JSON of a call, or of one of a transaction's writes.
+type request map[string]interface{}
+
+func (req request) str(name string) string {
+	value, _ := req[name].(string)
+	return value
+}
+
+func (req request) item(name string) fakeItem {
+	value, _ := req[name].(map[string]interface{})
+	return fakeItem(value)
+}
+
+func (req request) list(name string) []interface{} {
+	value, _ := req[name].([]interface{})
+	return value
+}
+
+// expression returns the names and values of the request's expressions.
+func (req request) expression() *expression {
+	e := &expression{names: map[string]string{}, values: req.item("ExpressionAttributeValues")}
+	if names, ok := req["ExpressionAttributeNames"].(map[string]interface{}); ok {
+		for placeholder, name := range names {
+			e.names[placeholder] = name.(string)
+		}
+	}
+	return e
+}
+
+func (f *fake) call(op string, req request) (interface{}, *fakeError) {
+	if op == "CreateTable" {
+		return f.createTable(req)
+	}
+	table, ok := f.tables[req.str("TableName")]
+	if !ok && op != "TransactWriteItems" && op != "BatchGetItem" && op != "BatchWriteItem" {
+		return nil, &fakeError{http.StatusBadRequest, "ResourceNotFoundException", "Requested resource not found", nil}
+	}
+	switch op {
+	case "DescribeTable":
+		return table.describe(req.str("TableName")), nil
+	case "GetItem":
+		item := table.items[itemKey(req.item("Key"))]
+		if item == nil {
+			return map[string]interface{}{}, nil
+		}
+		return map[string]interface{}{"Item": req.expression().project(item, req.str("ProjectionExpression"))}, nil
+	case "PutItem":
+		return table.write(req, func(fakeItem) (fakeItem, *fakeError) { return req.item("Item"), nil })
+	case "UpdateItem":
+		return table.write(req, func(old fakeItem) (fakeItem, *fakeError) {
+			return req.expression().update(old, req.item("Key"), req.str("UpdateExpression"))
+		})
+	case "DeleteItem":
+		return table.write(req, func(fakeItem) (fakeItem, *fakeError) { return nil, nil })
+	case "Query", "Scan":
+		return f.query(table, op, req)
+	case "TransactWriteItems":
+		return f.transact(req)
+	case "BatchGetItem":
+		return f.batchGet(req)
+	case "BatchWriteItem":
+		return f.batchWrite(req)
+	}
+	return nil, &fakeError{http.StatusBadRequest, "UnknownOperationException", op + " isn't faked", nil}
+}
+
+func (f *fake) createTable(req request) (interface{}, *fakeError) {
+	name := req.str("TableName")
+	if _, ok := f.tables[name]; ok {
+		return nil, &fakeError{http.StatusBadRequest, "ResourceInUseException", "Table already exists: " + name, nil}
+	}
+	table := &fakeTable{indexes: map[string][]string{"": keySchema(req.list("KeySchema"))}, items: map[string]fakeItem{}}
+	for _, indexes := range [][]interface{}{req.list("GlobalSecondaryIndexes"), req.list("LocalSecondaryIndexes")} {
+		for _, index := range indexes {
+			index := request(index.(map[string]interface{}))
+			table.indexes[index.str("IndexName")] = keySchema(index.list("KeySchema"))
+		}
+	}
+	f.tables[name] = table
+	return map[string]interface{}{"TableDescription": table.describe(name)["Table"]}, nil
+}
+
+// keySchema returns the key attributes of a key schema, the hash key first.
+func keySchema(schema []interface{}) []string {
+	keys := make([]string, len(schema))
+	for _, element := range schema {
+		element := request(element.(map[string]interface{}))
+		if element.str("KeyType") == "HASH" {
+			keys[0] = element.str("AttributeName")
+		} else {
+			keys[len(keys)-1] = element.str("AttributeName")
+		}
+	}
+	return keys
+}
+
+func (table *fakeTable) describe(name string) map[string]interface{} {
+	size := 0
+	for _, item := range table.items {
+		b, _ := json.Marshal(item)
+		size += len(b)
+	}
+	lsis := []interface{}{}
+	for index, keys := range table.indexes {
+		if index != "" && keys[0] == "type" {
+			lsis = append(lsis, map[string]interface{}{"IndexName": index, "ItemCount": len(table.indexed(index))})
+		}
+	}
+	return map[string]interface{}{"Table": map[string]interface{}{
+		"TableName":             name,
+		"TableStatus":           "ACTIVE",
+		"ItemCount":             len(table.items),
+		"TableSizeBytes":        size,
+		"LocalSecondaryIndexes": lsis,
+	}}
+}
+
+// write makes a single write of the item with the request's key, on the
+// request's condition, replacing it with what change returns.
+func (table *fakeTable) write(req request, change func(old fakeItem) (fakeItem, *fakeError)) (interface{}, *fakeError) {
+	key := req.item("Key")
+	if key == nil {
+		key = req.item("Item")
+	}
+	old := table.items[itemKey(key)]
+	ok, err := req.expression().condition(old, req.str("ConditionExpression"))
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, conditionFailed()
+	}
+	updated, err := change(old)
+	if err != nil {
+		return nil, err
+	}
+	table.store(itemKey(key), updated)
+	output := map[string]interface{}{}
+	switch req.str("ReturnValues") {
+	case "ALL_OLD":
+		if old != nil {
+			output["Attributes"] = old
+		}
+	case "ALL_NEW":
+		output["Attributes"] = updated
+	}
+	return output, nil
+}
+
+func (table *fakeTable) store(key string, item fakeItem) {
+	if item == nil {
+		delete(table.items, key)
+		return
+	}
+	table.items[key] = item
+}
+
+func (table *fakeTable) all() []fakeItem {
+	items := make([]fakeItem, 0, len(table.items))
+	for _, item := range table.items {
+		items = append(items, item)
+	}
+	return items
+}
+
+// indexed returns the items in the index: those with its key attributes.
+func (table *fakeTable) indexed(index string) []fakeItem {
+	items := []fakeItem{}
+	for _, item := range table.items {
+		has := true
+		for _, key := range table.indexes[index] {
+			if _, ok := item[key]; !ok {
+				has = false
+			}
+		}
+		if has {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// sorted sorts items by the index's keys, then the table's.
+func (table *fakeTable) sorted(items []fakeItem, index string) []fakeItem {
+	keys := append(append([]string{}, table.indexes[index]...), table.indexes[""]...)
+	sort.Slice(items, func(i, j int) bool {
+		for _, key := range keys {
+			if a, b := items[i].str(key), items[j].str(key); a != b {
+				return a < b
+			}
+		}
+		return false
+	})
+	return items
+}
+
+func (f *fake) query(table *fakeTable, op string, req request) (interface{}, *fakeError) {
+	e := req.expression()
+	index := req.str("IndexName")
+	if _, ok := table.indexes[index]; !ok {
+		return nil, invalid("The table does not have the specified index: %s", index)
+	}
+	items := table.sorted(table.indexed(index), index)
+	if op == "Query" {
+		matched := []fakeItem{}
+		for _, item := range items {
+			ok, err := e.condition(item, req.str("KeyConditionExpression"))
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				matched = append(matched, item)
+			}
+		}
+		items = matched
+	}
+	if start := req.item("ExclusiveStartKey"); start != nil {
+		for i, item := range items {
+			if itemKey(item) == itemKey(start) {
+				items = items[i+1:]
+				break
+			}
+		}
+	}
+	limit := len(items)
+	if n, ok := req["Limit"].(float64); ok && int(n) < limit {
+		limit = int(n)
+	}
+	if f.pageSize > 0 && f.pageSize < limit {
+		limit = f.pageSize
+	}
+	output := map[string]interface{}{}
+	if limit < len(items) {
+		last := items[limit-1]
+		lastKey := fakeItem{}
+		for _, key := range append(append([]string{}, table.indexes[index]...), table.indexes[""]...) {
+			lastKey[key] = last[key]
+		}
+		output["LastEvaluatedKey"] = lastKey
+		items = items[:limit]
+	}
+	page := []fakeItem{}
+	for _, item := range items {
+		ok, err := e.condition(item, req.str("FilterExpression"))
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			page = append(page, e.project(item, req.str("ProjectionExpression")))
+		}
+	}
+	output["Items"] = page
+	output["Count"] = len(page)
+	output["ScannedCount"] = len(items)
+	return output, nil
+}
+
+// transact makes a transaction's writes, if all their conditions hold, or
+// cancels it with the reason for each.
+func (f *fake) transact(req request) (interface{}, *fakeError) {
+	type write struct {
+		table  *fakeTable
+		key    string
+		kind   string
+		req    request
+		result fakeItem
+	}
+	writes := []*write{}
+	seen := map[string]bool{}
+	for _, element := range req.list("TransactItems") {
+		for kind, body := range element.(map[string]interface{}) {
+			w := &write{kind: kind, req: request(body.(map[string]interface{}))}
+			var ok bool
+			w.table, ok = f.tables[w.req.str("TableName")]
+			if !ok {
+				return nil, &fakeError{http.StatusBadRequest, "ResourceNotFoundException", "Requested resource not found", nil}
+			}
+			key := w.req.item("Key")
+			if kind == "Put" {
+				key = w.req.item("Item")
+			}
+			w.key = w.req.str("TableName") + "\x00" + itemKey(key)
+			if seen[w.key] {
+				return nil, invalid("Transaction request cannot include multiple operations on one item")
+			}
+			seen[w.key] = true
+			w.key = itemKey(key)
+			writes = append(writes, w)
+		}
+	}
+	if len(writes) > 100 {
+		return nil, invalid("Member must have length less than or equal to 100")
+	}
+
+	reasons := make([]interface{}, len(writes))
+	canceled := false
+	for i, w := range writes {
+		old := w.table.items[w.key]
+		e := w.req.expression()
+		ok, err := e.condition(old, w.req.str("ConditionExpression"))
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			canceled = true
+			reason := map[string]interface{}{"Code": "ConditionalCheckFailed", "Message": "The conditional request failed"}
+			if w.req.str("ReturnValuesOnConditionCheckFailure") == "ALL_OLD" && old != nil {
+				reason["Item"] = old
+			}
+			reasons[i] = reason
+			continue
+		}
+		reasons[i] = map[string]interface{}{"Code": "None"}
+		switch w.kind {
+		case "Put":
+			w.result = w.req.item("Item")
+		case "Update":
+			w.result, err = e.update(old, w.req.item("Key"), w.req.str("UpdateExpression"))
+			if err != nil {
+				return nil, err
+			}
+		case "Delete":
+		case "ConditionCheck":
+			w.result = old
+		default:
+			return nil, invalid("unknown transaction write %s", w.kind)
+		}
+	}
+	if canceled {
+		codes := make([]string, len(reasons))
+		for i, reason := range reasons {
+			codes[i] = reason.(map[string]interface{})["Code"].(string)
+		}
+		return nil, &fakeError{http.StatusBadRequest, "TransactionCanceledException",
+			fmt.Sprintf("Transaction cancelled, please refer cancellation reasons for specific reasons [%s]", strings.Join(codes, ", ")), reasons}
+	}
+	for _, w := range writes {
+		w.table.store(w.key, w.result)
+	}
+	return map[string]interface{}{}, nil
+}
+
+func (f *fake) batchGet(req request) (interface{}, *fakeError) {
+	responses := map[string]interface{}{}
+	for name, body := range req.item("RequestItems") {
+		table, ok := f.tables[name]
+		if !ok {
+			return nil, &fakeError{http.StatusBadRequest, "ResourceNotFoundException", "Requested resource not found", nil}
+		}
+		keys := request(body.(map[string]interface{})).list("Keys")
+		if len(keys) > 100 {
+			return nil, invalid("Too many items requested for the BatchGetItem call")
+		}
+		items := []fakeItem{}
+		for _, key := range keys {
+			if item, ok := table.items[itemKey(fakeItem(key.(map[string]interface{})))]; ok {
+				items = append(items, item)
+			}
+		}
+		responses[name] = items
+	}
+	return map[string]interface{}{"Responses": responses, "UnprocessedKeys": map[string]interface{}{}}, nil
+}
+
+func (f *fake) batchWrite(req request) (interface{}, *fakeError) {
+	for name, body := range req.item("RequestItems") {
+		table, ok := f.tables[name]
+		if !ok {
+			return nil, &fakeError{http.StatusBadRequest, "ResourceNotFoundException", "Requested resource not found", nil}
+		}
+		writes := body.([]interface{})
+		if len(writes) > 25 {
+			return nil, invalid("Too many items requested for the BatchWriteItem call")
+		}
+		seen := map[string]bool{}
+		for _, write := range writes {
+			for kind, body := range write.(map[string]interface{}) {
+				body := request(body.(map[string]interface{}))
+				item := body.item("Item")
+				if kind == "DeleteRequest" {
+					item = body.item("Key")
+				}
+				if seen[itemKey(item)] {
+					return nil, invalid("Provided list of item keys contains duplicates")
+				}
+				seen[itemKey(item)] = true
+			}
+		}
+		for _, write := range writes {
+			for kind, body := range write.(map[string]interface{}) {
+				body := request(body.(map[string]interface{}))
+				if kind == "DeleteRequest" {
+					table.store(itemKey(body.item("Key")), nil)
+				} else {
+					table.store(itemKey(body.item("Item")), body.item("Item"))
+				}
+			}
+		}
+	}
+	return map[string]interface{}{"UnprocessedItems": map[string]interface{}{}}, nil
+}
+
+// expression evaluates a request's expressions, with its placeholders.
+type expression struct {
+	names  map[string]string
+	values fakeItem
+}
+
+// path returns the attribute names of a document path, such as
+// "#columns.#key".
+func (e *expression) path(path string) []string {
+	parts := strings.Split(strings.TrimSpace(path), ".")
+	for i, part := range parts {
+		if name, ok := e.names[part]; ok {
+			parts[i] = name
+		}
+	}
+	return parts
+}
+
+// get returns the value at a path of the item, or nil.
+func get(item fakeItem, path []string) interface{} {
+	var value interface{} = map[string]interface{}{"M": map[string]interface{}(item)}
+	for _, name := range path {
+		parent, _ := value.(map[string]interface{})
+		m, _ := parent["M"].(map[string]interface{})
+		if m == nil {
+			return nil
+		}
+		value = m[name]
+	}
+	return value
+}
+
+var (
+	betweenExpression = regexp.MustCompile(`(\S+) BETWEEN (\S+) AND (\S+)`)
+	orOperator        = regexp.MustCompile(`(?i)\s+OR\s+`)
+	andOperator       = regexp.MustCompile(`(?i)\s+AND\s+`)
+	functionTerm      = regexp.MustCompile(`^(attribute_exists|attribute_not_exists|contains|between)\(([^,]+)(?:,\s*(\S+))?(?:,\s*(\S+))?\)$`)
+	comparisonTerm    = regexp.MustCompile(`^(\S+)\s*(=|<>|<=|>=|<|>)\s*(\S+)$`)
+)
+
+// condition reports whether the item, nil if there's none, meets the
+// condition, which may be empty. Conditions are terms joined by AND and OR,
+// without parentheses.
+func (e *expression) condition(item fakeItem, condition string) (bool, *fakeError) {
+	if strings.TrimSpace(condition) == "" {
+		return true, nil
+	}
+	condition = betweenExpression.ReplaceAllString(condition, "between($1, $2, $3)")
+	for _, any := range orOperator.Split(condition, -1) {
+		all := true
+		for _, term := range andOperator.Split(any, -1) {
+			ok, err := e.term(item, strings.TrimSpace(term))
+			if err != nil {
+				return false, err
+			}
+			all = all && ok
+		}
+		if all {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (e *expression) term(item fakeItem, term string) (bool, *fakeError) {
+	if m := functionTerm.FindStringSubmatch(term); m != nil {
+		value := get(item, e.path(m[2]))
+		switch m[1] {
+		case "attribute_exists":
+			return value != nil, nil
+		case "attribute_not_exists":
+			return value == nil, nil
+		case "contains":
+			return value != nil && strings.Contains(stringOf(value), stringOf(e.values[m[3]])), nil
+		case "between":
+			return compare(value, ">=", e.values[m[3]]) && compare(value, "<=", e.values[m[4]]), nil
+		}
+	}
+	if m := comparisonTerm.FindStringSubmatch(term); m != nil {
+		operand, ok := e.values[m[3]]
+		if !ok {
+			return false, invalid("An expression attribute value used in expression is not defined; attribute value: %s", m[3])
+		}
+		return compare(get(item, e.path(m[1])), m[2], operand), nil
+	}
+	return false, invalid("the fake doesn't know the condition %q", term)
+}
+
+// compare compares a value with an operand: equality of any values, and
+// order of strings.
+func compare(value interface{}, op string, operand interface{}) bool {
+	if value == nil {
+		return false
+	}
+	switch op {
+	case "=":
+		return equal(value, operand)
+	case "<>":
+		return !equal(value, operand)
+	}
+	a, b := stringOf(value), stringOf(operand)
+	switch op {
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	default:
+		return a >= b
+	}
+}
+
+// stringOf returns a string value's string, or "".
+func stringOf(value interface{}) string {
+	m, _ := value.(map[string]interface{})
+	str, _ := m["S"].(string)
+	return str
+}
+
+// equal reports whether two values are equal, as DynamoDB compares them:
+// string sets regardless of order.
+func equal(a, b interface{}) bool {
+	am, _ := a.(map[string]interface{})
+	bm, _ := b.(map[string]interface{})
+	if set, ok := am["SS"].([]interface{}); ok {
+		other, ok := bm["SS"].([]interface{})
+		return ok && reflect.DeepEqual(sortedSet(set), sortedSet(other))
+	}
+	if m, ok := am["M"].(map[string]interface{}); ok {
+		other, ok := bm["M"].(map[string]interface{})
+		if !ok || len(m) != len(other) {
+			return false
+		}
+		for name, value := range m {
+			if !equal(value, other[name]) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func sortedSet(set []interface{}) []string {
+	sorted := make([]string, len(set))
+	for i, element := range set {
+		sorted[i], _ = element.(string)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
+
+var updateClause = regexp.MustCompile(`(?i)\b(SET|REMOVE)\s+`)
+
+// update returns the item, or a new one with the key, updated as the
+// expression says. Only SET of values and REMOVE are known.
+func (e *expression) update(old, key fakeItem, update string) (fakeItem, *fakeError) {
+	item := copyItem(old)
+	if item == nil {
+		item = copyItem(key)
+	}
+	clauses := updateClause.FindAllStringSubmatchIndex(update, -1)
+	if len(clauses) == 0 {
+		return nil, invalid("the fake doesn't know the update %q", update)
+	}
+	for i, clause := range clauses {
+		end := len(update)
+		if i+1 < len(clauses) {
+			end = clauses[i+1][0]
+		}
+		action := strings.ToUpper(update[clause[2]:clause[3]])
+		for _, part := range strings.Split(update[clause[1]:end], ",") {
+			part = strings.TrimSpace(part)
+			if action == "REMOVE" {
+				if err := set(item, e.path(part), nil); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			path, placeholder, ok := strings.Cut(part, "=")
+			value, defined := e.values[strings.TrimSpace(placeholder)]
+			if !ok || !defined {
+				return nil, invalid("the fake doesn't know the update %q", part)
+			}
+			if err := set(item, e.path(path), value); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return item, nil
+}
+
+// set sets, or with a nil value removes, the value at a path of the item.
+// As in DynamoDB, the path's parent must exist.
+func set(item fakeItem, path []string, value interface{}) *fakeError {
+	parent := map[string]interface{}(item)
+	for _, name := range path[:len(path)-1] {
+		m, _ := parent[name].(map[string]interface{})
+		parent, _ = m["M"].(map[string]interface{})
+		if parent == nil {
+			return invalid("The document path provided in the update expression is invalid for update")
+		}
+	}
+	if value == nil {
+		delete(parent, path[len(path)-1])
+		return nil
+	}
+	parent[path[len(path)-1]] = value
+	return nil
+}
+
+// project returns the item with only the attributes at the projection's
+// paths, or the item itself if the projection is empty.
+func (e *expression) project(item fakeItem, projection string) fakeItem {
+	if projection == "" {
+		return item
+	}
+	projected := fakeItem{}
+	for _, path := range strings.Split(projection, ",") {
+		names := e.path(path)
+		value := get(item, names)
+		if value == nil {
+			continue
+		}
+		parent := map[string]interface{}(projected)
+		for _, name := range names[:len(names)-1] {
+			if _, ok := parent[name]; !ok {
+				parent[name] = map[string]interface{}{"M": map[string]interface{}{}}
+			}
+			parent = parent[name].(map[string]interface{})["M"].(map[string]interface{})
+		}
+		parent[names[len(names)-1]] = value
+	}
+	return projected
+}
+
+// copyItem copies the item deeply, so that updating the copy leaves the item
+// as it was.
+func copyItem(item fakeItem) fakeItem {
+	if item == nil {
+		return nil
+	}
+	b, _ := json.Marshal(item)
+	var copied fakeItem
+	json.Unmarshal(b, &copied)
+	return copied
+}
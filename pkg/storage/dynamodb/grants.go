@@ -0,0 +1,112 @@
+package dynamodb
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	kmstypes "github.com/aws/aws-sdk-go-v2/service/kms/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+// KeyOperations are the KMS operations a principal needs on the table's key
+// to use the table through DynamoDB, and to encrypt columns with it.
+var KeyOperations = []kmstypes.GrantOperation{
+	kmstypes.GrantOperationDecrypt,
+	kmstypes.GrantOperationEncrypt,
+	kmstypes.GrantOperationReEncryptFrom,
+	kmstypes.GrantOperationReEncryptTo,
+	kmstypes.GrantOperationGenerateDataKey,
+	kmstypes.GrantOperationGenerateDataKeyWithoutPlaintext,
+	kmstypes.GrantOperationDescribeKey,
+	kmstypes.GrantOperationCreateGrant,
+}
+
+// assumedRole matches the STS ARN of an assumed role's session.
+var assumedRole = regexp.MustCompile(`^arn:(aws[a-z-]*):sts::(\d+):assumed-role/([^/]+)/.+$`)
+
+// GranteePrincipal returns the principal to grant for a caller ARN, as STS
+// GetCallerIdentity returns it: grants name roles, not their sessions.
+func GranteePrincipal(callerARN string) string {
+	if m := assumedRole.FindStringSubmatch(callerARN); m != nil {
+		return fmt.Sprintf("arn:%s:iam::%s:role/%s", m[1], m[2], m[3])
+	}
+	return callerARN
+}
+
+// grantName names the grants the client creates, so that creating one again
+// returns the one that exists.
+func (client *Client) grantName() string {
+	return "tree-" + client.tableName
+}
+
+// MissingKeyGrants returns the KeyOperations that no grant of the table's key
+// gives principal, in KeyOperations' order. Key and IAM policies may allow
+// them instead, so operations missing here aren't necessarily denied. Listing
+// grants needs kms:ListGrants on the key.
+func (client *Client) MissingKeyGrants(ctx context.Context, principal string) ([]kmstypes.GrantOperation, error) {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemDynamoDB, fmt.Sprintf("MissingKeyGrants %q", principal))
+	granted := map[kmstypes.GrantOperation]bool{}
+	paginator := kms.NewListGrantsPaginator(client.kms, &kms.ListGrantsInput{
+		KeyId:            aws.String(client.keyARN),
+		GranteePrincipal: aws.String(principal),
+	})
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, grant := range output.Grants {
+			for _, op := range grant.Operations {
+				granted[op] = true
+			}
+		}
+	}
+	missing := []kmstypes.GrantOperation{}
+	for _, op := range KeyOperations {
+		if !granted[op] {
+			missing = append(missing, op)
+		}
+	}
+	return missing, nil
+}
+
+// GrantKeyAccess grants principal the KeyOperations on the table's key,
+// unless its grants already give them, and returns the operations it
+// granted. The client's own credentials need kms:CreateGrant on the key,
+// which is typically an administrator's.
+func (client *Client) GrantKeyAccess(ctx context.Context, principal string) ([]kmstypes.GrantOperation, error) {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemDynamoDB, fmt.Sprintf("GrantKeyAccess %q", principal))
+	missing, err := client.MissingKeyGrants(ctx, principal)
+	if err != nil {
+		return nil, err
+	}
+	if len(missing) == 0 {
+		return missing, nil
+	}
+	_, err = client.kms.CreateGrant(ctx, &kms.CreateGrantInput{
+		KeyId:            aws.String(client.keyARN),
+		GranteePrincipal: aws.String(principal),
+		Operations:       KeyOperations,
+		Name:             aws.String(client.grantName()),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return missing, nil
+}
+
+// KeyPermissions returns the IAM actions of operations, sorted, as a policy
+// would list them.
+func KeyPermissions(operations []kmstypes.GrantOperation) []string {
+	actions := make([]string, len(operations))
+	for i, op := range operations {
+		actions[i] = "kms:" + string(op)
+	}
+	sort.Strings(actions)
+	return actions
+}
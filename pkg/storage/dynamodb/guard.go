@@ -0,0 +1,186 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+// Root rows' labels are unique by type. A label guard is an item claiming a
+// label for the root row that has it, so that a transaction writing the row
+// can also claim its label, and fail if another row holds it, without first
+// querying for one. A guard's type is its row type's, with a suffix, and its
+// ID is the label.
+const (
+	labelGuardSuffix = "#label"
+	// storageAttrOwner is the ID of the row holding a guard's label. Rows
+	// never have it.
+	storageAttrOwner = "label_owner"
+)
+
+// maxLabelClaims is how many times a write claiming a label takes over the
+// guards of rows that no longer hold it, before giving up.
+const maxLabelClaims = 3
+
+// isLabelGuard reports whether an item is a label guard, not a row.
+func isLabelGuard(item map[string]types.AttributeValue) bool {
+	_, ok := item[storageAttrOwner]
+	return ok
+}
+
+func (client *Client) labelGuardKey(rowType, label string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		storageKeyType: &types.AttributeValueMemberS{Value: client.typeKey(rowType) + labelGuardSuffix},
+		storageKeyID:   &types.AttributeValueMemberS{Value: label},
+	}
+}
+
+func (client *Client) labelGuard(rowType, label, owner string) map[string]types.AttributeValue {
+	item := client.labelGuardKey(rowType, label)
+	item[storageAttrOwner] = &types.AttributeValueMemberS{Value: owner}
+	return item
+}
+
+// claimLabel returns the write claiming a root row's label for the row with
+// the ID, which fails if another row holds it. A staleOwner's claim, known
+// not to hold the label any more, is taken over.
+func (client *Client) claimLabel(rowType, label, id, staleOwner string) types.TransactWriteItem {
+	condition := "attribute_not_exists(#id) OR #owner = :owner"
+	values := map[string]types.AttributeValue{
+		":owner": &types.AttributeValueMemberS{Value: id},
+	}
+	if staleOwner != "" {
+		condition += " OR #owner = :stale_owner"
+		values[":stale_owner"] = &types.AttributeValueMemberS{Value: staleOwner}
+	}
+	return types.TransactWriteItem{Put: &types.Put{
+		TableName:           aws.String(client.tableName),
+		Item:                client.labelGuard(rowType, label, id),
+		ConditionExpression: aws.String(condition),
+		ExpressionAttributeNames: map[string]string{
+			"#id":    storageKeyID,
+			"#owner": storageAttrOwner,
+		},
+		ExpressionAttributeValues:           values,
+		ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+	}}
+}
+
+// releaseLabel returns the write releasing the row with the ID's claim on a
+// root row label, if it has one.
+func (client *Client) releaseLabel(rowType, label, id string) types.TransactWriteItem {
+	return types.TransactWriteItem{Delete: &types.Delete{
+		TableName:           aws.String(client.tableName),
+		Key:                 client.labelGuardKey(rowType, label),
+		ConditionExpression: aws.String("attribute_not_exists(#id) OR #owner = :owner"),
+		ExpressionAttributeNames: map[string]string{
+			"#id":    storageKeyID,
+			"#owner": storageAttrOwner,
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":owner": &types.AttributeValueMemberS{Value: id},
+		},
+	}}
+}
+
+// writeClaimingLabel writes the row with the ID in a transaction that also
+// claims its label. writes returns the transaction's writes, the claim among
+// them at claimIndex, taking over staleOwner's claim if set. A guard whose
+// owner no longer holds its label, because the owner was deleted or
+// relabeled, is taken over; one whose owner does fails the write with
+// ErrCollisionTypeLabel.
+func (client *Client) writeClaimingLabel(ctx context.Context, rowType, label, id string, claimIndex int, writes func(staleOwner string) []types.TransactWriteItem) error {
+	staleOwner := ""
+	for claim := 0; claim < maxLabelClaims; claim++ {
+		_, err := client.ddb.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+			TransactItems: writes(staleOwner),
+		})
+		owner, failed := conditionFailed(err, claimIndex)
+		if !failed || owner == "" {
+			return err
+		}
+		holds, err := client.holdsLabel(ctx, rowType, owner, label)
+		if err != nil {
+			return err
+		}
+		if holds {
+			return ErrCollisionTypeLabel
+		}
+		tflog.SubsystemDebug(ctx, storage.LogSubsystemDynamoDB, fmt.Sprintf("taking over the stale claim of %q on %s label %q", owner, rowType, label))
+		staleOwner = owner
+	}
+	return fmt.Errorf("%w: the claim on %s label %q kept changing", storage.ErrConflict, rowType, label)
+}
+
+// conditionFailed reports whether a transaction failed because the condition
+// of its write at index did, and if that write claimed a label, which row
+// holds it.
+func conditionFailed(err error, index int) (owner string, failed bool) {
+	var canceled *types.TransactionCanceledException
+	if !errors.As(err, &canceled) || index >= len(canceled.CancellationReasons) {
+		return "", false
+	}
+	reason := canceled.CancellationReasons[index]
+	if aws.ToString(reason.Code) != "ConditionalCheckFailed" {
+		return "", false
+	}
+	if held, ok := reason.Item[storageAttrOwner].(*types.AttributeValueMemberS); ok {
+		owner = held.Value
+	}
+	return owner, true
+}
+
+// holdsLabel reports whether the root row with the type and ID exists and
+// has the label, so that its claim on it isn't stale.
+func (client *Client) holdsLabel(ctx context.Context, rowType, id, label string) (bool, error) {
+	r, err := client.GetRowColumns(ctx, rowType, id, nil)
+	if errors.Is(err, ErrNotFoundRow) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return r.ParentID() == "" && r.Label() == label, nil
+}
+
+// ClaimLabels writes a label guard for each root row, so that renames check
+// their labels against rows stored before there were guards, and returns how
+// many root rows hold theirs. Rows whose label another row's guard claims,
+// which are most often duplicates, are left without one, and counted in
+// duplicates.
+func (client *Client) ClaimLabels(ctx context.Context) (claimed, duplicates int, err error) {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemDynamoDB, "ClaimLabels")
+	err = client.ScanRows(ctx, func(r storage.Row) error {
+		if r.ParentID() != "" {
+			return nil
+		}
+		_, err := client.ddb.PutItem(ctx, &dynamodb.PutItemInput{
+			TableName:           aws.String(client.tableName),
+			Item:                client.labelGuard(r.Type(), r.Label(), r.ID()),
+			ConditionExpression: aws.String("attribute_not_exists(#id) OR #owner = :owner"),
+			ExpressionAttributeNames: map[string]string{
+				"#id":    storageKeyID,
+				"#owner": storageAttrOwner,
+			},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":owner": &types.AttributeValueMemberS{Value: r.ID()},
+			},
+		})
+		switch {
+		case errors.Is(err, storage.ErrConflict):
+			duplicates++
+			return nil
+		case err != nil:
+			return err
+		}
+		claimed++
+		return nil
+	})
+	return claimed, duplicates, err
+}
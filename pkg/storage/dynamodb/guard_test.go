@@ -0,0 +1,108 @@
+package dynamodb_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+func TestLabelGuard(t *testing.T) {
+	ctx := context.Background()
+	f := newFake(t)
+	client, table := f.newClient(t)
+
+	org, err := client.CreateRow(ctx, "org", "acme")
+	if err != nil {
+		t.Fatalf("CreateRow: %s", err)
+	}
+	checkGuard(t, f, table, "org#label", "acme", org.ID())
+	team, err := client.CreateChild(ctx, "team", "dev", "org", org.ID(), nil)
+	if err != nil {
+		t.Fatalf("CreateChild: %s", err)
+	}
+	// children's labels are unique by parent, which an index finds
+	if guard := f.item(table, "team#label", team.Label()); guard != nil {
+		t.Errorf("CreateChild claimed its label: %v", guard)
+	}
+
+	if _, err := client.UpdateRow(ctx, "org", org.ID(), "umbrella"); err != nil {
+		t.Fatalf("UpdateRow: %s", err)
+	}
+	checkGuard(t, f, table, "org#label", "umbrella", org.ID())
+	if guard := f.item(table, "org#label", "acme"); guard != nil {
+		t.Errorf("UpdateRow left the old label's guard: %v", guard)
+	}
+
+	if err := client.DeleteRow(ctx, "org", "", org.ID()); err != nil {
+		t.Fatalf("DeleteRow: %s", err)
+	}
+	if guard := f.item(table, "org#label", "umbrella"); guard != nil {
+		t.Errorf("DeleteRow left the label's guard: %v", guard)
+	}
+}
+
+func TestLabelGuardHeld(t *testing.T) {
+	ctx := context.Background()
+	f := newFake(t)
+	client, table := f.newClient(t)
+
+	org, err := client.CreateRow(ctx, "org", "acme")
+	if err != nil {
+		t.Fatalf("CreateRow: %s", err)
+	}
+	other, err := client.CreateRow(ctx, "org", "umbrella")
+	if err != nil {
+		t.Fatalf("CreateRow: %s", err)
+	}
+	if _, err := client.CreateRow(ctx, "org", "acme"); !errors.Is(err, storage.ErrCollisionTypeLabel) {
+		t.Errorf("CreateRow of a held label returned %v, not %s", err, storage.ErrCollisionTypeLabel)
+	}
+	if _, err := client.UpdateRow(ctx, "org", other.ID(), "acme"); !errors.Is(err, storage.ErrCollisionTypeLabel) {
+		t.Errorf("UpdateRow to a held label returned %v, not %s", err, storage.ErrCollisionTypeLabel)
+	}
+	checkGuard(t, f, table, "org#label", "acme", org.ID())
+}
+
+func TestLabelGuardStale(t *testing.T) {
+	ctx := context.Background()
+	f := newFake(t)
+	client, table := f.newClient(t)
+	if _, err := client.CreateRow(ctx, "org", "umbrella"); err != nil {
+		t.Fatalf("CreateRow: %s", err)
+	}
+
+	// guards left by rows that are gone, or that no longer have the label,
+	// as a writer that failed midway leaves them
+	f.put(table, fakeItem{"type": s("org#label"), "id": s("acme"), "label_owner": s("org-gone")})
+	org, err := client.CreateRow(ctx, "org", "acme")
+	if err != nil {
+		t.Fatalf("CreateRow of a label whose owner is gone: %s", err)
+	}
+	checkGuard(t, f, table, "org#label", "acme", org.ID())
+
+	umbrella, err := client.GetRow(ctx, "org", "umbrella")
+	if err != nil {
+		t.Fatalf("GetRow: %s", err)
+	}
+	f.put(table, fakeItem{"type": s("org#label"), "id": s("globex"), "label_owner": s(umbrella.ID())})
+	if _, err := client.UpdateRow(ctx, "org", org.ID(), "globex"); err != nil {
+		t.Fatalf("UpdateRow to a label whose owner has another: %s", err)
+	}
+	checkGuard(t, f, table, "org#label", "globex", org.ID())
+}
+
+// checkGuard checks that the label's guard is stored, and claims the label
+// for the owner.
+func checkGuard(t *testing.T, f *fake, table, guardType, label, owner string) {
+	t.Helper()
+	guard := f.item(table, guardType, label)
+	if guard == nil {
+		t.Errorf("no guard of %s %q", guardType, label)
+		return
+	}
+	if got := guard.str("label_owner"); got != owner {
+		t.Errorf("guard of %s %q is %q's, not %q's", guardType, label, got, owner)
+	}
+}
@@ -0,0 +1,110 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+var ErrKeyInaccessible = errors.New("the table's KMS key is inaccessible")
+
+// keyPollInterval is how often RotateKey checks whether the table has
+// switched keys.
+const keyPollInterval = 10 * time.Second
+
+// RotateKey switches the table's encryption to another KMS key, and waits,
+// until ctx is done, for DynamoDB to finish re-encrypting. The client uses
+// the new key from then on. Rotating to the key the table already uses does
+// nothing.
+func (client *Client) RotateKey(ctx context.Context, keyARN string) error {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemDynamoDB, fmt.Sprintf("RotateKey %q", keyARN))
+	sse, err := client.describeSSE(ctx)
+	if err != nil {
+		return err
+	}
+	if sse == nil || sse.SSEType != types.SSETypeKms || aws.ToString(sse.KMSMasterKeyArn) != keyARN {
+		_, err := client.ddb.UpdateTable(ctx, &dynamodb.UpdateTableInput{
+			TableName: aws.String(client.tableName),
+			SSESpecification: &types.SSESpecification{
+				Enabled:        aws.Bool(true),
+				SSEType:        types.SSETypeKms,
+				KMSMasterKeyId: aws.String(keyARN),
+			},
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	for {
+		sse, err := client.describeSSE(ctx)
+		if err != nil {
+			return err
+		}
+		if sse != nil && sse.Status == types.SSEStatusEnabled && aws.ToString(sse.KMSMasterKeyArn) == keyARN {
+			client.keyARN = keyARN
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for the table to use key %s: %w", keyARN, ctx.Err())
+		case <-time.After(keyPollInterval):
+		}
+	}
+}
+
+// CheckKeyAccess checks that DynamoDB can use the table's KMS key, by its
+// reported status and by reading an item.
+func (client *Client) CheckKeyAccess(ctx context.Context) error {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemDynamoDB, "CheckKeyAccess")
+	output, err := client.ddb.DescribeTable(ctx, &dynamodb.DescribeTableInput{
+		TableName: aws.String(client.tableName),
+	})
+	if err != nil {
+		return err
+	}
+	if table := output.Table; table.TableStatus == types.TableStatusInaccessibleEncryptionCredentials {
+		since := ""
+		if sse := table.SSEDescription; sse != nil && sse.InaccessibleEncryptionDateTime != nil {
+			since = " since " + sse.InaccessibleEncryptionDateTime.Format(time.RFC3339)
+		}
+		return fmt.Errorf("%w: %w%s", storage.ErrPermissionDenied, ErrKeyInaccessible, since)
+	}
+	if client.tenant != "" {
+		// a tenant's credentials may not scan, so read an item of its
+		// slice instead, whether or not it exists
+		_, err = client.ddb.GetItem(ctx, &dynamodb.GetItemInput{
+			TableName: aws.String(client.tableName),
+			Key: map[string]types.AttributeValue{
+				storageKeyType: &types.AttributeValueMemberS{Value: client.typeKey("")},
+				storageKeyID:   &types.AttributeValueMemberS{Value: "-"},
+			},
+		})
+	} else {
+		_, err = client.ddb.Scan(ctx, &dynamodb.ScanInput{
+			TableName: aws.String(client.tableName),
+			Limit:     aws.Int32(1),
+		})
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %w: reading an item: %s", storage.ErrPermissionDenied, ErrKeyInaccessible, err)
+	}
+	return nil
+}
+
+func (client *Client) describeSSE(ctx context.Context) (*types.SSEDescription, error) {
+	output, err := client.ddb.DescribeTable(ctx, &dynamodb.DescribeTableInput{
+		TableName: aws.String(client.tableName),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return output.Table.SSEDescription, nil
+}
@@ -0,0 +1,170 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+// transactWriteSize is the most items one TransactWriteItems call may write.
+const transactWriteSize = 100
+
+var (
+	_ storage.ColumnPatcher = &Client{}
+	_ storage.ColumnDeleter = &Client{}
+)
+
+// PatchColumns applies the patches with TransactWriteItems, 100 rows at a
+// time, each transaction failing as a whole if one of its rows is missing.
+// Compact rows are patched one at a time, since each patch rewrites all of
+// its row's columns after reading them.
+func (client *Client) PatchColumns(ctx context.Context, patches []storage.ColumnPatch) error {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemDynamoDB, fmt.Sprintf("PatchColumns %d", len(patches)))
+	if client.compact {
+		for _, patch := range patches {
+			err := client.updateCompactColumns(ctx, patch.Type, patch.ID, patch.Columns)
+			client.cache.Invalidate(patch.Type, patch.ID)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	chunk := []storage.ColumnPatch{}
+	// patched is the rows chunk patches, since one transaction can't write
+	// an item twice
+	patched := map[cacheKey]bool{}
+	flush := func() error {
+		// whole is the patches of rows without columns maps, which are set
+		// whole
+		whole := map[int]bool{}
+		var err error
+		for attempt := 0; attempt < columnWriteAttempts; attempt++ {
+			writes := make([]types.TransactWriteItem, len(chunk))
+			for i, patch := range chunk {
+				writes[i] = types.TransactWriteItem{Update: client.columnsUpdate(patch.Type, patch.ID, patch.Columns, whole[i])}
+			}
+			_, err = client.ddb.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+				TransactItems: writes,
+			})
+			retry := false
+			for i := range chunk {
+				if _, failed := conditionFailed(err, i); failed {
+					whole[i] = !whole[i]
+					retry = true
+				}
+			}
+			if !retry {
+				break
+			}
+		}
+		for _, patch := range chunk {
+			client.cache.Invalidate(patch.Type, patch.ID)
+		}
+		chunk = chunk[:0]
+		clear(patched)
+		return err
+	}
+	for _, patch := range patches {
+		if len(patch.Columns) == 0 {
+			continue
+		}
+		row := cacheKey{patch.Type, patch.ID}
+		if len(chunk) == transactWriteSize || patched[row] {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+		chunk = append(chunk, patch)
+		patched[row] = true
+	}
+	if len(chunk) == 0 {
+		return nil
+	}
+	return flush()
+}
+
+// columnWriteAttempts is how many times a write of columns is tried, setting
+// them in the row's columns map and setting the map whole in turn, before its
+// row is taken to be missing.
+const columnWriteAttempts = 3
+
+// columnsUpdate returns the update setting columns of a row, on condition
+// that the row exists. A path into a map that doesn't exist can't be set, so
+// the columns are set in the row's columns map on condition that it has one,
+// or, if whole is set, as the whole map on condition that it has none, as
+// rows created without columns don't.
+func (client *Client) columnsUpdate(rowType, rowID string, columns map[string]interface{}, whole bool) *types.Update {
+	update := &types.Update{
+		TableName: aws.String(client.tableName),
+		Key: map[string]types.AttributeValue{
+			storageKeyType: &types.AttributeValueMemberS{Value: client.itemTypeKey(rowType, rowID)},
+			storageKeyID:   &types.AttributeValueMemberS{Value: rowID},
+		},
+		ExpressionAttributeNames: map[string]string{
+			"#columns": storageAttrColumns,
+			"#type":    storageKeyType,
+			"#id":      storageKeyID,
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{},
+	}
+	if whole {
+		update.UpdateExpression = aws.String("SET #columns = :columns")
+		update.ExpressionAttributeValues[":columns"] = &types.AttributeValueMemberM{Value: columnsToMap(columns)}
+		update.ConditionExpression = aws.String("attribute_exists(#type) AND attribute_exists(#id) AND attribute_not_exists(#columns)")
+		return update
+	}
+	expression := ""
+	i := 0
+	for name, value := range columns {
+		if i > 0 {
+			expression += ", "
+		}
+		expression += fmt.Sprintf("#columns.#column%d = :column%d", i, i)
+		update.ExpressionAttributeNames[fmt.Sprintf("#column%d", i)] = name
+		update.ExpressionAttributeValues[fmt.Sprintf(":column%d", i)] = ifaceToAttributeValue(value)
+		i++
+	}
+	update.UpdateExpression = aws.String("SET " + expression)
+	update.ConditionExpression = aws.String("attribute_exists(#type) AND attribute_exists(#id) AND attribute_exists(#columns)")
+	return update
+}
+
+// DeleteColumn removes the column from the row's columns, on condition that
+// it has them, or, for compact rows, rewrites them without it.
+func (client *Client) DeleteColumn(ctx context.Context, rowType, rowID, columnName string) error {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemDynamoDB, fmt.Sprintf("DeleteColumn %q %q %q", rowType, rowID, columnName))
+	if client.compact {
+		err := client.updateCompactColumns(ctx, rowType, rowID, map[string]interface{}{columnName: nil})
+		client.cache.Invalidate(rowType, rowID)
+		return err
+	}
+
+	_, err := client.ddb.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(client.tableName),
+		Key: map[string]types.AttributeValue{
+			storageKeyType: &types.AttributeValueMemberS{Value: client.itemTypeKey(rowType, rowID)},
+			storageKeyID:   &types.AttributeValueMemberS{Value: rowID},
+		},
+		UpdateExpression: aws.String("REMOVE #columns.#key"),
+		ExpressionAttributeNames: map[string]string{
+			"#columns": storageAttrColumns,
+			"#key":     columnName,
+		},
+		// a path into a map that doesn't exist can't be removed
+		ConditionExpression: aws.String("attribute_exists(#columns)"),
+	})
+	client.cache.Invalidate(rowType, rowID)
+	if errors.Is(err, storage.ErrConflict) {
+		// the row has no columns to remove the column from, if it exists
+		_, err = client.GetRowColumns(ctx, rowType, rowID, nil)
+	}
+	return err
+}
@@ -0,0 +1,141 @@
+package dynamodb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// The actions the table's resource policy allows.
+var (
+	policyReadActions = []string{
+		"dynamodb:BatchGetItem",
+		"dynamodb:ConditionCheckItem",
+		"dynamodb:DescribeTable",
+		"dynamodb:GetItem",
+		"dynamodb:Query",
+		"dynamodb:Scan",
+	}
+	policyWriteActions = []string{
+		"dynamodb:BatchWriteItem",
+		"dynamodb:DeleteItem",
+		"dynamodb:PutItem",
+		"dynamodb:UpdateItem",
+	}
+)
+
+// tablePolicy is who the table's resource policy allows to use it.
+type tablePolicy struct {
+	principals         []string
+	readOnlyPrincipals []string
+}
+
+// WithTablePolicy attaches a resource-based policy to the table when the
+// client creates it, allowing principals to read and write rows, and
+// readOnlyPrincipals only to read them. Principals are IAM role or user
+// ARNs, or account IDs. VerifySchema reports a table without a resource
+// policy, and can attach this one; it leaves a policy that exists alone.
+func WithTablePolicy(principals, readOnlyPrincipals []string) Option {
+	return func(o *options) {
+		o.tablePolicy = &tablePolicy{principals: principals, readOnlyPrincipals: readOnlyPrincipals}
+	}
+}
+
+type policyDocument struct {
+	Version   string            `json:"Version"`
+	Statement []policyStatement `json:"Statement"`
+}
+
+type policyStatement struct {
+	Sid       string              `json:"Sid"`
+	Effect    string              `json:"Effect"`
+	Principal map[string][]string `json:"Principal"`
+	Action    []string            `json:"Action"`
+	Resource  []string            `json:"Resource"`
+}
+
+// resourcePolicy returns the table's resource policy document, or nil if the
+// client has none to attach. Policies name the table by ARN, which is made
+// from the caller's account, since the table may not exist yet.
+func (client *Client) resourcePolicy(ctx context.Context) (*string, error) {
+	if client.tablePolicy == nil {
+		return nil, nil
+	}
+	identity, err := client.sts.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return nil, err
+	}
+	partition := "aws"
+	if parts := strings.SplitN(aws.ToString(identity.Arn), ":", 3); len(parts) == 3 {
+		partition = parts[1]
+	}
+	tableARN := fmt.Sprintf("arn:%s:dynamodb:%s:%s:table/%s", partition, client.region, aws.ToString(identity.Account), client.tableName)
+	resources := []string{tableARN, tableARN + "/index/*"}
+
+	document := policyDocument{Version: "2012-10-17"}
+	if len(client.tablePolicy.principals) > 0 {
+		document.Statement = append(document.Statement, policyStatement{
+			Sid:       "TreeReadWrite",
+			Effect:    "Allow",
+			Principal: map[string][]string{"AWS": client.tablePolicy.principals},
+			Action:    append(append([]string{}, policyReadActions...), policyWriteActions...),
+			Resource:  resources,
+		})
+	}
+	if len(client.tablePolicy.readOnlyPrincipals) > 0 {
+		document.Statement = append(document.Statement, policyStatement{
+			Sid:       "TreeReadOnly",
+			Effect:    "Allow",
+			Principal: map[string][]string{"AWS": client.tablePolicy.readOnlyPrincipals},
+			Action:    policyReadActions,
+			Resource:  resources,
+		})
+	}
+	if len(document.Statement) == 0 {
+		return nil, nil
+	}
+	encoded, err := json.Marshal(document)
+	if err != nil {
+		return nil, err
+	}
+	return aws.String(string(encoded)), nil
+}
+
+// verifyPolicy checks that the table has a resource policy, if the client
+// has one to attach.
+func (client *Client) verifyPolicy(ctx context.Context, tableARN *string) (SchemaProblem, bool, error) {
+	if client.tablePolicy == nil {
+		return SchemaProblem{}, true, nil
+	}
+	_, err := client.ddb.GetResourcePolicy(ctx, &dynamodb.GetResourcePolicyInput{
+		ResourceArn: tableARN,
+	})
+	var notFound *types.PolicyNotFoundException
+	if errors.As(err, &notFound) {
+		return SchemaProblem{
+			Problem: "the table has no resource policy",
+			fix: func(ctx context.Context) error {
+				policy, err := client.resourcePolicy(ctx)
+				if err != nil || policy == nil {
+					return err
+				}
+				_, err = client.ddb.PutResourcePolicy(ctx, &dynamodb.PutResourcePolicyInput{
+					ResourceArn: tableARN,
+					Policy:      policy,
+				})
+				return err
+			},
+		}, false, nil
+	}
+	if err != nil {
+		return SchemaProblem{}, false, err
+	}
+	return SchemaProblem{}, true, nil
+}
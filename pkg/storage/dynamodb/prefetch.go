@@ -0,0 +1,58 @@
+package dynamodb
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+// WithChildPrefetch has GetRowByID read the row's children along with it,
+// into the read cache, since reading a row is most often followed by reading
+// its children, by ID or by label. The children are read in one query, at the
+// same time as the row, up to one page of them. It needs WithReadCache, and
+// without it does nothing.
+func WithChildPrefetch() Option {
+	return func(o *options) { o.prefetchChildren = true }
+}
+
+// prefetchChildren starts reading the children of the row with the ID into
+// the cache, and returns a function that waits for them.
+func (client *Client) prefetchChildren(ctx context.Context, id string) func() {
+	if !client.prefetch || client.cache == nil {
+		return func() {}
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		output, err := client.ddb.Query(ctx, &dynamodb.QueryInput{
+			TableName:              aws.String(client.tableName),
+			IndexName:              aws.String(storageGSIByParentAndLabel),
+			KeyConditionExpression: aws.String("#parent_id = :parent_id"),
+			ExpressionAttributeNames: map[string]string{
+				"#parent_id": storageAttrParentID,
+			},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":parent_id": &types.AttributeValueMemberS{Value: client.parentKey(id)},
+			},
+		})
+		if err != nil {
+			// prefetching only saves later reads, which will fail on their
+			// own if this was more than a missing table
+			tflog.SubsystemDebug(ctx, storage.LogSubsystemDynamoDB, fmt.Sprintf("prefetching the children of %q failed: %s", id, err))
+			return
+		}
+		for _, item := range output.Items {
+			child, err := client.itemToRow(item)
+			if err != nil {
+				return
+			}
+			client.cache.Put(child)
+		}
+	}()
+	return func() { <-done }
+}
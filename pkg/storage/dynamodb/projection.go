@@ -0,0 +1,88 @@
+package dynamodb
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+var _ storage.ColumnReader = &Client{}
+
+// GetRowColumns reads the row with a projection expression naming only its
+// keys, label, parent, and the named columns, so that DynamoDB sends, and the
+// client unmarshals, none of its other columns. Column names are always
+// expression attribute names, so any name may be projected. Rows with compact
+// columns are read with all of them, since they are one attribute. The row is
+// taken from the read cache if it's there, but not cached, since it's only
+// part of the row.
+func (client *Client) GetRowColumns(ctx context.Context, rowType, id string, columnNames []string) (storage.Row, error) {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemDynamoDB, fmt.Sprintf("GetRowColumns %q %q", rowType, id))
+	if cached, ok := client.cache.GetByID(rowType, id); ok {
+		return storage.ProjectColumns(cached, columnNames), nil
+	}
+	projection, names := rowProjection(columnNames)
+	output, err := client.ddb.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(client.tableName),
+		Key: map[string]types.AttributeValue{
+			storageKeyType: &types.AttributeValueMemberS{Value: client.itemTypeKey(rowType, id)},
+			storageKeyID:   &types.AttributeValueMemberS{Value: id},
+		},
+		ProjectionExpression:     aws.String(projection),
+		ExpressionAttributeNames: names,
+		ConsistentRead:           aws.Bool(true),
+	})
+	if isTableMissing(err) {
+		output, err = &dynamodb.GetItemOutput{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if output.Item == nil {
+		return nil, fmt.Errorf("%w: %q", ErrNotFoundRow, id)
+	}
+	r, err := client.itemToRow(output.Item)
+	if err != nil {
+		return nil, err
+	}
+	// compact columns are read whole
+	return storage.ProjectColumns(r, columnNames), nil
+}
+
+// rowProjection returns the projection expression of a row with only the
+// named columns, and its attribute names.
+func rowProjection(columnNames []string) (string, map[string]string) {
+	names := map[string]string{
+		"#type":      storageKeyType,
+		"#id":        storageKeyID,
+		"#label":     storageAttrLabel,
+		"#parent_id": storageAttrParentID,
+	}
+	paths := []string{"#type", "#id", "#label", "#parent_id"}
+	if len(columnNames) > 0 {
+		names["#columns"] = storageAttrColumns
+		names["#columns_json"] = storageAttrColumnsJSON
+		paths = append(paths, "#columns_json")
+	}
+	for i, name := range columnNames {
+		placeholder := fmt.Sprintf("#column%d", i)
+		names[placeholder] = name
+		paths = append(paths, "#columns."+placeholder)
+	}
+	return strings.Join(paths, ", "), names
+}
+
+// keysOnly makes a query that checks whether any item matches read at most
+// one, and only its keys.
+func keysOnly(input *dynamodb.QueryInput) *dynamodb.QueryInput {
+	input.ExpressionAttributeNames["#type"] = storageKeyType
+	input.ExpressionAttributeNames["#id"] = storageKeyID
+	input.ProjectionExpression = aws.String("#type, #id")
+	input.Limit = aws.Int32(1)
+	return input
+}
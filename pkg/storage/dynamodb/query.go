@@ -0,0 +1,48 @@
+package dynamodb
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+var _ storage.ColumnQuerier = &Client{}
+
+// QueryByColumn queries the ByType index for the type's rows, as ListRows
+// does, with a filter expression on the column, so that rows without the
+// value aren't sent back. A filter can't see into compact columns, so rows
+// stored with them are sent back to be checked here, as are string sets,
+// whose order the filter can't ignore. The query reads, and is billed for,
+// every row of the type all the same.
+func (client *Client) QueryByColumn(ctx context.Context, rowType, columnName string, value interface{}) ([]storage.Row, error) {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemDynamoDB, fmt.Sprintf("QueryByColumn %q %q", rowType, columnName))
+	input := client.listInput(rowType, "", "")
+	input.ExpressionAttributeNames["#columns"] = storageAttrColumns
+	input.ExpressionAttributeNames["#columns_json"] = storageAttrColumnsJSON
+	input.ExpressionAttributeNames["#column"] = columnName
+	if s, ok := value.(string); ok {
+		input.FilterExpression = aws.String("#columns.#column = :value OR attribute_exists(#columns_json)")
+		input.ExpressionAttributeValues[":value"] = &types.AttributeValueMemberS{Value: s}
+	} else {
+		input.FilterExpression = aws.String("attribute_exists(#columns.#column) OR attribute_exists(#columns_json)")
+	}
+	items, err := client.queryShards(ctx, rowType, input, client.queryPages)
+	if err != nil {
+		return nil, err
+	}
+	rows := []storage.Row{}
+	for _, item := range items {
+		r, err := client.itemToRow(item)
+		if err != nil {
+			return nil, err
+		}
+		if storage.ColumnEquals(r, columnName, value) {
+			rows = append(rows, r)
+		}
+	}
+	return rows, nil
+}
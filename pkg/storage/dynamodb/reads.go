@@ -0,0 +1,50 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+	"golang.org/x/sync/singleflight"
+)
+
+// readKey identifies a read, so that identical reads can share one call.
+func readKey(kind string, parts ...string) string {
+	return kind + "\x00" + strings.Join(parts, "\x00")
+}
+
+// readOnce calls read, unless an identical read is already in flight, in
+// which case it waits for that read's result instead, so that resources
+// reading the same parent at once make one call to DynamoDB between them.
+// The read runs with the context of the caller that started it. A caller
+// whose context is done stops waiting, and one whose shared read was cut
+// short by the starter's context reads for itself. Callers sharing a row get
+// copies of it, so that none sees another change it.
+func (client *Client) readOnce(ctx context.Context, key string, read func() (storage.Row, error)) (storage.Row, error) {
+	results := client.reads.DoChan(key, func() (interface{}, error) {
+		return read()
+	})
+	var result singleflight.Result
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case result = <-results:
+	}
+	if result.Err != nil {
+		if result.Shared && isContextDone(result.Err) && ctx.Err() == nil {
+			return read()
+		}
+		return nil, result.Err
+	}
+	if result.Shared {
+		return copyRow(result.Val.(storage.Row)), nil
+	}
+	return result.Val.(storage.Row), nil
+}
+
+// isContextDone reports whether err is that of a cancelled or expired
+// context.
+func isContextDone(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
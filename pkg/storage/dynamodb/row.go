@@ -19,6 +19,13 @@ func itemToRow(item map[string]types.AttributeValue) (*row, error) {
 	if err != nil {
 		return nil, err
 	}
+	// rows written with WithCompactColumns
+	if encoded, ok := item[storageAttrColumnsJSON].(*types.AttributeValueMemberS); ok {
+		r.RowColumns, err = decodeColumns(encoded.Value)
+		if err != nil {
+			return nil, err
+		}
+	}
 	return &r, nil
 }
 
@@ -0,0 +1,360 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+var ErrNotFixable = errors.New("not safe to fix automatically")
+
+// tableInput describes the table the client expects: its keys, indexes,
+// encryption, and change stream.
+func (client *Client) tableInput() *dynamodb.CreateTableInput {
+	return &dynamodb.CreateTableInput{
+		TableName: aws.String(client.tableName),
+		AttributeDefinitions: []types.AttributeDefinition{
+			{
+				AttributeName: aws.String(storageKeyType),
+				AttributeType: types.ScalarAttributeTypeS,
+			},
+			{
+				AttributeName: aws.String(storageKeyID),
+				AttributeType: types.ScalarAttributeTypeS,
+			},
+			{
+				AttributeName: aws.String(storageAttrParentID),
+				AttributeType: types.ScalarAttributeTypeS,
+			},
+			{
+				AttributeName: aws.String(storageAttrLabel),
+				AttributeType: types.ScalarAttributeTypeS,
+			},
+		},
+		KeySchema: []types.KeySchemaElement{
+			{
+				AttributeName: aws.String(storageKeyType),
+				KeyType:       types.KeyTypeHash,
+			},
+			{
+				AttributeName: aws.String(storageKeyID),
+				KeyType:       types.KeyTypeRange,
+			},
+		},
+		GlobalSecondaryIndexes: []types.GlobalSecondaryIndex{
+			{
+				IndexName: aws.String(storageGSIByParentAndLabel),
+				KeySchema: []types.KeySchemaElement{
+					{
+						AttributeName: aws.String(storageAttrParentID),
+						KeyType:       types.KeyTypeHash,
+					},
+					{
+						AttributeName: aws.String(storageAttrLabel),
+						KeyType:       types.KeyTypeRange,
+					},
+				},
+				Projection: &types.Projection{ProjectionType: types.ProjectionTypeAll},
+			},
+			{
+				IndexName: aws.String(storageGSIByType),
+				KeySchema: []types.KeySchemaElement{
+					{
+						AttributeName: aws.String(storageKeyType),
+						KeyType:       types.KeyTypeHash,
+					},
+				},
+				Projection: &types.Projection{ProjectionType: types.ProjectionTypeAll},
+			},
+		},
+		LocalSecondaryIndexes: []types.LocalSecondaryIndex{
+			{
+				IndexName: aws.String(storageLSIByTypeAndLabel),
+				KeySchema: []types.KeySchemaElement{
+					{
+						AttributeName: aws.String(storageKeyType),
+						KeyType:       types.KeyTypeHash,
+					},
+					{
+						AttributeName: aws.String(storageAttrLabel),
+						KeyType:       types.KeyTypeRange,
+					},
+				},
+				Projection: &types.Projection{ProjectionType: types.ProjectionTypeAll},
+			},
+			{
+				IndexName: aws.String(storageLSIByTypeAndParent),
+				KeySchema: []types.KeySchemaElement{
+					{
+						AttributeName: aws.String(storageKeyType),
+						KeyType:       types.KeyTypeHash,
+					},
+					{
+						AttributeName: aws.String(storageAttrParentID),
+						KeyType:       types.KeyTypeRange,
+					},
+				},
+				Projection: &types.Projection{ProjectionType: types.ProjectionTypeAll},
+			},
+		},
+		BillingMode: types.BillingModePayPerRequest,
+		SSESpecification: &types.SSESpecification{
+			Enabled:        aws.Bool(true),
+			SSEType:        types.SSETypeKms,
+			KMSMasterKeyId: aws.String(client.keyARN),
+		},
+		StreamSpecification: &types.StreamSpecification{
+			StreamEnabled:  aws.Bool(true),
+			StreamViewType: types.StreamViewTypeNewAndOldImages,
+		},
+	}
+}
+
+// SchemaProblem is one way the table differs from what the client expects.
+type SchemaProblem struct {
+	Problem string
+	// fix corrects the problem, if that is safe to do on a live table
+	fix func(ctx context.Context) error
+}
+
+// Fixable reports whether Fix can correct the problem.
+func (problem SchemaProblem) Fixable() bool {
+	return problem.fix != nil
+}
+
+// Fix corrects the problem, or returns ErrNotFixable. Fixes that change the
+// table take effect asynchronously; verify again once the table is active.
+func (problem SchemaProblem) Fix(ctx context.Context) error {
+	if problem.fix == nil {
+		return fmt.Errorf("%w: %s", ErrNotFixable, problem.Problem)
+	}
+	return problem.fix(ctx)
+}
+
+// VerifySchema checks the table's keys, indexes, encryption, change stream,
+// resource policy, and point-in-time recovery against what the client
+// expects. Missing global indexes, encryption settings, a missing stream, a
+// missing resource policy, and point-in-time recovery can be fixed in place.
+// Wrong keys and local indexes can't: they are fixed only by creating a new
+// table and migrating the rows to it.
+func (client *Client) VerifySchema(ctx context.Context) ([]SchemaProblem, error) {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemDynamoDB, "VerifySchema")
+	output, err := client.ddb.DescribeTable(ctx, &dynamodb.DescribeTableInput{
+		TableName: aws.String(client.tableName),
+	})
+	if isTableMissing(err) {
+		// it would be created by the first write
+		return []SchemaProblem{{
+			Problem: "the table doesn't exist",
+			fix:     client.ensureTable,
+		}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	table := output.Table
+	want := client.tableInput()
+	problems := []SchemaProblem{}
+
+	if !sameKeySchema(table.KeySchema, want.KeySchema) {
+		problems = append(problems, SchemaProblem{
+			Problem: fmt.Sprintf("the table's key is %s, not %s", describeKeySchema(table.KeySchema), describeKeySchema(want.KeySchema)),
+		})
+	}
+
+	gsis := map[string]types.GlobalSecondaryIndexDescription{}
+	for _, gsi := range table.GlobalSecondaryIndexes {
+		gsis[aws.ToString(gsi.IndexName)] = gsi
+	}
+	for _, wantGSI := range want.GlobalSecondaryIndexes {
+		name := aws.ToString(wantGSI.IndexName)
+		gsi, ok := gsis[name]
+		switch {
+		case !ok:
+			problems = append(problems, SchemaProblem{
+				Problem: fmt.Sprintf("global secondary index %s is missing", name),
+				fix:     client.createGSIFix(wantGSI, want.AttributeDefinitions),
+			})
+		case !sameKeySchema(gsi.KeySchema, wantGSI.KeySchema) || !sameProjection(gsi.Projection, wantGSI.Projection):
+			problems = append(problems, SchemaProblem{
+				Problem: fmt.Sprintf("global secondary index %s has key %s, not %s, or doesn't project every attribute", name, describeKeySchema(gsi.KeySchema), describeKeySchema(wantGSI.KeySchema)),
+			})
+		}
+	}
+
+	lsis := map[string]types.LocalSecondaryIndexDescription{}
+	for _, lsi := range table.LocalSecondaryIndexes {
+		lsis[aws.ToString(lsi.IndexName)] = lsi
+	}
+	for _, wantLSI := range want.LocalSecondaryIndexes {
+		name := aws.ToString(wantLSI.IndexName)
+		lsi, ok := lsis[name]
+		switch {
+		case !ok:
+			problems = append(problems, SchemaProblem{
+				Problem: fmt.Sprintf("local secondary index %s is missing, and can only be added by recreating the table", name),
+			})
+		case !sameKeySchema(lsi.KeySchema, wantLSI.KeySchema) || !sameProjection(lsi.Projection, wantLSI.Projection):
+			problems = append(problems, SchemaProblem{
+				Problem: fmt.Sprintf("local secondary index %s has key %s, not %s, or doesn't project every attribute", name, describeKeySchema(lsi.KeySchema), describeKeySchema(wantLSI.KeySchema)),
+			})
+		}
+	}
+
+	if problem, ok := client.verifySSE(table.SSEDescription); !ok {
+		problems = append(problems, problem)
+	}
+
+	if problem, ok := client.verifyStream(table.StreamSpecification, want.StreamSpecification); !ok {
+		problems = append(problems, problem)
+	}
+
+	problem, ok, err := client.verifyPolicy(ctx, table.TableArn)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		problems = append(problems, problem)
+	}
+
+	backups, err := client.ddb.DescribeContinuousBackups(ctx, &dynamodb.DescribeContinuousBackupsInput{
+		TableName: aws.String(client.tableName),
+	})
+	if err != nil {
+		return nil, err
+	}
+	pitr := backups.ContinuousBackupsDescription.PointInTimeRecoveryDescription
+	if pitr == nil || pitr.PointInTimeRecoveryStatus != types.PointInTimeRecoveryStatusEnabled {
+		problems = append(problems, SchemaProblem{
+			Problem: "point-in-time recovery is not enabled",
+			fix: func(ctx context.Context) error {
+				_, err := client.ddb.UpdateContinuousBackups(ctx, &dynamodb.UpdateContinuousBackupsInput{
+					TableName: aws.String(client.tableName),
+					PointInTimeRecoverySpecification: &types.PointInTimeRecoverySpecification{
+						PointInTimeRecoveryEnabled: aws.Bool(true),
+					},
+				})
+				return err
+			},
+		})
+	}
+	return problems, nil
+}
+
+func (client *Client) createGSIFix(gsi types.GlobalSecondaryIndex, definitions []types.AttributeDefinition) func(context.Context) error {
+	// only the new index's key attributes may be defined
+	keys := []types.AttributeDefinition{}
+	for _, definition := range definitions {
+		for _, element := range gsi.KeySchema {
+			if aws.ToString(element.AttributeName) == aws.ToString(definition.AttributeName) {
+				keys = append(keys, definition)
+			}
+		}
+	}
+	return func(ctx context.Context) error {
+		_, err := client.ddb.UpdateTable(ctx, &dynamodb.UpdateTableInput{
+			TableName:            aws.String(client.tableName),
+			AttributeDefinitions: keys,
+			GlobalSecondaryIndexUpdates: []types.GlobalSecondaryIndexUpdate{
+				{
+					Create: &types.CreateGlobalSecondaryIndexAction{
+						IndexName:  gsi.IndexName,
+						KeySchema:  gsi.KeySchema,
+						Projection: gsi.Projection,
+					},
+				},
+			},
+		})
+		return err
+	}
+}
+
+// verifySSE checks that the table is encrypted with KMS, and with the
+// client's key if it has one.
+func (client *Client) verifySSE(sse *types.SSEDescription) (SchemaProblem, bool) {
+	fix := func(ctx context.Context) error {
+		spec := &types.SSESpecification{
+			Enabled: aws.Bool(true),
+			SSEType: types.SSETypeKms,
+		}
+		if client.keyARN != "" {
+			spec.KMSMasterKeyId = aws.String(client.keyARN)
+		}
+		_, err := client.ddb.UpdateTable(ctx, &dynamodb.UpdateTableInput{
+			TableName:        aws.String(client.tableName),
+			SSESpecification: spec,
+		})
+		return err
+	}
+
+	switch {
+	case sse == nil || sse.Status != types.SSEStatusEnabled:
+		return SchemaProblem{Problem: "the table is not encrypted with a KMS key", fix: fix}, false
+	case sse.SSEType != types.SSETypeKms:
+		return SchemaProblem{Problem: fmt.Sprintf("the table is encrypted with %s, not KMS", sse.SSEType), fix: fix}, false
+	case client.keyARN != "" && aws.ToString(sse.KMSMasterKeyArn) != client.keyARN:
+		return SchemaProblem{Problem: fmt.Sprintf("the table is encrypted with KMS key %s, not %s", aws.ToString(sse.KMSMasterKeyArn), client.keyARN), fix: fix}, false
+	}
+	return SchemaProblem{}, true
+}
+
+// verifyStream checks that the table streams new and old images of changed
+// items, for Watch.
+func (client *Client) verifyStream(stream, want *types.StreamSpecification) (SchemaProblem, bool) {
+	switch {
+	case stream == nil || !aws.ToBool(stream.StreamEnabled):
+		return SchemaProblem{
+			Problem: "the table has no change stream",
+			fix: func(ctx context.Context) error {
+				_, err := client.ddb.UpdateTable(ctx, &dynamodb.UpdateTableInput{
+					TableName:           aws.String(client.tableName),
+					StreamSpecification: want,
+				})
+				return err
+			},
+		}, false
+	case stream.StreamViewType != want.StreamViewType:
+		// a stream's view type can't be changed, and replacing the stream
+		// would cut off anything reading it
+		return SchemaProblem{
+			Problem: fmt.Sprintf("the table's change stream has view type %s, not %s; disable it and verify again to replace it", stream.StreamViewType, want.StreamViewType),
+		}, false
+	}
+	return SchemaProblem{}, true
+}
+
+func sameKeySchema(got, want []types.KeySchemaElement) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range want {
+		if aws.ToString(got[i].AttributeName) != aws.ToString(want[i].AttributeName) || got[i].KeyType != want[i].KeyType {
+			return false
+		}
+	}
+	return true
+}
+
+func sameProjection(got, want *types.Projection) bool {
+	return got != nil && want != nil && got.ProjectionType == want.ProjectionType
+}
+
+func describeKeySchema(schema []types.KeySchemaElement) string {
+	if len(schema) == 0 {
+		return "(none)"
+	}
+	out := ""
+	for i, element := range schema {
+		if i > 0 {
+			out += ", "
+		}
+		out += fmt.Sprintf("%s %s", aws.ToString(element.AttributeName), element.KeyType)
+	}
+	return out
+}
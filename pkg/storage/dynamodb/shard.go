@@ -0,0 +1,138 @@
+package dynamodb
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+// shardSeparator comes between a sharded type and its shard number, in the
+// type of the row's item.
+const shardSeparator = "#shard"
+
+// WithShardedTypes spreads the rows of each named type across that many
+// partition keys, "<type>#shard0" to "<type>#shard<n-1>", chosen by a hash of
+// each row's ID, so that a type written to heavily doesn't concentrate its
+// writes on one partition. Reading a row by ID still reads one item, but
+// finding rows of the type by label, listing them, and checking a parent for
+// children of the type query every shard, at once.
+//
+// Shard a type before it has rows, or copy them into a table whose writers
+// shard it, since rows stored under another scheme aren't found. Every
+// client of a table must shard the same types alike.
+func WithShardedTypes(shards map[string]int) Option {
+	return func(o *options) { o.shards = shards }
+}
+
+// itemTypeKey returns the stored type of the row with the type and ID: the
+// type's shard for the ID, if it's sharded.
+func (client *Client) itemTypeKey(rowType, id string) string {
+	n := client.shards[rowType]
+	if n <= 1 {
+		return client.typeKey(rowType)
+	}
+	hash := fnv.New32a()
+	hash.Write([]byte(id))
+	return client.shardKey(rowType, int(hash.Sum32()%uint32(n)))
+}
+
+func (client *Client) shardKey(rowType string, shard int) string {
+	return client.typeKey(rowType) + shardSeparator + strconv.Itoa(shard)
+}
+
+// unshardedType returns the row type of a stored type.
+func unshardedType(stored string) string {
+	i := strings.LastIndex(stored, shardSeparator)
+	if i < 0 {
+		return stored
+	}
+	if _, err := strconv.Atoi(stored[i+len(shardSeparator):]); err != nil {
+		return stored
+	}
+	return stored[:i]
+}
+
+// queryFunc runs a query, returning every item of it or only some.
+type queryFunc func(context.Context, *dynamodb.QueryInput) ([]map[string]types.AttributeValue, error)
+
+// queryShards runs a query of the type's rows, whose key condition takes the
+// stored type as :type, and returns the items it finds. For a sharded type,
+// it runs query for each shard at once, and returns the items of them all.
+func (client *Client) queryShards(ctx context.Context, rowType string, input *dynamodb.QueryInput, query queryFunc) ([]map[string]types.AttributeValue, error) {
+	n := client.shards[rowType]
+	if n <= 1 {
+		input.ExpressionAttributeValues[":type"] = &types.AttributeValueMemberS{Value: client.typeKey(rowType)}
+		return query(ctx, input)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	shards := make([][]map[string]types.AttributeValue, n)
+	var failed sync.Once
+	var firstErr error
+	var wg sync.WaitGroup
+	for i := range shards {
+		shard := *input
+		shard.ExpressionAttributeValues = map[string]types.AttributeValue{}
+		for name, value := range input.ExpressionAttributeValues {
+			shard.ExpressionAttributeValues[name] = value
+		}
+		shard.ExpressionAttributeValues[":type"] = &types.AttributeValueMemberS{Value: client.shardKey(rowType, i)}
+		wg.Add(1)
+		go func(i int, shard *dynamodb.QueryInput) {
+			defer wg.Done()
+			items, err := query(ctx, shard)
+			if err != nil {
+				failed.Do(func() {
+					firstErr = err
+					cancel()
+				})
+				return
+			}
+			shards[i] = items
+		}(i, &shard)
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	items := []map[string]types.AttributeValue{}
+	for _, shard := range shards {
+		items = append(items, shard...)
+	}
+	return items, nil
+}
+
+// queryPage returns the items of the first page of the query, for queries
+// that need no more, such as existence checks.
+func (client *Client) queryPage(ctx context.Context, input *dynamodb.QueryInput) ([]map[string]types.AttributeValue, error) {
+	output, err := client.ddb.Query(ctx, input)
+	if isTableMissing(err) {
+		// the table is created by the first write
+		return []map[string]types.AttributeValue{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if output == nil || output.Items == nil {
+		return nil, ErrNilQueryOutput
+	}
+	return output.Items, nil
+}
+
+// validShards checks the shard counts of WithShardedTypes.
+func validShards(shards map[string]int) error {
+	for rowType, n := range shards {
+		if n < 1 {
+			return fmt.Errorf("%w: type %q can't have %d shards", storage.ErrInvalid, rowType, n)
+		}
+	}
+	return nil
+}
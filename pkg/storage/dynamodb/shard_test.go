@@ -0,0 +1,65 @@
+package dynamodb_test
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"testing"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/dynamodb"
+)
+
+func TestShardedTypes(t *testing.T) {
+	ctx := context.Background()
+	f := newFake(t)
+	client, table := f.newClient(t, dynamodb.WithShardedTypes(map[string]int{"team": 4}))
+
+	org, err := client.CreateRow(ctx, "org", "acme")
+	if err != nil {
+		t.Fatalf("CreateRow: %s", err)
+	}
+	if item := f.item(table, "org", org.ID()); item == nil {
+		t.Errorf("the unsharded org isn't stored under its type")
+	}
+	shards := map[string]bool{}
+	for i := 0; i < 20; i++ {
+		team, err := client.CreateChild(ctx, "team", fmt.Sprintf("team-%d", i), "org", org.ID(), nil)
+		if err != nil {
+			t.Fatalf("CreateChild: %s", err)
+		}
+		hash := fnv.New32a()
+		hash.Write([]byte(team.ID()))
+		shard := fmt.Sprintf("team#shard%d", hash.Sum32()%4)
+		if item := f.item(table, shard, team.ID()); item == nil {
+			t.Errorf("team %q isn't stored in %s", team.ID(), shard)
+		}
+		shards[shard] = true
+	}
+	if len(shards) < 2 {
+		t.Errorf("20 teams were stored in %d shard, not spread among 4", len(shards))
+	}
+
+	teams, err := client.ListRows(ctx, "team", "", org.ID())
+	if err != nil {
+		t.Fatalf("ListRows: %s", err)
+	}
+	if len(teams) != 20 {
+		t.Errorf("ListRows found %d teams in every shard, not 20", len(teams))
+	}
+	for _, team := range teams {
+		if team.Type() != "team" {
+			t.Errorf("ListRows returned a row of type %q, not the unsharded %q", team.Type(), "team")
+		}
+	}
+}
+
+func TestShardedTypesInvalid(t *testing.T) {
+	f := newFake(t)
+	t.Setenv("AWS_ACCESS_KEY_ID", "fake")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "fake")
+	_, err := dynamodb.NewClient(context.Background(), "", "fake", "invalid", "",
+		dynamodb.WithEndpoint(f.server.URL), dynamodb.WithShardedTypes(map[string]int{"team": -1}))
+	if err == nil {
+		t.Errorf("NewClient accepted -1 shards")
+	}
+}
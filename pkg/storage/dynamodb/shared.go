@@ -0,0 +1,76 @@
+package dynamodb
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+)
+
+// Terraform configures a provider once per alias, and again for each
+// operation of a run, in the same process. What clients of the same profile,
+// region, and table can share is kept here, so that each configuration
+// doesn't resolve credentials and describe the table again.
+var shared = struct {
+	mu      sync.Mutex
+	configs map[sharedConfigKey]aws.Config
+	tables  map[sharedTableKey]bool
+}{
+	configs: map[sharedConfigKey]aws.Config{},
+	tables:  map[sharedTableKey]bool{},
+}
+
+type sharedConfigKey struct {
+	profile string
+	region  string
+}
+
+type sharedTableKey struct {
+	sharedConfigKey
+	tableName string
+}
+
+// sharedConfig loads the AWS configuration of a profile and region, or
+// returns a copy of the one loaded before, whose credentials are cached
+// between the clients that use them.
+func sharedConfig(ctx context.Context, profile, region string) (aws.Config, error) {
+	key := sharedConfigKey{profile, region}
+	shared.mu.Lock()
+	defer shared.mu.Unlock()
+	if cfg, ok := shared.configs[key]; ok {
+		return copyConfig(cfg), nil
+	}
+	cfg, err := config.LoadDefaultConfig(ctx,
+		config.WithSharedConfigProfile(profile),
+		config.WithRegion(region),
+	)
+	if err != nil {
+		return aws.Config{}, err
+	}
+	shared.configs[key] = cfg
+	return copyConfig(cfg), nil
+}
+
+// copyConfig copies cfg so that adding middleware to the copy doesn't add it
+// to the original's, as appending to a shared slice could.
+func copyConfig(cfg aws.Config) aws.Config {
+	cfg = cfg.Copy()
+	cfg.APIOptions = slices.Clip(cfg.APIOptions)
+	return cfg
+}
+
+// tableExists reports whether a client of the same profile and region has
+// found or created the table.
+func tableExists(profile, region, tableName string) bool {
+	shared.mu.Lock()
+	defer shared.mu.Unlock()
+	return shared.tables[sharedTableKey{sharedConfigKey{profile, region}, tableName}]
+}
+
+func setTableExists(profile, region, tableName string) {
+	shared.mu.Lock()
+	defer shared.mu.Unlock()
+	shared.tables[sharedTableKey{sharedConfigKey{profile, region}, tableName}] = true
+}
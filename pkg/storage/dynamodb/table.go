@@ -0,0 +1,49 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// tableActiveTimeout is how long the client waits for a table it creates to
+// become active before writing to it.
+const tableActiveTimeout = 5 * time.Minute
+
+// ensureTable creates the table, if it doesn't exist, before the client's
+// first write that may need it. Creating it only then lets plans, which only
+// read, run without permission to describe or create tables. Reads of a
+// table that doesn't exist yet find no rows.
+func (client *Client) ensureTable(ctx context.Context) error {
+	if tableExists(client.profile, client.region, client.tableName) {
+		return nil
+	}
+	client.tableMu.Lock()
+	defer client.tableMu.Unlock()
+	if tableExists(client.profile, client.region, client.tableName) {
+		return nil
+	}
+	if err := client.createTableIfNotExists(ctx); err != nil {
+		return err
+	}
+	setTableExists(client.profile, client.region, client.tableName)
+	return nil
+}
+
+// waitForTable waits for a table the client created to become active.
+func (client *Client) waitForTable(ctx context.Context) error {
+	return dynamodb.NewTableExistsWaiter(client.ddb).Wait(ctx, &dynamodb.DescribeTableInput{
+		TableName: aws.String(client.tableName),
+	}, tableActiveTimeout)
+}
+
+// isTableMissing reports whether err is DynamoDB's for a table that doesn't
+// exist.
+func isTableMissing(err error) bool {
+	var notFound *types.ResourceNotFoundException
+	return errors.As(err, &notFound)
+}
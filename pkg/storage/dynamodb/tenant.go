@@ -0,0 +1,80 @@
+package dynamodb
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+// tenantSeparator ends the tenant prefix of a key.
+const tenantSeparator = "#"
+
+// ErrInvalidTenant is returned by NewClient for a tenant it can't use.
+var ErrInvalidTenant = fmt.Errorf("%w: invalid tenant", storage.ErrInvalid)
+
+// WithTenant keeps the client's rows in the tenant's slice of the table, so
+// that an IAM policy's dynamodb:LeadingKeys condition on "<tenant>#*" can
+// restrict credentials to it. Every partition key the client writes or
+// queries, of the table and of its indexes, begins with the tenant: a row's
+// type and parent ID are stored as "<tenant>#<type>" and
+// "<tenant>#<parent ID>". Callers still see them unprefixed.
+//
+// Rows stored without a tenant, or with another, are invisible to the client.
+// ScanRows and Watch read the whole table, which such a policy can't allow,
+// and skip the rows of other tenants.
+func WithTenant(tenant string) Option {
+	return func(o *options) { o.tenant = tenant }
+}
+
+func validTenant(tenant string) error {
+	if strings.Contains(tenant, tenantSeparator) {
+		return fmt.Errorf("%w: %q contains %q", ErrInvalidTenant, tenant, tenantSeparator)
+	}
+	return nil
+}
+
+// typeKey returns the stored form of a row type.
+func (client *Client) typeKey(rowType string) string {
+	if client.tenant == "" {
+		return rowType
+	}
+	return client.tenant + tenantSeparator + rowType
+}
+
+// parentKey returns the stored form of a parent ID. Root rows have none.
+func (client *Client) parentKey(parentID string) string {
+	if client.tenant == "" || parentID == "" {
+		return parentID
+	}
+	return client.tenant + tenantSeparator + parentID
+}
+
+// inTenant reports whether an item belongs to the client's tenant.
+func (client *Client) inTenant(item map[string]types.AttributeValue) bool {
+	if client.tenant == "" {
+		return true
+	}
+	rowType, ok := item[storageKeyType].(*types.AttributeValueMemberS)
+	return ok && strings.HasPrefix(rowType.Value, client.tenant+tenantSeparator)
+}
+
+// itemToRow converts an item of the client's tenant to a row, removing the
+// tenant and any shard from its keys.
+func (client *Client) itemToRow(item map[string]types.AttributeValue) (*row, error) {
+	r, err := itemToRow(item)
+	if err != nil {
+		return r, err
+	}
+	if len(client.shards) > 0 {
+		r.RowType = unshardedType(r.RowType)
+	}
+	if client.tenant == "" {
+		return r, nil
+	}
+	prefix := client.tenant + tenantSeparator
+	r.RowType = strings.TrimPrefix(r.RowType, prefix)
+	r.RowParentID = strings.TrimPrefix(r.RowParentID, prefix)
+	return r, nil
+}
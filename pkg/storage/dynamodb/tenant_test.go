@@ -0,0 +1,73 @@
+package dynamodb_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/dynamodb"
+)
+
+func TestTenant(t *testing.T) {
+	ctx := context.Background()
+	f := newFake(t)
+	a, table := f.newClient(t, dynamodb.WithTenant("a"))
+	b := f.clientOf(t, table, dynamodb.WithTenant("b"))
+
+	org, err := a.CreateRow(ctx, "org", "acme")
+	if err != nil {
+		t.Fatalf("CreateRow: %s", err)
+	}
+	team, err := a.CreateChild(ctx, "team", "dev", "org", org.ID(), nil)
+	if err != nil {
+		t.Fatalf("CreateChild: %s", err)
+	}
+	if item := f.item(table, "a#org", org.ID()); item == nil {
+		t.Errorf("the org isn't stored under its tenant's type")
+	}
+	if item := f.item(table, "a#team", team.ID()); item == nil {
+		t.Errorf("the team isn't stored under its tenant's type")
+	} else if got, want := item.str("parent_id"), "a#"+org.ID(); got != want {
+		t.Errorf("the team's stored parent is %q, not %q", got, want)
+	}
+	if item := f.item(table, "a#org#label", "acme"); item == nil {
+		t.Errorf("the org's label guard isn't stored under its tenant's type")
+	}
+	got, err := a.GetRowByID(ctx, "team", team.ID())
+	if err != nil {
+		t.Fatalf("GetRowByID: %s", err)
+	}
+	if got.Type() != "team" || got.ParentID() != org.ID() {
+		t.Errorf("GetRowByID returned type %q and parent %q, not unprefixed ones", got.Type(), got.ParentID())
+	}
+
+	// the other tenant sees none of it, and can use the same labels
+	if _, err := b.GetRowByID(ctx, "org", org.ID()); !errors.Is(err, storage.ErrNotFoundRow) {
+		t.Errorf("GetRowByID of another tenant's row returned %v, not %s", err, storage.ErrNotFoundRow)
+	}
+	if _, err := b.CreateRow(ctx, "org", "acme"); err != nil {
+		t.Errorf("CreateRow of another tenant's label: %s", err)
+	}
+	scanned := 0
+	if err := b.ScanRows(ctx, func(storage.Row) error {
+		scanned++
+		return nil
+	}); err != nil {
+		t.Fatalf("ScanRows: %s", err)
+	}
+	if scanned != 1 {
+		t.Errorf("ScanRows found %d rows, not only the tenant's 1", scanned)
+	}
+}
+
+func TestTenantInvalid(t *testing.T) {
+	f := newFake(t)
+	t.Setenv("AWS_ACCESS_KEY_ID", "fake")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "fake")
+	_, err := dynamodb.NewClient(context.Background(), "", "fake", "invalid", "",
+		dynamodb.WithEndpoint(f.server.URL), dynamodb.WithTenant("a#b"))
+	if !errors.Is(err, dynamodb.ErrInvalidTenant) {
+		t.Errorf("NewClient of tenant %q returned %v, not %s", "a#b", err, dynamodb.ErrInvalidTenant)
+	}
+}
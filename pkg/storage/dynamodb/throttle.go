@@ -0,0 +1,156 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/smithy-go"
+	"github.com/aws/smithy-go/middleware"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+// throttleMiddlewareID names the adaptive throttling middleware in the
+// client's stack.
+const throttleMiddlewareID = "TreeAdaptiveThrottle"
+
+// minThrottleRate is the fewest calls a second a throttle slows to.
+const minThrottleRate = 1
+
+// Throttle paces a client's DynamoDB API calls, so that a large apply backs
+// off when the table is busy rather than leaving it to retries, which keep
+// calling at full speed and starve the table's other consumers.
+type Throttle struct {
+	// Rate is the most calls a second the client makes, and the rate it
+	// starts at. Each throttled call halves the rate, down to one a second,
+	// and each call that isn't throttled raises it by a hundredth of Rate,
+	// back up to it.
+	Rate float64
+	// Capacity is the most capacity units a second the client's calls
+	// consume, such as a share of the table's provisioned capacity. A call
+	// consuming more than its share delays those after it. 0 doesn't limit
+	// capacity.
+	Capacity float64
+}
+
+// WithAdaptiveThrottling paces the client's API calls by throttle. Every
+// attempt of a call is paced, retries included.
+func WithAdaptiveThrottling(throttle Throttle) Option {
+	return func(o *options) { o.throttle = &throttle }
+}
+
+func validThrottle(throttle *Throttle) error {
+	if throttle == nil {
+		return nil
+	}
+	if throttle.Rate < minThrottleRate {
+		return fmt.Errorf("%w: a throttle's rate must be at least %d call a second, not %g", storage.ErrInvalid, minThrottleRate, throttle.Rate)
+	}
+	if throttle.Capacity < 0 {
+		return fmt.Errorf("%w: a throttle's capacity can't be negative", storage.ErrInvalid)
+	}
+	return nil
+}
+
+// throttler is a Throttle's state: its current rate, and the time the next
+// call may start.
+type throttler struct {
+	Throttle
+	mu   sync.Mutex
+	rate float64
+	next time.Time
+}
+
+func newThrottler(throttle Throttle) *throttler {
+	return &throttler{Throttle: throttle, rate: throttle.Rate}
+}
+
+// wait waits for the next call's turn, which it takes, or for ctx to be done.
+func (t *throttler) wait(ctx context.Context) error {
+	t.mu.Lock()
+	now := time.Now()
+	start := t.next
+	if start.Before(now) {
+		start = now
+	}
+	t.next = start.Add(time.Duration(float64(time.Second) / t.rate))
+	t.mu.Unlock()
+
+	delay := time.Until(start)
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// observe adjusts the rate by whether a call was throttled, and delays the
+// next call by any capacity the call consumed beyond its share.
+func (t *throttler) observe(throttled bool, capacity float64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if throttled {
+		t.rate /= 2
+		if t.rate < minThrottleRate {
+			t.rate = minThrottleRate
+		}
+	} else {
+		t.rate += t.Rate / 100
+		if t.rate > t.Rate {
+			t.rate = t.Rate
+		}
+	}
+	if t.Capacity > 0 && capacity > 0 {
+		owed := time.Duration(capacity / t.Capacity * float64(time.Second))
+		// the call's turn already paid for some of it
+		owed -= time.Duration(float64(time.Second) / t.rate)
+		if owed > 0 {
+			if now := time.Now(); t.next.Before(now) {
+				t.next = now
+			}
+			t.next = t.next.Add(owed)
+		}
+	}
+}
+
+// isThrottledAttempt reports whether an attempt of a call was throttled:
+// refused as such, or, for a batch write, left items unprocessed.
+func isThrottledAttempt(result interface{}, err error) bool {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		return errorKinds[apiErr.ErrorCode()] == storage.ErrThrottled
+	}
+	output, ok := result.(*dynamodb.BatchWriteItemOutput)
+	return ok && len(output.UnprocessedItems) > 0
+}
+
+// middleware paces each attempt of each call, after the SDK's retries decide
+// to make it, and has consumed capacity returned for it to observe.
+func (t *throttler) middleware(stack *middleware.Stack) error {
+	err := stack.Initialize.Add(middleware.InitializeMiddlewareFunc(throttleMiddlewareID, func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+		if t.Capacity > 0 {
+			returnConsumedCapacity(in.Parameters)
+		}
+		return next.HandleInitialize(ctx, in)
+	}), middleware.After)
+	if err != nil {
+		return err
+	}
+	return stack.Finalize.Add(middleware.FinalizeMiddlewareFunc(throttleMiddlewareID, func(ctx context.Context, in middleware.FinalizeInput, next middleware.FinalizeHandler) (middleware.FinalizeOutput, middleware.Metadata, error) {
+		if err := t.wait(ctx); err != nil {
+			return middleware.FinalizeOutput{}, middleware.Metadata{}, err
+		}
+		out, metadata, err := next.HandleFinalize(ctx, in)
+		t.observe(isThrottledAttempt(out.Result, err), consumedCapacity(out.Result))
+		return out, metadata, err
+	}), middleware.After)
+}
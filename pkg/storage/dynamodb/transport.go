@@ -0,0 +1,77 @@
+package dynamodb
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+// Transport tunes the HTTP connections a client makes its calls over. The AWS
+// SDK keeps at most 10 idle connections to DynamoDB, so an apply running more
+// calls than that at once closes connections only to open new ones, each with
+// a TLS handshake. Fields left 0 keep the SDK's defaults.
+type Transport struct {
+	// MaxIdleConns is the most idle connections to keep open to each host,
+	// DynamoDB's among them, for later calls to reuse. The SDK's default is
+	// 10; set it to about Terraform's -parallelism.
+	MaxIdleConns int
+	// MaxConnsPerHost is the most connections to open to each host, idle or
+	// not. Calls beyond it wait for a connection. By default there's no
+	// limit.
+	MaxConnsPerHost int
+	// IdleTimeout is how long an idle connection is kept open. The SDK's
+	// default is 90 seconds.
+	IdleTimeout time.Duration
+	// KeepAlive is how often TCP keep-alive probes check an open connection,
+	// so that connections a network drops are found before a call uses them.
+	// The SDK's default is 30 seconds.
+	KeepAlive time.Duration
+}
+
+// WithHTTPTransport makes the client's calls, to DynamoDB and to the other
+// AWS services it uses, over connections tuned by transport.
+func WithHTTPTransport(transport Transport) Option {
+	return func(o *options) { o.transport = &transport }
+}
+
+func validTransport(transport *Transport) error {
+	if transport == nil {
+		return nil
+	}
+	if transport.MaxIdleConns < 0 || transport.MaxConnsPerHost < 0 {
+		return fmt.Errorf("%w: a transport's numbers of connections can't be negative", storage.ErrInvalid)
+	}
+	if transport.IdleTimeout < 0 || transport.KeepAlive < 0 {
+		return fmt.Errorf("%w: a transport's durations can't be negative", storage.ErrInvalid)
+	}
+	return nil
+}
+
+// httpClient returns an HTTP client of the SDK's defaults, tuned by the
+// transport.
+func (transport Transport) httpClient() *awshttp.BuildableClient {
+	return awshttp.NewBuildableClient().
+		WithTransportOptions(func(tr *http.Transport) {
+			if transport.MaxIdleConns > 0 {
+				tr.MaxIdleConnsPerHost = transport.MaxIdleConns
+				if tr.MaxIdleConns < transport.MaxIdleConns {
+					tr.MaxIdleConns = transport.MaxIdleConns
+				}
+			}
+			if transport.MaxConnsPerHost > 0 {
+				tr.MaxConnsPerHost = transport.MaxConnsPerHost
+			}
+			if transport.IdleTimeout > 0 {
+				tr.IdleConnTimeout = transport.IdleTimeout
+			}
+		}).
+		WithDialerOptions(func(dialer *net.Dialer) {
+			if transport.KeepAlive > 0 {
+				dialer.KeepAlive = transport.KeepAlive
+			}
+		})
+}
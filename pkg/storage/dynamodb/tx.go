@@ -0,0 +1,578 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/spilliams/tree-terraform-provider/internal/slug"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+var _ storage.Transactor = &Client{}
+
+// WithinTx calls fn with a storer that stages its writes, reading them back
+// over the table, and makes them in one TransactWriteItems call if fn returns
+// nil. Each write is on condition that its row hasn't been created, changed,
+// or deleted since the transaction read it, and root rows claim their labels
+// as CreateRow and UpdateRow do. The parents that rows are created or moved
+// under are checked, too: that they still exist, under the parents they had
+// when read. A transaction may write 100 items, counting the label claims of root
+// rows and the checks of parents: more fail with storage.ErrTxTooLarge, and
+// nothing is written.
+//
+// Reads of the table aren't isolated: fn sees other writers' changes as they
+// are made, though a write that depends on a row another writer changed
+// fails the transaction with an error of the ErrConflict kind. Other rows
+// fn reads, such as a parent's ancestors, aren't checked, and may change
+// before the transaction is made.
+func (client *Client) WithinTx(ctx context.Context, fn func(tx storage.RowStorer) error) error {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemDynamoDB, "WithinTx")
+	tx := &txStorer{client: client, writes: map[cacheKey]*txWrite{}, parents: map[cacheKey]storage.Row{}}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.commit(ctx)
+}
+
+// txStorer is the storer of a DynamoDB transaction.
+type txStorer struct {
+	client *Client
+	// writes are the transaction's writes, by their rows' type and ID, in
+	// order
+	writes map[cacheKey]*txWrite
+	order  []cacheKey
+	// parents are the parents of the rows the transaction creates or moves,
+	// as read, by type and ID, in order
+	parents     map[cacheKey]storage.Row
+	parentOrder []cacheKey
+}
+
+var (
+	_ storage.RowStorer     = &txStorer{}
+	_ storage.ChildScanner  = &txStorer{}
+	_ storage.ColumnDeleter = &txStorer{}
+)
+
+// txWrite is a transaction's write of a row.
+type txWrite struct {
+	// before is the row as the transaction first read it, nil if the
+	// transaction created it
+	before storage.Row
+	// after is the row as the transaction leaves it, nil if it deleted it
+	after *row
+	// put is set if PutRow wrote the row, as it is, whatever it was before
+	put bool
+}
+
+// stage records a write of the row with the key, which was before as read.
+func (tx *txStorer) stage(key cacheKey, before storage.Row, after *row) {
+	if w, ok := tx.writes[key]; ok {
+		w.after = after
+		return
+	}
+	tx.writes[key] = &txWrite{before: before, after: after}
+	tx.order = append(tx.order, key)
+}
+
+// checkParent records the parent of a row the transaction creates or moves,
+// as read, so that the transaction is made only if the parent is unchanged.
+func (tx *txStorer) checkParent(parent storage.Row) {
+	key := cacheKey{parent.Type(), parent.ID()}
+	if _, ok := tx.parents[key]; ok {
+		return
+	}
+	tx.parents[key] = parent
+	tx.parentOrder = append(tx.parentOrder, key)
+}
+
+// staged returns the rows the transaction has written that match.
+func (tx *txStorer) staged(match func(*row) bool) []storage.Row {
+	rows := []storage.Row{}
+	for _, key := range tx.order {
+		if after := tx.writes[key].after; after != nil && match(after) {
+			rows = append(rows, copyRow(after))
+		}
+	}
+	return rows
+}
+
+// unstaged reports whether a row the table holds is one the transaction
+// hasn't written, so that it's read as it is.
+func (tx *txStorer) unstaged(r storage.Row) bool {
+	_, ok := tx.writes[cacheKey{r.Type(), r.ID()}]
+	return !ok
+}
+
+func (tx *txStorer) GetRowByID(ctx context.Context, rowType, rowID string) (storage.Row, error) {
+	if w, ok := tx.writes[cacheKey{rowType, rowID}]; ok {
+		if w.after == nil {
+			return nil, fmt.Errorf("%w: %q", ErrNotFoundRow, rowID)
+		}
+		return copyRow(w.after), nil
+	}
+	// read past the cache, so that the writes' conditions hold
+	return tx.client.getRowByID(ctx, rowType, rowID)
+}
+
+func (tx *txStorer) GetRow(ctx context.Context, rowType, rowLabel string) (storage.Row, error) {
+	found := tx.staged(func(r *row) bool {
+		return r.RowType == rowType && r.RowLabel == rowLabel
+	})
+	r, err := tx.client.getRow(ctx, rowType, rowLabel)
+	switch {
+	case err == nil && tx.unstaged(r):
+		found = append(found, r)
+	case err != nil && !errors.Is(err, ErrNotFoundRow):
+		return nil, err
+	}
+	switch {
+	case len(found) == 0:
+		return nil, fmt.Errorf("%w: type %q and label %q", ErrNotFoundRow, rowType, rowLabel)
+	case len(found) > 1:
+		return nil, fmt.Errorf("%w: type %q and label %q", ErrTooManyFound, rowType, rowLabel)
+	}
+	return found[0], nil
+}
+
+func (tx *txStorer) GetChild(ctx context.Context, childLabel, parentID string) (storage.Row, error) {
+	staged := tx.staged(func(r *row) bool {
+		return r.RowParentID == parentID && r.RowLabel == childLabel
+	})
+	if len(staged) > 0 {
+		return staged[0], nil
+	}
+	r, err := tx.client.getChild(ctx, childLabel, parentID)
+	if err != nil {
+		return nil, err
+	}
+	if !tx.unstaged(r) {
+		return nil, fmt.Errorf("%w: label %q and parent %q", ErrNotFoundRow, childLabel, parentID)
+	}
+	return r, nil
+}
+
+func (tx *txStorer) ListRows(ctx context.Context, rowType, labelFilter, parentIDFilter string) ([]storage.Row, error) {
+	listed, err := tx.client.ListRows(ctx, rowType, labelFilter, parentIDFilter)
+	if err != nil {
+		return nil, err
+	}
+	rows := []storage.Row{}
+	for _, r := range listed {
+		if tx.unstaged(r) {
+			rows = append(rows, r)
+		}
+	}
+	return append(rows, tx.staged(func(r *row) bool {
+		return r.RowType == rowType &&
+			(labelFilter == "" || strings.Contains(r.RowLabel, labelFilter)) &&
+			(parentIDFilter == "" || r.RowParentID == parentIDFilter)
+	})...), nil
+}
+
+func (tx *txStorer) ScanRows(ctx context.Context, fn func(storage.Row) error) error {
+	err := tx.client.ScanRows(ctx, func(r storage.Row) error {
+		if !tx.unstaged(r) {
+			return nil
+		}
+		return fn(r)
+	})
+	if err != nil {
+		return err
+	}
+	for _, r := range tx.staged(func(*row) bool { return true }) {
+		if err := fn(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ScanChildren queries the ByParentAndLabel index for the row's children, as
+// Client.ScanChildren does, and reads the transaction's writes over them.
+func (tx *txStorer) ScanChildren(ctx context.Context, parentID string, fn func(storage.Row) error) error {
+	err := tx.client.ScanChildren(ctx, parentID, func(r storage.Row) error {
+		if !tx.unstaged(r) {
+			return nil
+		}
+		return fn(r)
+	})
+	if err != nil {
+		return err
+	}
+	for _, r := range tx.staged(func(r *row) bool { return r.RowParentID == parentID }) {
+		if err := fn(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (tx *txStorer) CreateRow(ctx context.Context, rowType, rowLabel string) (storage.Row, error) {
+	_, err := tx.GetRow(ctx, rowType, rowLabel)
+	if err == nil || errors.Is(err, ErrTooManyFound) {
+		return nil, ErrCollisionTypeLabel
+	}
+	if !errors.Is(err, ErrNotFoundRow) {
+		return nil, err
+	}
+	created := &row{RowType: rowType, RowID: slug.Generate(rowType), RowLabel: rowLabel}
+	tflog.SubsystemTrace(ctx, storage.LogSubsystemSlug, fmt.Sprintf("Generated ID %q", created.RowID))
+	tx.stage(cacheKey{rowType, created.RowID}, nil, created)
+	return copyRow(created), nil
+}
+
+func (tx *txStorer) CreateChild(ctx context.Context, rowType, rowLabel, parentType, parentID string, columns map[string]interface{}) (storage.Row, error) {
+	parent, err := tx.GetRowByID(ctx, parentType, parentID)
+	if err != nil {
+		return nil, err
+	}
+	tx.checkParent(parent)
+	if err := tx.childLabelFree(ctx, rowLabel, parentID); err != nil {
+		return nil, err
+	}
+	created := copyRow(&row{RowType: rowType, RowID: slug.Generate(rowType), RowLabel: rowLabel, RowParentID: parentID, RowColumns: columns})
+	tflog.SubsystemTrace(ctx, storage.LogSubsystemSlug, fmt.Sprintf("Generated ID %q", created.RowID))
+	tx.stage(cacheKey{rowType, created.RowID}, nil, created)
+	return copyRow(created), nil
+}
+
+// childLabelFree returns ErrCollisionParentLabel if a child of the parent has
+// the label.
+func (tx *txStorer) childLabelFree(ctx context.Context, label, parentID string) error {
+	_, err := tx.GetChild(ctx, label, parentID)
+	if err == nil {
+		return ErrCollisionParentLabel
+	}
+	if !errors.Is(err, ErrNotFoundRow) {
+		return err
+	}
+	return nil
+}
+
+// change stages a change to the row with the type and ID.
+func (tx *txStorer) change(ctx context.Context, rowType, rowID string, change func(*row)) (storage.Row, error) {
+	this, err := tx.GetRowByID(ctx, rowType, rowID)
+	if err != nil {
+		return nil, err
+	}
+	changed := copyRow(this)
+	change(changed)
+	tx.stage(cacheKey{rowType, rowID}, this, changed)
+	return copyRow(changed), nil
+}
+
+func (tx *txStorer) UpdateRow(ctx context.Context, rowType, rowID, newLabel string) (storage.Row, error) {
+	this, err := tx.GetRowByID(ctx, rowType, rowID)
+	if err != nil {
+		return nil, err
+	}
+	if this.Label() == newLabel {
+		return this, nil
+	}
+	if this.ParentID() != "" {
+		if err := tx.childLabelFree(ctx, newLabel, this.ParentID()); err != nil {
+			return nil, err
+		}
+	} else if len(tx.staged(func(r *row) bool {
+		return r.RowType == rowType && r.RowParentID == "" && r.RowLabel == newLabel
+	})) > 0 {
+		// the table's rows are checked by the label's claim
+		return nil, ErrCollisionTypeLabel
+	}
+	return tx.change(ctx, rowType, rowID, func(r *row) { r.RowLabel = newLabel })
+}
+
+func (tx *txStorer) UpdateChild(ctx context.Context, childType, childID, newChildLabel, parentType, newParentID string) (storage.Row, error) {
+	parent, err := tx.GetRowByID(ctx, parentType, newParentID)
+	if err != nil {
+		return nil, err
+	}
+	tx.checkParent(parent)
+	if err := tx.childLabelFree(ctx, newChildLabel, newParentID); err != nil {
+		return nil, err
+	}
+	return tx.change(ctx, childType, childID, func(r *row) {
+		r.RowLabel = newChildLabel
+		r.RowParentID = newParentID
+	})
+}
+
+func (tx *txStorer) UpdateColumn(ctx context.Context, rowType, rowID, columnName string, columnValue interface{}) error {
+	_, err := tx.change(ctx, rowType, rowID, func(r *row) {
+		if r.RowColumns == nil {
+			r.RowColumns = map[string]interface{}{}
+		}
+		r.RowColumns[columnName] = columnValue
+	})
+	return err
+}
+
+func (tx *txStorer) UpdateColumns(ctx context.Context, rowType, rowID string, columns map[string]interface{}) error {
+	_, err := tx.change(ctx, rowType, rowID, func(r *row) {
+		r.RowColumns = make(map[string]interface{}, len(columns))
+		for name, value := range columns {
+			r.RowColumns[name] = value
+		}
+	})
+	return err
+}
+
+func (tx *txStorer) DeleteColumn(ctx context.Context, rowType, rowID, columnName string) error {
+	_, err := tx.change(ctx, rowType, rowID, func(r *row) { delete(r.RowColumns, columnName) })
+	return err
+}
+
+func (tx *txStorer) DeleteRow(ctx context.Context, rowType, childType, rowID string) error {
+	if childType != "" {
+		children, err := tx.ListRows(ctx, childType, "", rowID)
+		if err != nil {
+			return err
+		}
+		if len(children) > 0 {
+			return fmt.Errorf("%s %s has children: %w", rowType, rowID, ErrCannotDeleteRow)
+		}
+	}
+	this, err := tx.GetRowByID(ctx, rowType, rowID)
+	if err != nil {
+		return err
+	}
+	tx.stage(cacheKey{rowType, rowID}, this, nil)
+	return nil
+}
+
+func (tx *txStorer) PutRow(ctx context.Context, r storage.Row) error {
+	key := cacheKey{r.Type(), r.ID()}
+	tx.stage(key, nil, copyRow(r))
+	tx.writes[key].put = true
+	return nil
+}
+
+// commit makes the transaction's writes, taking over the label claims of
+// rows that no longer hold their labels, as writeClaimingLabel does.
+func (tx *txStorer) commit(ctx context.Context) error {
+	if len(tx.order) == 0 {
+		return nil
+	}
+	if err := tx.client.ensureTable(ctx); err != nil {
+		return err
+	}
+	defer func() {
+		for _, key := range tx.order {
+			tx.client.cache.Invalidate(key.rowType, key.key)
+		}
+	}()
+
+	staleOwners := map[cacheKey]string{}
+	for claim := 0; claim < maxLabelClaims; claim++ {
+		items, claims, err := tx.items(staleOwners)
+		if err != nil {
+			return err
+		}
+		tflog.SubsystemDebug(ctx, storage.LogSubsystemDynamoDB, fmt.Sprintf("TransactWriteItems %d", len(items)))
+		_, err = tx.client.ddb.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+			TransactItems: items,
+		})
+		if err == nil {
+			return nil
+		}
+		stale := false
+		for index, guard := range claims {
+			owner, failed := conditionFailed(err, index)
+			if !failed || owner == "" {
+				continue
+			}
+			holds, err := tx.client.holdsLabel(ctx, guard.rowType, owner, guard.key)
+			if err != nil {
+				return err
+			}
+			if holds {
+				return ErrCollisionTypeLabel
+			}
+			tflog.SubsystemDebug(ctx, storage.LogSubsystemDynamoDB, fmt.Sprintf("taking over the stale claim of %q on %s label %q", owner, guard.rowType, guard.key))
+			staleOwners[guard] = owner
+			stale = true
+		}
+		if !stale {
+			if errors.Is(err, storage.ErrConflict) {
+				return fmt.Errorf("%w: a row the transaction writes, or a parent it writes under, was changed meanwhile", err)
+			}
+			return err
+		}
+	}
+	return fmt.Errorf("%w: the claims on the transaction's labels kept changing", storage.ErrConflict)
+}
+
+// items returns the transaction's writes and checks, and the root row
+// labels, by type and label, that the writes at their indexes claim.
+func (tx *txStorer) items(staleOwners map[cacheKey]string) ([]types.TransactWriteItem, map[int]cacheKey, error) {
+	client := tx.client
+	items := []types.TransactWriteItem{}
+	// the root rows holding each label once the transaction is made, and
+	// those holding them before
+	holders := map[cacheKey]string{}
+	released := map[cacheKey]string{}
+	for _, key := range tx.order {
+		w := tx.writes[key]
+		if w.before != nil && w.before.ParentID() == "" && (w.after == nil || w.after.RowParentID != "" || w.after.RowLabel != w.before.Label()) {
+			released[cacheKey{key.rowType, w.before.Label()}] = key.key
+		}
+		if w.after != nil && w.after.RowParentID == "" {
+			holders[cacheKey{key.rowType, w.after.RowLabel}] = key.key
+		}
+
+		itemKey := map[string]types.AttributeValue{
+			storageKeyType: &types.AttributeValueMemberS{Value: client.itemTypeKey(key.rowType, key.key)},
+			storageKeyID:   &types.AttributeValueMemberS{Value: key.key},
+		}
+		switch {
+		case w.after == nil && w.before == nil:
+			// created and deleted again
+		case w.after == nil:
+			condition, names, values, err := client.unchanged(w.before)
+			if err != nil {
+				return nil, nil, err
+			}
+			items = append(items, types.TransactWriteItem{Delete: &types.Delete{
+				TableName:                 aws.String(client.tableName),
+				Key:                       itemKey,
+				ConditionExpression:       aws.String(condition),
+				ExpressionAttributeNames:  names,
+				ExpressionAttributeValues: values,
+			}})
+		default:
+			item, err := client.rowToItem(w.after)
+			if err != nil {
+				return nil, nil, err
+			}
+			put := &types.Put{TableName: aws.String(client.tableName), Item: item}
+			switch {
+			case w.put:
+			case w.before == nil:
+				put.ExpressionAttributeNames = map[string]string{"#type": storageKeyType, "#id": storageKeyID}
+				put.ConditionExpression = aws.String("attribute_not_exists(#type) AND attribute_not_exists(#id)")
+			default:
+				condition, names, values, err := client.unchanged(w.before)
+				if err != nil {
+					return nil, nil, err
+				}
+				put.ConditionExpression = aws.String(condition)
+				put.ExpressionAttributeNames = names
+				put.ExpressionAttributeValues = values
+			}
+			items = append(items, types.TransactWriteItem{Put: put})
+		}
+	}
+
+	// one write per guard, since a transaction may write an item only once
+	claims := map[int]cacheKey{}
+	for _, key := range tx.order {
+		w := tx.writes[key]
+		if w.after == nil || w.after.RowParentID != "" {
+			continue
+		}
+		guard := cacheKey{key.rowType, w.after.RowLabel}
+		if holders[guard] != key.key {
+			continue
+		}
+		if w.put {
+			// the row takes its label, from whichever row claimed it
+			items = append(items, types.TransactWriteItem{Put: &types.Put{
+				TableName: aws.String(client.tableName),
+				Item:      client.labelGuard(key.rowType, w.after.RowLabel, key.key),
+			}})
+			continue
+		}
+		if w.before != nil && w.before.ParentID() == "" && w.before.Label() == w.after.RowLabel {
+			// its label is already its own
+			continue
+		}
+		staleOwner := staleOwners[guard]
+		if owner, ok := released[guard]; ok && owner != key.key {
+			// another row of the transaction gives it up
+			staleOwner = owner
+		}
+		claims[len(items)] = guard
+		items = append(items, client.claimLabel(key.rowType, w.after.RowLabel, key.key, staleOwner))
+	}
+	for guard, owner := range released {
+		if _, ok := holders[guard]; !ok {
+			items = append(items, client.releaseLabel(guard.rowType, guard.key, owner))
+		}
+	}
+
+	// the parents the transaction writes are checked by their writes
+	for _, key := range tx.parentOrder {
+		if _, ok := tx.writes[key]; ok {
+			continue
+		}
+		items = append(items, client.checkParent(tx.parents[key]))
+	}
+
+	if len(items) > transactWriteSize {
+		return nil, nil, fmt.Errorf("%w: %d writes, of at most %d", storage.ErrTxTooLarge, len(items), transactWriteSize)
+	}
+	return items, claims, nil
+}
+
+// unchanged returns the condition of a write that the row's item is as the
+// transaction read it: its label, its parent, and its columns, in whichever
+// form they're stored, so that another writer's change isn't overwritten. A
+// row without columns may have none stored, or an empty map or document of
+// them.
+func (client *Client) unchanged(before storage.Row) (string, map[string]string, map[string]types.AttributeValue, error) {
+	names := map[string]string{
+		"#id":           storageKeyID,
+		"#label":        storageAttrLabel,
+		"#parent_id":    storageAttrParentID,
+		"#columns":      storageAttrColumns,
+		"#columns_json": storageAttrColumnsJSON,
+	}
+	values := map[string]types.AttributeValue{":old_label": &types.AttributeValueMemberS{Value: before.Label()}}
+	same := "attribute_exists(#id) AND #label = :old_label AND attribute_not_exists(#parent_id)"
+	if before.ParentID() != "" {
+		values[":old_parent_id"] = &types.AttributeValueMemberS{Value: client.parentKey(before.ParentID())}
+		same = "attribute_exists(#id) AND #label = :old_label AND #parent_id = :old_parent_id"
+	}
+
+	encoded, err := encodeColumns(before.Columns())
+	if err != nil {
+		return "", nil, nil, err
+	}
+	values[":old_columns"] = &types.AttributeValueMemberM{Value: columnsToMap(before.Columns())}
+	values[":old_columns_json"] = encoded
+	alternatives := []string{
+		same + " AND #columns = :old_columns AND attribute_not_exists(#columns_json)",
+		same + " AND #columns_json = :old_columns_json AND attribute_not_exists(#columns)",
+	}
+	if len(before.Columns()) == 0 {
+		alternatives = append(alternatives, same+" AND attribute_not_exists(#columns) AND attribute_not_exists(#columns_json)")
+	}
+	// AND binds more tightly than OR
+	return strings.Join(alternatives, " OR "), names, values, nil
+}
+
+// checkParent returns a check that the parent, as read, still exists under
+// the same parent, so that a row isn't created or moved under a parent that
+// was deleted or moved meanwhile, such as under the row itself.
+func (client *Client) checkParent(parent storage.Row) types.TransactWriteItem {
+	check := &types.ConditionCheck{
+		TableName: aws.String(client.tableName),
+		Key: map[string]types.AttributeValue{
+			storageKeyType: &types.AttributeValueMemberS{Value: client.itemTypeKey(parent.Type(), parent.ID())},
+			storageKeyID:   &types.AttributeValueMemberS{Value: parent.ID()},
+		},
+		ExpressionAttributeNames: map[string]string{"#id": storageKeyID, "#parent_id": storageAttrParentID},
+		ConditionExpression:      aws.String("attribute_exists(#id) AND attribute_not_exists(#parent_id)"),
+	}
+	if parent.ParentID() != "" {
+		check.ExpressionAttributeValues = map[string]types.AttributeValue{":parent_id": &types.AttributeValueMemberS{Value: client.parentKey(parent.ParentID())}}
+		check.ConditionExpression = aws.String("attribute_exists(#id) AND #parent_id = :parent_id")
+	}
+	return types.TransactWriteItem{ConditionCheck: check}
+}
@@ -0,0 +1,142 @@
+package dynamodb_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+func TestWithinTxRollsBack(t *testing.T) {
+	ctx := context.Background()
+	f := newFake(t)
+	client, table := f.newClient(t)
+	errFailed := errors.New("failed")
+
+	err := client.WithinTx(ctx, func(tx storage.RowStorer) error {
+		org, err := tx.CreateRow(ctx, "org", "acme")
+		if err != nil {
+			return err
+		}
+		if _, err := tx.CreateChild(ctx, "team", "dev", "org", org.ID(), nil); err != nil {
+			return err
+		}
+		return errFailed
+	})
+	if !errors.Is(err, errFailed) {
+		t.Fatalf("WithinTx returned %v, not fn's error", err)
+	}
+	if items := f.items(table); len(items) != 0 {
+		t.Errorf("a failed transaction wrote %v", items)
+	}
+}
+
+func TestWithinTxCommits(t *testing.T) {
+	ctx := context.Background()
+	f := newFake(t)
+	client, table := f.newClient(t)
+
+	var orgID, teamID string
+	err := client.WithinTx(ctx, func(tx storage.RowStorer) error {
+		org, err := tx.CreateRow(ctx, "org", "acme")
+		if err != nil {
+			return err
+		}
+		team, err := tx.CreateChild(ctx, "team", "dev", "org", org.ID(), nil)
+		if err != nil {
+			return err
+		}
+		orgID, teamID = org.ID(), team.ID()
+		// nothing is written until fn returns
+		if items := f.items(table); len(items) != 0 {
+			t.Errorf("the transaction wrote %v before committing", items)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WithinTx: %s", err)
+	}
+	if f.item(table, "org", orgID) == nil || f.item(table, "team", teamID) == nil {
+		t.Errorf("the transaction didn't write its rows: %v", f.items(table))
+	}
+	checkGuard(t, f, table, "org#label", "acme", orgID)
+}
+
+func TestWithinTxConflict(t *testing.T) {
+	ctx := context.Background()
+	f := newFake(t)
+	client, table := f.newClient(t)
+	org, err := client.CreateRow(ctx, "org", "acme")
+	if err != nil {
+		t.Fatalf("CreateRow: %s", err)
+	}
+
+	err = client.WithinTx(ctx, func(tx storage.RowStorer) error {
+		if err := tx.UpdateColumn(ctx, "org", org.ID(), "owner", "ops"); err != nil {
+			return err
+		}
+		// another writer relabels the row after the transaction read it
+		changed := f.item(table, "org", org.ID())
+		changed["label"] = s("umbrella")
+		f.put(table, changed)
+		return nil
+	})
+	if !errors.Is(err, storage.ErrConflict) {
+		t.Fatalf("WithinTx returned %v, not %s", err, storage.ErrConflict)
+	}
+	if item := f.item(table, "org", org.ID()); item["columns"] != nil {
+		t.Errorf("the conflicting transaction wrote the column: %v", item)
+	}
+}
+
+func TestWithinTxParentChanged(t *testing.T) {
+	ctx := context.Background()
+	f := newFake(t)
+	client, table := f.newClient(t)
+	org, err := client.CreateRow(ctx, "org", "acme")
+	if err != nil {
+		t.Fatalf("CreateRow: %s", err)
+	}
+	other, err := client.CreateRow(ctx, "org", "umbrella")
+	if err != nil {
+		t.Fatalf("CreateRow: %s", err)
+	}
+
+	for name, change := range map[string]func(team fakeItem){
+		"moved": func(team fakeItem) {
+			team["parent_id"] = s(other.ID())
+			f.put(table, team)
+		},
+		"deleted": func(team fakeItem) {
+			f.mu.Lock()
+			defer f.mu.Unlock()
+			delete(f.tables[table].items, itemKey(team))
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			team, err := client.CreateChild(ctx, "team", name, "org", org.ID(), nil)
+			if err != nil {
+				t.Fatalf("CreateChild: %s", err)
+			}
+			var envID string
+			err = client.WithinTx(ctx, func(tx storage.RowStorer) error {
+				env, err := tx.CreateChild(ctx, "env", "prod", "team", team.ID(), nil)
+				if err != nil {
+					return err
+				}
+				envID = env.ID()
+				// another writer changes the parent after the transaction
+				// read it
+				change(f.item(table, "team", team.ID()))
+				return nil
+			})
+			if !errors.Is(err, storage.ErrConflict) {
+				t.Fatalf("WithinTx returned %v, not %s", err, storage.ErrConflict)
+			}
+			if item := f.item(table, "env", envID); item != nil {
+				t.Errorf("the transaction created a row under a parent %s meanwhile: %v", name, item)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package dynamodb
+
+import (
+	"context"
+	"sync"
+
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/smithy-go/middleware"
+)
+
+// usageMiddlewareID names the usage middleware in the client's stack.
+const usageMiddlewareID = "TreeUsage"
+
+// Usage totals the DynamoDB API calls of the clients given it with WithUsage:
+// how many there were, how many attempts were retries, and the capacity they
+// consumed, which is what DynamoDB bills for.
+type Usage struct {
+	mu       sync.Mutex
+	calls    map[string]int
+	retries  int
+	capacity float64
+}
+
+// UsageReport is what a Usage has totalled.
+type UsageReport struct {
+	// Calls counts API calls by operation, such as "Query".
+	Calls map[string]int `json:"calls"`
+	// Retries is the number of attempts beyond the first.
+	Retries int `json:"retries"`
+	// ConsumedCapacity is the capacity units the calls consumed.
+	ConsumedCapacity float64 `json:"consumed_capacity"`
+}
+
+func NewUsage() *Usage {
+	return &Usage{calls: map[string]int{}}
+}
+
+// WithUsage totals the client's API calls in usage, which may be shared by
+// several clients.
+func WithUsage(usage *Usage) Option {
+	return func(o *options) { o.usage = usage }
+}
+
+// Report returns the totals so far.
+func (usage *Usage) Report() UsageReport {
+	usage.mu.Lock()
+	defer usage.mu.Unlock()
+	report := UsageReport{
+		Calls:            map[string]int{},
+		Retries:          usage.retries,
+		ConsumedCapacity: usage.capacity,
+	}
+	for op, count := range usage.calls {
+		report.Calls[op] = count
+	}
+	return report
+}
+
+func (usage *Usage) middleware(stack *middleware.Stack) error {
+	return stack.Initialize.Add(middleware.InitializeMiddlewareFunc(usageMiddlewareID, func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+		returnConsumedCapacity(in.Parameters)
+		out, metadata, err := next.HandleInitialize(ctx, in)
+		retries := 0
+		if attempts, ok := retry.GetAttemptResults(metadata); ok && len(attempts.Results) > 1 {
+			retries = len(attempts.Results) - 1
+		}
+		usage.mu.Lock()
+		defer usage.mu.Unlock()
+		usage.calls[awsmiddleware.GetOperationName(ctx)]++
+		usage.retries += retries
+		usage.capacity += consumedCapacity(out.Result)
+		return out, metadata, err
+	}), middleware.After)
+}
@@ -0,0 +1,72 @@
+package dynamodb
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+// WarmCache reads every row of the types, and every row under the rows with
+// the subtrees' IDs, into the read cache, a page at a time, so that a plan
+// reading hundreds of them finds them in memory rather than calling DynamoDB
+// for each. Root rows of the types are also cached by label, unless another
+// root row of the type shares it. It returns how many rows it read, which
+// are more than the cache keeps if it's too small for them: the first read
+// are evicted first. It needs WithReadCache, and without it does nothing.
+func (client *Client) WarmCache(ctx context.Context, rowTypes, subtrees []string) (int, error) {
+	if client.cache == nil {
+		return 0, nil
+	}
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemDynamoDB, fmt.Sprintf("WarmCache %q %q", rowTypes, subtrees))
+	read := 0
+	for _, rowType := range rowTypes {
+		rows, err := client.IterRows(ctx, rowType, "", "").All()
+		if err != nil {
+			return read, err
+		}
+		labels := map[string]int{}
+		for _, r := range rows {
+			if r.ParentID() == "" {
+				labels[r.Label()]++
+			}
+		}
+		for _, r := range rows {
+			if r.ParentID() == "" && labels[r.Label()] == 1 {
+				client.cache.PutLabeled(r)
+			} else {
+				client.cache.Put(r)
+			}
+		}
+		read += len(rows)
+	}
+
+	seen := map[string]bool{}
+	level := subtrees
+	for len(level) > 0 {
+		next := []string{}
+		for _, parentID := range level {
+			if seen[parentID] {
+				// a parent cycle, or a subtree within another
+				continue
+			}
+			seen[parentID] = true
+			err := client.ScanChildren(ctx, parentID, func(child storage.Row) error {
+				client.cache.Put(child)
+				read++
+				next = append(next, child.ID())
+				return nil
+			})
+			if err != nil {
+				return read, err
+			}
+		}
+		level = next
+	}
+
+	if read > client.cache.Size() {
+		tflog.SubsystemWarn(ctx, storage.LogSubsystemDynamoDB, fmt.Sprintf("warming the read cache read %d rows, but it keeps %d", read, client.cache.Size()))
+	}
+	return read, nil
+}
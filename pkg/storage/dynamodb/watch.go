@@ -0,0 +1,209 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodbstreams"
+	streamstypes "github.com/aws/aws-sdk-go-v2/service/dynamodbstreams/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+var ErrNoStream = errors.New("the table has no change stream")
+
+const (
+	// watchPollInterval is how long Watch waits between reads of each shard.
+	// DynamoDB allows about five reads a second per shard, shared by every
+	// reader.
+	watchPollInterval = time.Second
+	// watchShardRefresh is how often Watch looks for new shards, which
+	// DynamoDB creates as the table's partitions split and roll over.
+	watchShardRefresh = 30 * time.Second
+)
+
+// Watch implements storage.Watcher by reading the table's DynamoDB stream,
+// which VerifySchema can enable. It returns nil once ctx is done.
+func (client *Client) Watch(ctx context.Context, fn func(storage.Change) error) error {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemDynamoDB, fmt.Sprintf("Watch %q", client.tableName))
+	table, err := client.ddb.DescribeTable(ctx, &dynamodb.DescribeTableInput{
+		TableName: aws.String(client.tableName),
+	})
+	if err != nil {
+		return err
+	}
+	streamARN := table.Table.LatestStreamArn
+	if streamARN == nil {
+		return fmt.Errorf("%w: %s", ErrNoStream, client.tableName)
+	}
+
+	// the iterator of each shard being read; shards that were open when Watch
+	// started are read from their latest record, and later ones from their
+	// first
+	iterators := map[string]*string{}
+	known := map[string]bool{}
+	refresh := func(iteratorType streamstypes.ShardIteratorType) error {
+		shards, err := client.streamShards(ctx, streamARN)
+		if err != nil {
+			return err
+		}
+		for _, shard := range shards {
+			id := aws.ToString(shard.ShardId)
+			if known[id] {
+				continue
+			}
+			if _, reading := iterators[aws.ToString(shard.ParentShardId)]; reading {
+				// read a shard only after its parent, to keep each row's
+				// changes in order
+				continue
+			}
+			known[id] = true
+			if iteratorType == streamstypes.ShardIteratorTypeLatest && shard.SequenceNumberRange != nil && shard.SequenceNumberRange.EndingSequenceNumber != nil {
+				// closed before Watch started
+				continue
+			}
+			output, err := client.streams.GetShardIterator(ctx, &dynamodbstreams.GetShardIteratorInput{
+				StreamArn:         streamARN,
+				ShardId:           shard.ShardId,
+				ShardIteratorType: iteratorType,
+			})
+			if err != nil {
+				return err
+			}
+			iterators[id] = output.ShardIterator
+		}
+		return nil
+	}
+	if err := refresh(streamstypes.ShardIteratorTypeLatest); err != nil {
+		return err
+	}
+
+	lastRefresh := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(watchPollInterval):
+		}
+
+		for id, iterator := range iterators {
+			output, err := client.streams.GetRecords(ctx, &dynamodbstreams.GetRecordsInput{ShardIterator: iterator})
+			if errors.Is(ctx.Err(), context.Canceled) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			for _, record := range output.Records {
+				if !client.recordInTenant(record) || isLabelGuardRecord(record) {
+					continue
+				}
+				change, err := client.streamChange(record)
+				if err != nil {
+					return err
+				}
+				if err := fn(change); err != nil {
+					return err
+				}
+			}
+			if output.NextShardIterator == nil {
+				// the shard is closed, and its children can be read
+				delete(iterators, id)
+				lastRefresh = time.Time{}
+				continue
+			}
+			iterators[id] = output.NextShardIterator
+		}
+
+		if time.Since(lastRefresh) > watchShardRefresh {
+			if err := refresh(streamstypes.ShardIteratorTypeTrimHorizon); err != nil {
+				return err
+			}
+			lastRefresh = time.Now()
+		}
+	}
+}
+
+func (client *Client) streamShards(ctx context.Context, streamARN *string) ([]streamstypes.Shard, error) {
+	shards := []streamstypes.Shard{}
+	var start *string
+	for {
+		output, err := client.streams.DescribeStream(ctx, &dynamodbstreams.DescribeStreamInput{
+			StreamArn:             streamARN,
+			ExclusiveStartShardId: start,
+		})
+		if err != nil {
+			return nil, err
+		}
+		shards = append(shards, output.StreamDescription.Shards...)
+		start = output.StreamDescription.LastEvaluatedShardId
+		if start == nil {
+			return shards, nil
+		}
+	}
+}
+
+// recordInTenant reports whether a stream record is of a row in the client's
+// tenant.
+func (client *Client) recordInTenant(record streamstypes.Record) bool {
+	if client.tenant == "" || record.Dynamodb == nil {
+		return true
+	}
+	rowType, ok := record.Dynamodb.Keys[storageKeyType].(*streamstypes.AttributeValueMemberS)
+	return ok && strings.HasPrefix(rowType.Value, client.tenant+tenantSeparator)
+}
+
+// isLabelGuardRecord reports whether a stream record is of a label guard,
+// which isn't a row.
+func isLabelGuardRecord(record streamstypes.Record) bool {
+	if record.Dynamodb == nil {
+		return false
+	}
+	rowType, ok := record.Dynamodb.Keys[storageKeyType].(*streamstypes.AttributeValueMemberS)
+	return ok && strings.HasSuffix(rowType.Value, labelGuardSuffix)
+}
+
+func (client *Client) streamChange(record streamstypes.Record) (storage.Change, error) {
+	change := storage.Change{}
+	switch record.EventName {
+	case streamstypes.OperationTypeInsert:
+		change.Kind = storage.ChangeCreated
+	case streamstypes.OperationTypeModify:
+		change.Kind = storage.ChangeUpdated
+	case streamstypes.OperationTypeRemove:
+		change.Kind = storage.ChangeDeleted
+	}
+	if record.Dynamodb == nil {
+		return change, nil
+	}
+	change.Time = aws.ToTime(record.Dynamodb.ApproximateCreationDateTime)
+	if len(record.Dynamodb.OldImage) > 0 {
+		oldRow, err := client.streamImageToRow(record.Dynamodb.OldImage)
+		if err != nil {
+			return change, err
+		}
+		change.Old = oldRow
+	}
+	if len(record.Dynamodb.NewImage) > 0 {
+		newRow, err := client.streamImageToRow(record.Dynamodb.NewImage)
+		if err != nil {
+			return change, err
+		}
+		change.New = newRow
+	}
+	return change, nil
+}
+
+func (client *Client) streamImageToRow(image map[string]streamstypes.AttributeValue) (*row, error) {
+	item, err := attributevalue.FromDynamoDBStreamsMap(image)
+	if err != nil {
+		return nil, err
+	}
+	return client.itemToRow(item)
+}
@@ -0,0 +1,21 @@
+package encryption_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/encryption"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/memory"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/storagetest"
+)
+
+func TestConformance(t *testing.T) {
+	keys, err := encryption.NewLocalKeys(bytes.Repeat([]byte{1}, 32))
+	if err != nil {
+		t.Fatalf("NewLocalKeys: %s", err)
+	}
+	storagetest.Run(t, func(t *testing.T) storage.RowStorer {
+		return encryption.NewStorer(memory.NewClient(), keys, []string{"owner", "tier"})
+	})
+}
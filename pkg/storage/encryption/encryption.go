@@ -0,0 +1,482 @@
+// Package encryption wraps a storage.RowStorer to encrypt designated columns
+// before they are written, and decrypt them after they are read, so that
+// secrets in columns are protected even from those who can read the table.
+//
+// Values are encrypted with AES-256-GCM under data keys that a KeyService,
+// such as KMS, generates and wraps: envelope encryption. Each value is stored
+// as a string holding the wrapped data key, the nonce, and the ciphertext, so
+// it can be decrypted by anyone allowed to unwrap the key, and by no one else.
+package encryption
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+// prefix marks an encrypted value.
+const prefix = "tree-enc:v1:"
+
+var ErrDecrypt = errors.New("cannot decrypt column")
+
+// KeyService generates data keys, and unwraps them.
+type KeyService interface {
+	// GenerateDataKey returns a new 256-bit data key, both in plain and
+	// wrapped.
+	GenerateDataKey(ctx context.Context) (plain, wrapped []byte, err error)
+	// Decrypt unwraps a data key GenerateDataKey wrapped.
+	Decrypt(ctx context.Context, wrapped []byte) ([]byte, error)
+}
+
+// envelope is an encrypted value, as stored.
+type envelope struct {
+	// Key is the wrapped data key.
+	Key []byte `json:"k"`
+	// Nonce is the AES-GCM nonce.
+	Nonce []byte `json:"n"`
+	// Ciphertext is the sealed JSON encoding of the value.
+	Ciphertext []byte `json:"c"`
+	// Set is whether the value is a string set, rather than a string.
+	Set bool `json:"s,omitempty"`
+}
+
+// Storer is a storage.RowStorer that encrypts some columns of the rows it
+// writes to the one it wraps, and decrypts them in the rows it reads.
+type Storer struct {
+	next    storage.RowStorer
+	columns map[string]bool
+	keys    *keyring
+}
+
+// keyring holds the data keys of a Storer, and of the storers of its
+// transactions.
+type keyring struct {
+	service KeyService
+
+	mu sync.Mutex
+	// key is the data key new values are encrypted under, generated on first
+	// use
+	key *dataKey
+	// unwrapped caches the data keys of values read, by wrapped key
+	unwrapped map[string]cipher.AEAD
+}
+
+type dataKey struct {
+	aead    cipher.AEAD
+	wrapped []byte
+}
+
+var (
+	_ storage.RowStorer     = &Storer{}
+	_ storage.Transactor    = &Storer{}
+	_ storage.ColumnDeleter = &Storer{}
+	_ storage.BatchGetter   = &Storer{}
+	_ storage.ColumnQuerier = &Storer{}
+	_ storage.ChildScanner  = &Storer{}
+	_ storage.BatchPutter   = &Storer{}
+	_ storage.ColumnPatcher = &Storer{}
+	_ storage.RowIterator   = &Storer{}
+)
+
+// NewStorer wraps next, encrypting the named columns, of any row type, with
+// data keys from keys.
+func NewStorer(next storage.RowStorer, keys KeyService, columns []string) *Storer {
+	client := &Storer{
+		next:    next,
+		columns: map[string]bool{},
+		keys:    &keyring{service: keys, unwrapped: map[string]cipher.AEAD{}},
+	}
+	for _, column := range columns {
+		client.columns[column] = true
+	}
+	return client
+}
+
+// Unwrap returns the wrapped storer.
+func (client *Storer) Unwrap() storage.RowStorer {
+	return client.next
+}
+
+// IsEncrypted reports whether a stored value is encrypted.
+func IsEncrypted(value interface{}) bool {
+	s, ok := value.(string)
+	return ok && strings.HasPrefix(s, prefix)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// additionalData binds a value to its row type and column, so that it can't
+// be moved to another undetected. Rows' IDs aren't known until the backend
+// creates them, so they can't be bound.
+func additionalData(rowType, column string) []byte {
+	return []byte(rowType + "\x00" + column)
+}
+
+func (client *Storer) dataKey(ctx context.Context) (*dataKey, error) {
+	client.keys.mu.Lock()
+	defer client.keys.mu.Unlock()
+	if client.keys.key != nil {
+		return client.keys.key, nil
+	}
+	plain, wrapped, err := client.keys.service.GenerateDataKey(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("generating a data key: %w", err)
+	}
+	aead, err := newAEAD(plain)
+	if err != nil {
+		return nil, err
+	}
+	client.keys.key = &dataKey{aead: aead, wrapped: wrapped}
+	client.keys.unwrapped[string(wrapped)] = aead
+	return client.keys.key, nil
+}
+
+func (client *Storer) encrypt(ctx context.Context, rowType, column string, value interface{}) (interface{}, error) {
+	if !client.columns[column] || value == nil || IsEncrypted(value) {
+		return value, nil
+	}
+	_, isSet := value.([]string)
+	plaintext, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	key, err := client.dataKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, key.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	sealed, err := json.Marshal(envelope{
+		Key:        key.wrapped,
+		Nonce:      nonce,
+		Ciphertext: key.aead.Seal(nil, nonce, plaintext, additionalData(rowType, column)),
+		Set:        isSet,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return prefix + base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// decrypt returns the plain value of an encrypted one, stored in a column of
+// a row of the type.
+func (client *Storer) decrypt(ctx context.Context, rowType, column string, value interface{}) (interface{}, error) {
+	sealed, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(value.(string), prefix))
+	if err != nil {
+		return nil, fmt.Errorf("%w %s: %s", ErrDecrypt, column, err)
+	}
+	var env envelope
+	if err := json.Unmarshal(sealed, &env); err != nil {
+		return nil, fmt.Errorf("%w %s: %s", ErrDecrypt, column, err)
+	}
+	aead, err := client.unwrap(ctx, env.Key)
+	if err != nil {
+		return nil, fmt.Errorf("%w %s: unwrapping its data key: %w", ErrDecrypt, column, err)
+	}
+	plaintext, err := aead.Open(nil, env.Nonce, env.Ciphertext, additionalData(rowType, column))
+	if err != nil {
+		return nil, fmt.Errorf("%w %s: %s", ErrDecrypt, column, err)
+	}
+	if env.Set {
+		var set []string
+		err = json.Unmarshal(plaintext, &set)
+		return set, err
+	}
+	var s string
+	err = json.Unmarshal(plaintext, &s)
+	return s, err
+}
+
+func (client *Storer) unwrap(ctx context.Context, wrapped []byte) (cipher.AEAD, error) {
+	client.keys.mu.Lock()
+	aead, ok := client.keys.unwrapped[string(wrapped)]
+	client.keys.mu.Unlock()
+	if ok {
+		return aead, nil
+	}
+	plain, err := client.keys.service.Decrypt(ctx, wrapped)
+	if err != nil {
+		return nil, err
+	}
+	aead, err = newAEAD(plain)
+	if err != nil {
+		return nil, err
+	}
+	client.keys.mu.Lock()
+	client.keys.unwrapped[string(wrapped)] = aead
+	client.keys.mu.Unlock()
+	return aead, nil
+}
+
+func (client *Storer) encryptColumns(ctx context.Context, rowType string, columns map[string]interface{}) (map[string]interface{}, error) {
+	if columns == nil {
+		return nil, nil
+	}
+	out := make(map[string]interface{}, len(columns))
+	for name, value := range columns {
+		encrypted, err := client.encrypt(ctx, rowType, name, value)
+		if err != nil {
+			return nil, err
+		}
+		out[name] = encrypted
+	}
+	return out, nil
+}
+
+// decryptedRow is a row with its columns decrypted.
+type decryptedRow struct {
+	storage.Row
+	columns map[string]interface{}
+}
+
+func (r *decryptedRow) Columns() map[string]interface{} {
+	return r.columns
+}
+
+// decryptRow decrypts every encrypted column of row, designated or not, so
+// that values stay readable after a column stops being designated.
+func (client *Storer) decryptRow(ctx context.Context, row storage.Row) (storage.Row, error) {
+	if row == nil {
+		return nil, nil
+	}
+	var columns map[string]interface{}
+	for name, value := range row.Columns() {
+		if !IsEncrypted(value) {
+			continue
+		}
+		if columns == nil {
+			columns = make(map[string]interface{}, len(row.Columns()))
+			for name, value := range row.Columns() {
+				columns[name] = value
+			}
+		}
+		plain, err := client.decrypt(ctx, row.Type(), name, value)
+		if err != nil {
+			return nil, fmt.Errorf("%s %s: %w", row.Type(), row.ID(), err)
+		}
+		columns[name] = plain
+	}
+	if columns == nil {
+		return row, nil
+	}
+	return &decryptedRow{Row: row, columns: columns}, nil
+}
+
+func (client *Storer) decryptRows(ctx context.Context, rows []storage.Row) ([]storage.Row, error) {
+	for i, row := range rows {
+		decrypted, err := client.decryptRow(ctx, row)
+		if err != nil {
+			return nil, err
+		}
+		rows[i] = decrypted
+	}
+	return rows, nil
+}
+
+func (client *Storer) GetRowByID(ctx context.Context, rowType, rowID string) (storage.Row, error) {
+	row, err := client.next.GetRowByID(ctx, rowType, rowID)
+	if err != nil {
+		return nil, err
+	}
+	return client.decryptRow(ctx, row)
+}
+
+func (client *Storer) GetRowsByIDs(ctx context.Context, rowType string, ids []string) ([]storage.Row, error) {
+	rows, err := storage.GetRowsByIDs(ctx, client.next, rowType, ids)
+	if err != nil {
+		return nil, err
+	}
+	return client.decryptRows(ctx, rows)
+}
+
+func (client *Storer) GetRow(ctx context.Context, rowType, rowLabel string) (storage.Row, error) {
+	row, err := client.next.GetRow(ctx, rowType, rowLabel)
+	if err != nil {
+		return nil, err
+	}
+	return client.decryptRow(ctx, row)
+}
+
+func (client *Storer) CreateRow(ctx context.Context, rowType, rowLabel string) (storage.Row, error) {
+	return client.next.CreateRow(ctx, rowType, rowLabel)
+}
+
+func (client *Storer) CreateChild(ctx context.Context, rowType, rowLabel, parentType, parentID string, columns map[string]interface{}) (storage.Row, error) {
+	encrypted, err := client.encryptColumns(ctx, rowType, columns)
+	if err != nil {
+		return nil, err
+	}
+	row, err := client.next.CreateChild(ctx, rowType, rowLabel, parentType, parentID, encrypted)
+	if err != nil {
+		return nil, err
+	}
+	return client.decryptRow(ctx, row)
+}
+
+func (client *Storer) GetChild(ctx context.Context, childLabel, parentID string) (storage.Row, error) {
+	row, err := client.next.GetChild(ctx, childLabel, parentID)
+	if err != nil {
+		return nil, err
+	}
+	return client.decryptRow(ctx, row)
+}
+
+func (client *Storer) ListRows(ctx context.Context, rowType, labelFilter, parentIDFilter string) ([]storage.Row, error) {
+	rows, err := client.next.ListRows(ctx, rowType, labelFilter, parentIDFilter)
+	if err != nil {
+		return nil, err
+	}
+	return client.decryptRows(ctx, rows)
+}
+
+func (client *Storer) IterRows(ctx context.Context, rowType, labelFilter, parentIDFilter string) *storage.Iter[storage.Row] {
+	return storage.WrapPages(storage.IterRows(ctx, client.next, rowType, labelFilter, parentIDFilter), func(ctx context.Context, fetch storage.PageFunc[storage.Row]) ([]storage.Row, bool, error) {
+		page, more, err := fetch(ctx)
+		if err != nil {
+			return nil, false, err
+		}
+		page, err = client.decryptRows(ctx, page)
+		return page, more, err
+	})
+}
+
+// QueryByColumn can't compare an encrypted column's values where they're
+// stored, so it reads every row of the type and compares the decrypted
+// values instead.
+func (client *Storer) QueryByColumn(ctx context.Context, rowType, columnName string, value interface{}) ([]storage.Row, error) {
+	if !client.columns[columnName] {
+		rows, err := storage.QueryByColumn(ctx, client.next, rowType, columnName, value)
+		if err != nil {
+			return nil, err
+		}
+		return client.decryptRows(ctx, rows)
+	}
+	rows := []storage.Row{}
+	it := storage.IterRows(ctx, client, rowType, "", "")
+	for it.Next() {
+		if storage.ColumnEquals(it.Value(), columnName, value) {
+			rows = append(rows, it.Value())
+		}
+	}
+	return rows, it.Err()
+}
+
+func (client *Storer) UpdateRow(ctx context.Context, rowType, rowID, newLabel string) (storage.Row, error) {
+	row, err := client.next.UpdateRow(ctx, rowType, rowID, newLabel)
+	if err != nil {
+		return nil, err
+	}
+	return client.decryptRow(ctx, row)
+}
+
+func (client *Storer) UpdateChild(ctx context.Context, childType, childID, newChildLabel, parentType, newParentID string) (storage.Row, error) {
+	row, err := client.next.UpdateChild(ctx, childType, childID, newChildLabel, parentType, newParentID)
+	if err != nil {
+		return nil, err
+	}
+	return client.decryptRow(ctx, row)
+}
+
+func (client *Storer) UpdateColumn(ctx context.Context, rowType, rowID, columnName string, columnValue interface{}) error {
+	encrypted, err := client.encrypt(ctx, rowType, columnName, columnValue)
+	if err != nil {
+		return err
+	}
+	return client.next.UpdateColumn(ctx, rowType, rowID, columnName, encrypted)
+}
+
+func (client *Storer) PatchColumns(ctx context.Context, patches []storage.ColumnPatch) error {
+	encrypted := make([]storage.ColumnPatch, len(patches))
+	for i, patch := range patches {
+		columns, err := client.encryptColumns(ctx, patch.Type, patch.Columns)
+		if err != nil {
+			return err
+		}
+		encrypted[i] = storage.ColumnPatch{Type: patch.Type, ID: patch.ID, Columns: columns}
+	}
+	return storage.PatchColumns(ctx, client.next, encrypted)
+}
+
+func (client *Storer) UpdateColumns(ctx context.Context, rowType, rowID string, columns map[string]interface{}) error {
+	encrypted, err := client.encryptColumns(ctx, rowType, columns)
+	if err != nil {
+		return err
+	}
+	return client.next.UpdateColumns(ctx, rowType, rowID, encrypted)
+}
+
+func (client *Storer) DeleteRow(ctx context.Context, rowType, childType, rowID string) error {
+	return client.next.DeleteRow(ctx, rowType, childType, rowID)
+}
+
+// PutRow encrypts the designated columns of row, except those already
+// encrypted, so that rows copied as stored stay as they were.
+func (client *Storer) PutRow(ctx context.Context, row storage.Row) error {
+	encrypted, err := client.encryptColumns(ctx, row.Type(), row.Columns())
+	if err != nil {
+		return err
+	}
+	return client.next.PutRow(ctx, &decryptedRow{Row: row, columns: encrypted})
+}
+
+func (client *Storer) PutRows(ctx context.Context, rows []storage.Row) error {
+	encrypted := make([]storage.Row, len(rows))
+	for i, row := range rows {
+		columns, err := client.encryptColumns(ctx, row.Type(), row.Columns())
+		if err != nil {
+			return err
+		}
+		encrypted[i] = &decryptedRow{Row: row, columns: columns}
+	}
+	return storage.PutRows(ctx, client.next, encrypted)
+}
+
+func (client *Storer) ScanRows(ctx context.Context, fn func(storage.Row) error) error {
+	return client.next.ScanRows(ctx, func(row storage.Row) error {
+		decrypted, err := client.decryptRow(ctx, row)
+		if err != nil {
+			return err
+		}
+		return fn(decrypted)
+	})
+}
+
+func (client *Storer) ScanChildren(ctx context.Context, parentID string, fn func(storage.Row) error) error {
+	return storage.ScanChildren(ctx, client.next, parentID, func(row storage.Row) error {
+		decrypted, err := client.decryptRow(ctx, row)
+		if err != nil {
+			return err
+		}
+		return fn(decrypted)
+	})
+}
+
+// DeleteColumn has no value to encrypt, so it passes through.
+func (client *Storer) DeleteColumn(ctx context.Context, rowType, rowID, columnName string) error {
+	return storage.DeleteColumn(ctx, client.next, rowType, rowID, columnName)
+}
+
+// WithinTx encrypts and decrypts the rows of the transaction, too, with the
+// same data keys.
+func (client *Storer) WithinTx(ctx context.Context, fn func(tx storage.RowStorer) error) error {
+	return storage.WithinTx(ctx, client.next, func(tx storage.RowStorer) error {
+		return fn(&Storer{next: tx, columns: client.columns, keys: client.keys})
+	})
+}
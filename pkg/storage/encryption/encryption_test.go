@@ -0,0 +1,158 @@
+package encryption_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/encryption"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/memory"
+)
+
+func newKeys(t *testing.T, b byte) *encryption.LocalKeys {
+	t.Helper()
+	keys, err := encryption.NewLocalKeys(bytes.Repeat([]byte{b}, 32))
+	if err != nil {
+		t.Fatalf("NewLocalKeys: %s", err)
+	}
+	return keys
+}
+
+func TestEncryptsColumns(t *testing.T) {
+	ctx := context.Background()
+	backend := memory.NewClient()
+	storer := encryption.NewStorer(backend, newKeys(t, 1), []string{"secret", "secrets"})
+	org, err := storer.CreateRow(ctx, "org", "acme")
+	if err != nil {
+		t.Fatalf("CreateRow: %s", err)
+	}
+	team, err := storer.CreateChild(ctx, "team", "dev", "org", org.ID(), map[string]interface{}{
+		"secret":  "hunter2",
+		"secrets": []string{"a", "b"},
+		"owner":   "ops",
+	})
+	if err != nil {
+		t.Fatalf("CreateChild: %s", err)
+	}
+	if team.Columns()["secret"] != "hunter2" {
+		t.Errorf("CreateChild returned secret %v, not decrypted", team.Columns()["secret"])
+	}
+
+	stored, err := backend.GetRowByID(ctx, "team", team.ID())
+	if err != nil {
+		t.Fatalf("GetRowByID: %s", err)
+	}
+	for _, column := range []string{"secret", "secrets"} {
+		if !encryption.IsEncrypted(stored.Columns()[column]) {
+			t.Errorf("%s is stored as %v, not encrypted", column, stored.Columns()[column])
+		}
+	}
+	if stored.Columns()["owner"] != "ops" {
+		t.Errorf("owner isn't designated, but is stored as %v", stored.Columns()["owner"])
+	}
+
+	got, err := storer.GetRowByID(ctx, "team", team.ID())
+	if err != nil {
+		t.Fatalf("GetRowByID: %s", err)
+	}
+	secrets, _ := got.Columns()["secrets"].([]string)
+	if got.Columns()["secret"] != "hunter2" || len(secrets) != 2 || secrets[0] != "a" || secrets[1] != "b" {
+		t.Errorf("GetRowByID decrypted the columns to %v", got.Columns())
+	}
+}
+
+func TestQueryByEncryptedColumn(t *testing.T) {
+	ctx := context.Background()
+	storer := encryption.NewStorer(memory.NewClient(), newKeys(t, 1), []string{"secret"})
+	for _, label := range []string{"acme", "globex"} {
+		org, err := storer.CreateRow(ctx, "org", label)
+		if err != nil {
+			t.Fatalf("CreateRow: %s", err)
+		}
+		if err := storer.UpdateColumn(ctx, "org", org.ID(), "secret", label+"-key"); err != nil {
+			t.Fatalf("UpdateColumn: %s", err)
+		}
+	}
+
+	rows, err := storer.QueryByColumn(ctx, "org", "secret", "globex-key")
+	if err != nil {
+		t.Fatalf("QueryByColumn: %s", err)
+	}
+	if len(rows) != 1 || rows[0].Label() != "globex" {
+		t.Errorf("QueryByColumn of an encrypted column returned %v", rows)
+	}
+}
+
+func TestWrongKey(t *testing.T) {
+	ctx := context.Background()
+	backend := memory.NewClient()
+	storer := encryption.NewStorer(backend, newKeys(t, 1), []string{"secret"})
+	org, err := storer.CreateRow(ctx, "org", "acme")
+	if err != nil {
+		t.Fatalf("CreateRow: %s", err)
+	}
+	if err := storer.UpdateColumn(ctx, "org", org.ID(), "secret", "hunter2"); err != nil {
+		t.Fatalf("UpdateColumn: %s", err)
+	}
+
+	other := encryption.NewStorer(backend, newKeys(t, 2), []string{"secret"})
+	if _, err := other.GetRowByID(ctx, "org", org.ID()); !errors.Is(err, encryption.ErrDecrypt) {
+		t.Errorf("GetRowByID under another key failed with %v, not %q", err, encryption.ErrDecrypt)
+	}
+}
+
+func TestMovedValue(t *testing.T) {
+	ctx := context.Background()
+	backend := memory.NewClient()
+	storer := encryption.NewStorer(backend, newKeys(t, 1), []string{"secret", "other"})
+	org, err := storer.CreateRow(ctx, "org", "acme")
+	if err != nil {
+		t.Fatalf("CreateRow: %s", err)
+	}
+	if err := storer.UpdateColumn(ctx, "org", org.ID(), "secret", "hunter2"); err != nil {
+		t.Fatalf("UpdateColumn: %s", err)
+	}
+	stored, err := backend.GetRowByID(ctx, "org", org.ID())
+	if err != nil {
+		t.Fatalf("GetRowByID: %s", err)
+	}
+
+	// a value copied, as stored, to another column doesn't decrypt there
+	if err := backend.UpdateColumn(ctx, "org", org.ID(), "other", stored.Columns()["secret"]); err != nil {
+		t.Fatalf("UpdateColumn: %s", err)
+	}
+	if _, err := storer.GetRowByID(ctx, "org", org.ID()); !errors.Is(err, encryption.ErrDecrypt) {
+		t.Errorf("GetRowByID of a moved value failed with %v, not %q", err, encryption.ErrDecrypt)
+	}
+}
+
+func TestPutRowKeepsEncrypted(t *testing.T) {
+	ctx := context.Background()
+	backend := memory.NewClient()
+	storer := encryption.NewStorer(backend, newKeys(t, 1), []string{"secret"})
+	org, err := storer.CreateRow(ctx, "org", "acme")
+	if err != nil {
+		t.Fatalf("CreateRow: %s", err)
+	}
+	if err := storer.UpdateColumn(ctx, "org", org.ID(), "secret", "hunter2"); err != nil {
+		t.Fatalf("UpdateColumn: %s", err)
+	}
+	stored, err := backend.GetRowByID(ctx, "org", org.ID())
+	if err != nil {
+		t.Fatalf("GetRowByID: %s", err)
+	}
+
+	// a row copied as stored isn't encrypted twice
+	if err := storer.PutRows(ctx, []storage.Row{stored}); err != nil {
+		t.Fatalf("PutRows: %s", err)
+	}
+	got, err := storer.GetRowByID(ctx, "org", org.ID())
+	if err != nil {
+		t.Fatalf("GetRowByID: %s", err)
+	}
+	if got.Columns()["secret"] != "hunter2" {
+		t.Errorf("the copied row's secret decrypted to %v", got.Columns()["secret"])
+	}
+}
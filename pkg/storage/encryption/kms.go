@@ -0,0 +1,55 @@
+package encryption
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+)
+
+// KMSKeys is a KeyService whose data keys are wrapped by a KMS key.
+type KMSKeys struct {
+	kms    *kms.Client
+	keyARN string
+}
+
+var _ KeyService = &KMSKeys{}
+
+// NewKMSKeys returns a key service wrapping data keys with the KMS key.
+func NewKMSKeys(ctx context.Context, profile, region, keyARN string) (*KMSKeys, error) {
+	cfg, err := config.LoadDefaultConfig(ctx,
+		config.WithSharedConfigProfile(profile),
+		config.WithRegion(region),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &KMSKeys{
+		kms:    kms.NewFromConfig(cfg),
+		keyARN: keyARN,
+	}, nil
+}
+
+func (keys *KMSKeys) GenerateDataKey(ctx context.Context) ([]byte, []byte, error) {
+	output, err := keys.kms.GenerateDataKey(ctx, &kms.GenerateDataKeyInput{
+		KeyId:   aws.String(keys.keyARN),
+		KeySpec: types.DataKeySpecAes256,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return output.Plaintext, output.CiphertextBlob, nil
+}
+
+func (keys *KMSKeys) Decrypt(ctx context.Context, wrapped []byte) ([]byte, error) {
+	output, err := keys.kms.Decrypt(ctx, &kms.DecryptInput{
+		KeyId:          aws.String(keys.keyARN),
+		CiphertextBlob: wrapped,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return output.Plaintext, nil
+}
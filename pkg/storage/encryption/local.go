@@ -0,0 +1,74 @@
+package encryption
+
+import (
+	"context"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// localKeyData is the additional data of wrapped data keys, so that a local
+// key's ciphertexts of other things can't pass for data keys.
+var localKeyData = []byte("tree-data-key")
+
+// LocalKeys is a KeyService whose data keys are wrapped by a 256-bit key the
+// caller holds, for backends without KMS, such as SQLite or a storage
+// server. Whoever holds the key can decrypt every value, so keep it as a
+// secret, out of the configuration that names the columns.
+type LocalKeys struct {
+	aead cipher.AEAD
+}
+
+var _ KeyService = &LocalKeys{}
+
+// NewLocalKeys returns a key service wrapping data keys with key, which must
+// be 32 bytes.
+func NewLocalKeys(key []byte) (*LocalKeys, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("a local encryption key must be 32 bytes, not %d", len(key))
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	return &LocalKeys{aead: aead}, nil
+}
+
+// ParseLocalKey decodes a local key written in base64, as by
+// `openssl rand -base64 32`.
+func ParseLocalKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("a local encryption key must be base64: %w", err)
+	}
+	return key, nil
+}
+
+// GenerateDataKey returns a new data key, and the key wrapped: a nonce and
+// the sealed key.
+func (keys *LocalKeys) GenerateDataKey(ctx context.Context) ([]byte, []byte, error) {
+	plain := make([]byte, 32)
+	if _, err := rand.Read(plain); err != nil {
+		return nil, nil, err
+	}
+	nonce := make([]byte, keys.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, nil, err
+	}
+	return plain, keys.aead.Seal(nonce, nonce, plain, localKeyData), nil
+}
+
+func (keys *LocalKeys) Decrypt(ctx context.Context, wrapped []byte) ([]byte, error) {
+	if len(wrapped) < keys.aead.NonceSize() {
+		return nil, errors.New("the wrapped data key is too short")
+	}
+	nonce, sealed := wrapped[:keys.aead.NonceSize()], wrapped[keys.aead.NonceSize():]
+	plain, err := keys.aead.Open(nil, nonce, sealed, localKeyData)
+	if err != nil {
+		return nil, errors.New("the data key isn't wrapped by this local key")
+	}
+	return plain, nil
+}
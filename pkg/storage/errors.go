@@ -0,0 +1,71 @@
+package storage
+
+import "errors"
+
+// errorKinds are the kinds of storage error, in the order ErrorKind checks
+// them.
+var errorKinds = []error{
+	ErrNotFoundRow,
+	ErrConflict,
+	ErrThrottled,
+	ErrPermissionDenied,
+	ErrInvalid,
+}
+
+// ErrorKind returns the kind of err: one of ErrNotFoundRow, ErrConflict,
+// ErrThrottled, ErrPermissionDenied, or ErrInvalid. It returns nil for nil and
+// for unexpected errors.
+func ErrorKind(err error) error {
+	if err == nil {
+		return nil
+	}
+	for _, kind := range errorKinds {
+		if errors.Is(err, kind) {
+			return kind
+		}
+	}
+	return nil
+}
+
+// Result names the outcome of an operation, for metrics and traces: ok,
+// not_found, conflict, throttled, server_error, permission_denied, invalid,
+// or error. A server error is of the throttled kind, but isn't a throttle.
+func Result(err error) string {
+	if err == nil {
+		return "ok"
+	}
+	switch ErrorKind(err) {
+	case ErrNotFoundRow:
+		return "not_found"
+	case ErrConflict:
+		return "conflict"
+	case ErrThrottled:
+		if errors.Is(err, ErrServerError) {
+			return "server_error"
+		}
+		return "throttled"
+	case ErrPermissionDenied:
+		return "permission_denied"
+	case ErrInvalid:
+		return "invalid"
+	}
+	return "error"
+}
+
+// kindedError is an error with its own message that is also of a kind.
+type kindedError struct {
+	kind    error
+	message string
+}
+
+func kindError(kind error, message string) error {
+	return &kindedError{kind: kind, message: message}
+}
+
+func (err *kindedError) Error() string {
+	return err.message
+}
+
+func (err *kindedError) Unwrap() error {
+	return err.kind
+}
@@ -0,0 +1,597 @@
+// Package fsjson implements storage.RowStorer in a directory of JSON files,
+// one per row, for air-gapped demos and for keeping small trees in git. Each
+// row is stored in <dir>/<type>/<id>.json, and <dir>/index.json records every
+// row's label and parent, so that rows are found by label without reading
+// every file. It keeps the same uniqueness rules as the in-memory backend.
+//
+// Lookups by label scan the index, so it suits trees of thousands of rows
+// rather than millions. One process at a time may write to a directory.
+package fsjson
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/spilliams/tree-terraform-provider/internal/slug"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+// indexFile is the name of the index in the directory.
+const indexFile = "index.json"
+
+type key struct {
+	rowType string
+	id      string
+}
+
+// entry is what the index records of a row.
+type entry struct {
+	Label    string `json:"label"`
+	ParentID string `json:"parent_id,omitempty"`
+}
+
+type Client struct {
+	dir string
+
+	// mu guards the index, and the files: reads of rows hold it for reading
+	// so that they don't see a file half replaced
+	mu    sync.RWMutex
+	index map[key]entry
+}
+
+var (
+	_ storage.RowStorer    = &Client{}
+	_ storage.ChildScanner = &Client{}
+)
+
+// NewClient keeps rows in dir, which it creates if it doesn't exist. The
+// index is read from dir, or, if it's missing, rebuilt from the rows' files
+// and written: delete index.json after editing labels or parents in the
+// files by hand, or after a merge, to have it rebuilt.
+func NewClient(ctx context.Context, dir string) (storage.RowStorer, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("%w: a directory is required", storage.ErrInvalid)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	client := &Client{dir: dir}
+	var err error
+	client.index, err = client.readIndex()
+	if errors.Is(err, fs.ErrNotExist) {
+		tflog.SubsystemInfo(ctx, storage.LogSubsystemStorage, fmt.Sprintf("Rebuilding the index of %s", dir))
+		client.index, err = client.rebuildIndex()
+		if err == nil {
+			err = client.writeIndex()
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// readIndex reads index.json, which maps types to IDs to entries.
+func (client *Client) readIndex() (map[key]entry, error) {
+	b, err := os.ReadFile(filepath.Join(client.dir, indexFile))
+	if err != nil {
+		return nil, err
+	}
+	byType := map[string]map[string]entry{}
+	if err := json.Unmarshal(b, &byType); err != nil {
+		return nil, fmt.Errorf("decoding %s: %w", indexFile, err)
+	}
+	index := map[key]entry{}
+	for rowType, ids := range byType {
+		for id, e := range ids {
+			index[key{rowType, id}] = e
+		}
+	}
+	return index, nil
+}
+
+// rebuildIndex reads every row's file for its label and parent.
+func (client *Client) rebuildIndex() (map[key]entry, error) {
+	index := map[key]entry{}
+	types, err := os.ReadDir(client.dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range types {
+		if !t.IsDir() || strings.HasPrefix(t.Name(), ".") {
+			continue
+		}
+		files, err := os.ReadDir(filepath.Join(client.dir, t.Name()))
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range files {
+			id, ok := strings.CutSuffix(f.Name(), ".json")
+			if f.IsDir() || !ok {
+				continue
+			}
+			r, err := client.readRow(t.Name(), id)
+			if err != nil {
+				return nil, err
+			}
+			index[key{r.RowType, r.RowID}] = entry{Label: r.RowLabel, ParentID: r.RowParentID}
+		}
+	}
+	return index, nil
+}
+
+// writeIndex writes index.json, sorted by type and ID. Callers must hold the
+// lock.
+func (client *Client) writeIndex() error {
+	byType := map[string]map[string]entry{}
+	for k, e := range client.index {
+		if byType[k.rowType] == nil {
+			byType[k.rowType] = map[string]entry{}
+		}
+		byType[k.rowType][k.id] = e
+	}
+	b, err := json.MarshalIndent(byType, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFile(filepath.Join(client.dir, indexFile), append(b, '\n'))
+}
+
+// writeFile replaces the file at path with data, all at once, by writing it
+// beside the file and renaming it over the file.
+func writeFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// rowPath returns the path of a row's file. Types and IDs name files, so
+// they must be valid file names.
+func (client *Client) rowPath(rowType, id string) (string, error) {
+	for _, name := range []string{rowType, id} {
+		if name == "" || strings.HasPrefix(name, ".") || strings.ContainsAny(name, `/\`) {
+			return "", fmt.Errorf("%w: %q can't name a file", storage.ErrInvalid, name)
+		}
+	}
+	return filepath.Join(client.dir, rowType, id+".json"), nil
+}
+
+// readRow reads a row's file.
+func (client *Client) readRow(rowType, id string) (*row, error) {
+	path, err := client.rowPath(rowType, id)
+	if err != nil {
+		return nil, err
+	}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %q", storage.ErrNotFoundRow, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decodeRow(rowType, id, b)
+}
+
+// getRow reads a row's file, for returning to callers.
+func (client *Client) getRow(rowType, id string) (storage.Row, error) {
+	r, err := client.readRow(rowType, id)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// writeRow writes a row's file, then the index, if the row's label or parent
+// changed. Callers must hold the lock.
+func (client *Client) writeRow(r *row) error {
+	path, err := client.rowPath(r.RowType, r.RowID)
+	if err != nil {
+		return err
+	}
+	b, err := encodeRow(r)
+	if err != nil {
+		return err
+	}
+	if err := writeFile(path, b); err != nil {
+		return err
+	}
+	k := key{r.RowType, r.RowID}
+	e := entry{Label: r.RowLabel, ParentID: r.RowParentID}
+	if old, ok := client.index[k]; ok && old == e {
+		return nil
+	}
+	client.index[k] = e
+	return client.writeIndex()
+}
+
+// readRows reads the files of the rows with the keys.
+func (client *Client) readRows(keys []key) ([]storage.Row, error) {
+	rows := make([]storage.Row, len(keys))
+	for i, k := range keys {
+		r, err := client.readRow(k.rowType, k.id)
+		if err != nil {
+			return nil, err
+		}
+		rows[i] = r
+	}
+	return rows, nil
+}
+
+func (client *Client) GetRowByID(ctx context.Context, rowType, id string) (storage.Row, error) {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemStorage, fmt.Sprintf("GetRowByID %q", id))
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	client.mu.RLock()
+	defer client.mu.RUnlock()
+
+	if _, ok := client.index[key{rowType, id}]; !ok {
+		return nil, fmt.Errorf("%w: %q", storage.ErrNotFoundRow, id)
+	}
+	return client.getRow(rowType, id)
+}
+
+func (client *Client) GetRow(ctx context.Context, rowType, label string) (storage.Row, error) {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemStorage, fmt.Sprintf("GetRow %q %q", rowType, label))
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	client.mu.RLock()
+	defer client.mu.RUnlock()
+
+	found := client.filter(func(k key, e entry) bool { return k.rowType == rowType && e.Label == label })
+	if len(found) == 0 {
+		return nil, fmt.Errorf("%w: type %q and label %q", storage.ErrNotFoundRow, rowType, label)
+	}
+	if len(found) > 1 {
+		return nil, fmt.Errorf("%w: type %q and label %q", storage.ErrTooManyFound, rowType, label)
+	}
+	return client.getRow(found[0].rowType, found[0].id)
+}
+
+func (client *Client) CreateRow(ctx context.Context, rowType, label string) (storage.Row, error) {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemStorage, fmt.Sprintf("CreateRow %q %q", rowType, label))
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	// make sure type+name doesn't collide
+	if len(client.filter(func(k key, e entry) bool { return k.rowType == rowType && e.Label == label })) > 0 {
+		return nil, storage.ErrCollisionTypeLabel
+	}
+
+	r := &row{
+		RowType:    rowType,
+		RowID:      client.newID(ctx, rowType),
+		RowLabel:   label,
+		RowColumns: map[string]interface{}{},
+	}
+	if err := client.writeRow(r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (client *Client) CreateChild(ctx context.Context, rowType, label, parentType, parentID string, columns map[string]interface{}) (storage.Row, error) {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemStorage, fmt.Sprintf("CreateChild %q %q %q %q", rowType, label, parentType, parentID))
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	// make sure parent exists
+	if _, ok := client.index[key{parentType, parentID}]; !ok {
+		return nil, fmt.Errorf("%w: %q", storage.ErrNotFoundRow, parentID)
+	}
+
+	// make sure label is unique within the parent
+	if len(client.filter(func(_ key, e entry) bool { return e.ParentID == parentID && e.Label == label })) > 0 {
+		return nil, storage.ErrCollisionParentLabel
+	}
+
+	r := &row{
+		RowType:     rowType,
+		RowID:       client.newID(ctx, rowType),
+		RowLabel:    label,
+		RowParentID: parentID,
+		RowColumns:  columns,
+	}
+	if err := client.writeRow(r); err != nil {
+		return nil, err
+	}
+	// the row read back has its own columns, as JSON decodes them
+	return client.getRow(r.RowType, r.RowID)
+}
+
+func (client *Client) GetChild(ctx context.Context, label, parentID string) (storage.Row, error) {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemStorage, fmt.Sprintf("GetChild %q %q", label, parentID))
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	client.mu.RLock()
+	defer client.mu.RUnlock()
+
+	found := client.filter(func(_ key, e entry) bool { return e.ParentID == parentID && e.Label == label })
+	if len(found) == 0 {
+		return nil, fmt.Errorf("%w with parent ID %q and label %q", storage.ErrNotFoundRow, parentID, label)
+	}
+	if len(found) > 1 {
+		return nil, fmt.Errorf("%w: parent ID %q and label %q", storage.ErrTooManyFound, parentID, label)
+	}
+	return client.getRow(found[0].rowType, found[0].id)
+}
+
+func (client *Client) ListRows(ctx context.Context, rowType, labelFilter, parentIDFilter string) ([]storage.Row, error) {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemStorage, fmt.Sprintf("ListRows %q %q %q", rowType, labelFilter, parentIDFilter))
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	client.mu.RLock()
+	defer client.mu.RUnlock()
+
+	return client.readRows(client.filter(func(k key, e entry) bool {
+		if k.rowType != rowType {
+			return false
+		}
+		if labelFilter != "" && !strings.Contains(e.Label, labelFilter) {
+			return false
+		}
+		if parentIDFilter != "" && e.ParentID != parentIDFilter {
+			return false
+		}
+		return true
+	}))
+}
+
+func (client *Client) UpdateRow(ctx context.Context, rowType, id, newLabel string) (storage.Row, error) {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemStorage, fmt.Sprintf("UpdateRow %q %q %q", rowType, id, newLabel))
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	this := key{rowType, id}
+	if _, ok := client.index[this]; !ok {
+		return nil, fmt.Errorf("%w: %q", storage.ErrNotFoundRow, id)
+	}
+	r, err := client.readRow(rowType, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// ensure new label is available
+	if r.RowParentID == "" {
+		if len(client.filter(func(k key, e entry) bool { return k != this && k.rowType == rowType && e.Label == newLabel })) > 0 {
+			return nil, storage.ErrCollisionTypeLabel
+		}
+	} else {
+		if len(client.filter(func(k key, e entry) bool { return k != this && e.ParentID == r.RowParentID && e.Label == newLabel })) > 0 {
+			return nil, storage.ErrCollisionParentLabel
+		}
+	}
+
+	r.RowLabel = newLabel
+	if err := client.writeRow(r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (client *Client) UpdateChild(ctx context.Context, childType, childID, newChildLabel, parentType, newParentID string) (storage.Row, error) {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemStorage, fmt.Sprintf("UpdateChild %q %q %q %q %q", childType, childID, newChildLabel, parentType, newParentID))
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	// ensure new parent exists
+	if _, ok := client.index[key{parentType, newParentID}]; !ok {
+		return nil, fmt.Errorf("%w: %q", storage.ErrNotFoundRow, newParentID)
+	}
+
+	this := key{childType, childID}
+	if _, ok := client.index[this]; !ok {
+		return nil, fmt.Errorf("%w: %q", storage.ErrNotFoundRow, childID)
+	}
+	r, err := client.readRow(childType, childID)
+	if err != nil {
+		return nil, err
+	}
+
+	// ensure new label is available
+	if len(client.filter(func(k key, e entry) bool { return k != this && e.ParentID == newParentID && e.Label == newChildLabel })) > 0 {
+		return nil, storage.ErrCollisionParentLabel
+	}
+
+	r.RowLabel = newChildLabel
+	r.RowParentID = newParentID
+	if err := client.writeRow(r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (client *Client) UpdateColumn(ctx context.Context, rowType, rowID, columnName string, columnValue interface{}) error {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemStorage, fmt.Sprintf("UpdateColumn %q %q %q %s", rowType, rowID, columnName, storage.LogColumnValue(rowType, columnName, columnValue)))
+	return client.updateColumns(ctx, rowType, rowID, func(columns map[string]interface{}) map[string]interface{} {
+		columns[columnName] = columnValue
+		return columns
+	})
+}
+
+func (client *Client) UpdateColumns(ctx context.Context, rowType, rowID string, columns map[string]interface{}) error {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemStorage, fmt.Sprintf("UpdateColumns %q %q %s", rowType, rowID, storage.LogColumns(rowType, columns)))
+	return client.updateColumns(ctx, rowType, rowID, func(map[string]interface{}) map[string]interface{} {
+		return columns
+	})
+}
+
+// updateColumns rewrites a row's file with the columns update returns, given
+// its current ones.
+func (client *Client) updateColumns(ctx context.Context, rowType, rowID string, update func(map[string]interface{}) map[string]interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	if _, ok := client.index[key{rowType, rowID}]; !ok {
+		return fmt.Errorf("%w: %q", storage.ErrNotFoundRow, rowID)
+	}
+	r, err := client.readRow(rowType, rowID)
+	if err != nil {
+		return err
+	}
+	r.RowColumns = update(r.RowColumns)
+	return client.writeRow(r)
+}
+
+func (client *Client) DeleteRow(ctx context.Context, rowType, childType, id string) error {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemStorage, fmt.Sprintf("DeleteRow %q %q %q", rowType, childType, id))
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	// ensure this row does not have any children
+	if len(childType) > 0 {
+		if len(client.filter(func(k key, e entry) bool { return k.rowType == childType && e.ParentID == id })) > 0 {
+			return fmt.Errorf("%s %s has children: %w", rowType, id, storage.ErrCannotDeleteRow)
+		}
+	}
+
+	k := key{rowType, id}
+	if _, ok := client.index[k]; !ok {
+		return fmt.Errorf("%w: %q", storage.ErrNotFoundRow, id)
+	}
+	path, err := client.rowPath(rowType, id)
+	if err != nil {
+		return err
+	}
+	// the index first, so that a row whose file outlives it isn't found
+	delete(client.index, k)
+	if err := client.writeIndex(); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+func (client *Client) PutRow(ctx context.Context, r storage.Row) error {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemStorage, fmt.Sprintf("PutRow %q %q", r.Type(), r.ID()))
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	return client.writeRow(&row{
+		RowType:     r.Type(),
+		RowID:       r.ID(),
+		RowLabel:    r.Label(),
+		RowParentID: r.ParentID(),
+		RowColumns:  r.Columns(),
+	})
+}
+
+func (client *Client) ScanRows(ctx context.Context, fn func(storage.Row) error) error {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemStorage, "ScanRows")
+	return client.scan(ctx, func(key, entry) bool { return true }, fn)
+}
+
+func (client *Client) ScanChildren(ctx context.Context, parentID string, fn func(storage.Row) error) error {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemStorage, fmt.Sprintf("ScanChildren %q", parentID))
+	return client.scan(ctx, func(_ key, e entry) bool { return e.ParentID == parentID }, fn)
+}
+
+// scan calls fn on the rows matching the predicate, reading each one's file
+// just before, without the lock, so that fn may use the client.
+func (client *Client) scan(ctx context.Context, match func(key, entry) bool, fn func(storage.Row) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	client.mu.RLock()
+	found := client.filter(match)
+	client.mu.RUnlock()
+
+	for _, k := range found {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		client.mu.RLock()
+		_, ok := client.index[k]
+		var r *row
+		var err error
+		if ok {
+			r, err = client.readRow(k.rowType, k.id)
+		}
+		client.mu.RUnlock()
+		if !ok {
+			// deleted since the scan started
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// filter returns the keys of the rows matching the predicate, ordered by type
+// and ID so that results are stable. Callers must hold the lock.
+func (client *Client) filter(match func(key, entry) bool) []key {
+	found := []key{}
+	for k, e := range client.index {
+		if match(k, e) {
+			found = append(found, k)
+		}
+	}
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].rowType != found[j].rowType {
+			return found[i].rowType < found[j].rowType
+		}
+		return found[i].id < found[j].id
+	})
+	return found
+}
+
+// newID generates an ID that isn't in use. Callers must hold the lock.
+func (client *Client) newID(ctx context.Context, rowType string) string {
+	for {
+		id := slug.Generate(rowType)
+		if _, ok := client.index[key{rowType, id}]; !ok {
+			tflog.SubsystemTrace(ctx, storage.LogSubsystemSlug, fmt.Sprintf("Generated ID %q", id))
+			return id
+		}
+	}
+}
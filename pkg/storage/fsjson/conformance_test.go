@@ -0,0 +1,20 @@
+package fsjson_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/fsjson"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/storagetest"
+)
+
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.RowStorer {
+		client, err := fsjson.NewClient(context.Background(), t.TempDir())
+		if err != nil {
+			t.Fatalf("NewClient: %s", err)
+		}
+		return client
+	})
+}
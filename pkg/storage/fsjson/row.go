@@ -0,0 +1,77 @@
+package fsjson
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+type row struct {
+	RowType     string
+	RowID       string
+	RowLabel    string
+	RowParentID string
+	RowColumns  map[string]interface{}
+}
+
+// rowFile is what a row's file holds. Its type and ID are in its path.
+type rowFile struct {
+	Label    string                 `json:"label"`
+	ParentID string                 `json:"parent_id,omitempty"`
+	Columns  map[string]interface{} `json:"columns"`
+}
+
+// encodeRow returns the indented JSON a row's file holds, so that changes to
+// it diff line by line.
+func encodeRow(r *row) ([]byte, error) {
+	columns := r.RowColumns
+	if columns == nil {
+		columns = map[string]interface{}{}
+	}
+	b, err := json.MarshalIndent(rowFile{Label: r.RowLabel, ParentID: r.RowParentID, Columns: columns}, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("%w: encoding columns: %s", storage.ErrInvalid, err)
+	}
+	return append(b, '\n'), nil
+}
+
+// decodeRow returns the row with the type and ID whose file holds encoded,
+// with JSON arrays as string sets, the only kind of array rows store.
+func decodeRow(rowType, id string, encoded []byte) (*row, error) {
+	var f rowFile
+	if err := json.Unmarshal(encoded, &f); err != nil {
+		return nil, fmt.Errorf("decoding %s %s: %w", rowType, id, err)
+	}
+	if f.Columns == nil {
+		f.Columns = map[string]interface{}{}
+	}
+	for name, value := range f.Columns {
+		list, ok := value.([]interface{})
+		if !ok {
+			continue
+		}
+		set := make([]string, 0, len(list))
+		for _, element := range list {
+			s, ok := element.(string)
+			if !ok {
+				return nil, fmt.Errorf("decoding %s %s: column %q isn't a set of strings", rowType, id, name)
+			}
+			set = append(set, s)
+		}
+		f.Columns[name] = set
+	}
+	return &row{
+		RowType:     rowType,
+		RowID:       id,
+		RowLabel:    f.Label,
+		RowParentID: f.ParentID,
+		RowColumns:  f.Columns,
+	}, nil
+}
+
+func (r *row) Type() string                    { return r.RowType }
+func (r *row) ID() string                      { return r.RowID }
+func (r *row) Label() string                   { return r.RowLabel }
+func (r *row) ParentID() string                { return r.RowParentID }
+func (r *row) Columns() map[string]interface{} { return r.RowColumns }
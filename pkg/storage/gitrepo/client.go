@@ -0,0 +1,499 @@
+// Package gitrepo implements storage.RowStorer in a git repository, as a YAML
+// file per row, committing and pushing each write, so that the tree's history
+// is the repository's. Each row is stored in <dir>/<type>/<id>.yaml, in a
+// clone of the repository.
+//
+// Rows are read from the files when the client is created, and again when a
+// write, or Refresh, pulls commits from the branch's upstream; between those,
+// they are read from memory. A write whose push is rejected, because the
+// branch moved on, is undone and fails with storage.ErrConflict, to be
+// retried. With WithReadOnly, the client only pulls, so that the tree can be
+// changed by reviewed pull requests alone.
+package gitrepo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/memory"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/readonly"
+)
+
+type key struct {
+	rowType string
+	id      string
+}
+
+type Client struct {
+	dir     string
+	gitArgs []string
+	// upstream is whether the branch has an upstream to pull from and push
+	// to
+	upstream bool
+
+	// mu guards rows and the working tree
+	mu   sync.RWMutex
+	rows storage.RowStorer
+}
+
+var (
+	_ storage.RowStorer     = &Client{}
+	_ storage.ChildScanner  = &Client{}
+	_ storage.ColumnPatcher = &Client{}
+)
+
+// Option configures a Client.
+type Option func(*options)
+
+type options struct {
+	readOnly    bool
+	authorName  string
+	authorEmail string
+}
+
+// WithReadOnly refuses writes with storage.ErrReadOnly, for trees that are
+// changed by pull requests.
+func WithReadOnly() Option {
+	return func(o *options) { o.readOnly = true }
+}
+
+// WithAuthor commits as the named author, rather than as the user git is
+// configured with.
+func WithAuthor(name, email string) Option {
+	return func(o *options) {
+		o.authorName = name
+		o.authorEmail = email
+	}
+}
+
+// NewClient keeps rows in dir, a directory of a clone of a git repository,
+// which it creates if it doesn't exist. It pulls the branch's upstream, if
+// it has one, before reading the rows.
+func NewClient(ctx context.Context, dir string, opts ...Option) (storage.RowStorer, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("%w: a directory is required", storage.ErrInvalid)
+	}
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	client := &Client{dir: dir}
+	if o.authorName != "" {
+		client.gitArgs = append(client.gitArgs, "-c", "user.name="+o.authorName)
+	}
+	if o.authorEmail != "" {
+		client.gitArgs = append(client.gitArgs, "-c", "user.email="+o.authorEmail)
+	}
+	if _, err := client.git(ctx, "rev-parse", "--show-toplevel"); err != nil {
+		return nil, fmt.Errorf("%w: %s isn't in a git repository: %w", storage.ErrInvalid, dir, err)
+	}
+	_, err := client.git(ctx, "rev-parse", "--abbrev-ref", "--symbolic-full-name", "@{upstream}")
+	client.upstream = err == nil
+	if _, err := client.pull(ctx); err != nil {
+		return nil, err
+	}
+	if err := client.load(ctx); err != nil {
+		return nil, err
+	}
+	if o.readOnly {
+		return readonly.NewStorer(client), nil
+	}
+	return client, nil
+}
+
+// Refresh pulls the branch's upstream, and reads the rows again if that
+// brought in commits.
+func (client *Client) Refresh(ctx context.Context) error {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	return client.sync(ctx)
+}
+
+// sync pulls the branch's upstream, and reads the rows again if that brought
+// in commits. Callers must hold the lock.
+func (client *Client) sync(ctx context.Context) error {
+	pulled, err := client.pull(ctx)
+	if err != nil || !pulled {
+		return err
+	}
+	return client.load(ctx)
+}
+
+// pull fast-forwards the branch to its upstream, if it has one, returning
+// whether that brought in commits.
+func (client *Client) pull(ctx context.Context) (bool, error) {
+	if !client.upstream {
+		return false, nil
+	}
+	before, err := client.git(ctx, "rev-parse", "HEAD")
+	if err != nil {
+		return false, err
+	}
+	if _, err := client.git(ctx, "pull", "--ff-only", "--quiet"); err != nil {
+		return false, err
+	}
+	after, err := client.git(ctx, "rev-parse", "HEAD")
+	if err != nil {
+		return false, err
+	}
+	return before != after, nil
+}
+
+// load reads every row's file into memory. Callers must hold the lock, or
+// be creating the client.
+func (client *Client) load(ctx context.Context) error {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemGit, fmt.Sprintf("Reading the rows in %s", client.dir))
+	rows := memory.NewClient()
+	types, err := os.ReadDir(client.dir)
+	if err != nil {
+		return err
+	}
+	for _, t := range types {
+		if !t.IsDir() || strings.HasPrefix(t.Name(), ".") {
+			continue
+		}
+		files, err := os.ReadDir(filepath.Join(client.dir, t.Name()))
+		if err != nil {
+			return err
+		}
+		for _, f := range files {
+			id, ok := strings.CutSuffix(f.Name(), ".yaml")
+			if f.IsDir() || !ok {
+				continue
+			}
+			b, err := os.ReadFile(filepath.Join(client.dir, t.Name(), f.Name()))
+			if err != nil {
+				return err
+			}
+			r, err := decodeRow(t.Name(), id, b)
+			if err != nil {
+				return err
+			}
+			if err := rows.PutRow(ctx, r); err != nil {
+				return err
+			}
+		}
+	}
+	client.rows = rows
+	return nil
+}
+
+// rowPath returns the path of a row's file, relative to the directory.
+// Types and IDs name files, so they must be valid file names.
+func rowPath(rowType, id string) (string, error) {
+	for _, name := range []string{rowType, id} {
+		if name == "" || strings.HasPrefix(name, ".") || strings.ContainsAny(name, `/\`) {
+			return "", fmt.Errorf("%w: %q can't name a file", storage.ErrInvalid, name)
+		}
+	}
+	return filepath.Join(rowType, id+".yaml"), nil
+}
+
+// write makes a change to the rows in memory, then writes the files of the
+// rows it changed, and commits and pushes them. change returns the commit's
+// message and the rows it changed. If anything fails, the change is undone,
+// in memory and in the working tree.
+func (client *Client) write(ctx context.Context, change func(rows storage.RowStorer) (string, []key, error)) error {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	if err := client.sync(ctx); err != nil {
+		return err
+	}
+	// a new repository has no commit yet
+	head, _ := client.git(ctx, "rev-parse", "--verify", "--quiet", "HEAD")
+	message, changed, err := change(client.rows)
+	if err != nil {
+		return err
+	}
+	if err := client.commit(ctx, message, changed); err != nil {
+		return client.undo(ctx, head, changed, err)
+	}
+	return nil
+}
+
+// commit writes the files of the changed rows, removing those of rows that
+// no longer exist, and commits and pushes them. Callers must hold the lock.
+func (client *Client) commit(ctx context.Context, message string, changed []key) error {
+	paths := make([]string, 0, len(changed))
+	for _, k := range changed {
+		path, err := rowPath(k.rowType, k.id)
+		if err != nil {
+			return err
+		}
+		paths = append(paths, path)
+		r, err := client.rows.GetRowByID(ctx, k.rowType, k.id)
+		if errors.Is(err, storage.ErrNotFoundRow) {
+			if err := os.Remove(filepath.Join(client.dir, path)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		b, err := encodeRow(r)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Join(client.dir, k.rowType), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(client.dir, path), b, 0o644); err != nil {
+			return err
+		}
+	}
+
+	if _, err := client.git(ctx, append([]string{"add", "--all", "--"}, paths...)...); err != nil {
+		return err
+	}
+	// a write that changed nothing, such as setting a column to its value,
+	// has nothing to commit
+	if _, err := client.git(ctx, append([]string{"diff", "--cached", "--quiet", "--"}, paths...)...); err == nil {
+		return nil
+	}
+	if _, err := client.git(ctx, append([]string{"commit", "--quiet", "--message", message, "--"}, paths...)...); err != nil {
+		return err
+	}
+	if !client.upstream {
+		return nil
+	}
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemGit, "Pushing "+message)
+	if _, err := client.git(ctx, "push", "--quiet"); err != nil {
+		if strings.Contains(err.Error(), "rejected") {
+			return fmt.Errorf("%w: the branch changed while writing; try again: %w", storage.ErrConflict, err)
+		}
+		return err
+	}
+	return nil
+}
+
+// undo puts the branch back at head, or, if head is empty, before its first
+// commit, and the changed rows' files as they were, then reads the rows
+// again, after a write failed with err, which it returns. Callers must hold
+// the lock.
+func (client *Client) undo(ctx context.Context, head string, changed []key, err error) error {
+	tflog.SubsystemWarn(ctx, storage.LogSubsystemGit, fmt.Sprintf("Undoing a failed write: %s", err))
+	var resetErr error
+	if head == "" {
+		// deleting the ref of a branch with no commits fails, harmlessly
+		_, _ = client.git(ctx, "update-ref", "-d", "HEAD")
+	} else {
+		_, resetErr = client.git(ctx, "reset", "--quiet", "--soft", head)
+	}
+	if resetErr != nil {
+		return errors.Join(err, resetErr)
+	}
+	for _, k := range changed {
+		path, pathErr := rowPath(k.rowType, k.id)
+		if pathErr != nil {
+			continue
+		}
+		if head == "" {
+			// no file was in the branch
+		} else if _, inHead := client.git(ctx, "cat-file", "-e", head+":"+filepath.ToSlash(path)); inHead == nil {
+			_, restoreErr := client.git(ctx, "checkout", "--quiet", head, "--", path)
+			if restoreErr != nil {
+				return errors.Join(err, restoreErr)
+			}
+			continue
+		}
+		_, _ = client.git(ctx, "rm", "--quiet", "--cached", "--ignore-unmatch", "--", path)
+		if removeErr := os.Remove(filepath.Join(client.dir, path)); removeErr != nil && !errors.Is(removeErr, fs.ErrNotExist) {
+			return errors.Join(err, removeErr)
+		}
+	}
+	if loadErr := client.load(ctx); loadErr != nil {
+		return errors.Join(err, loadErr)
+	}
+	return err
+}
+
+// git runs git in the directory, returning its output.
+func (client *Client) git(ctx context.Context, args ...string) (string, error) {
+	tflog.SubsystemTrace(ctx, storage.LogSubsystemGit, "git "+strings.Join(args, " "))
+	cmd := exec.CommandContext(ctx, "git", append(client.gitArgs, args...)...)
+	cmd.Dir = client.dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func (client *Client) GetRowByID(ctx context.Context, rowType, id string) (storage.Row, error) {
+	client.mu.RLock()
+	defer client.mu.RUnlock()
+	return client.rows.GetRowByID(ctx, rowType, id)
+}
+
+func (client *Client) GetRow(ctx context.Context, rowType, label string) (storage.Row, error) {
+	client.mu.RLock()
+	defer client.mu.RUnlock()
+	return client.rows.GetRow(ctx, rowType, label)
+}
+
+func (client *Client) CreateRow(ctx context.Context, rowType, label string) (storage.Row, error) {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemGit, fmt.Sprintf("CreateRow %q %q", rowType, label))
+	var created storage.Row
+	err := client.write(ctx, func(rows storage.RowStorer) (string, []key, error) {
+		var err error
+		if created, err = rows.CreateRow(ctx, rowType, label); err != nil {
+			return "", nil, err
+		}
+		return fmt.Sprintf("Create %s %s (%s)", rowType, label, created.ID()), []key{{rowType, created.ID()}}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+func (client *Client) CreateChild(ctx context.Context, rowType, label, parentType, parentID string, columns map[string]interface{}) (storage.Row, error) {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemGit, fmt.Sprintf("CreateChild %q %q %q %q", rowType, label, parentType, parentID))
+	var created storage.Row
+	err := client.write(ctx, func(rows storage.RowStorer) (string, []key, error) {
+		var err error
+		if created, err = rows.CreateChild(ctx, rowType, label, parentType, parentID, columns); err != nil {
+			return "", nil, err
+		}
+		return fmt.Sprintf("Create %s %s (%s) under %s", rowType, label, created.ID(), parentID), []key{{rowType, created.ID()}}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+func (client *Client) GetChild(ctx context.Context, label, parentID string) (storage.Row, error) {
+	client.mu.RLock()
+	defer client.mu.RUnlock()
+	return client.rows.GetChild(ctx, label, parentID)
+}
+
+func (client *Client) ListRows(ctx context.Context, rowType, labelFilter, parentIDFilter string) ([]storage.Row, error) {
+	client.mu.RLock()
+	defer client.mu.RUnlock()
+	return client.rows.ListRows(ctx, rowType, labelFilter, parentIDFilter)
+}
+
+func (client *Client) UpdateRow(ctx context.Context, rowType, id, newLabel string) (storage.Row, error) {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemGit, fmt.Sprintf("UpdateRow %q %q %q", rowType, id, newLabel))
+	var updated storage.Row
+	err := client.write(ctx, func(rows storage.RowStorer) (string, []key, error) {
+		var err error
+		if updated, err = rows.UpdateRow(ctx, rowType, id, newLabel); err != nil {
+			return "", nil, err
+		}
+		return fmt.Sprintf("Rename %s %s to %s", rowType, id, newLabel), []key{{rowType, id}}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+func (client *Client) UpdateChild(ctx context.Context, childType, childID, newChildLabel, parentType, newParentID string) (storage.Row, error) {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemGit, fmt.Sprintf("UpdateChild %q %q %q %q %q", childType, childID, newChildLabel, parentType, newParentID))
+	var updated storage.Row
+	err := client.write(ctx, func(rows storage.RowStorer) (string, []key, error) {
+		var err error
+		if updated, err = rows.UpdateChild(ctx, childType, childID, newChildLabel, parentType, newParentID); err != nil {
+			return "", nil, err
+		}
+		return fmt.Sprintf("Move %s %s to %s under %s", childType, childID, newChildLabel, newParentID), []key{{childType, childID}}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+func (client *Client) UpdateColumn(ctx context.Context, rowType, rowID, columnName string, columnValue interface{}) error {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemGit, fmt.Sprintf("UpdateColumn %q %q %q %s", rowType, rowID, columnName, storage.LogColumnValue(rowType, columnName, columnValue)))
+	return client.write(ctx, func(rows storage.RowStorer) (string, []key, error) {
+		if err := rows.UpdateColumn(ctx, rowType, rowID, columnName, columnValue); err != nil {
+			return "", nil, err
+		}
+		return fmt.Sprintf("Update %s of %s %s", columnName, rowType, rowID), []key{{rowType, rowID}}, nil
+	})
+}
+
+// PatchColumns applies every patch in one commit.
+func (client *Client) PatchColumns(ctx context.Context, patches []storage.ColumnPatch) error {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemGit, fmt.Sprintf("PatchColumns %d", len(patches)))
+	return client.write(ctx, func(rows storage.RowStorer) (string, []key, error) {
+		changed := make([]key, len(patches))
+		for i, patch := range patches {
+			changed[i] = key{patch.Type, patch.ID}
+		}
+		// the in-memory backend applies all of the patches or none
+		if err := storage.PatchColumns(ctx, rows, patches); err != nil {
+			return "", nil, err
+		}
+		return fmt.Sprintf("Update the columns of %d rows", len(patches)), changed, nil
+	})
+}
+
+func (client *Client) UpdateColumns(ctx context.Context, rowType, rowID string, columns map[string]interface{}) error {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemGit, fmt.Sprintf("UpdateColumns %q %q %s", rowType, rowID, storage.LogColumns(rowType, columns)))
+	return client.write(ctx, func(rows storage.RowStorer) (string, []key, error) {
+		if err := rows.UpdateColumns(ctx, rowType, rowID, columns); err != nil {
+			return "", nil, err
+		}
+		return fmt.Sprintf("Update the columns of %s %s", rowType, rowID), []key{{rowType, rowID}}, nil
+	})
+}
+
+func (client *Client) DeleteRow(ctx context.Context, rowType, childType, id string) error {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemGit, fmt.Sprintf("DeleteRow %q %q %q", rowType, childType, id))
+	return client.write(ctx, func(rows storage.RowStorer) (string, []key, error) {
+		if err := rows.DeleteRow(ctx, rowType, childType, id); err != nil {
+			return "", nil, err
+		}
+		return fmt.Sprintf("Delete %s %s", rowType, id), []key{{rowType, id}}, nil
+	})
+}
+
+func (client *Client) PutRow(ctx context.Context, r storage.Row) error {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemGit, fmt.Sprintf("PutRow %q %q", r.Type(), r.ID()))
+	return client.write(ctx, func(rows storage.RowStorer) (string, []key, error) {
+		if _, err := rowPath(r.Type(), r.ID()); err != nil {
+			return "", nil, err
+		}
+		if err := rows.PutRow(ctx, r); err != nil {
+			return "", nil, err
+		}
+		return fmt.Sprintf("Put %s %s", r.Type(), r.ID()), []key{{r.Type(), r.ID()}}, nil
+	})
+}
+
+func (client *Client) ScanRows(ctx context.Context, fn func(storage.Row) error) error {
+	client.mu.RLock()
+	defer client.mu.RUnlock()
+	return client.rows.ScanRows(ctx, fn)
+}
+
+func (client *Client) ScanChildren(ctx context.Context, parentID string, fn func(storage.Row) error) error {
+	client.mu.RLock()
+	defer client.mu.RUnlock()
+	return storage.ScanChildren(ctx, client.rows, parentID, fn)
+}
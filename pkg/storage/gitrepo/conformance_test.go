@@ -0,0 +1,28 @@
+package gitrepo_test
+
+import (
+	"context"
+	"os/exec"
+	"testing"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/gitrepo"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/storagetest"
+)
+
+func TestConformance(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git isn't installed")
+	}
+	storagetest.Run(t, func(t *testing.T) storage.RowStorer {
+		dir := t.TempDir()
+		if out, err := exec.Command("git", "init", "--quiet", dir).CombinedOutput(); err != nil {
+			t.Fatalf("git init: %s: %s", err, out)
+		}
+		client, err := gitrepo.NewClient(context.Background(), dir, gitrepo.WithAuthor("storagetest", "storagetest@example.com"))
+		if err != nil {
+			t.Fatalf("NewClient: %s", err)
+		}
+		return client
+	})
+}
@@ -0,0 +1,80 @@
+package gitrepo
+
+import (
+	"fmt"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+	"gopkg.in/yaml.v3"
+)
+
+type row struct {
+	RowType     string
+	RowID       string
+	RowLabel    string
+	RowParentID string
+	RowColumns  map[string]interface{}
+}
+
+// rowFile is what a row's file holds. Its type and ID are in its path.
+type rowFile struct {
+	Label    string                 `yaml:"label"`
+	ParentID string                 `yaml:"parent_id,omitempty"`
+	Columns  map[string]interface{} `yaml:"columns"`
+}
+
+// encodeRow returns the YAML a row's file holds.
+func encodeRow(r storage.Row) ([]byte, error) {
+	columns := r.Columns()
+	if columns == nil {
+		columns = map[string]interface{}{}
+	}
+	b, err := yaml.Marshal(rowFile{Label: r.Label(), ParentID: r.ParentID(), Columns: columns})
+	if err != nil {
+		return nil, fmt.Errorf("%w: encoding columns: %s", storage.ErrInvalid, err)
+	}
+	return b, nil
+}
+
+// decodeRow returns the row with the type and ID whose file holds encoded,
+// with YAML sequences as string sets, the only kind of list rows store.
+// Files are edited by hand and reviewed, so a column that isn't a string,
+// such as an unquoted number, is an error rather than converted.
+func decodeRow(rowType, id string, encoded []byte) (*row, error) {
+	var f rowFile
+	if err := yaml.Unmarshal(encoded, &f); err != nil {
+		return nil, fmt.Errorf("decoding %s %s: %w", rowType, id, err)
+	}
+	if f.Columns == nil {
+		f.Columns = map[string]interface{}{}
+	}
+	for name, value := range f.Columns {
+		switch v := value.(type) {
+		case nil, string:
+		case []interface{}:
+			set := make([]string, 0, len(v))
+			for _, element := range v {
+				s, ok := element.(string)
+				if !ok {
+					return nil, fmt.Errorf("decoding %s %s: column %q isn't a set of strings", rowType, id, name)
+				}
+				set = append(set, s)
+			}
+			f.Columns[name] = set
+		default:
+			return nil, fmt.Errorf("decoding %s %s: column %q isn't a string; quote it", rowType, id, name)
+		}
+	}
+	return &row{
+		RowType:     rowType,
+		RowID:       id,
+		RowLabel:    f.Label,
+		RowParentID: f.ParentID,
+		RowColumns:  f.Columns,
+	}, nil
+}
+
+func (r *row) Type() string                    { return r.RowType }
+func (r *row) ID() string                      { return r.RowID }
+func (r *row) Label() string                   { return r.RowLabel }
+func (r *row) ParentID() string                { return r.RowParentID }
+func (r *row) Columns() map[string]interface{} { return r.RowColumns }
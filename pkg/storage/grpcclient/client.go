@@ -0,0 +1,335 @@
+// Package grpcclient implements storage.RowStorer against the gRPC storage
+// service of storagepb, so that the provider can use a centrally hosted
+// backend over mutual TLS. Register serves the service in front of any other
+// storer, as cmd/storaged does.
+package grpcclient
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/grpcclient/storagepb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+)
+
+type Client struct {
+	conn *grpc.ClientConn
+	rows storagepb.RowStorerClient
+}
+
+var (
+	_ storage.RowStorer     = &Client{}
+	_ storage.ChildScanner  = &Client{}
+	_ storage.ColumnPatcher = &Client{}
+)
+
+// NewClient returns a client of the storage service at target, such as
+// "storage.internal:8443", which it connects to over TLS with tlsConfig, or
+// without TLS if tlsConfig is nil. It connects when it is first used.
+func NewClient(target string, tlsConfig *tls.Config) (storage.RowStorer, error) {
+	creds := insecure.NewCredentials()
+	if tlsConfig != nil {
+		creds = credentials.NewTLS(tlsConfig)
+	}
+	conn, err := grpc.NewClient(target, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", storage.ErrInvalid, err)
+	}
+	return &Client{conn: conn, rows: storagepb.NewRowStorerClient(conn)}, nil
+}
+
+// ClientTLS returns the TLS configuration of a client presenting the
+// certificate and key in the PEM files certFile and keyFile, for mutual TLS,
+// and trusting the certificate authorities in caFile. Without certFile and
+// keyFile, the client presents no certificate; without caFile, it trusts the
+// system's authorities.
+func ClientTLS(certFile, keyFile, caFile string) (*tls.Config, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	if caFile != "" {
+		pool, err := loadCertPool(caFile)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = pool
+	}
+	return config, nil
+}
+
+// ServerTLS returns the TLS configuration of a server presenting the
+// certificate and key in the PEM files certFile and keyFile, and requiring
+// clients to present certificates of the authorities in clientCAFile.
+func ServerTLS(certFile, keyFile, clientCAFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("loading server certificate: %w", err)
+	}
+	pool, err := loadCertPool(clientCAFile)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	}, nil
+}
+
+func loadCertPool(file string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("loading certificate authorities: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("loading certificate authorities: %s has no PEM certificates", file)
+	}
+	return pool, nil
+}
+
+// Close closes the client's connection to the service.
+func (client *Client) Close() error {
+	return client.conn.Close()
+}
+
+func (client *Client) GetRowByID(ctx context.Context, rowType, rowID string) (storage.Row, error) {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemGRPC, fmt.Sprintf("GetRowByID %q %q", rowType, rowID))
+	ctx = outgoing(ctx)
+	r, err := client.rows.GetRowByID(ctx, &storagepb.GetRowByIDRequest{Type: rowType, Id: rowID})
+	if err != nil {
+		return nil, fromStatus(ctx, err)
+	}
+	return fromPB(r), nil
+}
+
+func (client *Client) GetRow(ctx context.Context, rowType, rowLabel string) (storage.Row, error) {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemGRPC, fmt.Sprintf("GetRow %q %q", rowType, rowLabel))
+	ctx = outgoing(ctx)
+	r, err := client.rows.GetRow(ctx, &storagepb.GetRowRequest{Type: rowType, Label: rowLabel})
+	if err != nil {
+		return nil, fromStatus(ctx, err)
+	}
+	return fromPB(r), nil
+}
+
+func (client *Client) CreateRow(ctx context.Context, rowType, rowLabel string) (storage.Row, error) {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemGRPC, fmt.Sprintf("CreateRow %q %q", rowType, rowLabel))
+	ctx = outgoing(ctx)
+	r, err := client.rows.CreateRow(ctx, &storagepb.CreateRowRequest{Type: rowType, Label: rowLabel})
+	if err != nil {
+		return nil, fromStatus(ctx, err)
+	}
+	return fromPB(r), nil
+}
+
+func (client *Client) CreateChild(ctx context.Context, rowType, rowLabel, parentType, parentID string, columns map[string]interface{}) (storage.Row, error) {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemGRPC, fmt.Sprintf("CreateChild %q %q %q %q", rowType, rowLabel, parentType, parentID))
+	values, err := toColumns(columns)
+	if err != nil {
+		return nil, err
+	}
+	ctx = outgoing(ctx)
+	r, err := client.rows.CreateChild(ctx, &storagepb.CreateChildRequest{
+		Type:       rowType,
+		Label:      rowLabel,
+		ParentType: parentType,
+		ParentId:   parentID,
+		Columns:    values,
+	})
+	if err != nil {
+		return nil, fromStatus(ctx, err)
+	}
+	return fromPB(r), nil
+}
+
+func (client *Client) GetChild(ctx context.Context, childLabel, parentID string) (storage.Row, error) {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemGRPC, fmt.Sprintf("GetChild %q %q", childLabel, parentID))
+	ctx = outgoing(ctx)
+	r, err := client.rows.GetChild(ctx, &storagepb.GetChildRequest{Label: childLabel, ParentId: parentID})
+	if err != nil {
+		return nil, fromStatus(ctx, err)
+	}
+	return fromPB(r), nil
+}
+
+func (client *Client) ListRows(ctx context.Context, rowType, labelFilter, parentIDFilter string) ([]storage.Row, error) {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemGRPC, fmt.Sprintf("ListRows %q %q %q", rowType, labelFilter, parentIDFilter))
+	ctx = outgoing(ctx)
+	resp, err := client.rows.ListRows(ctx, &storagepb.ListRowsRequest{
+		Type:           rowType,
+		LabelFilter:    labelFilter,
+		ParentIdFilter: parentIDFilter,
+	})
+	if err != nil {
+		return nil, fromStatus(ctx, err)
+	}
+	rows := make([]storage.Row, len(resp.GetRows()))
+	for i, r := range resp.GetRows() {
+		rows[i] = fromPB(r)
+	}
+	return rows, nil
+}
+
+func (client *Client) UpdateRow(ctx context.Context, rowType, rowID, newLabel string) (storage.Row, error) {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemGRPC, fmt.Sprintf("UpdateRow %q %q %q", rowType, rowID, newLabel))
+	ctx = outgoing(ctx)
+	r, err := client.rows.UpdateRow(ctx, &storagepb.UpdateRowRequest{Type: rowType, Id: rowID, Label: newLabel})
+	if err != nil {
+		return nil, fromStatus(ctx, err)
+	}
+	return fromPB(r), nil
+}
+
+func (client *Client) UpdateChild(ctx context.Context, childType, childID, newChildLabel, parentType, newParentID string) (storage.Row, error) {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemGRPC, fmt.Sprintf("UpdateChild %q %q %q %q %q", childType, childID, newChildLabel, parentType, newParentID))
+	ctx = outgoing(ctx)
+	r, err := client.rows.UpdateChild(ctx, &storagepb.UpdateChildRequest{
+		Type:       childType,
+		Id:         childID,
+		Label:      newChildLabel,
+		ParentType: parentType,
+		ParentId:   newParentID,
+	})
+	if err != nil {
+		return nil, fromStatus(ctx, err)
+	}
+	return fromPB(r), nil
+}
+
+func (client *Client) UpdateColumn(ctx context.Context, rowType, rowID, columnName string, columnValue interface{}) error {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemGRPC, fmt.Sprintf("UpdateColumn %q %q %q %s", rowType, rowID, columnName, storage.LogColumnValue(rowType, columnName, columnValue)))
+	value, err := toValue(columnValue)
+	if err != nil {
+		return fmt.Errorf("column %q: %w", columnName, err)
+	}
+	ctx = outgoing(ctx)
+	_, err = client.rows.UpdateColumn(ctx, &storagepb.UpdateColumnRequest{Type: rowType, Id: rowID, Name: columnName, Value: value})
+	if err != nil {
+		return fromStatus(ctx, err)
+	}
+	return nil
+}
+
+func (client *Client) UpdateColumns(ctx context.Context, rowType, rowID string, columns map[string]interface{}) error {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemGRPC, fmt.Sprintf("UpdateColumns %q %q %s", rowType, rowID, storage.LogColumns(rowType, columns)))
+	values, err := toColumns(columns)
+	if err != nil {
+		return err
+	}
+	ctx = outgoing(ctx)
+	_, err = client.rows.UpdateColumns(ctx, &storagepb.UpdateColumnsRequest{Type: rowType, Id: rowID, Columns: values})
+	if err != nil {
+		return fromStatus(ctx, err)
+	}
+	return nil
+}
+
+// PatchColumns sends every patch in one call, which the server applies as
+// its storer's PatchColumns does.
+func (client *Client) PatchColumns(ctx context.Context, patches []storage.ColumnPatch) error {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemGRPC, fmt.Sprintf("PatchColumns %d", len(patches)))
+	request := &storagepb.PatchColumnsRequest{Patches: make([]*storagepb.ColumnPatch, len(patches))}
+	for i, patch := range patches {
+		values, err := toColumns(patch.Columns)
+		if err != nil {
+			return fmt.Errorf("%s %s: %w", patch.Type, patch.ID, err)
+		}
+		request.Patches[i] = &storagepb.ColumnPatch{Type: patch.Type, Id: patch.ID, Columns: values}
+	}
+	ctx = outgoing(ctx)
+	if _, err := client.rows.PatchColumns(ctx, request); err != nil {
+		return fromStatus(ctx, err)
+	}
+	return nil
+}
+
+func (client *Client) DeleteRow(ctx context.Context, rowType, childType, rowID string) error {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemGRPC, fmt.Sprintf("DeleteRow %q %q %q", rowType, childType, rowID))
+	ctx = outgoing(ctx)
+	_, err := client.rows.DeleteRow(ctx, &storagepb.DeleteRowRequest{Type: rowType, ChildType: childType, Id: rowID})
+	if err != nil {
+		return fromStatus(ctx, err)
+	}
+	return nil
+}
+
+func (client *Client) PutRow(ctx context.Context, r storage.Row) error {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemGRPC, fmt.Sprintf("PutRow %q %q", r.Type(), r.ID()))
+	request, err := toPB(r)
+	if err != nil {
+		return err
+	}
+	ctx = outgoing(ctx)
+	if _, err := client.rows.PutRow(ctx, request); err != nil {
+		return fromStatus(ctx, err)
+	}
+	return nil
+}
+
+// ScanRows streams every row from the service in one call.
+func (client *Client) ScanRows(ctx context.Context, fn func(storage.Row) error) error {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemGRPC, "ScanRows")
+	ctx, cancel := context.WithCancel(outgoing(ctx))
+	defer cancel()
+	stream, err := client.rows.ScanRows(ctx, &storagepb.ScanRowsRequest{})
+	if err != nil {
+		return fromStatus(ctx, err)
+	}
+	return receive(ctx, stream, fn)
+}
+
+// ScanChildren streams the row's children from the service in one call.
+func (client *Client) ScanChildren(ctx context.Context, parentID string, fn func(storage.Row) error) error {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemGRPC, fmt.Sprintf("ScanChildren %q", parentID))
+	ctx, cancel := context.WithCancel(outgoing(ctx))
+	defer cancel()
+	stream, err := client.rows.ScanChildren(ctx, &storagepb.ScanChildrenRequest{ParentId: parentID})
+	if err != nil {
+		return fromStatus(ctx, err)
+	}
+	return receive(ctx, stream, fn)
+}
+
+// receive calls fn with each row the stream receives. The stream's context
+// must be cancelled when it returns, which, if fn stopped the scan, stops
+// the server's too.
+func receive(ctx context.Context, stream grpc.ServerStreamingClient[storagepb.Row], fn func(storage.Row) error) error {
+	for {
+		r, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fromStatus(ctx, err)
+		}
+		if err := fn(fromPB(r)); err != nil {
+			return err
+		}
+	}
+}
+
+// outgoing returns ctx with the correlation ID of its operation, if it has
+// one, in the metadata of the calls made with it.
+func outgoing(ctx context.Context) context.Context {
+	if id := storage.CorrelationID(ctx); id != "" {
+		return metadata.AppendToOutgoingContext(ctx, correlationIDKey, id)
+	}
+	return ctx
+}
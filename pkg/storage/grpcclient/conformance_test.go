@@ -0,0 +1,39 @@
+package grpcclient
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/grpcclient/storagepb"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/memory"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/storagetest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.RowStorer {
+		listener := bufconn.Listen(1 << 20)
+		server := grpc.NewServer()
+		Register(server, memory.NewClient(), nil)
+		go server.Serve(listener)
+		t.Cleanup(server.Stop)
+
+		// NewClient dials a network address, so the client is made here
+		// with a connection to the listener in memory
+		conn, err := grpc.NewClient("passthrough:///bufconn",
+			grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+				return listener.DialContext(ctx)
+			}),
+			grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if err != nil {
+			t.Fatalf("grpc.NewClient: %s", err)
+		}
+		client := &Client{conn: conn, rows: storagepb.NewRowStorerClient(conn)}
+		t.Cleanup(func() { client.Close() })
+		return client
+	})
+}
@@ -0,0 +1,162 @@
+package grpcclient
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/grpcclient/storagepb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+type server struct {
+	storagepb.UnimplementedRowStorerServer
+
+	storer   storage.RowStorer
+	errorLog *log.Logger
+}
+
+// Register registers the storage service, serving the storer's rows, with
+// the gRPC server. It logs the errors of calls that fail unexpectedly to
+// errorLog, or with the log package if errorLog is nil. Callers are
+// authenticated by the server's transport, such as with ServerTLS.
+func Register(registrar grpc.ServiceRegistrar, storer storage.RowStorer, errorLog *log.Logger) {
+	if errorLog == nil {
+		errorLog = log.Default()
+	}
+	storagepb.RegisterRowStorerServer(registrar, &server{storer: storer, errorLog: errorLog})
+}
+
+func (s *server) GetRowByID(ctx context.Context, req *storagepb.GetRowByIDRequest) (*storagepb.Row, error) {
+	return s.rowResponse(ctx, "GetRowByID")(s.storer.GetRowByID(ctx, req.GetType(), req.GetId()))
+}
+
+func (s *server) GetRow(ctx context.Context, req *storagepb.GetRowRequest) (*storagepb.Row, error) {
+	return s.rowResponse(ctx, "GetRow")(s.storer.GetRow(ctx, req.GetType(), req.GetLabel()))
+}
+
+func (s *server) CreateRow(ctx context.Context, req *storagepb.CreateRowRequest) (*storagepb.Row, error) {
+	return s.rowResponse(ctx, "CreateRow")(s.storer.CreateRow(ctx, req.GetType(), req.GetLabel()))
+}
+
+func (s *server) CreateChild(ctx context.Context, req *storagepb.CreateChildRequest) (*storagepb.Row, error) {
+	return s.rowResponse(ctx, "CreateChild")(s.storer.CreateChild(ctx, req.GetType(), req.GetLabel(), req.GetParentType(), req.GetParentId(), fromColumns(req.GetColumns())))
+}
+
+func (s *server) GetChild(ctx context.Context, req *storagepb.GetChildRequest) (*storagepb.Row, error) {
+	return s.rowResponse(ctx, "GetChild")(s.storer.GetChild(ctx, req.GetLabel(), req.GetParentId()))
+}
+
+func (s *server) ListRows(ctx context.Context, req *storagepb.ListRowsRequest) (*storagepb.ListRowsResponse, error) {
+	found, err := s.storer.ListRows(ctx, req.GetType(), req.GetLabelFilter(), req.GetParentIdFilter())
+	if err != nil {
+		return nil, s.status(ctx, "ListRows", err)
+	}
+	resp := &storagepb.ListRowsResponse{Rows: make([]*storagepb.Row, len(found))}
+	for i, r := range found {
+		if resp.Rows[i], err = toPB(r); err != nil {
+			return nil, s.status(ctx, "ListRows", err)
+		}
+	}
+	return resp, nil
+}
+
+func (s *server) UpdateRow(ctx context.Context, req *storagepb.UpdateRowRequest) (*storagepb.Row, error) {
+	return s.rowResponse(ctx, "UpdateRow")(s.storer.UpdateRow(ctx, req.GetType(), req.GetId(), req.GetLabel()))
+}
+
+func (s *server) UpdateChild(ctx context.Context, req *storagepb.UpdateChildRequest) (*storagepb.Row, error) {
+	return s.rowResponse(ctx, "UpdateChild")(s.storer.UpdateChild(ctx, req.GetType(), req.GetId(), req.GetLabel(), req.GetParentType(), req.GetParentId()))
+}
+
+func (s *server) UpdateColumn(ctx context.Context, req *storagepb.UpdateColumnRequest) (*emptypb.Empty, error) {
+	return s.emptyResponse(ctx, "UpdateColumn", s.storer.UpdateColumn(ctx, req.GetType(), req.GetId(), req.GetName(), fromValue(req.GetValue())))
+}
+
+func (s *server) UpdateColumns(ctx context.Context, req *storagepb.UpdateColumnsRequest) (*emptypb.Empty, error) {
+	return s.emptyResponse(ctx, "UpdateColumns", s.storer.UpdateColumns(ctx, req.GetType(), req.GetId(), fromColumns(req.GetColumns())))
+}
+
+func (s *server) PatchColumns(ctx context.Context, req *storagepb.PatchColumnsRequest) (*emptypb.Empty, error) {
+	patches := make([]storage.ColumnPatch, len(req.GetPatches()))
+	for i, patch := range req.GetPatches() {
+		patches[i] = storage.ColumnPatch{Type: patch.GetType(), ID: patch.GetId(), Columns: fromColumns(patch.GetColumns())}
+	}
+	return s.emptyResponse(ctx, "PatchColumns", storage.PatchColumns(ctx, s.storer, patches))
+}
+
+func (s *server) DeleteRow(ctx context.Context, req *storagepb.DeleteRowRequest) (*emptypb.Empty, error) {
+	return s.emptyResponse(ctx, "DeleteRow", s.storer.DeleteRow(ctx, req.GetType(), req.GetChildType(), req.GetId()))
+}
+
+func (s *server) PutRow(ctx context.Context, req *storagepb.Row) (*emptypb.Empty, error) {
+	return s.emptyResponse(ctx, "PutRow", s.storer.PutRow(ctx, fromPB(req)))
+}
+
+func (s *server) ScanRows(_ *storagepb.ScanRowsRequest, stream grpc.ServerStreamingServer[storagepb.Row]) error {
+	ctx := stream.Context()
+	if err := s.storer.ScanRows(ctx, send(stream)); err != nil {
+		return s.status(ctx, "ScanRows", err)
+	}
+	return nil
+}
+
+func (s *server) ScanChildren(req *storagepb.ScanChildrenRequest, stream grpc.ServerStreamingServer[storagepb.Row]) error {
+	ctx := stream.Context()
+	if err := storage.ScanChildren(ctx, s.storer, req.GetParentId(), send(stream)); err != nil {
+		return s.status(ctx, "ScanChildren", err)
+	}
+	return nil
+}
+
+// send returns a scan's function sending each row on the stream.
+func send(stream grpc.ServerStreamingServer[storagepb.Row]) func(storage.Row) error {
+	return func(r storage.Row) error {
+		pb, err := toPB(r)
+		if err != nil {
+			return err
+		}
+		return stream.Send(pb)
+	}
+}
+
+// rowResponse returns a function returning the response of a row the method
+// found, or its error.
+func (s *server) rowResponse(ctx context.Context, method string) func(storage.Row, error) (*storagepb.Row, error) {
+	return func(found storage.Row, err error) (*storagepb.Row, error) {
+		if err != nil {
+			return nil, s.status(ctx, method, err)
+		}
+		r, err := toPB(found)
+		if err != nil {
+			return nil, s.status(ctx, method, err)
+		}
+		return r, nil
+	}
+}
+
+func (s *server) emptyResponse(ctx context.Context, method string, err error) (*emptypb.Empty, error) {
+	if err != nil {
+		return nil, s.status(ctx, method, err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// status returns the status error of the method's err, logging it if it is
+// unexpected.
+func (s *server) status(ctx context.Context, method string, err error) error {
+	st := toStatus(err)
+	if status.Code(st) == codes.Internal {
+		prefix := method
+		if md, ok := metadata.FromIncomingContext(ctx); ok && len(md.Get(correlationIDKey)) > 0 {
+			prefix = fmt.Sprintf("%s (correlation ID %s)", method, md.Get(correlationIDKey)[0])
+		}
+		s.errorLog.Printf("%s: %s", prefix, err)
+	}
+	return st
+}
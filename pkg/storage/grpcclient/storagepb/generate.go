@@ -0,0 +1,5 @@
+// Package storagepb holds the protocol buffers of the storage service,
+// generated from storage.proto.
+package storagepb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative storage.proto
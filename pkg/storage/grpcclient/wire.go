@@ -0,0 +1,206 @@
+package grpcclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/grpcclient/storagepb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// correlationIDKey is the metadata key of the correlation ID of a call's
+// operation, so that the server's logs can be joined with the client's.
+const correlationIDKey = "x-correlation-id"
+
+type row struct {
+	RowType     string
+	RowID       string
+	RowLabel    string
+	RowParentID string
+	RowColumns  map[string]interface{}
+}
+
+func (r *row) Type() string                    { return r.RowType }
+func (r *row) ID() string                      { return r.RowID }
+func (r *row) Label() string                   { return r.RowLabel }
+func (r *row) ParentID() string                { return r.RowParentID }
+func (r *row) Columns() map[string]interface{} { return r.RowColumns }
+
+func fromPB(r *storagepb.Row) *row {
+	return &row{
+		RowType:     r.GetType(),
+		RowID:       r.GetId(),
+		RowLabel:    r.GetLabel(),
+		RowParentID: r.GetParentId(),
+		RowColumns:  fromColumns(r.GetColumns()),
+	}
+}
+
+func toPB(r storage.Row) (*storagepb.Row, error) {
+	columns, err := toColumns(r.Columns())
+	if err != nil {
+		return nil, fmt.Errorf("%s %s: %w", r.Type(), r.ID(), err)
+	}
+	return &storagepb.Row{
+		Type:     r.Type(),
+		Id:       r.ID(),
+		Label:    r.Label(),
+		ParentId: r.ParentID(),
+		Columns:  columns,
+	}, nil
+}
+
+func toColumns(columns map[string]interface{}) (map[string]*storagepb.Value, error) {
+	values := make(map[string]*storagepb.Value, len(columns))
+	for name, column := range columns {
+		value, err := toValue(column)
+		if err != nil {
+			return nil, fmt.Errorf("column %q: %w", name, err)
+		}
+		values[name] = value
+	}
+	return values, nil
+}
+
+// toValue returns a column's value, which must be a string, a set of
+// strings, or nil, the kinds of value rows store.
+func toValue(column interface{}) (*storagepb.Value, error) {
+	switch v := column.(type) {
+	case nil:
+		return &storagepb.Value{}, nil
+	case string:
+		return &storagepb.Value{Kind: &storagepb.Value_StringValue{StringValue: v}}, nil
+	case []string:
+		return &storagepb.Value{Kind: &storagepb.Value_SetValue{SetValue: &storagepb.StringSet{Values: v}}}, nil
+	}
+	return nil, fmt.Errorf("%w: a %T can't be stored", storage.ErrInvalid, column)
+}
+
+func fromColumns(values map[string]*storagepb.Value) map[string]interface{} {
+	columns := make(map[string]interface{}, len(values))
+	for name, value := range values {
+		columns[name] = fromValue(value)
+	}
+	return columns
+}
+
+func fromValue(value *storagepb.Value) interface{} {
+	switch kind := value.GetKind().(type) {
+	case *storagepb.Value_StringValue:
+		return kind.StringValue
+	case *storagepb.Value_SetValue:
+		set := kind.SetValue.GetValues()
+		if set == nil {
+			set = []string{}
+		}
+		return set
+	}
+	return nil
+}
+
+// errorCodes are the storage errors that cross the wire, by the code of
+// their ErrorDetail, the more specific before the kinds they are of.
+var errorCodes = []struct {
+	code string
+	err  error
+}{
+	{"collision_type_label", storage.ErrCollisionTypeLabel},
+	{"collision_parent_label", storage.ErrCollisionParentLabel},
+	{"cannot_delete_row", storage.ErrCannotDeleteRow},
+	{"too_many_found", storage.ErrTooManyFound},
+	{"read_only", storage.ErrReadOnly},
+	{"too_large", storage.ErrTooLarge},
+	{"unavailable", storage.ErrUnavailable},
+	{"server_error", storage.ErrServerError},
+	{"not_found", storage.ErrNotFoundRow},
+	{"conflict", storage.ErrConflict},
+	{"throttled", storage.ErrThrottled},
+	{"permission_denied", storage.ErrPermissionDenied},
+	{"invalid", storage.ErrInvalid},
+}
+
+// kindCodes are the status codes of the kinds of storage error.
+var kindCodes = map[error]codes.Code{
+	storage.ErrNotFoundRow:      codes.NotFound,
+	storage.ErrConflict:         codes.Aborted,
+	storage.ErrThrottled:        codes.ResourceExhausted,
+	storage.ErrPermissionDenied: codes.PermissionDenied,
+	storage.ErrInvalid:          codes.InvalidArgument,
+}
+
+// toStatus returns the status error of err, with an ErrorDetail naming the
+// storage error it is.
+func toStatus(err error) error {
+	code, ok := kindCodes[storage.ErrorKind(err)]
+	switch {
+	case ok:
+	case errors.Is(err, context.Canceled):
+		code = codes.Canceled
+	case errors.Is(err, context.DeadlineExceeded):
+		code = codes.DeadlineExceeded
+	default:
+		code = codes.Internal
+	}
+	st := status.New(code, err.Error())
+	for _, c := range errorCodes {
+		if errors.Is(err, c.err) {
+			if detailed, err := st.WithDetails(&storagepb.ErrorDetail{Code: c.code}); err == nil {
+				st = detailed
+			}
+			break
+		}
+	}
+	return st.Err()
+}
+
+// remoteError is an error the server returned, of the storage error its
+// status names.
+type remoteError struct {
+	err     error
+	message string
+}
+
+func (err *remoteError) Error() string {
+	return err.message
+}
+
+func (err *remoteError) Unwrap() error {
+	return err.err
+}
+
+// fromStatus returns the storage error of a failed call's error, or the
+// error of ctx if it ended the call. Internal and unknown errors whose status
+// names no storage error are storage.ErrServerError.
+func fromStatus(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+	for _, detail := range st.Details() {
+		if d, ok := detail.(*storagepb.ErrorDetail); ok {
+			for _, c := range errorCodes {
+				if c.code == d.GetCode() {
+					return &remoteError{err: c.err, message: st.Message()}
+				}
+			}
+		}
+	}
+	message := "storage service: " + st.Message()
+	switch st.Code() {
+	case codes.Unauthenticated, codes.PermissionDenied:
+		return &remoteError{err: storage.ErrPermissionDenied, message: message}
+	case codes.ResourceExhausted:
+		return &remoteError{err: storage.ErrThrottled, message: message}
+	case codes.Unavailable:
+		return &remoteError{err: storage.ErrUnavailable, message: message}
+	case codes.Internal, codes.Unknown:
+		return &remoteError{err: storage.ErrServerError, message: message}
+	}
+	return errors.New(message)
+}
@@ -0,0 +1,316 @@
+// Package httpclient implements storage.RowStorer against a storage server's
+// REST API, so that Terraform users need only an HTTP endpoint and a token,
+// while the datastore behind it is owned elsewhere. NewHandler serves that
+// API in front of any other storer, as cmd/storaged does.
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+// maxErrorSize is the most of an error response's body the client reads.
+const maxErrorSize = 1 << 20
+
+type Client struct {
+	// baseURL is the server's URL, without a trailing slash
+	baseURL string
+	token   string
+	http    *http.Client
+}
+
+var (
+	_ storage.RowStorer     = &Client{}
+	_ storage.ChildScanner  = &Client{}
+	_ storage.ColumnPatcher = &Client{}
+)
+
+// NewClient returns a client of the storage server at baseURL, such as
+// "https://storage.example.com", which authenticates its requests with the
+// bearer token.
+func NewClient(baseURL, token string) (storage.RowStorer, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", storage.ErrInvalid, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("%w: %q isn't an http or https URL", storage.ErrInvalid, baseURL)
+	}
+	if token == "" {
+		return nil, fmt.Errorf("%w: a token is required", storage.ErrInvalid)
+	}
+	u.RawQuery = ""
+	u.Fragment = ""
+	return &Client{
+		baseURL: strings.TrimSuffix(u.String(), "/"),
+		token:   token,
+		// requests aren't given a timeout here, since a scan's response
+		// streams for as long as the scan takes; their contexts end them
+		http: &http.Client{},
+	}, nil
+}
+
+func (client *Client) GetRowByID(ctx context.Context, rowType, rowID string) (storage.Row, error) {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemHTTP, fmt.Sprintf("GetRowByID %q %q", rowType, rowID))
+	return client.rowRequest(ctx, http.MethodGet, client.url(nil, "types", rowType, "rows", rowID), nil)
+}
+
+func (client *Client) GetRow(ctx context.Context, rowType, rowLabel string) (storage.Row, error) {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemHTTP, fmt.Sprintf("GetRow %q %q", rowType, rowLabel))
+	return client.rowRequest(ctx, http.MethodGet, client.url(nil, "types", rowType, "labels", rowLabel), nil)
+}
+
+func (client *Client) CreateRow(ctx context.Context, rowType, rowLabel string) (storage.Row, error) {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemHTTP, fmt.Sprintf("CreateRow %q %q", rowType, rowLabel))
+	return client.rowRequest(ctx, http.MethodPost, client.url(nil, "types", rowType, "rows"), &createRequest{
+		Label: rowLabel,
+	})
+}
+
+func (client *Client) CreateChild(ctx context.Context, rowType, rowLabel, parentType, parentID string, columns map[string]interface{}) (storage.Row, error) {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemHTTP, fmt.Sprintf("CreateChild %q %q %q %q", rowType, rowLabel, parentType, parentID))
+	return client.rowRequest(ctx, http.MethodPost, client.url(nil, "types", rowType, "rows"), &createRequest{
+		Label:   rowLabel,
+		Parent:  &parentRef{Type: parentType, ID: parentID},
+		Columns: columns,
+	})
+}
+
+func (client *Client) GetChild(ctx context.Context, childLabel, parentID string) (storage.Row, error) {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemHTTP, fmt.Sprintf("GetChild %q %q", childLabel, parentID))
+	return client.rowRequest(ctx, http.MethodGet, client.url(nil, "parents", parentID, "children", childLabel), nil)
+}
+
+func (client *Client) ListRows(ctx context.Context, rowType, labelFilter, parentIDFilter string) ([]storage.Row, error) {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemHTTP, fmt.Sprintf("ListRows %q %q %q", rowType, labelFilter, parentIDFilter))
+	query := url.Values{}
+	if labelFilter != "" {
+		query.Set("label_filter", labelFilter)
+	}
+	if parentIDFilter != "" {
+		query.Set("parent_id", parentIDFilter)
+	}
+	var response rowsResponse
+	if err := client.do(ctx, http.MethodGet, client.url(query, "types", rowType, "rows"), nil, &response); err != nil {
+		return nil, err
+	}
+	rows := make([]storage.Row, len(response.Rows))
+	for i, r := range response.Rows {
+		if err := r.decode(); err != nil {
+			return nil, err
+		}
+		rows[i] = r
+	}
+	return rows, nil
+}
+
+func (client *Client) UpdateRow(ctx context.Context, rowType, rowID, newLabel string) (storage.Row, error) {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemHTTP, fmt.Sprintf("UpdateRow %q %q %q", rowType, rowID, newLabel))
+	return client.rowRequest(ctx, http.MethodPatch, client.url(nil, "types", rowType, "rows", rowID), &updateRequest{
+		Label: newLabel,
+	})
+}
+
+func (client *Client) UpdateChild(ctx context.Context, childType, childID, newChildLabel, parentType, newParentID string) (storage.Row, error) {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemHTTP, fmt.Sprintf("UpdateChild %q %q %q %q %q", childType, childID, newChildLabel, parentType, newParentID))
+	return client.rowRequest(ctx, http.MethodPatch, client.url(nil, "types", childType, "rows", childID), &updateRequest{
+		Label:  newChildLabel,
+		Parent: &parentRef{Type: parentType, ID: newParentID},
+	})
+}
+
+func (client *Client) UpdateColumn(ctx context.Context, rowType, rowID, columnName string, columnValue interface{}) error {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemHTTP, fmt.Sprintf("UpdateColumn %q %q %q %s", rowType, rowID, columnName, storage.LogColumnValue(rowType, columnName, columnValue)))
+	return client.do(ctx, http.MethodPut, client.url(nil, "types", rowType, "rows", rowID, "columns", columnName), &columnRequest{
+		Value: columnValue,
+	}, nil)
+}
+
+func (client *Client) UpdateColumns(ctx context.Context, rowType, rowID string, columns map[string]interface{}) error {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemHTTP, fmt.Sprintf("UpdateColumns %q %q %s", rowType, rowID, storage.LogColumns(rowType, columns)))
+	if columns == nil {
+		columns = map[string]interface{}{}
+	}
+	return client.do(ctx, http.MethodPut, client.url(nil, "types", rowType, "rows", rowID, "columns"), &columnsRequest{
+		Columns: columns,
+	}, nil)
+}
+
+// PatchColumns sends every patch in one request, which the server applies as
+// its storer's PatchColumns does.
+func (client *Client) PatchColumns(ctx context.Context, patches []storage.ColumnPatch) error {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemHTTP, fmt.Sprintf("PatchColumns %d", len(patches)))
+	request := &patchesRequest{Patches: make([]patch, len(patches))}
+	for i, p := range patches {
+		request.Patches[i] = patch{Type: p.Type, ID: p.ID, Columns: p.Columns}
+	}
+	return client.do(ctx, http.MethodPost, client.url(nil, "patches"), request, nil)
+}
+
+func (client *Client) DeleteRow(ctx context.Context, rowType, childType, rowID string) error {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemHTTP, fmt.Sprintf("DeleteRow %q %q %q", rowType, childType, rowID))
+	query := url.Values{}
+	if childType != "" {
+		query.Set("child_type", childType)
+	}
+	return client.do(ctx, http.MethodDelete, client.url(query, "types", rowType, "rows", rowID), nil, nil)
+}
+
+func (client *Client) PutRow(ctx context.Context, r storage.Row) error {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemHTTP, fmt.Sprintf("PutRow %q %q", r.Type(), r.ID()))
+	return client.do(ctx, http.MethodPut, client.url(nil, "types", r.Type(), "rows", r.ID()), toRow(r), nil)
+}
+
+// ScanRows streams every row from the server in one response.
+func (client *Client) ScanRows(ctx context.Context, fn func(storage.Row) error) error {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemHTTP, "ScanRows")
+	return client.scan(ctx, client.url(nil, "rows"), fn)
+}
+
+// ScanChildren streams the row's children from the server in one response.
+func (client *Client) ScanChildren(ctx context.Context, parentID string, fn func(storage.Row) error) error {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemHTTP, fmt.Sprintf("ScanChildren %q", parentID))
+	return client.scan(ctx, client.url(nil, "parents", parentID, "children"), fn)
+}
+
+// url returns the URL of the API's path of segments, each escaped, with the
+// query.
+func (client *Client) url(query url.Values, segments ...string) string {
+	var b strings.Builder
+	b.WriteString(client.baseURL)
+	b.WriteString("/v1")
+	for _, segment := range segments {
+		b.WriteByte('/')
+		b.WriteString(url.PathEscape(segment))
+	}
+	if len(query) > 0 {
+		b.WriteByte('?')
+		b.WriteString(query.Encode())
+	}
+	return b.String()
+}
+
+// rowRequest sends a request whose response is a row, and returns the row.
+func (client *Client) rowRequest(ctx context.Context, method, u string, in interface{}) (storage.Row, error) {
+	var r row
+	if err := client.do(ctx, method, u, in, &r); err != nil {
+		return nil, err
+	}
+	if err := r.decode(); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// decode decodes the row's columns, as decodeColumns does.
+func (r *row) decode() error {
+	columns, err := decodeColumns(r.RowColumns)
+	if err != nil {
+		return fmt.Errorf("decoding %s %s: %w", r.RowType, r.RowID, err)
+	}
+	r.RowColumns = columns
+	return nil
+}
+
+// do sends a request with the body in, if it isn't nil, and decodes the
+// response's body into out, if it isn't nil.
+func (client *Client) do(ctx context.Context, method, u string, in, out interface{}) error {
+	resp, err := client.send(ctx, method, u, in)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+	return nil
+}
+
+// scan sends a request whose response streams rows, and calls fn with each.
+func (client *Client) scan(ctx context.Context, u string, fn func(storage.Row) error) error {
+	// cancelling the request, when fn stops the scan, stops the server's
+	// scan too
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	resp, err := client.send(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var line streamLine
+		if err := decoder.Decode(&line); err != nil {
+			if errors.Is(err, io.EOF) {
+				return errors.New("storage server: scan ended early")
+			}
+			return fmt.Errorf("decoding response: %w", err)
+		}
+		switch {
+		case line.Error != nil:
+			return decodeError(http.StatusInternalServerError, line.Error)
+		case line.Done:
+			return nil
+		case line.Row != nil:
+			if err := line.Row.decode(); err != nil {
+				return err
+			}
+			if err := fn(line.Row); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// send sends a request with the body in, if it isn't nil, and returns its
+// response if it succeeded, or its error.
+func (client *Client) send(ctx context.Context, method, u string, in interface{}) (*http.Response, error) {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return nil, fmt.Errorf("%w: encoding request: %s", storage.ErrInvalid, err)
+		}
+		body = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", storage.ErrInvalid, err)
+	}
+	req.Header.Set("Authorization", "Bearer "+client.token)
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if id := storage.CorrelationID(ctx); id != "" {
+		req.Header.Set(correlationIDHeader, id)
+	}
+
+	resp, err := client.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < http.StatusMultipleChoices {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	var e errorBody
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxErrorSize)).Decode(&e); err != nil {
+		return nil, decodeError(resp.StatusCode, nil)
+	}
+	return nil, decodeError(resp.StatusCode, &e)
+}
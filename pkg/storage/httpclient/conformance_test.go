@@ -0,0 +1,23 @@
+package httpclient_test
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/httpclient"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/memory"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/storagetest"
+)
+
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.RowStorer {
+		server := httptest.NewServer(httpclient.NewHandler(memory.NewClient(), "token", nil))
+		t.Cleanup(server.Close)
+		client, err := httpclient.NewClient(server.URL, "token")
+		if err != nil {
+			t.Fatalf("NewClient: %s", err)
+		}
+		return client
+	})
+}
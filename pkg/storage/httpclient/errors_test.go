@@ -0,0 +1,70 @@
+package httpclient_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/httpclient"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/memory"
+)
+
+// TestStatusErrors checks the kinds of the errors of responses with no
+// storage error in their bodies, as a proxy in front of the server sends.
+func TestStatusErrors(t *testing.T) {
+	tests := []struct {
+		status int
+		want   error
+	}{
+		{http.StatusUnauthorized, storage.ErrPermissionDenied},
+		{http.StatusTooManyRequests, storage.ErrThrottled},
+		{http.StatusServiceUnavailable, storage.ErrUnavailable},
+		{http.StatusInternalServerError, storage.ErrServerError},
+		{http.StatusBadGateway, storage.ErrServerError},
+		{http.StatusGatewayTimeout, storage.ErrServerError},
+	}
+	for _, test := range tests {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, http.StatusText(test.status), test.status)
+		}))
+		client, err := httpclient.NewClient(server.URL, "token")
+		if err != nil {
+			t.Fatalf("NewClient: %s", err)
+		}
+		_, err = client.GetRowByID(context.Background(), "org", "org-1")
+		if !errors.Is(err, test.want) {
+			t.Errorf("a %d response failed with %v, not %q", test.status, err, test.want)
+		}
+		if !storage.IsTransient(err) && storage.IsTransient(test.want) {
+			t.Errorf("a %d response failed with %v, which isn't transient", test.status, err)
+		}
+		server.Close()
+	}
+}
+
+// serverFailing is a backend that fails every read with a server error.
+type serverFailing struct {
+	storage.RowStorer
+}
+
+func (serverFailing) GetRowByID(ctx context.Context, rowType, rowID string) (storage.Row, error) {
+	return nil, storage.ErrServerError
+}
+
+// TestServerErrorRoundTrip checks that a backend's server error reaches the
+// client as one.
+func TestServerErrorRoundTrip(t *testing.T) {
+	server := httptest.NewServer(httpclient.NewHandler(serverFailing{memory.NewClient()}, "token", nil))
+	defer server.Close()
+	client, err := httpclient.NewClient(server.URL, "token")
+	if err != nil {
+		t.Fatalf("NewClient: %s", err)
+	}
+	_, err = client.GetRowByID(context.Background(), "org", "org-1")
+	if !errors.Is(err, storage.ErrServerError) {
+		t.Errorf("GetRowByID failed with %v, not %q", err, storage.ErrServerError)
+	}
+}
@@ -0,0 +1,287 @@
+package httpclient
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+// maxRequestSize is the largest request body the server reads, well above
+// the size of any row a backend stores.
+const maxRequestSize = 16 << 20
+
+type handler struct {
+	storer   storage.RowStorer
+	token    []byte
+	errorLog *log.Logger
+	mux      *http.ServeMux
+}
+
+// NewHandler returns a handler that serves the storer's rows, with the API
+// Client calls, to requests bearing the token. It logs the errors of
+// requests that fail unexpectedly to errorLog, or with the log package if
+// errorLog is nil.
+func NewHandler(storer storage.RowStorer, token string, errorLog *log.Logger) http.Handler {
+	if errorLog == nil {
+		errorLog = log.Default()
+	}
+	h := &handler{
+		storer:   storer,
+		token:    []byte(token),
+		errorLog: errorLog,
+		mux:      http.NewServeMux(),
+	}
+	h.handle("GET /v1/types/{type}/rows/{id}", h.getRowByID)
+	h.handle("GET /v1/types/{type}/labels/{label}", h.getRow)
+	h.handle("POST /v1/types/{type}/rows", h.createRow)
+	h.handle("GET /v1/parents/{parent_id}/children/{label}", h.getChild)
+	h.handle("GET /v1/types/{type}/rows", h.listRows)
+	h.handle("PATCH /v1/types/{type}/rows/{id}", h.updateRow)
+	h.handle("PUT /v1/types/{type}/rows/{id}/columns/{name}", h.updateColumn)
+	h.handle("PUT /v1/types/{type}/rows/{id}/columns", h.updateColumns)
+	h.handle("POST /v1/patches", h.patchColumns)
+	h.handle("DELETE /v1/types/{type}/rows/{id}", h.deleteRow)
+	h.handle("PUT /v1/types/{type}/rows/{id}", h.putRow)
+	h.mux.HandleFunc("GET /v1/rows", h.scanRows)
+	h.mux.HandleFunc("GET /v1/parents/{parent_id}/children", h.scanChildren)
+	return h
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !h.authorized(r) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeJSON(w, http.StatusUnauthorized, &errorBody{Message: "a valid bearer token is required", Code: "unauthorized"})
+		return
+	}
+	h.mux.ServeHTTP(w, r)
+}
+
+// authorized returns whether the request bears the handler's token. No
+// request is authorized by an empty token.
+func (h *handler) authorized(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || len(h.token) == 0 {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(token), h.token) == 1
+}
+
+// handle registers fn to respond to the pattern's requests with what it
+// returns: a JSON body, no content if that is nil, or an error.
+func (h *handler) handle(pattern string, fn func(*http.Request) (interface{}, error)) {
+	h.mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+		out, err := fn(r)
+		if err != nil {
+			h.writeError(w, r, err)
+			return
+		}
+		if out == nil {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		writeJSON(w, http.StatusOK, out)
+	})
+}
+
+func (h *handler) getRowByID(r *http.Request) (interface{}, error) {
+	return rowResponse(h.storer.GetRowByID(r.Context(), r.PathValue("type"), r.PathValue("id")))
+}
+
+func (h *handler) getRow(r *http.Request) (interface{}, error) {
+	return rowResponse(h.storer.GetRow(r.Context(), r.PathValue("type"), r.PathValue("label")))
+}
+
+func (h *handler) createRow(r *http.Request) (interface{}, error) {
+	var request createRequest
+	if err := decodeRequest(r, &request); err != nil {
+		return nil, err
+	}
+	if request.Parent == nil {
+		return rowResponse(h.storer.CreateRow(r.Context(), r.PathValue("type"), request.Label))
+	}
+	columns, err := decodeColumns(request.Columns)
+	if err != nil {
+		return nil, err
+	}
+	return rowResponse(h.storer.CreateChild(r.Context(), r.PathValue("type"), request.Label, request.Parent.Type, request.Parent.ID, columns))
+}
+
+func (h *handler) getChild(r *http.Request) (interface{}, error) {
+	return rowResponse(h.storer.GetChild(r.Context(), r.PathValue("label"), r.PathValue("parent_id")))
+}
+
+func (h *handler) listRows(r *http.Request) (interface{}, error) {
+	query := r.URL.Query()
+	found, err := h.storer.ListRows(r.Context(), r.PathValue("type"), query.Get("label_filter"), query.Get("parent_id"))
+	if err != nil {
+		return nil, err
+	}
+	response := &rowsResponse{Rows: make([]*row, len(found))}
+	for i, f := range found {
+		response.Rows[i] = toRow(f)
+	}
+	return response, nil
+}
+
+func (h *handler) updateRow(r *http.Request) (interface{}, error) {
+	var request updateRequest
+	if err := decodeRequest(r, &request); err != nil {
+		return nil, err
+	}
+	if request.Parent == nil {
+		return rowResponse(h.storer.UpdateRow(r.Context(), r.PathValue("type"), r.PathValue("id"), request.Label))
+	}
+	return rowResponse(h.storer.UpdateChild(r.Context(), r.PathValue("type"), r.PathValue("id"), request.Label, request.Parent.Type, request.Parent.ID))
+}
+
+func (h *handler) updateColumn(r *http.Request) (interface{}, error) {
+	var request columnRequest
+	if err := decodeRequest(r, &request); err != nil {
+		return nil, err
+	}
+	value, err := decodeValue(request.Value)
+	if err != nil {
+		return nil, fmt.Errorf("column %q: %w", r.PathValue("name"), err)
+	}
+	return nil, h.storer.UpdateColumn(r.Context(), r.PathValue("type"), r.PathValue("id"), r.PathValue("name"), value)
+}
+
+func (h *handler) updateColumns(r *http.Request) (interface{}, error) {
+	var request columnsRequest
+	if err := decodeRequest(r, &request); err != nil {
+		return nil, err
+	}
+	columns, err := decodeColumns(request.Columns)
+	if err != nil {
+		return nil, err
+	}
+	return nil, h.storer.UpdateColumns(r.Context(), r.PathValue("type"), r.PathValue("id"), columns)
+}
+
+func (h *handler) patchColumns(r *http.Request) (interface{}, error) {
+	var request patchesRequest
+	if err := decodeRequest(r, &request); err != nil {
+		return nil, err
+	}
+	patches := make([]storage.ColumnPatch, len(request.Patches))
+	for i, p := range request.Patches {
+		columns, err := decodeColumns(p.Columns)
+		if err != nil {
+			return nil, fmt.Errorf("%s %s: %w", p.Type, p.ID, err)
+		}
+		patches[i] = storage.ColumnPatch{Type: p.Type, ID: p.ID, Columns: columns}
+	}
+	return nil, storage.PatchColumns(r.Context(), h.storer, patches)
+}
+
+func (h *handler) deleteRow(r *http.Request) (interface{}, error) {
+	return nil, h.storer.DeleteRow(r.Context(), r.PathValue("type"), r.URL.Query().Get("child_type"), r.PathValue("id"))
+}
+
+func (h *handler) putRow(r *http.Request) (interface{}, error) {
+	var request row
+	if err := decodeRequest(r, &request); err != nil {
+		return nil, err
+	}
+	if err := request.decode(); err != nil {
+		return nil, err
+	}
+	// the path names the row, whatever the body says
+	request.RowType = r.PathValue("type")
+	request.RowID = r.PathValue("id")
+	return nil, h.storer.PutRow(r.Context(), &request)
+}
+
+func (h *handler) scanRows(w http.ResponseWriter, r *http.Request) {
+	h.stream(w, r, func(fn func(storage.Row) error) error {
+		return h.storer.ScanRows(r.Context(), fn)
+	})
+}
+
+func (h *handler) scanChildren(w http.ResponseWriter, r *http.Request) {
+	h.stream(w, r, func(fn func(storage.Row) error) error {
+		return storage.ScanChildren(r.Context(), h.storer, r.PathValue("parent_id"), fn)
+	})
+}
+
+// stream responds with the rows scan calls fn with, one per line, ending
+// with a line that is done or, if the scan fails once the response has
+// begun, with a line of its error.
+func (h *handler) stream(w http.ResponseWriter, r *http.Request, scan func(fn func(storage.Row) error) error) {
+	encoder := json.NewEncoder(w)
+	started := false
+	err := scan(func(found storage.Row) error {
+		if !started {
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			started = true
+		}
+		return encoder.Encode(&streamLine{Row: toRow(found)})
+	})
+	if err != nil && !started {
+		h.writeError(w, r, err)
+		return
+	}
+	if !started {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+	}
+	if err != nil {
+		if r.Context().Err() != nil {
+			// the client has gone, and with it anyone to tell
+			return
+		}
+		h.logError(r, err)
+		_, body := encodeError(err)
+		_ = encoder.Encode(&streamLine{Error: body})
+		return
+	}
+	_ = encoder.Encode(&streamLine{Done: true})
+}
+
+// rowResponse returns the response of a found row, or its error.
+func rowResponse(found storage.Row, err error) (interface{}, error) {
+	if err != nil {
+		return nil, err
+	}
+	return toRow(found), nil
+}
+
+// decodeRequest decodes the request's JSON body into v.
+func decodeRequest(r *http.Request, v interface{}) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(nil, r.Body, maxRequestSize))
+	if err := decoder.Decode(v); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return fmt.Errorf("%w: request is over %d bytes", storage.ErrTooLarge, tooLarge.Limit)
+		}
+		return fmt.Errorf("%w: decoding request: %s", storage.ErrInvalid, err)
+	}
+	return nil
+}
+
+func (h *handler) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	status, body := encodeError(err)
+	if status == http.StatusInternalServerError {
+		h.logError(r, err)
+	}
+	writeJSON(w, status, body)
+}
+
+func (h *handler) logError(r *http.Request, err error) {
+	if id := r.Header.Get(correlationIDHeader); id != "" {
+		h.errorLog.Printf("%s %s (correlation ID %s): %s", r.Method, r.URL.Path, id, err)
+		return
+	}
+	h.errorLog.Printf("%s %s: %s", r.Method, r.URL.Path, err)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,218 @@
+package httpclient
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+// correlationIDHeader carries the correlation ID of a request's operation, so
+// that the server's logs can be joined with the client's.
+const correlationIDHeader = "X-Correlation-Id"
+
+type row struct {
+	RowType     string                 `json:"type"`
+	RowID       string                 `json:"id"`
+	RowLabel    string                 `json:"label"`
+	RowParentID string                 `json:"parent_id,omitempty"`
+	RowColumns  map[string]interface{} `json:"columns"`
+}
+
+func toRow(r storage.Row) *row {
+	columns := r.Columns()
+	if columns == nil {
+		columns = map[string]interface{}{}
+	}
+	return &row{
+		RowType:     r.Type(),
+		RowID:       r.ID(),
+		RowLabel:    r.Label(),
+		RowParentID: r.ParentID(),
+		RowColumns:  columns,
+	}
+}
+
+func (r *row) Type() string                    { return r.RowType }
+func (r *row) ID() string                      { return r.RowID }
+func (r *row) Label() string                   { return r.RowLabel }
+func (r *row) ParentID() string                { return r.RowParentID }
+func (r *row) Columns() map[string]interface{} { return r.RowColumns }
+
+// decodeColumns returns columns as decoded from JSON, with JSON arrays as
+// string sets, the only kind of array rows store.
+func decodeColumns(columns map[string]interface{}) (map[string]interface{}, error) {
+	if columns == nil {
+		return map[string]interface{}{}, nil
+	}
+	for name, value := range columns {
+		decoded, err := decodeValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("column %q: %w", name, err)
+		}
+		columns[name] = decoded
+	}
+	return columns, nil
+}
+
+// decodeValue returns a column's value as decoded from JSON, with a JSON
+// array as a string set.
+func decodeValue(value interface{}) (interface{}, error) {
+	list, ok := value.([]interface{})
+	if !ok {
+		return value, nil
+	}
+	set := make([]string, 0, len(list))
+	for _, element := range list {
+		s, ok := element.(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: isn't a set of strings", storage.ErrInvalid)
+		}
+		set = append(set, s)
+	}
+	return set, nil
+}
+
+// The bodies of requests.
+type (
+	createRequest struct {
+		Label string `json:"label"`
+		// Parent is set to create a child.
+		Parent  *parentRef             `json:"parent,omitempty"`
+		Columns map[string]interface{} `json:"columns,omitempty"`
+	}
+	updateRequest struct {
+		Label string `json:"label"`
+		// Parent is set to move a child.
+		Parent *parentRef `json:"parent,omitempty"`
+	}
+	parentRef struct {
+		Type string `json:"type"`
+		ID   string `json:"id"`
+	}
+	columnRequest struct {
+		Value interface{} `json:"value"`
+	}
+	columnsRequest struct {
+		Columns map[string]interface{} `json:"columns"`
+	}
+	patchesRequest struct {
+		Patches []patch `json:"patches"`
+	}
+	patch struct {
+		Type    string                 `json:"type"`
+		ID      string                 `json:"id"`
+		Columns map[string]interface{} `json:"columns"`
+	}
+)
+
+// rowsResponse is the body of a response listing rows.
+type rowsResponse struct {
+	Rows []*row `json:"rows"`
+}
+
+// streamLine is a line of a response that streams rows, one per line, as
+// scans do. A scan ends with a line that is done, or, if it fails once the
+// response has begun, with a line of its error, so that a response cut short
+// isn't mistaken for a finished scan.
+type streamLine struct {
+	Row   *row       `json:"row,omitempty"`
+	Done  bool       `json:"done,omitempty"`
+	Error *errorBody `json:"error,omitempty"`
+}
+
+// errorBody is the body of an error response.
+type errorBody struct {
+	Message string `json:"message"`
+	Code    string `json:"code"`
+}
+
+// errorCodes are the storage errors that cross the wire, by code, the more
+// specific before the kinds they are of.
+var errorCodes = []struct {
+	code string
+	err  error
+}{
+	{"collision_type_label", storage.ErrCollisionTypeLabel},
+	{"collision_parent_label", storage.ErrCollisionParentLabel},
+	{"cannot_delete_row", storage.ErrCannotDeleteRow},
+	{"too_many_found", storage.ErrTooManyFound},
+	{"read_only", storage.ErrReadOnly},
+	{"too_large", storage.ErrTooLarge},
+	{"unavailable", storage.ErrUnavailable},
+	{"server_error", storage.ErrServerError},
+	{"not_found", storage.ErrNotFoundRow},
+	{"conflict", storage.ErrConflict},
+	{"throttled", storage.ErrThrottled},
+	{"permission_denied", storage.ErrPermissionDenied},
+	{"invalid", storage.ErrInvalid},
+}
+
+// kindStatuses are the HTTP statuses of the kinds of storage error.
+var kindStatuses = map[error]int{
+	storage.ErrNotFoundRow:      http.StatusNotFound,
+	storage.ErrConflict:         http.StatusConflict,
+	storage.ErrThrottled:        http.StatusTooManyRequests,
+	storage.ErrPermissionDenied: http.StatusForbidden,
+	storage.ErrInvalid:          http.StatusBadRequest,
+}
+
+// encodeError returns the status and body of the response to err.
+func encodeError(err error) (int, *errorBody) {
+	body := &errorBody{Message: err.Error(), Code: "internal"}
+	for _, c := range errorCodes {
+		if errors.Is(err, c.err) {
+			body.Code = c.code
+			break
+		}
+	}
+	status, ok := kindStatuses[storage.ErrorKind(err)]
+	if !ok {
+		status = http.StatusInternalServerError
+	}
+	return status, body
+}
+
+// remoteError is an error the server returned, of the storage error its code
+// names.
+type remoteError struct {
+	err     error
+	message string
+}
+
+func (err *remoteError) Error() string {
+	return err.message
+}
+
+func (err *remoteError) Unwrap() error {
+	return err.err
+}
+
+// decodeError returns the error of a response's status and body. The body
+// may be nil, as it is when a proxy in front of the server responds. Server
+// errors whose body names no storage error, such as a proxy's 502, are
+// storage.ErrServerError.
+func decodeError(status int, body *errorBody) error {
+	message := http.StatusText(status)
+	if body != nil && body.Message != "" {
+		message = body.Message
+		for _, c := range errorCodes {
+			if c.code == body.Code {
+				return &remoteError{err: c.err, message: message}
+			}
+		}
+	}
+	message = "storage server: " + message
+	switch {
+	case status == http.StatusUnauthorized:
+		return &remoteError{err: storage.ErrPermissionDenied, message: message}
+	case status == http.StatusTooManyRequests:
+		return &remoteError{err: storage.ErrThrottled, message: message}
+	case status == http.StatusServiceUnavailable:
+		return &remoteError{err: storage.ErrUnavailable, message: message}
+	case status >= http.StatusInternalServerError:
+		return &remoteError{err: storage.ErrServerError, message: message}
+	}
+	return errors.New(message)
+}
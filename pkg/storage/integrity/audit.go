@@ -0,0 +1,103 @@
+package integrity
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+// Finding is a row an audit found fault with.
+type Finding struct {
+	Type     string `json:"type"`
+	ID       string `json:"id"`
+	Label    string `json:"label"`
+	ParentID string `json:"parent_id,omitempty"`
+	// Problem is "unsigned" or "tampered".
+	Problem string `json:"problem"`
+}
+
+// Report is the result of an audit of every row.
+type Report struct {
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+	Rows     int       `json:"rows"`
+	Signed   int       `json:"signed"`
+	// Findings are ordered by type and ID.
+	Findings []Finding `json:"findings"`
+	// Digest is a SHA-256 of the content of every row, unsigned rows
+	// included, so that audits can tell whether anything changed between
+	// them.
+	Digest string `json:"digest"`
+}
+
+// OK reports whether every row is signed, and its signature matches.
+func (report Report) OK() bool {
+	return len(report.Findings) == 0
+}
+
+// Audit checks the signature of every row, as stored, and reports the rows
+// that are unsigned or whose signatures don't match. Unlike SignAll, it
+// changes nothing.
+func (client *Storer) Audit(ctx context.Context) (Report, error) {
+	report := Report{Started: time.Now().UTC(), Findings: []Finding{}}
+	digests := map[[2]string][]byte{}
+	err := client.next.ScanRows(ctx, func(row storage.Row) error {
+		report.Rows++
+		message, err := canonical(row)
+		if err != nil {
+			return err
+		}
+		digest := sha256.Sum256(message)
+		digests[[2]string{row.Type(), row.ID()}] = digest[:]
+
+		finding := Finding{Type: row.Type(), ID: row.ID(), Label: row.Label(), ParentID: row.ParentID()}
+		err = client.Verify(ctx, row)
+		switch {
+		case err == nil:
+			report.Signed++
+		case errors.Is(err, ErrUnsigned):
+			finding.Problem = "unsigned"
+			report.Findings = append(report.Findings, finding)
+		case errors.Is(err, ErrTampered):
+			finding.Problem = "tampered"
+			report.Findings = append(report.Findings, finding)
+		default:
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return Report{}, err
+	}
+
+	sort.Slice(report.Findings, func(i, j int) bool {
+		if report.Findings[i].Type != report.Findings[j].Type {
+			return report.Findings[i].Type < report.Findings[j].Type
+		}
+		return report.Findings[i].ID < report.Findings[j].ID
+	})
+	// the digest of the dataset is of its rows' digests, in a fixed order,
+	// since rows are scanned in none
+	keys := make([][2]string, 0, len(digests))
+	for key := range digests {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i][0] != keys[j][0] {
+			return keys[i][0] < keys[j][0]
+		}
+		return keys[i][1] < keys[j][1]
+	})
+	dataset := sha256.New()
+	for _, key := range keys {
+		dataset.Write(digests[key])
+	}
+	report.Digest = hex.EncodeToString(dataset.Sum(nil))
+	report.Finished = time.Now().UTC()
+	return report, nil
+}
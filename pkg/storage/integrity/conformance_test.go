@@ -0,0 +1,16 @@
+package integrity_test
+
+import (
+	"testing"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/integrity"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/memory"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/storagetest"
+)
+
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.RowStorer {
+		return integrity.NewStorer(memory.NewClient(), integrity.NewHMACSigner([]byte("storagetest")), true)
+	})
+}
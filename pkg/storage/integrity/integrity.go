@@ -0,0 +1,478 @@
+// Package integrity wraps a storage.RowStorer to sign every row it writes, and
+// verify the signature of every row it reads, so that rows changed in the
+// table directly, bypassing the provider, are detected.
+//
+// A row's signature is a MAC over its canonical content: its type, ID, label,
+// parent ID, and columns, as stored. It is kept in the row's SignatureColumn,
+// which readers never see.
+package integrity
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+// SignatureColumn is the column a row's signature is kept in.
+const SignatureColumn = "__signature"
+
+var (
+	ErrTampered = errors.New("row signature doesn't match its content")
+	ErrUnsigned = errors.New("row isn't signed")
+)
+
+// Signer computes and checks MACs.
+type Signer interface {
+	Sign(ctx context.Context, message []byte) ([]byte, error)
+	// Verify returns ErrTampered if mac isn't message's.
+	Verify(ctx context.Context, message, mac []byte) error
+}
+
+// Storer is a storage.RowStorer that signs the rows it writes to the one it
+// wraps, and verifies the rows it reads.
+type Storer struct {
+	next   storage.RowStorer
+	signer Signer
+	// require makes unsigned rows errors, rather than warnings
+	require bool
+}
+
+var (
+	_ storage.RowStorer     = &Storer{}
+	_ storage.Transactor    = &Storer{}
+	_ storage.ColumnDeleter = &Storer{}
+	_ storage.BatchGetter   = &Storer{}
+	_ storage.ColumnQuerier = &Storer{}
+	_ storage.ChildScanner  = &Storer{}
+	_ storage.BatchPutter   = &Storer{}
+	_ storage.ColumnPatcher = &Storer{}
+	_ storage.RowIterator   = &Storer{}
+)
+
+// NewStorer wraps next, signing rows with signer. If require is set, reading
+// an unsigned row is an error; otherwise it is logged, so that signing can be
+// turned on for a table of unsigned rows, which are signed as they are
+// written, or all at once with SignAll.
+func NewStorer(next storage.RowStorer, signer Signer, require bool) *Storer {
+	return &Storer{next: next, signer: signer, require: require}
+}
+
+// Unwrap returns the wrapped storer.
+func (client *Storer) Unwrap() storage.RowStorer {
+	return client.next
+}
+
+// canonical encodes the content of a row that its signature covers. JSON
+// encodes map keys in order; string sets are sorted, since not every backend
+// keeps their order.
+func canonical(row storage.Row) ([]byte, error) {
+	columns := map[string]interface{}{}
+	for name, value := range row.Columns() {
+		if name == SignatureColumn {
+			continue
+		}
+		if set, ok := value.([]string); ok {
+			sorted := append([]string(nil), set...)
+			sort.Strings(sorted)
+			value = sorted
+		}
+		columns[name] = value
+	}
+	return json.Marshal(struct {
+		Type     string                 `json:"type"`
+		ID       string                 `json:"id"`
+		Label    string                 `json:"label"`
+		ParentID string                 `json:"parent_id"`
+		Columns  map[string]interface{} `json:"columns"`
+	}{row.Type(), row.ID(), row.Label(), row.ParentID(), columns})
+}
+
+func (client *Storer) signature(ctx context.Context, row storage.Row) (string, error) {
+	message, err := canonical(row)
+	if err != nil {
+		return "", err
+	}
+	mac, err := client.signer.Sign(ctx, message)
+	if err != nil {
+		return "", fmt.Errorf("signing %s %s: %w", row.Type(), row.ID(), err)
+	}
+	return base64.StdEncoding.EncodeToString(mac), nil
+}
+
+// sign stores the signature of row, as read from the backend.
+func (client *Storer) sign(ctx context.Context, row storage.Row) error {
+	signature, err := client.signature(ctx, row)
+	if err != nil {
+		return err
+	}
+	return client.next.UpdateColumn(ctx, row.Type(), row.ID(), SignatureColumn, signature)
+}
+
+// resign reads a row, and stores its signature. Rows are signed as read back,
+// rather than as written, since backends may store values in other forms than
+// they were given.
+func (client *Storer) resign(ctx context.Context, rowType, rowID string) error {
+	row, err := client.next.GetRowByID(ctx, rowType, rowID)
+	if err != nil {
+		return err
+	}
+	return client.sign(ctx, row)
+}
+
+// signaturePatch reads a row, as resign does, and returns a patch storing its
+// signature, for batches to store many in one go.
+func (client *Storer) signaturePatch(ctx context.Context, rowType, rowID string) (storage.ColumnPatch, error) {
+	row, err := client.next.GetRowByID(ctx, rowType, rowID)
+	if err != nil {
+		return storage.ColumnPatch{}, err
+	}
+	signature, err := client.signature(ctx, row)
+	if err != nil {
+		return storage.ColumnPatch{}, err
+	}
+	return storage.ColumnPatch{Type: rowType, ID: rowID, Columns: map[string]interface{}{SignatureColumn: signature}}, nil
+}
+
+// verifiedRow is a row without its signature column.
+type verifiedRow struct {
+	storage.Row
+	columns map[string]interface{}
+}
+
+func (r *verifiedRow) Columns() map[string]interface{} {
+	return r.columns
+}
+
+func withoutSignature(row storage.Row) storage.Row {
+	if _, ok := row.Columns()[SignatureColumn]; !ok {
+		return row
+	}
+	columns := make(map[string]interface{}, len(row.Columns())-1)
+	for name, value := range row.Columns() {
+		if name != SignatureColumn {
+			columns[name] = value
+		}
+	}
+	return &verifiedRow{Row: row, columns: columns}
+}
+
+// Verify checks row's signature, returning ErrTampered if it doesn't match,
+// and ErrUnsigned if there is none.
+func (client *Storer) Verify(ctx context.Context, row storage.Row) error {
+	signature, ok := row.Columns()[SignatureColumn].(string)
+	if !ok {
+		return fmt.Errorf("%w: %s %s", ErrUnsigned, row.Type(), row.ID())
+	}
+	mac, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return fmt.Errorf("%w: %s %s", ErrTampered, row.Type(), row.ID())
+	}
+	message, err := canonical(row)
+	if err != nil {
+		return err
+	}
+	if err := client.signer.Verify(ctx, message, mac); err != nil {
+		return fmt.Errorf("%s %s: %w", row.Type(), row.ID(), err)
+	}
+	return nil
+}
+
+// verify checks a row read, and hides its signature.
+func (client *Storer) verify(ctx context.Context, row storage.Row) (storage.Row, error) {
+	if row == nil {
+		return nil, nil
+	}
+	err := client.Verify(ctx, row)
+	if errors.Is(err, ErrUnsigned) && !client.require {
+		tflog.SubsystemWarn(ctx, storage.LogSubsystemStorage, err.Error())
+		err = nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return withoutSignature(row), nil
+}
+
+// SignAll signs every row that isn't signed, and returns how many it signed.
+// Rows whose signatures don't match are left alone, and counted in the
+// returned error.
+func (client *Storer) SignAll(ctx context.Context) (int, error) {
+	var unsigned []storage.Row
+	tampered := 0
+	err := client.next.ScanRows(ctx, func(row storage.Row) error {
+		err := client.Verify(ctx, row)
+		switch {
+		case errors.Is(err, ErrUnsigned):
+			unsigned = append(unsigned, row)
+		case errors.Is(err, ErrTampered):
+			tampered++
+		case err != nil:
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	for i, row := range unsigned {
+		if err := client.sign(ctx, row); err != nil {
+			return i, err
+		}
+	}
+	if tampered > 0 {
+		return len(unsigned), fmt.Errorf("%w: %d rows", ErrTampered, tampered)
+	}
+	return len(unsigned), nil
+}
+
+func (client *Storer) GetRowByID(ctx context.Context, rowType, rowID string) (storage.Row, error) {
+	row, err := client.next.GetRowByID(ctx, rowType, rowID)
+	if err != nil {
+		return nil, err
+	}
+	return client.verify(ctx, row)
+}
+
+func (client *Storer) GetRowsByIDs(ctx context.Context, rowType string, ids []string) ([]storage.Row, error) {
+	rows, err := storage.GetRowsByIDs(ctx, client.next, rowType, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i, row := range rows {
+		rows[i], err = client.verify(ctx, row)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return rows, nil
+}
+
+func (client *Storer) GetRow(ctx context.Context, rowType, rowLabel string) (storage.Row, error) {
+	row, err := client.next.GetRow(ctx, rowType, rowLabel)
+	if err != nil {
+		return nil, err
+	}
+	return client.verify(ctx, row)
+}
+
+func (client *Storer) CreateRow(ctx context.Context, rowType, rowLabel string) (storage.Row, error) {
+	row, err := client.next.CreateRow(ctx, rowType, rowLabel)
+	if err != nil {
+		return nil, err
+	}
+	if err := client.resign(ctx, row.Type(), row.ID()); err != nil {
+		return nil, err
+	}
+	return withoutSignature(row), nil
+}
+
+func (client *Storer) CreateChild(ctx context.Context, rowType, rowLabel, parentType, parentID string, columns map[string]interface{}) (storage.Row, error) {
+	row, err := client.next.CreateChild(ctx, rowType, rowLabel, parentType, parentID, columns)
+	if err != nil {
+		return nil, err
+	}
+	if err := client.resign(ctx, row.Type(), row.ID()); err != nil {
+		return nil, err
+	}
+	return withoutSignature(row), nil
+}
+
+func (client *Storer) GetChild(ctx context.Context, childLabel, parentID string) (storage.Row, error) {
+	row, err := client.next.GetChild(ctx, childLabel, parentID)
+	if err != nil {
+		return nil, err
+	}
+	return client.verify(ctx, row)
+}
+
+func (client *Storer) ListRows(ctx context.Context, rowType, labelFilter, parentIDFilter string) ([]storage.Row, error) {
+	rows, err := client.next.ListRows(ctx, rowType, labelFilter, parentIDFilter)
+	if err != nil {
+		return nil, err
+	}
+	for i, row := range rows {
+		rows[i], err = client.verify(ctx, row)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return rows, nil
+}
+
+func (client *Storer) IterRows(ctx context.Context, rowType, labelFilter, parentIDFilter string) *storage.Iter[storage.Row] {
+	return storage.WrapPages(storage.IterRows(ctx, client.next, rowType, labelFilter, parentIDFilter), func(ctx context.Context, fetch storage.PageFunc[storage.Row]) ([]storage.Row, bool, error) {
+		page, more, err := fetch(ctx)
+		if err != nil {
+			return nil, false, err
+		}
+		for i, row := range page {
+			page[i], err = client.verify(ctx, row)
+			if err != nil {
+				return nil, false, err
+			}
+		}
+		return page, more, nil
+	})
+}
+
+func (client *Storer) QueryByColumn(ctx context.Context, rowType, columnName string, value interface{}) ([]storage.Row, error) {
+	rows, err := storage.QueryByColumn(ctx, client.next, rowType, columnName, value)
+	if err != nil {
+		return nil, err
+	}
+	for i, row := range rows {
+		rows[i], err = client.verify(ctx, row)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return rows, nil
+}
+
+// UpdateRow re-signs the row. The row must verify first, so that a tampered
+// row isn't signed over, as must the rows of the other updates.
+func (client *Storer) UpdateRow(ctx context.Context, rowType, rowID, newLabel string) (storage.Row, error) {
+	if _, err := client.GetRowByID(ctx, rowType, rowID); err != nil {
+		return nil, err
+	}
+	row, err := client.next.UpdateRow(ctx, rowType, rowID, newLabel)
+	if err != nil {
+		return nil, err
+	}
+	if err := client.resign(ctx, rowType, rowID); err != nil {
+		return nil, err
+	}
+	return withoutSignature(row), nil
+}
+
+func (client *Storer) UpdateChild(ctx context.Context, childType, childID, newChildLabel, parentType, newParentID string) (storage.Row, error) {
+	if _, err := client.GetRowByID(ctx, childType, childID); err != nil {
+		return nil, err
+	}
+	row, err := client.next.UpdateChild(ctx, childType, childID, newChildLabel, parentType, newParentID)
+	if err != nil {
+		return nil, err
+	}
+	if err := client.resign(ctx, childType, childID); err != nil {
+		return nil, err
+	}
+	return withoutSignature(row), nil
+}
+
+func (client *Storer) UpdateColumn(ctx context.Context, rowType, rowID, columnName string, columnValue interface{}) error {
+	if _, err := client.GetRowByID(ctx, rowType, rowID); err != nil {
+		return err
+	}
+	if err := client.next.UpdateColumn(ctx, rowType, rowID, columnName, columnValue); err != nil {
+		return err
+	}
+	return client.resign(ctx, rowType, rowID)
+}
+
+// PatchColumns verifies every patched row before applying any of the
+// patches, and then stores the rows' signatures in another batch of patches.
+func (client *Storer) PatchColumns(ctx context.Context, patches []storage.ColumnPatch) error {
+	for _, patch := range patches {
+		if _, err := client.GetRowByID(ctx, patch.Type, patch.ID); err != nil {
+			return err
+		}
+	}
+	if err := storage.PatchColumns(ctx, client.next, patches); err != nil {
+		return err
+	}
+	signatures := make([]storage.ColumnPatch, len(patches))
+	for i, patch := range patches {
+		signature, err := client.signaturePatch(ctx, patch.Type, patch.ID)
+		if err != nil {
+			return err
+		}
+		signatures[i] = signature
+	}
+	return storage.PatchColumns(ctx, client.next, signatures)
+}
+
+func (client *Storer) UpdateColumns(ctx context.Context, rowType, rowID string, columns map[string]interface{}) error {
+	if _, err := client.GetRowByID(ctx, rowType, rowID); err != nil {
+		return err
+	}
+	if err := client.next.UpdateColumns(ctx, rowType, rowID, columns); err != nil {
+		return err
+	}
+	return client.resign(ctx, rowType, rowID)
+}
+
+func (client *Storer) DeleteRow(ctx context.Context, rowType, childType, rowID string) error {
+	return client.next.DeleteRow(ctx, rowType, childType, rowID)
+}
+
+func (client *Storer) PutRow(ctx context.Context, row storage.Row) error {
+	if err := client.next.PutRow(ctx, withoutSignature(row)); err != nil {
+		return err
+	}
+	return client.resign(ctx, row.Type(), row.ID())
+}
+
+// PutRows puts the rows in a batch, and then stores their signatures in a
+// batch of patches.
+func (client *Storer) PutRows(ctx context.Context, rows []storage.Row) error {
+	unsigned := make([]storage.Row, len(rows))
+	for i, row := range rows {
+		unsigned[i] = withoutSignature(row)
+	}
+	if err := storage.PutRows(ctx, client.next, unsigned); err != nil {
+		return err
+	}
+	signatures := make([]storage.ColumnPatch, len(rows))
+	for i, row := range rows {
+		patch, err := client.signaturePatch(ctx, row.Type(), row.ID())
+		if err != nil {
+			return err
+		}
+		signatures[i] = patch
+	}
+	return storage.PatchColumns(ctx, client.next, signatures)
+}
+
+func (client *Storer) ScanRows(ctx context.Context, fn func(storage.Row) error) error {
+	return client.next.ScanRows(ctx, func(row storage.Row) error {
+		verified, err := client.verify(ctx, row)
+		if err != nil {
+			return err
+		}
+		return fn(verified)
+	})
+}
+
+func (client *Storer) ScanChildren(ctx context.Context, parentID string, fn func(storage.Row) error) error {
+	return storage.ScanChildren(ctx, client.next, parentID, func(row storage.Row) error {
+		verified, err := client.verify(ctx, row)
+		if err != nil {
+			return err
+		}
+		return fn(verified)
+	})
+}
+
+func (client *Storer) DeleteColumn(ctx context.Context, rowType, rowID, columnName string) error {
+	if _, err := client.GetRowByID(ctx, rowType, rowID); err != nil {
+		return err
+	}
+	if err := storage.DeleteColumn(ctx, client.next, rowType, rowID, columnName); err != nil {
+		return err
+	}
+	return client.resign(ctx, rowType, rowID)
+}
+
+// WithinTx signs the rows the transaction writes, and verifies those it
+// reads, too.
+func (client *Storer) WithinTx(ctx context.Context, fn func(tx storage.RowStorer) error) error {
+	return storage.WithinTx(ctx, client.next, func(tx storage.RowStorer) error {
+		return fn(NewStorer(tx, client.signer, client.require))
+	})
+}
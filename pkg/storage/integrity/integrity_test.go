@@ -0,0 +1,162 @@
+package integrity_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/integrity"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/memory"
+)
+
+var signer = integrity.NewHMACSigner([]byte("integrity"))
+
+func TestSignsWrites(t *testing.T) {
+	ctx := context.Background()
+	backend := memory.NewClient()
+	storer := integrity.NewStorer(backend, signer, true)
+	org, err := storer.CreateRow(ctx, "org", "acme")
+	if err != nil {
+		t.Fatalf("CreateRow: %s", err)
+	}
+	if err := storer.UpdateColumn(ctx, "org", org.ID(), "owner", "ops"); err != nil {
+		t.Fatalf("UpdateColumn: %s", err)
+	}
+
+	stored, err := backend.GetRowByID(ctx, "org", org.ID())
+	if err != nil {
+		t.Fatalf("GetRowByID: %s", err)
+	}
+	if err := storer.Verify(ctx, stored); err != nil {
+		t.Errorf("the stored row doesn't verify: %s", err)
+	}
+	got, err := storer.GetRowByID(ctx, "org", org.ID())
+	if err != nil {
+		t.Fatalf("GetRowByID: %s", err)
+	}
+	if _, ok := got.Columns()[integrity.SignatureColumn]; ok {
+		t.Errorf("GetRowByID returned the signature: %v", got.Columns())
+	}
+}
+
+func TestTampered(t *testing.T) {
+	ctx := context.Background()
+	backend := memory.NewClient()
+	storer := integrity.NewStorer(backend, signer, false)
+	org, err := storer.CreateRow(ctx, "org", "acme")
+	if err != nil {
+		t.Fatalf("CreateRow: %s", err)
+	}
+
+	// changed in the table directly, bypassing the storer
+	if err := backend.UpdateColumn(ctx, "org", org.ID(), "owner", "mallory"); err != nil {
+		t.Fatalf("UpdateColumn: %s", err)
+	}
+	if _, err := storer.GetRowByID(ctx, "org", org.ID()); !errors.Is(err, integrity.ErrTampered) {
+		t.Errorf("GetRowByID of a tampered row failed with %v, not %q", err, integrity.ErrTampered)
+	}
+	// nor is it signed over
+	if err := storer.UpdateColumn(ctx, "org", org.ID(), "owner", "ops"); !errors.Is(err, integrity.ErrTampered) {
+		t.Errorf("UpdateColumn of a tampered row failed with %v, not %q", err, integrity.ErrTampered)
+	}
+}
+
+func TestUnsigned(t *testing.T) {
+	ctx := context.Background()
+	backend := memory.NewClient()
+	org, err := backend.CreateRow(ctx, "org", "acme")
+	if err != nil {
+		t.Fatalf("CreateRow: %s", err)
+	}
+
+	required := integrity.NewStorer(backend, signer, true)
+	if _, err := required.GetRowByID(ctx, "org", org.ID()); !errors.Is(err, integrity.ErrUnsigned) {
+		t.Errorf("GetRowByID of an unsigned row, with signatures required, failed with %v, not %q", err, integrity.ErrUnsigned)
+	}
+	optional := integrity.NewStorer(backend, signer, false)
+	if _, err := optional.GetRowByID(ctx, "org", org.ID()); err != nil {
+		t.Errorf("GetRowByID of an unsigned row, with signatures optional: %s", err)
+	}
+}
+
+func TestSignAll(t *testing.T) {
+	ctx := context.Background()
+	backend := memory.NewClient()
+	storer := integrity.NewStorer(backend, signer, true)
+	for _, label := range []string{"acme", "globex"} {
+		if _, err := backend.CreateRow(ctx, "org", label); err != nil {
+			t.Fatalf("CreateRow: %s", err)
+		}
+	}
+	tampered, err := storer.CreateRow(ctx, "org", "initech")
+	if err != nil {
+		t.Fatalf("CreateRow: %s", err)
+	}
+	if err := backend.UpdateColumn(ctx, "org", tampered.ID(), "owner", "mallory"); err != nil {
+		t.Fatalf("UpdateColumn: %s", err)
+	}
+
+	signed, err := storer.SignAll(ctx)
+	if signed != 2 || !errors.Is(err, integrity.ErrTampered) {
+		t.Errorf("SignAll signed %d rows and failed with %v, not 2 and %q", signed, err, integrity.ErrTampered)
+	}
+	rows, err := storage.IterRows(ctx, backend, "org", "", "").All()
+	if err != nil {
+		t.Fatalf("IterRows: %s", err)
+	}
+	for _, row := range rows {
+		err := storer.Verify(ctx, row)
+		if row.ID() == tampered.ID() {
+			if !errors.Is(err, integrity.ErrTampered) {
+				t.Errorf("SignAll signed over the tampered row: %v", err)
+			}
+		} else if err != nil {
+			t.Errorf("%s isn't signed after SignAll: %s", row.Label(), err)
+		}
+	}
+}
+
+func TestAudit(t *testing.T) {
+	ctx := context.Background()
+	backend := memory.NewClient()
+	storer := integrity.NewStorer(backend, signer, true)
+	org, err := storer.CreateRow(ctx, "org", "acme")
+	if err != nil {
+		t.Fatalf("CreateRow: %s", err)
+	}
+	unsigned, err := backend.CreateRow(ctx, "org", "globex")
+	if err != nil {
+		t.Fatalf("CreateRow: %s", err)
+	}
+
+	before, err := storer.Audit(ctx)
+	if err != nil {
+		t.Fatalf("Audit: %s", err)
+	}
+	if before.Rows != 2 || before.Signed != 1 || len(before.Findings) != 1 || before.Findings[0].ID != unsigned.ID() || before.Findings[0].Problem != "unsigned" {
+		t.Errorf("Audit reported %+v, not the one unsigned row", before)
+	}
+
+	if err := backend.UpdateColumn(ctx, "org", org.ID(), "owner", "mallory"); err != nil {
+		t.Fatalf("UpdateColumn: %s", err)
+	}
+	after, err := storer.Audit(ctx)
+	if err != nil {
+		t.Fatalf("Audit: %s", err)
+	}
+	problems := map[string]string{}
+	for _, finding := range after.Findings {
+		problems[finding.ID] = finding.Problem
+	}
+	if after.OK() || len(problems) != 2 || problems[org.ID()] != "tampered" {
+		t.Errorf("Audit reported %+v, without the tampered row", after.Findings)
+	}
+	if after.Digest == before.Digest {
+		t.Error("the digest didn't change with a row")
+	}
+	// auditing changes nothing
+	if again, _ := storer.Audit(ctx); again.Digest != after.Digest {
+		t.Error("the digest changed between audits of the same rows")
+	}
+}
@@ -0,0 +1,88 @@
+package integrity
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+)
+
+// HMACSigner signs with HMAC-SHA256 and a key held in memory.
+type HMACSigner struct {
+	key []byte
+}
+
+var _ Signer = &HMACSigner{}
+
+func NewHMACSigner(key []byte) *HMACSigner {
+	return &HMACSigner{key: key}
+}
+
+func (signer *HMACSigner) Sign(_ context.Context, message []byte) ([]byte, error) {
+	mac := hmac.New(sha256.New, signer.key)
+	mac.Write(message)
+	return mac.Sum(nil), nil
+}
+
+func (signer *HMACSigner) Verify(ctx context.Context, message, mac []byte) error {
+	expected, _ := signer.Sign(ctx, message)
+	if !hmac.Equal(mac, expected) {
+		return ErrTampered
+	}
+	return nil
+}
+
+// KMSSigner signs with a KMS HMAC key, which never leaves KMS. Every sign and
+// verify is a KMS request.
+type KMSSigner struct {
+	kms    *kms.Client
+	keyARN string
+}
+
+var _ Signer = &KMSSigner{}
+
+// NewKMSSigner returns a signer using the KMS HMAC_256 key.
+func NewKMSSigner(ctx context.Context, profile, region, keyARN string) (*KMSSigner, error) {
+	cfg, err := config.LoadDefaultConfig(ctx,
+		config.WithSharedConfigProfile(profile),
+		config.WithRegion(region),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &KMSSigner{
+		kms:    kms.NewFromConfig(cfg),
+		keyARN: keyARN,
+	}, nil
+}
+
+func (signer *KMSSigner) Sign(ctx context.Context, message []byte) ([]byte, error) {
+	output, err := signer.kms.GenerateMac(ctx, &kms.GenerateMacInput{
+		KeyId:        aws.String(signer.keyARN),
+		MacAlgorithm: types.MacAlgorithmSpecHmacSha256,
+		Message:      message,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return output.Mac, nil
+}
+
+func (signer *KMSSigner) Verify(ctx context.Context, message, mac []byte) error {
+	_, err := signer.kms.VerifyMac(ctx, &kms.VerifyMacInput{
+		KeyId:        aws.String(signer.keyARN),
+		MacAlgorithm: types.MacAlgorithmSpecHmacSha256,
+		Message:      message,
+		Mac:          mac,
+	})
+	var invalid *types.KMSInvalidMacException
+	if errors.As(err, &invalid) {
+		return ErrTampered
+	}
+	return err
+}
@@ -0,0 +1,109 @@
+package storage
+
+import "context"
+
+// PageFunc reads the next page of results. It reports whether there are more
+// pages after this one; once it doesn't, or it returns an error, Iter doesn't
+// call it again. A PageFunc that fails leaves its place unchanged, so that a
+// wrapper can call it again to retry the page.
+type PageFunc[T any] func(ctx context.Context) (page []T, more bool, err error)
+
+// Iter reads results a page at a time, as they're consumed, so that callers
+// can stop early, or handle a large result without holding all of it. Use it
+// as
+//
+//	it := storage.IterRows(ctx, storer, rowType, "", "")
+//	for it.Next() {
+//		row := it.Value()
+//		...
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+//
+// An Iter isn't safe for use by more than one goroutine at once.
+type Iter[T any] struct {
+	ctx   context.Context
+	fetch PageFunc[T]
+	page  []T
+	value T
+	more  bool
+	err   error
+}
+
+// NewIter returns an iterator of the pages fetch reads. No page is read until
+// the first call to Next.
+func NewIter[T any](ctx context.Context, fetch PageFunc[T]) *Iter[T] {
+	return &Iter[T]{ctx: ctx, fetch: fetch, more: true}
+}
+
+// WrapPages returns an iterator of the results of it that reads each page
+// with wrap, given the page func of it, so that storage wrappers can act on
+// each page as it's read. The iterator mustn't have been advanced.
+func WrapPages[T any](it *Iter[T], wrap func(ctx context.Context, fetch PageFunc[T]) ([]T, bool, error)) *Iter[T] {
+	return NewIter(it.ctx, func(ctx context.Context) ([]T, bool, error) {
+		return wrap(ctx, it.fetch)
+	})
+}
+
+// Next advances to the next result, reading the next page if it must, and
+// reports whether there is one. It returns false at the end of the results,
+// or after an error, which Err returns.
+func (it *Iter[T]) Next() bool {
+	for len(it.page) == 0 {
+		if !it.more || it.err != nil {
+			var zero T
+			it.value = zero
+			return false
+		}
+		if err := it.ctx.Err(); err != nil {
+			it.err = err
+			continue
+		}
+		it.page, it.more, it.err = it.fetch(it.ctx)
+		if it.err != nil {
+			it.page = nil
+		}
+	}
+	it.value, it.page = it.page[0], it.page[1:]
+	return true
+}
+
+// Value returns the result Next advanced to.
+func (it *Iter[T]) Value() T {
+	return it.value
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (it *Iter[T]) Err() error {
+	return it.err
+}
+
+// All returns the rest of the results, reading every page.
+func (it *Iter[T]) All() ([]T, error) {
+	results := []T{}
+	for it.Next() {
+		results = append(results, it.Value())
+	}
+	return results, it.Err()
+}
+
+// RowIterator is implemented by storage backends that can list a type's rows
+// a page at a time.
+type RowIterator interface {
+	// IterRows returns an iterator of the rows ListRows would return,
+	// reading them a page at a time as the iterator is advanced.
+	IterRows(ctx context.Context, rowType, labelFilter, parentIDFilter string) *Iter[Row]
+}
+
+// IterRows returns an iterator of the rows ListRows would return, read in one
+// call to it if the storer isn't a RowIterator.
+func IterRows(ctx context.Context, storer RowStorer, rowType, labelFilter, parentIDFilter string) *Iter[Row] {
+	if iterator, ok := storer.(RowIterator); ok {
+		return iterator.IterRows(ctx, rowType, labelFilter, parentIDFilter)
+	}
+	return NewIter(ctx, func(ctx context.Context) ([]Row, bool, error) {
+		rows, err := storer.ListRows(ctx, rowType, labelFilter, parentIDFilter)
+		return rows, false, err
+	})
+}
@@ -0,0 +1,275 @@
+// Package limits wraps a storage.RowStorer to refuse writes that would make a
+// column or row larger than configured maximums, so that an oversized value
+// fails with an error naming it, rather than with the backend's own limit
+// (DynamoDB's is 400 KB per item) partway through an apply.
+package limits
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+// Storer is a storage.RowStorer that checks the size of what it writes, as
+// storage.ColumnSize and storage.RowSize estimate it, before passing writes
+// to the one it wraps. Writes that are too large fail with an error wrapping
+// storage.ErrTooLarge.
+//
+// Wrap the backend itself, inside decorators such as encryption and
+// integrity, so that sizes count the columns the backend will store.
+type Storer struct {
+	next      storage.RowStorer
+	maxColumn int
+	maxRow    int
+}
+
+var (
+	_ storage.RowStorer     = &Storer{}
+	_ storage.Transactor    = &Storer{}
+	_ storage.ColumnDeleter = &Storer{}
+	_ storage.BatchGetter   = &Storer{}
+	_ storage.ColumnQuerier = &Storer{}
+	_ storage.ChildScanner  = &Storer{}
+	_ storage.BatchPutter   = &Storer{}
+	_ storage.ColumnPatcher = &Storer{}
+	_ storage.RowIterator   = &Storer{}
+)
+
+// NewStorer returns a storer allowing columns of at most maxColumn bytes, and
+// rows of at most maxRow bytes. A maximum of 0 doesn't limit that size.
+// Checking a row's size when changing part of it reads the row first.
+func NewStorer(next storage.RowStorer, maxColumn, maxRow int) *Storer {
+	return &Storer{next: next, maxColumn: maxColumn, maxRow: maxRow}
+}
+
+// Unwrap returns the wrapped storer.
+func (client *Storer) Unwrap() storage.RowStorer {
+	return client.next
+}
+
+// sized is a row as a write would leave it, for measuring.
+type sized struct {
+	rowType  string
+	id       string
+	label    string
+	parentID string
+	columns  map[string]interface{}
+}
+
+func (r *sized) Type() string                    { return r.rowType }
+func (r *sized) ID() string                      { return r.id }
+func (r *sized) Label() string                   { return r.label }
+func (r *sized) ParentID() string                { return r.parentID }
+func (r *sized) Columns() map[string]interface{} { return r.columns }
+
+func fromRow(row storage.Row) *sized {
+	return &sized{
+		rowType:  row.Type(),
+		id:       row.ID(),
+		label:    row.Label(),
+		parentID: row.ParentID(),
+		columns:  row.Columns(),
+	}
+}
+
+// describe names a row in errors, by ID if it has one yet.
+func describe(row *sized) string {
+	if row.id == "" {
+		return fmt.Sprintf("new %s %q", row.rowType, row.label)
+	}
+	return fmt.Sprintf("%s %q", row.rowType, row.id)
+}
+
+func (client *Storer) checkColumn(row *sized, name string, value interface{}) error {
+	if client.maxColumn <= 0 {
+		return nil
+	}
+	if size := storage.ColumnSize(name, value); size > client.maxColumn {
+		return fmt.Errorf("%w: column %q of %s is %d bytes, more than the %d allowed", storage.ErrTooLarge, name, describe(row), size, client.maxColumn)
+	}
+	return nil
+}
+
+// check checks each of the row's columns, then the whole row.
+func (client *Storer) check(row *sized) error {
+	for name, value := range row.columns {
+		if err := client.checkColumn(row, name, value); err != nil {
+			return err
+		}
+	}
+	if client.maxRow <= 0 {
+		return nil
+	}
+	if size := storage.RowSize(row); size > client.maxRow {
+		return fmt.Errorf("%w: %s would be %d bytes, more than the %d allowed", storage.ErrTooLarge, describe(row), size, client.maxRow)
+	}
+	return nil
+}
+
+// checkStored checks the stored row as change would leave it. It reads the
+// row only if rows are limited.
+func (client *Storer) checkStored(ctx context.Context, rowType, rowID string, change func(*sized)) error {
+	if client.maxRow <= 0 {
+		row := &sized{rowType: rowType, id: rowID}
+		change(row)
+		return client.check(row)
+	}
+	stored, err := client.next.GetRowByID(ctx, rowType, rowID)
+	if err != nil {
+		return err
+	}
+	row := fromRow(stored)
+	change(row)
+	return client.check(row)
+}
+
+func (client *Storer) GetRowByID(ctx context.Context, rowType, rowID string) (storage.Row, error) {
+	return client.next.GetRowByID(ctx, rowType, rowID)
+}
+
+func (client *Storer) GetRowsByIDs(ctx context.Context, rowType string, ids []string) ([]storage.Row, error) {
+	return storage.GetRowsByIDs(ctx, client.next, rowType, ids)
+}
+
+func (client *Storer) GetRow(ctx context.Context, rowType, rowLabel string) (storage.Row, error) {
+	return client.next.GetRow(ctx, rowType, rowLabel)
+}
+
+func (client *Storer) CreateRow(ctx context.Context, rowType, rowLabel string) (storage.Row, error) {
+	if err := client.check(&sized{rowType: rowType, label: rowLabel}); err != nil {
+		return nil, err
+	}
+	return client.next.CreateRow(ctx, rowType, rowLabel)
+}
+
+func (client *Storer) CreateChild(ctx context.Context, rowType, rowLabel, parentType, parentID string, columns map[string]interface{}) (storage.Row, error) {
+	row := &sized{rowType: rowType, label: rowLabel, parentID: parentID, columns: columns}
+	if err := client.check(row); err != nil {
+		return nil, err
+	}
+	return client.next.CreateChild(ctx, rowType, rowLabel, parentType, parentID, columns)
+}
+
+func (client *Storer) GetChild(ctx context.Context, childLabel, parentID string) (storage.Row, error) {
+	return client.next.GetChild(ctx, childLabel, parentID)
+}
+
+func (client *Storer) ListRows(ctx context.Context, rowType, labelFilter, parentIDFilter string) ([]storage.Row, error) {
+	return client.next.ListRows(ctx, rowType, labelFilter, parentIDFilter)
+}
+
+func (client *Storer) IterRows(ctx context.Context, rowType, labelFilter, parentIDFilter string) *storage.Iter[storage.Row] {
+	return storage.IterRows(ctx, client.next, rowType, labelFilter, parentIDFilter)
+}
+
+func (client *Storer) QueryByColumn(ctx context.Context, rowType, columnName string, value interface{}) ([]storage.Row, error) {
+	return storage.QueryByColumn(ctx, client.next, rowType, columnName, value)
+}
+
+func (client *Storer) UpdateRow(ctx context.Context, rowType, rowID, newLabel string) (storage.Row, error) {
+	err := client.checkStored(ctx, rowType, rowID, func(row *sized) {
+		row.label = newLabel
+	})
+	if err != nil {
+		return nil, err
+	}
+	return client.next.UpdateRow(ctx, rowType, rowID, newLabel)
+}
+
+func (client *Storer) UpdateChild(ctx context.Context, childType, childID, newChildLabel, parentType, newParentID string) (storage.Row, error) {
+	err := client.checkStored(ctx, childType, childID, func(row *sized) {
+		row.label = newChildLabel
+		row.parentID = newParentID
+	})
+	if err != nil {
+		return nil, err
+	}
+	return client.next.UpdateChild(ctx, childType, childID, newChildLabel, parentType, newParentID)
+}
+
+func (client *Storer) UpdateColumn(ctx context.Context, rowType, rowID, columnName string, columnValue interface{}) error {
+	err := client.checkStored(ctx, rowType, rowID, func(row *sized) {
+		columns := make(map[string]interface{}, len(row.columns)+1)
+		for name, value := range row.columns {
+			columns[name] = value
+		}
+		columns[columnName] = columnValue
+		row.columns = columns
+	})
+	if err != nil {
+		return err
+	}
+	return client.next.UpdateColumn(ctx, rowType, rowID, columnName, columnValue)
+}
+
+// PatchColumns checks every patched row before applying any of the patches.
+func (client *Storer) PatchColumns(ctx context.Context, patches []storage.ColumnPatch) error {
+	for _, patch := range patches {
+		err := client.checkStored(ctx, patch.Type, patch.ID, func(row *sized) {
+			columns := make(map[string]interface{}, len(row.columns)+len(patch.Columns))
+			for name, value := range row.columns {
+				columns[name] = value
+			}
+			for name, value := range patch.Columns {
+				columns[name] = value
+			}
+			row.columns = columns
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return storage.PatchColumns(ctx, client.next, patches)
+}
+
+func (client *Storer) UpdateColumns(ctx context.Context, rowType, rowID string, columns map[string]interface{}) error {
+	err := client.checkStored(ctx, rowType, rowID, func(row *sized) {
+		row.columns = columns
+	})
+	if err != nil {
+		return err
+	}
+	return client.next.UpdateColumns(ctx, rowType, rowID, columns)
+}
+
+func (client *Storer) DeleteRow(ctx context.Context, rowType, childType, rowID string) error {
+	return client.next.DeleteRow(ctx, rowType, childType, rowID)
+}
+
+func (client *Storer) PutRow(ctx context.Context, row storage.Row) error {
+	if err := client.check(fromRow(row)); err != nil {
+		return err
+	}
+	return client.next.PutRow(ctx, row)
+}
+
+// PutRows checks every row before putting any of them.
+func (client *Storer) PutRows(ctx context.Context, rows []storage.Row) error {
+	for _, row := range rows {
+		if err := client.check(fromRow(row)); err != nil {
+			return err
+		}
+	}
+	return storage.PutRows(ctx, client.next, rows)
+}
+
+func (client *Storer) ScanRows(ctx context.Context, fn func(storage.Row) error) error {
+	return client.next.ScanRows(ctx, fn)
+}
+
+func (client *Storer) ScanChildren(ctx context.Context, parentID string, fn func(storage.Row) error) error {
+	return storage.ScanChildren(ctx, client.next, parentID, fn)
+}
+
+// DeleteColumn only shrinks the row, so it isn't checked.
+func (client *Storer) DeleteColumn(ctx context.Context, rowType, rowID, columnName string) error {
+	return storage.DeleteColumn(ctx, client.next, rowType, rowID, columnName)
+}
+
+// WithinTx limits the sizes of the rows the transaction writes, too.
+func (client *Storer) WithinTx(ctx context.Context, fn func(tx storage.RowStorer) error) error {
+	return storage.WithinTx(ctx, client.next, func(tx storage.RowStorer) error {
+		return fn(NewStorer(tx, client.maxColumn, client.maxRow))
+	})
+}
@@ -0,0 +1,81 @@
+package limits_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/dataset"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/limits"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/memory"
+)
+
+func TestColumnTooLarge(t *testing.T) {
+	ctx := context.Background()
+	backend := memory.NewClient()
+	storer := limits.NewStorer(backend, 100, 0)
+	org, err := storer.CreateRow(ctx, "org", "acme")
+	if err != nil {
+		t.Fatalf("CreateRow: %s", err)
+	}
+
+	large := strings.Repeat("x", 200)
+	_, err = storer.CreateChild(ctx, "team", "dev", "org", org.ID(), map[string]interface{}{"notes": large})
+	if !errors.Is(err, storage.ErrTooLarge) {
+		t.Errorf("CreateChild of a large column failed with %v, not %q", err, storage.ErrTooLarge)
+	}
+	if _, err := backend.GetChild(ctx, "dev", org.ID()); err == nil {
+		t.Error("the refused child was created")
+	}
+	if err := storer.UpdateColumn(ctx, "org", org.ID(), "notes", large); !errors.Is(err, storage.ErrTooLarge) {
+		t.Errorf("UpdateColumn of a large column failed with %v, not %q", err, storage.ErrTooLarge)
+	}
+	if err := storer.UpdateColumn(ctx, "org", org.ID(), "notes", "short"); err != nil {
+		t.Errorf("UpdateColumn of a small column: %s", err)
+	}
+}
+
+func TestRowTooLarge(t *testing.T) {
+	ctx := context.Background()
+	backend := memory.NewClient()
+	storer := limits.NewStorer(backend, 0, 300)
+	org, err := storer.CreateRow(ctx, "org", "acme")
+	if err != nil {
+		t.Fatalf("CreateRow: %s", err)
+	}
+	half := strings.Repeat("x", 150)
+	if err := storer.UpdateColumn(ctx, "org", org.ID(), "a", half); err != nil {
+		t.Fatalf("UpdateColumn: %s", err)
+	}
+
+	// each column is small enough, but not with the stored one
+	if err := storer.UpdateColumn(ctx, "org", org.ID(), "b", half); !errors.Is(err, storage.ErrTooLarge) {
+		t.Errorf("UpdateColumn growing the row too large failed with %v, not %q", err, storage.ErrTooLarge)
+	}
+	patch := []storage.ColumnPatch{{Type: "org", ID: org.ID(), Columns: map[string]interface{}{"b": half}}}
+	if err := storer.PatchColumns(ctx, patch); !errors.Is(err, storage.ErrTooLarge) {
+		t.Errorf("PatchColumns growing the row too large failed with %v, not %q", err, storage.ErrTooLarge)
+	}
+	// replacing the columns counts only the new ones
+	if err := storer.UpdateColumns(ctx, "org", org.ID(), map[string]interface{}{"b": half}); err != nil {
+		t.Errorf("UpdateColumns replacing the large column: %s", err)
+	}
+}
+
+func TestPutRowsChecksFirst(t *testing.T) {
+	ctx := context.Background()
+	backend := memory.NewClient()
+	storer := limits.NewStorer(backend, 100, 0)
+	rows := []storage.Row{
+		&dataset.Record{RowType: "org", RowID: "org-acme", RowLabel: "acme"},
+		&dataset.Record{RowType: "org", RowID: "org-globex", RowLabel: "globex", RowColumns: map[string]interface{}{"notes": strings.Repeat("x", 200)}},
+	}
+	if err := storer.PutRows(ctx, rows); !errors.Is(err, storage.ErrTooLarge) {
+		t.Errorf("PutRows of a large row failed with %v, not %q", err, storage.ErrTooLarge)
+	}
+	if _, err := backend.GetRowByID(ctx, "org", "org-acme"); err == nil {
+		t.Error("PutRows put rows before the one too large")
+	}
+}
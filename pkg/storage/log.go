@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// redacted stands in for a column value in logs.
+const redacted = "(redacted)"
+
+// AllColumns, in a LogPolicy's AllowColumns, allows every column's values.
+const AllColumns = "*"
+
+// LogPolicy decides which column values backends may write to their logs.
+// Columns can hold sensitive configuration, so by default no values are
+// logged, only column names.
+type LogPolicy struct {
+	// AllowColumns names the columns whose values may be logged. AllColumns
+	// allows them all, except PII columns, which must be named.
+	AllowColumns []string
+	// PII names the columns holding personal data.
+	PII PIIColumns
+}
+
+var (
+	logPolicyMu sync.RWMutex
+	logPolicy   LogPolicy
+)
+
+// SetLogPolicy sets the policy of every backend in the process.
+func SetLogPolicy(policy LogPolicy) {
+	logPolicyMu.Lock()
+	defer logPolicyMu.Unlock()
+	logPolicy = policy
+}
+
+// LogColumnValue formats a column's value for logging, or redacts it, as the
+// log policy says.
+func LogColumnValue(rowType, columnName string, value interface{}) string {
+	logPolicyMu.RLock()
+	defer logPolicyMu.RUnlock()
+	pii := logPolicy.PII.Contains(rowType, columnName)
+	for _, allowed := range logPolicy.AllowColumns {
+		if allowed == columnName || (allowed == AllColumns && !pii) {
+			return fmt.Sprintf("%q", fmt.Sprint(value))
+		}
+	}
+	return redacted
+}
+
+// LogColumns formats the columns a write sets for logging, in order of name,
+// each as its name and its value as LogColumnValue formats it.
+func LogColumns(rowType string, columns map[string]interface{}) string {
+	names := make([]string, 0, len(columns))
+	for name := range columns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	logged := make([]string, len(names))
+	for i, name := range names {
+		logged[i] = fmt.Sprintf("%q=%s", name, LogColumnValue(rowType, name, columns[name]))
+	}
+	return strings.Join(logged, " ")
+}
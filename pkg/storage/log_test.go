@@ -0,0 +1,26 @@
+package storage_test
+
+import (
+	"testing"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+func TestLogColumns(t *testing.T) {
+	t.Cleanup(func() { storage.SetLogPolicy(storage.LogPolicy{}) })
+	columns := map[string]interface{}{"tier": "gold", "owner": "ops", "email": "ops@example.com"}
+
+	want := `"email"=(redacted) "owner"=(redacted) "tier"=(redacted)`
+	if got := storage.LogColumns("team", columns); got != want {
+		t.Errorf("LogColumns logged %s, not %s", got, want)
+	}
+
+	storage.SetLogPolicy(storage.LogPolicy{
+		AllowColumns: []string{storage.AllColumns},
+		PII:          storage.PIIColumns{"team": {"email"}},
+	})
+	want = `"email"=(redacted) "owner"="ops" "tier"="gold"`
+	if got := storage.LogColumns("team", columns); got != want {
+		t.Errorf("LogColumns logged %s, not %s", got, want)
+	}
+}
@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"context"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// The tflog subsystems of this module. Each has its own level, set with
+// TF_LOG_PROVIDER_TREE_<SUBSYSTEM> (e.g. TF_LOG_PROVIDER_TREE_DYNAMODB=TRACE),
+// so that storage logs can be raised without raising everything else.
+const (
+	// LogSubsystemStorage logs the storage package, the in-memory and JSON
+	// file backends, and the storage decorators.
+	LogSubsystemStorage = "storage"
+	// LogSubsystemDynamoDB logs the DynamoDB backend.
+	LogSubsystemDynamoDB = "dynamodb"
+	// LogSubsystemPostgres logs the PostgreSQL backend.
+	LogSubsystemPostgres = "postgres"
+	// LogSubsystemSQLite logs the SQLite backend.
+	LogSubsystemSQLite = "sqlite"
+	// LogSubsystemBolt logs the bbolt backend.
+	LogSubsystemBolt = "bolt"
+	// LogSubsystemGit logs the git repository backend, and the git commands
+	// it runs at TRACE.
+	LogSubsystemGit = "git"
+	// LogSubsystemHTTP logs the client of a storage server.
+	LogSubsystemHTTP = "http"
+	// LogSubsystemGRPC logs the client of a gRPC storage service.
+	LogSubsystemGRPC = "grpc"
+	// LogSubsystemSlug logs the IDs backends generate for new rows.
+	LogSubsystemSlug = "slug"
+	// LogSubsystemBlocks logs generated resources and data sources.
+	LogSubsystemBlocks = "blocks"
+)
+
+// logLevelEnvPrefix prefixes the environment variables of the subsystems'
+// levels.
+const logLevelEnvPrefix = "TF_LOG_PROVIDER_TREE"
+
+var logSubsystems = []string{
+	LogSubsystemStorage,
+	LogSubsystemDynamoDB,
+	LogSubsystemPostgres,
+	LogSubsystemSQLite,
+	LogSubsystemBolt,
+	LogSubsystemGit,
+	LogSubsystemHTTP,
+	LogSubsystemGRPC,
+	LogSubsystemSlug,
+	LogSubsystemBlocks,
+}
+
+// NewLogSubsystems registers this module's tflog subsystems in ctx. Their logs
+// carry the fields already set on ctx, such as the correlation ID, so call it
+// after setting those.
+func NewLogSubsystems(ctx context.Context) context.Context {
+	for _, subsystem := range logSubsystems {
+		ctx = tflog.NewSubsystem(ctx, subsystem,
+			tflog.WithLevelFromEnv(logLevelEnvPrefix, strings.ToUpper(subsystem)),
+			tflog.WithRootFields(),
+		)
+	}
+	return ctx
+}
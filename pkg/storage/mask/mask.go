@@ -0,0 +1,173 @@
+// Package mask wraps a storage.RowStorer to mask the values of PII columns in
+// the rows it returns, for tools that show or export rows to people who
+// needn't see personal data.
+package mask
+
+import (
+	"context"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+// Storer is a storage.RowStorer whose rows have their PII columns masked.
+// Writes pass through unchanged: a masked row written back would overwrite
+// the values with storage.Masked, so callers that copy rows shouldn't read
+// them through a Storer.
+type Storer struct {
+	next storage.RowStorer
+	pii  storage.PIIColumns
+}
+
+var (
+	_ storage.RowStorer     = &Storer{}
+	_ storage.Transactor    = &Storer{}
+	_ storage.ColumnDeleter = &Storer{}
+	_ storage.BatchGetter   = &Storer{}
+	_ storage.ColumnQuerier = &Storer{}
+	_ storage.ChildScanner  = &Storer{}
+	_ storage.BatchPutter   = &Storer{}
+	_ storage.ColumnPatcher = &Storer{}
+	_ storage.RowIterator   = &Storer{}
+)
+
+func NewStorer(next storage.RowStorer, pii storage.PIIColumns) *Storer {
+	return &Storer{next: next, pii: pii}
+}
+
+// Unwrap returns the wrapped storer.
+func (client *Storer) Unwrap() storage.RowStorer {
+	return client.next
+}
+
+func (client *Storer) mask(row storage.Row, err error) (storage.Row, error) {
+	if err != nil {
+		return row, err
+	}
+	return client.pii.Mask(row), nil
+}
+
+func (client *Storer) GetRowByID(ctx context.Context, rowType, rowID string) (storage.Row, error) {
+	return client.mask(client.next.GetRowByID(ctx, rowType, rowID))
+}
+
+func (client *Storer) GetRow(ctx context.Context, rowType, rowLabel string) (storage.Row, error) {
+	return client.mask(client.next.GetRow(ctx, rowType, rowLabel))
+}
+
+func (client *Storer) CreateRow(ctx context.Context, rowType, rowLabel string) (storage.Row, error) {
+	return client.mask(client.next.CreateRow(ctx, rowType, rowLabel))
+}
+
+func (client *Storer) CreateChild(ctx context.Context, rowType, rowLabel, parentType, parentID string, columns map[string]interface{}) (storage.Row, error) {
+	return client.mask(client.next.CreateChild(ctx, rowType, rowLabel, parentType, parentID, columns))
+}
+
+func (client *Storer) GetChild(ctx context.Context, childLabel, parentID string) (storage.Row, error) {
+	return client.mask(client.next.GetChild(ctx, childLabel, parentID))
+}
+
+func (client *Storer) ListRows(ctx context.Context, rowType, labelFilter, parentIDFilter string) ([]storage.Row, error) {
+	rows, err := client.next.ListRows(ctx, rowType, labelFilter, parentIDFilter)
+	if err != nil {
+		return nil, err
+	}
+	for i, row := range rows {
+		rows[i] = client.pii.Mask(row)
+	}
+	return rows, nil
+}
+
+func (client *Storer) IterRows(ctx context.Context, rowType, labelFilter, parentIDFilter string) *storage.Iter[storage.Row] {
+	return storage.WrapPages(storage.IterRows(ctx, client.next, rowType, labelFilter, parentIDFilter), func(ctx context.Context, fetch storage.PageFunc[storage.Row]) ([]storage.Row, bool, error) {
+		page, more, err := fetch(ctx)
+		if err != nil {
+			return nil, false, err
+		}
+		for i, row := range page {
+			page[i] = client.pii.Mask(row)
+		}
+		return page, more, nil
+	})
+}
+
+func (client *Storer) QueryByColumn(ctx context.Context, rowType, columnName string, value interface{}) ([]storage.Row, error) {
+	rows, err := storage.QueryByColumn(ctx, client.next, rowType, columnName, value)
+	if err != nil {
+		return nil, err
+	}
+	for i, row := range rows {
+		rows[i] = client.pii.Mask(row)
+	}
+	return rows, nil
+}
+
+func (client *Storer) UpdateRow(ctx context.Context, rowType, rowID, newLabel string) (storage.Row, error) {
+	return client.mask(client.next.UpdateRow(ctx, rowType, rowID, newLabel))
+}
+
+func (client *Storer) UpdateChild(ctx context.Context, childType, childID, newChildLabel, parentType, newParentID string) (storage.Row, error) {
+	return client.mask(client.next.UpdateChild(ctx, childType, childID, newChildLabel, parentType, newParentID))
+}
+
+func (client *Storer) UpdateColumn(ctx context.Context, rowType, rowID, columnName string, columnValue interface{}) error {
+	return client.next.UpdateColumn(ctx, rowType, rowID, columnName, columnValue)
+}
+
+func (client *Storer) PatchColumns(ctx context.Context, patches []storage.ColumnPatch) error {
+	return storage.PatchColumns(ctx, client.next, patches)
+}
+
+func (client *Storer) UpdateColumns(ctx context.Context, rowType, rowID string, columns map[string]interface{}) error {
+	return client.next.UpdateColumns(ctx, rowType, rowID, columns)
+}
+
+func (client *Storer) DeleteRow(ctx context.Context, rowType, childType, rowID string) error {
+	return client.next.DeleteRow(ctx, rowType, childType, rowID)
+}
+
+func (client *Storer) PutRow(ctx context.Context, row storage.Row) error {
+	return client.next.PutRow(ctx, row)
+}
+
+func (client *Storer) PutRows(ctx context.Context, rows []storage.Row) error {
+	return storage.PutRows(ctx, client.next, rows)
+}
+
+func (client *Storer) ScanRows(ctx context.Context, fn func(storage.Row) error) error {
+	return client.next.ScanRows(ctx, func(row storage.Row) error {
+		return fn(client.pii.Mask(row))
+	})
+}
+
+// ScanChildren masks the children the wrapped storer finds, which it scans
+// every row for if it isn't a storage.ChildScanner.
+func (client *Storer) ScanChildren(ctx context.Context, parentID string, fn func(storage.Row) error) error {
+	return storage.ScanChildren(ctx, client.next, parentID, func(row storage.Row) error {
+		return fn(client.pii.Mask(row))
+	})
+}
+
+// GetRowsByIDs masks the rows the wrapped storer reads, which it reads one at
+// a time if it isn't a storage.BatchGetter.
+func (client *Storer) GetRowsByIDs(ctx context.Context, rowType string, ids []string) ([]storage.Row, error) {
+	rows, err := storage.GetRowsByIDs(ctx, client.next, rowType, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i, row := range rows {
+		rows[i] = client.pii.Mask(row)
+	}
+	return rows, nil
+}
+
+// DeleteColumn has no value to mask, so it passes through.
+func (client *Storer) DeleteColumn(ctx context.Context, rowType, rowID, columnName string) error {
+	return storage.DeleteColumn(ctx, client.next, rowType, rowID, columnName)
+}
+
+// WithinTx masks the rows the transaction reads, too.
+func (client *Storer) WithinTx(ctx context.Context, fn func(tx storage.RowStorer) error) error {
+	return storage.WithinTx(ctx, client.next, func(tx storage.RowStorer) error {
+		return fn(NewStorer(tx, client.pii))
+	})
+}
@@ -0,0 +1,85 @@
+package mask_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/mask"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/memory"
+)
+
+var pii = storage.PIIColumns{"team": {"email", "members"}}
+
+func TestMasksReads(t *testing.T) {
+	ctx := context.Background()
+	backend := memory.NewClient()
+	storer := mask.NewStorer(backend, pii)
+	org, err := storer.CreateRow(ctx, "org", "acme")
+	if err != nil {
+		t.Fatalf("CreateRow: %s", err)
+	}
+	team, err := storer.CreateChild(ctx, "team", "dev", "org", org.ID(), map[string]interface{}{
+		"email":   "dev@example.com",
+		"members": []string{"alice", "bob"},
+		"owner":   "ops",
+	})
+	if err != nil {
+		t.Fatalf("CreateChild: %s", err)
+	}
+
+	got, err := storer.GetRowByID(ctx, "team", team.ID())
+	if err != nil {
+		t.Fatalf("GetRowByID: %s", err)
+	}
+	members, _ := got.Columns()["members"].([]string)
+	if got.Columns()["email"] != storage.Masked || len(members) != 1 || members[0] != storage.Masked {
+		t.Errorf("GetRowByID didn't mask the PII columns: %v", got.Columns())
+	}
+	if got.Columns()["owner"] != "ops" {
+		t.Errorf("GetRowByID masked a column that isn't PII: %v", got.Columns())
+	}
+
+	var scanned []storage.Row
+	if err := storer.ScanChildren(ctx, org.ID(), func(row storage.Row) error {
+		scanned = append(scanned, row)
+		return nil
+	}); err != nil {
+		t.Fatalf("ScanChildren: %s", err)
+	}
+	if len(scanned) != 1 || scanned[0].Columns()["email"] != storage.Masked {
+		t.Errorf("ScanChildren didn't mask the PII columns: %v", scanned)
+	}
+	rows, err := storage.IterRows(ctx, storer, "team", "", "").All()
+	if err != nil {
+		t.Fatalf("IterRows: %s", err)
+	}
+	if len(rows) != 1 || rows[0].Columns()["email"] != storage.Masked {
+		t.Errorf("IterRows didn't mask the PII columns: %v", rows)
+	}
+}
+
+func TestWritesUnmasked(t *testing.T) {
+	ctx := context.Background()
+	backend := memory.NewClient()
+	storer := mask.NewStorer(backend, pii)
+	org, err := backend.CreateRow(ctx, "org", "acme")
+	if err != nil {
+		t.Fatalf("CreateRow: %s", err)
+	}
+	team, err := storer.CreateChild(ctx, "team", "dev", "org", org.ID(), nil)
+	if err != nil {
+		t.Fatalf("CreateChild: %s", err)
+	}
+	if err := storer.UpdateColumn(ctx, "team", team.ID(), "email", "dev@example.com"); err != nil {
+		t.Fatalf("UpdateColumn: %s", err)
+	}
+
+	stored, err := backend.GetRowByID(ctx, "team", team.ID())
+	if err != nil {
+		t.Fatalf("GetRowByID: %s", err)
+	}
+	if stored.Columns()["email"] != "dev@example.com" {
+		t.Errorf("UpdateColumn stored %v, not the value given", stored.Columns()["email"])
+	}
+}
@@ -0,0 +1,417 @@
+// Package memory implements storage.RowStorer in process memory. It keeps the
+// same uniqueness rules as the DynamoDB backend, and is meant for tests and
+// local experiments.
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/spilliams/tree-terraform-provider/internal/slug"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+type key struct {
+	rowType string
+	id      string
+}
+
+type Client struct {
+	mu   sync.RWMutex
+	rows map[key]*row
+}
+
+func NewClient() storage.RowStorer {
+	return &Client{
+		rows: map[key]*row{},
+	}
+}
+
+func (client *Client) GetRowByID(ctx context.Context, rowType, id string) (storage.Row, error) {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemStorage, fmt.Sprintf("GetRowByID %q", id))
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	client.mu.RLock()
+	defer client.mu.RUnlock()
+
+	r, ok := client.rows[key{rowType, id}]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", storage.ErrNotFoundRow, id)
+	}
+	return r.clone(), nil
+}
+
+func (client *Client) GetRow(ctx context.Context, rowType, label string) (storage.Row, error) {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemStorage, fmt.Sprintf("GetRow %q %q", rowType, label))
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	client.mu.RLock()
+	defer client.mu.RUnlock()
+
+	found := client.filter(func(r *row) bool { return r.RowType == rowType && r.RowLabel == label })
+	if len(found) == 0 {
+		return nil, fmt.Errorf("%w: type %q and label %q", storage.ErrNotFoundRow, rowType, label)
+	}
+	if len(found) > 1 {
+		return nil, fmt.Errorf("%w: type %q and label %q", storage.ErrTooManyFound, rowType, label)
+	}
+	return found[0].clone(), nil
+}
+
+func (client *Client) CreateRow(ctx context.Context, rowType, label string) (storage.Row, error) {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemStorage, fmt.Sprintf("CreateRow %q %q", rowType, label))
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	// make sure type+name doesn't collide
+	if len(client.filter(func(r *row) bool { return r.RowType == rowType && r.RowLabel == label })) > 0 {
+		return nil, storage.ErrCollisionTypeLabel
+	}
+
+	r := &row{
+		RowType:  rowType,
+		RowID:    client.newID(ctx, rowType),
+		RowLabel: label,
+	}
+	client.rows[key{rowType, r.RowID}] = r
+	return r.clone(), nil
+}
+
+func (client *Client) CreateChild(ctx context.Context, rowType, label, parentType, parentID string, columns map[string]interface{}) (storage.Row, error) {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemStorage, fmt.Sprintf("CreateChild %q %q %q %q", rowType, label, parentType, parentID))
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	// make sure parent exists
+	if _, ok := client.rows[key{parentType, parentID}]; !ok {
+		return nil, fmt.Errorf("%w: %q", storage.ErrNotFoundRow, parentID)
+	}
+
+	// make sure label is unique within the parent
+	if len(client.filter(func(r *row) bool { return r.RowParentID == parentID && r.RowLabel == label })) > 0 {
+		return nil, storage.ErrCollisionParentLabel
+	}
+
+	r := &row{
+		RowType:     rowType,
+		RowID:       client.newID(ctx, rowType),
+		RowLabel:    label,
+		RowParentID: parentID,
+		RowColumns:  copyColumns(columns),
+	}
+	client.rows[key{rowType, r.RowID}] = r
+	return r.clone(), nil
+}
+
+func (client *Client) GetChild(ctx context.Context, label, parentID string) (storage.Row, error) {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemStorage, fmt.Sprintf("GetChild %q %q", label, parentID))
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	client.mu.RLock()
+	defer client.mu.RUnlock()
+
+	found := client.filter(func(r *row) bool { return r.RowParentID == parentID && r.RowLabel == label })
+	if len(found) == 0 {
+		return nil, fmt.Errorf("%w with parent ID %q and label %q", storage.ErrNotFoundRow, parentID, label)
+	}
+	if len(found) > 1 {
+		return nil, fmt.Errorf("%w: parent ID %q and label %q", storage.ErrTooManyFound, parentID, label)
+	}
+	return found[0].clone(), nil
+}
+
+func (client *Client) ListRows(ctx context.Context, rowType, labelFilter, parentIDFilter string) ([]storage.Row, error) {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemStorage, fmt.Sprintf("ListRows %q %q %q", rowType, labelFilter, parentIDFilter))
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	client.mu.RLock()
+	defer client.mu.RUnlock()
+
+	found := client.filter(func(r *row) bool {
+		if r.RowType != rowType {
+			return false
+		}
+		if labelFilter != "" && !strings.Contains(r.RowLabel, labelFilter) {
+			return false
+		}
+		if parentIDFilter != "" && r.RowParentID != parentIDFilter {
+			return false
+		}
+		return true
+	})
+	rows := make([]storage.Row, len(found))
+	for i, r := range found {
+		rows[i] = r.clone()
+	}
+	return rows, nil
+}
+
+func (client *Client) UpdateRow(ctx context.Context, rowType, id, newLabel string) (storage.Row, error) {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemStorage, fmt.Sprintf("UpdateRow %q %q %q", rowType, id, newLabel))
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	this, ok := client.rows[key{rowType, id}]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", storage.ErrNotFoundRow, id)
+	}
+
+	// ensure new label is available
+	if this.RowParentID == "" {
+		if len(client.filter(func(r *row) bool { return r != this && r.RowType == rowType && r.RowLabel == newLabel })) > 0 {
+			return nil, storage.ErrCollisionTypeLabel
+		}
+	} else {
+		if len(client.filter(func(r *row) bool { return r != this && r.RowParentID == this.RowParentID && r.RowLabel == newLabel })) > 0 {
+			return nil, storage.ErrCollisionParentLabel
+		}
+	}
+
+	this.RowLabel = newLabel
+	return this.clone(), nil
+}
+
+func (client *Client) UpdateChild(ctx context.Context, childType, childID, newChildLabel, parentType, newParentID string) (storage.Row, error) {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemStorage, fmt.Sprintf("UpdateChild %q %q %q %q %q", childType, childID, newChildLabel, parentType, newParentID))
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	// ensure new parent exists
+	if _, ok := client.rows[key{parentType, newParentID}]; !ok {
+		return nil, fmt.Errorf("%w: %q", storage.ErrNotFoundRow, newParentID)
+	}
+
+	this, ok := client.rows[key{childType, childID}]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", storage.ErrNotFoundRow, childID)
+	}
+
+	// ensure new label is available
+	if len(client.filter(func(r *row) bool { return r != this && r.RowParentID == newParentID && r.RowLabel == newChildLabel })) > 0 {
+		return nil, storage.ErrCollisionParentLabel
+	}
+
+	this.RowLabel = newChildLabel
+	this.RowParentID = newParentID
+	return this.clone(), nil
+}
+
+func (client *Client) UpdateColumn(ctx context.Context, rowType, rowID, columnName string, columnValue interface{}) error {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemStorage, fmt.Sprintf("UpdateColumn %q %q %q %s", rowType, rowID, columnName, storage.LogColumnValue(rowType, columnName, columnValue)))
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	this, ok := client.rows[key{rowType, rowID}]
+	if !ok {
+		return fmt.Errorf("%w: %q", storage.ErrNotFoundRow, rowID)
+	}
+	if this.RowColumns == nil {
+		this.RowColumns = map[string]interface{}{}
+	}
+	this.RowColumns[columnName] = copyValue(columnValue)
+	return nil
+}
+
+// PatchColumns applies every patch at once, or none of them if a row is
+// missing.
+func (client *Client) PatchColumns(ctx context.Context, patches []storage.ColumnPatch) error {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemStorage, fmt.Sprintf("PatchColumns %d", len(patches)))
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	for _, patch := range patches {
+		if _, ok := client.rows[key{patch.Type, patch.ID}]; !ok {
+			return fmt.Errorf("%w: %q", storage.ErrNotFoundRow, patch.ID)
+		}
+	}
+	for _, patch := range patches {
+		this := client.rows[key{patch.Type, patch.ID}]
+		if this.RowColumns == nil {
+			this.RowColumns = map[string]interface{}{}
+		}
+		for name, value := range patch.Columns {
+			this.RowColumns[name] = copyValue(value)
+		}
+	}
+	return nil
+}
+
+func (client *Client) UpdateColumns(ctx context.Context, rowType, rowID string, columns map[string]interface{}) error {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemStorage, fmt.Sprintf("UpdateColumns %q %q %s", rowType, rowID, storage.LogColumns(rowType, columns)))
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	this, ok := client.rows[key{rowType, rowID}]
+	if !ok {
+		return fmt.Errorf("%w: %q", storage.ErrNotFoundRow, rowID)
+	}
+	this.RowColumns = copyColumns(columns)
+	if this.RowColumns == nil {
+		this.RowColumns = map[string]interface{}{}
+	}
+	return nil
+}
+
+func (client *Client) DeleteColumn(ctx context.Context, rowType, rowID, columnName string) error {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemStorage, fmt.Sprintf("DeleteColumn %q %q %q", rowType, rowID, columnName))
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	this, ok := client.rows[key{rowType, rowID}]
+	if !ok {
+		return fmt.Errorf("%w: %q", storage.ErrNotFoundRow, rowID)
+	}
+	delete(this.RowColumns, columnName)
+	return nil
+}
+
+func (client *Client) DeleteRow(ctx context.Context, rowType, childType, id string) error {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemStorage, fmt.Sprintf("DeleteRow %q %q %q", rowType, childType, id))
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	// ensure this row does not have any children
+	if len(childType) > 0 {
+		if len(client.filter(func(r *row) bool { return r.RowType == childType && r.RowParentID == id })) > 0 {
+			return fmt.Errorf("%s %s has children: %w", rowType, id, storage.ErrCannotDeleteRow)
+		}
+	}
+
+	k := key{rowType, id}
+	if _, ok := client.rows[k]; !ok {
+		return fmt.Errorf("%w: %q", storage.ErrNotFoundRow, id)
+	}
+	delete(client.rows, k)
+	return nil
+}
+
+func (client *Client) PutRow(ctx context.Context, r storage.Row) error {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemStorage, fmt.Sprintf("PutRow %q %q", r.Type(), r.ID()))
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	client.rows[key{r.Type(), r.ID()}] = &row{
+		RowType:     r.Type(),
+		RowID:       r.ID(),
+		RowLabel:    r.Label(),
+		RowParentID: r.ParentID(),
+		RowColumns:  copyColumns(r.Columns()),
+	}
+	return nil
+}
+
+func (client *Client) ScanRows(ctx context.Context, fn func(storage.Row) error) error {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemStorage, "ScanRows")
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	client.mu.RLock()
+	found := client.filter(func(*row) bool { return true })
+	rows := make([]*row, len(found))
+	for i, r := range found {
+		rows[i] = r.clone()
+	}
+	client.mu.RUnlock()
+
+	// call fn without the lock, so that it may use the client
+	for _, r := range rows {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (client *Client) ScanChildren(ctx context.Context, parentID string, fn func(storage.Row) error) error {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemStorage, fmt.Sprintf("ScanChildren %q", parentID))
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	client.mu.RLock()
+	found := client.filter(func(r *row) bool { return r.RowParentID == parentID })
+	rows := make([]*row, len(found))
+	for i, r := range found {
+		rows[i] = r.clone()
+	}
+	client.mu.RUnlock()
+
+	for _, r := range rows {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// filter returns the rows matching the predicate, ordered by type and ID so
+// that results are stable. Callers must hold the lock.
+func (client *Client) filter(match func(*row) bool) []*row {
+	found := []*row{}
+	for _, r := range client.rows {
+		if match(r) {
+			found = append(found, r)
+		}
+	}
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].RowType != found[j].RowType {
+			return found[i].RowType < found[j].RowType
+		}
+		return found[i].RowID < found[j].RowID
+	})
+	return found
+}
+
+// newID generates an ID that isn't in use. Callers must hold the lock.
+func (client *Client) newID(ctx context.Context, rowType string) string {
+	for {
+		id := slug.Generate(rowType)
+		if _, ok := client.rows[key{rowType, id}]; !ok {
+			tflog.SubsystemTrace(ctx, storage.LogSubsystemSlug, fmt.Sprintf("Generated ID %q", id))
+			return id
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package memory
+
+type row struct {
+	RowType     string
+	RowID       string
+	RowLabel    string
+	RowParentID string
+	RowColumns  map[string]interface{}
+}
+
+// clone copies the row, so that rows handed to callers never share column
+// values with the stored ones.
+func (r *row) clone() *row {
+	c := *r
+	c.RowColumns = copyColumns(r.RowColumns)
+	return &c
+}
+
+func copyColumns(columns map[string]interface{}) map[string]interface{} {
+	if columns == nil {
+		return nil
+	}
+	out := make(map[string]interface{}, len(columns))
+	for k, v := range columns {
+		out[k] = copyValue(v)
+	}
+	return out
+}
+
+func copyValue(v interface{}) interface{} {
+	if vStringList, isStringList := v.([]string); isStringList {
+		return append([]string(nil), vStringList...)
+	}
+	return v
+}
+
+func (r *row) Type() string                    { return r.RowType }
+func (r *row) ID() string                      { return r.RowID }
+func (r *row) Label() string                   { return r.RowLabel }
+func (r *row) ParentID() string                { return r.RowParentID }
+func (r *row) Columns() map[string]interface{} { return r.RowColumns }
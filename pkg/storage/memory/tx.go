@@ -0,0 +1,32 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+var _ storage.Transactor = &Client{}
+
+// WithinTx calls fn with a copy of the rows, which replaces them if fn
+// returns nil. Other callers wait until fn returns, so that transactions are
+// made one at a time, and fn can't see them but through the copy.
+func (client *Client) WithinTx(ctx context.Context, fn func(tx storage.RowStorer) error) error {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemStorage, "WithinTx")
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	tx := &Client{rows: make(map[key]*row, len(client.rows))}
+	for k, r := range client.rows {
+		tx.rows[k] = r.clone()
+	}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	client.rows = tx.rows
+	return nil
+}
@@ -0,0 +1,265 @@
+// Package metrics measures storage operations: how many there are, how long
+// they take, how often the backend throttles them, and how large the rows
+// they read and write are.
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/aws/smithy-go"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+// Recorder receives the measurements of storage operations. Operations are
+// named after RowStorer methods.
+type Recorder interface {
+	// ObserveOperation records one finished operation, and its error, if any.
+	ObserveOperation(op string, duration time.Duration, err error)
+	// ObserveThrottle records an operation the backend throttled.
+	ObserveThrottle(op string)
+	// ObserveItemSize records the size in bytes of a row an operation read
+	// or wrote.
+	ObserveItemSize(op string, bytes int)
+}
+
+// throttleCodes are the DynamoDB error codes for throttled requests.
+var throttleCodes = map[string]bool{
+	"ProvisionedThroughputExceededException": true,
+	"ThrottlingException":                    true,
+	"RequestLimitExceeded":                   true,
+}
+
+// IsThrottle reports whether err is the backend refusing a request for
+// exceeding its capacity. Server errors, though of the same kind, aren't.
+func IsThrottle(err error) bool {
+	if errors.Is(err, storage.ErrServerError) {
+		return false
+	}
+	if errors.Is(err, storage.ErrThrottled) {
+		return true
+	}
+	var apiErr smithy.APIError
+	return errors.As(err, &apiErr) && throttleCodes[apiErr.ErrorCode()]
+}
+
+// Result names the outcome of an operation, as storage.Result does.
+func Result(err error) string {
+	return storage.Result(err)
+}
+
+// Storer is a storage.RowStorer that records measurements of each operation
+// of the one it wraps.
+type Storer struct {
+	next     storage.RowStorer
+	recorder Recorder
+}
+
+var (
+	_ storage.RowStorer     = &Storer{}
+	_ storage.Transactor    = &Storer{}
+	_ storage.ColumnDeleter = &Storer{}
+	_ storage.BatchGetter   = &Storer{}
+	_ storage.ColumnQuerier = &Storer{}
+	_ storage.ChildScanner  = &Storer{}
+	_ storage.BatchPutter   = &Storer{}
+	_ storage.ColumnPatcher = &Storer{}
+	_ storage.RowIterator   = &Storer{}
+)
+
+func NewStorer(next storage.RowStorer, recorder Recorder) *Storer {
+	return &Storer{next: next, recorder: recorder}
+}
+
+// Unwrap returns the wrapped storer.
+func (client *Storer) Unwrap() storage.RowStorer {
+	return client.next
+}
+
+// observe records an operation that started at start. Rows are measured by
+// their JSON encoding, which tracks their stored size closely enough to spot
+// outliers.
+func (client *Storer) observe(op string, start time.Time, err error, rows ...storage.Row) {
+	client.recorder.ObserveOperation(op, time.Since(start), err)
+	if IsThrottle(err) {
+		client.recorder.ObserveThrottle(op)
+	}
+	if err != nil {
+		return
+	}
+	for _, row := range rows {
+		client.recorder.ObserveItemSize(op, rowSize(row))
+	}
+}
+
+func rowSize(row storage.Row) int {
+	b, _ := json.Marshal(struct {
+		Type     string                 `json:"type"`
+		ID       string                 `json:"id"`
+		Label    string                 `json:"label"`
+		ParentID string                 `json:"parent_id,omitempty"`
+		Columns  map[string]interface{} `json:"columns,omitempty"`
+	}{row.Type(), row.ID(), row.Label(), row.ParentID(), row.Columns()})
+	return len(b)
+}
+
+func (client *Storer) GetRowByID(ctx context.Context, rowType, rowID string) (storage.Row, error) {
+	start := time.Now()
+	row, err := client.next.GetRowByID(ctx, rowType, rowID)
+	client.observe("GetRowByID", start, err, row)
+	return row, err
+}
+
+func (client *Storer) GetRowsByIDs(ctx context.Context, rowType string, ids []string) ([]storage.Row, error) {
+	start := time.Now()
+	rows, err := storage.GetRowsByIDs(ctx, client.next, rowType, ids)
+	client.observe("GetRowsByIDs", start, err, rows...)
+	return rows, err
+}
+
+func (client *Storer) GetRow(ctx context.Context, rowType, rowLabel string) (storage.Row, error) {
+	start := time.Now()
+	row, err := client.next.GetRow(ctx, rowType, rowLabel)
+	client.observe("GetRow", start, err, row)
+	return row, err
+}
+
+func (client *Storer) CreateRow(ctx context.Context, rowType, rowLabel string) (storage.Row, error) {
+	start := time.Now()
+	row, err := client.next.CreateRow(ctx, rowType, rowLabel)
+	client.observe("CreateRow", start, err, row)
+	return row, err
+}
+
+func (client *Storer) CreateChild(ctx context.Context, rowType, rowLabel, parentType, parentID string, columns map[string]interface{}) (storage.Row, error) {
+	start := time.Now()
+	row, err := client.next.CreateChild(ctx, rowType, rowLabel, parentType, parentID, columns)
+	client.observe("CreateChild", start, err, row)
+	return row, err
+}
+
+func (client *Storer) GetChild(ctx context.Context, childLabel, parentID string) (storage.Row, error) {
+	start := time.Now()
+	row, err := client.next.GetChild(ctx, childLabel, parentID)
+	client.observe("GetChild", start, err, row)
+	return row, err
+}
+
+func (client *Storer) ListRows(ctx context.Context, rowType, labelFilter, parentIDFilter string) ([]storage.Row, error) {
+	start := time.Now()
+	rows, err := client.next.ListRows(ctx, rowType, labelFilter, parentIDFilter)
+	client.observe("ListRows", start, err, rows...)
+	return rows, err
+}
+
+// IterRows observes each page it reads as an operation.
+func (client *Storer) IterRows(ctx context.Context, rowType, labelFilter, parentIDFilter string) *storage.Iter[storage.Row] {
+	return storage.WrapPages(storage.IterRows(ctx, client.next, rowType, labelFilter, parentIDFilter), func(ctx context.Context, fetch storage.PageFunc[storage.Row]) ([]storage.Row, bool, error) {
+		start := time.Now()
+		page, more, err := fetch(ctx)
+		client.observe("IterRows", start, err, page...)
+		return page, more, err
+	})
+}
+
+func (client *Storer) QueryByColumn(ctx context.Context, rowType, columnName string, value interface{}) ([]storage.Row, error) {
+	start := time.Now()
+	rows, err := storage.QueryByColumn(ctx, client.next, rowType, columnName, value)
+	client.observe("QueryByColumn", start, err, rows...)
+	return rows, err
+}
+
+func (client *Storer) UpdateRow(ctx context.Context, rowType, rowID, newLabel string) (storage.Row, error) {
+	start := time.Now()
+	row, err := client.next.UpdateRow(ctx, rowType, rowID, newLabel)
+	client.observe("UpdateRow", start, err, row)
+	return row, err
+}
+
+func (client *Storer) UpdateChild(ctx context.Context, childType, childID, newChildLabel, parentType, newParentID string) (storage.Row, error) {
+	start := time.Now()
+	row, err := client.next.UpdateChild(ctx, childType, childID, newChildLabel, parentType, newParentID)
+	client.observe("UpdateChild", start, err, row)
+	return row, err
+}
+
+func (client *Storer) UpdateColumn(ctx context.Context, rowType, rowID, columnName string, columnValue interface{}) error {
+	start := time.Now()
+	err := client.next.UpdateColumn(ctx, rowType, rowID, columnName, columnValue)
+	client.observe("UpdateColumn", start, err)
+	return err
+}
+
+func (client *Storer) PatchColumns(ctx context.Context, patches []storage.ColumnPatch) error {
+	start := time.Now()
+	err := storage.PatchColumns(ctx, client.next, patches)
+	client.observe("PatchColumns", start, err)
+	return err
+}
+
+func (client *Storer) UpdateColumns(ctx context.Context, rowType, rowID string, columns map[string]interface{}) error {
+	start := time.Now()
+	err := client.next.UpdateColumns(ctx, rowType, rowID, columns)
+	client.observe("UpdateColumns", start, err)
+	return err
+}
+
+func (client *Storer) DeleteRow(ctx context.Context, rowType, childType, rowID string) error {
+	start := time.Now()
+	err := client.next.DeleteRow(ctx, rowType, childType, rowID)
+	client.observe("DeleteRow", start, err)
+	return err
+}
+
+func (client *Storer) PutRow(ctx context.Context, row storage.Row) error {
+	start := time.Now()
+	err := client.next.PutRow(ctx, row)
+	client.observe("PutRow", start, err, row)
+	return err
+}
+
+func (client *Storer) PutRows(ctx context.Context, rows []storage.Row) error {
+	start := time.Now()
+	err := storage.PutRows(ctx, client.next, rows)
+	client.observe("PutRows", start, err, rows...)
+	return err
+}
+
+func (client *Storer) ScanRows(ctx context.Context, fn func(storage.Row) error) error {
+	start := time.Now()
+	err := client.next.ScanRows(ctx, func(row storage.Row) error {
+		client.recorder.ObserveItemSize("ScanRows", rowSize(row))
+		return fn(row)
+	})
+	client.observe("ScanRows", start, err)
+	return err
+}
+
+func (client *Storer) ScanChildren(ctx context.Context, parentID string, fn func(storage.Row) error) error {
+	start := time.Now()
+	err := storage.ScanChildren(ctx, client.next, parentID, func(row storage.Row) error {
+		client.recorder.ObserveItemSize("ScanChildren", rowSize(row))
+		return fn(row)
+	})
+	client.observe("ScanChildren", start, err)
+	return err
+}
+
+func (client *Storer) DeleteColumn(ctx context.Context, rowType, rowID, columnName string) error {
+	start := time.Now()
+	err := storage.DeleteColumn(ctx, client.next, rowType, rowID, columnName)
+	client.observe("DeleteColumn", start, err)
+	return err
+}
+
+// WithinTx measures the transaction, and each of its operations.
+func (client *Storer) WithinTx(ctx context.Context, fn func(tx storage.RowStorer) error) error {
+	start := time.Now()
+	err := storage.WithinTx(ctx, client.next, func(tx storage.RowStorer) error {
+		return fn(NewStorer(tx, client.recorder))
+	})
+	client.observe("WithinTx", start, err)
+	return err
+}
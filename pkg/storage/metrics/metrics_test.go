@@ -0,0 +1,148 @@
+package metrics_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/memory"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/metrics"
+)
+
+// recording is a Recorder that keeps what it's given.
+type recording struct {
+	operations []string
+	throttles  []string
+	sizes      map[string]int
+}
+
+func (r *recording) ObserveOperation(op string, duration time.Duration, err error) {
+	r.operations = append(r.operations, op+" "+metrics.Result(err))
+}
+
+func (r *recording) ObserveThrottle(op string) {
+	r.throttles = append(r.throttles, op)
+}
+
+func (r *recording) ObserveItemSize(op string, bytes int) {
+	r.sizes[op] += bytes
+}
+
+// throttled throttles every DeleteRow, and fails every DeleteColumn.
+type throttled struct {
+	storage.RowStorer
+}
+
+func (client *throttled) DeleteRow(ctx context.Context, rowType, childType, rowID string) error {
+	return fmt.Errorf("deleting %s: %w", rowID, storage.ErrThrottled)
+}
+
+func (client *throttled) DeleteColumn(ctx context.Context, rowType, rowID, columnName string) error {
+	return fmt.Errorf("deleting %s: %w", columnName, storage.ErrServerError)
+}
+
+func TestObserves(t *testing.T) {
+	ctx := context.Background()
+	recorder := &recording{sizes: map[string]int{}}
+	storer := metrics.NewStorer(memory.NewClient(), recorder)
+	org, err := storer.CreateRow(ctx, "org", "acme")
+	if err != nil {
+		t.Fatalf("CreateRow: %s", err)
+	}
+	if _, err := storer.GetRowByID(ctx, "org", org.ID()); err != nil {
+		t.Fatalf("GetRowByID: %s", err)
+	}
+	if _, err := storer.GetRowByID(ctx, "org", "org-missing"); err == nil {
+		t.Fatal("GetRowByID of a missing row succeeded")
+	}
+
+	want := []string{"CreateRow ok", "GetRowByID ok", "GetRowByID not_found"}
+	if fmt.Sprint(recorder.operations) != fmt.Sprint(want) {
+		t.Errorf("observed operations %v, not %v", recorder.operations, want)
+	}
+	if recorder.sizes["CreateRow"] == 0 || recorder.sizes["GetRowByID"] != recorder.sizes["CreateRow"] {
+		t.Errorf("observed sizes %v, not the one row's, written and read", recorder.sizes)
+	}
+}
+
+func TestThrottles(t *testing.T) {
+	ctx := context.Background()
+	recorder := &recording{sizes: map[string]int{}}
+	storer := metrics.NewStorer(&throttled{RowStorer: memory.NewClient()}, recorder)
+	_ = storer.DeleteRow(ctx, "org", "team", "org-acme")
+	// a server error is of the throttled kind, but isn't a throttle
+	_ = storer.DeleteColumn(ctx, "org", "org-acme", "owner")
+
+	want := []string{"DeleteRow throttled", "DeleteColumn server_error"}
+	if fmt.Sprint(recorder.operations) != fmt.Sprint(want) {
+		t.Errorf("observed operations %v, not %v", recorder.operations, want)
+	}
+	if fmt.Sprint(recorder.throttles) != "[DeleteRow]" {
+		t.Errorf("observed throttles of %v, not of DeleteRow alone", recorder.throttles)
+	}
+}
+
+func TestPrometheus(t *testing.T) {
+	ctx := context.Background()
+	reg := prometheus.NewRegistry()
+	recorder, err := metrics.NewPrometheus(reg)
+	if err != nil {
+		t.Fatalf("NewPrometheus: %s", err)
+	}
+	storer := metrics.NewStorer(&throttled{RowStorer: memory.NewClient()}, recorder)
+	if _, err := storer.CreateRow(ctx, "org", "acme"); err != nil {
+		t.Fatalf("CreateRow: %s", err)
+	}
+	_, _ = storer.GetRowByID(ctx, "org", "org-missing")
+	_ = storer.DeleteRow(ctx, "org", "team", "org-acme")
+
+	operations := `
+# HELP tree_storage_operations_total Storage operations, by result: ok, not_found, conflict, throttled, server_error, permission_denied, invalid, or error.
+# TYPE tree_storage_operations_total counter
+tree_storage_operations_total{op="CreateRow",result="ok"} 1
+tree_storage_operations_total{op="DeleteRow",result="throttled"} 1
+tree_storage_operations_total{op="GetRowByID",result="not_found"} 1
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(operations), "tree_storage_operations_total"); err != nil {
+		t.Error(err)
+	}
+	// a row not found isn't an error
+	errs := `
+# HELP tree_storage_errors_total Storage operations that failed, by result. A row not found is an answer, not a failure, so it isn't counted.
+# TYPE tree_storage_errors_total counter
+tree_storage_errors_total{op="DeleteRow",result="throttled"} 1
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(errs), "tree_storage_errors_total"); err != nil {
+		t.Error(err)
+	}
+	if count, err := testutil.GatherAndCount(reg, "tree_storage_throttles_total"); err != nil || count != 1 {
+		t.Errorf("%d ops have throttles, not 1 (%v)", count, err)
+	}
+}
+
+func TestSummary(t *testing.T) {
+	ctx := context.Background()
+	summary := metrics.NewSummary()
+	storer := metrics.NewStorer(&throttled{RowStorer: memory.NewClient()}, summary)
+	for _, label := range []string{"acme", "globex"} {
+		if _, err := storer.CreateRow(ctx, "org", label); err != nil {
+			t.Fatalf("CreateRow: %s", err)
+		}
+	}
+	_ = storer.DeleteRow(ctx, "org", "team", "org-acme")
+
+	report := summary.Report()
+	if report.Total != 3 || report.Throttles != 1 || report.Operations["CreateRow"]["ok"] != 2 || report.Operations["DeleteRow"]["throttled"] != 1 {
+		t.Errorf("the summary is %+v, not 2 rows created and 1 delete throttled", report)
+	}
+	// the report is a copy
+	report.Operations["CreateRow"]["ok"] = 0
+	if summary.Report().Operations["CreateRow"]["ok"] != 2 {
+		t.Error("changing the report changed the summary")
+	}
+}
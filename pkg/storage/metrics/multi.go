@@ -0,0 +1,29 @@
+package metrics
+
+import "time"
+
+// multi is a Recorder that passes every measurement to several.
+type multi []Recorder
+
+// Multi returns a Recorder that passes every measurement to each of recorders.
+func Multi(recorders ...Recorder) Recorder {
+	return multi(recorders)
+}
+
+func (m multi) ObserveOperation(op string, duration time.Duration, err error) {
+	for _, recorder := range m {
+		recorder.ObserveOperation(op, duration, err)
+	}
+}
+
+func (m multi) ObserveThrottle(op string) {
+	for _, recorder := range m {
+		recorder.ObserveThrottle(op)
+	}
+}
+
+func (m multi) ObserveItemSize(op string, bytes int) {
+	for _, recorder := range m {
+		recorder.ObserveItemSize(op, bytes)
+	}
+}
@@ -0,0 +1,93 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+const namespace = "tree_storage"
+
+// Prometheus is a Recorder that keeps Prometheus metrics:
+//
+//   - tree_storage_operations_total, by op and result
+//   - tree_storage_errors_total, by op and result
+//   - tree_storage_operation_duration_seconds, by op
+//   - tree_storage_throttles_total, by op
+//   - tree_storage_item_size_bytes, by op
+type Prometheus struct {
+	operations *prometheus.CounterVec
+	errors     *prometheus.CounterVec
+	durations  *prometheus.HistogramVec
+	throttles  *prometheus.CounterVec
+	itemSizes  *prometheus.HistogramVec
+}
+
+var _ Recorder = &Prometheus{}
+
+// NewPrometheus returns a recorder whose metrics are registered with reg.
+func NewPrometheus(reg prometheus.Registerer) (*Prometheus, error) {
+	p := &Prometheus{
+		operations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "operations_total",
+			Help:      "Storage operations, by result: ok, not_found, conflict, throttled, server_error, permission_denied, invalid, or error.",
+		}, []string{"op", "result"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "errors_total",
+			Help:      "Storage operations that failed, by result. A row not found is an answer, not a failure, so it isn't counted.",
+		}, []string{"op", "result"}),
+		durations: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "operation_duration_seconds",
+			Help:      "How long storage operations take.",
+			Buckets:   prometheus.ExponentialBuckets(0.005, 2, 12),
+		}, []string{"op"}),
+		throttles: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "throttles_total",
+			Help:      "Storage operations the backend throttled.",
+		}, []string{"op"}),
+		itemSizes: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "item_size_bytes",
+			Help:      "Sizes of the rows storage operations read and write.",
+			// DynamoDB items can't exceed 400 KB
+			Buckets: prometheus.ExponentialBuckets(256, 2, 12),
+		}, []string{"op"}),
+	}
+	for _, collector := range []prometheus.Collector{p.operations, p.errors, p.durations, p.throttles, p.itemSizes} {
+		if err := reg.Register(collector); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+func (p *Prometheus) ObserveOperation(op string, duration time.Duration, err error) {
+	result := Result(err)
+	p.operations.WithLabelValues(op, result).Inc()
+	if err != nil && !errors.Is(err, storage.ErrNotFoundRow) {
+		p.errors.WithLabelValues(op, result).Inc()
+	}
+	p.durations.WithLabelValues(op).Observe(duration.Seconds())
+}
+
+func (p *Prometheus) ObserveThrottle(op string) {
+	p.throttles.WithLabelValues(op).Inc()
+}
+
+func (p *Prometheus) ObserveItemSize(op string, bytes int) {
+	p.itemSizes.WithLabelValues(op).Observe(float64(bytes))
+}
+
+// Handler serves the metrics registered with gatherer, in the Prometheus text
+// format.
+func Handler(gatherer prometheus.Gatherer) http.Handler {
+	return promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{})
+}
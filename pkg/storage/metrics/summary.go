@@ -0,0 +1,70 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+)
+
+// Summary is a Recorder that totals operations over a run, for a report at
+// its end.
+type Summary struct {
+	mu        sync.Mutex
+	ops       map[string]map[string]int
+	throttles int
+	duration  time.Duration
+}
+
+var _ Recorder = &Summary{}
+
+// SummaryReport is what a Summary has totalled.
+type SummaryReport struct {
+	// Operations counts operations by name, then by Result.
+	Operations map[string]map[string]int `json:"operations"`
+	// Total is the number of operations.
+	Total int `json:"total"`
+	// Throttles is the number of operations the backend throttled.
+	Throttles int `json:"throttles"`
+	// Duration is the time spent in operations, which may overlap.
+	Duration time.Duration `json:"duration_ns"`
+}
+
+func NewSummary() *Summary {
+	return &Summary{ops: map[string]map[string]int{}}
+}
+
+func (s *Summary) ObserveOperation(op string, duration time.Duration, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ops[op] == nil {
+		s.ops[op] = map[string]int{}
+	}
+	s.ops[op][Result(err)]++
+	s.duration += duration
+}
+
+func (s *Summary) ObserveThrottle(op string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.throttles++
+}
+
+func (s *Summary) ObserveItemSize(op string, bytes int) {}
+
+// Report returns the totals so far.
+func (s *Summary) Report() SummaryReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	report := SummaryReport{
+		Operations: map[string]map[string]int{},
+		Throttles:  s.throttles,
+		Duration:   s.duration,
+	}
+	for op, results := range s.ops {
+		report.Operations[op] = map[string]int{}
+		for result, count := range results {
+			report.Operations[op][result] = count
+			report.Total += count
+		}
+	}
+	return report
+}
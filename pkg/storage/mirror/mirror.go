@@ -0,0 +1,379 @@
+// Package mirror wraps two storage.RowStorers, a primary and a shadow, to
+// write every change to both and compare what they read, so that a migration
+// from one backend to another, such as from DynamoDB to PostgreSQL, can run
+// alongside the real thing for a while before cutting over.
+//
+// The primary is the source of truth: its results are returned, and the
+// shadow's failures and differences are only logged and counted. Copy the
+// existing rows to the shadow first, as with schemactl migrate, so that
+// changes to them have something to change.
+package mirror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+// Report counts what a Storer mirrored and compared.
+type Report struct {
+	// Writes is how many of the primary's writes were written to the
+	// shadow, and WriteErrors how many of those failed there.
+	Writes      int `json:"writes"`
+	WriteErrors int `json:"write_errors"`
+	// Reads is how many reads were compared, and Mismatches how many of
+	// them the shadow answered differently.
+	Reads      int `json:"reads"`
+	Mismatches int `json:"mismatches"`
+}
+
+// Storer is a storage.RowStorer that writes to both the primary and the
+// shadow, and reads from both, returning the primary's results.
+type Storer struct {
+	primary storage.RowStorer
+	shadow  storage.RowStorer
+
+	mu     sync.Mutex
+	report Report
+}
+
+var (
+	_ storage.RowStorer     = &Storer{}
+	_ storage.Transactor    = &Storer{}
+	_ storage.ColumnDeleter = &Storer{}
+	_ storage.BatchGetter   = &Storer{}
+	_ storage.ColumnQuerier = &Storer{}
+	_ storage.ChildScanner  = &Storer{}
+	_ storage.BatchPutter   = &Storer{}
+	_ storage.ColumnPatcher = &Storer{}
+	_ storage.RowIterator   = &Storer{}
+)
+
+// NewStorer mirrors the primary's writes to the shadow, and compares their
+// reads.
+func NewStorer(primary, shadow storage.RowStorer) *Storer {
+	return &Storer{primary: primary, shadow: shadow}
+}
+
+// Unwrap returns the primary.
+func (client *Storer) Unwrap() storage.RowStorer {
+	return client.primary
+}
+
+// Report returns the counts of what has been mirrored and compared so far.
+func (client *Storer) Report() Report {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	return client.report
+}
+
+// mirrored records a write made to the shadow after the primary, warning
+// if it failed there.
+func (client *Storer) mirrored(ctx context.Context, op, rowType, rowID string, err error) {
+	client.mu.Lock()
+	client.report.Writes++
+	if err != nil {
+		client.report.WriteErrors++
+	}
+	client.mu.Unlock()
+	if err != nil {
+		tflog.SubsystemWarn(ctx, storage.LogSubsystemStorage, fmt.Sprintf("Shadow backend failed to mirror %s", op), map[string]interface{}{
+			"type":  rowType,
+			"id":    rowID,
+			"error": err.Error(),
+		})
+	}
+}
+
+// compared records a read compared between the backends, warning of the
+// differences, if any. differences name fields, never their values, which
+// may be sensitive.
+func (client *Storer) compared(ctx context.Context, op string, fields map[string]interface{}, differences []string) {
+	client.mu.Lock()
+	client.report.Reads++
+	if len(differences) > 0 {
+		client.report.Mismatches++
+	}
+	client.mu.Unlock()
+	if len(differences) > 0 {
+		fields["differences"] = differences
+		tflog.SubsystemWarn(ctx, storage.LogSubsystemStorage, fmt.Sprintf("Shadow backend read differs for %s", op), fields)
+	}
+}
+
+// results are the results of a read by each backend.
+type results[T any] struct {
+	primary   T
+	err       error
+	shadow    T
+	shadowErr error
+}
+
+// both calls read with each backend at once.
+func both[T any](client *Storer, read func(storage.RowStorer) (T, error)) results[T] {
+	var r results[T]
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		r.shadow, r.shadowErr = read(client.shadow)
+	}()
+	r.primary, r.err = read(client.primary)
+	<-done
+	return r
+}
+
+// compareRow compares the row each backend read, records the read, and
+// returns the primary's result.
+func (client *Storer) compareRow(ctx context.Context, op string, fields map[string]interface{}, r results[storage.Row]) (storage.Row, error) {
+	client.compared(ctx, op, fields, diffResults(r.primary, r.err, r.shadow, r.shadowErr))
+	return r.primary, r.err
+}
+
+// compareRows compares the rows each backend read, by ID, records the read,
+// and returns the primary's result.
+func (client *Storer) compareRows(ctx context.Context, op string, fields map[string]interface{}, r results[[]storage.Row]) ([]storage.Row, error) {
+	if r.err != nil || r.shadowErr != nil {
+		client.compared(ctx, op, fields, diffResults(nil, r.err, nil, r.shadowErr))
+		return r.primary, r.err
+	}
+	differences := []string{}
+	shadowByID := make(map[string]storage.Row, len(r.shadow))
+	for _, row := range r.shadow {
+		shadowByID[row.ID()] = row
+	}
+	for _, row := range r.primary {
+		shadow, ok := shadowByID[row.ID()]
+		if !ok {
+			differences = append(differences, "missing "+row.ID())
+			continue
+		}
+		delete(shadowByID, row.ID())
+		if len(diffRows(row, shadow)) > 0 {
+			differences = append(differences, "changed "+row.ID())
+		}
+	}
+	for id := range shadowByID {
+		differences = append(differences, "extra "+id)
+	}
+	sort.Strings(differences)
+	client.compared(ctx, op, fields, differences)
+	return r.primary, nil
+}
+
+// diffResults names the differences between two backends' results of a read:
+// its error, or, if both succeeded, its row's fields.
+func diffResults(primary storage.Row, err error, shadow storage.Row, shadowErr error) []string {
+	if err != nil || shadowErr != nil {
+		if storage.Result(err) != storage.Result(shadowErr) {
+			return []string{fmt.Sprintf("result %s -> %s", storage.Result(err), storage.Result(shadowErr))}
+		}
+		return nil
+	}
+	return diffRows(primary, shadow)
+}
+
+// diffRows names the fields that differ between two rows.
+func diffRows(a, b storage.Row) []string {
+	differences := []string{}
+	if a.Label() != b.Label() {
+		differences = append(differences, "label")
+	}
+	if a.ParentID() != b.ParentID() {
+		differences = append(differences, "parent_id")
+	}
+	names := map[string]bool{}
+	for name := range a.Columns() {
+		names[name] = true
+	}
+	for name := range b.Columns() {
+		names[name] = true
+	}
+	for name := range names {
+		av, aok := a.Columns()[name]
+		bv, bok := b.Columns()[name]
+		if aok != bok || !storage.EqualColumns(map[string]interface{}{name: av}, map[string]interface{}{name: bv}) {
+			differences = append(differences, "column "+name)
+		}
+	}
+	sort.Strings(differences)
+	return differences
+}
+
+func (client *Storer) GetRowByID(ctx context.Context, rowType, rowID string) (storage.Row, error) {
+	r := both(client, func(storer storage.RowStorer) (storage.Row, error) {
+		return storer.GetRowByID(ctx, rowType, rowID)
+	})
+	return client.compareRow(ctx, "GetRowByID", map[string]interface{}{"type": rowType, "id": rowID}, r)
+}
+
+func (client *Storer) GetRowsByIDs(ctx context.Context, rowType string, ids []string) ([]storage.Row, error) {
+	r := both(client, func(storer storage.RowStorer) ([]storage.Row, error) {
+		return storage.GetRowsByIDs(ctx, storer, rowType, ids)
+	})
+	return client.compareRows(ctx, "GetRowsByIDs", map[string]interface{}{"type": rowType, "ids": ids}, r)
+}
+
+func (client *Storer) GetRow(ctx context.Context, rowType, rowLabel string) (storage.Row, error) {
+	r := both(client, func(storer storage.RowStorer) (storage.Row, error) {
+		return storer.GetRow(ctx, rowType, rowLabel)
+	})
+	return client.compareRow(ctx, "GetRow", map[string]interface{}{"type": rowType, "label": rowLabel}, r)
+}
+
+func (client *Storer) GetChild(ctx context.Context, childLabel, parentID string) (storage.Row, error) {
+	r := both(client, func(storer storage.RowStorer) (storage.Row, error) {
+		return storer.GetChild(ctx, childLabel, parentID)
+	})
+	return client.compareRow(ctx, "GetChild", map[string]interface{}{"label": childLabel, "parent_id": parentID}, r)
+}
+
+func (client *Storer) ListRows(ctx context.Context, rowType, labelFilter, parentIDFilter string) ([]storage.Row, error) {
+	r := both(client, func(storer storage.RowStorer) ([]storage.Row, error) {
+		return storer.ListRows(ctx, rowType, labelFilter, parentIDFilter)
+	})
+	return client.compareRows(ctx, "ListRows", map[string]interface{}{"type": rowType, "label": labelFilter, "parent_id": parentIDFilter}, r)
+}
+
+// IterRows reads only the primary, as ScanRows does.
+func (client *Storer) IterRows(ctx context.Context, rowType, labelFilter, parentIDFilter string) *storage.Iter[storage.Row] {
+	return storage.IterRows(ctx, client.primary, rowType, labelFilter, parentIDFilter)
+}
+
+func (client *Storer) QueryByColumn(ctx context.Context, rowType, columnName string, value interface{}) ([]storage.Row, error) {
+	r := both(client, func(storer storage.RowStorer) ([]storage.Row, error) {
+		return storage.QueryByColumn(ctx, storer, rowType, columnName, value)
+	})
+	return client.compareRows(ctx, "QueryByColumn", map[string]interface{}{"type": rowType, "column": columnName}, r)
+}
+
+// ScanRows scans only the primary: comparing every row is for schemactl
+// diff, at a quiet moment.
+func (client *Storer) ScanRows(ctx context.Context, fn func(storage.Row) error) error {
+	return client.primary.ScanRows(ctx, fn)
+}
+
+// ScanChildren scans only the primary, as ScanRows does.
+func (client *Storer) ScanChildren(ctx context.Context, parentID string, fn func(storage.Row) error) error {
+	return storage.ScanChildren(ctx, client.primary, parentID, fn)
+}
+
+// The writes are made to the primary, and, if they succeed, to the shadow,
+// as the primary made them: rows the primary creates or updates are put in
+// the shadow as they are, so that their IDs match.
+
+func (client *Storer) CreateRow(ctx context.Context, rowType, rowLabel string) (storage.Row, error) {
+	created, err := client.primary.CreateRow(ctx, rowType, rowLabel)
+	if err != nil {
+		return nil, err
+	}
+	client.mirrored(ctx, "CreateRow", rowType, created.ID(), client.shadow.PutRow(ctx, created))
+	return created, nil
+}
+
+func (client *Storer) CreateChild(ctx context.Context, rowType, rowLabel, parentType, parentID string, columns map[string]interface{}) (storage.Row, error) {
+	created, err := client.primary.CreateChild(ctx, rowType, rowLabel, parentType, parentID, columns)
+	if err != nil {
+		return nil, err
+	}
+	client.mirrored(ctx, "CreateChild", rowType, created.ID(), client.shadow.PutRow(ctx, created))
+	return created, nil
+}
+
+func (client *Storer) UpdateRow(ctx context.Context, rowType, rowID, newLabel string) (storage.Row, error) {
+	updated, err := client.primary.UpdateRow(ctx, rowType, rowID, newLabel)
+	if err != nil {
+		return nil, err
+	}
+	client.mirrored(ctx, "UpdateRow", rowType, rowID, client.shadow.PutRow(ctx, updated))
+	return updated, nil
+}
+
+func (client *Storer) UpdateChild(ctx context.Context, childType, childID, newChildLabel, parentType, newParentID string) (storage.Row, error) {
+	updated, err := client.primary.UpdateChild(ctx, childType, childID, newChildLabel, parentType, newParentID)
+	if err != nil {
+		return nil, err
+	}
+	client.mirrored(ctx, "UpdateChild", childType, childID, client.shadow.PutRow(ctx, updated))
+	return updated, nil
+}
+
+func (client *Storer) UpdateColumn(ctx context.Context, rowType, rowID, columnName string, columnValue interface{}) error {
+	if err := client.primary.UpdateColumn(ctx, rowType, rowID, columnName, columnValue); err != nil {
+		return err
+	}
+	client.mirrored(ctx, "UpdateColumn", rowType, rowID, client.shadow.UpdateColumn(ctx, rowType, rowID, columnName, columnValue))
+	return nil
+}
+
+// PatchColumns mirrors the patches once the primary has applied all of them,
+// counting each patched row as a write.
+func (client *Storer) PatchColumns(ctx context.Context, patches []storage.ColumnPatch) error {
+	if err := storage.PatchColumns(ctx, client.primary, patches); err != nil {
+		return err
+	}
+	err := storage.PatchColumns(ctx, client.shadow, patches)
+	for _, patch := range patches {
+		client.mirrored(ctx, "PatchColumns", patch.Type, patch.ID, err)
+	}
+	return nil
+}
+
+func (client *Storer) UpdateColumns(ctx context.Context, rowType, rowID string, columns map[string]interface{}) error {
+	if err := client.primary.UpdateColumns(ctx, rowType, rowID, columns); err != nil {
+		return err
+	}
+	client.mirrored(ctx, "UpdateColumns", rowType, rowID, client.shadow.UpdateColumns(ctx, rowType, rowID, columns))
+	return nil
+}
+
+func (client *Storer) DeleteRow(ctx context.Context, rowType, childType, rowID string) error {
+	if err := client.primary.DeleteRow(ctx, rowType, childType, rowID); err != nil {
+		return err
+	}
+	client.mirrored(ctx, "DeleteRow", rowType, rowID, deleteShadow(ctx, client.shadow, rowType, childType, rowID))
+	return nil
+}
+
+// deleteShadow deletes a row the primary deleted from the shadow.
+func deleteShadow(ctx context.Context, shadow storage.RowStorer, rowType, childType, rowID string) error {
+	err := shadow.DeleteRow(ctx, rowType, childType, rowID)
+	if errors.Is(err, storage.ErrNotFoundRow) {
+		// the shadow already lacks it, as it should
+		return nil
+	}
+	return err
+}
+
+func (client *Storer) PutRow(ctx context.Context, row storage.Row) error {
+	if err := client.primary.PutRow(ctx, row); err != nil {
+		return err
+	}
+	client.mirrored(ctx, "PutRow", row.Type(), row.ID(), client.shadow.PutRow(ctx, row))
+	return nil
+}
+
+// PutRows mirrors the batch once the primary has put all of it, counting each
+// row as a write.
+func (client *Storer) PutRows(ctx context.Context, rows []storage.Row) error {
+	if err := storage.PutRows(ctx, client.primary, rows); err != nil {
+		return err
+	}
+	err := storage.PutRows(ctx, client.shadow, rows)
+	for _, row := range rows {
+		client.mirrored(ctx, "PutRows", row.Type(), row.ID(), err)
+	}
+	return nil
+}
+
+func (client *Storer) DeleteColumn(ctx context.Context, rowType, rowID, columnName string) error {
+	if err := storage.DeleteColumn(ctx, client.primary, rowType, rowID, columnName); err != nil {
+		return err
+	}
+	client.mirrored(ctx, "DeleteColumn", rowType, rowID, storage.DeleteColumn(ctx, client.shadow, rowType, rowID, columnName))
+	return nil
+}
@@ -0,0 +1,108 @@
+package mirror_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/dataset"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/memory"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/mirror"
+)
+
+func TestMirrorsWrites(t *testing.T) {
+	ctx := context.Background()
+	primary, shadow := memory.NewClient(), memory.NewClient()
+	storer := mirror.NewStorer(primary, shadow)
+
+	org, err := storer.CreateRow(ctx, "org", "acme")
+	if err != nil {
+		t.Fatalf("CreateRow: %s", err)
+	}
+	if err := storer.UpdateColumn(ctx, "org", org.ID(), "owner", "ops"); err != nil {
+		t.Fatalf("UpdateColumn: %s", err)
+	}
+	others := []storage.Row{
+		&dataset.Record{RowType: "org", RowID: "org-globex", RowLabel: "globex"},
+		&dataset.Record{RowType: "org", RowID: "org-initech", RowLabel: "initech"},
+	}
+	if err := storer.PutRows(ctx, others); err != nil {
+		t.Fatalf("PutRows: %s", err)
+	}
+
+	got, err := shadow.GetRowByID(ctx, "org", org.ID())
+	if err != nil {
+		t.Fatalf("the shadow doesn't have the created row: %s", err)
+	}
+	if got.ID() != org.ID() || got.Columns()["owner"] != "ops" {
+		t.Errorf("the shadow's row is %s with columns %v", got.ID(), got.Columns())
+	}
+	for _, other := range others {
+		if _, err := shadow.GetRowByID(ctx, "org", other.ID()); err != nil {
+			t.Errorf("the shadow doesn't have a put row: %s", err)
+		}
+	}
+	if report := storer.Report(); report != (mirror.Report{Writes: 4}) {
+		t.Errorf("Report is %+v, not 4 writes", report)
+	}
+}
+
+func TestShadowFailures(t *testing.T) {
+	ctx := context.Background()
+	primary, shadow := memory.NewClient(), memory.NewClient()
+	storer := mirror.NewStorer(primary, shadow)
+
+	// the shadow lacks the row, so can't update it
+	org, err := primary.CreateRow(ctx, "org", "acme")
+	if err != nil {
+		t.Fatalf("CreateRow: %s", err)
+	}
+	if err := storer.UpdateColumn(ctx, "org", org.ID(), "owner", "ops"); err != nil {
+		t.Errorf("UpdateColumn failed with the shadow's error: %s", err)
+	}
+	if report := storer.Report(); report != (mirror.Report{Writes: 1, WriteErrors: 1}) {
+		t.Errorf("Report is %+v, not 1 write that failed", report)
+	}
+
+	// and what the primary fails isn't mirrored
+	if err := storer.UpdateColumn(ctx, "org", "org-missing", "owner", "ops"); err == nil {
+		t.Error("UpdateColumn of a row the primary lacks succeeded")
+	}
+	if report := storer.Report(); report.Writes != 1 {
+		t.Errorf("a write the primary failed was mirrored: %+v", report)
+	}
+}
+
+func TestComparesReads(t *testing.T) {
+	ctx := context.Background()
+	primary, shadow := memory.NewClient(), memory.NewClient()
+	storer := mirror.NewStorer(primary, shadow)
+	org, err := storer.CreateRow(ctx, "org", "acme")
+	if err != nil {
+		t.Fatalf("CreateRow: %s", err)
+	}
+
+	if _, err := storer.GetRowByID(ctx, "org", org.ID()); err != nil {
+		t.Fatalf("GetRowByID: %s", err)
+	}
+	if report := storer.Report(); report.Reads != 1 || report.Mismatches != 0 {
+		t.Errorf("a read the backends agree on made Report %+v", report)
+	}
+
+	if err := shadow.UpdateColumn(ctx, "org", org.ID(), "owner", "ops"); err != nil {
+		t.Fatalf("UpdateColumn: %s", err)
+	}
+	got, err := storer.GetRowByID(ctx, "org", org.ID())
+	if err != nil {
+		t.Fatalf("GetRowByID: %s", err)
+	}
+	if got.Columns()["owner"] != nil {
+		t.Errorf("GetRowByID returned the shadow's row: %v", got.Columns())
+	}
+	if _, err := storer.ListRows(ctx, "org", "", ""); err != nil {
+		t.Fatalf("ListRows: %s", err)
+	}
+	if report := storer.Report(); report.Reads != 3 || report.Mismatches != 2 {
+		t.Errorf("2 reads the backends differ on made Report %+v", report)
+	}
+}
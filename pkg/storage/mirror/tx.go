@@ -0,0 +1,141 @@
+package mirror
+
+import (
+	"context"
+	"sync"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+// WithinTx makes the transaction with the primary, and mirrors its writes to
+// the shadow once it commits, and none if it doesn't. Its reads aren't
+// compared, since the shadow can't see its writes until then.
+func (client *Storer) WithinTx(ctx context.Context, fn func(tx storage.RowStorer) error) error {
+	var within *txStorer
+	err := storage.WithinTx(ctx, client.primary, func(tx storage.RowStorer) error {
+		// a transaction that's retried starts over
+		within = &txStorer{RowStorer: tx}
+		return fn(within)
+	})
+	if err != nil {
+		return err
+	}
+	for _, write := range within.writes {
+		client.mirrored(ctx, write.op, write.rowType, write.rowID, write.apply(ctx, client.shadow))
+	}
+	return nil
+}
+
+// shadowWrite is a write to make to the shadow.
+type shadowWrite struct {
+	op      string
+	rowType string
+	rowID   string
+	apply   func(ctx context.Context, shadow storage.RowStorer) error
+}
+
+// txStorer is the storer of a transaction of the primary, which reads from
+// it, and queues the writes it makes to it for the shadow.
+type txStorer struct {
+	storage.RowStorer
+
+	mu     sync.Mutex
+	writes []shadowWrite
+}
+
+func (tx *txStorer) queue(op, rowType, rowID string, apply func(ctx context.Context, shadow storage.RowStorer) error) {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	tx.writes = append(tx.writes, shadowWrite{op: op, rowType: rowType, rowID: rowID, apply: apply})
+}
+
+// put queues putting the row as the primary wrote it.
+func (tx *txStorer) put(op string, row storage.Row) {
+	tx.queue(op, row.Type(), row.ID(), func(ctx context.Context, shadow storage.RowStorer) error {
+		return shadow.PutRow(ctx, row)
+	})
+}
+
+func (tx *txStorer) CreateRow(ctx context.Context, rowType, rowLabel string) (storage.Row, error) {
+	created, err := tx.RowStorer.CreateRow(ctx, rowType, rowLabel)
+	if err != nil {
+		return nil, err
+	}
+	tx.put("CreateRow", created)
+	return created, nil
+}
+
+func (tx *txStorer) CreateChild(ctx context.Context, rowType, rowLabel, parentType, parentID string, columns map[string]interface{}) (storage.Row, error) {
+	created, err := tx.RowStorer.CreateChild(ctx, rowType, rowLabel, parentType, parentID, columns)
+	if err != nil {
+		return nil, err
+	}
+	tx.put("CreateChild", created)
+	return created, nil
+}
+
+func (tx *txStorer) UpdateRow(ctx context.Context, rowType, rowID, newLabel string) (storage.Row, error) {
+	updated, err := tx.RowStorer.UpdateRow(ctx, rowType, rowID, newLabel)
+	if err != nil {
+		return nil, err
+	}
+	tx.put("UpdateRow", updated)
+	return updated, nil
+}
+
+func (tx *txStorer) UpdateChild(ctx context.Context, childType, childID, newChildLabel, parentType, newParentID string) (storage.Row, error) {
+	updated, err := tx.RowStorer.UpdateChild(ctx, childType, childID, newChildLabel, parentType, newParentID)
+	if err != nil {
+		return nil, err
+	}
+	tx.put("UpdateChild", updated)
+	return updated, nil
+}
+
+func (tx *txStorer) UpdateColumn(ctx context.Context, rowType, rowID, columnName string, columnValue interface{}) error {
+	if err := tx.RowStorer.UpdateColumn(ctx, rowType, rowID, columnName, columnValue); err != nil {
+		return err
+	}
+	tx.queue("UpdateColumn", rowType, rowID, func(ctx context.Context, shadow storage.RowStorer) error {
+		return shadow.UpdateColumn(ctx, rowType, rowID, columnName, columnValue)
+	})
+	return nil
+}
+
+func (tx *txStorer) UpdateColumns(ctx context.Context, rowType, rowID string, columns map[string]interface{}) error {
+	if err := tx.RowStorer.UpdateColumns(ctx, rowType, rowID, columns); err != nil {
+		return err
+	}
+	tx.queue("UpdateColumns", rowType, rowID, func(ctx context.Context, shadow storage.RowStorer) error {
+		return shadow.UpdateColumns(ctx, rowType, rowID, columns)
+	})
+	return nil
+}
+
+func (tx *txStorer) DeleteRow(ctx context.Context, rowType, childType, rowID string) error {
+	if err := tx.RowStorer.DeleteRow(ctx, rowType, childType, rowID); err != nil {
+		return err
+	}
+	tx.queue("DeleteRow", rowType, rowID, func(ctx context.Context, shadow storage.RowStorer) error {
+		return deleteShadow(ctx, shadow, rowType, childType, rowID)
+	})
+	return nil
+}
+
+func (tx *txStorer) PutRow(ctx context.Context, row storage.Row) error {
+	if err := tx.RowStorer.PutRow(ctx, row); err != nil {
+		return err
+	}
+	tx.put("PutRow", row)
+	return nil
+}
+
+func (tx *txStorer) DeleteColumn(ctx context.Context, rowType, rowID, columnName string) error {
+	if err := storage.DeleteColumn(ctx, tx.RowStorer, rowType, rowID, columnName); err != nil {
+		return err
+	}
+	tx.queue("DeleteColumn", rowType, rowID, func(ctx context.Context, shadow storage.RowStorer) error {
+		return storage.DeleteColumn(ctx, shadow, rowType, rowID, columnName)
+	})
+	return nil
+}
@@ -0,0 +1,6 @@
+package storage
+
+// ParentTypes names, by row type, the types a row's parent may have. Rows
+// record only their parent's ID, and rows are identified by type and ID
+// together, so finding a row's parent takes the types it may have.
+type ParentTypes map[string][]string
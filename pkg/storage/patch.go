@@ -0,0 +1,69 @@
+package storage
+
+import "context"
+
+// ColumnPatch sets some of a row's columns, leaving its others as they are.
+type ColumnPatch struct {
+	Type    string
+	ID      string
+	Columns map[string]interface{}
+}
+
+// ColumnPatcher is implemented by storage backends that can patch the columns
+// of many rows in fewer requests than updating them one column at a time, for
+// bulk updates.
+type ColumnPatcher interface {
+	// PatchColumns sets each patch's columns as UpdateColumn does. A
+	// backend may apply the patches in batches, each atomically, but not
+	// all of them at once: after an error, some of them may have been
+	// applied.
+	PatchColumns(ctx context.Context, patches []ColumnPatch) error
+}
+
+// PatchColumns applies the patches, one column at a time if the storer isn't
+// a ColumnPatcher.
+func PatchColumns(ctx context.Context, storer RowStorer, patches []ColumnPatch) error {
+	if patcher, ok := storer.(ColumnPatcher); ok {
+		return patcher.PatchColumns(ctx, patches)
+	}
+	for _, patch := range patches {
+		for name, value := range patch.Columns {
+			if err := storer.UpdateColumn(ctx, patch.Type, patch.ID, name, value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ColumnDeleter is implemented by storage backends that can remove one of a
+// row's columns without writing its others back.
+type ColumnDeleter interface {
+	// DeleteColumn removes the named column from the row with the type and
+	// ID. A column the row doesn't have is already removed, but a missing
+	// row is ErrNotFoundRow.
+	DeleteColumn(ctx context.Context, rowType, rowID, columnName string) error
+}
+
+// DeleteColumn removes the column from the row. If the storer isn't a
+// ColumnDeleter, the row is read and its other columns are written back with
+// UpdateColumns, undoing any change another writer makes to them meanwhile.
+func DeleteColumn(ctx context.Context, storer RowStorer, rowType, rowID, columnName string) error {
+	if deleter, ok := storer.(ColumnDeleter); ok {
+		return deleter.DeleteColumn(ctx, rowType, rowID, columnName)
+	}
+	row, err := storer.GetRowByID(ctx, rowType, rowID)
+	if err != nil {
+		return err
+	}
+	if _, ok := row.Columns()[columnName]; !ok {
+		return nil
+	}
+	columns := make(map[string]interface{}, len(row.Columns()))
+	for name, value := range row.Columns() {
+		if name != columnName {
+			columns[name] = value
+		}
+	}
+	return storer.UpdateColumns(ctx, rowType, rowID, columns)
+}
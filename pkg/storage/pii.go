@@ -0,0 +1,48 @@
+package storage
+
+// Masked stands in for the value of a PII column.
+const Masked = "(masked)"
+
+// PIIColumns names, by row type, the columns that hold personally
+// identifiable information. Their values are masked in logs, exports, and
+// command output, unless revealed explicitly.
+type PIIColumns map[string][]string
+
+// Contains reports whether the row type's column is PII.
+func (pii PIIColumns) Contains(rowType, columnName string) bool {
+	for _, name := range pii[rowType] {
+		if name == columnName {
+			return true
+		}
+	}
+	return false
+}
+
+// Mask returns the row with the values of its PII columns masked, or the row
+// itself if it has none. String sets stay string sets, of one masked value.
+func (pii PIIColumns) Mask(row Row) Row {
+	if row == nil || len(pii[row.Type()]) == 0 {
+		return row
+	}
+	columns := make(map[string]interface{}, len(row.Columns()))
+	for name, value := range row.Columns() {
+		if pii.Contains(row.Type(), name) {
+			if _, ok := value.([]string); ok {
+				value = []string{Masked}
+			} else {
+				value = Masked
+			}
+		}
+		columns[name] = value
+	}
+	return &maskedRow{Row: row, columns: columns}
+}
+
+type maskedRow struct {
+	Row
+	columns map[string]interface{}
+}
+
+func (row *maskedRow) Columns() map[string]interface{} {
+	return row.columns
+}
@@ -0,0 +1,23 @@
+package policy_test
+
+import (
+	"testing"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/memory"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/policy"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/storagetest"
+)
+
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.RowStorer {
+		storer, err := policy.NewStorer(memory.NewClient(), []policy.Rule{
+			{Effect: policy.Allow},
+			{Effect: policy.Deny, RowTypes: []string{"secret"}},
+		}, nil)
+		if err != nil {
+			t.Fatalf("NewStorer: %s", err)
+		}
+		return storer
+	})
+}
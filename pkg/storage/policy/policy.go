@@ -0,0 +1,479 @@
+// Package policy wraps a storage.RowStorer to allow or deny each operation by
+// the type of row, the kind of operation, and the subtree the row is in, so
+// that, for example, one team's CI role can't change another team's branch
+// of the tree.
+package policy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+// The kinds of operation rules match.
+const (
+	OpRead   = "read"
+	OpCreate = "create"
+	OpUpdate = "update"
+	OpDelete = "delete"
+)
+
+// The effects of a rule.
+const (
+	Allow = "allow"
+	Deny  = "deny"
+)
+
+// ErrInvalidRule is returned by NewStorer for a rule it can't apply.
+var ErrInvalidRule = errors.New("invalid policy rule")
+
+// Rule allows or denies the operations it matches. Empty lists match
+// everything.
+type Rule struct {
+	// Effect is Allow or Deny.
+	Effect string
+	// Operations are the kinds of operation the rule matches: OpRead,
+	// OpCreate, OpUpdate, or OpDelete.
+	Operations []string
+	// RowTypes are the types of row the rule matches.
+	RowTypes []string
+	// Subtree, if set, is the ID of a row: the rule matches only that row
+	// and its descendants.
+	Subtree string
+}
+
+func (rule Rule) validate() error {
+	if rule.Effect != Allow && rule.Effect != Deny {
+		return fmt.Errorf("%w: effect must be %q or %q, not %q", ErrInvalidRule, Allow, Deny, rule.Effect)
+	}
+	for _, op := range rule.Operations {
+		switch op {
+		case OpRead, OpCreate, OpUpdate, OpDelete:
+		default:
+			return fmt.Errorf("%w: operation must be %q, %q, %q, or %q, not %q", ErrInvalidRule, OpRead, OpCreate, OpUpdate, OpDelete, op)
+		}
+	}
+	return nil
+}
+
+// matches reports whether the rule matches an operation of kind op on a row
+// of rowType. lineage is the IDs of the row and its ancestors, nearest
+// first; a row that doesn't exist yet has only its ancestors.
+func (rule Rule) matches(op, rowType string, lineage []string) bool {
+	if len(rule.Operations) > 0 && !contains(rule.Operations, op) {
+		return false
+	}
+	if len(rule.RowTypes) > 0 && !contains(rule.RowTypes, rowType) {
+		return false
+	}
+	if rule.Subtree != "" && !contains(lineage, rule.Subtree) {
+		return false
+	}
+	return true
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Storer is a storage.RowStorer that refuses the operations its rules don't
+// allow. An operation is allowed if some rule allows it and no rule denies
+// it. Refused operations return an error wrapping
+// storage.ErrPermissionDenied, and reads of many rows leave out the rows
+// that may not be read.
+type Storer struct {
+	next    storage.RowStorer
+	rules   []Rule
+	parents storage.ParentTypes
+	// subtrees is whether any rule has a subtree, so that rows' ancestors
+	// are only looked up when they matter
+	subtrees bool
+}
+
+var (
+	_ storage.RowStorer     = &Storer{}
+	_ storage.Transactor    = &Storer{}
+	_ storage.ColumnDeleter = &Storer{}
+	_ storage.BatchGetter   = &Storer{}
+	_ storage.ColumnQuerier = &Storer{}
+	_ storage.ChildScanner  = &Storer{}
+	_ storage.BatchPutter   = &Storer{}
+	_ storage.ColumnPatcher = &Storer{}
+	_ storage.RowIterator   = &Storer{}
+)
+
+// NewStorer wraps next, applying rules to every operation. Rules with
+// subtrees find rows' ancestors by parents, the types each row type's
+// parents may have.
+func NewStorer(next storage.RowStorer, rules []Rule, parents storage.ParentTypes) (*Storer, error) {
+	client := &Storer{next: next, rules: rules, parents: parents}
+	for i, rule := range rules {
+		if err := rule.validate(); err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i, err)
+		}
+		if rule.Subtree != "" {
+			client.subtrees = true
+		}
+	}
+	return client, nil
+}
+
+// Unwrap returns the wrapped storer.
+func (client *Storer) Unwrap() storage.RowStorer {
+	return client.next
+}
+
+// allowed reports whether the rules allow an operation of kind op on a row
+// of rowType, with the lineage given.
+func (client *Storer) allowed(op, rowType string, lineage []string) bool {
+	allowed := false
+	for _, rule := range client.rules {
+		if !rule.matches(op, rowType, lineage) {
+			continue
+		}
+		if rule.Effect == Deny {
+			return false
+		}
+		allowed = true
+	}
+	return allowed
+}
+
+// check returns an error wrapping storage.ErrPermissionDenied if the rules
+// don't allow the operation. rowID may be empty, for a row yet to be
+// created.
+func (client *Storer) check(ctx context.Context, op, rowType, rowID string, lineage []string) error {
+	if client.allowed(op, rowType, lineage) {
+		return nil
+	}
+	tflog.SubsystemWarn(ctx, storage.LogSubsystemStorage, fmt.Sprintf("Policy denied %s of %s %q", op, rowType, rowID), map[string]interface{}{
+		"operation": op,
+		"type":      rowType,
+		"id":        rowID,
+		"lineage":   lineage,
+	})
+	if rowID == "" {
+		return fmt.Errorf("%w: the policy doesn't allow %s of %s rows here", storage.ErrPermissionDenied, op, rowType)
+	}
+	return fmt.Errorf("%w: the policy doesn't allow %s of %s %q", storage.ErrPermissionDenied, op, rowType, rowID)
+}
+
+// checkRow checks an operation on a row that has been read.
+func (client *Storer) checkRow(ctx context.Context, op string, row storage.Row) error {
+	ancestors, err := client.ancestors(ctx, client.parents[row.Type()], row.ParentID())
+	if err != nil {
+		return err
+	}
+	return client.check(ctx, op, row.Type(), row.ID(), append([]string{row.ID()}, ancestors...))
+}
+
+// readable reports whether a row that has been read may be. Unlike the
+// checks, it doesn't log, since reads of many rows leave out those that
+// aren't readable as a matter of course. A row whose ancestors can't be
+// found isn't readable.
+func (client *Storer) readable(ctx context.Context, row storage.Row) (bool, error) {
+	ancestors, err := client.ancestors(ctx, client.parents[row.Type()], row.ParentID())
+	if errors.Is(err, storage.ErrPermissionDenied) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return client.allowed(OpRead, row.Type(), append([]string{row.ID()}, ancestors...)), nil
+}
+
+// readableRows returns the rows the policy allows reading, leaving out the
+// rest.
+func (client *Storer) readableRows(ctx context.Context, rows []storage.Row) ([]storage.Row, error) {
+	allowed := make([]storage.Row, 0, len(rows))
+	for _, row := range rows {
+		ok, err := client.readable(ctx, row)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		allowed = append(allowed, row)
+	}
+	return allowed, nil
+}
+
+// checkStored checks an operation on the stored row with rowType and rowID.
+// A row that doesn't exist is checked on its own, and left for the wrapped
+// storer to report missing.
+func (client *Storer) checkStored(ctx context.Context, op, rowType, rowID string) error {
+	if !client.subtrees {
+		return client.check(ctx, op, rowType, rowID, []string{rowID})
+	}
+	row, err := client.next.GetRowByID(ctx, rowType, rowID)
+	if errors.Is(err, storage.ErrNotFoundRow) {
+		return client.check(ctx, op, rowType, rowID, []string{rowID})
+	}
+	if err != nil {
+		return err
+	}
+	return client.checkRow(ctx, op, row)
+}
+
+// ancestors returns the IDs of the row with parentID, whose type is one of
+// parentTypes, and of its ancestors, nearest first. Each parent is looked up
+// by the types its child's type may have a parent of. A parent that can't be
+// found under any of them leaves the row's subtree unknown, so the walk
+// fails with an error wrapping storage.ErrPermissionDenied.
+func (client *Storer) ancestors(ctx context.Context, parentTypes []string, parentID string) ([]string, error) {
+	ids := []string{}
+	if !client.subtrees {
+		return ids, nil
+	}
+	// a visited set keeps a parent cycle from looping forever
+	visited := map[string]bool{}
+	for parentID != "" && !visited[parentID] {
+		visited[parentID] = true
+		ids = append(ids, parentID)
+		parent, err := client.parent(ctx, parentTypes, parentID)
+		if err != nil {
+			return nil, err
+		}
+		parentTypes = client.parents[parent.Type()]
+		parentID = parent.ParentID()
+	}
+	return ids, nil
+}
+
+// parent returns the row with parentID, trying each of parentTypes.
+func (client *Storer) parent(ctx context.Context, parentTypes []string, parentID string) (storage.Row, error) {
+	for _, parentType := range parentTypes {
+		parent, err := client.next.GetRowByID(ctx, parentType, parentID)
+		if errors.Is(err, storage.ErrNotFoundRow) {
+			continue
+		}
+		return parent, err
+	}
+	tflog.SubsystemWarn(ctx, storage.LogSubsystemStorage, fmt.Sprintf("Policy can't find parent %q", parentID), map[string]interface{}{
+		"id":    parentID,
+		"types": parentTypes,
+	})
+	return nil, fmt.Errorf("%w: the policy can't place the row in the tree: no %s has ID %q", storage.ErrPermissionDenied, strings.Join(parentTypes, " or "), parentID)
+}
+
+func (client *Storer) GetRowByID(ctx context.Context, rowType, rowID string) (storage.Row, error) {
+	row, err := client.next.GetRowByID(ctx, rowType, rowID)
+	if err != nil {
+		return nil, err
+	}
+	if err := client.checkRow(ctx, OpRead, row); err != nil {
+		return nil, err
+	}
+	return row, nil
+}
+
+// GetRowsByIDs leaves out the rows the policy doesn't allow reading, as if
+// they didn't exist.
+func (client *Storer) GetRowsByIDs(ctx context.Context, rowType string, ids []string) ([]storage.Row, error) {
+	rows, err := storage.GetRowsByIDs(ctx, client.next, rowType, ids)
+	if err != nil {
+		return nil, err
+	}
+	return client.readableRows(ctx, rows)
+}
+
+func (client *Storer) GetRow(ctx context.Context, rowType, rowLabel string) (storage.Row, error) {
+	row, err := client.next.GetRow(ctx, rowType, rowLabel)
+	if err != nil {
+		return nil, err
+	}
+	if err := client.checkRow(ctx, OpRead, row); err != nil {
+		return nil, err
+	}
+	return row, nil
+}
+
+func (client *Storer) CreateRow(ctx context.Context, rowType, rowLabel string) (storage.Row, error) {
+	// a new root row is in no existing subtree
+	if err := client.check(ctx, OpCreate, rowType, "", nil); err != nil {
+		return nil, err
+	}
+	return client.next.CreateRow(ctx, rowType, rowLabel)
+}
+
+func (client *Storer) CreateChild(ctx context.Context, rowType, rowLabel, parentType, parentID string, columns map[string]interface{}) (storage.Row, error) {
+	ancestors, err := client.ancestors(ctx, []string{parentType}, parentID)
+	if err != nil {
+		return nil, err
+	}
+	if err := client.check(ctx, OpCreate, rowType, "", ancestors); err != nil {
+		return nil, err
+	}
+	return client.next.CreateChild(ctx, rowType, rowLabel, parentType, parentID, columns)
+}
+
+func (client *Storer) GetChild(ctx context.Context, childLabel, parentID string) (storage.Row, error) {
+	row, err := client.next.GetChild(ctx, childLabel, parentID)
+	if err != nil {
+		return nil, err
+	}
+	if err := client.checkRow(ctx, OpRead, row); err != nil {
+		return nil, err
+	}
+	return row, nil
+}
+
+func (client *Storer) ListRows(ctx context.Context, rowType, labelFilter, parentIDFilter string) ([]storage.Row, error) {
+	rows, err := client.next.ListRows(ctx, rowType, labelFilter, parentIDFilter)
+	if err != nil {
+		return nil, err
+	}
+	return client.readableRows(ctx, rows)
+}
+
+// IterRows leaves out the rows that may not be read, page by page.
+func (client *Storer) IterRows(ctx context.Context, rowType, labelFilter, parentIDFilter string) *storage.Iter[storage.Row] {
+	return storage.WrapPages(storage.IterRows(ctx, client.next, rowType, labelFilter, parentIDFilter), func(ctx context.Context, fetch storage.PageFunc[storage.Row]) ([]storage.Row, bool, error) {
+		page, more, err := fetch(ctx)
+		if err != nil {
+			return nil, false, err
+		}
+		page, err = client.readableRows(ctx, page)
+		return page, more, err
+	})
+}
+
+func (client *Storer) QueryByColumn(ctx context.Context, rowType, columnName string, value interface{}) ([]storage.Row, error) {
+	rows, err := storage.QueryByColumn(ctx, client.next, rowType, columnName, value)
+	if err != nil {
+		return nil, err
+	}
+	return client.readableRows(ctx, rows)
+}
+
+func (client *Storer) UpdateRow(ctx context.Context, rowType, rowID, newLabel string) (storage.Row, error) {
+	if err := client.checkStored(ctx, OpUpdate, rowType, rowID); err != nil {
+		return nil, err
+	}
+	return client.next.UpdateRow(ctx, rowType, rowID, newLabel)
+}
+
+// UpdateChild checks the child where it is, and where it would move to, so
+// that a row can't be moved out of or into a subtree the policy protects.
+func (client *Storer) UpdateChild(ctx context.Context, childType, childID, newChildLabel, parentType, newParentID string) (storage.Row, error) {
+	if err := client.checkStored(ctx, OpUpdate, childType, childID); err != nil {
+		return nil, err
+	}
+	ancestors, err := client.ancestors(ctx, []string{parentType}, newParentID)
+	if err != nil {
+		return nil, err
+	}
+	if err := client.check(ctx, OpUpdate, childType, childID, append([]string{childID}, ancestors...)); err != nil {
+		return nil, err
+	}
+	return client.next.UpdateChild(ctx, childType, childID, newChildLabel, parentType, newParentID)
+}
+
+func (client *Storer) UpdateColumn(ctx context.Context, rowType, rowID, columnName string, columnValue interface{}) error {
+	if err := client.checkStored(ctx, OpUpdate, rowType, rowID); err != nil {
+		return err
+	}
+	return client.next.UpdateColumn(ctx, rowType, rowID, columnName, columnValue)
+}
+
+// PatchColumns checks every patched row before applying any of the patches.
+func (client *Storer) PatchColumns(ctx context.Context, patches []storage.ColumnPatch) error {
+	for _, patch := range patches {
+		if err := client.checkStored(ctx, OpUpdate, patch.Type, patch.ID); err != nil {
+			return err
+		}
+	}
+	return storage.PatchColumns(ctx, client.next, patches)
+}
+
+func (client *Storer) UpdateColumns(ctx context.Context, rowType, rowID string, columns map[string]interface{}) error {
+	if err := client.checkStored(ctx, OpUpdate, rowType, rowID); err != nil {
+		return err
+	}
+	return client.next.UpdateColumns(ctx, rowType, rowID, columns)
+}
+
+func (client *Storer) DeleteRow(ctx context.Context, rowType, childType, rowID string) error {
+	if err := client.checkStored(ctx, OpDelete, rowType, rowID); err != nil {
+		return err
+	}
+	return client.next.DeleteRow(ctx, rowType, childType, rowID)
+}
+
+// PutRow is an update if the row exists, and a create if it doesn't. The row
+// is checked where it would be put, and, if it exists, where it is.
+func (client *Storer) PutRow(ctx context.Context, row storage.Row) error {
+	if err := client.checkPut(ctx, row); err != nil {
+		return err
+	}
+	return client.next.PutRow(ctx, row)
+}
+
+// PutRows checks every row as PutRow does before putting any of them.
+func (client *Storer) PutRows(ctx context.Context, rows []storage.Row) error {
+	for _, row := range rows {
+		if err := client.checkPut(ctx, row); err != nil {
+			return err
+		}
+	}
+	return storage.PutRows(ctx, client.next, rows)
+}
+
+func (client *Storer) checkPut(ctx context.Context, row storage.Row) error {
+	op := OpUpdate
+	_, err := client.next.GetRowByID(ctx, row.Type(), row.ID())
+	if errors.Is(err, storage.ErrNotFoundRow) {
+		op = OpCreate
+	} else if err != nil {
+		return err
+	} else if err := client.checkStored(ctx, op, row.Type(), row.ID()); err != nil {
+		return err
+	}
+	return client.checkRow(ctx, op, row)
+}
+
+// ScanRows leaves out the rows that may not be read.
+func (client *Storer) ScanRows(ctx context.Context, fn func(storage.Row) error) error {
+	return client.next.ScanRows(ctx, func(row storage.Row) error {
+		ok, err := client.readable(ctx, row)
+		if err != nil || !ok {
+			return err
+		}
+		return fn(row)
+	})
+}
+
+// ScanChildren leaves out the children that may not be read.
+func (client *Storer) ScanChildren(ctx context.Context, parentID string, fn func(storage.Row) error) error {
+	return storage.ScanChildren(ctx, client.next, parentID, func(row storage.Row) error {
+		ok, err := client.readable(ctx, row)
+		if err != nil || !ok {
+			return err
+		}
+		return fn(row)
+	})
+}
+
+func (client *Storer) DeleteColumn(ctx context.Context, rowType, rowID, columnName string) error {
+	if err := client.checkStored(ctx, OpUpdate, rowType, rowID); err != nil {
+		return err
+	}
+	return storage.DeleteColumn(ctx, client.next, rowType, rowID, columnName)
+}
+
+// WithinTx applies the rules to the transaction's operations, too.
+func (client *Storer) WithinTx(ctx context.Context, fn func(tx storage.RowStorer) error) error {
+	return storage.WithinTx(ctx, client.next, func(tx storage.RowStorer) error {
+		return fn(&Storer{next: tx, rules: client.rules, parents: client.parents, subtrees: client.subtrees})
+	})
+}
@@ -0,0 +1,194 @@
+package policy_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/memory"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/policy"
+)
+
+var parents = storage.ParentTypes{
+	"team":        {"org"},
+	"environment": {"team"},
+}
+
+// tree stores an org with two teams, and an environment under the first.
+func tree(t *testing.T) (backend storage.RowStorer, org, team, other, env storage.Row) {
+	t.Helper()
+	ctx := context.Background()
+	backend = memory.NewClient()
+	var err error
+	if org, err = backend.CreateRow(ctx, "org", "acme"); err != nil {
+		t.Fatalf("CreateRow: %s", err)
+	}
+	if team, err = backend.CreateChild(ctx, "team", "web", "org", org.ID(), nil); err != nil {
+		t.Fatalf("CreateChild: %s", err)
+	}
+	if other, err = backend.CreateChild(ctx, "team", "data", "org", org.ID(), nil); err != nil {
+		t.Fatalf("CreateChild: %s", err)
+	}
+	if env, err = backend.CreateChild(ctx, "environment", "prod", "team", team.ID(), nil); err != nil {
+		t.Fatalf("CreateChild: %s", err)
+	}
+	return backend, org, team, other, env
+}
+
+func TestNewStorerInvalidRule(t *testing.T) {
+	rules := [][]policy.Rule{
+		{{Effect: "maybe"}},
+		{{Effect: policy.Allow, Operations: []string{"scan"}}},
+	}
+	for _, rules := range rules {
+		if _, err := policy.NewStorer(memory.NewClient(), rules, nil); !errors.Is(err, policy.ErrInvalidRule) {
+			t.Errorf("NewStorer(%v) failed with %v, not %q", rules, err, policy.ErrInvalidRule)
+		}
+	}
+}
+
+func TestDenyWins(t *testing.T) {
+	ctx := context.Background()
+	backend, org, _, _, _ := tree(t)
+	storer, err := policy.NewStorer(backend, []policy.Rule{
+		{Effect: policy.Allow},
+		{Effect: policy.Deny, Operations: []string{policy.OpDelete}, RowTypes: []string{"org"}},
+	}, parents)
+	if err != nil {
+		t.Fatalf("NewStorer: %s", err)
+	}
+	if _, err := storer.GetRowByID(ctx, "org", org.ID()); err != nil {
+		t.Errorf("GetRowByID: %s", err)
+	}
+	if err := storer.DeleteRow(ctx, "org", "", org.ID()); !errors.Is(err, storage.ErrPermissionDenied) {
+		t.Errorf("DeleteRow failed with %v, not %q", err, storage.ErrPermissionDenied)
+	}
+	if _, err := backend.GetRowByID(ctx, "org", org.ID()); err != nil {
+		t.Errorf("the denied delete deleted the row: %s", err)
+	}
+}
+
+func TestNoRuleDenies(t *testing.T) {
+	storer, err := policy.NewStorer(memory.NewClient(), []policy.Rule{
+		{Effect: policy.Allow, Operations: []string{policy.OpRead}},
+	}, parents)
+	if err != nil {
+		t.Fatalf("NewStorer: %s", err)
+	}
+	if _, err := storer.CreateRow(context.Background(), "org", "acme"); !errors.Is(err, storage.ErrPermissionDenied) {
+		t.Errorf("CreateRow failed with %v, not %q", err, storage.ErrPermissionDenied)
+	}
+}
+
+func TestSubtree(t *testing.T) {
+	ctx := context.Background()
+	backend, org, team, other, env := tree(t)
+	storer, err := policy.NewStorer(backend, []policy.Rule{
+		{Effect: policy.Allow, Operations: []string{policy.OpRead}, RowTypes: []string{"org"}},
+		{Effect: policy.Allow, Subtree: team.ID()},
+	}, parents)
+	if err != nil {
+		t.Fatalf("NewStorer: %s", err)
+	}
+
+	if _, err := storer.GetRowByID(ctx, "environment", env.ID()); err != nil {
+		t.Errorf("GetRowByID of a grandchild of the subtree: %s", err)
+	}
+	if err := storer.UpdateColumn(ctx, "team", team.ID(), "owner", "amy"); err != nil {
+		t.Errorf("UpdateColumn of the subtree's root: %s", err)
+	}
+	if _, err := storer.CreateChild(ctx, "environment", "dev", "team", team.ID(), nil); err != nil {
+		t.Errorf("CreateChild in the subtree: %s", err)
+	}
+	if _, err := storer.CreateChild(ctx, "environment", "dev", "team", other.ID(), nil); !errors.Is(err, storage.ErrPermissionDenied) {
+		t.Errorf("CreateChild outside the subtree failed with %v, not %q", err, storage.ErrPermissionDenied)
+	}
+	if _, err := storer.UpdateChild(ctx, "environment", env.ID(), "prod", "team", other.ID()); !errors.Is(err, storage.ErrPermissionDenied) {
+		t.Errorf("UpdateChild out of the subtree failed with %v, not %q", err, storage.ErrPermissionDenied)
+	}
+	if _, err := storer.GetRowByID(ctx, "team", other.ID()); !errors.Is(err, storage.ErrPermissionDenied) {
+		t.Errorf("GetRowByID outside the subtree failed with %v, not %q", err, storage.ErrPermissionDenied)
+	}
+
+	teams, err := storer.ListRows(ctx, "team", "", org.ID())
+	if err != nil {
+		t.Fatalf("ListRows: %s", err)
+	}
+	if len(teams) != 1 || teams[0].ID() != team.ID() {
+		t.Errorf("ListRows returned %d teams, not only %q", len(teams), team.ID())
+	}
+}
+
+// TestUnresolvedAncestry checks that a row whose parent can't be found is
+// denied, rather than checked as if it were a root.
+func TestUnresolvedAncestry(t *testing.T) {
+	ctx := context.Background()
+	backend, org, team, _, env := tree(t)
+	orphan := &row{RowType: "environment", RowID: "environment_orphan", RowLabel: "orphan", RowParentID: "team_gone"}
+	if err := backend.PutRow(ctx, orphan); err != nil {
+		t.Fatalf("PutRow: %s", err)
+	}
+	storer, err := policy.NewStorer(backend, []policy.Rule{
+		{Effect: policy.Allow},
+		{Effect: policy.Deny, Subtree: org.ID()},
+	}, parents)
+	if err != nil {
+		t.Fatalf("NewStorer: %s", err)
+	}
+
+	if _, err := storer.GetRowByID(ctx, "environment", env.ID()); !errors.Is(err, storage.ErrPermissionDenied) {
+		t.Errorf("GetRowByID in the denied subtree failed with %v, not %q", err, storage.ErrPermissionDenied)
+	}
+	if _, err := storer.GetRowByID(ctx, "environment", orphan.ID()); !errors.Is(err, storage.ErrPermissionDenied) {
+		t.Errorf("GetRowByID of an orphan failed with %v, not %q", err, storage.ErrPermissionDenied)
+	}
+	if err := storer.DeleteRow(ctx, "environment", "", orphan.ID()); !errors.Is(err, storage.ErrPermissionDenied) {
+		t.Errorf("DeleteRow of an orphan failed with %v, not %q", err, storage.ErrPermissionDenied)
+	}
+	rows, err := storer.ListRows(ctx, "environment", "", "")
+	if err != nil {
+		t.Fatalf("ListRows: %s", err)
+	}
+	if len(rows) != 0 {
+		t.Errorf("ListRows returned %d environments, not leaving out the orphan and the denied subtree", len(rows))
+	}
+
+	// without the parent types, not even the team's parent can be found
+	storer, err = policy.NewStorer(backend, []policy.Rule{{Effect: policy.Allow, Subtree: org.ID()}}, nil)
+	if err != nil {
+		t.Fatalf("NewStorer: %s", err)
+	}
+	if _, err := storer.GetRowByID(ctx, "team", team.ID()); !errors.Is(err, storage.ErrPermissionDenied) {
+		t.Errorf("GetRowByID without parent types failed with %v, not %q", err, storage.ErrPermissionDenied)
+	}
+}
+
+func TestWithinTx(t *testing.T) {
+	ctx := context.Background()
+	backend, _, team, other, _ := tree(t)
+	storer, err := policy.NewStorer(backend, []policy.Rule{{Effect: policy.Allow, Subtree: team.ID()}}, parents)
+	if err != nil {
+		t.Fatalf("NewStorer: %s", err)
+	}
+	err = storage.WithinTx(ctx, storer, func(tx storage.RowStorer) error {
+		return tx.UpdateColumn(ctx, "team", other.ID(), "owner", "amy")
+	})
+	if !errors.Is(err, storage.ErrPermissionDenied) {
+		t.Errorf("UpdateColumn in a transaction failed with %v, not %q", err, storage.ErrPermissionDenied)
+	}
+}
+
+type row struct {
+	RowType     string
+	RowID       string
+	RowLabel    string
+	RowParentID string
+	RowColumns  map[string]interface{}
+}
+
+func (r *row) Type() string                    { return r.RowType }
+func (r *row) ID() string                      { return r.RowID }
+func (r *row) Label() string                   { return r.RowLabel }
+func (r *row) ParentID() string                { return r.RowParentID }
+func (r *row) Columns() map[string]interface{} { return r.RowColumns }
@@ -0,0 +1,54 @@
+package postgres
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+// uniqueViolation is the SQLSTATE of a write breaking a unique index.
+const uniqueViolation = "23505"
+
+// errorKinds maps PostgreSQL's SQLSTATE codes to the kinds of storage error.
+var errorKinds = map[string]error{
+	"40001": storage.ErrConflict,         // serialization_failure
+	"40P01": storage.ErrConflict,         // deadlock_detected
+	"55P03": storage.ErrConflict,         // lock_not_available
+	"53300": storage.ErrThrottled,        // too_many_connections
+	"53400": storage.ErrThrottled,        // configuration_limit_exceeded
+	"28000": storage.ErrPermissionDenied, // invalid_authorization_specification
+	"28P01": storage.ErrPermissionDenied, // invalid_password
+	"42501": storage.ErrPermissionDenied, // insufficient_privilege
+	"25006": storage.ErrReadOnly,         // read_only_sql_transaction
+	"54000": storage.ErrInvalid,          // program_limit_exceeded
+}
+
+// kindError wraps a PostgreSQL error with its kind, so that callers can tell
+// errors apart with errors.Is. A write breaking a unique index of labels is a
+// collision, and data exceptions, class 22, are invalid requests. The
+// PostgreSQL error stays in the chain, for errors.As.
+func (client *Client) kindError(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+	if pgErr.Code == uniqueViolation {
+		switch pgErr.ConstraintName {
+		case client.typeLabelIndex:
+			return fmt.Errorf("%w: %w", storage.ErrCollisionTypeLabel, err)
+		case client.parentLabelIndex:
+			return fmt.Errorf("%w: %w", storage.ErrCollisionParentLabel, err)
+		}
+		return fmt.Errorf("%w: %w", storage.ErrConflict, err)
+	}
+	if kind, ok := errorKinds[pgErr.Code]; ok {
+		return fmt.Errorf("%w: %w", kind, err)
+	}
+	if strings.HasPrefix(pgErr.Code, "22") {
+		return fmt.Errorf("%w: %w", storage.ErrInvalid, err)
+	}
+	return err
+}
@@ -0,0 +1,212 @@
+// Package profiling wraps a storage.RowStorer to label the work of each
+// operation for pprof, so that CPU and goroutine profiles of a provider or
+// tool attribute samples to the storage operation and row type they were
+// taken in, including in goroutines the backend starts.
+package profiling
+
+import (
+	"context"
+	"runtime/pprof"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+// The pprof labels of a storage operation. Filter a profile by them with
+// pprof's -tagfocus, as in -tagfocus=storage_operation=ListRows.
+const (
+	LabelOperation = "storage_operation"
+	LabelRowType   = "row_type"
+)
+
+// Storer is a storage.RowStorer that runs each operation of the one it wraps
+// with pprof labels naming it. Labels never include IDs, labels, or column
+// values, which would make profiles too fine-grained to read.
+type Storer struct {
+	next storage.RowStorer
+}
+
+var (
+	_ storage.RowStorer     = &Storer{}
+	_ storage.Transactor    = &Storer{}
+	_ storage.ColumnDeleter = &Storer{}
+	_ storage.BatchGetter   = &Storer{}
+	_ storage.ColumnQuerier = &Storer{}
+	_ storage.ChildScanner  = &Storer{}
+	_ storage.BatchPutter   = &Storer{}
+	_ storage.ColumnPatcher = &Storer{}
+	_ storage.RowIterator   = &Storer{}
+)
+
+func NewStorer(next storage.RowStorer) *Storer {
+	return &Storer{next: next}
+}
+
+// Unwrap returns the wrapped storer.
+func (client *Storer) Unwrap() storage.RowStorer {
+	return client.next
+}
+
+// do runs f with the operation's labels, added to any the context has.
+// Operations that aren't of one row type leave the row type out.
+func do(ctx context.Context, op, rowType string, f func(context.Context)) {
+	labels := pprof.Labels(LabelOperation, op)
+	if rowType != "" {
+		labels = pprof.Labels(LabelOperation, op, LabelRowType, rowType)
+	}
+	pprof.Do(ctx, labels, f)
+}
+
+func (client *Storer) GetRowByID(ctx context.Context, rowType, rowID string) (row storage.Row, err error) {
+	do(ctx, "GetRowByID", rowType, func(ctx context.Context) {
+		row, err = client.next.GetRowByID(ctx, rowType, rowID)
+	})
+	return row, err
+}
+
+func (client *Storer) GetRowsByIDs(ctx context.Context, rowType string, ids []string) (rows []storage.Row, err error) {
+	do(ctx, "GetRowsByIDs", rowType, func(ctx context.Context) {
+		rows, err = storage.GetRowsByIDs(ctx, client.next, rowType, ids)
+	})
+	return rows, err
+}
+
+func (client *Storer) GetRow(ctx context.Context, rowType, rowLabel string) (row storage.Row, err error) {
+	do(ctx, "GetRow", rowType, func(ctx context.Context) {
+		row, err = client.next.GetRow(ctx, rowType, rowLabel)
+	})
+	return row, err
+}
+
+func (client *Storer) CreateRow(ctx context.Context, rowType, rowLabel string) (row storage.Row, err error) {
+	do(ctx, "CreateRow", rowType, func(ctx context.Context) {
+		row, err = client.next.CreateRow(ctx, rowType, rowLabel)
+	})
+	return row, err
+}
+
+func (client *Storer) CreateChild(ctx context.Context, rowType, rowLabel, parentType, parentID string, columns map[string]interface{}) (row storage.Row, err error) {
+	do(ctx, "CreateChild", rowType, func(ctx context.Context) {
+		row, err = client.next.CreateChild(ctx, rowType, rowLabel, parentType, parentID, columns)
+	})
+	return row, err
+}
+
+// GetChild's row type isn't known until the child is found.
+func (client *Storer) GetChild(ctx context.Context, childLabel, parentID string) (row storage.Row, err error) {
+	do(ctx, "GetChild", "", func(ctx context.Context) {
+		row, err = client.next.GetChild(ctx, childLabel, parentID)
+	})
+	return row, err
+}
+
+func (client *Storer) ListRows(ctx context.Context, rowType, labelFilter, parentIDFilter string) (rows []storage.Row, err error) {
+	do(ctx, "ListRows", rowType, func(ctx context.Context) {
+		rows, err = client.next.ListRows(ctx, rowType, labelFilter, parentIDFilter)
+	})
+	return rows, err
+}
+
+func (client *Storer) IterRows(ctx context.Context, rowType, labelFilter, parentIDFilter string) *storage.Iter[storage.Row] {
+	return storage.WrapPages(storage.IterRows(ctx, client.next, rowType, labelFilter, parentIDFilter), func(ctx context.Context, fetch storage.PageFunc[storage.Row]) (page []storage.Row, more bool, err error) {
+		do(ctx, "IterRows", rowType, func(ctx context.Context) {
+			page, more, err = fetch(ctx)
+		})
+		return page, more, err
+	})
+}
+
+func (client *Storer) QueryByColumn(ctx context.Context, rowType, columnName string, value interface{}) (rows []storage.Row, err error) {
+	do(ctx, "QueryByColumn", rowType, func(ctx context.Context) {
+		rows, err = storage.QueryByColumn(ctx, client.next, rowType, columnName, value)
+	})
+	return rows, err
+}
+
+func (client *Storer) UpdateRow(ctx context.Context, rowType, rowID, newLabel string) (row storage.Row, err error) {
+	do(ctx, "UpdateRow", rowType, func(ctx context.Context) {
+		row, err = client.next.UpdateRow(ctx, rowType, rowID, newLabel)
+	})
+	return row, err
+}
+
+func (client *Storer) UpdateChild(ctx context.Context, childType, childID, newChildLabel, parentType, newParentID string) (row storage.Row, err error) {
+	do(ctx, "UpdateChild", childType, func(ctx context.Context) {
+		row, err = client.next.UpdateChild(ctx, childType, childID, newChildLabel, parentType, newParentID)
+	})
+	return row, err
+}
+
+func (client *Storer) UpdateColumn(ctx context.Context, rowType, rowID, columnName string, columnValue interface{}) (err error) {
+	do(ctx, "UpdateColumn", rowType, func(ctx context.Context) {
+		err = client.next.UpdateColumn(ctx, rowType, rowID, columnName, columnValue)
+	})
+	return err
+}
+
+func (client *Storer) PatchColumns(ctx context.Context, patches []storage.ColumnPatch) (err error) {
+	do(ctx, "PatchColumns", "", func(ctx context.Context) {
+		err = storage.PatchColumns(ctx, client.next, patches)
+	})
+	return err
+}
+
+func (client *Storer) UpdateColumns(ctx context.Context, rowType, rowID string, columns map[string]interface{}) (err error) {
+	do(ctx, "UpdateColumns", rowType, func(ctx context.Context) {
+		err = client.next.UpdateColumns(ctx, rowType, rowID, columns)
+	})
+	return err
+}
+
+func (client *Storer) DeleteRow(ctx context.Context, rowType, childType, rowID string) (err error) {
+	do(ctx, "DeleteRow", rowType, func(ctx context.Context) {
+		err = client.next.DeleteRow(ctx, rowType, childType, rowID)
+	})
+	return err
+}
+
+func (client *Storer) PutRow(ctx context.Context, row storage.Row) (err error) {
+	do(ctx, "PutRow", row.Type(), func(ctx context.Context) {
+		err = client.next.PutRow(ctx, row)
+	})
+	return err
+}
+
+func (client *Storer) PutRows(ctx context.Context, rows []storage.Row) (err error) {
+	do(ctx, "PutRows", "", func(ctx context.Context) {
+		err = storage.PutRows(ctx, client.next, rows)
+	})
+	return err
+}
+
+// ScanRows labels fn's work too, as it runs within the scan.
+func (client *Storer) ScanRows(ctx context.Context, fn func(storage.Row) error) (err error) {
+	do(ctx, "ScanRows", "", func(ctx context.Context) {
+		err = client.next.ScanRows(ctx, fn)
+	})
+	return err
+}
+
+// ScanChildren labels fn's work too, as ScanRows does.
+func (client *Storer) ScanChildren(ctx context.Context, parentID string, fn func(storage.Row) error) (err error) {
+	do(ctx, "ScanChildren", "", func(ctx context.Context) {
+		err = storage.ScanChildren(ctx, client.next, parentID, fn)
+	})
+	return err
+}
+
+func (client *Storer) DeleteColumn(ctx context.Context, rowType, rowID, columnName string) (err error) {
+	do(ctx, "DeleteColumn", rowType, func(ctx context.Context) {
+		err = storage.DeleteColumn(ctx, client.next, rowType, rowID, columnName)
+	})
+	return err
+}
+
+// WithinTx labels the transaction, and each of its operations.
+func (client *Storer) WithinTx(ctx context.Context, fn func(tx storage.RowStorer) error) (err error) {
+	do(ctx, "WithinTx", "", func(ctx context.Context) {
+		err = storage.WithinTx(ctx, client.next, func(tx storage.RowStorer) error {
+			return fn(NewStorer(tx))
+		})
+	})
+	return err
+}
@@ -0,0 +1,101 @@
+package profiling_test
+
+import (
+	"context"
+	"runtime/pprof"
+	"testing"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/memory"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/profiling"
+)
+
+// labeled keeps the pprof labels of the last read it was asked for.
+type labeled struct {
+	storage.RowStorer
+	labels map[string]string
+}
+
+func (client *labeled) keep(ctx context.Context) {
+	client.labels = map[string]string{}
+	pprof.ForLabels(ctx, func(key, value string) bool {
+		client.labels[key] = value
+		return true
+	})
+}
+
+func (client *labeled) GetRowByID(ctx context.Context, rowType, rowID string) (storage.Row, error) {
+	client.keep(ctx)
+	return client.RowStorer.GetRowByID(ctx, rowType, rowID)
+}
+
+func (client *labeled) GetChild(ctx context.Context, childLabel, parentID string) (storage.Row, error) {
+	client.keep(ctx)
+	return client.RowStorer.GetChild(ctx, childLabel, parentID)
+}
+
+func (client *labeled) IterRows(ctx context.Context, rowType, labelFilter, parentIDFilter string) *storage.Iter[storage.Row] {
+	return storage.NewIter(ctx, func(ctx context.Context) ([]storage.Row, bool, error) {
+		client.keep(ctx)
+		rows, err := client.RowStorer.ListRows(ctx, rowType, labelFilter, parentIDFilter)
+		return rows, false, err
+	})
+}
+
+func TestLabels(t *testing.T) {
+	backend := &labeled{RowStorer: memory.NewClient()}
+	storer := profiling.NewStorer(backend)
+	org, err := backend.CreateRow(context.Background(), "org", "acme")
+	if err != nil {
+		t.Fatalf("CreateRow: %s", err)
+	}
+	// the caller's own labels are kept
+	ctx := pprof.WithLabels(context.Background(), pprof.Labels("tool", "schemactl"))
+
+	tests := []struct {
+		name string
+		read func() error
+		want map[string]string
+	}{
+		{
+			name: "GetRowByID",
+			read: func() error {
+				_, err := storer.GetRowByID(ctx, "org", org.ID())
+				return err
+			},
+			want: map[string]string{"tool": "schemactl", profiling.LabelOperation: "GetRowByID", profiling.LabelRowType: "org"},
+		},
+		{
+			// the child's type isn't known
+			name: "GetChild",
+			read: func() error {
+				_, err := storer.GetChild(ctx, "acme", "")
+				return err
+			},
+			want: map[string]string{"tool": "schemactl", profiling.LabelOperation: "GetChild"},
+		},
+		{
+			name: "IterRows",
+			read: func() error {
+				_, err := storage.IterRows(ctx, storer, "org", "", "").All()
+				return err
+			},
+			want: map[string]string{"tool": "schemactl", profiling.LabelOperation: "IterRows", profiling.LabelRowType: "org"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend.labels = nil
+			_ = tt.read()
+			if len(backend.labels) != len(tt.want) {
+				t.Errorf("the backend's labels are %v, not %v", backend.labels, tt.want)
+			}
+			for key, value := range tt.want {
+				if backend.labels[key] != value {
+					t.Errorf("the backend's labels are %v, not %v", backend.labels, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
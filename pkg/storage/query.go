@@ -0,0 +1,40 @@
+package storage
+
+import "context"
+
+// ColumnQuerier is implemented by storage backends that can find rows by a
+// column's value without handing every row of the type back to be filtered.
+type ColumnQuerier interface {
+	// QueryByColumn returns the rows of the type whose column columnName
+	// holds value, in no particular order. A string set matches a value
+	// holding the same strings in any order.
+	QueryByColumn(ctx context.Context, rowType, columnName string, value interface{}) ([]Row, error)
+}
+
+// QueryByColumn returns the rows of the type whose column columnName holds
+// value, as ColumnQuerier does, reading every row of the type and filtering
+// them if the storer isn't a ColumnQuerier.
+func QueryByColumn(ctx context.Context, storer RowStorer, rowType, columnName string, value interface{}) ([]Row, error) {
+	if querier, ok := storer.(ColumnQuerier); ok {
+		return querier.QueryByColumn(ctx, rowType, columnName, value)
+	}
+	rows := []Row{}
+	it := IterRows(ctx, storer, rowType, "", "")
+	for it.Next() {
+		if ColumnEquals(it.Value(), columnName, value) {
+			rows = append(rows, it.Value())
+		}
+	}
+	return rows, it.Err()
+}
+
+// ColumnEquals reports whether the row's column columnName holds value, as
+// EqualColumns compares them. Backends whose own filters can only narrow the
+// rows down, such as to those with the column, use it to check the rest.
+func ColumnEquals(row Row, columnName string, value interface{}) bool {
+	got, ok := row.Columns()[columnName]
+	if !ok {
+		return false
+	}
+	return EqualColumns(map[string]interface{}{columnName: got}, map[string]interface{}{columnName: value})
+}
@@ -0,0 +1,16 @@
+package ratelimit_test
+
+import (
+	"testing"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/memory"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/ratelimit"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/storagetest"
+)
+
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.RowStorer {
+		return ratelimit.NewStorer(memory.NewClient(), ratelimit.Limits{Read: ratelimit.Limit{Rate: 100000}, Write: ratelimit.Limit{Rate: 100000}})
+	})
+}
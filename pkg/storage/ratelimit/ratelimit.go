@@ -0,0 +1,277 @@
+// Package ratelimit wraps a storage.RowStorer to pace its operations with
+// token buckets, so that a large apply with high parallelism stays within a
+// backend's provisioned capacity or a storage server's quota rather than
+// being throttled.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+// Limit is how often operations may start: Rate a second on average, and up
+// to Burst at once after a lull. A Rate of 0 doesn't limit them. A Burst of
+// 0 allows as many as Rate, rounded up.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Limits are the limits of reads and writes, which backends such as
+// DynamoDB provision separately.
+type Limits struct {
+	// Read limits GetRowByID, GetRow, GetChild, ListRows, ScanRows, and
+	// ScanChildren, which start once however many rows they read, and each
+	// page IterRows reads.
+	Read Limit
+	// Write limits the operations that create, update, delete, or put rows.
+	Write Limit
+}
+
+// Storer is a storage.RowStorer that waits for its turn before each operation
+// of the one it wraps, or until the operation's context is done.
+type Storer struct {
+	next   storage.RowStorer
+	reads  *bucket
+	writes *bucket
+}
+
+var (
+	_ storage.RowStorer     = &Storer{}
+	_ storage.Transactor    = &Storer{}
+	_ storage.ColumnDeleter = &Storer{}
+	_ storage.BatchGetter   = &Storer{}
+	_ storage.ColumnQuerier = &Storer{}
+	_ storage.ChildScanner  = &Storer{}
+	_ storage.BatchPutter   = &Storer{}
+	_ storage.ColumnPatcher = &Storer{}
+	_ storage.RowIterator   = &Storer{}
+)
+
+func NewStorer(next storage.RowStorer, limits Limits) *Storer {
+	return &Storer{
+		next:   next,
+		reads:  newBucket(limits.Read),
+		writes: newBucket(limits.Write),
+	}
+}
+
+// Unwrap returns the wrapped storer.
+func (client *Storer) Unwrap() storage.RowStorer {
+	return client.next
+}
+
+// bucket is a token bucket: it holds up to burst tokens, gaining rate a
+// second, and each operation takes one. A nil bucket doesn't limit.
+type bucket struct {
+	rate  float64
+	burst float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func newBucket(limit Limit) *bucket {
+	if limit.Rate <= 0 {
+		return nil
+	}
+	burst := float64(limit.Burst)
+	if burst <= 0 {
+		burst = math.Ceil(limit.Rate)
+	}
+	return &bucket{rate: limit.Rate, burst: burst, tokens: burst, last: time.Now()}
+}
+
+// wait takes a token, waiting for one if there are none, or returns ctx's
+// error if it's done first.
+func (b *bucket) wait(ctx context.Context, op string) error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	now := time.Now()
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	// the token is taken now, even if it's yet to be gained, so that those
+	// waiting take turns
+	b.tokens--
+	delay := time.Duration(-b.tokens / b.rate * float64(time.Second))
+	b.mu.Unlock()
+	if delay <= 0 {
+		return nil
+	}
+
+	tflog.SubsystemTrace(ctx, storage.LogSubsystemStorage, fmt.Sprintf("Rate limiting %s for %s", op, delay.Round(time.Millisecond)))
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		// give the turn back, for those behind it
+		b.mu.Lock()
+		b.tokens++
+		b.mu.Unlock()
+		return fmt.Errorf("waiting to start %s: %w", op, context.Cause(ctx))
+	case <-timer.C:
+		return nil
+	}
+}
+
+func (client *Storer) GetRowByID(ctx context.Context, rowType, rowID string) (storage.Row, error) {
+	if err := client.reads.wait(ctx, "GetRowByID"); err != nil {
+		return nil, err
+	}
+	return client.next.GetRowByID(ctx, rowType, rowID)
+}
+
+func (client *Storer) GetRowsByIDs(ctx context.Context, rowType string, ids []string) ([]storage.Row, error) {
+	if err := client.reads.wait(ctx, "GetRowsByIDs"); err != nil {
+		return nil, err
+	}
+	return storage.GetRowsByIDs(ctx, client.next, rowType, ids)
+}
+
+func (client *Storer) GetRow(ctx context.Context, rowType, rowLabel string) (storage.Row, error) {
+	if err := client.reads.wait(ctx, "GetRow"); err != nil {
+		return nil, err
+	}
+	return client.next.GetRow(ctx, rowType, rowLabel)
+}
+
+func (client *Storer) CreateRow(ctx context.Context, rowType, rowLabel string) (storage.Row, error) {
+	if err := client.writes.wait(ctx, "CreateRow"); err != nil {
+		return nil, err
+	}
+	return client.next.CreateRow(ctx, rowType, rowLabel)
+}
+
+func (client *Storer) CreateChild(ctx context.Context, rowType, rowLabel, parentType, parentID string, columns map[string]interface{}) (storage.Row, error) {
+	if err := client.writes.wait(ctx, "CreateChild"); err != nil {
+		return nil, err
+	}
+	return client.next.CreateChild(ctx, rowType, rowLabel, parentType, parentID, columns)
+}
+
+func (client *Storer) GetChild(ctx context.Context, childLabel, parentID string) (storage.Row, error) {
+	if err := client.reads.wait(ctx, "GetChild"); err != nil {
+		return nil, err
+	}
+	return client.next.GetChild(ctx, childLabel, parentID)
+}
+
+func (client *Storer) ListRows(ctx context.Context, rowType, labelFilter, parentIDFilter string) ([]storage.Row, error) {
+	if err := client.reads.wait(ctx, "ListRows"); err != nil {
+		return nil, err
+	}
+	return client.next.ListRows(ctx, rowType, labelFilter, parentIDFilter)
+}
+
+// IterRows takes a token for each page it reads.
+func (client *Storer) IterRows(ctx context.Context, rowType, labelFilter, parentIDFilter string) *storage.Iter[storage.Row] {
+	return storage.WrapPages(storage.IterRows(ctx, client.next, rowType, labelFilter, parentIDFilter), func(ctx context.Context, fetch storage.PageFunc[storage.Row]) ([]storage.Row, bool, error) {
+		if err := client.reads.wait(ctx, "IterRows"); err != nil {
+			return nil, false, err
+		}
+		return fetch(ctx)
+	})
+}
+
+func (client *Storer) QueryByColumn(ctx context.Context, rowType, columnName string, value interface{}) ([]storage.Row, error) {
+	if err := client.reads.wait(ctx, "QueryByColumn"); err != nil {
+		return nil, err
+	}
+	return storage.QueryByColumn(ctx, client.next, rowType, columnName, value)
+}
+
+func (client *Storer) UpdateRow(ctx context.Context, rowType, rowID, newLabel string) (storage.Row, error) {
+	if err := client.writes.wait(ctx, "UpdateRow"); err != nil {
+		return nil, err
+	}
+	return client.next.UpdateRow(ctx, rowType, rowID, newLabel)
+}
+
+func (client *Storer) UpdateChild(ctx context.Context, childType, childID, newChildLabel, parentType, newParentID string) (storage.Row, error) {
+	if err := client.writes.wait(ctx, "UpdateChild"); err != nil {
+		return nil, err
+	}
+	return client.next.UpdateChild(ctx, childType, childID, newChildLabel, parentType, newParentID)
+}
+
+func (client *Storer) UpdateColumn(ctx context.Context, rowType, rowID, columnName string, columnValue interface{}) error {
+	if err := client.writes.wait(ctx, "UpdateColumn"); err != nil {
+		return err
+	}
+	return client.next.UpdateColumn(ctx, rowType, rowID, columnName, columnValue)
+}
+
+func (client *Storer) PatchColumns(ctx context.Context, patches []storage.ColumnPatch) error {
+	if err := client.writes.wait(ctx, "PatchColumns"); err != nil {
+		return err
+	}
+	return storage.PatchColumns(ctx, client.next, patches)
+}
+
+func (client *Storer) UpdateColumns(ctx context.Context, rowType, rowID string, columns map[string]interface{}) error {
+	if err := client.writes.wait(ctx, "UpdateColumns"); err != nil {
+		return err
+	}
+	return client.next.UpdateColumns(ctx, rowType, rowID, columns)
+}
+
+func (client *Storer) DeleteRow(ctx context.Context, rowType, childType, rowID string) error {
+	if err := client.writes.wait(ctx, "DeleteRow"); err != nil {
+		return err
+	}
+	return client.next.DeleteRow(ctx, rowType, childType, rowID)
+}
+
+func (client *Storer) PutRow(ctx context.Context, row storage.Row) error {
+	if err := client.writes.wait(ctx, "PutRow"); err != nil {
+		return err
+	}
+	return client.next.PutRow(ctx, row)
+}
+
+// PutRows takes one token for the batch, as each operation does.
+func (client *Storer) PutRows(ctx context.Context, rows []storage.Row) error {
+	if err := client.writes.wait(ctx, "PutRows"); err != nil {
+		return err
+	}
+	return storage.PutRows(ctx, client.next, rows)
+}
+
+func (client *Storer) ScanRows(ctx context.Context, fn func(storage.Row) error) error {
+	if err := client.reads.wait(ctx, "ScanRows"); err != nil {
+		return err
+	}
+	return client.next.ScanRows(ctx, fn)
+}
+
+func (client *Storer) ScanChildren(ctx context.Context, parentID string, fn func(storage.Row) error) error {
+	if err := client.reads.wait(ctx, "ScanChildren"); err != nil {
+		return err
+	}
+	return storage.ScanChildren(ctx, client.next, parentID, fn)
+}
+
+func (client *Storer) DeleteColumn(ctx context.Context, rowType, rowID, columnName string) error {
+	if err := client.writes.wait(ctx, "DeleteColumn"); err != nil {
+		return err
+	}
+	return storage.DeleteColumn(ctx, client.next, rowType, rowID, columnName)
+}
+
+// WithinTx waits for one turn to write for the whole transaction, since
+// waiting within it would hold it open.
+func (client *Storer) WithinTx(ctx context.Context, fn func(tx storage.RowStorer) error) error {
+	if err := client.writes.wait(ctx, "WithinTx"); err != nil {
+		return err
+	}
+	return storage.WithinTx(ctx, client.next, fn)
+}
@@ -0,0 +1,78 @@
+package ratelimit_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/memory"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/ratelimit"
+)
+
+// timed returns how long reading the org n times took.
+func timed(t *testing.T, storer storage.RowStorer, org storage.Row, n int) time.Duration {
+	t.Helper()
+	start := time.Now()
+	for i := 0; i < n; i++ {
+		if _, err := storer.GetRowByID(context.Background(), "org", org.ID()); err != nil {
+			t.Fatalf("GetRowByID: %s", err)
+		}
+	}
+	return time.Since(start)
+}
+
+func newOrg(t *testing.T) (storage.RowStorer, storage.Row) {
+	t.Helper()
+	backend := memory.NewClient()
+	org, err := backend.CreateRow(context.Background(), "org", "acme")
+	if err != nil {
+		t.Fatalf("CreateRow: %s", err)
+	}
+	return backend, org
+}
+
+func TestBurstThenRate(t *testing.T) {
+	backend, org := newOrg(t)
+	storer := ratelimit.NewStorer(backend, ratelimit.Limits{Read: ratelimit.Limit{Rate: 10, Burst: 3}})
+
+	if took := timed(t, storer, org, 3); took > 100*time.Millisecond {
+		t.Errorf("a burst of 3 reads took %s", took)
+	}
+	// the next two wait for tokens, gained every 100ms
+	if took := timed(t, storer, org, 2); took < 150*time.Millisecond {
+		t.Errorf("2 reads past the burst took %s, not about 200ms", took)
+	}
+}
+
+func TestReadsAndWritesApart(t *testing.T) {
+	ctx := context.Background()
+	backend, org := newOrg(t)
+	storer := ratelimit.NewStorer(backend, ratelimit.Limits{Write: ratelimit.Limit{Rate: 1, Burst: 1}})
+
+	if err := storer.UpdateColumn(ctx, "org", org.ID(), "owner", "ops"); err != nil {
+		t.Fatalf("UpdateColumn: %s", err)
+	}
+	// writes have used their bucket up, but reads aren't limited
+	if took := timed(t, storer, org, 20); took > 100*time.Millisecond {
+		t.Errorf("20 unlimited reads took %s", took)
+	}
+}
+
+func TestWaitCanceled(t *testing.T) {
+	backend, org := newOrg(t)
+	storer := ratelimit.NewStorer(backend, ratelimit.Limits{Read: ratelimit.Limit{Rate: 1, Burst: 1}})
+	timed(t, storer, org, 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := storer.GetRowByID(ctx, "org", org.ID())
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("GetRowByID waiting past its deadline failed with %v, not %q", err, context.DeadlineExceeded)
+	}
+	if took := time.Since(start); took > 500*time.Millisecond {
+		t.Errorf("GetRowByID waited %s for its turn, past its deadline", took)
+	}
+}
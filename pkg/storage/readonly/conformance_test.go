@@ -0,0 +1,93 @@
+package readonly_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/memory"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/readonly"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/split"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/storagetest"
+)
+
+// TestConformance runs the conformance tests' reads through the read-only
+// storer, and their writes around it, since it refuses them.
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.RowStorer {
+		client := memory.NewClient()
+		return split.NewStorer(client, readonly.NewStorer(client))
+	})
+}
+
+func TestWritesRefused(t *testing.T) {
+	ctx := context.Background()
+	client := memory.NewClient()
+	org, err := client.CreateRow(ctx, "org", "acme")
+	if err != nil {
+		t.Fatalf("CreateRow: %s", err)
+	}
+	storer := readonly.NewStorer(client)
+
+	writes := map[string]func() error{
+		"CreateRow": func() error {
+			_, err := storer.CreateRow(ctx, "org", "globex")
+			return err
+		},
+		"CreateChild": func() error {
+			_, err := storer.CreateChild(ctx, "team", "infra", "org", org.ID(), nil)
+			return err
+		},
+		"UpdateRow": func() error {
+			_, err := storer.UpdateRow(ctx, "org", org.ID(), "globex")
+			return err
+		},
+		"UpdateColumn": func() error {
+			return storer.UpdateColumn(ctx, "org", org.ID(), "owner", "ops")
+		},
+		"UpdateColumns": func() error {
+			return storer.UpdateColumns(ctx, "org", org.ID(), map[string]interface{}{"owner": "ops"})
+		},
+		"DeleteRow": func() error {
+			return storer.DeleteRow(ctx, "org", "", org.ID())
+		},
+		"UpdateChild": func() error {
+			_, err := storer.UpdateChild(ctx, "team", "team-1", "infra", "org", org.ID())
+			return err
+		},
+		"DeleteColumn": func() error {
+			return storer.DeleteColumn(ctx, "org", org.ID(), "owner")
+		},
+		"PutRow": func() error {
+			return storer.PutRow(ctx, org)
+		},
+		"PutRows": func() error {
+			return storer.PutRows(ctx, []storage.Row{org})
+		},
+		"PatchColumns": func() error {
+			return storer.PatchColumns(ctx, []storage.ColumnPatch{{Type: "org", ID: org.ID(), Columns: map[string]interface{}{"owner": "ops"}}})
+		},
+		"UpdateColumn within a transaction": func() error {
+			return storer.WithinTx(ctx, func(tx storage.RowStorer) error {
+				if _, err := tx.GetRowByID(ctx, "org", org.ID()); err != nil {
+					return err
+				}
+				return tx.UpdateColumn(ctx, "org", org.ID(), "owner", "ops")
+			})
+		},
+	}
+	for name, write := range writes {
+		if err := write(); !errors.Is(err, storage.ErrReadOnly) {
+			t.Errorf("%s returned %v, not an error wrapping storage.ErrReadOnly", name, err)
+		}
+	}
+
+	got, err := client.GetRowByID(ctx, "org", org.ID())
+	if err != nil {
+		t.Fatalf("GetRowByID: %s", err)
+	}
+	if got.Label() != "acme" || len(got.Columns()) != 0 {
+		t.Errorf("the refused writes changed the row: label %q, columns %v", got.Label(), got.Columns())
+	}
+}
@@ -0,0 +1,138 @@
+// Package readonly wraps a storage.RowStorer to refuse every write, so that a
+// provider meant only for plans, or configured with read-only credentials,
+// fails clearly before it touches the backend.
+package readonly
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+// Storer is a storage.RowStorer that reads from the one it wraps, and refuses
+// writes with an error wrapping storage.ErrReadOnly.
+type Storer struct {
+	next storage.RowStorer
+}
+
+var (
+	_ storage.RowStorer     = &Storer{}
+	_ storage.Transactor    = &Storer{}
+	_ storage.ColumnDeleter = &Storer{}
+	_ storage.BatchGetter   = &Storer{}
+	_ storage.ColumnQuerier = &Storer{}
+	_ storage.ChildScanner  = &Storer{}
+	_ storage.BatchPutter   = &Storer{}
+	_ storage.ColumnPatcher = &Storer{}
+	_ storage.RowIterator   = &Storer{}
+)
+
+func NewStorer(next storage.RowStorer) *Storer {
+	return &Storer{next: next}
+}
+
+// Unwrap returns the wrapped storer.
+func (client *Storer) Unwrap() storage.RowStorer {
+	return client.next
+}
+
+func refuse(operation, rowType, rowID string) error {
+	if rowID == "" {
+		return fmt.Errorf("%w: %s of a %s row refused", storage.ErrReadOnly, operation, rowType)
+	}
+	return fmt.Errorf("%w: %s of %s %q refused", storage.ErrReadOnly, operation, rowType, rowID)
+}
+
+func (client *Storer) GetRowByID(ctx context.Context, rowType, rowID string) (storage.Row, error) {
+	return client.next.GetRowByID(ctx, rowType, rowID)
+}
+
+func (client *Storer) GetRowsByIDs(ctx context.Context, rowType string, ids []string) ([]storage.Row, error) {
+	return storage.GetRowsByIDs(ctx, client.next, rowType, ids)
+}
+
+func (client *Storer) GetRow(ctx context.Context, rowType, rowLabel string) (storage.Row, error) {
+	return client.next.GetRow(ctx, rowType, rowLabel)
+}
+
+func (client *Storer) CreateRow(ctx context.Context, rowType, rowLabel string) (storage.Row, error) {
+	return nil, refuse("CreateRow", rowType, "")
+}
+
+func (client *Storer) CreateChild(ctx context.Context, rowType, rowLabel, parentType, parentID string, columns map[string]interface{}) (storage.Row, error) {
+	return nil, refuse("CreateChild", rowType, "")
+}
+
+func (client *Storer) GetChild(ctx context.Context, childLabel, parentID string) (storage.Row, error) {
+	return client.next.GetChild(ctx, childLabel, parentID)
+}
+
+func (client *Storer) ListRows(ctx context.Context, rowType, labelFilter, parentIDFilter string) ([]storage.Row, error) {
+	return client.next.ListRows(ctx, rowType, labelFilter, parentIDFilter)
+}
+
+func (client *Storer) IterRows(ctx context.Context, rowType, labelFilter, parentIDFilter string) *storage.Iter[storage.Row] {
+	return storage.IterRows(ctx, client.next, rowType, labelFilter, parentIDFilter)
+}
+
+func (client *Storer) QueryByColumn(ctx context.Context, rowType, columnName string, value interface{}) ([]storage.Row, error) {
+	return storage.QueryByColumn(ctx, client.next, rowType, columnName, value)
+}
+
+func (client *Storer) UpdateRow(ctx context.Context, rowType, rowID, newLabel string) (storage.Row, error) {
+	return nil, refuse("UpdateRow", rowType, rowID)
+}
+
+func (client *Storer) UpdateChild(ctx context.Context, childType, childID, newChildLabel, parentType, newParentID string) (storage.Row, error) {
+	return nil, refuse("UpdateChild", childType, childID)
+}
+
+func (client *Storer) UpdateColumn(ctx context.Context, rowType, rowID, columnName string, columnValue interface{}) error {
+	return refuse("UpdateColumn", rowType, rowID)
+}
+
+func (client *Storer) PatchColumns(ctx context.Context, patches []storage.ColumnPatch) error {
+	if len(patches) == 0 {
+		return nil
+	}
+	return refuse("PatchColumns", patches[0].Type, patches[0].ID)
+}
+
+func (client *Storer) UpdateColumns(ctx context.Context, rowType, rowID string, columns map[string]interface{}) error {
+	return refuse("UpdateColumns", rowType, rowID)
+}
+
+func (client *Storer) DeleteRow(ctx context.Context, rowType, childType, rowID string) error {
+	return refuse("DeleteRow", rowType, rowID)
+}
+
+func (client *Storer) PutRow(ctx context.Context, row storage.Row) error {
+	return refuse("PutRow", row.Type(), row.ID())
+}
+
+func (client *Storer) PutRows(ctx context.Context, rows []storage.Row) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	return refuse("PutRows", rows[0].Type(), rows[0].ID())
+}
+
+func (client *Storer) ScanRows(ctx context.Context, fn func(storage.Row) error) error {
+	return client.next.ScanRows(ctx, fn)
+}
+
+func (client *Storer) ScanChildren(ctx context.Context, parentID string, fn func(storage.Row) error) error {
+	return storage.ScanChildren(ctx, client.next, parentID, fn)
+}
+
+func (client *Storer) DeleteColumn(ctx context.Context, rowType, rowID, columnName string) error {
+	return refuse("DeleteColumn", rowType, rowID)
+}
+
+// WithinTx refuses the transaction's writes, too, so it can only read.
+func (client *Storer) WithinTx(ctx context.Context, fn func(tx storage.RowStorer) error) error {
+	return storage.WithinTx(ctx, client.next, func(tx storage.RowStorer) error {
+		return fn(NewStorer(tx))
+	})
+}
@@ -0,0 +1,272 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// DefaultRetryAttempts is how many times WithRetry tries an operation if its
+// policy doesn't say.
+const DefaultRetryAttempts = 5
+
+// RetryPolicy is when and how often WithRetry retries an operation.
+type RetryPolicy struct {
+	// Attempts is how many times an operation is tried, the first time
+	// included. Zero means DefaultRetryAttempts.
+	Attempts int
+	// Backoff is the delay before each retry.
+	Backoff Backoff
+	// Retryable says whether an operation that failed with an error may
+	// succeed if it's tried again. Nil means IsTransient.
+	Retryable func(error) bool
+}
+
+// IsTransient returns whether err is of a kind that passes, such as
+// ErrThrottled, ErrUnavailable, or ErrServerError. Each but ErrServerError
+// means the operation wasn't made. A write that failed with a server error
+// may have been made, but retrying it is still safe: a create that was made
+// fails again as a collision, and the other writes set what they set before.
+func IsTransient(err error) bool {
+	return errors.Is(err, ErrThrottled)
+}
+
+type retryStorer struct {
+	next   RowStorer
+	policy RetryPolicy
+}
+
+// WithRetry wraps next to retry operations that fail with errors the policy
+// says are retryable, waiting as its backoff says before each retry, so that
+// backends need not retry on their own. It stops waiting when ctx is done.
+// A scan is retried only if it failed before calling its function, so that
+// no row is seen twice.
+func WithRetry(next RowStorer, policy RetryPolicy) RowStorer {
+	if policy.Attempts <= 0 {
+		policy.Attempts = DefaultRetryAttempts
+	}
+	if policy.Retryable == nil {
+		policy.Retryable = IsTransient
+	}
+	return &retryStorer{next: next, policy: policy}
+}
+
+// Unwrap returns the wrapped storer.
+func (client *retryStorer) Unwrap() RowStorer {
+	return client.next
+}
+
+// retry calls fn until it succeeds, fails with an error that isn't
+// retryable, or has been tried as often as the policy allows, and returns
+// its last error.
+func (client *retryStorer) retry(ctx context.Context, op string, fn func() error) error {
+	var delay time.Duration
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= client.policy.Attempts || !client.policy.Retryable(err) {
+			return err
+		}
+		delay = client.policy.Backoff.Delay(attempt, delay)
+		tflog.SubsystemDebug(ctx, LogSubsystemStorage, fmt.Sprintf("Retrying %s in %s", op, delay.Round(time.Millisecond)), map[string]interface{}{
+			"attempt": attempt,
+			"error":   err.Error(),
+		})
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%w (gave up retrying: %w)", err, context.Cause(ctx))
+		case <-timer.C:
+		}
+	}
+}
+
+func (client *retryStorer) GetRowByID(ctx context.Context, rowType, rowID string) (row Row, err error) {
+	err = client.retry(ctx, "GetRowByID", func() (err error) {
+		row, err = client.next.GetRowByID(ctx, rowType, rowID)
+		return err
+	})
+	return row, err
+}
+
+func (client *retryStorer) GetRowsByIDs(ctx context.Context, rowType string, ids []string) (rows []Row, err error) {
+	err = client.retry(ctx, "GetRowsByIDs", func() (err error) {
+		rows, err = GetRowsByIDs(ctx, client.next, rowType, ids)
+		return err
+	})
+	return rows, err
+}
+
+func (client *retryStorer) GetRow(ctx context.Context, rowType, rowLabel string) (row Row, err error) {
+	err = client.retry(ctx, "GetRow", func() (err error) {
+		row, err = client.next.GetRow(ctx, rowType, rowLabel)
+		return err
+	})
+	return row, err
+}
+
+func (client *retryStorer) CreateRow(ctx context.Context, rowType, rowLabel string) (row Row, err error) {
+	err = client.retry(ctx, "CreateRow", func() (err error) {
+		row, err = client.next.CreateRow(ctx, rowType, rowLabel)
+		return err
+	})
+	return row, err
+}
+
+func (client *retryStorer) CreateChild(ctx context.Context, rowType, rowLabel, parentType, parentID string, columns map[string]interface{}) (row Row, err error) {
+	err = client.retry(ctx, "CreateChild", func() (err error) {
+		row, err = client.next.CreateChild(ctx, rowType, rowLabel, parentType, parentID, columns)
+		return err
+	})
+	return row, err
+}
+
+func (client *retryStorer) GetChild(ctx context.Context, childLabel, parentID string) (row Row, err error) {
+	err = client.retry(ctx, "GetChild", func() (err error) {
+		row, err = client.next.GetChild(ctx, childLabel, parentID)
+		return err
+	})
+	return row, err
+}
+
+func (client *retryStorer) ListRows(ctx context.Context, rowType, labelFilter, parentIDFilter string) (rows []Row, err error) {
+	err = client.retry(ctx, "ListRows", func() (err error) {
+		rows, err = client.next.ListRows(ctx, rowType, labelFilter, parentIDFilter)
+		return err
+	})
+	return rows, err
+}
+
+// IterRows retries each page that fails, from where the iteration is.
+func (client *retryStorer) IterRows(ctx context.Context, rowType, labelFilter, parentIDFilter string) *Iter[Row] {
+	return WrapPages(IterRows(ctx, client.next, rowType, labelFilter, parentIDFilter), func(ctx context.Context, fetch PageFunc[Row]) (page []Row, more bool, err error) {
+		err = client.retry(ctx, "IterRows", func() (err error) {
+			page, more, err = fetch(ctx)
+			return err
+		})
+		return page, more, err
+	})
+}
+
+func (client *retryStorer) QueryByColumn(ctx context.Context, rowType, columnName string, value interface{}) (rows []Row, err error) {
+	err = client.retry(ctx, "QueryByColumn", func() (err error) {
+		rows, err = QueryByColumn(ctx, client.next, rowType, columnName, value)
+		return err
+	})
+	return rows, err
+}
+
+func (client *retryStorer) UpdateRow(ctx context.Context, rowType, rowID, newLabel string) (row Row, err error) {
+	err = client.retry(ctx, "UpdateRow", func() (err error) {
+		row, err = client.next.UpdateRow(ctx, rowType, rowID, newLabel)
+		return err
+	})
+	return row, err
+}
+
+func (client *retryStorer) UpdateChild(ctx context.Context, childType, childID, newChildLabel, parentType, newParentID string) (row Row, err error) {
+	err = client.retry(ctx, "UpdateChild", func() (err error) {
+		row, err = client.next.UpdateChild(ctx, childType, childID, newChildLabel, parentType, newParentID)
+		return err
+	})
+	return row, err
+}
+
+func (client *retryStorer) UpdateColumn(ctx context.Context, rowType, rowID, columnName string, columnValue interface{}) error {
+	return client.retry(ctx, "UpdateColumn", func() error {
+		return client.next.UpdateColumn(ctx, rowType, rowID, columnName, columnValue)
+	})
+}
+
+// PatchColumns retries all of the patches, which sets again the columns
+// patched before the error.
+func (client *retryStorer) PatchColumns(ctx context.Context, patches []ColumnPatch) error {
+	return client.retry(ctx, "PatchColumns", func() error {
+		return PatchColumns(ctx, client.next, patches)
+	})
+}
+
+func (client *retryStorer) UpdateColumns(ctx context.Context, rowType, rowID string, columns map[string]interface{}) error {
+	return client.retry(ctx, "UpdateColumns", func() error {
+		return client.next.UpdateColumns(ctx, rowType, rowID, columns)
+	})
+}
+
+func (client *retryStorer) DeleteRow(ctx context.Context, rowType, childType, rowID string) error {
+	return client.retry(ctx, "DeleteRow", func() error {
+		return client.next.DeleteRow(ctx, rowType, childType, rowID)
+	})
+}
+
+func (client *retryStorer) PutRow(ctx context.Context, row Row) error {
+	return client.retry(ctx, "PutRow", func() error {
+		return client.next.PutRow(ctx, row)
+	})
+}
+
+// PutRows retries the whole batch, which puts again any rows put before the
+// error.
+func (client *retryStorer) PutRows(ctx context.Context, rows []Row) error {
+	return client.retry(ctx, "PutRows", func() error {
+		return PutRows(ctx, client.next, rows)
+	})
+}
+
+func (client *retryStorer) ScanRows(ctx context.Context, fn func(Row) error) error {
+	scanned := false
+	var scanErr error
+	err := client.retry(ctx, "ScanRows", func() error {
+		err := client.next.ScanRows(ctx, func(row Row) error {
+			scanned = true
+			return fn(row)
+		})
+		if err != nil && scanned {
+			// fn has seen rows, which it would see again: stop retrying
+			scanErr = err
+			return nil
+		}
+		return err
+	})
+	if scanErr != nil {
+		return scanErr
+	}
+	return err
+}
+
+// ScanChildren stops retrying once fn has seen children, as ScanRows does.
+func (client *retryStorer) ScanChildren(ctx context.Context, parentID string, fn func(Row) error) error {
+	scanned := false
+	var scanErr error
+	err := client.retry(ctx, "ScanChildren", func() error {
+		err := ScanChildren(ctx, client.next, parentID, func(row Row) error {
+			scanned = true
+			return fn(row)
+		})
+		if err != nil && scanned {
+			scanErr = err
+			return nil
+		}
+		return err
+	})
+	if scanErr != nil {
+		return scanErr
+	}
+	return err
+}
+
+func (client *retryStorer) DeleteColumn(ctx context.Context, rowType, rowID, columnName string) error {
+	return client.retry(ctx, "DeleteColumn", func() error {
+		return DeleteColumn(ctx, client.next, rowType, rowID, columnName)
+	})
+}
+
+// WithinTx retries the whole transaction, which makes none of its writes if
+// it fails, so fn may be called more than once.
+func (client *retryStorer) WithinTx(ctx context.Context, fn func(tx RowStorer) error) error {
+	return client.retry(ctx, "WithinTx", func() error {
+		return WithinTx(ctx, client.next, fn)
+	})
+}
@@ -0,0 +1,138 @@
+package storage_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/memory"
+)
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{storage.ErrThrottled, true},
+		{storage.ErrUnavailable, true},
+		{storage.ErrServerError, true},
+		{fmt.Errorf("%w: InternalServerError", storage.ErrServerError), true},
+		{storage.ErrCircuitOpen, true},
+		{storage.ErrNotFoundRow, false},
+		{storage.ErrCollisionTypeLabel, false},
+		{storage.ErrPermissionDenied, false},
+		{errors.New("unexpected"), false},
+	}
+	for _, test := range tests {
+		if got := storage.IsTransient(test.err); got != test.want {
+			t.Errorf("IsTransient(%q) is %t, not %t", test.err, got, test.want)
+		}
+	}
+}
+
+// failing fails its first reads with err.
+type failing struct {
+	storage.RowStorer
+	err      error
+	failures int
+	calls    int
+}
+
+func (client *failing) GetRowByID(ctx context.Context, rowType, rowID string) (storage.Row, error) {
+	client.calls++
+	if client.calls <= client.failures {
+		return nil, client.err
+	}
+	return client.RowStorer.GetRowByID(ctx, rowType, rowID)
+}
+
+func TestWithRetry(t *testing.T) {
+	ctx := context.Background()
+	errUnexpected := errors.New("unexpected")
+	policy := storage.RetryPolicy{Attempts: 3, Backoff: storage.Backoff{Base: time.Millisecond, Max: time.Millisecond}}
+	tests := []struct {
+		name      string
+		err       error
+		failures  int
+		wantCalls int
+		wantErr   error
+	}{
+		{"throttled", storage.ErrThrottled, 2, 3, nil},
+		{"server error", fmt.Errorf("%w: HTTP 500", storage.ErrServerError), 2, 3, nil},
+		{"server errors past the attempts", storage.ErrServerError, 3, 3, storage.ErrServerError},
+		{"not found", storage.ErrNotFoundRow, 1, 1, storage.ErrNotFoundRow},
+		{"unexpected", errUnexpected, 1, 1, errUnexpected},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			backend := memory.NewClient()
+			created, err := backend.CreateRow(ctx, "org", "initech")
+			if err != nil {
+				t.Fatalf("CreateRow: %s", err)
+			}
+			next := &failing{RowStorer: backend, err: test.err, failures: test.failures}
+			_, err = storage.WithRetry(next, policy).GetRowByID(ctx, "org", created.ID())
+			if next.calls != test.wantCalls {
+				t.Errorf("GetRowByID was called %d times, not %d", next.calls, test.wantCalls)
+			}
+			if test.wantErr == nil && err != nil {
+				t.Errorf("GetRowByID: %s", err)
+			}
+			if test.wantErr != nil && !errors.Is(err, test.wantErr) {
+				t.Errorf("GetRowByID failed with %v, not %q", err, test.wantErr)
+			}
+		})
+	}
+}
+
+// failingPages lists rows a page of one at a time, failing its second page
+// the first time with err.
+type failingPages struct {
+	storage.RowStorer
+	err   error
+	calls int
+}
+
+func (client *failingPages) IterRows(ctx context.Context, rowType, labelFilter, parentIDFilter string) *storage.Iter[storage.Row] {
+	var rows []storage.Row
+	return storage.NewIter(ctx, func(ctx context.Context) ([]storage.Row, bool, error) {
+		client.calls++
+		if client.calls == 2 {
+			return nil, false, client.err
+		}
+		if rows == nil {
+			var err error
+			if rows, err = client.RowStorer.ListRows(ctx, rowType, labelFilter, parentIDFilter); err != nil || len(rows) == 0 {
+				return nil, false, err
+			}
+		}
+		page := rows[:1]
+		rows = rows[1:]
+		return page, len(rows) > 0, nil
+	})
+}
+
+func TestWithRetryIterRows(t *testing.T) {
+	ctx := context.Background()
+	backend := memory.NewClient()
+	for _, label := range []string{"initech", "initrode", "hooli"} {
+		if _, err := backend.CreateRow(ctx, "org", label); err != nil {
+			t.Fatalf("CreateRow: %s", err)
+		}
+	}
+	next := &failingPages{RowStorer: backend, err: storage.ErrThrottled}
+	policy := storage.RetryPolicy{Attempts: 3, Backoff: storage.Backoff{Base: time.Millisecond, Max: time.Millisecond}}
+	rows, err := storage.IterRows(ctx, storage.WithRetry(next, policy), "org", "", "").All()
+	if err != nil {
+		t.Fatalf("IterRows: %s", err)
+	}
+	if len(rows) != 3 {
+		t.Errorf("IterRows read %d rows after retrying a page, not 3", len(rows))
+	}
+	if next.calls != 4 {
+		t.Errorf("pages were read %d times, not 4", next.calls)
+	}
+}
@@ -0,0 +1,263 @@
+// Package slowlog wraps a storage.RowStorer to log a warning for each
+// operation that takes longer than a threshold, to help find hot partitions
+// and oversized items during applies.
+package slowlog
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+// Storer is a storage.RowStorer that warns of the slow operations of the one
+// it wraps.
+type Storer struct {
+	next      storage.RowStorer
+	threshold time.Duration
+}
+
+var (
+	_ storage.RowStorer     = &Storer{}
+	_ storage.Transactor    = &Storer{}
+	_ storage.ColumnDeleter = &Storer{}
+	_ storage.BatchGetter   = &Storer{}
+	_ storage.ColumnQuerier = &Storer{}
+	_ storage.ChildScanner  = &Storer{}
+	_ storage.BatchPutter   = &Storer{}
+	_ storage.ColumnPatcher = &Storer{}
+	_ storage.RowIterator   = &Storer{}
+)
+
+// NewStorer wraps next, warning of operations that take longer than
+// threshold.
+func NewStorer(next storage.RowStorer, threshold time.Duration) *Storer {
+	return &Storer{next: next, threshold: threshold}
+}
+
+// Unwrap returns the wrapped storer.
+func (client *Storer) Unwrap() storage.RowStorer {
+	return client.next
+}
+
+// check warns if the operation begun at start has taken too long. fields
+// describe the operation; column values may be sensitive, so they are never
+// among them.
+func (client *Storer) check(ctx context.Context, op string, start time.Time, err error, fields map[string]interface{}) {
+	elapsed := time.Since(start)
+	if elapsed <= client.threshold {
+		return
+	}
+	fields["operation"] = op
+	fields["duration_ms"] = elapsed.Milliseconds()
+	fields["threshold_ms"] = client.threshold.Milliseconds()
+	if err != nil {
+		fields["error"] = err.Error()
+	}
+	tflog.SubsystemWarn(ctx, storage.LogSubsystemStorage, fmt.Sprintf("Slow storage operation: %s took %s", op, elapsed.Round(time.Millisecond)), fields)
+}
+
+func columnNames(columns map[string]interface{}) []string {
+	names := make([]string, 0, len(columns))
+	for name := range columns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (client *Storer) GetRowByID(ctx context.Context, rowType, rowID string) (row storage.Row, err error) {
+	defer func(start time.Time) {
+		client.check(ctx, "GetRowByID", start, err, map[string]interface{}{"type": rowType, "id": rowID})
+	}(time.Now())
+	return client.next.GetRowByID(ctx, rowType, rowID)
+}
+
+func (client *Storer) GetRowsByIDs(ctx context.Context, rowType string, ids []string) (rows []storage.Row, err error) {
+	defer func(start time.Time) {
+		client.check(ctx, "GetRowsByIDs", start, err, map[string]interface{}{
+			"type": rowType,
+			"ids":  len(ids),
+			"rows": len(rows),
+		})
+	}(time.Now())
+	return storage.GetRowsByIDs(ctx, client.next, rowType, ids)
+}
+
+func (client *Storer) GetRow(ctx context.Context, rowType, rowLabel string) (row storage.Row, err error) {
+	defer func(start time.Time) {
+		client.check(ctx, "GetRow", start, err, map[string]interface{}{"type": rowType, "label": rowLabel})
+	}(time.Now())
+	return client.next.GetRow(ctx, rowType, rowLabel)
+}
+
+func (client *Storer) CreateRow(ctx context.Context, rowType, rowLabel string) (row storage.Row, err error) {
+	defer func(start time.Time) {
+		client.check(ctx, "CreateRow", start, err, map[string]interface{}{"type": rowType, "label": rowLabel})
+	}(time.Now())
+	return client.next.CreateRow(ctx, rowType, rowLabel)
+}
+
+func (client *Storer) CreateChild(ctx context.Context, rowType, rowLabel, parentType, parentID string, columns map[string]interface{}) (row storage.Row, err error) {
+	defer func(start time.Time) {
+		client.check(ctx, "CreateChild", start, err, map[string]interface{}{
+			"type":        rowType,
+			"label":       rowLabel,
+			"parent_type": parentType,
+			"parent_id":   parentID,
+			"columns":     columnNames(columns),
+		})
+	}(time.Now())
+	return client.next.CreateChild(ctx, rowType, rowLabel, parentType, parentID, columns)
+}
+
+func (client *Storer) GetChild(ctx context.Context, childLabel, parentID string) (row storage.Row, err error) {
+	defer func(start time.Time) {
+		client.check(ctx, "GetChild", start, err, map[string]interface{}{"label": childLabel, "parent_id": parentID})
+	}(time.Now())
+	return client.next.GetChild(ctx, childLabel, parentID)
+}
+
+func (client *Storer) ListRows(ctx context.Context, rowType, labelFilter, parentIDFilter string) (rows []storage.Row, err error) {
+	defer func(start time.Time) {
+		client.check(ctx, "ListRows", start, err, map[string]interface{}{
+			"type":      rowType,
+			"label":     labelFilter,
+			"parent_id": parentIDFilter,
+			"rows":      len(rows),
+		})
+	}(time.Now())
+	return client.next.ListRows(ctx, rowType, labelFilter, parentIDFilter)
+}
+
+// IterRows checks each page it reads, rather than the whole iteration.
+func (client *Storer) IterRows(ctx context.Context, rowType, labelFilter, parentIDFilter string) *storage.Iter[storage.Row] {
+	return storage.WrapPages(storage.IterRows(ctx, client.next, rowType, labelFilter, parentIDFilter), func(ctx context.Context, fetch storage.PageFunc[storage.Row]) (page []storage.Row, more bool, err error) {
+		defer func(start time.Time) {
+			client.check(ctx, "IterRows", start, err, map[string]interface{}{
+				"type":      rowType,
+				"label":     labelFilter,
+				"parent_id": parentIDFilter,
+				"rows":      len(page),
+			})
+		}(time.Now())
+		return fetch(ctx)
+	})
+}
+
+func (client *Storer) QueryByColumn(ctx context.Context, rowType, columnName string, value interface{}) (rows []storage.Row, err error) {
+	defer func(start time.Time) {
+		client.check(ctx, "QueryByColumn", start, err, map[string]interface{}{
+			"type":   rowType,
+			"column": columnName,
+			"rows":   len(rows),
+		})
+	}(time.Now())
+	return storage.QueryByColumn(ctx, client.next, rowType, columnName, value)
+}
+
+func (client *Storer) UpdateRow(ctx context.Context, rowType, rowID, newLabel string) (row storage.Row, err error) {
+	defer func(start time.Time) {
+		client.check(ctx, "UpdateRow", start, err, map[string]interface{}{"type": rowType, "id": rowID, "label": newLabel})
+	}(time.Now())
+	return client.next.UpdateRow(ctx, rowType, rowID, newLabel)
+}
+
+func (client *Storer) UpdateChild(ctx context.Context, childType, childID, newChildLabel, parentType, newParentID string) (row storage.Row, err error) {
+	defer func(start time.Time) {
+		client.check(ctx, "UpdateChild", start, err, map[string]interface{}{
+			"type":        childType,
+			"id":          childID,
+			"label":       newChildLabel,
+			"parent_type": parentType,
+			"parent_id":   newParentID,
+		})
+	}(time.Now())
+	return client.next.UpdateChild(ctx, childType, childID, newChildLabel, parentType, newParentID)
+}
+
+func (client *Storer) UpdateColumn(ctx context.Context, rowType, rowID, columnName string, columnValue interface{}) (err error) {
+	defer func(start time.Time) {
+		client.check(ctx, "UpdateColumn", start, err, map[string]interface{}{"type": rowType, "id": rowID, "columns": []string{columnName}})
+	}(time.Now())
+	return client.next.UpdateColumn(ctx, rowType, rowID, columnName, columnValue)
+}
+
+func (client *Storer) PatchColumns(ctx context.Context, patches []storage.ColumnPatch) (err error) {
+	defer func(start time.Time) {
+		client.check(ctx, "PatchColumns", start, err, map[string]interface{}{"rows": len(patches)})
+	}(time.Now())
+	return storage.PatchColumns(ctx, client.next, patches)
+}
+
+func (client *Storer) UpdateColumns(ctx context.Context, rowType, rowID string, columns map[string]interface{}) (err error) {
+	defer func(start time.Time) {
+		client.check(ctx, "UpdateColumns", start, err, map[string]interface{}{"type": rowType, "id": rowID, "columns": columnNames(columns)})
+	}(time.Now())
+	return client.next.UpdateColumns(ctx, rowType, rowID, columns)
+}
+
+func (client *Storer) DeleteRow(ctx context.Context, rowType, childType, rowID string) (err error) {
+	defer func(start time.Time) {
+		client.check(ctx, "DeleteRow", start, err, map[string]interface{}{"type": rowType, "child_type": childType, "id": rowID})
+	}(time.Now())
+	return client.next.DeleteRow(ctx, rowType, childType, rowID)
+}
+
+func (client *Storer) PutRow(ctx context.Context, row storage.Row) (err error) {
+	defer func(start time.Time) {
+		client.check(ctx, "PutRow", start, err, map[string]interface{}{"type": row.Type(), "id": row.ID(), "columns": columnNames(row.Columns())})
+	}(time.Now())
+	return client.next.PutRow(ctx, row)
+}
+
+func (client *Storer) PutRows(ctx context.Context, rows []storage.Row) (err error) {
+	defer func(start time.Time) {
+		client.check(ctx, "PutRows", start, err, map[string]interface{}{"rows": len(rows)})
+	}(time.Now())
+	return storage.PutRows(ctx, client.next, rows)
+}
+
+// ScanRows's time includes the time fn takes.
+func (client *Storer) ScanRows(ctx context.Context, fn func(storage.Row) error) (err error) {
+	count := 0
+	defer func(start time.Time) {
+		client.check(ctx, "ScanRows", start, err, map[string]interface{}{"rows": count})
+	}(time.Now())
+	return client.next.ScanRows(ctx, func(row storage.Row) error {
+		count++
+		return fn(row)
+	})
+}
+
+// ScanChildren's time includes the time fn takes, as ScanRows's does.
+func (client *Storer) ScanChildren(ctx context.Context, parentID string, fn func(storage.Row) error) (err error) {
+	count := 0
+	defer func(start time.Time) {
+		client.check(ctx, "ScanChildren", start, err, map[string]interface{}{"parent_id": parentID, "rows": count})
+	}(time.Now())
+	return storage.ScanChildren(ctx, client.next, parentID, func(row storage.Row) error {
+		count++
+		return fn(row)
+	})
+}
+
+func (client *Storer) DeleteColumn(ctx context.Context, rowType, rowID, columnName string) (err error) {
+	defer func(start time.Time) {
+		client.check(ctx, "DeleteColumn", start, err, map[string]interface{}{"type": rowType, "id": rowID, "columns": []string{columnName}})
+	}(time.Now())
+	return storage.DeleteColumn(ctx, client.next, rowType, rowID, columnName)
+}
+
+// WithinTx warns of a slow transaction, and of its slow operations.
+func (client *Storer) WithinTx(ctx context.Context, fn func(tx storage.RowStorer) error) (err error) {
+	defer func(start time.Time) {
+		client.check(ctx, "WithinTx", start, err, map[string]interface{}{})
+	}(time.Now())
+	return storage.WithinTx(ctx, client.next, func(tx storage.RowStorer) error {
+		return fn(NewStorer(tx, client.threshold))
+	})
+}
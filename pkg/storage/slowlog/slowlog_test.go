@@ -0,0 +1,81 @@
+package slowlog_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflogtest"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/memory"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/slowlog"
+)
+
+// slow takes delay over each column update.
+type slow struct {
+	storage.RowStorer
+	delay time.Duration
+}
+
+func (client *slow) UpdateColumn(ctx context.Context, rowType, rowID, columnName string, columnValue interface{}) error {
+	time.Sleep(client.delay)
+	return client.RowStorer.UpdateColumn(ctx, rowType, rowID, columnName, columnValue)
+}
+
+// logged returns a context logging to a buffer, and a function returning the
+// warnings logged to it so far: the backend logs its operations, too.
+func logged(t *testing.T) (context.Context, func() []map[string]interface{}) {
+	t.Helper()
+	var buf bytes.Buffer
+	ctx := storage.NewLogSubsystems(tflogtest.RootLogger(context.Background(), &buf))
+	return ctx, func() []map[string]interface{} {
+		entries, err := tflogtest.MultilineJSONDecode(&buf)
+		if err != nil {
+			t.Fatalf("decoding the logs: %s", err)
+		}
+		var warnings []map[string]interface{}
+		for _, entry := range entries {
+			if entry["@level"] == "warn" {
+				warnings = append(warnings, entry)
+			}
+		}
+		return warnings
+	}
+}
+
+func TestWarnsOfSlow(t *testing.T) {
+	ctx, entries := logged(t)
+	backend := &slow{RowStorer: memory.NewClient(), delay: 50 * time.Millisecond}
+	storer := slowlog.NewStorer(backend, 20*time.Millisecond)
+	org, err := storer.CreateRow(ctx, "org", "acme")
+	if err != nil {
+		t.Fatalf("CreateRow: %s", err)
+	}
+	if got := entries(); len(got) != 0 {
+		t.Errorf("a fast CreateRow logged %v", got)
+	}
+
+	if err := storer.UpdateColumn(ctx, "org", org.ID(), "secret", "hunter2"); err != nil {
+		t.Fatalf("UpdateColumn: %s", err)
+	}
+	got := entries()
+	if len(got) != 1 {
+		t.Fatalf("a slow UpdateColumn logged %d warnings, not 1", len(got))
+	}
+	entry := got[0]
+	if entry["operation"] != "UpdateColumn" || entry["id"] != org.ID() {
+		t.Errorf("a slow UpdateColumn logged %v", entry)
+	}
+	if duration, _ := entry["duration_ms"].(float64); duration < 50 {
+		t.Errorf("a slow UpdateColumn logged it took %vms", entry["duration_ms"])
+	}
+	encoded, err := json.Marshal(entry)
+	if err != nil {
+		t.Fatalf("encoding the entry: %s", err)
+	}
+	if bytes.Contains(encoded, []byte("hunter2")) {
+		t.Errorf("a slow UpdateColumn logged the column's value: %s", encoded)
+	}
+}
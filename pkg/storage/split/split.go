@@ -0,0 +1,185 @@
+// Package split wraps two storage.RowStorers to read from one, such as a read
+// replica, and write to the other, so that refresh-heavy plans, which mostly
+// read, take their load off the primary store.
+package split
+
+import (
+	"context"
+	"errors"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+// Storer is a storage.RowStorer that reads from its reads storer and writes
+// to its writes storer. A replica may lag behind: rows it can't find by ID,
+// label, or parent are read from the writes storer too, so that a row just
+// created can be read back, but other reads may briefly see rows as they were.
+type Storer struct {
+	writes storage.RowStorer
+	reads  storage.RowStorer
+}
+
+var (
+	_ storage.RowStorer     = &Storer{}
+	_ storage.Transactor    = &Storer{}
+	_ storage.ColumnDeleter = &Storer{}
+	_ storage.BatchGetter   = &Storer{}
+	_ storage.ColumnQuerier = &Storer{}
+	_ storage.ChildScanner  = &Storer{}
+	_ storage.BatchPutter   = &Storer{}
+	_ storage.ColumnPatcher = &Storer{}
+	_ storage.RowIterator   = &Storer{}
+)
+
+// NewStorer writes to writes, and reads from reads.
+func NewStorer(writes, reads storage.RowStorer) *Storer {
+	return &Storer{writes: writes, reads: reads}
+}
+
+// Unwrap returns the storer that writes go to.
+func (client *Storer) Unwrap() storage.RowStorer {
+	return client.writes
+}
+
+// read calls fn with the reads storer, and again with the writes storer if
+// the row wasn't found.
+func (client *Storer) read(ctx context.Context, op string, fn func(storage.RowStorer) (storage.Row, error)) (storage.Row, error) {
+	row, err := fn(client.reads)
+	if !errors.Is(err, storage.ErrNotFoundRow) {
+		return row, err
+	}
+	tflog.SubsystemTrace(ctx, storage.LogSubsystemStorage, "Row not found by the reads backend, reading it from the writes backend", map[string]interface{}{
+		"operation": op,
+	})
+	return fn(client.writes)
+}
+
+func (client *Storer) GetRowByID(ctx context.Context, rowType, rowID string) (storage.Row, error) {
+	return client.read(ctx, "GetRowByID", func(storer storage.RowStorer) (storage.Row, error) {
+		return storer.GetRowByID(ctx, rowType, rowID)
+	})
+}
+
+// GetRowsByIDs reads the rows from the reads storer, and those it doesn't
+// have from the writes storer.
+func (client *Storer) GetRowsByIDs(ctx context.Context, rowType string, ids []string) ([]storage.Row, error) {
+	read, err := storage.GetRowsByIDs(ctx, client.reads, rowType, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]storage.Row, len(read))
+	for _, row := range read {
+		byID[row.ID()] = row
+	}
+	missing := []string{}
+	for _, id := range ids {
+		if _, ok := byID[id]; !ok {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) == 0 {
+		return read, nil
+	}
+	tflog.SubsystemTrace(ctx, storage.LogSubsystemStorage, "Rows not found by the reads backend, reading them from the writes backend", map[string]interface{}{
+		"operation": "GetRowsByIDs",
+		"rows":      len(missing),
+	})
+	written, err := storage.GetRowsByIDs(ctx, client.writes, rowType, missing)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range written {
+		byID[row.ID()] = row
+	}
+	rows := []storage.Row{}
+	for _, id := range ids {
+		if row, ok := byID[id]; ok {
+			rows = append(rows, row)
+			delete(byID, id)
+		}
+	}
+	return rows, nil
+}
+
+func (client *Storer) GetRow(ctx context.Context, rowType, rowLabel string) (storage.Row, error) {
+	return client.read(ctx, "GetRow", func(storer storage.RowStorer) (storage.Row, error) {
+		return storer.GetRow(ctx, rowType, rowLabel)
+	})
+}
+
+func (client *Storer) CreateRow(ctx context.Context, rowType, rowLabel string) (storage.Row, error) {
+	return client.writes.CreateRow(ctx, rowType, rowLabel)
+}
+
+func (client *Storer) CreateChild(ctx context.Context, rowType, rowLabel, parentType, parentID string, columns map[string]interface{}) (storage.Row, error) {
+	return client.writes.CreateChild(ctx, rowType, rowLabel, parentType, parentID, columns)
+}
+
+func (client *Storer) GetChild(ctx context.Context, childLabel, parentID string) (storage.Row, error) {
+	return client.read(ctx, "GetChild", func(storer storage.RowStorer) (storage.Row, error) {
+		return storer.GetChild(ctx, childLabel, parentID)
+	})
+}
+
+func (client *Storer) ListRows(ctx context.Context, rowType, labelFilter, parentIDFilter string) ([]storage.Row, error) {
+	return client.reads.ListRows(ctx, rowType, labelFilter, parentIDFilter)
+}
+
+func (client *Storer) IterRows(ctx context.Context, rowType, labelFilter, parentIDFilter string) *storage.Iter[storage.Row] {
+	return storage.IterRows(ctx, client.reads, rowType, labelFilter, parentIDFilter)
+}
+
+func (client *Storer) QueryByColumn(ctx context.Context, rowType, columnName string, value interface{}) ([]storage.Row, error) {
+	return storage.QueryByColumn(ctx, client.reads, rowType, columnName, value)
+}
+
+func (client *Storer) UpdateRow(ctx context.Context, rowType, rowID, newLabel string) (storage.Row, error) {
+	return client.writes.UpdateRow(ctx, rowType, rowID, newLabel)
+}
+
+func (client *Storer) UpdateChild(ctx context.Context, childType, childID, newChildLabel, parentType, newParentID string) (storage.Row, error) {
+	return client.writes.UpdateChild(ctx, childType, childID, newChildLabel, parentType, newParentID)
+}
+
+func (client *Storer) UpdateColumn(ctx context.Context, rowType, rowID, columnName string, columnValue interface{}) error {
+	return client.writes.UpdateColumn(ctx, rowType, rowID, columnName, columnValue)
+}
+
+func (client *Storer) PatchColumns(ctx context.Context, patches []storage.ColumnPatch) error {
+	return storage.PatchColumns(ctx, client.writes, patches)
+}
+
+func (client *Storer) UpdateColumns(ctx context.Context, rowType, rowID string, columns map[string]interface{}) error {
+	return client.writes.UpdateColumns(ctx, rowType, rowID, columns)
+}
+
+func (client *Storer) DeleteRow(ctx context.Context, rowType, childType, rowID string) error {
+	return client.writes.DeleteRow(ctx, rowType, childType, rowID)
+}
+
+func (client *Storer) PutRow(ctx context.Context, row storage.Row) error {
+	return client.writes.PutRow(ctx, row)
+}
+
+func (client *Storer) PutRows(ctx context.Context, rows []storage.Row) error {
+	return storage.PutRows(ctx, client.writes, rows)
+}
+
+func (client *Storer) ScanRows(ctx context.Context, fn func(storage.Row) error) error {
+	return client.reads.ScanRows(ctx, fn)
+}
+
+func (client *Storer) ScanChildren(ctx context.Context, parentID string, fn func(storage.Row) error) error {
+	return storage.ScanChildren(ctx, client.reads, parentID, fn)
+}
+
+func (client *Storer) DeleteColumn(ctx context.Context, rowType, rowID, columnName string) error {
+	return storage.DeleteColumn(ctx, client.writes, rowType, rowID, columnName)
+}
+
+// WithinTx makes the transaction with the writes storer, which its reads
+// use too, so that they see its writes.
+func (client *Storer) WithinTx(ctx context.Context, fn func(tx storage.RowStorer) error) error {
+	return storage.WithinTx(ctx, client.writes, fn)
+}
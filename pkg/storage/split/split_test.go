@@ -0,0 +1,97 @@
+package split_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/dataset"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/memory"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/split"
+)
+
+// replicated returns a primary and a replica that holds the org as it was,
+// without the primary's owner.
+func replicated(t *testing.T) (primary, replica storage.RowStorer, org storage.Row) {
+	t.Helper()
+	ctx := context.Background()
+	primary, replica = memory.NewClient(), memory.NewClient()
+	org, err := primary.CreateRow(ctx, "org", "acme")
+	if err != nil {
+		t.Fatalf("CreateRow: %s", err)
+	}
+	if err := replica.PutRow(ctx, org); err != nil {
+		t.Fatalf("PutRow: %s", err)
+	}
+	if err := primary.UpdateColumn(ctx, "org", org.ID(), "owner", "ops"); err != nil {
+		t.Fatalf("UpdateColumn: %s", err)
+	}
+	return primary, replica, org
+}
+
+func TestReadsFromReplica(t *testing.T) {
+	ctx := context.Background()
+	primary, replica, org := replicated(t)
+	storer := split.NewStorer(primary, replica)
+
+	got, err := storer.GetRowByID(ctx, "org", org.ID())
+	if err != nil {
+		t.Fatalf("GetRowByID: %s", err)
+	}
+	if got.Columns()["owner"] != nil {
+		t.Errorf("GetRowByID read the primary's row: %v", got.Columns())
+	}
+	rows, err := storer.ListRows(ctx, "org", "", "")
+	if err != nil {
+		t.Fatalf("ListRows: %s", err)
+	}
+	if len(rows) != 1 || rows[0].Columns()["owner"] != nil {
+		t.Errorf("ListRows didn't read the replica's rows: %v", rows)
+	}
+}
+
+func TestWritesToPrimary(t *testing.T) {
+	ctx := context.Background()
+	primary, replica, org := replicated(t)
+	storer := split.NewStorer(primary, replica)
+
+	team, err := storer.CreateChild(ctx, "team", "dev", "org", org.ID(), nil)
+	if err != nil {
+		t.Fatalf("CreateChild: %s", err)
+	}
+	if _, err := replica.GetRowByID(ctx, "team", team.ID()); err == nil {
+		t.Error("CreateChild wrote to the replica")
+	}
+	other := &dataset.Record{RowType: "org", RowID: "org-globex", RowLabel: "globex"}
+	if err := storer.PutRows(ctx, []storage.Row{other}); err != nil {
+		t.Fatalf("PutRows: %s", err)
+	}
+	if _, err := primary.GetRowByID(ctx, "org", other.ID()); err != nil {
+		t.Errorf("PutRows didn't write to the primary: %s", err)
+	}
+}
+
+func TestReadsLaggingRowsFromPrimary(t *testing.T) {
+	ctx := context.Background()
+	primary, replica, org := replicated(t)
+	storer := split.NewStorer(primary, replica)
+	team, err := storer.CreateChild(ctx, "team", "dev", "org", org.ID(), nil)
+	if err != nil {
+		t.Fatalf("CreateChild: %s", err)
+	}
+
+	// the replica doesn't have the team yet
+	if _, err := storer.GetRowByID(ctx, "team", team.ID()); err != nil {
+		t.Errorf("GetRowByID of a row the replica lacks: %s", err)
+	}
+	if _, err := storer.GetChild(ctx, "dev", org.ID()); err != nil {
+		t.Errorf("GetChild of a row the replica lacks: %s", err)
+	}
+	rows, err := storer.GetRowsByIDs(ctx, "team", []string{team.ID(), "team-missing"})
+	if err != nil {
+		t.Fatalf("GetRowsByIDs: %s", err)
+	}
+	if len(rows) != 1 || rows[0].ID() != team.ID() {
+		t.Errorf("GetRowsByIDs of a row the replica lacks returned %v", rows)
+	}
+}
@@ -0,0 +1,630 @@
+// Package sqlite implements storage.RowStorer in a SQLite database, a local
+// file or memory, so that the provider and schemactl can be run without a
+// network or an AWS account. Writes check the same uniqueness rules as the
+// other backends, in the transaction that makes them: a root row's label is
+// unique by type, and a child's by parent. The rules aren't unique indexes,
+// since PutRow doesn't keep them.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	_ "github.com/mattn/go-sqlite3" // registers the "sqlite3" database/sql driver
+	"github.com/spilliams/tree-terraform-provider/internal/slug"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+// Memory is the DSN of a database kept in memory, which is gone when the
+// client is closed.
+const Memory = ":memory:"
+
+// pageSize is how many rows a query reads at once, when listing or scanning
+// rows a page at a time.
+const pageSize = 1000
+
+// newIDAttempts is how many IDs CreateRow and CreateChild generate for a new
+// row before giving up on finding one that isn't in use.
+const newIDAttempts = 5
+
+type Client struct {
+	db *sql.DB
+	// tx is the transaction of a client given to WithinTx's fn, which its
+	// queries use rather than db
+	tx *sql.Tx
+}
+
+var (
+	_ storage.RowStorer          = &Client{}
+	_ storage.ChildScanner       = &Client{}
+	_ storage.ColumnPatcher      = &Client{}
+	_ storage.ColumnDeleter      = &Client{}
+	_ storage.RowIterator        = &Client{}
+	_ storage.ApproximateCounter = &Client{}
+	_ storage.Transactor         = &Client{}
+	_ storage.ColumnQuerier      = &Client{}
+)
+
+// NewClient opens the SQLite database at dsn, a file path, a "file:" URI, or
+// Memory, and keeps rows in its rows table, which it creates, with its
+// indexes, if it doesn't exist. The file is created if it doesn't exist.
+//
+// The client uses one connection, since SQLite writes one transaction at a
+// time and each connection to Memory opens a database of its own. Other
+// processes may use the file at once; their writes wait for each other, up
+// to SQLite's busy timeout.
+func NewClient(ctx context.Context, dsn string) (storage.RowStorer, error) {
+	if dsn == "" {
+		return nil, fmt.Errorf("%w: a database path is required", storage.ErrInvalid)
+	}
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", storage.ErrInvalid, err)
+	}
+	db.SetMaxOpenConns(1)
+	// an idle connection to Memory must be kept, or the rows go with it
+	db.SetConnMaxIdleTime(0)
+	db.SetConnMaxLifetime(0)
+	client := &Client{db: db}
+	if err := client.createTableIfNotExists(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return client, nil
+}
+
+// Close closes the database.
+func (client *Client) Close() error {
+	return client.db.Close()
+}
+
+// createTableIfNotExists creates the table and its indexes, those that don't
+// exist.
+//
+// Rows' parent IDs are empty for root rows, so that a root row's label is
+// checked among the rows of its type with an empty parent ID.
+func (client *Client) createTableIfNotExists(ctx context.Context) error {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemSQLite, "createTableIfNotExists")
+	statements := []string{
+		`CREATE TABLE IF NOT EXISTS rows (
+			type      TEXT NOT NULL,
+			id        TEXT NOT NULL,
+			label     TEXT NOT NULL,
+			parent_id TEXT NOT NULL DEFAULT '',
+			columns   TEXT NOT NULL DEFAULT '{}',
+			PRIMARY KEY (type, id)
+		) WITHOUT ROWID`,
+		// GetRow finds children by type and label too, as the other
+		// backends do
+		`CREATE INDEX IF NOT EXISTS rows_labels ON rows (type, label)`,
+		`CREATE INDEX IF NOT EXISTS rows_parent_labels ON rows (parent_id, label)`,
+	}
+
+	tx, err := client.db.BeginTx(ctx, nil)
+	if err != nil {
+		return kindError(err)
+	}
+	defer tx.Rollback()
+	for _, statement := range statements {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return kindError(err)
+		}
+	}
+	return kindError(tx.Commit())
+}
+
+func (client *Client) GetRowByID(ctx context.Context, rowType, id string) (storage.Row, error) {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemSQLite, fmt.Sprintf("GetRowByID %q", id))
+	r, err := client.getRowByID(ctx, client.conn(), rowType, id)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// querier is a *sql.DB or *sql.Tx.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// conn returns the client's transaction, if it has one, or its database.
+func (client *Client) conn() querier {
+	if client.tx != nil {
+		return client.tx
+	}
+	return client.db
+}
+
+// inTx calls fn with the client's transaction, if it has one, or with a new
+// one, which it commits if fn returns nil.
+func (client *Client) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	if client.tx != nil {
+		return fn(client.tx)
+	}
+	tx, err := client.db.BeginTx(ctx, nil)
+	if err != nil {
+		return kindError(err)
+	}
+	defer tx.Rollback()
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return kindError(tx.Commit())
+}
+
+// WithinTx calls fn with a client whose queries are made in one transaction.
+// The client has one connection, which the transaction holds until fn
+// returns, so fn must not use this client meanwhile, or it waits forever.
+// Within a transaction, WithinTx joins it.
+func (client *Client) WithinTx(ctx context.Context, fn func(tx storage.RowStorer) error) error {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemSQLite, "WithinTx")
+	return client.inTx(ctx, func(tx *sql.Tx) error {
+		return fn(&Client{db: client.db, tx: tx})
+	})
+}
+
+func (client *Client) getRowByID(ctx context.Context, db querier, rowType, id string) (*row, error) {
+	r, err := scanRow(db.QueryRowContext(ctx,
+		`SELECT `+rowColumns+` FROM rows WHERE type = ?1 AND id = ?2`,
+		rowType, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %q", storage.ErrNotFoundRow, id)
+	}
+	if err != nil {
+		return nil, kindError(err)
+	}
+	return r, nil
+}
+
+func (client *Client) GetRow(ctx context.Context, rowType, label string) (storage.Row, error) {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemSQLite, fmt.Sprintf("GetRow %q %q", rowType, label))
+	rows, err := client.conn().QueryContext(ctx,
+		`SELECT `+rowColumns+` FROM rows WHERE type = ?1 AND label = ?2 ORDER BY id LIMIT 2`,
+		rowType, label)
+	if err != nil {
+		return nil, kindError(err)
+	}
+	found, err := scanRows(rows)
+	if err != nil {
+		return nil, kindError(err)
+	}
+	if len(found) == 0 {
+		return nil, fmt.Errorf("%w: type %q and label %q", storage.ErrNotFoundRow, rowType, label)
+	}
+	if len(found) > 1 {
+		return nil, fmt.Errorf("%w: type %q and label %q", storage.ErrTooManyFound, rowType, label)
+	}
+	return found[0], nil
+}
+
+// CreateRow inserts the row, in a transaction that fails with
+// ErrCollisionTypeLabel if another root row of the type has the label.
+func (client *Client) CreateRow(ctx context.Context, rowType, label string) (storage.Row, error) {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemSQLite, fmt.Sprintf("CreateRow %q %q", rowType, label))
+	var r storage.Row
+	err := client.inTx(ctx, func(tx *sql.Tx) error {
+		if err := checkLabel(ctx, tx, rowType, "", label, ""); err != nil {
+			return err
+		}
+		var err error
+		r, err = client.insertNew(ctx, rowType, func(id string) *sql.Row {
+			return tx.QueryRowContext(ctx,
+				`INSERT INTO rows (type, id, label) VALUES (?1, ?2, ?3) RETURNING `+rowColumns,
+				rowType, id, label)
+		})
+		return err
+	})
+	return r, err
+}
+
+// CreateChild inserts the row on condition that its parent exists, in a
+// transaction that fails with ErrCollisionParentLabel if a sibling has the
+// label.
+func (client *Client) CreateChild(ctx context.Context, rowType, label, parentType, parentID string, columns map[string]interface{}) (storage.Row, error) {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemSQLite, fmt.Sprintf("CreateChild %q %q %q %q", rowType, label, parentType, parentID))
+	encoded, err := encodeColumns(columns)
+	if err != nil {
+		return nil, err
+	}
+	var r storage.Row
+	err = client.inTx(ctx, func(tx *sql.Tx) error {
+		if err := checkLabel(ctx, tx, rowType, "", label, parentID); err != nil {
+			return err
+		}
+		var err error
+		r, err = client.insertNew(ctx, rowType, func(id string) *sql.Row {
+			return tx.QueryRowContext(ctx,
+				`INSERT INTO rows (type, id, label, parent_id, columns)
+					SELECT ?1, ?2, ?3, ?4, ?5
+					WHERE EXISTS (SELECT 1 FROM rows WHERE type = ?6 AND id = ?4)
+					RETURNING `+rowColumns,
+				rowType, id, label, parentID, encoded, parentType)
+		})
+		return err
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %q", storage.ErrNotFoundRow, parentID)
+	}
+	return r, err
+}
+
+// checkLabel fails with a collision error if a row other than the one with
+// the type and ID has the label where a row of the type with the parent ID
+// would: among the root rows of the type, or among the parent's children.
+// Since PutRow may store rows whose labels other rows have, this is the only
+// check, so it must be made in the transaction of the write it's for.
+func checkLabel(ctx context.Context, db querier, rowType, id, label, parentID string) error {
+	var taken bool
+	if parentID == "" {
+		err := db.QueryRowContext(ctx,
+			`SELECT EXISTS (SELECT 1 FROM rows WHERE type = ?1 AND label = ?2 AND parent_id = '' AND id <> ?3)`,
+			rowType, label, id).Scan(&taken)
+		if err != nil {
+			return kindError(err)
+		}
+		if taken {
+			return fmt.Errorf("%w: type %q and label %q", storage.ErrCollisionTypeLabel, rowType, label)
+		}
+		return nil
+	}
+	err := db.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM rows WHERE parent_id = ?1 AND label = ?2 AND NOT (type = ?3 AND id = ?4))`,
+		parentID, label, rowType, id).Scan(&taken)
+	if err != nil {
+		return kindError(err)
+	}
+	if taken {
+		return fmt.Errorf("%w: parent ID %q and label %q", storage.ErrCollisionParentLabel, parentID, label)
+	}
+	return nil
+}
+
+// insertNew runs insert with a newly generated ID, and again with another if
+// a row already has it.
+func (client *Client) insertNew(ctx context.Context, rowType string, insert func(id string) *sql.Row) (storage.Row, error) {
+	for attempt := 0; attempt < newIDAttempts; attempt++ {
+		id := slug.Generate(rowType)
+		r, err := scanRow(insert(id))
+		if isIDCollision(err) {
+			continue
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		if err != nil {
+			return nil, kindError(err)
+		}
+		tflog.SubsystemTrace(ctx, storage.LogSubsystemSlug, fmt.Sprintf("Generated ID %q", id))
+		return r, nil
+	}
+	return nil, fmt.Errorf("%w: no unused ID for a new %s after %d attempts", storage.ErrConflict, rowType, newIDAttempts)
+}
+
+func (client *Client) GetChild(ctx context.Context, label, parentID string) (storage.Row, error) {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemSQLite, fmt.Sprintf("GetChild %q %q", label, parentID))
+	rows, err := client.conn().QueryContext(ctx,
+		`SELECT `+rowColumns+` FROM rows WHERE parent_id = ?1 AND parent_id <> '' AND label = ?2 ORDER BY type, id LIMIT 2`,
+		parentID, label)
+	if err != nil {
+		return nil, kindError(err)
+	}
+	found, err := scanRows(rows)
+	if err != nil {
+		return nil, kindError(err)
+	}
+	if len(found) == 0 {
+		return nil, fmt.Errorf("%w with parent ID %q and label %q", storage.ErrNotFoundRow, parentID, label)
+	}
+	if len(found) > 1 {
+		return nil, fmt.Errorf("%w: parent ID %q and label %q", storage.ErrTooManyFound, parentID, label)
+	}
+	return found[0], nil
+}
+
+func (client *Client) ListRows(ctx context.Context, rowType, labelFilter, parentIDFilter string) ([]storage.Row, error) {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemSQLite, fmt.Sprintf("ListRows %q %q %q", rowType, labelFilter, parentIDFilter))
+	return client.IterRows(ctx, rowType, labelFilter, parentIDFilter).All()
+}
+
+// IterRows returns an iterator of the rows ListRows would return, in order of
+// ID, reading a page of them at a time as it's advanced.
+func (client *Client) IterRows(ctx context.Context, rowType, labelFilter, parentIDFilter string) *storage.Iter[storage.Row] {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemSQLite, fmt.Sprintf("IterRows %q %q %q", rowType, labelFilter, parentIDFilter))
+	query := fmt.Sprintf(`SELECT %s FROM rows
+		WHERE type = ?1 AND (?2 = '' OR instr(label, ?2) > 0) AND (?3 = '' OR parent_id = ?3) AND id > ?4
+		ORDER BY id LIMIT %d`, rowColumns, pageSize)
+	after := ""
+	return storage.NewIter(ctx, func(ctx context.Context) ([]storage.Row, bool, error) {
+		rows, err := client.conn().QueryContext(ctx, query, rowType, labelFilter, parentIDFilter, after)
+		if err != nil {
+			return nil, false, kindError(err)
+		}
+		found, err := scanRows(rows)
+		if err != nil {
+			return nil, false, kindError(err)
+		}
+		page := make([]storage.Row, len(found))
+		for i, r := range found {
+			page[i] = r
+		}
+		if len(found) > 0 {
+			after = found[len(found)-1].RowID
+		}
+		return page, len(found) == pageSize, nil
+	})
+}
+
+// QueryByColumn has SQLite look into the rows' JSON columns, so that only
+// rows with the column, holding the value if it's a string, are read back.
+func (client *Client) QueryByColumn(ctx context.Context, rowType, columnName string, value interface{}) ([]storage.Row, error) {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemSQLite, fmt.Sprintf("QueryByColumn %q %q", rowType, columnName))
+	var want interface{}
+	if s, ok := value.(string); ok {
+		want = s
+	}
+	rows, err := client.conn().QueryContext(ctx,
+		`SELECT `+rowColumns+` FROM rows WHERE type = ?1 AND EXISTS (
+			SELECT 1 FROM json_each(rows.columns) WHERE key = ?2 AND (?3 IS NULL OR value = ?3)
+		) ORDER BY id`,
+		rowType, columnName, want)
+	if err != nil {
+		return nil, kindError(err)
+	}
+	found, err := scanRows(rows)
+	if err != nil {
+		return nil, kindError(err)
+	}
+	matched := []storage.Row{}
+	for _, r := range found {
+		if storage.ColumnEquals(r, columnName, value) {
+			matched = append(matched, r)
+		}
+	}
+	return matched, nil
+}
+
+// UpdateRow relabels the row, in a transaction that fails with a collision
+// error if another row has the label: a root row of the type, or a sibling.
+func (client *Client) UpdateRow(ctx context.Context, rowType, id, newLabel string) (storage.Row, error) {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemSQLite, fmt.Sprintf("UpdateRow %q %q %q", rowType, id, newLabel))
+	var r storage.Row
+	err := client.inTx(ctx, func(tx *sql.Tx) error {
+		current, err := client.getRowByID(ctx, tx, rowType, id)
+		if err != nil {
+			return err
+		}
+		if err := checkLabel(ctx, tx, rowType, id, newLabel, current.RowParentID); err != nil {
+			return err
+		}
+		r, err = scanRow(tx.QueryRowContext(ctx,
+			`UPDATE rows SET label = ?3 WHERE type = ?1 AND id = ?2 RETURNING `+rowColumns,
+			rowType, id, newLabel))
+		return kindError(err)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// UpdateChild relabels and moves the row on condition that its new parent
+// exists, in a transaction that fails with ErrCollisionParentLabel if one of
+// the new parent's children has the label.
+func (client *Client) UpdateChild(ctx context.Context, childType, childID, newChildLabel, parentType, newParentID string) (storage.Row, error) {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemSQLite, fmt.Sprintf("UpdateChild %q %q %q %q %q", childType, childID, newChildLabel, parentType, newParentID))
+	var r storage.Row
+	err := client.inTx(ctx, func(tx *sql.Tx) error {
+		if err := checkLabel(ctx, tx, childType, childID, newChildLabel, newParentID); err != nil {
+			return err
+		}
+		updated, err := scanRow(tx.QueryRowContext(ctx,
+			`UPDATE rows SET label = ?3, parent_id = ?4
+				WHERE type = ?1 AND id = ?2 AND EXISTS (SELECT 1 FROM rows WHERE type = ?5 AND id = ?4)
+				RETURNING `+rowColumns,
+			childType, childID, newChildLabel, newParentID, parentType))
+		if errors.Is(err, sql.ErrNoRows) {
+			// find which of them is missing
+			if _, err := client.getRowByID(ctx, tx, parentType, newParentID); err != nil {
+				return err
+			}
+			return fmt.Errorf("%w: %q", storage.ErrNotFoundRow, childID)
+		}
+		if err != nil {
+			return kindError(err)
+		}
+		r = updated
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (client *Client) UpdateColumn(ctx context.Context, rowType, rowID, columnName string, columnValue interface{}) error {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemSQLite, fmt.Sprintf("UpdateColumn %q %q %q %s", rowType, rowID, columnName, storage.LogColumnValue(rowType, columnName, columnValue)))
+	return client.patchColumns(ctx, []storage.ColumnPatch{{Type: rowType, ID: rowID, Columns: map[string]interface{}{columnName: columnValue}}})
+}
+
+func (client *Client) UpdateColumns(ctx context.Context, rowType, rowID string, columns map[string]interface{}) error {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemSQLite, fmt.Sprintf("UpdateColumns %q %q %s", rowType, rowID, storage.LogColumns(rowType, columns)))
+	encoded, err := encodeColumns(columns)
+	if err != nil {
+		return err
+	}
+	result, err := client.conn().ExecContext(ctx,
+		`UPDATE rows SET columns = ?3 WHERE type = ?1 AND id = ?2`,
+		rowType, rowID, encoded)
+	return updated(result, err, rowID)
+}
+
+// DeleteColumn reads the row's columns and writes them back without the
+// column, in one transaction.
+func (client *Client) DeleteColumn(ctx context.Context, rowType, rowID, columnName string) error {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemSQLite, fmt.Sprintf("DeleteColumn %q %q %q", rowType, rowID, columnName))
+	return client.inTx(ctx, func(tx *sql.Tx) error {
+		r, err := client.getRowByID(ctx, tx, rowType, rowID)
+		if err != nil {
+			return err
+		}
+		delete(r.RowColumns, columnName)
+		encoded, err := encodeColumns(r.RowColumns)
+		if err != nil {
+			return err
+		}
+		result, err := tx.ExecContext(ctx,
+			`UPDATE rows SET columns = ?3 WHERE type = ?1 AND id = ?2`,
+			rowType, rowID, encoded)
+		return updated(result, err, rowID)
+	})
+}
+
+// PatchColumns applies every patch in one transaction, or none of them if a
+// row is missing.
+func (client *Client) PatchColumns(ctx context.Context, patches []storage.ColumnPatch) error {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemSQLite, fmt.Sprintf("PatchColumns %d", len(patches)))
+	return client.patchColumns(ctx, patches)
+}
+
+// patchColumns sets some of the rows' columns, leaving their others as they
+// are. The columns are read and written back in one transaction.
+func (client *Client) patchColumns(ctx context.Context, patches []storage.ColumnPatch) error {
+	return client.inTx(ctx, func(tx *sql.Tx) error {
+		for _, patch := range patches {
+			r, err := client.getRowByID(ctx, tx, patch.Type, patch.ID)
+			if err != nil {
+				return err
+			}
+			for name, value := range patch.Columns {
+				r.RowColumns[name] = value
+			}
+			encoded, err := encodeColumns(r.RowColumns)
+			if err != nil {
+				return err
+			}
+			result, err := tx.ExecContext(ctx,
+				`UPDATE rows SET columns = ?3 WHERE type = ?1 AND id = ?2`,
+				patch.Type, patch.ID, encoded)
+			if err := updated(result, err, patch.ID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// updated returns the error of an update of the row with the ID, which is
+// ErrNotFoundRow if it updated nothing.
+func updated(result sql.Result, err error, rowID string) error {
+	if err != nil {
+		return kindError(err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return kindError(err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%w: %q", storage.ErrNotFoundRow, rowID)
+	}
+	return nil
+}
+
+// DeleteRow deletes the row on condition that it has no children of
+// childType, if that's set, in one statement.
+func (client *Client) DeleteRow(ctx context.Context, rowType, childType, id string) error {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemSQLite, fmt.Sprintf("DeleteRow %q %q %q", rowType, childType, id))
+	result, err := client.conn().ExecContext(ctx,
+		`DELETE FROM rows
+			WHERE type = ?1 AND id = ?2
+			AND (?3 = '' OR NOT EXISTS (SELECT 1 FROM rows WHERE type = ?3 AND parent_id = ?2))`,
+		rowType, id, childType)
+	err = updated(result, err, id)
+	if !errors.Is(err, storage.ErrNotFoundRow) {
+		return err
+	}
+	// find whether it's missing or has children
+	if _, err := client.GetRowByID(ctx, rowType, id); err != nil {
+		return err
+	}
+	return fmt.Errorf("%s %s has children: %w", rowType, id, storage.ErrCannotDeleteRow)
+}
+
+// PutRow stores the row as given, replacing any with its type and ID, whether
+// or not another row has its label.
+func (client *Client) PutRow(ctx context.Context, r storage.Row) error {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemSQLite, fmt.Sprintf("PutRow %q %q", r.Type(), r.ID()))
+	encoded, err := encodeColumns(r.Columns())
+	if err != nil {
+		return err
+	}
+	_, err = client.conn().ExecContext(ctx,
+		`INSERT INTO rows (type, id, label, parent_id, columns) VALUES (?1, ?2, ?3, ?4, ?5)
+			ON CONFLICT (type, id) DO UPDATE
+			SET label = excluded.label, parent_id = excluded.parent_id, columns = excluded.columns`,
+		r.Type(), r.ID(), r.Label(), r.ParentID(), encoded)
+	return kindError(err)
+}
+
+// ScanRows calls fn on every row, in order of type and ID, reading them a
+// page at a time, so that fn may use the client.
+func (client *Client) ScanRows(ctx context.Context, fn func(storage.Row) error) error {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemSQLite, "ScanRows")
+	return client.scanPages(ctx,
+		fmt.Sprintf(`SELECT %s FROM rows WHERE (type, id) > (?1, ?2) ORDER BY type, id LIMIT %d`, rowColumns, pageSize),
+		nil, fn)
+}
+
+// ScanChildren calls fn on each child of the row with parentID, reading them
+// a page at a time.
+func (client *Client) ScanChildren(ctx context.Context, parentID string, fn func(storage.Row) error) error {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemSQLite, fmt.Sprintf("ScanChildren %q", parentID))
+	return client.scanPages(ctx,
+		fmt.Sprintf(`SELECT %s FROM rows WHERE (type, id) > (?1, ?2) AND parent_id = ?3 AND parent_id <> '' ORDER BY type, id LIMIT %d`, rowColumns, pageSize),
+		[]interface{}{parentID}, fn)
+}
+
+// scanPages calls fn on the rows of a query ordered by type and ID, a page at
+// a time. The query's first two parameters are the type and ID of the last
+// row of the page before, and args are the rest.
+func (client *Client) scanPages(ctx context.Context, query string, args []interface{}, fn func(storage.Row) error) error {
+	afterType, afterID := "", ""
+	for {
+		rows, err := client.conn().QueryContext(ctx, query, append([]interface{}{afterType, afterID}, args...)...)
+		if err != nil {
+			return kindError(err)
+		}
+		// the page is read whole before fn is called, so that the
+		// connection is free for fn to use
+		found, err := scanRows(rows)
+		if err != nil {
+			return kindError(err)
+		}
+		for _, r := range found {
+			if err := fn(r); err != nil {
+				return err
+			}
+		}
+		if len(found) < pageSize {
+			return nil
+		}
+		last := found[len(found)-1]
+		afterType, afterID = last.RowType, last.RowID
+	}
+}
+
+// ApproximateCount counts the rows exactly, from the primary key, and returns
+// the size of the whole database, which is cheap to read. Local databases are
+// small enough that neither needs estimating.
+func (client *Client) ApproximateCount(ctx context.Context) (storage.ApproximateCount, error) {
+	tflog.SubsystemDebug(ctx, storage.LogSubsystemSQLite, "ApproximateCount")
+	var count storage.ApproximateCount
+	err := client.conn().QueryRowContext(ctx,
+		`SELECT (SELECT count(*) FROM rows), page_count * page_size FROM pragma_page_count(), pragma_page_size()`,
+	).Scan(&count.Rows, &count.Bytes)
+	return count, kindError(err)
+}
@@ -0,0 +1,21 @@
+package sqlite_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/sqlite"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/storagetest"
+)
+
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.RowStorer {
+		client, err := sqlite.NewClient(context.Background(), sqlite.Memory)
+		if err != nil {
+			t.Fatalf("NewClient: %s", err)
+		}
+		t.Cleanup(func() { client.(*sqlite.Client).Close() })
+		return client
+	})
+}
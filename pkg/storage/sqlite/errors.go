@@ -0,0 +1,45 @@
+package sqlite
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/mattn/go-sqlite3"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+// errorKinds maps SQLite's result codes to the kinds of storage error.
+var errorKinds = map[sqlite3.ErrNo]error{
+	sqlite3.ErrBusy:     storage.ErrConflict,
+	sqlite3.ErrLocked:   storage.ErrConflict,
+	sqlite3.ErrReadonly: storage.ErrReadOnly,
+	sqlite3.ErrPerm:     storage.ErrPermissionDenied,
+	sqlite3.ErrAuth:     storage.ErrPermissionDenied,
+	sqlite3.ErrCantOpen: storage.ErrPermissionDenied,
+	sqlite3.ErrTooBig:   storage.ErrInvalid,
+	sqlite3.ErrMismatch: storage.ErrInvalid,
+}
+
+// kindError wraps a SQLite error with its kind, so that callers can tell
+// errors apart with errors.Is. The SQLite error stays in the chain, for
+// errors.As.
+func kindError(err error) error {
+	var sqliteErr sqlite3.Error
+	if !errors.As(err, &sqliteErr) {
+		return err
+	}
+	if sqliteErr.Code == sqlite3.ErrConstraint {
+		return fmt.Errorf("%w: %w", storage.ErrConflict, err)
+	}
+	if kind, ok := errorKinds[sqliteErr.Code]; ok {
+		return fmt.Errorf("%w: %w", kind, err)
+	}
+	return err
+}
+
+// isIDCollision reports whether a write failed because a row with the type
+// and ID already exists.
+func isIDCollision(err error) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey
+}
@@ -0,0 +1,97 @@
+package sqlite
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+type row struct {
+	RowType     string
+	RowID       string
+	RowLabel    string
+	RowParentID string
+	RowColumns  map[string]interface{}
+}
+
+// rowColumns are the columns of the table a row is selected as, in the order
+// scanRow scans them.
+const rowColumns = "type, id, label, parent_id, columns"
+
+// scanner is a *sql.Row or *sql.Rows.
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanRow(s scanner) (*row, error) {
+	var r row
+	var encoded []byte
+	if err := s.Scan(&r.RowType, &r.RowID, &r.RowLabel, &r.RowParentID, &encoded); err != nil {
+		return nil, err
+	}
+	columns, err := decodeColumns(encoded)
+	if err != nil {
+		return nil, err
+	}
+	r.RowColumns = columns
+	return &r, nil
+}
+
+// scanRows returns the rows of a query, and closes them.
+func scanRows(rows *sql.Rows) ([]*row, error) {
+	defer rows.Close()
+	found := []*row{}
+	for rows.Next() {
+		r, err := scanRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		found = append(found, r)
+	}
+	return found, rows.Err()
+}
+
+// encodeColumns returns the JSON object a row's columns are stored as.
+func encodeColumns(columns map[string]interface{}) (string, error) {
+	if columns == nil {
+		columns = map[string]interface{}{}
+	}
+	b, err := json.Marshal(columns)
+	if err != nil {
+		return "", fmt.Errorf("%w: encoding columns: %s", storage.ErrInvalid, err)
+	}
+	return string(b), nil
+}
+
+// decodeColumns returns the columns of a stored JSON object, with JSON arrays
+// as string sets, the only kind of array rows store.
+func decodeColumns(encoded []byte) (map[string]interface{}, error) {
+	columns := map[string]interface{}{}
+	if err := json.Unmarshal(encoded, &columns); err != nil {
+		return nil, fmt.Errorf("decoding columns: %w", err)
+	}
+	for name, value := range columns {
+		list, ok := value.([]interface{})
+		if !ok {
+			continue
+		}
+		set := make([]string, 0, len(list))
+		for _, element := range list {
+			s, ok := element.(string)
+			if !ok {
+				return nil, fmt.Errorf("decoding columns: column %q isn't a set of strings", name)
+			}
+			set = append(set, s)
+		}
+		columns[name] = set
+	}
+	return columns, nil
+}
+
+func (r *row) Type() string                    { return r.RowType }
+func (r *row) ID() string                      { return r.RowID }
+func (r *row) Label() string                   { return r.RowLabel }
+func (r *row) ParentID() string                { return r.RowParentID }
+func (r *row) Columns() map[string]interface{} { return r.RowColumns }
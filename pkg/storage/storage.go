@@ -1,6 +1,50 @@
 package storage
 
-import "context"
+import (
+	"context"
+	"errors"
+)
+
+// The kinds of storage error. Every error a backend returns that isn't
+// unexpected is one of these kinds, by errors.Is, so that callers can handle
+// errors without knowing the backend. Backends may also return the more
+// specific errors below, or their own errors wrapped with a kind.
+var (
+	ErrNotFoundRow      = errors.New("row not found")
+	ErrConflict         = errors.New("conflict")
+	ErrThrottled        = errors.New("throttled")
+	ErrPermissionDenied = errors.New("permission denied")
+	ErrInvalid          = errors.New("invalid request")
+)
+
+var (
+	ErrCannotDeleteRow      = kindError(ErrConflict, "cannot delete row")
+	ErrCollisionParentLabel = kindError(ErrConflict, "a row with that parent and label already exists")
+	ErrCollisionTypeLabel   = kindError(ErrConflict, "a row with that type and label already exists")
+	ErrTooManyFound         = kindError(ErrConflict, "multiple exist where there must only be one")
+	// ErrReadOnly is a write refused because the storer, or the credentials
+	// it uses, may only read.
+	ErrReadOnly = kindError(ErrPermissionDenied, "read-only")
+	// ErrTooLarge is a write refused because a column or row would be larger
+	// than the storer allows.
+	ErrTooLarge = kindError(ErrInvalid, "too large")
+	// ErrTxTooLarge is a transaction refused because it writes more than
+	// the backend can write atomically.
+	ErrTxTooLarge = kindError(ErrInvalid, "transaction too large")
+	// ErrUnavailable is a request the backend couldn't serve for now, and
+	// didn't make, such as one a storage server refused while overloaded.
+	// Like throttling, it passes.
+	ErrUnavailable = kindError(ErrThrottled, "unavailable")
+	// ErrServerError is a request the backend failed with an error of its
+	// own, such as an HTTP 500 or DynamoDB's InternalServerError. It
+	// usually passes, like throttling, but unlike ErrUnavailable, the
+	// request may have been made.
+	ErrServerError = kindError(ErrThrottled, "server error")
+	// ErrCircuitOpen is a request a circuit breaker refused without making
+	// it, because the backend failed again and again just before. It passes
+	// once the backend recovers.
+	ErrCircuitOpen = kindError(ErrThrottled, "circuit breaker open")
+)
 
 type Row interface {
 	Type() string
@@ -22,4 +66,22 @@ type RowStorer interface {
 	UpdateColumn(ctx context.Context, rowType, rowID, columnName string, columnValue interface{}) error
 	UpdateColumns(ctx context.Context, rowType, rowID string, columns map[string]interface{}) error
 	DeleteRow(ctx context.Context, rowType, childType, rowID string) error
+	// PutRow stores the row as given, keeping its ID, and replaces any row
+	// with the same type and ID. It checks neither the parent nor the
+	// uniqueness of the label: it is for restoring and copying rows that
+	// were valid where they came from.
+	PutRow(ctx context.Context, row Row) error
+	// ScanRows calls fn with every stored row, of every type, in no
+	// particular order. It stops at the first error fn returns, and returns
+	// it.
+	ScanRows(ctx context.Context, fn func(Row) error) error
+}
+
+// Wrapper is implemented by storers that wrap another, such as the ones in
+// this package's subpackages and WithRetry's. Unwrap returns the wrapped
+// storer, so that helpers can reach the backend for what no wrapper changes,
+// as CountApproximately does. Optional interfaces whose calls a wrapper has
+// something to do with, such as Transactor, it forwards itself instead.
+type Wrapper interface {
+	Unwrap() RowStorer
 }
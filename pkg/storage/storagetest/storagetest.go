@@ -0,0 +1,929 @@
+// Package storagetest checks that a storage.RowStorer keeps the rules the
+// provider relies on, so that every backend, and every wrapper of one, keeps
+// the same semantics. Call Run from a backend's tests:
+//
+//	func TestConformance(t *testing.T) {
+//		storagetest.Run(t, func(t *testing.T) storage.RowStorer {
+//			return memory.NewClient()
+//		})
+//	}
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+const (
+	// manyRows is how many rows the tests of listing and scanning store.
+	// With their padding, they're more than a megabyte, DynamoDB's most in
+	// one page, so that backends that page through results must.
+	manyRows = 300
+	// paddingBytes is the size of the column padding each of manyRows.
+	paddingBytes = 4 * 1024
+	// racers is how many goroutines the tests of concurrent writes run.
+	racers = 8
+	// wrapperColumnPrefix starts the names of the columns wrappers keep of
+	// their own.
+	wrapperColumnPrefix = "__"
+)
+
+// Run runs the conformance tests, each as a subtest with a storer of its own,
+// which newStorer returns empty. The subtests don't run in parallel, so
+// newStorer may call t.Setenv.
+func Run(t *testing.T, newStorer func(t *testing.T) storage.RowStorer) {
+	tests := []struct {
+		name string
+		test func(*testing.T, storage.RowStorer)
+	}{
+		{"CreateRow", testCreateRow},
+		{"TypeLabelUnique", testTypeLabelUnique},
+		{"CreateChild", testCreateChild},
+		{"ParentLabelUnique", testParentLabelUnique},
+		{"UpdateRow", testUpdateRow},
+		{"UpdateChild", testUpdateChild},
+		{"MoveSubtree", testMoveSubtree},
+		{"Columns", testColumns},
+		{"MissingRows", testMissingRows},
+		{"DeleteRow", testDeleteRow},
+		{"PutRow", testPutRow},
+		{"ListRows", testListRows},
+		{"ListChildren", testListChildren},
+		{"GetRowsByIDs", testGetRowsByIDs},
+		{"QueryByColumn", testQueryByColumn},
+		{"ScanRows", testScanRows},
+		{"ConcurrentCreates", testConcurrentCreates},
+		{"ConcurrentLabelClaims", testConcurrentLabelClaims},
+		{"WithinTx", testWithinTx},
+		{"WithinTxConflict", testWithinTxConflict},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.test(t, newStorer(t))
+		})
+	}
+}
+
+func testCreateRow(t *testing.T, storer storage.RowStorer) {
+	ctx := context.Background()
+	created := createRow(t, storer, "org", "acme")
+	if created.ID() == "" {
+		t.Fatal("CreateRow returned a row without an ID")
+	}
+	checkRow(t, "CreateRow", created, "org", "acme", "")
+
+	byID, err := storer.GetRowByID(ctx, "org", created.ID())
+	if err != nil {
+		t.Fatalf("GetRowByID: %s", err)
+	}
+	checkRow(t, "GetRowByID", byID, "org", "acme", "")
+	if byID.ID() != created.ID() {
+		t.Errorf("GetRowByID returned ID %q, not %q", byID.ID(), created.ID())
+	}
+
+	byLabel, err := storer.GetRow(ctx, "org", "acme")
+	if err != nil {
+		t.Fatalf("GetRow: %s", err)
+	}
+	if byLabel.ID() != created.ID() {
+		t.Errorf("GetRow returned ID %q, not %q", byLabel.ID(), created.ID())
+	}
+
+	// IDs are unique to a row
+	other := createRow(t, storer, "org", "globex")
+	if other.ID() == created.ID() {
+		t.Errorf("two rows were both given ID %q", created.ID())
+	}
+}
+
+func testTypeLabelUnique(t *testing.T, storer storage.RowStorer) {
+	ctx := context.Background()
+	createRow(t, storer, "org", "acme")
+	_, err := storer.CreateRow(ctx, "org", "acme")
+	checkErr(t, "CreateRow of a taken label", err, storage.ErrCollisionTypeLabel)
+
+	// labels are unique by type
+	createRow(t, storer, "team", "acme")
+}
+
+func testCreateChild(t *testing.T, storer storage.RowStorer) {
+	ctx := context.Background()
+	parent := createRow(t, storer, "org", "acme")
+	columns := map[string]interface{}{"owner": "ops", "tags": []string{"a", "b"}}
+	child, err := storer.CreateChild(ctx, "team", "infra", "org", parent.ID(), columns)
+	if err != nil {
+		t.Fatalf("CreateChild: %s", err)
+	}
+	checkRow(t, "CreateChild", child, "team", "infra", parent.ID())
+	checkColumns(t, "CreateChild", child, columns)
+
+	// the storer keeps its own copy of the columns
+	columns["owner"] = "changed"
+	found, err := storer.GetChild(ctx, "infra", parent.ID())
+	if err != nil {
+		t.Fatalf("GetChild: %s", err)
+	}
+	if found.ID() != child.ID() {
+		t.Errorf("GetChild returned ID %q, not %q", found.ID(), child.ID())
+	}
+	checkColumns(t, "GetChild", found, map[string]interface{}{"owner": "ops", "tags": []string{"a", "b"}})
+
+	_, err = storer.CreateChild(ctx, "team", "web", "org", "missing", nil)
+	checkErr(t, "CreateChild under a missing parent", err, storage.ErrNotFoundRow)
+}
+
+func testParentLabelUnique(t *testing.T, storer storage.RowStorer) {
+	ctx := context.Background()
+	acme := createRow(t, storer, "org", "acme")
+	globex := createRow(t, storer, "org", "globex")
+	createChild(t, storer, "team", "infra", "org", acme.ID())
+	_, err := storer.CreateChild(ctx, "team", "infra", "org", acme.ID(), nil)
+	checkErr(t, "CreateChild of a taken label", err, storage.ErrCollisionParentLabel)
+	// even of another type
+	_, err = storer.CreateChild(ctx, "project", "infra", "org", acme.ID(), nil)
+	checkErr(t, "CreateChild of a label a child of another type has", err, storage.ErrCollisionParentLabel)
+
+	// labels are unique by parent
+	createChild(t, storer, "team", "infra", "org", globex.ID())
+}
+
+func testUpdateRow(t *testing.T, storer storage.RowStorer) {
+	ctx := context.Background()
+	acme := createRow(t, storer, "org", "acme")
+	createRow(t, storer, "org", "globex")
+
+	updated, err := storer.UpdateRow(ctx, "org", acme.ID(), "initech")
+	if err != nil {
+		t.Fatalf("UpdateRow: %s", err)
+	}
+	checkRow(t, "UpdateRow", updated, "org", "initech", "")
+	if updated.ID() != acme.ID() {
+		t.Errorf("UpdateRow changed the ID from %q to %q", acme.ID(), updated.ID())
+	}
+	if _, err := storer.GetRow(ctx, "org", "initech"); err != nil {
+		t.Errorf("GetRow of the new label: %s", err)
+	}
+
+	_, err = storer.UpdateRow(ctx, "org", acme.ID(), "globex")
+	checkErr(t, "UpdateRow to a taken label", err, storage.ErrCollisionTypeLabel)
+
+	// the old label is free again
+	createRow(t, storer, "org", "acme")
+
+	// relabeling a row to its own label changes nothing
+	if _, err := storer.UpdateRow(ctx, "org", acme.ID(), "initech"); err != nil {
+		t.Errorf("UpdateRow to the same label: %s", err)
+	}
+
+	parent := createRow(t, storer, "company", "umbrella")
+	child := createChild(t, storer, "team", "infra", "company", parent.ID())
+	createChild(t, storer, "team", "web", "company", parent.ID())
+	_, err = storer.UpdateRow(ctx, "team", child.ID(), "web")
+	checkErr(t, "UpdateRow of a child to a sibling's label", err, storage.ErrCollisionParentLabel)
+	updated, err = storer.UpdateRow(ctx, "team", child.ID(), "platform")
+	if err != nil {
+		t.Fatalf("UpdateRow of a child: %s", err)
+	}
+	checkRow(t, "UpdateRow of a child", updated, "team", "platform", parent.ID())
+}
+
+func testUpdateChild(t *testing.T, storer storage.RowStorer) {
+	ctx := context.Background()
+	acme := createRow(t, storer, "org", "acme")
+	globex := createRow(t, storer, "org", "globex")
+	child := createChild(t, storer, "team", "infra", "org", acme.ID())
+	createChild(t, storer, "team", "web", "org", globex.ID())
+
+	_, err := storer.UpdateChild(ctx, "team", child.ID(), "web", "org", globex.ID())
+	checkErr(t, "UpdateChild to a label taken under the new parent", err, storage.ErrCollisionParentLabel)
+
+	moved, err := storer.UpdateChild(ctx, "team", child.ID(), "platform", "org", globex.ID())
+	if err != nil {
+		t.Fatalf("UpdateChild: %s", err)
+	}
+	checkRow(t, "UpdateChild", moved, "team", "platform", globex.ID())
+	if _, err := storer.GetChild(ctx, "platform", globex.ID()); err != nil {
+		t.Errorf("GetChild under the new parent: %s", err)
+	}
+	_, err = storer.GetChild(ctx, "infra", acme.ID())
+	checkErr(t, "GetChild under the old parent", err, storage.ErrNotFoundRow)
+
+	_, err = storer.UpdateChild(ctx, "team", child.ID(), "platform", "org", "missing")
+	checkErr(t, "UpdateChild under a missing parent", err, storage.ErrNotFoundRow)
+}
+
+func testMoveSubtree(t *testing.T, storer storage.RowStorer) {
+	ctx := context.Background()
+	acme := createRow(t, storer, "org", "acme")
+	globex := createRow(t, storer, "org", "globex")
+	infra := createChild(t, storer, "team", "infra", "org", acme.ID())
+	prod := createChild(t, storer, "env", "prod", "team", infra.ID())
+	web := createChild(t, storer, "team", "web", "org", acme.ID())
+	createChild(t, storer, "team", "web", "org", globex.ID())
+
+	moved, err := storage.MoveSubtree(ctx, storer, "team", infra.ID(), "org", globex.ID())
+	if err != nil {
+		t.Fatalf("MoveSubtree: %s", err)
+	}
+	checkRow(t, "MoveSubtree", moved, "team", "infra", globex.ID())
+	if _, err := storer.GetChild(ctx, "infra", globex.ID()); err != nil {
+		t.Errorf("GetChild under the new parent: %s", err)
+	}
+	checkRow(t, "GetRowByID of a moved row's child", getRowByID(t, storer, "env", prod.ID()), "env", "prod", infra.ID())
+
+	_, err = storage.MoveSubtree(ctx, storer, "team", web.ID(), "org", globex.ID())
+	checkErr(t, "MoveSubtree to a parent with a child of the label", err, storage.ErrCollisionParentLabel)
+	checkRow(t, "GetRowByID of a row not moved", getRowByID(t, storer, "team", web.ID()), "team", "web", acme.ID())
+
+	_, err = storage.MoveSubtree(ctx, storer, "org", globex.ID(), "env", prod.ID())
+	checkErr(t, "MoveSubtree under a descendant", err, storage.ErrInvalid)
+	_, err = storage.MoveSubtree(ctx, storer, "team", infra.ID(), "org", "missing")
+	checkErr(t, "MoveSubtree under a missing parent", err, storage.ErrNotFoundRow)
+	_, err = storage.MoveSubtree(ctx, storer, "team", "missing", "org", acme.ID())
+	checkErr(t, "MoveSubtree of a missing row", err, storage.ErrNotFoundRow)
+}
+
+func testColumns(t *testing.T, storer storage.RowStorer) {
+	ctx := context.Background()
+	parent := createRow(t, storer, "org", "acme")
+	child, err := storer.CreateChild(ctx, "team", "infra", "org", parent.ID(), map[string]interface{}{"owner": "ops"})
+	if err != nil {
+		t.Fatalf("CreateChild: %s", err)
+	}
+
+	if err := storer.UpdateColumn(ctx, "team", child.ID(), "tags", []string{"x"}); err != nil {
+		t.Fatalf("UpdateColumn: %s", err)
+	}
+	checkColumns(t, "UpdateColumn", getRowByID(t, storer, "team", child.ID()), map[string]interface{}{"owner": "ops", "tags": []string{"x"}})
+
+	if err := storer.UpdateColumns(ctx, "team", child.ID(), map[string]interface{}{"oncall": "sre"}); err != nil {
+		t.Fatalf("UpdateColumns: %s", err)
+	}
+	checkColumns(t, "UpdateColumns", getRowByID(t, storer, "team", child.ID()), map[string]interface{}{"oncall": "sre"})
+
+	patches := []storage.ColumnPatch{{Type: "team", ID: child.ID(), Columns: map[string]interface{}{"owner": "dev"}}}
+	if err := storage.PatchColumns(ctx, storer, patches); err != nil {
+		t.Fatalf("PatchColumns: %s", err)
+	}
+	checkColumns(t, "PatchColumns", getRowByID(t, storer, "team", child.ID()), map[string]interface{}{"oncall": "sre", "owner": "dev"})
+
+	// columns read back can be changed without changing the stored ones
+	read := getRowByID(t, storer, "team", child.ID())
+	read.Columns()["owner"] = "changed"
+	checkColumns(t, "GetRowByID", getRowByID(t, storer, "team", child.ID()), map[string]interface{}{"oncall": "sre", "owner": "dev"})
+
+	// rows made by CreateRow start without columns
+	root := createRow(t, storer, "org", "globex")
+	if err := storer.UpdateColumn(ctx, "org", root.ID(), "owner", "ops"); err != nil {
+		t.Fatalf("UpdateColumn of a root row: %s", err)
+	}
+	checkColumns(t, "UpdateColumn of a root row", getRowByID(t, storer, "org", root.ID()), map[string]interface{}{"owner": "ops"})
+	other := createRow(t, storer, "org", "initech")
+	if err := storer.UpdateColumns(ctx, "org", other.ID(), map[string]interface{}{"owner": "dev"}); err != nil {
+		t.Fatalf("UpdateColumns of a root row: %s", err)
+	}
+	checkColumns(t, "UpdateColumns of a root row", getRowByID(t, storer, "org", other.ID()), map[string]interface{}{"owner": "dev"})
+	patched := createRow(t, storer, "org", "umbrella")
+	patches = []storage.ColumnPatch{
+		{Type: "org", ID: patched.ID(), Columns: map[string]interface{}{"owner": "sre"}},
+		{Type: "org", ID: root.ID(), Columns: map[string]interface{}{"tier": "gold"}},
+	}
+	if err := storage.PatchColumns(ctx, storer, patches); err != nil {
+		t.Fatalf("PatchColumns of root rows: %s", err)
+	}
+	checkColumns(t, "PatchColumns of a root row", getRowByID(t, storer, "org", patched.ID()), map[string]interface{}{"owner": "sre"})
+	checkColumns(t, "PatchColumns of a root row", getRowByID(t, storer, "org", root.ID()), map[string]interface{}{"owner": "ops", "tier": "gold"})
+
+	if err := storage.DeleteColumn(ctx, storer, "team", child.ID(), "oncall"); err != nil {
+		t.Fatalf("DeleteColumn: %s", err)
+	}
+	checkColumns(t, "DeleteColumn", getRowByID(t, storer, "team", child.ID()), map[string]interface{}{"owner": "dev"})
+	// removing it again removes nothing
+	if err := storage.DeleteColumn(ctx, storer, "team", child.ID(), "oncall"); err != nil {
+		t.Fatalf("DeleteColumn of a column the row doesn't have: %s", err)
+	}
+	if err := storage.DeleteColumn(ctx, storer, "org", parent.ID(), "owner"); err != nil {
+		t.Fatalf("DeleteColumn of a row without columns: %s", err)
+	}
+	err = storage.DeleteColumn(ctx, storer, "team", "missing", "owner")
+	checkErr(t, "DeleteColumn of a missing row", err, storage.ErrNotFoundRow)
+}
+
+// testMissingRows checks the errors of reads and writes of rows that don't
+// exist. Writes may fail with storage.ErrConflict instead of
+// storage.ErrNotFoundRow, since a backend that checks that a row exists with
+// a condition of the write can't tell a row that never existed from one
+// deleted concurrently.
+func testMissingRows(t *testing.T, storer storage.RowStorer) {
+	ctx := context.Background()
+	parent := createRow(t, storer, "org", "acme")
+
+	_, err := storer.GetRowByID(ctx, "org", "missing")
+	checkErr(t, "GetRowByID", err, storage.ErrNotFoundRow)
+	_, err = storer.GetRow(ctx, "org", "missing")
+	checkErr(t, "GetRow", err, storage.ErrNotFoundRow)
+	_, err = storer.GetChild(ctx, "missing", parent.ID())
+	checkErr(t, "GetChild", err, storage.ErrNotFoundRow)
+	// a row of another type with the ID isn't the row
+	_, err = storer.GetRowByID(ctx, "team", parent.ID())
+	checkErr(t, "GetRowByID of another type", err, storage.ErrNotFoundRow)
+
+	_, err = storer.UpdateRow(ctx, "org", "missing", "globex")
+	checkErr(t, "UpdateRow", err, storage.ErrNotFoundRow, storage.ErrConflict)
+	checkErr(t, "UpdateColumn", storer.UpdateColumn(ctx, "org", "missing", "owner", "ops"), storage.ErrNotFoundRow, storage.ErrConflict)
+	checkErr(t, "UpdateColumns", storer.UpdateColumns(ctx, "org", "missing", map[string]interface{}{"owner": "ops"}), storage.ErrNotFoundRow, storage.ErrConflict)
+	checkErr(t, "DeleteRow", storer.DeleteRow(ctx, "org", "", "missing"), storage.ErrNotFoundRow, storage.ErrConflict)
+}
+
+func testDeleteRow(t *testing.T, storer storage.RowStorer) {
+	ctx := context.Background()
+	parent := createRow(t, storer, "org", "acme")
+	child := createChild(t, storer, "team", "infra", "org", parent.ID())
+
+	checkErr(t, "DeleteRow of a row with children", storer.DeleteRow(ctx, "org", "team", parent.ID()), storage.ErrCannotDeleteRow)
+
+	if err := storer.DeleteRow(ctx, "team", "", child.ID()); err != nil {
+		t.Fatalf("DeleteRow of the child: %s", err)
+	}
+	_, err := storer.GetRowByID(ctx, "team", child.ID())
+	checkErr(t, "GetRowByID of the deleted child", err, storage.ErrNotFoundRow)
+	_, err = storer.GetChild(ctx, "infra", parent.ID())
+	checkErr(t, "GetChild of the deleted child", err, storage.ErrNotFoundRow)
+
+	if err := storer.DeleteRow(ctx, "org", "team", parent.ID()); err != nil {
+		t.Fatalf("DeleteRow of the parent: %s", err)
+	}
+	// its label is free again
+	createRow(t, storer, "org", "acme")
+}
+
+func testPutRow(t *testing.T, storer storage.RowStorer) {
+	ctx := context.Background()
+	put := &row{RowType: "org", RowID: "org-put", RowLabel: "acme", RowColumns: map[string]interface{}{"owner": "ops"}}
+	if err := storer.PutRow(ctx, put); err != nil {
+		t.Fatalf("PutRow: %s", err)
+	}
+	found := getRowByID(t, storer, "org", "org-put")
+	checkRow(t, "PutRow", found, "org", "acme", "")
+	checkColumns(t, "PutRow", found, put.RowColumns)
+
+	// a row with the same ID is replaced
+	replacement := &row{RowType: "org", RowID: "org-put", RowLabel: "globex"}
+	if err := storer.PutRow(ctx, replacement); err != nil {
+		t.Fatalf("PutRow of a replacement: %s", err)
+	}
+	found = getRowByID(t, storer, "org", "org-put")
+	checkRow(t, "PutRow of a replacement", found, "org", "globex", "")
+	checkColumns(t, "PutRow of a replacement", found, nil)
+
+	child := &row{RowType: "team", RowID: "team-put", RowLabel: "infra", RowParentID: "org-put"}
+	if err := storer.PutRow(ctx, child); err != nil {
+		t.Fatalf("PutRow of a child: %s", err)
+	}
+	found, err := storer.GetChild(ctx, "infra", "org-put")
+	if err != nil {
+		t.Fatalf("GetChild of a put child: %s", err)
+	}
+	checkRow(t, "PutRow of a child", found, "team", "infra", "org-put")
+
+	// rows are stored as given, even with a label another row has, as
+	// restores and copies may store them on the way to a relabel
+	taken := createRow(t, storer, "org", "initech")
+	twin := &row{RowType: "org", RowID: "org-twin", RowLabel: "initech"}
+	if err := storer.PutRow(ctx, twin); err != nil {
+		t.Fatalf("PutRow of a taken label: %s", err)
+	}
+	checkRow(t, "PutRow of a taken label", getRowByID(t, storer, "org", "org-twin"), "org", "initech", "")
+	checkRow(t, "PutRow of a taken label", getRowByID(t, storer, "org", taken.ID()), "org", "initech", "")
+	sibling := &row{RowType: "team", RowID: "team-twin", RowLabel: "infra", RowParentID: "org-put"}
+	if err := storer.PutRow(ctx, sibling); err != nil {
+		t.Fatalf("PutRow of a sibling's label: %s", err)
+	}
+	checkRow(t, "PutRow of a sibling's label", getRowByID(t, storer, "team", "team-twin"), "team", "infra", "org-put")
+
+	// but the labels stay taken for the writes that check them
+	_, err = storer.CreateRow(ctx, "org", "initech")
+	checkErr(t, "CreateRow of a label a put row has", err, storage.ErrCollisionTypeLabel)
+	_, err = storer.CreateChild(ctx, "team", "infra", "org", "org-put", nil)
+	checkErr(t, "CreateChild of a label a put row has", err, storage.ErrCollisionParentLabel)
+}
+
+func testListRows(t *testing.T, storer storage.RowStorer) {
+	ctx := context.Background()
+	acme := createRow(t, storer, "org", "acme")
+	globex := createRow(t, storer, "org", "globex")
+	want := map[string]storage.Row{}
+	for i := 0; i < manyRows; i++ {
+		parent := acme
+		if i%2 == 1 {
+			parent = globex
+		}
+		child := createPaddedChild(t, storer, fmt.Sprintf("team-%03d", i), parent.ID())
+		want[child.ID()] = child
+	}
+	createRow(t, storer, "project", "team-000")
+
+	rows, err := storer.ListRows(ctx, "team", "", "")
+	if err != nil {
+		t.Fatalf("ListRows: %s", err)
+	}
+	checkListed(t, "ListRows", rows, want, func(storage.Row) bool { return true })
+
+	rows, err = storer.ListRows(ctx, "team", "-01", "")
+	if err != nil {
+		t.Fatalf("ListRows with a label filter: %s", err)
+	}
+	checkListed(t, "ListRows with a label filter", rows, want, func(r storage.Row) bool { return strings.Contains(r.Label(), "-01") })
+
+	rows, err = storer.ListRows(ctx, "team", "", globex.ID())
+	if err != nil {
+		t.Fatalf("ListRows with a parent filter: %s", err)
+	}
+	checkListed(t, "ListRows with a parent filter", rows, want, func(r storage.Row) bool { return r.ParentID() == globex.ID() })
+
+	rows, err = storage.IterRows(ctx, storer, "team", "", acme.ID()).All()
+	if err != nil {
+		t.Fatalf("IterRows: %s", err)
+	}
+	checkListed(t, "IterRows", rows, want, func(r storage.Row) bool { return r.ParentID() == acme.ID() })
+
+	rows, err = storer.ListRows(ctx, "missing", "", "")
+	if err != nil {
+		t.Fatalf("ListRows of a type without rows: %s", err)
+	}
+	if len(rows) > 0 {
+		t.Errorf("ListRows of a type without rows listed %d", len(rows))
+	}
+}
+
+func testListChildren(t *testing.T, storer storage.RowStorer) {
+	ctx := context.Background()
+	acme := createRow(t, storer, "org", "acme")
+	globex := createRow(t, storer, "org", "globex")
+	want := map[string]storage.Row{}
+	for i, parent := range []storage.Row{acme, globex, acme} {
+		child := createChild(t, storer, "team", fmt.Sprintf("team-%d", i), "org", parent.ID())
+		want[child.ID()] = child
+	}
+	// children of another type aren't listed
+	project := createChild(t, storer, "project", "team-0", "org", globex.ID())
+
+	rows, err := storage.ListChildren(ctx, storer, "team", acme.ID())
+	if err != nil {
+		t.Fatalf("ListChildren: %s", err)
+	}
+	checkListed(t, "ListChildren", rows, want, func(r storage.Row) bool { return r.ParentID() == acme.ID() })
+
+	rows, err = storage.ListChildren(ctx, storer, "team", project.ID())
+	if err != nil {
+		t.Fatalf("ListChildren of a row without children: %s", err)
+	}
+	if len(rows) > 0 {
+		t.Errorf("ListChildren of a row without children listed %d", len(rows))
+	}
+}
+
+func testGetRowsByIDs(t *testing.T, storer storage.RowStorer) {
+	ctx := context.Background()
+	acme := createRow(t, storer, "org", "acme")
+	a := createChild(t, storer, "team", "a", "org", acme.ID())
+	b := createChild(t, storer, "team", "b", "org", acme.ID())
+	createChild(t, storer, "team", "c", "org", acme.ID())
+
+	rows, err := storage.GetRowsByIDs(ctx, storer, "team", []string{b.ID(), "missing", a.ID(), b.ID(), acme.ID()})
+	if err != nil {
+		t.Fatalf("GetRowsByIDs: %s", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("GetRowsByIDs returned %d rows, want 2", len(rows))
+	}
+	checkRow(t, "GetRowsByIDs", rows[0], "team", "b", acme.ID())
+	checkRow(t, "GetRowsByIDs", rows[1], "team", "a", acme.ID())
+
+	rows, err = storage.GetRowsByIDs(ctx, storer, "team", nil)
+	if err != nil {
+		t.Fatalf("GetRowsByIDs of no IDs: %s", err)
+	}
+	if len(rows) > 0 {
+		t.Errorf("GetRowsByIDs of no IDs returned %d rows", len(rows))
+	}
+}
+
+func testQueryByColumn(t *testing.T, storer storage.RowStorer) {
+	ctx := context.Background()
+	acme := createRow(t, storer, "org", "acme")
+	want := map[string]storage.Row{}
+	for i, columns := range []map[string]interface{}{
+		{"tier": "gold", "tags": []string{"a", "b"}},
+		{"tier": "gold"},
+		{"tier": "silver", "tags": []string{"b"}},
+		{"owner": "gold"},
+		nil,
+	} {
+		r, err := storer.CreateChild(ctx, "team", fmt.Sprintf("team-%d", i), "org", acme.ID(), columns)
+		if err != nil {
+			t.Fatalf("CreateChild team-%d: %s", i, err)
+		}
+		want[r.ID()] = r
+	}
+	if _, err := storer.CreateChild(ctx, "project", "project", "org", acme.ID(), map[string]interface{}{"tier": "gold"}); err != nil {
+		t.Fatalf("CreateChild project: %s", err)
+	}
+
+	rows, err := storage.QueryByColumn(ctx, storer, "team", "tier", "gold")
+	if err != nil {
+		t.Fatalf("QueryByColumn: %s", err)
+	}
+	checkListed(t, "QueryByColumn", rows, want, func(r storage.Row) bool { return r.Columns()["tier"] == "gold" })
+
+	rows, err = storage.QueryByColumn(ctx, storer, "team", "tags", []string{"b", "a"})
+	if err != nil {
+		t.Fatalf("QueryByColumn of a string set: %s", err)
+	}
+	checkListed(t, "QueryByColumn of a string set", rows, want, func(r storage.Row) bool { return r.Label() == "team-0" })
+
+	rows, err = storage.QueryByColumn(ctx, storer, "team", "tier", "bronze")
+	if err != nil {
+		t.Fatalf("QueryByColumn of a value no row holds: %s", err)
+	}
+	if len(rows) > 0 {
+		t.Errorf("QueryByColumn of a value no row holds found %d rows", len(rows))
+	}
+}
+
+func testScanRows(t *testing.T, storer storage.RowStorer) {
+	ctx := context.Background()
+	parent := createRow(t, storer, "org", "acme")
+	want := map[string]bool{"org/" + parent.ID(): true}
+	for i := 0; i < manyRows; i++ {
+		child := createPaddedChild(t, storer, fmt.Sprintf("team-%03d", i), parent.ID())
+		want["team/"+child.ID()] = true
+	}
+
+	seen := map[string]int{}
+	err := storer.ScanRows(ctx, func(r storage.Row) error {
+		seen[r.Type()+"/"+r.ID()]++
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ScanRows: %s", err)
+	}
+	for key := range want {
+		if seen[key] != 1 {
+			t.Errorf("ScanRows saw %s %d times", key, seen[key])
+		}
+	}
+	for key := range seen {
+		if !want[key] {
+			t.Errorf("ScanRows saw %s, which wasn't stored", key)
+		}
+	}
+
+	stop := errors.New("stop")
+	calls := 0
+	err = storer.ScanRows(ctx, func(storage.Row) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) {
+		t.Errorf("ScanRows returned %v, not the error its function did", err)
+	}
+	if calls != 1 {
+		t.Errorf("ScanRows called its function %d times after it returned an error", calls)
+	}
+}
+
+func testConcurrentCreates(t *testing.T, storer storage.RowStorer) {
+	ctx := context.Background()
+	parent := createRow(t, storer, "org", "acme")
+	ids := make([]string, racers)
+	errs := make([]error, racers)
+	var wg sync.WaitGroup
+	for i := 0; i < racers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			child, err := storer.CreateChild(ctx, "team", fmt.Sprintf("team-%d", i), "org", parent.ID(), nil)
+			if err == nil {
+				ids[i] = child.ID()
+			}
+			errs[i] = err
+		}(i)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("CreateChild %d: %s", i, err)
+		}
+	}
+	slices.Sort(ids)
+	if len(slices.Compact(ids)) != racers {
+		t.Errorf("rows created at once were given the same IDs")
+	}
+	rows, err := storer.ListRows(ctx, "team", "", parent.ID())
+	if err != nil {
+		t.Fatalf("ListRows: %s", err)
+	}
+	if len(rows) != racers {
+		t.Errorf("ListRows listed %d rows created at once, not %d", len(rows), racers)
+	}
+}
+
+// testConcurrentLabelClaims checks that of rows created or relabeled with the
+// same label at once, one gets it.
+func testConcurrentLabelClaims(t *testing.T, storer storage.RowStorer) {
+	ctx := context.Background()
+	race := func(name string, claim func(i int) error) {
+		errs := make([]error, racers)
+		var wg sync.WaitGroup
+		for i := 0; i < racers; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				errs[i] = claim(i)
+			}(i)
+		}
+		wg.Wait()
+		won := 0
+		for _, err := range errs {
+			switch {
+			case err == nil:
+				won++
+			case !errors.Is(err, storage.ErrConflict):
+				t.Errorf("%s lost with %v, not a conflict", name, err)
+			}
+		}
+		if won != 1 {
+			t.Errorf("%s: %d of %d got the label at once, not 1", name, won, racers)
+		}
+	}
+
+	race("CreateRow", func(int) error {
+		_, err := storer.CreateRow(ctx, "org", "acme")
+		return err
+	})
+
+	rows := make([]storage.Row, racers)
+	for i := range rows {
+		rows[i] = createRow(t, storer, "team", fmt.Sprintf("team-%d", i))
+	}
+	race("UpdateRow", func(i int) error {
+		_, err := storer.UpdateRow(ctx, "team", rows[i].ID(), "infra")
+		return err
+	})
+	found, err := storer.ListRows(ctx, "team", "infra", "")
+	if err != nil {
+		t.Fatalf("ListRows: %s", err)
+	}
+	if len(found) != 1 {
+		t.Errorf("%d rows have the label rows were relabeled to at once, not 1", len(found))
+	}
+}
+
+// testWithinTx checks the transactions of storers that are
+// storage.Transactors.
+func testWithinTx(t *testing.T, storer storage.RowStorer) {
+	if !storage.Transactional(storer) {
+		t.Skip("the storer isn't storage.Transactional")
+	}
+	ctx := context.Background()
+	var parent storage.Row
+	err := storage.WithinTx(ctx, storer, func(tx storage.RowStorer) error {
+		var err error
+		parent, err = tx.CreateRow(ctx, "org", "acme")
+		if err != nil {
+			return err
+		}
+		for _, label := range []string{"infra", "web"} {
+			if _, err := tx.CreateChild(ctx, "team", label, "org", parent.ID(), nil); err != nil {
+				return err
+			}
+		}
+		// the transaction reads its own writes
+		_, err = tx.CreateChild(ctx, "team", "web", "org", parent.ID(), nil)
+		checkErr(t, "CreateChild of a label taken within the transaction", err, storage.ErrCollisionParentLabel)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WithinTx: %s", err)
+	}
+	checkRow(t, "WithinTx", getRowByID(t, storer, "org", parent.ID()), "org", "acme", "")
+	for _, label := range []string{"infra", "web"} {
+		if _, err := storer.GetChild(ctx, label, parent.ID()); err != nil {
+			t.Errorf("GetChild %q created within a transaction: %s", label, err)
+		}
+	}
+
+	// a failed transaction writes nothing
+	failed := errors.New("failed")
+	err = storage.WithinTx(ctx, storer, func(tx storage.RowStorer) error {
+		globex, err := tx.CreateRow(ctx, "org", "globex")
+		if err != nil {
+			return err
+		}
+		if _, err := tx.CreateChild(ctx, "team", "infra", "org", globex.ID(), nil); err != nil {
+			return err
+		}
+		if _, err := tx.UpdateRow(ctx, "org", parent.ID(), "initech"); err != nil {
+			return err
+		}
+		return failed
+	})
+	if !errors.Is(err, failed) {
+		t.Fatalf("WithinTx returned %v, not fn's error", err)
+	}
+	_, err = storer.GetRow(ctx, "org", "globex")
+	checkErr(t, "GetRow of a row created by a failed transaction", err, storage.ErrNotFoundRow)
+	checkRow(t, "GetRowByID of a row relabeled by a failed transaction", getRowByID(t, storer, "org", parent.ID()), "org", "acme", "")
+}
+
+// testWithinTxConflict checks that a transaction doesn't overwrite a change
+// another writer makes to a row after the transaction read it. Backends
+// that make other writers wait for the transaction, or fail them, keep both
+// changes; the others must fail the transaction with storage.ErrConflict.
+func testWithinTxConflict(t *testing.T, storer storage.RowStorer) {
+	if !storage.Transactional(storer) {
+		t.Skip("the storer isn't storage.Transactional")
+	}
+	ctx := context.Background()
+	parent := createRow(t, storer, "org", "acme")
+	team, err := storer.CreateChild(ctx, "team", "infra", "org", parent.ID(), map[string]interface{}{"owner": "ops"})
+	if err != nil {
+		t.Fatalf("CreateChild: %s", err)
+	}
+
+	var otherErr error
+	otherDone := make(chan struct{})
+	otherMeanwhile := false
+	err = storage.WithinTx(ctx, storer, func(tx storage.RowStorer) error {
+		if err := tx.UpdateColumn(ctx, "team", team.ID(), "tier", "gold"); err != nil {
+			return err
+		}
+		go func() {
+			defer close(otherDone)
+			otherErr = storer.UpdateColumn(ctx, "team", team.ID(), "owner", "dev")
+		}()
+		select {
+		case <-otherDone:
+			otherMeanwhile = true
+		case <-time.After(200 * time.Millisecond):
+			// the other writer waits for the transaction
+		}
+		return nil
+	})
+	<-otherDone
+
+	switch {
+	case otherMeanwhile && otherErr == nil:
+		checkErr(t, "WithinTx writing a row another writer changed since", err, storage.ErrConflict)
+		checkColumns(t, "GetRowByID after the conflict", getRowByID(t, storer, "team", team.ID()), map[string]interface{}{"owner": "dev"})
+	case err != nil:
+		t.Fatalf("WithinTx, with the other writer waiting for it or failed: %s", err)
+	case otherErr != nil:
+		checkColumns(t, "GetRowByID after the other writer failed", getRowByID(t, storer, "team", team.ID()), map[string]interface{}{"owner": "ops", "tier": "gold"})
+	default:
+		checkColumns(t, "GetRowByID after both writes", getRowByID(t, storer, "team", team.ID()), map[string]interface{}{"owner": "dev", "tier": "gold"})
+	}
+}
+
+func createRow(t *testing.T, storer storage.RowStorer, rowType, label string) storage.Row {
+	t.Helper()
+	r, err := storer.CreateRow(context.Background(), rowType, label)
+	if err != nil {
+		t.Fatalf("CreateRow %s %q: %s", rowType, label, err)
+	}
+	return r
+}
+
+func createChild(t *testing.T, storer storage.RowStorer, rowType, label, parentType, parentID string) storage.Row {
+	t.Helper()
+	r, err := storer.CreateChild(context.Background(), rowType, label, parentType, parentID, nil)
+	if err != nil {
+		t.Fatalf("CreateChild %s %q: %s", rowType, label, err)
+	}
+	return r
+}
+
+// createPaddedChild creates a team of an org, with a column of padding.
+func createPaddedChild(t *testing.T, storer storage.RowStorer, label, parentID string) storage.Row {
+	t.Helper()
+	columns := map[string]interface{}{"padding": strings.Repeat("x", paddingBytes)}
+	r, err := storer.CreateChild(context.Background(), "team", label, "org", parentID, columns)
+	if err != nil {
+		t.Fatalf("CreateChild team %q: %s", label, err)
+	}
+	return r
+}
+
+func getRowByID(t *testing.T, storer storage.RowStorer, rowType, id string) storage.Row {
+	t.Helper()
+	r, err := storer.GetRowByID(context.Background(), rowType, id)
+	if err != nil {
+		t.Fatalf("GetRowByID %s %q: %s", rowType, id, err)
+	}
+	return r
+}
+
+func checkRow(t *testing.T, op string, r storage.Row, rowType, label, parentID string) {
+	t.Helper()
+	if r.Type() != rowType || r.Label() != label || r.ParentID() != parentID {
+		t.Errorf("%s returned a %s %q with parent %q, not a %s %q with parent %q", op, r.Type(), r.Label(), r.ParentID(), rowType, label, parentID)
+	}
+}
+
+// checkErr checks that an operation failed with an error of one of the kinds.
+func checkErr(t *testing.T, op string, err error, kinds ...error) {
+	t.Helper()
+	for _, kind := range kinds {
+		if errors.Is(err, kind) {
+			return
+		}
+	}
+	t.Errorf("%s returned %v, not %v", op, err, errors.Join(kinds...))
+}
+
+// checkColumns checks a row's columns. No columns and empty columns are the
+// same, and the columns wrappers keep of their own, such as the signatures of
+// integrity's, are left out.
+func checkColumns(t *testing.T, op string, r storage.Row, want map[string]interface{}) {
+	t.Helper()
+	got := map[string]interface{}{}
+	for name, value := range r.Columns() {
+		if !strings.HasPrefix(name, wrapperColumnPrefix) {
+			got[name] = value
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("%s returned columns %v, not %v", op, got, want)
+		return
+	}
+	for name, value := range want {
+		if !sameValue(got[name], value) {
+			t.Errorf("%s returned column %q as %v, not %v", op, name, got[name], value)
+		}
+	}
+}
+
+// sameValue reports whether two column values are the same. String sets are
+// the same in any order.
+func sameValue(a, b interface{}) bool {
+	aSet, aIsSet := a.([]string)
+	bSet, bIsSet := b.([]string)
+	if aIsSet || bIsSet {
+		if !aIsSet || !bIsSet {
+			return false
+		}
+		aSet, bSet = slices.Clone(aSet), slices.Clone(bSet)
+		slices.Sort(aSet)
+		slices.Sort(bSet)
+		return slices.Equal(aSet, bSet)
+	}
+	return a == b
+}
+
+// checkListed checks that rows are those of want, by ID, that match.
+func checkListed(t *testing.T, op string, rows []storage.Row, want map[string]storage.Row, match func(storage.Row) bool) {
+	t.Helper()
+	wanted := 0
+	for _, r := range want {
+		if match(r) {
+			wanted++
+		}
+	}
+	seen := map[string]bool{}
+	for _, r := range rows {
+		if seen[r.ID()] {
+			t.Errorf("%s listed %q twice", op, r.ID())
+		}
+		seen[r.ID()] = true
+		if stored, ok := want[r.ID()]; !ok || !match(stored) {
+			t.Errorf("%s listed %s %q (%q), which it shouldn't have", op, r.Type(), r.Label(), r.ID())
+		}
+	}
+	if len(seen) != wanted {
+		t.Errorf("%s listed %d rows, not %d", op, len(seen), wanted)
+	}
+}
+
+// row is a row to put.
+type row struct {
+	RowType     string
+	RowID       string
+	RowLabel    string
+	RowParentID string
+	RowColumns  map[string]interface{}
+}
+
+func (r *row) Type() string                    { return r.RowType }
+func (r *row) ID() string                      { return r.RowID }
+func (r *row) Label() string                   { return r.RowLabel }
+func (r *row) ParentID() string                { return r.RowParentID }
+func (r *row) Columns() map[string]interface{} { return r.RowColumns }
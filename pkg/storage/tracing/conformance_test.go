@@ -0,0 +1,18 @@
+package tracing_test
+
+import (
+	"testing"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/memory"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/storagetest"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/tracing"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.RowStorer {
+		return tracing.NewStorer(memory.NewClient(), noop.NewTracerProvider(), trace.SpanContext{})
+	})
+}
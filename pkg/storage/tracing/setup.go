@@ -0,0 +1,88 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/contrib/propagators/aws/xray"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Exporters, as named by the standard OTEL_TRACES_EXPORTER variable.
+const (
+	ExporterNone    = "none"
+	ExporterOTLP    = "otlp"
+	ExporterConsole = "console"
+	// ExporterXRay exports to AWS X-Ray, by way of an OpenTelemetry
+	// collector, such as the ADOT collector or the CloudWatch agent, that
+	// receives OTLP and exports to X-Ray. It isn't a standard exporter name.
+	ExporterXRay = "xray"
+)
+
+// NewTracerProvider returns a tracer provider that exports spans with the
+// named exporter, or nil for ExporterNone and the empty string. The OTLP and
+// X-Ray exporters are configured by the standard OTEL_EXPORTER_OTLP_*
+// variables, and the console exporter writes to standard error. The X-Ray
+// exporter's trace IDs are ones X-Ray accepts.
+//
+// Spans are exported as each one ends, rather than in batches, since
+// Terraform stops the provider without warning.
+func NewTracerProvider(ctx context.Context, exporter, serviceName string) (*sdktrace.TracerProvider, error) {
+	var spanExporter sdktrace.SpanExporter
+	var err error
+	opts := []sdktrace.TracerProviderOption{}
+	switch exporter {
+	case "", ExporterNone:
+		return nil, nil
+	case ExporterOTLP:
+		spanExporter, err = otlptracehttp.New(ctx)
+	case ExporterXRay:
+		spanExporter, err = otlptracehttp.New(ctx)
+		opts = append(opts, sdktrace.WithIDGenerator(xray.NewIDGenerator()))
+	case ExporterConsole:
+		spanExporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stderr))
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q: use %s, %s, %s, or %s", exporter, ExporterOTLP, ExporterXRay, ExporterConsole, ExporterNone)
+	}
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts,
+		sdktrace.WithSyncer(spanExporter),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(serviceName))),
+	)
+	return sdktrace.NewTracerProvider(opts...), nil
+}
+
+// Propagator returns the propagator that carries traces into the requests of
+// AWS clients, for the named exporter: X-Ray's trace header for
+// ExporterXRay, so that calls join X-Ray's service map, and nil, for the
+// clients' default, otherwise.
+func Propagator(exporter string) propagation.TextMapPropagator {
+	if exporter == ExporterXRay {
+		return xray.Propagator{}
+	}
+	return nil
+}
+
+// ParentFromEnv returns the span context in the TRACEPARENT variable, as set
+// by CI systems and wrappers that trace the Terraform run around the
+// provider, or failing that in _X_AMZN_TRACE_ID, as set by AWS Lambda. It is
+// invalid if neither is set.
+func ParentFromEnv() trace.SpanContext {
+	carrier := propagation.MapCarrier{"traceparent": os.Getenv("TRACEPARENT")}
+	ctx := propagation.TraceContext{}.Extract(context.Background(), carrier)
+	if parent := trace.SpanContextFromContext(ctx); parent.IsValid() {
+		return parent
+	}
+	carrier = propagation.MapCarrier{"x-amzn-trace-id": os.Getenv("_X_AMZN_TRACE_ID")}
+	ctx = xray.Propagator{}.Extract(context.Background(), carrier)
+	return trace.SpanContextFromContext(ctx)
+}
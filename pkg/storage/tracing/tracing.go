@@ -0,0 +1,275 @@
+// Package tracing wraps a storage.RowStorer with OpenTelemetry spans, one per
+// operation, so that slow Terraform operations can be traced down to the
+// backend calls they make.
+package tracing
+
+import (
+	"context"
+	"errors"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/spilliams/tree-terraform-provider/pkg/storage/tracing"
+
+const (
+	attrRowType    = attribute.Key("row.type")
+	attrRowID      = attribute.Key("row.id")
+	attrRowLabel   = attribute.Key("row.label")
+	attrParentType = attribute.Key("row.parent_type")
+	attrParentID   = attribute.Key("row.parent_id")
+	attrChildType  = attribute.Key("row.child_type")
+	// column values may be sensitive, so only names are recorded
+	attrColumns = attribute.Key("row.columns")
+	attrRows    = attribute.Key("rows.count")
+	// the outcome, as the metrics of the operation name it
+	attrResult = attribute.Key("storage.result")
+	// the Terraform operation the storage operation is part of, and its
+	// correlation ID, which the provider's logs carry
+	attrOperation     = attribute.Key("terraform.operation")
+	attrCorrelationID = attribute.Key("terraform.correlation_id")
+)
+
+// Storer is a storage.RowStorer that records a span for each operation of the
+// one it wraps.
+type Storer struct {
+	next   storage.RowStorer
+	tracer trace.Tracer
+	// parent is the span to parent operations that have none in their
+	// context, such as one passed to the provider in TRACEPARENT
+	parent trace.SpanContext
+}
+
+var (
+	_ storage.RowStorer     = &Storer{}
+	_ storage.Transactor    = &Storer{}
+	_ storage.ColumnDeleter = &Storer{}
+	_ storage.BatchGetter   = &Storer{}
+	_ storage.ColumnQuerier = &Storer{}
+	_ storage.ChildScanner  = &Storer{}
+	_ storage.BatchPutter   = &Storer{}
+	_ storage.ColumnPatcher = &Storer{}
+	_ storage.RowIterator   = &Storer{}
+)
+
+// NewStorer wraps next, recording spans with the provider's tracer.
+func NewStorer(next storage.RowStorer, tp trace.TracerProvider, parent trace.SpanContext) *Storer {
+	return &Storer{
+		next:   next,
+		tracer: tp.Tracer(instrumentationName),
+		parent: parent,
+	}
+}
+
+// Unwrap returns the wrapped storer.
+func (client *Storer) Unwrap() storage.RowStorer {
+	return client.next
+}
+
+func (client *Storer) start(ctx context.Context, op string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	if !trace.SpanContextFromContext(ctx).IsValid() && client.parent.IsValid() {
+		ctx = trace.ContextWithRemoteSpanContext(ctx, client.parent)
+	}
+	if operation := storage.Operation(ctx); operation != "" {
+		attributes = append(attributes, attrOperation.String(operation))
+	}
+	if id := storage.CorrelationID(ctx); id != "" {
+		attributes = append(attributes, attrCorrelationID.String(id))
+	}
+	return client.tracer.Start(ctx, "RowStorer."+op,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attributes...),
+	)
+}
+
+// end records the result and err on the span, and ends it. A row that isn't
+// found is an answer, not a failure, so it doesn't mark the span as an error.
+func end(span trace.Span, err error) {
+	span.SetAttributes(attrResult.String(storage.Result(err)))
+	if err != nil {
+		span.RecordError(err)
+		if !errors.Is(err, storage.ErrNotFoundRow) {
+			span.SetStatus(codes.Error, err.Error())
+		}
+	}
+	span.End()
+}
+
+func columnNames(columns map[string]interface{}) attribute.KeyValue {
+	names := make([]string, 0, len(columns))
+	for name := range columns {
+		names = append(names, name)
+	}
+	return attrColumns.StringSlice(names)
+}
+
+func (client *Storer) GetRowByID(ctx context.Context, rowType, rowID string) (row storage.Row, err error) {
+	ctx, span := client.start(ctx, "GetRowByID", attrRowType.String(rowType), attrRowID.String(rowID))
+	defer func() { end(span, err) }()
+	return client.next.GetRowByID(ctx, rowType, rowID)
+}
+
+func (client *Storer) GetRowsByIDs(ctx context.Context, rowType string, ids []string) (rows []storage.Row, err error) {
+	ctx, span := client.start(ctx, "GetRowsByIDs", attrRowType.String(rowType), attrRowID.StringSlice(ids))
+	defer func() { end(span, err) }()
+	rows, err = storage.GetRowsByIDs(ctx, client.next, rowType, ids)
+	span.SetAttributes(attrRows.Int(len(rows)))
+	return rows, err
+}
+
+func (client *Storer) GetRow(ctx context.Context, rowType, rowLabel string) (row storage.Row, err error) {
+	ctx, span := client.start(ctx, "GetRow", attrRowType.String(rowType), attrRowLabel.String(rowLabel))
+	defer func() { end(span, err) }()
+	return client.next.GetRow(ctx, rowType, rowLabel)
+}
+
+func (client *Storer) CreateRow(ctx context.Context, rowType, rowLabel string) (row storage.Row, err error) {
+	ctx, span := client.start(ctx, "CreateRow", attrRowType.String(rowType), attrRowLabel.String(rowLabel))
+	defer func() { end(span, err) }()
+	row, err = client.next.CreateRow(ctx, rowType, rowLabel)
+	if err == nil {
+		span.SetAttributes(attrRowID.String(row.ID()))
+	}
+	return row, err
+}
+
+func (client *Storer) CreateChild(ctx context.Context, rowType, rowLabel, parentType, parentID string, columns map[string]interface{}) (row storage.Row, err error) {
+	ctx, span := client.start(ctx, "CreateChild",
+		attrRowType.String(rowType), attrRowLabel.String(rowLabel),
+		attrParentType.String(parentType), attrParentID.String(parentID),
+		columnNames(columns),
+	)
+	defer func() { end(span, err) }()
+	row, err = client.next.CreateChild(ctx, rowType, rowLabel, parentType, parentID, columns)
+	if err == nil {
+		span.SetAttributes(attrRowID.String(row.ID()))
+	}
+	return row, err
+}
+
+func (client *Storer) GetChild(ctx context.Context, childLabel, parentID string) (row storage.Row, err error) {
+	ctx, span := client.start(ctx, "GetChild", attrRowLabel.String(childLabel), attrParentID.String(parentID))
+	defer func() { end(span, err) }()
+	return client.next.GetChild(ctx, childLabel, parentID)
+}
+
+func (client *Storer) ListRows(ctx context.Context, rowType, labelFilter, parentIDFilter string) (rows []storage.Row, err error) {
+	ctx, span := client.start(ctx, "ListRows", attrRowType.String(rowType), attrRowLabel.String(labelFilter), attrParentID.String(parentIDFilter))
+	defer func() { end(span, err) }()
+	rows, err = client.next.ListRows(ctx, rowType, labelFilter, parentIDFilter)
+	span.SetAttributes(attrRows.Int(len(rows)))
+	return rows, err
+}
+
+// IterRows traces each page it reads in a span of its own.
+func (client *Storer) IterRows(ctx context.Context, rowType, labelFilter, parentIDFilter string) *storage.Iter[storage.Row] {
+	return storage.WrapPages(storage.IterRows(ctx, client.next, rowType, labelFilter, parentIDFilter), func(ctx context.Context, fetch storage.PageFunc[storage.Row]) (page []storage.Row, more bool, err error) {
+		ctx, span := client.start(ctx, "IterRows", attrRowType.String(rowType), attrRowLabel.String(labelFilter), attrParentID.String(parentIDFilter))
+		defer func() { end(span, err) }()
+		page, more, err = fetch(ctx)
+		span.SetAttributes(attrRows.Int(len(page)))
+		return page, more, err
+	})
+}
+
+func (client *Storer) QueryByColumn(ctx context.Context, rowType, columnName string, value interface{}) (rows []storage.Row, err error) {
+	ctx, span := client.start(ctx, "QueryByColumn", attrRowType.String(rowType), attrColumns.StringSlice([]string{columnName}))
+	defer func() { end(span, err) }()
+	rows, err = storage.QueryByColumn(ctx, client.next, rowType, columnName, value)
+	span.SetAttributes(attrRows.Int(len(rows)))
+	return rows, err
+}
+
+func (client *Storer) UpdateRow(ctx context.Context, rowType, rowID, newLabel string) (row storage.Row, err error) {
+	ctx, span := client.start(ctx, "UpdateRow", attrRowType.String(rowType), attrRowID.String(rowID), attrRowLabel.String(newLabel))
+	defer func() { end(span, err) }()
+	return client.next.UpdateRow(ctx, rowType, rowID, newLabel)
+}
+
+func (client *Storer) UpdateChild(ctx context.Context, childType, childID, newChildLabel, parentType, newParentID string) (row storage.Row, err error) {
+	ctx, span := client.start(ctx, "UpdateChild",
+		attrRowType.String(childType), attrRowID.String(childID), attrRowLabel.String(newChildLabel),
+		attrParentType.String(parentType), attrParentID.String(newParentID),
+	)
+	defer func() { end(span, err) }()
+	return client.next.UpdateChild(ctx, childType, childID, newChildLabel, parentType, newParentID)
+}
+
+func (client *Storer) UpdateColumn(ctx context.Context, rowType, rowID, columnName string, columnValue interface{}) (err error) {
+	ctx, span := client.start(ctx, "UpdateColumn", attrRowType.String(rowType), attrRowID.String(rowID), attrColumns.StringSlice([]string{columnName}))
+	defer func() { end(span, err) }()
+	return client.next.UpdateColumn(ctx, rowType, rowID, columnName, columnValue)
+}
+
+func (client *Storer) PatchColumns(ctx context.Context, patches []storage.ColumnPatch) (err error) {
+	ctx, span := client.start(ctx, "PatchColumns", attrRows.Int(len(patches)))
+	defer func() { end(span, err) }()
+	return storage.PatchColumns(ctx, client.next, patches)
+}
+
+func (client *Storer) UpdateColumns(ctx context.Context, rowType, rowID string, columns map[string]interface{}) (err error) {
+	ctx, span := client.start(ctx, "UpdateColumns", attrRowType.String(rowType), attrRowID.String(rowID), columnNames(columns))
+	defer func() { end(span, err) }()
+	return client.next.UpdateColumns(ctx, rowType, rowID, columns)
+}
+
+func (client *Storer) DeleteRow(ctx context.Context, rowType, childType, rowID string) (err error) {
+	ctx, span := client.start(ctx, "DeleteRow", attrRowType.String(rowType), attrChildType.String(childType), attrRowID.String(rowID))
+	defer func() { end(span, err) }()
+	return client.next.DeleteRow(ctx, rowType, childType, rowID)
+}
+
+func (client *Storer) PutRow(ctx context.Context, row storage.Row) (err error) {
+	ctx, span := client.start(ctx, "PutRow", attrRowType.String(row.Type()), attrRowID.String(row.ID()), attrParentID.String(row.ParentID()))
+	defer func() { end(span, err) }()
+	return client.next.PutRow(ctx, row)
+}
+
+func (client *Storer) PutRows(ctx context.Context, rows []storage.Row) (err error) {
+	ctx, span := client.start(ctx, "PutRows", attrRows.Int(len(rows)))
+	defer func() { end(span, err) }()
+	return storage.PutRows(ctx, client.next, rows)
+}
+
+func (client *Storer) ScanRows(ctx context.Context, fn func(storage.Row) error) (err error) {
+	ctx, span := client.start(ctx, "ScanRows")
+	defer func() { end(span, err) }()
+	scanned := 0
+	err = client.next.ScanRows(ctx, func(row storage.Row) error {
+		scanned++
+		return fn(row)
+	})
+	span.SetAttributes(attrRows.Int(scanned))
+	return err
+}
+
+func (client *Storer) ScanChildren(ctx context.Context, parentID string, fn func(storage.Row) error) (err error) {
+	ctx, span := client.start(ctx, "ScanChildren", attrParentID.String(parentID))
+	defer func() { end(span, err) }()
+	scanned := 0
+	err = storage.ScanChildren(ctx, client.next, parentID, func(row storage.Row) error {
+		scanned++
+		return fn(row)
+	})
+	span.SetAttributes(attrRows.Int(scanned))
+	return err
+}
+
+func (client *Storer) DeleteColumn(ctx context.Context, rowType, rowID, columnName string) (err error) {
+	ctx, span := client.start(ctx, "DeleteColumn", attrRowType.String(rowType), attrRowID.String(rowID), attrColumns.StringSlice([]string{columnName}))
+	defer func() { end(span, err) }()
+	return storage.DeleteColumn(ctx, client.next, rowType, rowID, columnName)
+}
+
+// WithinTx records a span for the transaction, and one for each of its
+// operations.
+func (client *Storer) WithinTx(ctx context.Context, fn func(tx storage.RowStorer) error) (err error) {
+	ctx, span := client.start(ctx, "WithinTx")
+	defer func() { end(span, err) }()
+	return storage.WithinTx(ctx, client.next, func(tx storage.RowStorer) error {
+		return fn(&Storer{next: tx, tracer: client.tracer, parent: client.parent})
+	})
+}
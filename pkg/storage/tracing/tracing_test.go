@@ -0,0 +1,126 @@
+package tracing_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/memory"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// failing fails every DeleteRow.
+type failing struct {
+	storage.RowStorer
+}
+
+func (client *failing) DeleteRow(ctx context.Context, rowType, childType, rowID string) error {
+	return errors.New("the backend is down")
+}
+
+func newRecorded(parent trace.SpanContext) (*tracing.Storer, *tracetest.SpanRecorder) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	return tracing.NewStorer(&failing{RowStorer: memory.NewClient()}, tp, parent), recorder
+}
+
+func attributeOf(span sdktrace.ReadOnlySpan, key attribute.Key) attribute.Value {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestSpans(t *testing.T) {
+	storer, recorder := newRecorded(trace.SpanContext{})
+	ctx := storage.WithOperation(context.Background(), "Create org")
+	org, err := storer.CreateRow(ctx, "org", "acme")
+	if err != nil {
+		t.Fatalf("CreateRow: %s", err)
+	}
+	if err := storer.UpdateColumn(ctx, "org", org.ID(), "secret", "hunter2"); err != nil {
+		t.Fatalf("UpdateColumn: %s", err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 2 || spans[0].Name() != "RowStorer.CreateRow" || spans[1].Name() != "RowStorer.UpdateColumn" {
+		t.Fatalf("recorded %d spans, not one for CreateRow and one for UpdateColumn", len(spans))
+	}
+	created := spans[0]
+	if got := attributeOf(created, "row.id").AsString(); got != org.ID() {
+		t.Errorf("the CreateRow span has row ID %q, not %q", got, org.ID())
+	}
+	if got := attributeOf(created, "terraform.operation").AsString(); got != "Create org" {
+		t.Errorf("the CreateRow span has operation %q", got)
+	}
+	if got := attributeOf(created, "storage.result").AsString(); got != "ok" || created.Status().Code == codes.Error {
+		t.Errorf("the CreateRow span has result %q and status %v", got, created.Status())
+	}
+	// column names are recorded, but not their values
+	for _, kv := range spans[1].Attributes() {
+		if kv.Value.Emit() == "hunter2" {
+			t.Errorf("the UpdateColumn span records the column's value, as %s", kv.Key)
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	ctx := context.Background()
+	storer, recorder := newRecorded(trace.SpanContext{})
+
+	// a row that isn't found is an answer
+	if _, err := storer.GetRowByID(ctx, "org", "org-missing"); !errors.Is(err, storage.ErrNotFoundRow) {
+		t.Fatalf("GetRowByID of a missing row failed with %v", err)
+	}
+	if err := storer.DeleteRow(ctx, "org", "team", "org-acme"); err == nil {
+		t.Fatal("DeleteRow of a failing backend succeeded")
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("recorded %d spans, not 2", len(spans))
+	}
+	if got := attributeOf(spans[0], "storage.result").AsString(); got != "not_found" || spans[0].Status().Code == codes.Error {
+		t.Errorf("the span of a missing row has result %q and status %v", got, spans[0].Status())
+	}
+	if spans[1].Status().Code != codes.Error || len(spans[1].Events()) == 0 {
+		t.Errorf("the span of a failure has status %v and events %v", spans[1].Status(), spans[1].Events())
+	}
+}
+
+func TestParent(t *testing.T) {
+	parent := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1},
+		SpanID:     trace.SpanID{2},
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	})
+	storer, recorder := newRecorded(parent)
+	if _, err := storer.CreateRow(context.Background(), "org", "acme"); err != nil {
+		t.Fatalf("CreateRow: %s", err)
+	}
+	if _, err := storage.IterRows(context.Background(), storer, "org", "", "").All(); err != nil {
+		t.Fatalf("IterRows: %s", err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 2 || spans[1].Name() != "RowStorer.IterRows" {
+		t.Fatalf("recorded %d spans, not one for CreateRow and one for IterRows' page", len(spans))
+	}
+	for _, span := range spans {
+		if span.Parent().SpanID() != parent.SpanID() || span.SpanContext().TraceID() != parent.TraceID() {
+			t.Errorf("%s isn't a child of the storer's parent span", span.Name())
+		}
+	}
+	if got := attributeOf(spans[1], "rows.count").AsInt64(); got != 1 {
+		t.Errorf("the IterRows span counted %d rows, not 1", got)
+	}
+}
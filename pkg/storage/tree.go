@@ -0,0 +1,273 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// Orphan is a row whose parent no longer exists.
+type Orphan struct {
+	Row
+	// Children counts the orphan's own children, which deleting it would
+	// orphan in turn.
+	Children int
+}
+
+// FindOrphans scans every row, and returns those whose parent ID matches no
+// stored row, ordered by type and ID.
+func FindOrphans(ctx context.Context, storer RowStorer) ([]Orphan, error) {
+	rows := []Row{}
+	ids := map[string]bool{}
+	children := map[string]int{}
+	err := storer.ScanRows(ctx, func(row Row) error {
+		rows = append(rows, row)
+		ids[row.ID()] = true
+		if row.ParentID() != "" {
+			children[row.ParentID()]++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	orphans := []Orphan{}
+	for _, row := range rows {
+		if row.ParentID() != "" && !ids[row.ParentID()] {
+			orphans = append(orphans, Orphan{Row: row, Children: children[row.ID()]})
+		}
+	}
+	sort.Slice(orphans, func(i, j int) bool {
+		if orphans[i].Type() != orphans[j].Type() {
+			return orphans[i].Type() < orphans[j].Type()
+		}
+		return orphans[i].ID() < orphans[j].ID()
+	})
+	return orphans, nil
+}
+
+// FindChildren scans every row, and returns those whose parent ID is parentID,
+// whether or not that parent still exists, ordered by type and ID.
+func FindChildren(ctx context.Context, storer RowStorer, parentID string) ([]Row, error) {
+	children := []Row{}
+	err := storer.ScanRows(ctx, func(row Row) error {
+		if row.ParentID() == parentID {
+			children = append(children, row)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(children, func(i, j int) bool {
+		if children[i].Type() != children[j].Type() {
+			return children[i].Type() < children[j].Type()
+		}
+		return children[i].ID() < children[j].ID()
+	})
+	return children, nil
+}
+
+// Node is a row in a tree, with its children.
+type Node struct {
+	Row
+	// ParentType is the type of the row's parent, or empty if it has none or
+	// the parent no longer exists.
+	ParentType string
+	Children   []*Node
+}
+
+// LoadTree scans every row, and returns the subtree under the row with rootID,
+// with each node's children ordered by type, then label. It returns an error
+// wrapping ErrNotFoundRow if no row has that ID.
+func LoadTree(ctx context.Context, storer RowStorer, rootID string) (*Node, error) {
+	var root *Node
+	types := map[string]string{}
+	children := map[string][]*Node{}
+	err := storer.ScanRows(ctx, func(row Row) error {
+		node := &Node{Row: row}
+		if row.ID() == rootID {
+			root = node
+		}
+		types[row.ID()] = row.Type()
+		children[row.ParentID()] = append(children[row.ParentID()], node)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if root == nil {
+		return nil, fmt.Errorf("%w: %q", ErrNotFoundRow, rootID)
+	}
+	root.ParentType = types[root.ParentID()]
+
+	// a visited set keeps a parent cycle from recursing forever
+	visited := map[string]bool{}
+	var attach func(node *Node)
+	attach = func(node *Node) {
+		visited[node.ID()] = true
+		for _, child := range children[node.ID()] {
+			if visited[child.ID()] {
+				continue
+			}
+			child.ParentType = node.Type()
+			node.Children = append(node.Children, child)
+			attach(child)
+		}
+		sort.Slice(node.Children, func(i, j int) bool {
+			a, b := node.Children[i], node.Children[j]
+			if a.Type() != b.Type() {
+				return a.Type() < b.Type()
+			}
+			return a.Label() < b.Label()
+		})
+	}
+	attach(root)
+	return root, nil
+}
+
+// Walk calls fn on the node and each of its descendants, parents before their
+// children, with each one's depth below the node.
+func (node *Node) Walk(fn func(node *Node, depth int) error) error {
+	return node.walk(fn, 0)
+}
+
+func (node *Node) walk(fn func(node *Node, depth int) error, depth int) error {
+	if err := fn(node, depth); err != nil {
+		return err
+	}
+	for _, child := range node.Children {
+		if err := child.walk(fn, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Size counts the node and its descendants.
+func (node *Node) Size() int {
+	size := 1
+	for _, child := range node.Children {
+		size += child.Size()
+	}
+	return size
+}
+
+// DeleteTree deletes the node's descendants, children before their parents,
+// then the node itself, and returns how many rows it deleted. Rows created
+// under the tree since it was loaded make their parents' deletion fail, on
+// backends that check for children.
+func DeleteTree(ctx context.Context, storer RowStorer, root *Node) (int, error) {
+	deleted := 0
+	var remove func(node *Node) error
+	remove = func(node *Node) error {
+		for _, child := range node.Children {
+			if err := remove(child); err != nil {
+				return err
+			}
+		}
+		childType := ""
+		if len(node.Children) > 0 {
+			childType = node.Children[0].Type()
+		}
+		if err := storer.DeleteRow(ctx, node.Type(), childType, node.ID()); err != nil {
+			return fmt.Errorf("%s %s: %w", node.Type(), node.ID(), err)
+		}
+		deleted++
+		return nil
+	}
+	err := remove(root)
+	return deleted, err
+}
+
+// MoveSubtree moves the row with the type and ID, keeping its label, to the
+// parent with the type and ID, and with it the rows under it, whose parent
+// IDs don't change. It fails with an error of the ErrInvalid kind if the new
+// parent is the row or one of its descendants, which would cut the subtree off
+// from its root, and with ErrCollisionParentLabel if another of the new
+// parent's children has the row's label. To find out, it reads the rows under
+// the row a level at a time, as ScanChildren does, until it finds the new
+// parent or runs out of them.
+//
+// If the storer is Transactional, the check and the move are made in one
+// transaction, which keeps other writers from moving rows between them only
+// as far as the backend isolates its transactions. The memory, bbolt, and
+// SQLite backends make transactions one at a time. DynamoDB's check only
+// that the new parent still exists under the same parent, and PostgreSQL's
+// read other writers' changes as they're committed, so on those, another
+// writer moving an ancestor of the new parent under the row meanwhile can
+// still make a cycle of them.
+func MoveSubtree(ctx context.Context, storer RowStorer, rowType, rowID, parentType, newParentID string) (Row, error) {
+	switch newParentID {
+	case "":
+		return nil, fmt.Errorf("%w: %q must be moved to a parent", ErrInvalid, rowID)
+	case rowID:
+		return nil, fmt.Errorf("%w: %q can't be its own parent", ErrInvalid, rowID)
+	}
+	var moved Row
+	move := func(tx RowStorer) error {
+		row, err := tx.GetRowByID(ctx, rowType, rowID)
+		if err != nil {
+			return err
+		}
+		if row.ParentID() == newParentID {
+			moved = row
+			return nil
+		}
+		if _, err := tx.GetRowByID(ctx, parentType, newParentID); err != nil {
+			return err
+		}
+		under, err := isDescendant(ctx, tx, rowID, newParentID)
+		if err != nil {
+			return err
+		}
+		if under {
+			return fmt.Errorf("%w: %q can't be moved under %q, one of its descendants", ErrInvalid, rowID, newParentID)
+		}
+		moved, err = tx.UpdateChild(ctx, rowType, rowID, row.Label(), parentType, newParentID)
+		return err
+	}
+
+	if Transactional(storer) {
+		err := WithinTx(ctx, storer, move)
+		return moved, err
+	}
+	err := move(storer)
+	return moved, err
+}
+
+// errFound stops a scan that found what it was looking for.
+var errFound = errors.New("found")
+
+// isDescendant reports whether the row with id is under the row with
+// ancestorID, reading the rows under it a level at a time.
+func isDescendant(ctx context.Context, storer RowStorer, ancestorID, id string) (bool, error) {
+	// a visited set keeps a parent cycle from looping forever
+	visited := map[string]bool{ancestorID: true}
+	level := []string{ancestorID}
+	for len(level) > 0 {
+		next := []string{}
+		for _, parentID := range level {
+			err := ScanChildren(ctx, storer, parentID, func(child Row) error {
+				if child.ID() == id {
+					return errFound
+				}
+				if !visited[child.ID()] {
+					visited[child.ID()] = true
+					next = append(next, child.ID())
+				}
+				return nil
+			})
+			if errors.Is(err, errFound) {
+				return true, nil
+			}
+			if err != nil {
+				return false, err
+			}
+		}
+		level = next
+	}
+	return false, nil
+}
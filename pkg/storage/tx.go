@@ -0,0 +1,45 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+)
+
+// Transactor is implemented by storage backends that can make several writes
+// atomically, such as a parent and its children created together.
+type Transactor interface {
+	// WithinTx calls fn with a storer for the transaction, and makes the
+	// writes fn makes with it all at once if fn returns nil, or none of
+	// them if it returns an error, which WithinTx returns. If the writes
+	// conflict with another writer's, as over a label, none of them are
+	// made, and WithinTx returns an error of the ErrConflict kind. fn must
+	// use only the storer it's given, which is good only until fn returns.
+	WithinTx(ctx context.Context, fn func(tx RowStorer) error) error
+}
+
+// WithinTx calls fn within a transaction of the storer, as Transactor does. It
+// fails with an error of the ErrInvalid kind if the storer isn't
+// Transactional, since its writes can't be made atomically.
+func WithinTx(ctx context.Context, storer RowStorer, fn func(tx RowStorer) error) error {
+	if !Transactional(storer) {
+		return fmt.Errorf("%w: the storage backend can't make writes atomically", ErrInvalid)
+	}
+	return storer.(Transactor).WithinTx(ctx, fn)
+}
+
+// Transactional reports whether WithinTx can make the storer's writes
+// atomically. Wrappers are Transactors whatever they wrap, forwarding
+// transactions to the storers they wrap, so a wrapper is transactional only
+// if the storer it wraps is.
+func Transactional(storer RowStorer) bool {
+	for next := storer; ; {
+		if _, ok := next.(Transactor); !ok {
+			return false
+		}
+		wrapper, ok := next.(Wrapper)
+		if !ok {
+			return true
+		}
+		next = wrapper.Unwrap()
+	}
+}
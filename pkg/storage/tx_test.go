@@ -0,0 +1,31 @@
+package storage_test
+
+import (
+	"testing"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage/memory"
+)
+
+// plain is a storer without transactions.
+type plain struct {
+	storage.RowStorer
+}
+
+func TestTransactional(t *testing.T) {
+	tests := []struct {
+		name   string
+		storer storage.RowStorer
+		want   bool
+	}{
+		{"backend", memory.NewClient(), true},
+		{"wrapped backend", storage.WithRetry(memory.NewClient(), storage.RetryPolicy{}), true},
+		{"backend without transactions", plain{memory.NewClient()}, false},
+		{"wrapped backend without transactions", storage.WithRetry(plain{memory.NewClient()}, storage.RetryPolicy{}), false},
+	}
+	for _, test := range tests {
+		if got := storage.Transactional(test.storer); got != test.want {
+			t.Errorf("Transactional of a %s is %t, not %t", test.name, got, test.want)
+		}
+	}
+}
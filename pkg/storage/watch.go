@@ -0,0 +1,40 @@
+package storage
+
+import (
+	"context"
+	"time"
+)
+
+type ChangeKind string
+
+const (
+	ChangeCreated ChangeKind = "created"
+	ChangeUpdated ChangeKind = "updated"
+	ChangeDeleted ChangeKind = "deleted"
+)
+
+// Change is one write to a row.
+type Change struct {
+	Kind ChangeKind
+	Time time.Time
+	// Old is the row before the change, or nil if it was created.
+	Old Row
+	// New is the row after the change, or nil if it was deleted.
+	New Row
+}
+
+// Row returns the changed row: the new one, or the old one if it was deleted.
+func (change Change) Row() Row {
+	if change.New != nil {
+		return change.New
+	}
+	return change.Old
+}
+
+// Watcher is implemented by storage backends that can stream changes to their
+// rows as they happen.
+type Watcher interface {
+	// Watch calls fn with each change made after it starts, until ctx is
+	// done or fn returns an error. Changes to one row arrive in order.
+	Watch(ctx context.Context, fn func(Change) error) error
+}
@@ -0,0 +1,165 @@
+package tfgen
+
+import (
+	"fmt"
+	"strings"
+)
+
+// the labels of the row under test in each step of its acceptance test
+var testLabels = []string{"test", "test-updated"}
+
+// AcceptanceTest describes the generated acceptance test of one row type.
+type AcceptanceTest struct {
+	// ResourceType is the Terraform resource type, e.g. tree_environment.
+	ResourceType string
+	// Address is the address of the resource under test.
+	Address string
+	Steps   []AcceptanceTestStep
+}
+
+// AcceptanceTestStep is one apply of the acceptance test, and what to check
+// after it.
+type AcceptanceTestStep struct {
+	Config string
+	Checks []AcceptanceTestCheck
+}
+
+// AcceptanceTestCheck asserts the value of one attribute of the resource under
+// test.
+type AcceptanceTestCheck struct {
+	Attribute string
+	Value     string
+}
+
+func newAcceptanceTest(providerTypeName string, def *Definition, byType map[string]*Definition) *AcceptanceTest {
+	resourceType := providerTypeName + "_" + def.Type
+	test := &AcceptanceTest{
+		ResourceType: resourceType,
+		Address:      resourceType + ".test",
+	}
+	ancestors := ancestry(def, byType)
+
+	for step, label := range testLabels {
+		var config strings.Builder
+		parentAddress := ""
+		// write ancestors root first, so the config reads top-down
+		for i := len(ancestors) - 1; i >= 0; i-- {
+			name := fmt.Sprintf("ancestor_%d", i+1)
+			writeTestResource(&config, providerTypeName, ancestors[i], name, "test-"+name, parentAddress, 0, true)
+			parentAddress = fmt.Sprintf("%s_%s.%s", providerTypeName, ancestors[i].Type, name)
+		}
+		writeTestResource(&config, providerTypeName, def, "test", label, parentAddress, step, false)
+
+		checks := []AcceptanceTestCheck{{Attribute: attrLabel, Value: label}}
+		for _, column := range def.Columns {
+			checks = append(checks, column.testChecks(step)...)
+		}
+		test.Steps = append(test.Steps, AcceptanceTestStep{Config: config.String(), Checks: checks})
+	}
+	return test
+}
+
+// ancestry returns the shortest chain of parent definitions from def to a
+// root row type, nearest first.
+func ancestry(def *Definition, byType map[string]*Definition) []*Definition {
+	type path struct {
+		def   *Definition
+		chain []*Definition
+	}
+	queue := []path{{def: def}}
+	visited := map[string]bool{def.Type: true}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if len(current.def.Parents) == 0 {
+			return current.chain
+		}
+		for _, parentType := range current.def.Parents {
+			if visited[parentType] {
+				continue
+			}
+			visited[parentType] = true
+			parent := byType[parentType]
+			chain := append(append([]*Definition{}, current.chain...), parent)
+			queue = append(queue, path{def: parent, chain: chain})
+		}
+	}
+	return nil
+}
+
+func writeTestResource(config *strings.Builder, providerTypeName string, def *Definition, name, label, parentAddress string, step int, requiredOnly bool) {
+	fmt.Fprintf(config, "resource %q %q {\n", providerTypeName+"_"+def.Type, name)
+	fmt.Fprintf(config, "  %s = %q\n", attrLabel, label)
+	if parentAddress != "" {
+		fmt.Fprintf(config, "  %s = %s.id\n", attrParentID, parentAddress)
+	}
+	for _, column := range def.Columns {
+		if requiredOnly && !column.Required {
+			continue
+		}
+		fmt.Fprintf(config, "  %s = %s\n", column.Name, column.testValue(step))
+	}
+	config.WriteString("}\n")
+}
+
+// testValue returns an HCL value for the column in the given test step.
+func (column Column) testValue(step int) string {
+	values := column.testStrings(step)
+	if !column.IsStringSet() {
+		return fmt.Sprintf("%q", values[0])
+	}
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = fmt.Sprintf("%q", v)
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
+
+// testStrings returns one value for a string column, and step+2 values for a
+// string set column, so that the size of the set changes between steps too.
+func (column Column) testStrings(step int) []string {
+	examples := column.columnType().TestValues
+	if !column.IsStringSet() {
+		if len(examples) > 0 {
+			return []string{examples[step%len(examples)]}
+		}
+		return []string{fmt.Sprintf("%s-%d", column.Name, step+1)}
+	}
+
+	values := []string{}
+	for i := 0; i < step+2; i++ {
+		if len(examples) > 0 {
+			values = append(values, examples[i%len(examples)])
+			continue
+		}
+		values = append(values, fmt.Sprintf("%s-%d-%d", column.Name, step+1, i))
+	}
+	return values
+}
+
+// checkTestValues makes sure custom column types give enough example values
+// for generated tests to use distinct ones in every step.
+func checkTestValues(def *Definition) error {
+	for _, column := range def.Columns {
+		ct := column.columnType()
+		if _, builtin := builtinColumnTypes[ct.Name]; builtin {
+			continue
+		}
+		need := len(testLabels)
+		if column.IsStringSet() {
+			need = len(testLabels) + 1
+		}
+		if len(ct.TestValues) < need {
+			return fmt.Errorf("column type %q needs at least %d test values to generate tests for %s.%s", ct.Name, need, def.Type, column.Name)
+		}
+	}
+	return nil
+}
+
+func (column Column) testChecks(step int) []AcceptanceTestCheck {
+	values := column.testStrings(step)
+	if !column.IsStringSet() {
+		return []AcceptanceTestCheck{{Attribute: column.Name, Value: values[0]}}
+	}
+	return []AcceptanceTestCheck{{Attribute: column.Name + ".#", Value: fmt.Sprint(len(values))}}
+}
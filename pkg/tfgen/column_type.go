@@ -0,0 +1,141 @@
+package tfgen
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ColumnType is the hook point for custom column types. It maps a type name
+// used in definitions (e.g. "cidr" or "arn") to the built-in type it is stored
+// as, and to the Go expressions the generated schema and model use for it.
+// Every expression is copied into generated code verbatim, so it must compile
+// in the generated package with the given imports.
+type ColumnType struct {
+	// Name is how definitions refer to the type.
+	Name string `yaml:"name"`
+	// Base is the built-in type the column is stored as, ColumnTypeString or
+	// ColumnTypeStringSet.
+	Base string `yaml:"base"`
+	// Imports lists the import paths the expressions below need. An import
+	// may be given a name, as in "cidrtypes github.com/example/cidrtypes".
+	Imports []string `yaml:"imports"`
+
+	// CustomType is an expression for the schema attribute's CustomType, e.g.
+	// "cidrtypes.IPv4PrefixType{}". If it is set, ValueType, NewValue, and
+	// NullValue must be too. Only string columns support custom types.
+	CustomType string `yaml:"custom_type"`
+	// ValueType is the Go type of the column in generated models, e.g.
+	// "cidrtypes.IPv4Prefix".
+	ValueType string `yaml:"value_type"`
+	// NewValue is a function converting a string to a ValueType, e.g.
+	// "cidrtypes.NewIPv4PrefixValue".
+	NewValue string `yaml:"new_value"`
+	// NullValue is an expression for a null ValueType, e.g.
+	// "cidrtypes.NewIPv4PrefixNull()".
+	NullValue string `yaml:"null_value"`
+
+	// Validators are expressions of validator.String values for string
+	// columns, or validator.Set values for string set columns.
+	Validators []string `yaml:"validators"`
+	// TestValues are valid example values, used by generated tests. String
+	// columns need at least two, string set columns at least three.
+	TestValues []string `yaml:"test_values"`
+}
+
+var builtinColumnTypes = map[string]ColumnType{
+	ColumnTypeString: {
+		Name:      ColumnTypeString,
+		Base:      ColumnTypeString,
+		ValueType: "types.String",
+		NewValue:  "types.StringValue",
+		NullValue: "types.StringNull()",
+	},
+	ColumnTypeStringSet: {
+		Name:      ColumnTypeStringSet,
+		Base:      ColumnTypeStringSet,
+		ValueType: "types.Set",
+	},
+}
+
+// LoadColumnTypes reads a YAML list of custom column types.
+func LoadColumnTypes(path string) ([]ColumnType, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	columnTypes := []ColumnType{}
+	decoder := yaml.NewDecoder(bytes.NewReader(b))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&columnTypes); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return columnTypes, nil
+}
+
+func (ct ColumnType) validate() error {
+	errorf := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%w column type %q: %s", ErrInvalidDefinition, ct.Name, fmt.Sprintf(format, args...))
+	}
+	if !namePattern.MatchString(ct.Name) {
+		return errorf("name must be snake_case")
+	}
+	if _, ok := builtinColumnTypes[ct.Name]; ok {
+		return errorf("cannot redefine a built-in type")
+	}
+	if _, ok := builtinColumnTypes[ct.Base]; !ok {
+		return errorf("unknown base type %q", ct.Base)
+	}
+	if ct.CustomType != "" {
+		if ct.Base != ColumnTypeString {
+			return errorf("only string columns support custom types")
+		}
+		if ct.ValueType == "" || ct.NewValue == "" || ct.NullValue == "" {
+			return errorf("a custom type needs value_type, new_value, and null_value")
+		}
+	}
+	return nil
+}
+
+// resolve fills in the expressions a custom type leaves empty from its base
+// type.
+func (ct ColumnType) resolve() ColumnType {
+	base := builtinColumnTypes[ct.Base]
+	if ct.CustomType == "" {
+		ct.ValueType = base.ValueType
+		ct.NewValue = base.NewValue
+		ct.NullValue = base.NullValue
+	}
+	return ct
+}
+
+// resolveColumnTypes attaches a column type to every column of every
+// definition, from the built-in types and the given custom ones.
+func resolveColumnTypes(defs []*Definition, custom []ColumnType) error {
+	known := map[string]ColumnType{}
+	for name, ct := range builtinColumnTypes {
+		known[name] = ct
+	}
+	for _, ct := range custom {
+		if err := ct.validate(); err != nil {
+			return err
+		}
+		if _, ok := known[ct.Name]; ok {
+			return fmt.Errorf("%w column type %q: declared more than once", ErrInvalidDefinition, ct.Name)
+		}
+		known[ct.Name] = ct.resolve()
+	}
+
+	for _, def := range defs {
+		for i, column := range def.Columns {
+			ct, ok := known[column.Type]
+			if !ok {
+				return def.errorf("column %q has unknown type %q", column.Name, column.Type)
+			}
+			def.Columns[i].kind = ct
+		}
+	}
+	return nil
+}
@@ -0,0 +1,423 @@
+// Package tfgen generates terraform-plugin-framework resources and data
+// sources from declarative row type definitions.
+package tfgen
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/importid"
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	ColumnTypeString    = "string"
+	ColumnTypeStringSet = "string_set"
+)
+
+// attribute names the generated schemas use for every row type.
+const (
+	attrID          = "id"
+	attrLabel       = "label"
+	attrLabelFilter = "label_filter"
+	attrParentID    = "parent_id"
+)
+
+// statsDataSourceName is the name of the generated data source of approximate
+// row counts, which no row type or plural may share.
+const statsDataSourceName = "stats"
+
+var (
+	ErrInvalidDefinition = errors.New("invalid definition")
+	ErrDuplicateType     = errors.New("duplicate row type")
+
+	namePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+)
+
+// Definition declares a single row type, and thus one generated resource and
+// its data sources.
+type Definition struct {
+	Type string `yaml:"type"`
+	// Plural names the plural data source. It defaults to the type with an
+	// English plural suffix.
+	Plural      string `yaml:"plural,omitempty"`
+	Description string `yaml:"description,omitempty"`
+	// Example is Terraform configuration using the resource, shown in its
+	// documentation.
+	Example string `yaml:"example,omitempty"`
+	// Deprecated, if set, is the warning Terraform shows configurations
+	// that still use the resource or its data sources.
+	Deprecated string `yaml:"deprecated,omitempty"`
+	// Parents lists the row types a row of this type may be a child of. Row
+	// types without parents are roots of the tree.
+	Parents []string `yaml:"parents,omitempty"`
+	// Movable child rows can be moved to a new parent in place. Otherwise,
+	// changing a row's parent replaces it.
+	Movable bool     `yaml:"movable,omitempty"`
+	Columns []Column `yaml:"columns,omitempty"`
+
+	// the file this definition was read from, for error messages
+	source string
+}
+
+// Column declares a single column of a row type.
+type Column struct {
+	Name        string `yaml:"name"`
+	Type        string `yaml:"type"`
+	Description string `yaml:"description,omitempty"`
+	// Example is an example value, shown in the attribute's documentation.
+	Example string `yaml:"example,omitempty"`
+	// Deprecated, if set, is the warning Terraform shows configurations
+	// that still set the column.
+	Deprecated string `yaml:"deprecated,omitempty"`
+	Required   bool   `yaml:"required,omitempty"`
+	// Filterable columns can be used to filter the plural data source.
+	Filterable bool `yaml:"filterable,omitempty"`
+	// PII columns hold personally identifiable information. Terraform hides
+	// their values in plans, and logs and tools mask them.
+	PII bool `yaml:"pii,omitempty"`
+
+	// the resolved type, see resolveColumnTypes
+	kind ColumnType
+}
+
+// LoadDefinitions reads every .yaml or .yml file in dir, validates them, and
+// returns them sorted by type.
+func LoadDefinitions(dir string) ([]*Definition, error) {
+	paths, err := definitionFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	defs := make([]*Definition, 0, len(paths))
+	for _, p := range paths {
+		def, err := LoadDefinition(p)
+		if err != nil {
+			return nil, err
+		}
+		defs = append(defs, def)
+	}
+
+	if err := Validate(defs); err != nil {
+		return nil, err
+	}
+	sort.Slice(defs, func(i, j int) bool { return defs[i].Type < defs[j].Type })
+	return defs, nil
+}
+
+// LoadDefinition reads a single definition file. It does not validate the
+// definition against any others.
+func LoadDefinition(path string) (*Definition, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	def := &Definition{}
+	decoder := yaml.NewDecoder(bytes.NewReader(b))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(def); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	def.source = path
+	return def, nil
+}
+
+func definitionFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	paths := []string{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		switch filepath.Ext(entry.Name()) {
+		case ".yaml", ".yml":
+			paths = append(paths, filepath.Join(dir, entry.Name()))
+		}
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// Validate checks each definition on its own, and the set of definitions as a
+// whole.
+func Validate(defs []*Definition) error {
+	// the stats data source is generated for every set of definitions
+	seen := map[string]string{statsDataSourceName: "the stats data source"}
+	for _, def := range defs {
+		if err := def.validate(); err != nil {
+			return err
+		}
+		if other, ok := seen[def.Type]; ok {
+			return fmt.Errorf("%w %q in %s and %s", ErrDuplicateType, def.Type, other, def.source)
+		}
+		seen[def.Type] = def.source
+	}
+	// plural data source names share the namespace of type names
+	for _, def := range defs {
+		if other, ok := seen[def.PluralName()]; ok {
+			return fmt.Errorf("%w %q, the plural of %s, in %s and %s", ErrDuplicateType, def.PluralName(), def.Type, other, def.source)
+		}
+		seen[def.PluralName()] = def.source
+	}
+
+	byType := map[string]*Definition{}
+	for _, def := range defs {
+		byType[def.Type] = def
+	}
+	for _, def := range defs {
+		for _, parent := range def.Parents {
+			if _, ok := byType[parent]; !ok {
+				return def.errorf("parent type %q is not defined", parent)
+			}
+		}
+	}
+	// a row can only be created once its parent exists, so every child type
+	// needs a chain of parents that ends at a root type
+	for _, def := range defs {
+		if len(def.Parents) > 0 && ancestry(def, byType) == nil {
+			return def.errorf("no chain of parent types leads to a root type")
+		}
+	}
+	return nil
+}
+
+func (def *Definition) validate() error {
+	if !namePattern.MatchString(def.Type) {
+		return def.errorf("type %q must be snake_case", def.Type)
+	}
+
+	if def.Plural != "" && !namePattern.MatchString(def.Plural) {
+		return def.errorf("plural %q must be snake_case", def.Plural)
+	}
+	if def.PluralName() == def.Type {
+		return def.errorf("plural %q must differ from the type", def.PluralName())
+	}
+
+	parents := map[string]bool{}
+	for _, parent := range def.Parents {
+		if parents[parent] {
+			return def.errorf("parent type %q is declared more than once", parent)
+		}
+		parents[parent] = true
+	}
+	if def.Movable && len(def.Parents) == 0 {
+		return def.errorf("only row types with parents can be movable")
+	}
+
+	columns := map[string]bool{}
+	for _, column := range def.Columns {
+		if !namePattern.MatchString(column.Name) {
+			return def.errorf("column name %q must be snake_case", column.Name)
+		}
+		if isReservedAttribute(column.Name) {
+			return def.errorf("column name %q is reserved", column.Name)
+		}
+		if column.Name == def.PluralName() {
+			return def.errorf("column name %q is the plural data source's list attribute", column.Name)
+		}
+		if columns[column.Name] {
+			return def.errorf("column %q is declared more than once", column.Name)
+		}
+		columns[column.Name] = true
+
+		// column types are resolved at generation time, since custom ones
+		// come from the generator's configuration
+		if column.Type == "" {
+			return def.errorf("column %q has no type", column.Name)
+		}
+	}
+	return nil
+}
+
+func (def *Definition) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("%w %s: %s", ErrInvalidDefinition, def.source, fmt.Sprintf(format, args...))
+}
+
+func isReservedAttribute(name string) bool {
+	switch name {
+	case attrID, attrLabel, attrLabelFilter, attrParentID:
+		return true
+	}
+	return false
+}
+
+// Describe returns the definition's description, or a default one.
+func (def *Definition) Describe() string {
+	if def.Description != "" {
+		return def.Description
+	}
+	return fmt.Sprintf("A %s.", humanName(def.Type))
+}
+
+// MarkdownDescribe returns the definition's description with its example
+// configuration, for documentation.
+func (def *Definition) MarkdownDescribe() string {
+	if def.Example == "" {
+		return def.Describe()
+	}
+	return def.Describe() + "\n\n## Example Usage\n\n```terraform\n" + strings.TrimSpace(def.Example) + "\n```"
+}
+
+// PluralName returns the name of the plural data source.
+func (def *Definition) PluralName() string {
+	if def.Plural != "" {
+		return def.Plural
+	}
+	return pluralize(def.Type)
+}
+
+// FilterColumns returns the columns the plural data source can filter on.
+func (def *Definition) FilterColumns() []Column {
+	columns := []Column{}
+	for _, column := range def.Columns {
+		if column.Filterable {
+			columns = append(columns, column)
+		}
+	}
+	return columns
+}
+
+// PIIColumnNames returns the names of the columns that hold PII.
+func (def *Definition) PIIColumnNames() []string {
+	names := []string{}
+	for _, column := range def.Columns {
+		if column.PII {
+			names = append(names, column.Name)
+		}
+	}
+	return names
+}
+
+// PIIColumns returns the PII columns of every definition, by row type.
+func PIIColumns(defs []*Definition) storage.PIIColumns {
+	pii := storage.PIIColumns{}
+	for _, def := range defs {
+		if names := def.PIIColumnNames(); len(names) > 0 {
+			pii[def.Type] = names
+		}
+	}
+	return pii
+}
+
+// DescribeParent returns the description of the parent_id attribute.
+func (def *Definition) DescribeParent() string {
+	return fmt.Sprintf("The ID of the %s's parent %s.", humanName(def.Type), def.HumanParents())
+}
+
+// HumanParents returns the parent types in words, e.g. "team or organization".
+func (def *Definition) HumanParents() string {
+	names := make([]string, len(def.Parents))
+	for i, parent := range def.Parents {
+		names[i] = humanName(parent)
+	}
+	return strings.Join(names, " or ")
+}
+
+// ImportFormats describes the import IDs the generated resource accepts.
+func (def *Definition) ImportFormats() string {
+	return importid.Formats(def.Type, len(def.Parents) > 0)
+}
+
+// HasStringSet reports whether any of the definition's columns is a string set.
+func (def *Definition) HasStringSet() bool {
+	for _, column := range def.Columns {
+		if column.IsStringSet() {
+			return true
+		}
+	}
+	return false
+}
+
+// Imports lists the extra imports the definition's column types need.
+func (def *Definition) Imports() []string {
+	seen := map[string]bool{}
+	imports := []string{}
+	for _, column := range def.Columns {
+		for _, imp := range column.columnType().Imports {
+			if !seen[imp] {
+				seen[imp] = true
+				imports = append(imports, imp)
+			}
+		}
+	}
+	sort.Strings(imports)
+	return imports
+}
+
+// Describe returns the column's description, or a default one.
+func (column Column) Describe(rowType string) string {
+	if column.Description != "" {
+		return column.Description
+	}
+	return fmt.Sprintf("The %s of the %s.", humanName(column.Name), humanName(rowType))
+}
+
+// MarkdownDescribe returns the column's description with its example value,
+// for documentation.
+func (column Column) MarkdownDescribe(rowType string) string {
+	if column.Example == "" {
+		return column.Describe(rowType)
+	}
+	return fmt.Sprintf("%s Example: `%s`.", column.Describe(rowType), column.Example)
+}
+
+func (column Column) columnType() ColumnType {
+	if column.kind.Name != "" {
+		return column.kind
+	}
+	return builtinColumnTypes[column.Type]
+}
+
+func (column Column) IsStringSet() bool {
+	return column.columnType().Base == ColumnTypeStringSet
+}
+
+// ModelType is the Go type used for the column in generated models.
+func (column Column) ModelType() string {
+	return column.columnType().ValueType
+}
+
+// NewValue converts a string to the column's model type.
+func (column Column) NewValue() string {
+	return column.columnType().NewValue
+}
+
+// NullValue is a null value of the column's model type.
+func (column Column) NullValue() string {
+	return column.columnType().NullValue
+}
+
+// CustomType is the schema attribute's custom type, if it has one.
+func (column Column) CustomType() string {
+	return column.columnType().CustomType
+}
+
+func (column Column) Validators() []string {
+	return column.columnType().Validators
+}
+
+// ValidatorType is the type of the column's validators.
+func (column Column) ValidatorType() string {
+	if column.IsStringSet() {
+		return "validator.Set"
+	}
+	return "validator.String"
+}
+
+// AttributeType is the framework schema attribute used for the column.
+func (column Column) AttributeType() string {
+	if column.IsStringSet() {
+		return "SetAttribute"
+	}
+	return "StringAttribute"
+}
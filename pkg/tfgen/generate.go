@@ -0,0 +1,402 @@
+package tfgen
+
+import (
+	"bufio"
+	"bytes"
+	"embed"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	pathpkg "path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// GeneratedHeader marks every file the generator writes. Files in the output
+// directory that start with it are owned by the generator, and are removed
+// when their definition goes away.
+const GeneratedHeader = "// Code generated by schema-tfgen. DO NOT EDIT."
+
+//go:embed templates/*.tmpl
+var templateFS embed.FS
+
+var templates = template.Must(
+	template.New("").Funcs(template.FuncMap{
+		"exported":   exportedName,
+		"unexported": unexportedName,
+		"human":      humanName,
+		"quote":      func(s string) string { return fmt.Sprintf("%q", s) },
+		"importSpec": importSpec,
+	}).ParseFS(templateFS, "templates/*.tmpl"),
+)
+
+// Config controls where and how code is generated.
+type Config struct {
+	// Package is the name of the Go package to generate.
+	Package string
+	// OutputDir is the directory the package lives in.
+	OutputDir string
+	// Tests also generates an acceptance test per resource, run against the
+	// in-memory storage backend. The generated package then depends on
+	// terraform-plugin-testing.
+	Tests bool
+	// ProviderTypeName prefixes the resource types in generated tests, e.g.
+	// "tree" for tree_environment. It is required when generating tests.
+	ProviderTypeName string
+	// ColumnTypes are custom column types definitions may use, in addition
+	// to the built-in ones.
+	ColumnTypes []ColumnType
+	// MigrationsDir, if set, receives a numbered file of migration steps
+	// whenever the definitions changed since the last generation in a way
+	// existing rows must follow. The definitions are recorded in LockFile in
+	// the output directory to compare against.
+	MigrationsDir string
+
+	// ClientDir, if set, receives a package of typed Go clients, one per row
+	// type, for services that read and write rows without Terraform. The
+	// package depends only on pkg/storage.
+	ClientDir string
+	// ClientPackage is the name of the client package. It defaults to the
+	// base name of ClientDir.
+	ClientPackage string
+}
+
+type fileData struct {
+	Header  string
+	Package string
+	Defs    []*Definition
+	Def     *Definition
+	// Children lists the row types that declare Def as a parent.
+	Children []string
+
+	ProviderTypeName string
+	Test             *AcceptanceTest
+}
+
+// Generate writes a model, a resource, and two data source files (singular and
+// plural) per definition,
+// plus files registering all of them, holding shared helpers, and for a data
+// source of approximate row counts, into the
+// configured output directory. Previously generated files that are no longer
+// produced are removed.
+func Generate(cfg Config, defs []*Definition) error {
+	if cfg.Package == "" {
+		return fmt.Errorf("a package name is required")
+	}
+	if cfg.Tests && cfg.ProviderTypeName == "" {
+		return fmt.Errorf("a provider type name is required to generate tests")
+	}
+	if err := Validate(defs); err != nil {
+		return err
+	}
+	if err := resolveColumnTypes(defs, cfg.ColumnTypes); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(cfg.OutputDir, 0o755); err != nil {
+		return err
+	}
+	var previous []*Definition
+	if cfg.MigrationsDir != "" {
+		var err error
+		previous, err = readLock(cfg.OutputDir)
+		if err != nil {
+			return err
+		}
+	}
+
+	files := map[string][]byte{}
+	base := fileData{
+		Header:           GeneratedHeader,
+		Package:          cfg.Package,
+		Defs:             defs,
+		ProviderTypeName: cfg.ProviderTypeName,
+	}
+
+	out, err := render("blocks.go.tmpl", base)
+	if err != nil {
+		return err
+	}
+	files["blocks.go"] = out
+
+	out, err = render("helpers.go.tmpl", base)
+	if err != nil {
+		return err
+	}
+	files["helpers.go"] = out
+
+	out, err = render("stats_data_source.go.tmpl", base)
+	if err != nil {
+		return err
+	}
+	files["stats_data_source.go"] = out
+
+	if cfg.Tests {
+		out, err = render("provider_test.go.tmpl", base)
+		if err != nil {
+			return err
+		}
+		files["provider_test.go"] = out
+	}
+
+	byType := map[string]*Definition{}
+	children := map[string][]string{}
+	for _, def := range defs {
+		byType[def.Type] = def
+		for _, parent := range def.Parents {
+			children[parent] = append(children[parent], def.Type)
+		}
+	}
+
+	for _, def := range defs {
+		data := base
+		data.Def = def
+		data.Children = children[def.Type]
+
+		out, err := render("model.go.tmpl", data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", def.Type, err)
+		}
+		files[def.Type+"_model.go"] = out
+
+		out, err = render("resource.go.tmpl", data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", def.Type, err)
+		}
+		files[def.Type+"_resource.go"] = out
+
+		out, err = render("data_source.go.tmpl", data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", def.Type, err)
+		}
+		files[def.Type+"_data_source.go"] = out
+
+		out, err = render("list_data_source.go.tmpl", data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", def.Type, err)
+		}
+		files[def.PluralName()+"_data_source.go"] = out
+
+		if cfg.Tests {
+			if err := checkTestValues(def); err != nil {
+				return err
+			}
+			data.Test = newAcceptanceTest(cfg.ProviderTypeName, def, byType)
+			out, err = render("resource_test.go.tmpl", data)
+			if err != nil {
+				return fmt.Errorf("%s: %w", def.Type, err)
+			}
+			files[def.Type+"_resource_test.go"] = out
+		}
+	}
+
+	if err := writeFiles(cfg.OutputDir, files); err != nil {
+		return err
+	}
+	if cfg.ClientDir != "" {
+		if err := generateClients(cfg, defs, children); err != nil {
+			return err
+		}
+	}
+
+	if cfg.MigrationsDir == "" {
+		return nil
+	}
+	if previous != nil {
+		if steps := DiffDefinitions(previous, defs); len(steps) > 0 {
+			if err := writeMigration(cfg.MigrationsDir, steps); err != nil {
+				return err
+			}
+		}
+	}
+	lock, err := marshalLock(defs)
+	if err != nil {
+		return err
+	}
+	return writeIfChanged(filepath.Join(cfg.OutputDir, LockFile), lock)
+}
+
+// generateClients writes the typed client package.
+func generateClients(cfg Config, defs []*Definition, children map[string][]string) error {
+	pkg := cfg.ClientPackage
+	if pkg == "" {
+		abs, err := filepath.Abs(cfg.ClientDir)
+		if err != nil {
+			return err
+		}
+		pkg = filepath.Base(abs)
+	}
+	if err := os.MkdirAll(cfg.ClientDir, 0o755); err != nil {
+		return err
+	}
+
+	files := map[string][]byte{}
+	base := fileData{
+		Header:  GeneratedHeader,
+		Package: pkg,
+		Defs:    defs,
+	}
+	out, err := render("client.go.tmpl", base)
+	if err != nil {
+		return err
+	}
+	files["client.go"] = out
+
+	for _, def := range defs {
+		data := base
+		data.Def = def
+		data.Children = children[def.Type]
+		out, err := render("row_client.go.tmpl", data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", def.Type, err)
+		}
+		files[def.Type+"_client.go"] = out
+	}
+	return writeFiles(cfg.ClientDir, files)
+}
+
+// writeFiles writes the files into dir, and removes generated files that are
+// no longer among them.
+func writeFiles(dir string, files map[string][]byte) error {
+	if err := removeStale(dir, files); err != nil {
+		return err
+	}
+	for name, content := range files {
+		if err := writeIfChanged(filepath.Join(dir, name), content); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func render(name string, data fileData) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := templates.ExecuteTemplate(&buf, name, data); err != nil {
+		return nil, err
+	}
+	pruned, err := pruneImports(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w\n%s", name, err, buf.String())
+	}
+	formatted, err := format.Source(pruned)
+	if err != nil {
+		return nil, fmt.Errorf("formatting %s: %w\n%s", name, err, buf.String())
+	}
+	return formatted, nil
+}
+
+// importSpec turns "path" or "name path" into an import spec.
+func importSpec(imp string) string {
+	if name, path, ok := strings.Cut(imp, " "); ok {
+		return fmt.Sprintf("%s %q", name, path)
+	}
+	return fmt.Sprintf("%q", imp)
+}
+
+// pruneImports removes imports the source never refers to. Custom column
+// types bring their imports into every file of a row type, but not every file
+// uses every expression. Templates write one import per line, so unused ones
+// are removed by line.
+func pruneImports(src []byte) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.ImportsOnly)
+	if err != nil {
+		return nil, err
+	}
+	full, err := parser.ParseFile(token.NewFileSet(), "", src, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	used := map[string]bool{}
+	ast.Inspect(full, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if ident, ok := sel.X.(*ast.Ident); ok {
+				used[ident.Name] = true
+			}
+		}
+		return true
+	})
+
+	unused := map[int]bool{}
+	for _, imp := range file.Imports {
+		path, err := strconv.Unquote(imp.Path.Value)
+		if err != nil {
+			return nil, err
+		}
+		name := pathpkg.Base(path)
+		if imp.Name != nil {
+			name = imp.Name.Name
+		}
+		if name == "_" || name == "." || used[name] {
+			continue
+		}
+		unused[fset.Position(imp.Pos()).Line] = true
+	}
+	if len(unused) == 0 {
+		return src, nil
+	}
+
+	lines := bytes.SplitAfter(src, []byte("\n"))
+	var buf bytes.Buffer
+	for i, line := range lines {
+		if !unused[i+1] {
+			buf.Write(line)
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// removeStale deletes generated files in dir that are not in keep.
+func removeStale(dir string, keep map[string][]byte) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".go") {
+			continue
+		}
+		if _, ok := keep[entry.Name()]; ok {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		generated, err := isGenerated(path)
+		if err != nil {
+			return err
+		}
+		if generated {
+			if err := os.Remove(path); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func isGenerated(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	if !scanner.Scan() {
+		return false, scanner.Err()
+	}
+	return scanner.Text() == GeneratedHeader, nil
+}
+
+// writeIfChanged avoids touching files whose content is the same, so that
+// watch mode doesn't trigger needless rebuilds in editors and tools.
+func writeIfChanged(path string, content []byte) error {
+	existing, err := os.ReadFile(path)
+	if err == nil && bytes.Equal(existing, content) {
+		return nil
+	}
+	return os.WriteFile(path, content, 0o644)
+}
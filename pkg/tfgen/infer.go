@@ -0,0 +1,147 @@
+package tfgen
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/spilliams/tree-terraform-provider/pkg/storage"
+)
+
+// inferredType collects what the stored rows of one type have in common.
+type inferredType struct {
+	rows        int
+	roots       int
+	parentTypes map[string]bool
+	// missingParents counts rows whose parent ID matches no stored row
+	missingParents int
+	columns        map[string]*inferredColumn
+}
+
+type inferredColumn struct {
+	rows    int
+	strings int
+	sets    int
+	// other counts values that are neither strings nor string sets
+	other int
+}
+
+// InferDefinitions scans every row in the given storage, and proposes a row
+// type definition for each type it finds. Like ProposeFromOpenAPI, the
+// proposals are a starting point to be reviewed: a column is required only if
+// every row of its type has it, no type is movable, and columns whose values
+// don't agree on a column type are left out. Everything left out or guessed
+// at is described in the returned warnings.
+func InferDefinitions(ctx context.Context, storer storage.RowStorer) ([]*Definition, []string, error) {
+	rows := []storage.Row{}
+	typeOfID := map[string]string{}
+	err := storer.ScanRows(ctx, func(row storage.Row) error {
+		rows = append(rows, row)
+		typeOfID[row.ID()] = row.Type()
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	inferred := map[string]*inferredType{}
+	for _, row := range rows {
+		t, ok := inferred[row.Type()]
+		if !ok {
+			t = &inferredType{parentTypes: map[string]bool{}, columns: map[string]*inferredColumn{}}
+			inferred[row.Type()] = t
+		}
+		t.rows++
+
+		switch parentType, ok := typeOfID[row.ParentID()]; {
+		case row.ParentID() == "":
+			t.roots++
+		case ok:
+			t.parentTypes[parentType] = true
+		default:
+			t.missingParents++
+		}
+
+		for name, value := range row.Columns() {
+			c, ok := t.columns[name]
+			if !ok {
+				c = &inferredColumn{}
+				t.columns[name] = c
+			}
+			c.rows++
+			switch value.(type) {
+			case string:
+				c.strings++
+			case []string:
+				c.sets++
+			default:
+				c.other++
+			}
+		}
+	}
+
+	rowTypes := make([]string, 0, len(inferred))
+	for rowType := range inferred {
+		rowTypes = append(rowTypes, rowType)
+	}
+	sort.Strings(rowTypes)
+
+	defs := []*Definition{}
+	warnings := []string{}
+	for _, rowType := range rowTypes {
+		def, typeWarnings := inferDefinition(rowType, inferred[rowType])
+		defs = append(defs, def)
+		warnings = append(warnings, typeWarnings...)
+	}
+	return defs, warnings, nil
+}
+
+func inferDefinition(rowType string, t *inferredType) (*Definition, []string) {
+	def := &Definition{Type: rowType}
+	warnings := []string{}
+
+	for parentType := range t.parentTypes {
+		def.Parents = append(def.Parents, parentType)
+	}
+	sort.Strings(def.Parents)
+	if t.missingParents > 0 {
+		warnings = append(warnings, fmt.Sprintf("%s: %d of %d rows have a parent that no longer exists", rowType, t.missingParents, t.rows))
+	}
+	if len(def.Parents) > 0 && t.roots > 0 {
+		warnings = append(warnings, fmt.Sprintf("%s: %d of %d rows have no parent, but the type is proposed as a child type", rowType, t.roots, t.rows))
+	}
+
+	names := make([]string, 0, len(t.columns))
+	for name := range t.columns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if isReservedAttribute(name) || !namePattern.MatchString(name) {
+			warnings = append(warnings, fmt.Sprintf("%s.%s: skipped, the name can't be used for a column", rowType, name))
+			continue
+		}
+		c := t.columns[name]
+		columnType := ""
+		switch {
+		case c.other > 0:
+			warnings = append(warnings, fmt.Sprintf("%s.%s: skipped, it has %d values that are neither strings nor string sets", rowType, name, c.other))
+		case c.strings > 0 && c.sets > 0:
+			warnings = append(warnings, fmt.Sprintf("%s.%s: skipped, it has %d string values and %d string set values", rowType, name, c.strings, c.sets))
+		case c.sets > 0:
+			columnType = ColumnTypeStringSet
+		default:
+			columnType = ColumnTypeString
+		}
+		if columnType == "" {
+			continue
+		}
+		def.Columns = append(def.Columns, Column{
+			Name:     name,
+			Type:     columnType,
+			Required: c.rows == t.rows,
+		})
+	}
+	return def, warnings
+}
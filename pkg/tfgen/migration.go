@@ -0,0 +1,255 @@
+package tfgen
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LockFile records, in the output directory, the definitions the package was
+// last generated from. Generation compares against it to find the changes
+// that existing rows must be migrated through.
+const LockFile = "schema-tfgen.lock.yaml"
+
+// kinds of migration step
+const (
+	MigrationRemoveType       = "remove_type"
+	MigrationChangeUniqueness = "change_uniqueness_scope"
+	MigrationBackfillColumn   = "backfill_column"
+	MigrationRemoveColumn     = "remove_column"
+	MigrationConvertColumn    = "convert_column"
+	MigrationIndexColumn      = "index_column"
+)
+
+// backend names used for migration instructions
+const (
+	BackendDynamoDB = "dynamodb"
+)
+
+// MigrationStep is one change to existing rows that a change of definitions
+// calls for.
+type MigrationStep struct {
+	Kind        string `yaml:"kind"`
+	RowType     string `yaml:"row_type"`
+	Column      string `yaml:"column,omitempty"`
+	Description string `yaml:"description"`
+	// Instructions say how to carry the step out, per storage backend.
+	Instructions map[string]string `yaml:"instructions"`
+}
+
+// Migration is the set of steps between two generations.
+type Migration struct {
+	Steps []MigrationStep `yaml:"steps"`
+}
+
+// DiffDefinitions compares the definitions a package was generated from with
+// new ones, and returns the migration steps existing rows need. Changes that
+// existing rows satisfy as they are, like new row types or new optional
+// columns, need no steps.
+func DiffDefinitions(previous, current []*Definition) []MigrationStep {
+	before := map[string]*Definition{}
+	for _, def := range previous {
+		before[def.Type] = def
+	}
+	after := map[string]*Definition{}
+	for _, def := range current {
+		after[def.Type] = def
+	}
+
+	steps := []MigrationStep{}
+	for _, old := range previous {
+		if _, ok := after[old.Type]; !ok {
+			steps = append(steps, removeTypeStep(old.Type))
+		}
+	}
+	for _, def := range current {
+		old, ok := before[def.Type]
+		if !ok {
+			continue
+		}
+		steps = append(steps, diffParents(old, def)...)
+		steps = append(steps, diffColumns(old, def)...)
+	}
+	return steps
+}
+
+func removeTypeStep(rowType string) MigrationStep {
+	return MigrationStep{
+		Kind:        MigrationRemoveType,
+		RowType:     rowType,
+		Description: fmt.Sprintf("The %s row type was removed, so the provider no longer manages its rows.", rowType),
+		Instructions: map[string]string{
+			BackendDynamoDB: fmt.Sprintf("Export, then delete, every item whose type is %q. Remove any of their children first.", rowType),
+		},
+	}
+}
+
+func diffParents(old, def *Definition) []MigrationStep {
+	switch {
+	case len(old.Parents) == 0 && len(def.Parents) > 0:
+		return []MigrationStep{{
+			Kind:    MigrationChangeUniqueness,
+			RowType: def.Type,
+			Description: fmt.Sprintf("%s rows now need a %s parent, and their labels are unique per parent instead of per type.",
+				def.Type, strings.Join(def.Parents, " or ")),
+			Instructions: map[string]string{
+				BackendDynamoDB: fmt.Sprintf("Set parent_id on every item whose type is %q to the ID of an existing %s item.", def.Type, strings.Join(def.Parents, " or ")),
+			},
+		}}
+	case len(old.Parents) > 0 && len(def.Parents) == 0:
+		return []MigrationStep{{
+			Kind:        MigrationChangeUniqueness,
+			RowType:     def.Type,
+			Description: fmt.Sprintf("%s rows are now roots, and their labels must be unique per type instead of per parent.", def.Type),
+			Instructions: map[string]string{
+				BackendDynamoDB: fmt.Sprintf("Relabel items whose type is %q until no two share a label, then remove their parent_id attribute.", def.Type),
+			},
+		}}
+	}
+
+	removed := []string{}
+	for _, parent := range old.Parents {
+		if !slices.Contains(def.Parents, parent) {
+			removed = append(removed, parent)
+		}
+	}
+	if len(removed) == 0 {
+		return nil
+	}
+	return []MigrationStep{{
+		Kind:        MigrationChangeUniqueness,
+		RowType:     def.Type,
+		Description: fmt.Sprintf("%s rows can no longer be children of %s.", def.Type, strings.Join(removed, " or ")),
+		Instructions: map[string]string{
+			BackendDynamoDB: fmt.Sprintf("Move items whose type is %q and whose parent is a %s item to a %s parent.", def.Type, strings.Join(removed, " or "), strings.Join(def.Parents, " or ")),
+		},
+	}}
+}
+
+func diffColumns(old, def *Definition) []MigrationStep {
+	before := map[string]Column{}
+	for _, column := range old.Columns {
+		before[column.Name] = column
+	}
+	after := map[string]Column{}
+	for _, column := range def.Columns {
+		after[column.Name] = column
+	}
+
+	steps := []MigrationStep{}
+	for _, column := range old.Columns {
+		if _, ok := after[column.Name]; !ok {
+			steps = append(steps, MigrationStep{
+				Kind:        MigrationRemoveColumn,
+				RowType:     def.Type,
+				Column:      column.Name,
+				Description: fmt.Sprintf("The %s column of %s rows was removed.", column.Name, def.Type),
+				Instructions: map[string]string{
+					BackendDynamoDB: fmt.Sprintf("On every item whose type is %q, UpdateItem with \"REMOVE #columns.#%s\".", def.Type, column.Name),
+				},
+			})
+		}
+	}
+
+	for _, column := range def.Columns {
+		previous, existed := before[column.Name]
+		if column.Required && (!existed || !previous.Required) {
+			steps = append(steps, MigrationStep{
+				Kind:        MigrationBackfillColumn,
+				RowType:     def.Type,
+				Column:      column.Name,
+				Description: fmt.Sprintf("The %s column of %s rows is now required.", column.Name, def.Type),
+				Instructions: map[string]string{
+					BackendDynamoDB: fmt.Sprintf("Set columns.%s on every item whose type is %q and that lacks it.", column.Name, def.Type),
+				},
+			})
+		}
+		if !existed {
+			continue
+		}
+		if previous.Type != column.Type {
+			steps = append(steps, MigrationStep{
+				Kind:        MigrationConvertColumn,
+				RowType:     def.Type,
+				Column:      column.Name,
+				Description: fmt.Sprintf("The %s column of %s rows changed type from %s to %s.", column.Name, def.Type, previous.Type, column.Type),
+				Instructions: map[string]string{
+					BackendDynamoDB: fmt.Sprintf("Rewrite columns.%s on every item whose type is %q as a %s.", column.Name, def.Type, column.Type),
+				},
+			})
+		}
+		if column.Filterable && !previous.Filterable {
+			steps = append(steps, MigrationStep{
+				Kind:        MigrationIndexColumn,
+				RowType:     def.Type,
+				Column:      column.Name,
+				Description: fmt.Sprintf("The %s column of %s rows is now filterable.", column.Name, def.Type),
+				Instructions: map[string]string{
+					BackendDynamoDB: "None: the DynamoDB backend filters columns as it reads them, so the table needs no new index.",
+				},
+			})
+		}
+	}
+	return steps
+}
+
+// readLock reads the definitions recorded in dir's lock file. It returns nil
+// if there is no lock file yet.
+func readLock(dir string) ([]*Definition, error) {
+	b, err := os.ReadFile(filepath.Join(dir, LockFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defs := []*Definition{}
+	if err := yaml.Unmarshal(b, &defs); err != nil {
+		return nil, fmt.Errorf("%s: %w", LockFile, err)
+	}
+	return defs, nil
+}
+
+func marshalLock(defs []*Definition) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("# " + strings.TrimPrefix(GeneratedHeader, "// ") + "\n")
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(defs); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeMigration writes the steps to the next numbered file in dir.
+func writeMigration(dir string, steps []MigrationStep) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	existing, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(dir, fmt.Sprintf("%04d.yaml", len(existing)+1))
+
+	var buf bytes.Buffer
+	buf.WriteString("# Migration steps for rows stored under the previous definitions, written by\n# schema-tfgen. Carry them out before applying the regenerated provider.\n")
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(Migration{Steps: steps}); err != nil {
+		return err
+	}
+	if err := encoder.Close(); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
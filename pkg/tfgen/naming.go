@@ -0,0 +1,99 @@
+package tfgen
+
+import (
+	"strings"
+	"unicode"
+)
+
+// initialisms are kept upper-case in generated Go identifiers, per Go naming
+// conventions.
+var initialisms = map[string]string{
+	"api":  "API",
+	"arn":  "ARN",
+	"aws":  "AWS",
+	"cidr": "CIDR",
+	"dns":  "DNS",
+	"http": "HTTP",
+	"id":   "ID",
+	"ip":   "IP",
+	"kms":  "KMS",
+	"uri":  "URI",
+	"url":  "URL",
+}
+
+// exportedName converts a snake_case name to an exported Go identifier.
+func exportedName(name string) string {
+	parts := strings.Split(name, "_")
+	for i, part := range parts {
+		parts[i] = titleWord(part)
+	}
+	return strings.Join(parts, "")
+}
+
+// unexportedName converts a snake_case name to an unexported Go identifier.
+func unexportedName(name string) string {
+	parts := strings.Split(name, "_")
+	for i, part := range parts {
+		if i == 0 {
+			parts[i] = strings.ToLower(part)
+			continue
+		}
+		parts[i] = titleWord(part)
+	}
+	return strings.Join(parts, "")
+}
+
+func titleWord(word string) string {
+	if word == "" {
+		return ""
+	}
+	if initialism, ok := initialisms[word]; ok {
+		return initialism
+	}
+	return strings.ToUpper(word[:1]) + word[1:]
+}
+
+// humanName converts a snake_case name to words, for descriptions.
+func humanName(name string) string {
+	return strings.ReplaceAll(name, "_", " ")
+}
+
+// pluralize adds an English plural suffix to the last word of a snake_case
+// name. Definitions can set an explicit plural for irregular ones.
+func pluralize(name string) string {
+	switch {
+	case strings.HasSuffix(name, "s"), strings.HasSuffix(name, "x"), strings.HasSuffix(name, "z"),
+		strings.HasSuffix(name, "ch"), strings.HasSuffix(name, "sh"):
+		return name + "es"
+	case strings.HasSuffix(name, "y") && len(name) > 1 && !strings.ContainsRune("aeiou", rune(name[len(name)-2])):
+		return name[:len(name)-1] + "ies"
+	}
+	return name + "s"
+}
+
+// snakeName converts a camelCase, PascalCase, kebab-case, or space separated
+// name to snake_case. Runs of capitals are kept together as one word, so
+// "APIKey" becomes "api_key".
+func snakeName(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		switch {
+		case r == '-' || r == ' ' || r == '.' || r == '_':
+			if b.Len() > 0 && !strings.HasSuffix(b.String(), "_") {
+				b.WriteByte('_')
+			}
+			continue
+		case unicode.IsUpper(r):
+			prevLower := i > 0 && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1]))
+			nextLower := i > 0 && i+1 < len(runes) && unicode.IsUpper(runes[i-1]) && unicode.IsLower(runes[i+1])
+			if (prevLower || nextLower) && !strings.HasSuffix(b.String(), "_") {
+				b.WriteByte('_')
+			}
+			b.WriteRune(unicode.ToLower(r))
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+		}
+	}
+	return strings.Trim(b.String(), "_")
+}
@@ -0,0 +1,190 @@
+package tfgen
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// openAPIDocument holds the parts of an OpenAPI 3 (or Swagger 2) document
+// that describe schemas.
+type openAPIDocument struct {
+	Components struct {
+		Schemas map[string]*openAPISchema `yaml:"schemas"`
+	} `yaml:"components"`
+	Definitions map[string]*openAPISchema `yaml:"definitions"`
+}
+
+type openAPISchema struct {
+	Type        openAPIType               `yaml:"type"`
+	Description string                    `yaml:"description"`
+	Properties  map[string]*openAPISchema `yaml:"properties"`
+	Required    []string                  `yaml:"required"`
+	Items       *openAPISchema            `yaml:"items"`
+	Ref         string                    `yaml:"$ref"`
+	Example     interface{}               `yaml:"example"`
+	Deprecated  bool                      `yaml:"deprecated"`
+}
+
+// openAPIType is a schema's type, which OpenAPI 3.1 allows to be a list, as in
+// ["string", "null"].
+type openAPIType []string
+
+func (t *openAPIType) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*t = openAPIType{node.Value}
+		return nil
+	}
+	var types []string
+	if err := node.Decode(&types); err != nil {
+		return err
+	}
+	*t = types
+	return nil
+}
+
+// is reports whether name is one of the schema's types.
+func (t openAPIType) is(name string) bool {
+	for _, v := range t {
+		if v == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ProposeFromOpenAPI reads an OpenAPI document, in JSON or YAML, and proposes
+// a row type definition for each object schema in it. Proposals are a starting
+// point to be reviewed, not a faithful translation: properties that have no
+// column type are left out, and each one left out is described in the
+// returned warnings.
+//
+// A string property named "name" or "label" becomes the row's label. A string
+// property named after another proposed type with an "_id" suffix (like
+// "teamId" in an Environment schema) makes that type a parent.
+func ProposeFromOpenAPI(r io.Reader) ([]*Definition, []string, error) {
+	doc := openAPIDocument{}
+	if err := yaml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, nil, fmt.Errorf("reading OpenAPI document: %w", err)
+	}
+	schemas := doc.Components.Schemas
+	if len(schemas) == 0 {
+		schemas = doc.Definitions
+	}
+
+	names := make([]string, 0, len(schemas))
+	types := map[string]bool{}
+	for name, schema := range schemas {
+		if schema == nil || !isOpenAPIObject(schema) {
+			continue
+		}
+		names = append(names, name)
+		types[snakeName(name)] = true
+	}
+	sort.Strings(names)
+
+	defs := []*Definition{}
+	warnings := []string{}
+	for _, name := range names {
+		def, w := proposeDefinition(name, schemas[name], types)
+		defs = append(defs, def)
+		warnings = append(warnings, w...)
+	}
+	return defs, warnings, nil
+}
+
+// openAPIDeprecation is the warning proposed for whatever the API deprecates.
+const openAPIDeprecation = "Deprecated by the API."
+
+func isOpenAPIObject(schema *openAPISchema) bool {
+	return schema.Type.is("object") || (len(schema.Type) == 0 && len(schema.Properties) > 0)
+}
+
+func proposeDefinition(name string, schema *openAPISchema, types map[string]bool) (*Definition, []string) {
+	def := &Definition{
+		Type:        snakeName(name),
+		Description: strings.TrimSpace(schema.Description),
+	}
+	if schema.Deprecated {
+		def.Deprecated = openAPIDeprecation
+	}
+	warnings := []string{}
+
+	required := map[string]bool{}
+	for _, r := range schema.Required {
+		required[r] = true
+	}
+
+	properties := make([]string, 0, len(schema.Properties))
+	for property := range schema.Properties {
+		properties = append(properties, property)
+	}
+	sort.Strings(properties)
+
+	for _, property := range properties {
+		prop := schema.Properties[property]
+		column := snakeName(property)
+		if prop == nil {
+			continue
+		}
+
+		switch {
+		case column == attrID:
+			// every row has an ID already
+			continue
+		case column == attrLabel || column == "name":
+			// the label stands in for the row's name
+			continue
+		case strings.HasSuffix(column, "_id") && types[strings.TrimSuffix(column, "_id")]:
+			parent := strings.TrimSuffix(column, "_id")
+			def.Parents = append(def.Parents, parent)
+			continue
+		case isReservedAttribute(column):
+			warnings = append(warnings, fmt.Sprintf("%s.%s: skipped, %q is a reserved attribute name", name, property, column))
+			continue
+		}
+
+		columnType := ""
+		switch {
+		case prop.Type.is("string"):
+			columnType = ColumnTypeString
+		case prop.Type.is("array") && prop.Items != nil && prop.Items.Type.is("string"):
+			columnType = ColumnTypeStringSet
+		}
+		if columnType == "" {
+			warnings = append(warnings, fmt.Sprintf("%s.%s: skipped, %s properties have no column type", name, property, describeOpenAPIType(prop)))
+			continue
+		}
+
+		c := Column{
+			Name:        column,
+			Type:        columnType,
+			Description: strings.TrimSpace(prop.Description),
+			Required:    required[property],
+		}
+		if example, ok := prop.Example.(string); ok && columnType == ColumnTypeString {
+			c.Example = example
+		}
+		if prop.Deprecated {
+			c.Deprecated = openAPIDeprecation
+		}
+		def.Columns = append(def.Columns, c)
+	}
+	return def, warnings
+}
+
+func describeOpenAPIType(schema *openAPISchema) string {
+	if schema.Ref != "" {
+		return "$ref"
+	}
+	if len(schema.Type) == 0 {
+		return "untyped"
+	}
+	if schema.Type.is("array") && schema.Items != nil {
+		return "array of " + describeOpenAPIType(schema.Items)
+	}
+	return strings.Join(schema.Type, "/")
+}
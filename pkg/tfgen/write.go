@@ -0,0 +1,43 @@
+package tfgen
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+var ErrDefinitionExists = errors.New("definition file already exists")
+
+// WriteDefinitions writes each definition to its own YAML file in dir, named
+// after its type. Existing files are only replaced if overwrite is set, so
+// that proposed definitions never clobber hand-edited ones.
+func WriteDefinitions(dir string, defs []*Definition, overwrite bool) ([]string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	written := []string{}
+	for _, def := range defs {
+		path := filepath.Join(dir, def.Type+".yaml")
+		if _, err := os.Stat(path); err == nil && !overwrite {
+			return written, fmt.Errorf("%w: %s", ErrDefinitionExists, path)
+		}
+		var buf bytes.Buffer
+		encoder := yaml.NewEncoder(&buf)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(def); err != nil {
+			return written, err
+		}
+		if err := encoder.Close(); err != nil {
+			return written, err
+		}
+		if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+			return written, err
+		}
+		written = append(written, path)
+	}
+	return written, nil
+}